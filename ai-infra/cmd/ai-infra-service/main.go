@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -17,11 +19,15 @@ import (
 
 	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/reasoning"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/runner"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/storage"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 func enforceProdGuardrails() {
@@ -34,8 +40,8 @@ func enforceProdGuardrails() {
 		log.Fatalf("[startup] DEV_SKIP_MTLS=true is forbidden in production")
 	}
 	if nodeEnv == "production" || requireKMS {
-		if os.Getenv("AI_INFRA_KMS_ENDPOINT") == "" && os.Getenv("KMS_ENDPOINT") == "" {
-			log.Fatalf("[startup] KMS endpoint is required in production (set AI_INFRA_KMS_ENDPOINT or KMS_ENDPOINT)")
+		if os.Getenv("KERNEL_API_URL") == "" && os.Getenv("AI_INFRA_KMS_ENDPOINT") == "" && os.Getenv("KMS_ENDPOINT") == "" {
+			log.Fatalf("[startup] Kernel or KMS signing is required in production (set KERNEL_API_URL, AI_INFRA_KMS_ENDPOINT or KMS_ENDPOINT)")
 		}
 	}
 }
@@ -50,6 +56,12 @@ func main() {
 	if err != nil {
 		log.Fatalf("config load: %v", err)
 	}
+	tracer, err := tracing.FromEnv("ai-infra")
+	if err != nil {
+		log.Fatalf("tracing init: %v", err)
+	}
+	tracing.SetDefault(tracer)
+	defer tracer.Close()
 	db, err := sql.Open("postgres", cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("db open: %v", err)
@@ -62,8 +74,19 @@ func main() {
 		log.Fatalf("db ping: %v", err)
 	}
 
-	st := store.NewPGStore(db)
-	signer, err := signing.NewSignerFromConfig(cfg)
+	st := store.WithTracing(store.NewPGStore(db))
+	var kernel *kernelclient.Client
+	if cfg.KernelAPIURL != "" {
+		kernel, err = kernelclient.New(kernelclient.ConfigFromEnv("ai-infra"))
+		if err != nil {
+			log.Fatalf("kernel client init: %v", err)
+		}
+	}
+	var kernelSigning signing.KernelClient // stays a nil interface without a Kernel
+	if kernel != nil {
+		kernelSigning = kernel
+	}
+	signer, err := signing.NewSignerFromConfig(cfg, kernelSigning)
 	if err != nil {
 		log.Fatalf("signer init: %v", err)
 	}
@@ -81,6 +104,58 @@ func main() {
 	}
 
 	svc := service.New(st, sentinelClient, signer)
+	if cfg.SignerPublicKeys != "" || kernel != nil {
+		ring, err := signing.ParseKeyRing(cfg.SignerPublicKeys)
+		if err != nil {
+			log.Fatalf("signer public keys: %v", err)
+		}
+		if local, ok := signer.(*signing.Ed25519Signer); ok {
+			ring.Add(local.SignerID(), local.PublicKey())
+		}
+		if kernel != nil {
+			keyCtx, keyCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			kernelRing, err := signing.KeyRingFromKernel(keyCtx, kernel)
+			keyCancel()
+			if err != nil {
+				log.Printf("kernel signer keys unavailable, /verify uses configured keys only: %v", err)
+			} else {
+				ring.Merge(kernelRing)
+			}
+		}
+		svc.SetVerifier(ring)
+	}
+	if kernel != nil {
+		svc.SetAuditor(kernel)
+		svc.SetKernelManifests(kernel, signing.NewKernelKeys(kernel, time.Minute))
+	}
+	svc.RequireManifestSignatures(cfg.RequireManifestSignatures)
+	svc.RequireDatasetVersions(cfg.RequireDatasetVersions)
+	if cfg.ReasoningGraphURL != "" {
+		reasoningClient, err := reasoning.NewHTTPClient(reasoning.HTTPClientConfig{
+			BaseURL: cfg.ReasoningGraphURL,
+			Token:   cfg.ReasoningGraphToken,
+			Timeout: 5 * time.Second,
+		})
+		if err != nil {
+			log.Fatalf("reasoning graph client init: %v", err)
+		}
+		svc.SetReasoningGraph(reasoningClient)
+	}
+	svc.SetJobPolicy(service.JobPolicy{
+		MaxAttempts:   cfg.JobMaxAttempts,
+		LeaseDuration: cfg.JobLease,
+		BackoffBase:   cfg.JobBackoff,
+	})
+	workDir := cfg.RunnerWorkDir
+	if workDir == "" {
+		workDir = runner.DefaultWorkDir()
+	}
+	blobs, err := newBlobStore(cfg)
+	if err != nil {
+		log.Fatalf("artifact blob store: %v", err)
+	}
+	svc.SetBlobStore(blobs)
+	svc.SetArtifactFetcher(storage.WithBlobs(blobs, storage.NewURIFetcher(append([]string{workDir}, cfg.ArtifactRoots...), cfg.ArtifactURLPrefixes, time.Minute)))
 	server := httpserver.New(cfg, svc, st)
 
 	httpServer := &http.Server{
@@ -91,8 +166,22 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	if shouldRunRunner(*runRunner) {
-		log.Printf("starting training runner")
-		go runner.RunWorker(ctx, svc, st, runner.Config{})
+		executor, err := runner.NewExecutor(cfg.RunnerExecutor, runner.ProcessConfig{
+			WorkDir:         cfg.RunnerWorkDir,
+			Timeout:         cfg.RunnerTimeout,
+			ContainerImage:  cfg.RunnerImage,
+			EntrypointRoots: cfg.RunnerEntrypointRoots,
+		})
+		if err != nil {
+			log.Fatalf("training runner: %v", err)
+		}
+		log.Printf("starting training runner (%s executor)", executor.Name())
+		go runner.RunWorker(ctx, svc, runner.Config{Executor: executor})
+	}
+	go svc.RunCanaryController(ctx, cfg.CanaryInterval)
+	go svc.RunJobReaper(ctx, cfg.JobReaperInterval)
+	if cfg.BlobGCInterval > 0 {
+		go svc.RunBlobGC(ctx, cfg.BlobGCInterval, cfg.BlobGCGrace)
 	}
 
 	go func() {
@@ -128,3 +217,41 @@ func shouldRunRunner(flagValue bool) bool {
 	}
 	return false
 }
+
+// newBlobStore opens the configured artifact blob store. Download URLs of the local
+// backend are served by this service; without AI_INFRA_BLOB_URL_SECRET they are signed
+// with a per-process key and stop working on restart.
+func newBlobStore(cfg config.Config) (storage.BlobStore, error) {
+	if cfg.BlobBackend == "s3" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return storage.NewS3BlobStore(ctx, storage.S3Config{
+			Bucket:   cfg.BlobS3Bucket,
+			Prefix:   cfg.BlobS3Prefix,
+			Endpoint: cfg.BlobS3Endpoint,
+		})
+	}
+	dir := cfg.BlobDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "ai-infra-blobs")
+	}
+	key := []byte(cfg.BlobURLSecret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		log.Printf("AI_INFRA_BLOB_URL_SECRET unset: blob download urls are only valid until restart")
+	}
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		publicURL = "http://localhost" + cfg.Addr
+		if !strings.HasPrefix(cfg.Addr, ":") {
+			publicURL = "http://" + cfg.Addr
+		}
+	}
+	return storage.NewLocalBlobStore(dir, &storage.URLSigner{
+		Key:     key,
+		BaseURL: strings.TrimRight(publicURL, "/") + "/ai-infra/blobs",
+	})
+}
@@ -0,0 +1,160 @@
+package acceptance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/storage"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+func TestArtifactBlobStore(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	root := t.TempDir()
+	blobs, err := storage.NewLocalBlobStore(root, &storage.URLSigner{Key: []byte("secret"), BaseURL: server.URL + "/ai-infra/blobs"})
+	if err != nil {
+		t.Fatalf("blob store: %v", err)
+	}
+	svc.SetBlobStore(blobs)
+	svc.SetArtifactFetcher(storage.WithBlobs(blobs, nil))
+	mux.Handle("/", httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev", BlobMaxBytes: 1 << 10}, svc, memStore).Router())
+
+	api := newAPIClient(t, server.URL)
+
+	content := []byte("trained weights")
+	digest := sha256.Sum256(content)
+	sum := hex.EncodeToString(digest[:])
+	var blob, again storage.Blob
+	api.call(http.MethodPost, "/ai-infra/blobs?sha256=sha256:"+sum, bytes.NewReader(content), http.StatusCreated, &blob)
+	if blob.SHA256 != sum || blob.Size != int64(len(content)) || !strings.HasSuffix(blob.URI, "/sha256/"+sum[:2]+"/"+sum) {
+		t.Fatalf("unexpected blob: %+v", blob)
+	}
+	api.call(http.MethodPost, "/ai-infra/blobs", bytes.NewReader(content), http.StatusCreated, &again)
+	if again.URI != blob.URI {
+		t.Fatalf("identical content must share a blob: %+v %+v", blob, again)
+	}
+	api.call(http.MethodPost, "/ai-infra/blobs?sha256="+strings.Repeat("0", 64), bytes.NewReader([]byte("other")), http.StatusBadRequest, nil)
+	api.call(http.MethodPost, "/ai-infra/blobs", bytes.NewReader(make([]byte, 2<<10)), http.StatusRequestEntityTooLarge, nil)
+	if stored, _ := blobs.List(ctx); len(stored) != 1 {
+		t.Fatalf("rejected uploads must not be stored: %+v", stored)
+	}
+	api.call(http.MethodGet, "/ai-infra/blobs/"+strings.Repeat("f", 64), nil, http.StatusNotFound, nil)
+
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: "git://repo@blobs", ContainerDigest: "sha256:def"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	register := func(uri, checksum string, want int) models.ModelArtifact {
+		t.Helper()
+		body, _ := json.Marshal(map[string]string{"trainingJobId": job.ID.String(), "artifactUri": uri, "checksum": checksum})
+		var artifact models.ModelArtifact
+		if want != http.StatusCreated {
+			api.call(http.MethodPost, "/ai-infra/register", bytes.NewReader(body), want, nil)
+			return artifact
+		}
+		api.call(http.MethodPost, "/ai-infra/register", bytes.NewReader(body), want, &artifact)
+		return artifact
+	}
+	register(blob.URI, strings.Repeat("0", 64), http.StatusBadRequest)
+	register(blobs.URI(strings.Repeat("a", 64)), strings.Repeat("a", 64), http.StatusBadRequest)
+	// Blob URIs are stored in canonical form, so garbage collection finds the reference.
+	artifact := register(strings.Replace(blob.URI, "/sha256/", "/./sha256/", 1), sum, http.StatusCreated)
+	if artifact.ArtifactURI != blob.URI {
+		t.Fatalf("expected the canonical blob uri %s, got %s", blob.URI, artifact.ArtifactURI)
+	}
+
+	// Artifacts held by the store can be downloaded through a presigned URL.
+	var link service.DownloadURL
+	api.call(http.MethodGet, "/ai-infra/models/"+artifact.ID.String()+"/download-url?ttlSeconds=60", nil, http.StatusOK, &link)
+	if link.SHA256 != sum || time.Until(link.ExpiresAt) > time.Minute {
+		t.Fatalf("unexpected download url: %+v", link)
+	}
+	resp, err := http.Get(link.URL)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	downloaded, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(downloaded, content) {
+		t.Fatalf("download: %d %q", resp.StatusCode, downloaded)
+	}
+	for _, bad := range []string{
+		strings.Replace(link.URL, "signature=", "signature=00", 1),
+		server.URL + "/ai-infra/blobs/" + sum + "/content?expires=1&signature=00",
+	} {
+		resp, err := http.Get(bad)
+		if err != nil {
+			t.Fatalf("download: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected a tampered or expired url to be refused, got %d", resp.StatusCode)
+		}
+	}
+	remote := register("s3://elsewhere/model.pt", "1234", http.StatusCreated)
+	api.call(http.MethodGet, "/ai-infra/models/"+remote.ID.String()+"/download-url", nil, http.StatusConflict, nil)
+
+	// Verification reads the blob back through the store.
+	res, err := svc.VerifyAndRecord(ctx, artifact.ID, service.VerifyRequest{Checksum: true})
+	if err != nil || res.Verification.Status != models.VerificationPassed || res.Verification.Checks[1].Status != models.CheckPassed {
+		t.Fatalf("verify blob artifact: %+v %v", res.Verification, err)
+	}
+
+	// Only blobs no artifact references are collected, once past the grace period.
+	orphan, err := svc.UploadBlob(ctx, bytes.NewReader([]byte("abandoned upload")), "")
+	if err != nil {
+		t.Fatalf("upload orphan: %v", err)
+	}
+	gc, err := svc.CollectBlobGarbage(ctx, time.Now(), time.Hour, false)
+	if err != nil || gc.Scanned != 2 || len(gc.Deleted) != 0 {
+		t.Fatalf("expected the grace period to keep new blobs: %+v %v", gc, err)
+	}
+	// Uploading a stored blob again restarts its grace period.
+	aged := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(root, "sha256", orphan.SHA256[:2], orphan.SHA256), aged, aged); err != nil {
+		t.Fatalf("age orphan: %v", err)
+	}
+	if _, err := svc.UploadBlob(ctx, bytes.NewReader([]byte("abandoned upload")), ""); err != nil {
+		t.Fatalf("upload orphan again: %v", err)
+	}
+	gc, err = svc.CollectBlobGarbage(ctx, time.Now(), time.Hour, false)
+	if err != nil || len(gc.Deleted) != 0 {
+		t.Fatalf("expected the re-uploaded blob to be kept: %+v %v", gc, err)
+	}
+	var dry service.BlobGCResult
+	api.call(http.MethodPost, "/ai-infra/blobs/gc?dryRun=true", nil, http.StatusOK, &dry)
+	if !dry.DryRun || len(dry.Deleted) != 1 || dry.Deleted[0].SHA256 != orphan.SHA256 {
+		t.Fatalf("unexpected dry run: %+v", dry)
+	}
+	gc, err = svc.CollectBlobGarbage(ctx, time.Now(), 0, false)
+	if err != nil || len(gc.Deleted) != 1 || gc.Deleted[0].SHA256 != orphan.SHA256 || gc.FreedBytes != orphan.Size {
+		t.Fatalf("unexpected collection: %+v %v", gc, err)
+	}
+	if _, err := blobs.Stat(ctx, orphan.SHA256); err != storage.ErrBlobNotFound {
+		t.Fatalf("expected the orphan to be deleted: %v", err)
+	}
+	if _, err := blobs.Stat(ctx, sum); err != nil {
+		t.Fatalf("referenced blob was deleted: %v", err)
+	}
+}
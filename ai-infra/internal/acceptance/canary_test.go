@@ -0,0 +1,278 @@
+package acceptance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+func TestCanaryPromotesAndRollsBack(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	router := httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev"}, svc, memStore).Router()
+
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{
+		CodeRef:         "git://repo@canary",
+		ContainerDigest: "sha256:789",
+	})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	register := func(checksum string) models.ModelArtifact {
+		t.Helper()
+		artifact, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
+			TrainingJobID: job.ID,
+			ArtifactURI:   "s3://bucket/" + checksum + ".pt",
+			Checksum:      checksum,
+		})
+		if err != nil {
+			t.Fatalf("register artifact: %v", err)
+		}
+		return artifact
+	}
+	policy := &models.CanaryPolicy{
+		WindowMinutes: 30,
+		MinSamples:    2,
+		MetricSignals: []string{"accuracy", "p95_latency"},
+		LowerIsBetter: []string{"p95_latency"},
+	}
+	startCanary := func(artifact models.ModelArtifact) models.ModelPromotion {
+		t.Helper()
+		promo, err := svc.PromoteArtifact(ctx, service.PromotionRequest{
+			ArtifactID:  artifact.ID,
+			Environment: "prod",
+			Evaluation:  json.RawMessage(`{"quality":0.9}`),
+			RequestedBy: "ml-lead",
+			Canary:      policy,
+		})
+		if err != nil {
+			t.Fatalf("start canary: %v", err)
+		}
+		if promo.Status != models.PromotionCanary || promo.Signature != nil || promo.Canary == nil {
+			t.Fatalf("expected an unsigned canary promotion, got %+v", promo)
+		}
+		return promo
+	}
+	observe := func(id uuid.UUID, body string) models.ModelPromotion {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/ai-infra/promotions/"+id.String()+"/observations", strings.NewReader(body))
+		req.Header.Set("X-Debug-Token", "dev")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("observations: %d %s", rec.Code, rec.Body.String())
+		}
+		var promo models.ModelPromotion
+		if err := json.NewDecoder(rec.Body).Decode(&promo); err != nil {
+			t.Fatalf("decode promotion: %v", err)
+		}
+		return promo
+	}
+	verify := func(artifactID uuid.UUID) {
+		t.Helper()
+		res, err := svc.VerifyArtifact(ctx, artifactID)
+		if err != nil || !res.OK || len(res.Promotions) != 1 {
+			t.Fatalf("verify artifact: %+v %v", res, err)
+		}
+	}
+
+	incumbent := register("sum-incumbent")
+	if _, err := svc.PromoteArtifact(ctx, service.PromotionRequest{
+		ArtifactID:  incumbent.ID,
+		Environment: "prod",
+		Evaluation:  json.RawMessage(`{"quality":0.9}`),
+	}); err != nil {
+		t.Fatalf("promote incumbent: %v", err)
+	}
+
+	// A healthy canary is applied once its window ends.
+	candidate := register("sum-candidate")
+	promo := startCanary(candidate)
+	if promo.Canary.IncumbentArtifactID == nil || *promo.Canary.IncumbentArtifactID != incumbent.ID {
+		t.Fatalf("incumbent not recorded: %+v", promo.Canary)
+	}
+	if _, err := svc.PromoteArtifact(ctx, service.PromotionRequest{
+		ArtifactID: register("sum-other").ID, Environment: "prod", Canary: policy,
+	}); !errors.Is(err, service.ErrCanaryInProgress) {
+		t.Fatalf("expected ErrCanaryInProgress, got %v", err)
+	}
+	promo = observe(promo.ID, `{"observations":[
+		{"arm":"incumbent","metrics":{"accuracy":0.90,"p95_latency":100}},
+		{"arm":"incumbent","metrics":{"accuracy":0.90,"p95_latency":100}},
+		{"arm":"canary","metrics":{"accuracy":0.91,"p95_latency":101}},
+		{"arm":"canary","metrics":{"accuracy":0.90,"p95_latency":99}}]}`)
+	if promo.Status != models.PromotionCanary {
+		t.Fatalf("healthy canary decided early: %+v", promo)
+	}
+	if live, err := svc.GetPromotion(ctx, promo.ID); err != nil || live.Canary.CanaryArm.Samples != 2 || len(live.Canary.Comparisons) != 2 {
+		t.Fatalf("live canary summary: %+v %v", live.Canary, err)
+	}
+	if decided, _ := svc.EvaluateCanaries(ctx, time.Now()); len(decided) != 0 {
+		t.Fatalf("canary decided before its window ended: %+v", decided)
+	}
+	decided, err := svc.EvaluateCanaries(ctx, time.Now().Add(time.Hour))
+	if err != nil || len(decided) != 1 {
+		t.Fatalf("evaluate canaries: %+v %v", decided, err)
+	}
+	applied := decided[0]
+	if applied.Status != models.PromotionApplied || applied.Signature == nil || applied.PromotedAt == nil ||
+		applied.Canary.Outcome != models.PromotionApplied || len(applied.Canary.History) != 2 {
+		t.Fatalf("unexpected applied canary: %+v %+v", applied, applied.Canary)
+	}
+	verify(candidate.ID)
+
+	// A regressing canary is rolled back as soon as both arms have enough samples.
+	regressing := register("sum-regressing")
+	promo = startCanary(regressing)
+	if *promo.Canary.IncumbentArtifactID != candidate.ID {
+		t.Fatalf("expected the applied canary as incumbent, got %s", promo.Canary.IncumbentArtifactID)
+	}
+	promo = observe(promo.ID, `{"observations":[
+		{"arm":"incumbent","metrics":{"accuracy":0.91,"p95_latency":100}},
+		{"arm":"incumbent","metrics":{"accuracy":0.91,"p95_latency":100}},
+		{"arm":"canary","metrics":{"accuracy":0.91,"p95_latency":150}},
+		{"arm":"canary","metrics":{"accuracy":0.91,"p95_latency":150}}]}`)
+	if promo.Status != models.PromotionRolledBack || promo.Signature == nil || promo.PromotedAt != nil ||
+		!strings.Contains(promo.Canary.Reason, "p95_latency") {
+		t.Fatalf("expected an early signed rollback, got %+v %+v", promo, promo.Canary)
+	}
+	verify(regressing.ID)
+	if latest, err := memStore.LatestAppliedPromotion(ctx, "prod"); err != nil || latest.ArtifactID != candidate.ID {
+		t.Fatalf("rollback changed the applied artifact: %+v %v", latest, err)
+	}
+
+	// A canary without enough samples fails safe at the end of its window.
+	starved := startCanary(register("sum-starved"))
+	decided, _ = svc.EvaluateCanaries(ctx, time.Now().Add(time.Hour))
+	if len(decided) != 1 || decided[0].ID != starved.ID || decided[0].Status != models.PromotionRolledBack {
+		t.Fatalf("expected starved canary rolled back, got %+v", decided)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ai-infra/promotions/"+starved.ID.String(), nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"outcome":"rolled_back"`) {
+		t.Fatalf("get promotion: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCanaryZeroThresholdAndConcurrentStarts(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	router := httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev"}, svc, memStore).Router()
+
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: "git://repo@canary", ContainerDigest: "sha256:789"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	register := func(checksum string) models.ModelArtifact {
+		t.Helper()
+		artifact, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
+			TrainingJobID: job.ID,
+			ArtifactURI:   "s3://bucket/" + checksum + ".pt",
+			Checksum:      checksum,
+		})
+		if err != nil {
+			t.Fatalf("register artifact: %v", err)
+		}
+		return artifact
+	}
+	quality := json.RawMessage(`{"quality":0.9}`)
+	if _, err := svc.PromoteArtifact(ctx, service.PromotionRequest{ArtifactID: register("sum-incumbent").ID, Environment: "prod", Evaluation: quality}); err != nil {
+		t.Fatalf("promote incumbent: %v", err)
+	}
+
+	// Concurrent canary starts for one environment: exactly one wins and the others are
+	// rejected instead of left pending.
+	const starts = 8
+	artifacts := make([]models.ModelArtifact, starts)
+	for i := range artifacts {
+		artifacts[i] = register(fmt.Sprintf("sum-concurrent-%d", i))
+	}
+	policy := &models.CanaryPolicy{MinSamples: 1, MetricSignals: []string{"accuracy"}}
+	type result struct {
+		promo models.ModelPromotion
+		err   error
+	}
+	results := make(chan result, starts)
+	for _, artifact := range artifacts {
+		go func(id uuid.UUID) {
+			promo, err := svc.PromoteArtifact(ctx, service.PromotionRequest{ArtifactID: id, Environment: "prod", Evaluation: quality, Canary: policy})
+			results <- result{promo, err}
+		}(artifact.ID)
+	}
+	var winner models.ModelPromotion
+	for i := 0; i < starts; i++ {
+		r := <-results
+		switch {
+		case r.err == nil:
+			if winner.ID != uuid.Nil {
+				t.Fatalf("two canaries started for one environment: %s and %s", winner.ID, r.promo.ID)
+			}
+			winner = r.promo
+		case errors.Is(r.err, service.ErrCanaryInProgress):
+			if lost, err := svc.GetPromotion(ctx, r.promo.ID); err == nil && lost.Status != models.PromotionRejected {
+				t.Fatalf("losing canary left in %q", lost.Status)
+			}
+		default:
+			t.Fatalf("start canary: %v", r.err)
+		}
+	}
+	if winner.Status != models.PromotionCanary {
+		t.Fatalf("expected one running canary, got %+v", winner)
+	}
+	// The store enforces the rule even when two promotions passed the service's check.
+	racer, err := memStore.CreatePromotion(ctx, store.PromotionInput{ArtifactID: artifacts[0].ID, Environment: "prod", Status: models.PromotionPending})
+	if err != nil {
+		t.Fatalf("create promotion: %v", err)
+	}
+	if _, err := memStore.UpdatePromotionStatus(ctx, store.PromotionStatusUpdate{ID: racer.ID, Status: models.PromotionCanary}); !errors.Is(err, store.ErrCanaryExists) {
+		t.Fatalf("expected ErrCanaryExists, got %v", err)
+	}
+	if *winner.Canary.Policy.RollbackThreshold != 0.02 {
+		t.Fatalf("omitted rollbackThreshold not defaulted: %v", *winner.Canary.Policy.RollbackThreshold)
+	}
+	if decided, err := svc.EvaluateCanaries(ctx, time.Now().Add(2*time.Hour)); err != nil || len(decided) != 1 {
+		t.Fatalf("finish canary: %+v %v", decided, err)
+	}
+
+	// An explicit rollbackThreshold of 0 rolls back on any drop.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/ai-infra/models/"+register("sum-zero").ID.String()+"/canary",
+		strings.NewReader(`{"environment":"prod","evaluation":{"quality":0.9},"minSamples":1,"metricSignals":["accuracy"],"rollbackThreshold":0}`))
+	req.Header.Set("X-Debug-Token", "dev")
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("start zero-threshold canary: %d %s", rec.Code, rec.Body.String())
+	}
+	var zero models.ModelPromotion
+	if err := json.NewDecoder(rec.Body).Decode(&zero); err != nil {
+		t.Fatalf("decode promotion: %v", err)
+	}
+	if zero.Canary.Policy.RollbackThreshold == nil || *zero.Canary.Policy.RollbackThreshold != 0 {
+		t.Fatalf("explicit zero threshold replaced: %+v", zero.Canary.Policy)
+	}
+	promo, err := svc.RecordCanaryObservations(ctx, zero.ID, []service.CanaryObservationInput{
+		{Arm: models.ArmIncumbent, Metrics: map[string]float64{"accuracy": 0.900}},
+		{Arm: models.ArmCanary, Metrics: map[string]float64{"accuracy": 0.899}},
+	})
+	if err != nil || promo.Status != models.PromotionRolledBack {
+		t.Fatalf("expected a 0.1%% drop to roll back with threshold 0: %+v %v", promo, err)
+	}
+}
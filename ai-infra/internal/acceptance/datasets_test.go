@@ -0,0 +1,149 @@
+package acceptance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/canonical"
+)
+
+func TestDatasetRegistry(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	server := httptest.NewServer(httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev"}, svc, memStore).Router())
+	defer server.Close()
+
+	api := newAPIClient(t, server.URL)
+
+	manifest := []models.DatasetFile{
+		{Path: "val.jsonl", SHA256: "sha256:" + strings.Repeat("B", 64), Size: 10},
+		{Path: "train.jsonl", SHA256: strings.Repeat("a", 64), Size: 100},
+	}
+	request := service.DatasetVersionRequest{
+		Name: "support-chats", Version: "2026-10", Manifest: manifest, License: "CC-BY-4.0", PIIFlags: []string{"Email", "name", "email"},
+	}
+	var ds models.DatasetVersion
+	api.call(http.MethodPost, "/ai-infra/datasets", request, http.StatusCreated, &ds)
+	if ds.Status != models.DatasetActive || len(ds.ManifestHash) != 64 || ds.Manifest[0].Path != "train.jsonl" ||
+		ds.Manifest[1].SHA256 != strings.Repeat("b", 64) || strings.Join(ds.PIIFlags, ",") != "email,name" {
+		t.Fatalf("unexpected dataset version: %+v", ds)
+	}
+	canonicalManifest, err := canonical.Marshal(ds.Manifest)
+	if err != nil {
+		t.Fatalf("canonicalize manifest: %v", err)
+	}
+	if sum := sha256.Sum256(canonicalManifest); ds.ManifestHash != hex.EncodeToString(sum[:]) {
+		t.Fatalf("manifestHash is not the sha256 of the canonical manifest: %s", ds.ManifestHash)
+	}
+	api.call(http.MethodPost, "/ai-infra/datasets", request, http.StatusConflict, nil)
+	bad := request
+	bad.Version, bad.Manifest = "bad", []models.DatasetFile{{Path: "x", SHA256: "not-a-hash"}}
+	api.call(http.MethodPost, "/ai-infra/datasets", bad, http.StatusBadRequest, nil)
+
+	// Reordering the manifest does not change its hash.
+	reordered := request
+	reordered.Version, reordered.Manifest = "2026-10-copy", []models.DatasetFile{manifest[1], manifest[0]}
+	copyVersion, err := svc.RegisterDatasetVersion(ctx, reordered)
+	if err != nil || copyVersion.ManifestHash != ds.ManifestHash {
+		t.Fatalf("expected the manifest hash to ignore file order: %+v %v", copyVersion, err)
+	}
+	var listed []models.DatasetVersion
+	api.call(http.MethodGet, "/ai-infra/datasets?name=support-chats&status=active", nil, http.StatusOK, &listed)
+	if len(listed) != 2 {
+		t.Fatalf("expected 2 listed versions, got %+v", listed)
+	}
+
+	// Training jobs reference versions by id and store the resolved reference.
+	train := func(code string, refs string, want int) models.TrainingJob {
+		t.Helper()
+		var job models.TrainingJob
+		body := map[string]interface{}{"codeRef": code, "containerDigest": "sha256:ds", "datasetRefs": json.RawMessage(refs)}
+		if want != http.StatusCreated {
+			api.call(http.MethodPost, "/ai-infra/train", body, want, nil)
+			return job
+		}
+		api.call(http.MethodPost, "/ai-infra/train", body, want, &job)
+		return job
+	}
+	job := train("git://repo@trained", `["s3://datasets/extra",{"datasetVersionId":"`+ds.ID.String()+`"}]`, http.StatusCreated)
+	var entries []json.RawMessage
+	var resolved models.DatasetRef
+	_ = json.Unmarshal(job.DatasetRefs, &entries)
+	if len(entries) != 2 || string(entries[0]) != `"s3://datasets/extra"` {
+		t.Fatalf("expected other entries to be kept as given: %s", job.DatasetRefs)
+	}
+	if err := json.Unmarshal(entries[1], &resolved); err != nil || resolved.Checksum != ds.ManifestHash || resolved.ID != "support-chats" ||
+		resolved.Version != "2026-10" || resolved.DatasetVersionID == nil || *resolved.DatasetVersionID != ds.ID {
+		t.Fatalf("unexpected resolved datasetRefs: %s %v", job.DatasetRefs, err)
+	}
+	train("git://repo@unknown", `[{"datasetVersionId":"`+copyVersion.Name+`"}]`, http.StatusBadRequest)
+	train("git://repo@unknown", `[{"datasetVersionId":"00000000-0000-0000-0000-000000000001"}]`, http.StatusBadRequest)
+	running := train("git://repo@running", `[{"datasetVersionId":"`+ds.ID.String()+`"}]`, http.StatusCreated)
+
+	trained, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{TrainingJobID: job.ID, ArtifactURI: "s3://bucket/chat.pt", Checksum: "sum-chat"})
+	if err != nil {
+		t.Fatalf("register artifact: %v", err)
+	}
+	other := train("git://repo@other", `[{"datasetVersionId":"`+copyVersion.ID.String()+`"}]`, http.StatusCreated)
+	untouched, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{TrainingJobID: other.ID, ArtifactURI: "s3://bucket/other.pt", Checksum: "sum-other"})
+	if err != nil {
+		t.Fatalf("register artifact: %v", err)
+	}
+
+	// With dataset versions required, free-form references are refused.
+	svc.RequireDatasetVersions(true)
+	train("git://repo@free-form", `["s3://datasets/extra",{"datasetVersionId":"`+copyVersion.ID.String()+`"}]`, http.StatusBadRequest)
+	train("git://repo@versioned", `[{"datasetVersionId":"`+copyVersion.ID.String()+`"}]`, http.StatusCreated)
+	svc.RequireDatasetVersions(false)
+
+	// Revoking flags the artifacts trained on the version and refuses new jobs.
+	api.call(http.MethodPost, "/ai-infra/datasets/"+ds.ID.String()+"/revoke", map[string]string{}, http.StatusBadRequest, nil)
+	var revocation service.DatasetRevocationResult
+	api.call(http.MethodPost, "/ai-infra/datasets/"+ds.ID.String()+"/revoke", map[string]string{"reason": "consent withdrawn", "revokedBy": "privacy"}, http.StatusOK, &revocation)
+	if revocation.Dataset.Status != models.DatasetRevoked || revocation.Dataset.RevokedAt == nil ||
+		len(revocation.FlaggedArtifacts) != 1 || revocation.FlaggedArtifacts[0] != trained.ID {
+		t.Fatalf("unexpected revocation: %+v", revocation)
+	}
+	api.call(http.MethodPost, "/ai-infra/datasets/"+ds.ID.String()+"/revoke", map[string]string{"reason": "again"}, http.StatusConflict, nil)
+	var detail struct {
+		Artifact models.ModelArtifact `json:"artifact"`
+	}
+	api.call(http.MethodGet, "/ai-infra/models/"+trained.ID.String(), nil, http.StatusOK, &detail)
+	flags := detail.Artifact.Flags
+	if len(flags) != 1 || flags[0].Reason != models.FlagDatasetRevoked || *flags[0].DatasetVersionID != ds.ID ||
+		!strings.Contains(flags[0].Detail, "consent withdrawn") {
+		t.Fatalf("unexpected artifact flags: %+v", flags)
+	}
+	if got, _ := memStore.GetArtifact(ctx, untouched.ID); len(got.Flags) != 0 {
+		t.Fatalf("artifact trained on another version was flagged: %+v", got.Flags)
+	}
+	train("git://repo@revoked", `[{"datasetVersionId":"`+ds.ID.String()+`"}]`, http.StatusBadRequest)
+
+	// A job that was already running registers a flagged artifact.
+	late, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{TrainingJobID: running.ID, ArtifactURI: "s3://bucket/late.pt", Checksum: "sum-late"})
+	if err != nil || len(late.Flags) != 1 || late.Flags[0].Reason != models.FlagDatasetRevoked {
+		t.Fatalf("expected an artifact registered after revocation to be flagged: %+v %v", late.Flags, err)
+	}
+
+	// Reproducing an artifact trained on a revoked version is refused.
+	res, err := svc.VerifyAndRecord(ctx, trained.ID, service.VerifyRequest{Reproduce: true})
+	if err == nil {
+		t.Fatalf("expected reproduction on a revoked dataset to fail: %+v", res.Verification)
+	}
+	if !strings.Contains(err.Error(), store.ErrDatasetRevoked.Error()) {
+		t.Fatalf("unexpected reproduction error: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package acceptance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+func TestEnvironmentRollback(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	gate := sentinel.NewStaticClient(0.5)
+	svc := service.New(memStore, gate, newTestSigner(t))
+	router := httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev"}, svc, memStore).Router()
+
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{
+		CodeRef:         "git://repo@env",
+		ContainerDigest: "sha256:env",
+	})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	var artifacts []models.ModelArtifact
+	promotions := map[uuid.UUID]models.ModelPromotion{}
+	for _, checksum := range []string{"sum-a", "sum-b", "sum-c"} {
+		artifact, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
+			TrainingJobID: job.ID,
+			ArtifactURI:   "s3://bucket/" + checksum + ".pt",
+			Checksum:      checksum,
+		})
+		if err != nil {
+			t.Fatalf("register artifact: %v", err)
+		}
+		promo, err := svc.PromoteArtifact(ctx, service.PromotionRequest{
+			ArtifactID:  artifact.ID,
+			Environment: "prod",
+			Evaluation:  json.RawMessage(`{"quality":0.9}`),
+		})
+		if err != nil || promo.Status != models.PromotionApplied {
+			t.Fatalf("promote artifact: %+v %v", promo, err)
+		}
+		artifacts = append(artifacts, artifact)
+		promotions[artifact.ID] = promo
+	}
+
+	getEnv := func() service.EnvironmentStatus {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ai-infra/environments/prod", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("get environment: %d %s", rec.Code, rec.Body.String())
+		}
+		var env service.EnvironmentStatus
+		if err := json.NewDecoder(rec.Body).Decode(&env); err != nil {
+			t.Fatalf("decode environment: %v", err)
+		}
+		return env
+	}
+	rollback := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/ai-infra/environments/prod/rollback", strings.NewReader(body))
+		req.Header.Set("X-Debug-Token", "dev")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) models.ModelPromotion {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("rollback: %d %s", rec.Code, rec.Body.String())
+		}
+		var promo models.ModelPromotion
+		if err := json.NewDecoder(rec.Body).Decode(&promo); err != nil {
+			t.Fatalf("decode promotion: %v", err)
+		}
+		return promo
+	}
+
+	env := getEnv()
+	if env.ActiveArtifact == nil || env.ActiveArtifact.ID != artifacts[2].ID || len(env.History) != 3 {
+		t.Fatalf("unexpected environment: %+v", env)
+	}
+
+	restored := decode(rollback(`{"reason":"p95 regression","requestedBy":"oncall"}`))
+	if restored.Status != models.PromotionApplied || restored.ArtifactID != artifacts[1].ID || restored.Signature == nil ||
+		restored.Rollback == nil || restored.Rollback.RolledBackPromotionID != promotions[artifacts[2].ID].ID {
+		t.Fatalf("unexpected rollback promotion: %+v", restored)
+	}
+	if env = getEnv(); env.ActivePromotion.ID != restored.ID {
+		t.Fatalf("rollback not live: %+v", env.ActivePromotion)
+	}
+	if retired, _ := memStore.GetPromotion(ctx, promotions[artifacts[2].ID].ID); retired.Status != models.PromotionRolledBack {
+		t.Fatalf("expected the live promotion rolled back, got %s", retired.Status)
+	}
+	for _, artifact := range artifacts[1:] {
+		if res, err := svc.VerifyArtifact(ctx, artifact.ID); err != nil || !res.OK {
+			t.Fatalf("verify %s: %+v %v", artifact.Checksum, res, err)
+		}
+	}
+
+	// A second rollback walks further back instead of restoring the rolled back artifact.
+	if promo := decode(rollback(``)); promo.ArtifactID != artifacts[0].ID {
+		t.Fatalf("expected artifact a restored, got %s", promo.ArtifactID)
+	}
+	if rec := rollback(`{}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 without an earlier artifact, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// A rollback SentinelNet denies is recorded and leaves the environment unchanged.
+	live := getEnv().ActivePromotion.ID
+	gate.MinScore = 0.99
+	denied := decode(rollback(`{"targetPromotionId":"` + promotions[artifacts[1].ID].ID.String() + `"}`))
+	if denied.Status != models.PromotionRejected || denied.Signature != nil {
+		t.Fatalf("expected a rejected rollback, got %+v", denied)
+	}
+	if env = getEnv(); env.ActivePromotion.ID != live {
+		t.Fatalf("denied rollback changed the live promotion")
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ai-infra/environments/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown environment, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,56 @@
+package acceptance
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// apiClient calls a test server's HTTP API with the debug token.
+type apiClient struct {
+	t       *testing.T
+	baseURL string
+}
+
+func newAPIClient(t *testing.T, baseURL string) apiClient {
+	return apiClient{t: t, baseURL: baseURL}
+}
+
+// call sends body (an io.Reader as is, anything else but nil as JSON), fails the test
+// unless the response status is want and decodes the response into v when v is non-nil.
+func (c apiClient) call(method, path string, body interface{}, want int, v interface{}) {
+	c.t.Helper()
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			c.t.Fatalf("encode %s: %v", path, err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	req.Header.Set("X-Debug-Token", "dev")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != want {
+		msg, _ := io.ReadAll(resp.Body)
+		c.t.Fatalf("%s %s: expected %d, got %d: %s", method, path, want, resp.StatusCode, msg)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			c.t.Fatalf("decode %s: %v", path, err)
+		}
+	}
+}
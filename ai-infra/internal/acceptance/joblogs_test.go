@@ -0,0 +1,232 @@
+package acceptance
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/runner"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+type sseEvent struct {
+	id, event, data string
+}
+
+// readSSE collects the events of a stream until it ends.
+func readSSE(url, lastEventID string) ([]sseEvent, error) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		return nil, fmt.Errorf("unexpected stream response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var (
+		events []sseEvent
+		cur    sseEvent
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if cur != (sseEvent{}) {
+				events = append(events, cur)
+			}
+			cur = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			cur.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			cur.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			cur.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return events, scanner.Err()
+}
+
+func TestTrainingJobLogsAndMetrics(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	server := httptest.NewServer(httpserver.New(config.Config{}, svc, memStore).Router())
+	defer server.Close()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "train.sh")
+	if err := os.WriteFile(script, []byte(`#!/bin/sh
+echo "epoch 1"
+echo 'AI_INFRA_METRIC {"step":100,"loss":0.9,"throughput":1200}'
+sleep 0.2
+echo "warning: lr decayed" >&2
+echo 'AI_INFRA_METRIC {"step":200,"loss":0.5}'
+echo 'AI_INFRA_METRIC not json'
+echo "epoch 2 done"
+printf weights > "$AI_INFRA_OUTPUT_PATH"
+`), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: script, ContainerDigest: "sha256:local"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	logsURL := server.URL + "/ai-infra/jobs/" + job.ID.String() + "/logs"
+
+	// Follow the job from before it starts until it finishes.
+	streamed := make(chan []sseEvent, 1)
+	go func() {
+		events, err := readSSE(logsURL+"?follow=true", "")
+		if err != nil {
+			t.Errorf("follow logs: %v", err)
+		}
+		streamed <- events
+	}()
+	cfg := runner.Config{
+		Executor:         runner.NewLocalExecutor(runner.ProcessConfig{WorkDir: filepath.Join(dir, "runs"), EntrypointRoots: []string{dir}}),
+		LogFlushInterval: 20 * time.Millisecond,
+	}
+	if processed, err := runner.ExecuteNextJob(ctx, svc, cfg); err != nil || !processed {
+		t.Fatalf("execute job: %v %v", processed, err)
+	}
+	var events []sseEvent
+	select {
+	case events = <-streamed:
+		if len(events) == 0 {
+			t.Fatalf("log stream sent no events")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("log stream did not end with the job")
+	}
+	var (
+		output string
+		stderr bool
+	)
+	for _, ev := range events[:len(events)-1] {
+		var chunk models.JobLogChunk
+		if ev.event != "log" || json.Unmarshal([]byte(ev.data), &chunk) != nil || ev.id == "" {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+		output += chunk.Content
+		stderr = stderr || (chunk.Stream == models.LogStderr && strings.Contains(chunk.Content, "lr decayed"))
+	}
+	if end := events[len(events)-1]; end.event != "end" || !strings.Contains(end.data, `"status":"succeeded"`) {
+		t.Fatalf("expected an end event with the finished job, got %+v", end)
+	}
+	if !strings.Contains(output, "epoch 1") || !strings.Contains(output, "epoch 2 done") || !stderr {
+		t.Fatalf("stream is missing output: %q", output)
+	}
+
+	getJSON := func(url string, v interface{}) {
+		t.Helper()
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("get %s: %v", url, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("get %s: %d", url, resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decode %s: %v", url, err)
+		}
+	}
+	var all, first, rest []models.JobLogChunk
+	getJSON(logsURL, &all)
+	getJSON(logsURL+"?limit=1", &first)
+	getJSON(logsURL+"?after=1", &rest)
+	if len(all) < 2 || len(first) != 1 || first[0].Seq != 1 || len(rest) != len(all)-1 || rest[0].Seq != 2 || all[0].Attempt != 1 {
+		t.Fatalf("unexpected pages: all=%d first=%+v rest=%d", len(all), first, len(rest))
+	}
+	resumed, err := readSSE(logsURL+"?follow=true", "1")
+	if err != nil {
+		t.Fatalf("resume logs: %v", err)
+	}
+	if len(resumed) != len(all) || resumed[0].id != "2" || resumed[len(resumed)-1].event != "end" {
+		t.Fatalf("expected the resumed stream to replay from seq 2, got %+v", resumed)
+	}
+
+	var points []models.JobMetricPoint
+	getJSON(server.URL+"/ai-infra/jobs/"+job.ID.String()+"/metrics", &points)
+	if len(points) != 2 || points[0].Step != 100 || points[0].Metrics["throughput"] != 1200 || points[1].Metrics["loss"] != 0.5 {
+		t.Fatalf("unexpected metrics: %+v", points)
+	}
+	arts, err := memStore.ListArtifacts(ctx, store.ListArtifactsFilter{TrainingJobID: &job.ID})
+	if err != nil || len(arts) != 1 {
+		t.Fatalf("list artifacts: %+v %v", arts, err)
+	}
+	var meta struct {
+		Metrics     map[string]float64 `json:"metrics"`
+		MetricsStep int64              `json:"metricsStep"`
+	}
+	if err := json.Unmarshal(arts[0].Metadata, &meta); err != nil || meta.Metrics["loss"] != 0.5 || meta.Metrics["throughput"] != 1200 || meta.MetricsStep != 200 {
+		t.Fatalf("expected the final metrics on the artifact, got %s", arts[0].Metadata)
+	}
+
+	resp, err := http.Get(server.URL + "/ai-infra/jobs/00000000-0000-0000-0000-000000000000/logs?follow=true")
+	if err != nil {
+		t.Fatalf("get unknown job logs: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown job, got %d", resp.StatusCode)
+	}
+}
+
+func TestTrainingJobLogsStayValidUTF8(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	dir := t.TempDir()
+	script := filepath.Join(dir, "train.sh")
+	// 16384 two-byte runes after a one-byte prefix put every chunk boundary mid-rune,
+	// and the trailing 0xff is not UTF-8 at all.
+	if err := os.WriteFile(script, []byte(`#!/bin/sh
+s=é
+for i in 1 2 3 4 5 6 7 8 9 10 11 12 13 14; do s=$s$s; done
+printf 'x%s\377\n' "$s"
+printf weights > "$AI_INFRA_OUTPUT_PATH"
+`), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: script, ContainerDigest: "sha256:local"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	cfg := runner.Config{Executor: runner.NewLocalExecutor(runner.ProcessConfig{WorkDir: filepath.Join(dir, "runs"), EntrypointRoots: []string{dir}})}
+	if processed, err := runner.ExecuteNextJob(ctx, svc, cfg); err != nil || !processed {
+		t.Fatalf("execute job: %v %v", processed, err)
+	}
+	chunks, err := memStore.ListJobLogs(ctx, job.ID, 0, 0)
+	if err != nil || len(chunks) < 2 {
+		t.Fatalf("expected the output split into chunks: %d %v", len(chunks), err)
+	}
+	var output string
+	for _, c := range chunks {
+		if !utf8.ValidString(c.Content) {
+			t.Fatalf("chunk %d is not valid UTF-8", c.Seq)
+		}
+		output += c.Content
+	}
+	if want := "x" + strings.Repeat("é", 1<<14) + "\uFFFD\n"; output != want {
+		t.Fatalf("output changed: got %d bytes, want %d", len(output), len(want))
+	}
+}
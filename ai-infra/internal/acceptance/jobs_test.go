@@ -0,0 +1,248 @@
+package acceptance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/runner"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+func TestTrainingJobLeaseExpiryRetriesThenFails(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	svc.SetJobPolicy(service.JobPolicy{MaxAttempts: 2, LeaseDuration: 20 * time.Millisecond, BackoffBase: time.Millisecond})
+
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: "git://repo@lease", ContainerDigest: "sha256:lease"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if job.Status != models.JobQueued || job.MaxAttempts != 2 {
+		t.Fatalf("unexpected new job: %+v", job)
+	}
+
+	claimed, err := svc.ClaimTrainingJob(ctx, "worker-a")
+	if err != nil || claimed.ID != job.ID || claimed.Status != models.JobRunning || claimed.Attempts != 1 {
+		t.Fatalf("claim: %+v %v", claimed, err)
+	}
+	if _, err := svc.ClaimTrainingJob(ctx, "worker-b"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected no second claim, got %v", err)
+	}
+	if _, err := svc.HeartbeatTrainingJob(ctx, job.ID, "worker-b"); !errors.Is(err, service.ErrLeaseLost) {
+		t.Fatalf("expected a heartbeat from another worker to be refused, got %v", err)
+	}
+	if reaped, err := svc.ReapTrainingJobs(ctx, time.Now()); err != nil || len(reaped) != 0 {
+		t.Fatalf("expected a live lease to be left alone: %+v %v", reaped, err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	reaped, err := svc.ReapTrainingJobs(ctx, time.Now())
+	if err != nil || len(reaped) != 1 {
+		t.Fatalf("reap: %+v %v", reaped, err)
+	}
+	if requeued := reaped[0]; requeued.Status != models.JobQueued || requeued.WorkerID != "" || requeued.NextAttemptAt == nil ||
+		!strings.Contains(requeued.LastError, "worker-a") {
+		t.Fatalf("expected the job requeued with backoff, got %+v", requeued)
+	}
+	if _, err := svc.SucceedTrainingJob(ctx, job.ID, "worker-a"); !errors.Is(err, service.ErrLeaseLost) {
+		t.Fatalf("expected the expired worker to lose the job, got %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if claimed, err := svc.ClaimTrainingJob(ctx, "worker-b"); err != nil || claimed.Attempts != 2 {
+		t.Fatalf("reclaim: %+v %v", claimed, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	reaped, err = svc.ReapTrainingJobs(ctx, time.Now())
+	if err != nil || len(reaped) != 1 {
+		t.Fatalf("reap: %+v %v", reaped, err)
+	}
+	if failed := reaped[0]; failed.Status != models.JobFailed || failed.FinishedAt == nil {
+		t.Fatalf("expected the job failed after its last attempt, got %+v", failed)
+	}
+}
+
+// heartbeatOnList heartbeats a job right after it is listed, like a worker that renews its
+// lease while the reaper is deciding what to do with it.
+type heartbeatOnList struct {
+	store.Store
+	jobID    uuid.UUID
+	workerID string
+}
+
+func (h *heartbeatOnList) ListTrainingJobs(ctx context.Context, filter store.ListTrainingJobsFilter) ([]models.TrainingJob, error) {
+	jobs, err := h.Store.ListTrainingJobs(ctx, filter)
+	if err == nil {
+		_, err = h.Store.HeartbeatTrainingJob(ctx, h.jobID, h.workerID, time.Minute)
+	}
+	return jobs, err
+}
+
+func TestTrainingJobReaperKeepsJobHeartbeatedWhileReaping(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	racing := &heartbeatOnList{Store: memStore, workerID: "worker-a"}
+	svc := service.New(racing, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	svc.SetJobPolicy(service.JobPolicy{MaxAttempts: 2, LeaseDuration: 10 * time.Millisecond, BackoffBase: time.Millisecond})
+
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: "git://repo@race", ContainerDigest: "sha256:race"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if _, err := svc.ClaimTrainingJob(ctx, "worker-a"); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	racing.jobID = job.ID
+	time.Sleep(20 * time.Millisecond)
+
+	reaped, err := svc.ReapTrainingJobs(ctx, time.Now())
+	if err != nil || len(reaped) != 0 {
+		t.Fatalf("expected the renewed lease to be left alone: %+v %v", reaped, err)
+	}
+	if got, err := svc.GetTrainingJob(ctx, job.ID); err != nil || got.Status != models.JobRunning || got.WorkerID != "worker-a" {
+		t.Fatalf("expected worker-a to keep the job: %+v %v", got, err)
+	}
+}
+
+func TestTrainingJobCancelTimeoutAndList(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	router := httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev"}, svc, memStore).Router()
+	dir := t.TempDir()
+	script := filepath.Join(dir, "slow.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexec sleep 30\n"), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	cfg := runner.Config{
+		Executor:          runner.NewLocalExecutor(runner.ProcessConfig{WorkDir: filepath.Join(dir, "runs"), EntrypointRoots: []string{dir}}),
+		WorkerID:          "worker-test",
+		HeartbeatInterval: 20 * time.Millisecond,
+	}
+	cancelJob := func(id string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ai-infra/jobs/"+id+"/cancel", strings.NewReader(body))
+		req.Header.Set("X-Debug-Token", "dev")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	queued, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: script, ContainerDigest: "sha256:local"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	rec := cancelJob(queued.ID.String(), `{"reason":"wrong dataset","requestedBy":"alice"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cancel queued job: %d %s", rec.Code, rec.Body.String())
+	}
+	var cancelled models.TrainingJob
+	if err := json.NewDecoder(rec.Body).Decode(&cancelled); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	if cancelled.Status != models.JobCancelled || cancelled.LastError != "cancelled by alice: wrong dataset" {
+		t.Fatalf("unexpected cancelled job: %+v", cancelled)
+	}
+	if rec := cancelJob(queued.ID.String(), ""); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 cancelling a finished job, got %d", rec.Code)
+	}
+	if rec := cancelJob("00000000-0000-0000-0000-000000000000", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown job, got %d", rec.Code)
+	}
+
+	// A running job is cancelled at its worker's next heartbeat and the run is stopped.
+	running, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: script, ContainerDigest: "sha256:local"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := runner.ExecuteNextJob(ctx, svc, cfg)
+		done <- err
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ := svc.GetTrainingJob(ctx, running.ID)
+		if job.Status == models.JobRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job never started: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := svc.CancelTrainingJob(ctx, running.ID, service.CancelJobRequest{Reason: "preempted"}); err != nil {
+		t.Fatalf("cancel running job: %v", err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, service.ErrLeaseLost) {
+			t.Fatalf("expected the run abandoned, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("cancelled run did not stop")
+	}
+	if job, _ := svc.GetTrainingJob(ctx, running.ID); job.Status != models.JobCancelled {
+		t.Fatalf("expected cancelled status, got %s", job.Status)
+	}
+	if arts, _ := memStore.ListArtifacts(ctx, store.ListArtifactsFilter{TrainingJobID: &running.ID}); len(arts) != 0 {
+		t.Fatalf("expected no artifact from a cancelled run, got %+v", arts)
+	}
+
+	slow, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: script, ContainerDigest: "sha256:local", MaxRuntimeSeconds: 1})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if _, err := runner.ExecuteNextJob(ctx, svc, cfg); err == nil || !strings.Contains(err.Error(), "max runtime") {
+		t.Fatalf("expected the run to time out, got %v", err)
+	}
+	if job, _ := svc.GetTrainingJob(ctx, slow.ID); job.Status != models.JobTimedOut || job.Attempts != 1 {
+		t.Fatalf("expected timed_out without a retry, got %+v", job)
+	}
+
+	listJobs := func(query string) []models.TrainingJob {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ai-infra/jobs"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list jobs: %d %s", rec.Code, rec.Body.String())
+		}
+		var jobs []models.TrainingJob
+		if err := json.NewDecoder(rec.Body).Decode(&jobs); err != nil {
+			t.Fatalf("decode jobs: %v", err)
+		}
+		return jobs
+	}
+	if jobs := listJobs(""); len(jobs) != 3 || jobs[0].ID != slow.ID {
+		t.Fatalf("expected all jobs newest first, got %+v", jobs)
+	}
+	if jobs := listJobs("?status=cancelled"); len(jobs) != 2 {
+		t.Fatalf("expected both cancelled jobs, got %+v", jobs)
+	}
+	if jobs := listJobs("?status=timed_out&limit=1"); len(jobs) != 1 || jobs[0].ID != slow.ID {
+		t.Fatalf("expected the timed out job, got %+v", jobs)
+	}
+	if jobs := listJobs("?status=queued"); len(jobs) != 0 {
+		t.Fatalf("expected no queued jobs, got %+v", jobs)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ai-infra/jobs?status=completed", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown status, got %d", rec.Code)
+	}
+}
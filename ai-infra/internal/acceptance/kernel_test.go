@@ -0,0 +1,149 @@
+package acceptance
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/canonical"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+// fakeKernel implements /kernel/sign, /kernel/audit and /kernel/security/status with the
+// Kernel's semantics: signatures are over sha256(JCS(manifest)).
+type fakeKernel struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+
+	mu           sync.Mutex
+	events       []string
+	traceparents []string
+}
+
+func newFakeKernel(t *testing.T) (*fakeKernel, *httptest.Server) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	k := &fakeKernel{pub: pub, priv: priv}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kernel/sign", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Manifest json.RawMessage `json:"manifest"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		canon, err := canonical.Transform(req.Manifest)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sum := sha256.Sum256(canon)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"manifestId": "m-1",
+			"signerId":   "kernel-signer-1",
+			"signature":  base64.StdEncoding.EncodeToString(ed25519.Sign(k.priv, sum[:])),
+			"ts":         time.Now().UTC(),
+		})
+	})
+	mux.HandleFunc("/kernel/audit", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			EventType string `json:"eventType"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		k.mu.Lock()
+		k.events = append(k.events, req.EventType)
+		k.traceparents = append(k.traceparents, r.Header.Get(tracing.TraceparentHeader))
+		k.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": "ev", "eventType": req.EventType})
+	})
+	mux.HandleFunc("/kernel/security/status", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"signers": []map[string]string{{
+			"signerId": "kernel-signer-1", "algorithm": "Ed25519", "publicKey": base64.StdEncoding.EncodeToString(k.pub),
+		}}})
+	})
+	return k, httptest.NewServer(mux)
+}
+
+func TestKernelSigningAndAudit(t *testing.T) {
+	ctx, span := tracing.Start(context.Background(), "test.promotion")
+	defer span.End()
+	traceID := span.SpanContext().TraceIDString()
+	kernel, srv := newFakeKernel(t)
+	defer srv.Close()
+
+	client, err := kernelclient.New(kernelclient.Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("kernel client: %v", err)
+	}
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), signing.NewKernelSigner(client, "ai-infra-dev"))
+	ring, err := signing.KeyRingFromKernel(ctx, client)
+	if err != nil {
+		t.Fatalf("kernel keys: %v", err)
+	}
+	svc.SetVerifier(ring)
+	svc.SetAuditor(client)
+
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: "git://repo@k", ContainerDigest: "sha256:k"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	artifact, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
+		TrainingJobID: job.ID,
+		ArtifactURI:   "s3://bucket/k.pt",
+		Checksum:      "k-sum",
+		Metadata:      json.RawMessage(`{"lr": 1.0E-3}`),
+	})
+	if err != nil {
+		t.Fatalf("register artifact: %v", err)
+	}
+	if artifact.SignerID != "kernel-signer-1" {
+		t.Fatalf("expected kernel signer id, got %q", artifact.SignerID)
+	}
+	if _, err := svc.PromoteArtifact(ctx, service.PromotionRequest{
+		ArtifactID:  artifact.ID,
+		Environment: "staging",
+		Evaluation:  json.RawMessage(`{"quality":0.9}`),
+	}); err != nil {
+		t.Fatalf("promote artifact: %v", err)
+	}
+
+	res, err := svc.VerifyArtifact(ctx, artifact.ID)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !res.OK {
+		t.Fatalf("kernel signatures should verify against the envelope hash: %+v", res)
+	}
+
+	kernel.mu.Lock()
+	defer kernel.mu.Unlock()
+	want := []string{"registry.artifact.registered", "registry.promotion.applied"}
+	if len(kernel.events) != len(want) || kernel.events[0] != want[0] || kernel.events[1] != want[1] {
+		t.Fatalf("expected kernel audit events %v, got %v", want, kernel.events)
+	}
+	for _, tp := range kernel.traceparents {
+		sc, err := tracing.ParseTraceparent(tp)
+		if err != nil || sc.TraceIDString() != traceID {
+			t.Fatalf("kernel audit call should carry trace %s, got traceparent %q", traceID, tp)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package acceptance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/reasoning"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+func TestArtifactLineage(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	router := httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev"}, svc, memStore).Router()
+
+	baseJob, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{
+		CodeRef:         "git://repo@base",
+		ContainerDigest: "sha256:base",
+		DatasetRefs:     json.RawMessage(`["s3://datasets/pretrain",{"id":"web-text","version":"v3","checksum":"sha256:abc"}]`),
+	})
+	if err != nil {
+		t.Fatalf("create base job: %v", err)
+	}
+	tuneJob, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{
+		CodeRef:         "git://repo@tune",
+		ContainerDigest: "sha256:tune",
+		DatasetRefs:     json.RawMessage(`[{"id":"support-chats","version":"2026-09"}]`),
+	})
+	if err != nil {
+		t.Fatalf("create tune job: %v", err)
+	}
+	if _, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{
+		CodeRef: "git://repo@bad", ContainerDigest: "sha256:bad", DatasetRefs: json.RawMessage(`[{"version":"v1"}]`),
+	}); err == nil {
+		t.Fatalf("expected a dataset without an id to be rejected")
+	}
+
+	base, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
+		TrainingJobID: baseJob.ID, ArtifactURI: "s3://bucket/base.pt", Checksum: "sum-base",
+	})
+	if err != nil {
+		t.Fatalf("register base: %v", err)
+	}
+	if _, err := svc.PromoteArtifact(ctx, service.PromotionRequest{
+		ArtifactID: base.ID, Environment: "prod", Evaluation: json.RawMessage(`{"quality":0.9}`),
+	}); err != nil {
+		t.Fatalf("promote base: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/ai-infra/register", strings.NewReader(fmt.Sprintf(
+		`{"trainingJobId":%q,"artifactUri":"s3://bucket/tuned.pt","checksum":"sum-tuned","parents":[{"artifactId":%q,"relation":"fine_tune"}]}`,
+		tuneJob.ID, base.ID)))
+	req.Header.Set("X-Debug-Token", "dev")
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register tuned: %d %s", rec.Code, rec.Body.String())
+	}
+	var tuned models.ModelArtifact
+	if err := json.NewDecoder(rec.Body).Decode(&tuned); err != nil {
+		t.Fatalf("decode tuned: %v", err)
+	}
+	distilled, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
+		TrainingJobID: tuneJob.ID, ArtifactURI: "s3://bucket/distilled.pt", Checksum: "sum-distilled",
+		Parents: []models.ArtifactParent{{ArtifactID: tuned.ID, Relation: models.RelationDistillation}},
+	})
+	if err != nil {
+		t.Fatalf("register distilled: %v", err)
+	}
+	if _, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
+		TrainingJobID: tuneJob.ID, ArtifactURI: "s3://bucket/x.pt", Checksum: "sum-x",
+		Parents: []models.ArtifactParent{{ArtifactID: base.ID, Relation: "copy"}},
+	}); err == nil {
+		t.Fatalf("expected an unknown relation to be rejected")
+	}
+	if res, err := svc.VerifyArtifact(ctx, tuned.ID); err != nil || !res.OK {
+		t.Fatalf("verify tuned: %+v %v", res, err)
+	}
+
+	getLineage := func(query string) service.Lineage {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ai-infra/lineage/"+tuned.ID.String()+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("get lineage: %d %s", rec.Code, rec.Body.String())
+		}
+		var lineage service.Lineage
+		if err := json.NewDecoder(rec.Body).Decode(&lineage); err != nil {
+			t.Fatalf("decode lineage: %v", err)
+		}
+		return lineage
+	}
+	lineage := getLineage("")
+	nodes := map[string]service.LineageNode{}
+	for _, node := range lineage.Nodes {
+		nodes[node.ID] = node
+	}
+	edges := map[string]bool{}
+	for _, edge := range lineage.Edges {
+		edges[edge.From+" "+edge.Relation+" "+edge.To] = true
+	}
+	baseNode, tunedNode := "artifact:"+base.ID.String(), "artifact:"+tuned.ID.String()
+	distilledNode := "artifact:" + distilled.ID.String()
+	if !lineage.OK || lineage.Truncated || len(nodes) != 9 {
+		t.Fatalf("unexpected lineage: %+v", lineage)
+	}
+	if nodes[baseNode].Depth != -1 || nodes[distilledNode].Depth != 1 || nodes[tunedNode].Signature == nil || !nodes[tunedNode].Signature.SignatureOK {
+		t.Fatalf("unexpected artifact nodes: %+v", nodes)
+	}
+	for _, want := range []string{
+		baseNode + " fine_tune " + tunedNode,
+		tunedNode + " distillation " + distilledNode,
+		"training_job:" + tuneJob.ID.String() + " produced " + tunedNode,
+		"dataset:web-text@v3 trained_on training_job:" + baseJob.ID.String(),
+		"dataset:s3://datasets/pretrain trained_on training_job:" + baseJob.ID.String(),
+		baseNode + " promoted_to environment:prod",
+	} {
+		if !edges[want] {
+			t.Fatalf("missing edge %q in %+v", want, lineage.Edges)
+		}
+	}
+	if ancestors := getLineage("?direction=ancestors&depth=1"); len(ancestors.Nodes) != 8 {
+		t.Fatalf("expected tuned, base, both jobs, their datasets and prod, got %+v", ancestors.Nodes)
+	}
+
+	publish := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ai-infra/lineage/"+tuned.ID.String()+"/publish", nil)
+		req.Header.Set("X-Debug-Token", "dev")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := publish(); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a reasoning graph, got %d", rec.Code)
+	}
+
+	var (
+		mu       sync.Mutex
+		rgNodes  int
+		rgEdges  []map[string]interface{}
+		rgAuthor string
+	)
+	rg := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+		switch r.URL.Path {
+		case "/reason/node":
+			rgNodes++
+			rgAuthor, _ = body["author"].(string)
+			fmt.Fprintf(w, `{"nodeId":"00000000-0000-0000-0000-%012d"}`, rgNodes)
+		case "/reason/edge":
+			rgEdges = append(rgEdges, body)
+			fmt.Fprint(w, `{"edgeId":"e"}`)
+		default:
+			t.Errorf("unexpected reasoning graph path %s", r.URL.Path)
+		}
+	}))
+	defer rg.Close()
+	client, err := reasoning.NewHTTPClient(reasoning.HTTPClientConfig{BaseURL: rg.URL})
+	if err != nil {
+		t.Fatalf("reasoning client: %v", err)
+	}
+	svc.SetReasoningGraph(client)
+
+	rec = publish()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("publish: %d %s", rec.Code, rec.Body.String())
+	}
+	var published service.PublishedLineage
+	if err := json.NewDecoder(rec.Body).Decode(&published); err != nil {
+		t.Fatalf("decode published: %v", err)
+	}
+	if len(published.Nodes) != len(lineage.Nodes) || published.Edges != len(lineage.Edges) || rgNodes != len(lineage.Nodes) || rgAuthor != "service:ai-infra" {
+		t.Fatalf("unexpected publish: %+v (reasoning graph saw %d nodes)", published, rgNodes)
+	}
+	for _, edge := range rgEdges {
+		if edge["from"] == "" || edge["to"] == "" || edge["type"] != "causes" {
+			t.Fatalf("unexpected reasoning graph edge: %+v", edge)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package acceptance
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/canonical"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+)
+
+// manifestKernel signs manifests the way Kernel POST /kernel/sign does, over
+// sha256(JCS(manifest)) of the JSON it receives, and publishes the keys of its current
+// and earlier signers.
+type manifestKernel struct {
+	mu       sync.Mutex
+	signerID string
+	priv     ed25519.PrivateKey
+	keys     []kernelclient.SignerKey
+	fail     bool
+}
+
+func newManifestKernel(t *testing.T, signerID string) *manifestKernel {
+	t.Helper()
+	k := &manifestKernel{}
+	k.rotate(t, signerID)
+	return k
+}
+
+func (k *manifestKernel) rotate(t *testing.T, signerID string) {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	k.mu.Lock()
+	k.signerID, k.priv = signerID, priv
+	k.keys = append(k.keys, kernelclient.SignerKey{
+		SignerID:  signerID,
+		Algorithm: signing.AlgorithmEd25519,
+		PublicKey: base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey)),
+	})
+	k.mu.Unlock()
+}
+
+func (k *manifestKernel) Sign(ctx context.Context, manifest interface{}, version string) (kernelclient.ManifestSignature, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.fail {
+		return kernelclient.ManifestSignature{}, errors.New("kernel unavailable")
+	}
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return kernelclient.ManifestSignature{}, err
+	}
+	canon, err := canonical.Transform(raw)
+	if err != nil {
+		return kernelclient.ManifestSignature{}, err
+	}
+	var doc struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(raw, &doc)
+	sum := sha256.Sum256(canon)
+	return kernelclient.ManifestSignature{
+		ID:         uuid.NewString(),
+		ManifestID: doc.ID,
+		SignerID:   k.signerID,
+		Signature:  base64.StdEncoding.EncodeToString(ed25519.Sign(k.priv, sum[:])),
+		Version:    version,
+		Ts:         time.Now().UTC(),
+	}, nil
+}
+
+func (k *manifestKernel) Signers(ctx context.Context) ([]kernelclient.SignerKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return append([]kernelclient.SignerKey(nil), k.keys...), nil
+}
+
+func TestKernelManifestSignatures(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	signer := newTestSigner(t)
+	kernel := newManifestKernel(t, "kernel-signer-1")
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), signer)
+	svc.SetKernelManifests(kernel, signing.NewKernelKeys(kernel, 0))
+	// plain shares the store but signs no manifests, like a registry without a Kernel.
+	plain := service.New(memStore, sentinel.NewStaticClient(0.5), signer)
+	server := httptest.NewServer(httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev"}, svc, memStore).Router())
+	defer server.Close()
+
+	api := newAPIClient(t, server.URL)
+	promote := func(artifactID uuid.UUID, want int) models.ModelPromotion {
+		t.Helper()
+		var promo models.ModelPromotion
+		body := map[string]interface{}{"artifactId": artifactID.String(), "environment": "prod", "evaluation": map[string]float64{"quality": 0.9}}
+		if want != http.StatusOK {
+			api.call(http.MethodPost, "/ai-infra/promote", body, want, nil)
+			return promo
+		}
+		api.call(http.MethodPost, "/ai-infra/promote", body, want, &promo)
+		return promo
+	}
+
+	// Seeds above 2^53 survive the manifest because they are signed as strings.
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{
+		CodeRef: "git://repo@signed", ContainerDigest: "sha256:kernel", Seed: 1<<62 + 1,
+		Hyperparams: json.RawMessage(`{"lr": 0.001, "epochs": 3}`), DatasetRefs: json.RawMessage(`["s3://datasets/a"]`),
+	})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	register := func(uri string) models.ModelArtifact {
+		t.Helper()
+		var artifact models.ModelArtifact
+		api.call(http.MethodPost, "/ai-infra/register", map[string]interface{}{
+			"trainingJobId": job.ID.String(), "artifactUri": uri, "checksum": "sum-" + uri, "metadata": map[string]string{"framework": "torch"},
+		}, http.StatusCreated, &artifact)
+		return artifact
+	}
+	artifact := register("s3://bucket/signed.pt")
+	if artifact.ManifestSignatureID == nil {
+		t.Fatalf("expected a Kernel manifest signature on registration: %+v", artifact)
+	}
+	ms, err := memStore.GetManifestSignature(ctx, *artifact.ManifestSignatureID)
+	if err != nil || ms.SubjectType != signing.ManifestTypeArtifact || ms.SubjectID != artifact.ID ||
+		ms.ManifestID != "ai-infra:artifact:"+artifact.ID.String() || ms.SignerID != "kernel-signer-1" || ms.Version != signing.ManifestVersion {
+		t.Fatalf("unexpected stored manifest signature: %+v %v", ms, err)
+	}
+	api.call(http.MethodPost, "/ai-infra/register", map[string]interface{}{
+		"trainingJobId": job.ID.String(), "artifactUri": "s3://bucket/x.pt", "checksum": "x", "manifestSignatureId": "elsewhere",
+	}, http.StatusBadRequest, nil)
+
+	// Promotion verifies the artifact's signature and signs its own manifest.
+	promo := promote(artifact.ID, http.StatusOK)
+	if promo.Status != models.PromotionApplied || promo.ManifestSignatureID == nil {
+		t.Fatalf("expected an applied promotion with a manifest signature: %+v", promo)
+	}
+	if pm, err := memStore.GetManifestSignature(ctx, *promo.ManifestSignatureID); err != nil || pm.SubjectType != signing.ManifestTypePromotion || pm.SubjectID != promo.ID {
+		t.Fatalf("unexpected promotion manifest signature: %+v %v", pm, err)
+	}
+	res, err := svc.VerifyAndRecord(ctx, artifact.ID, service.VerifyRequest{ManifestSignature: true})
+	if err != nil || !res.OK {
+		t.Fatalf("expected the artifact and its promotion to verify: %+v %v", res, err)
+	}
+	for _, c := range res.Verification.Checks {
+		if c.Name == models.CheckManifestSignature && (c.Status != models.CheckPassed || c.Actual != ms.ManifestHash) {
+			t.Fatalf("unexpected manifest check: %+v", c)
+		}
+	}
+
+	// Keys rotated by the Kernel are fetched when a signature names an unknown signer.
+	kernel.rotate(t, "kernel-signer-2")
+	rotated := register("s3://bucket/rotated.pt")
+	promote(rotated.ID, http.StatusOK)
+
+	// Artifacts without a signature, or with one that does not verify, are refused.
+	unsigned, err := plain.RegisterArtifact(ctx, service.RegisterArtifactRequest{TrainingJobID: job.ID, ArtifactURI: "s3://bucket/unsigned.pt", Checksum: "u"})
+	if err != nil {
+		t.Fatalf("register unsigned artifact: %v", err)
+	}
+	promote(unsigned.ID, http.StatusConflict)
+	if promotions, _ := memStore.ListPromotionsByArtifact(ctx, unsigned.ID); len(promotions) != 0 {
+		t.Fatalf("a refused promotion was recorded: %+v", promotions)
+	}
+	foreignID := "issued-elsewhere"
+	foreign, err := plain.RegisterArtifact(ctx, service.RegisterArtifactRequest{TrainingJobID: job.ID, ArtifactURI: "s3://bucket/foreign.pt", Checksum: "f", ManifestSignatureID: &foreignID})
+	if err != nil {
+		t.Fatalf("register artifact: %v", err)
+	}
+	promote(foreign.ID, http.StatusConflict)
+	// Without a Kernel, requiring manifest signatures refuses even signed artifacts.
+	strict := service.New(memStore, sentinel.NewStaticClient(0.5), signer)
+	strict.RequireManifestSignatures(true)
+	if _, err := strict.PromoteArtifact(ctx, service.PromotionRequest{ArtifactID: artifact.ID, Environment: "prod"}); !errors.Is(err, service.ErrManifestSignature) {
+		t.Fatalf("expected promotion without a Kernel verifier to be refused, got %v", err)
+	}
+
+	// Rollbacks restore only artifacts whose signature verifies; the restored promotion is
+	// signed like any other.
+	rolledBack, err := svc.RollbackEnvironment(ctx, "prod", service.RollbackRequest{Reason: "regression"})
+	if err != nil || rolledBack.ArtifactID != artifact.ID || rolledBack.ManifestSignatureID == nil {
+		t.Fatalf("expected a signed rollback to the first artifact: %+v %v", rolledBack, err)
+	}
+	quality := json.RawMessage(`{"quality":0.9}`)
+	if _, err := plain.PromoteArtifact(ctx, service.PromotionRequest{ArtifactID: unsigned.ID, Environment: "staging", Evaluation: quality}); err != nil {
+		t.Fatalf("promote unsigned artifact without a Kernel: %v", err)
+	}
+	if _, err := svc.PromoteArtifact(ctx, service.PromotionRequest{ArtifactID: artifact.ID, Environment: "staging", Evaluation: quality}); err != nil {
+		t.Fatalf("promote artifact: %v", err)
+	}
+	if _, err := svc.RollbackEnvironment(ctx, "staging", service.RollbackRequest{Reason: "regression"}); !errors.Is(err, service.ErrManifestSignature) {
+		t.Fatalf("expected a rollback to an unsigned artifact to be refused, got %v", err)
+	}
+	// A canary whose artifact no longer verifies when its window ends is rolled back.
+	canary, err := plain.PromoteArtifact(ctx, service.PromotionRequest{ArtifactID: unsigned.ID, Environment: "canary", Evaluation: quality,
+		Canary: &models.CanaryPolicy{WindowMinutes: 1, MinSamples: 1, MetricSignals: []string{"accuracy"}}})
+	if err != nil {
+		t.Fatalf("start canary: %v", err)
+	}
+	if _, err := svc.RecordCanaryObservations(ctx, canary.ID, []service.CanaryObservationInput{{Arm: "canary", Metrics: map[string]float64{"accuracy": 0.9}}}); err != nil {
+		t.Fatalf("record observations: %v", err)
+	}
+	decided, err := svc.EvaluateCanaries(ctx, time.Now().Add(time.Hour))
+	if err != nil || len(decided) != 1 || decided[0].Status != models.PromotionRolledBack ||
+		!strings.Contains(decided[0].Canary.Reason, service.ErrManifestSignature.Error()) {
+		t.Fatalf("expected the unsigned canary to be rolled back: %+v %v", decided, err)
+	}
+
+	// A signature the Kernel issued for one artifact does not cover another.
+	id := uuid.New()
+	copied := ms
+	copied.ID, copied.SubjectID = "copied", id
+	if _, err := memStore.CreateManifestSignature(ctx, copied); err != nil {
+		t.Fatalf("store copied signature: %v", err)
+	}
+	if _, err := memStore.CreateArtifact(ctx, store.ArtifactInput{
+		ID: id, TrainingJobID: job.ID, ArtifactURI: "s3://bucket/copied.pt", Checksum: "c", Metadata: json.RawMessage(`{}`),
+		SignerID: signer.SignerID(), Signature: "x", ManifestSignatureID: &copied.ID,
+	}); err != nil {
+		t.Fatalf("create artifact: %v", err)
+	}
+	if _, err := svc.PromoteArtifact(ctx, service.PromotionRequest{ArtifactID: id, Environment: "prod"}); !errors.Is(err, service.ErrManifestSignature) ||
+		!strings.Contains(err.Error(), "no longer matches") {
+		t.Fatalf("expected a copied signature to be refused, got %v", err)
+	}
+	// Signatures by an impostor claiming a Kernel signer id, or by an unknown signer, fail.
+	for _, signerID := range []string{"kernel-signer-2", "impostor"} {
+		impostor := service.New(memStore, sentinel.NewStaticClient(0.5), signer)
+		impostorKernel := newManifestKernel(t, signerID)
+		impostor.SetKernelManifests(impostorKernel, signing.NewKernelKeys(impostorKernel, 0))
+		forged, err := impostor.RegisterArtifact(ctx, service.RegisterArtifactRequest{TrainingJobID: job.ID, ArtifactURI: "s3://bucket/" + signerID + ".pt", Checksum: signerID})
+		if err != nil {
+			t.Fatalf("register forged artifact: %v", err)
+		}
+		_, err = svc.PromoteArtifact(ctx, service.PromotionRequest{ArtifactID: forged.ID, Environment: "prod"})
+		if !errors.Is(err, service.ErrManifestSignature) {
+			t.Fatalf("%s: expected ErrManifestSignature, got %v", signerID, err)
+		}
+	}
+
+	// Kernel failures surface as a bad gateway.
+	kernel.mu.Lock()
+	kernel.fail = true
+	kernel.mu.Unlock()
+	var failure map[string]string
+	api.call(http.MethodPost, "/ai-infra/register", map[string]interface{}{
+		"trainingJobId": job.ID.String(), "artifactUri": "s3://bucket/down.pt", "checksum": "down",
+	}, http.StatusBadGateway, &failure)
+	if !strings.Contains(failure["error"], "kernel unavailable") {
+		t.Fatalf("unexpected error: %+v", failure)
+	}
+}
@@ -4,11 +4,19 @@ import (
 	"context"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/runner"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
@@ -38,7 +46,7 @@ func TestTrainingRunnerProducesDeterministicArtifact(t *testing.T) {
 		t.Fatalf("compute checksum: %v", err)
 	}
 
-	processed, err := runner.ProcessNextJob(ctx, svc, memStore)
+	processed, err := runner.ProcessNextJob(ctx, svc)
 	if err != nil {
 		t.Fatalf("process job: %v", err)
 	}
@@ -64,8 +72,70 @@ func TestTrainingRunnerProducesDeterministicArtifact(t *testing.T) {
 	if err != nil {
 		t.Fatalf("get job: %v", err)
 	}
-	if finalJob.Status != "completed" {
-		t.Fatalf("expected completed status, got %s", finalJob.Status)
+	if finalJob.Status != models.JobSucceeded {
+		t.Fatalf("expected succeeded status, got %s", finalJob.Status)
+	}
+}
+
+func TestLocalExecutorRunsTrainingScript(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.1), newTestSigner(t))
+	dir := t.TempDir()
+	executor := runner.NewLocalExecutor(runner.ProcessConfig{WorkDir: filepath.Join(dir, "runs"), EntrypointRoots: []string{dir}, Timeout: 10 * time.Second})
+
+	script := filepath.Join(dir, "train.sh")
+	if err := os.WriteFile(script, []byte(`#!/bin/sh
+echo "training $AI_INFRA_JOB_ID lr=$AI_INFRA_HP_LR"
+printf 'weights seed=%s lr=%s data=%s' "$AI_INFRA_SEED" "$AI_INFRA_HP_LR" "$AI_INFRA_DATASET_REFS" > "$AI_INFRA_OUTPUT_PATH"
+`), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{
+		CodeRef:         "file://" + script,
+		ContainerDigest: "sha256:local",
+		Hyperparams:     json.RawMessage(`{"lr":0.01,"layers":[64]}`),
+		DatasetRefs:     json.RawMessage(`["s3://datasets/train"]`),
+		Seed:            42,
+	})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if processed, err := runner.ExecuteNextJob(ctx, svc, runner.Config{Executor: executor}); err != nil || !processed {
+		t.Fatalf("execute job: %v %v", processed, err)
+	}
+	arts, err := memStore.ListArtifacts(ctx, store.ListArtifactsFilter{TrainingJobID: &job.ID})
+	if err != nil || len(arts) != 1 {
+		t.Fatalf("list artifacts: %+v %v", arts, err)
+	}
+	sum := sha256.Sum256([]byte(`weights seed=42 lr=0.01 data=["s3://datasets/train"]`))
+	if arts[0].Checksum != hex.EncodeToString(sum[:]) || !strings.HasPrefix(arts[0].ArtifactURI, "file://") {
+		t.Fatalf("artifact does not match the script output: %+v", arts[0])
+	}
+	var meta struct {
+		Runner  string `json:"runner"`
+		LogPath string `json:"logPath"`
+	}
+	if err := json.Unmarshal(arts[0].Metadata, &meta); err != nil || meta.Runner != "local" {
+		t.Fatalf("unexpected metadata: %s", arts[0].Metadata)
+	}
+	if logs, err := os.ReadFile(meta.LogPath); err != nil || !strings.Contains(string(logs), "lr=0.01") {
+		t.Fatalf("expected captured logs, got %q %v", logs, err)
+	}
+
+	failing := filepath.Join(dir, "fail.sh")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\necho 'out of memory' >&2\nexit 3\n"), 0o755); err != nil {
+		t.Fatalf("write script: %v", err)
+	}
+	failed, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: failing, ContainerDigest: "sha256:local", MaxAttempts: 1})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if _, err := runner.ExecuteNextJob(ctx, svc, runner.Config{Executor: executor}); err == nil || !strings.Contains(err.Error(), "out of memory") {
+		t.Fatalf("expected the script failure with its output, got %v", err)
+	}
+	if job, _ := memStore.GetTrainingJob(ctx, failed.ID); job.Status != models.JobFailed {
+		t.Fatalf("expected failed status, got %s", job.Status)
 	}
 }
 
@@ -81,3 +151,91 @@ func newTestSigner(t *testing.T) signing.Signer {
 	}
 	return signer
 }
+
+// discardSink drops a run's output and metrics.
+type discardSink struct{}
+
+func (discardSink) Log(string, []byte)               {}
+func (discardSink) Metric(int64, map[string]float64) {}
+
+func TestContainerExecutorRemovesContainerOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls.log")
+	runtime := filepath.Join(dir, "fake-docker")
+	script := "#!/bin/sh\necho \"$@\" >> " + calls + "\nif [ \"$1\" = run ]; then exec sleep 30; fi\n"
+	if err := os.WriteFile(runtime, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake runtime: %v", err)
+	}
+	executor := runner.NewContainerExecutor(runner.ProcessConfig{
+		WorkDir:          filepath.Join(dir, "runs"),
+		ContainerRuntime: runtime,
+		ContainerImage:   "registry.example.com/trainer",
+	})
+	job := models.TrainingJob{ID: uuid.New(), CodeRef: "git://repo@main", ContainerDigest: "sha256:abc"}
+	name := "ai-infra-" + job.ID.String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if _, err := executor.Execute(ctx, job, discardSink{}); err == nil {
+		t.Fatalf("expected the cancelled run to fail")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("cancellation took %s", elapsed)
+	}
+
+	b, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("read runtime calls: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 3 || lines[0] != "rm -f "+name || !strings.HasPrefix(lines[1], "run --rm --name "+name+" ") ||
+		!strings.HasSuffix(lines[1], "registry.example.com/trainer@sha256:abc") || lines[2] != "rm -f "+name {
+		t.Fatalf("unexpected runtime calls:\n%s", b)
+	}
+}
+
+func TestLocalExecutorConfinesEntrypoints(t *testing.T) {
+	dir := t.TempDir()
+	roots := filepath.Join(dir, "trainers")
+	if err := os.MkdirAll(roots, 0o755); err != nil {
+		t.Fatalf("create entrypoint root: %v", err)
+	}
+	body := []byte("#!/bin/sh\nprintf 'secret=%s extra=%s' \"$AI_INFRA_TEST_SECRET\" \"$EXTRA\" > \"$AI_INFRA_OUTPUT_PATH\"\n")
+	inside := filepath.Join(roots, "train.sh")
+	outside := filepath.Join(dir, "train.sh")
+	for _, p := range []string{inside, outside} {
+		if err := os.WriteFile(p, body, 0o755); err != nil {
+			t.Fatalf("write script: %v", err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(roots, "escape.sh")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	t.Setenv("AI_INFRA_TEST_SECRET", "leaked")
+	executor := runner.NewLocalExecutor(runner.ProcessConfig{
+		WorkDir:         filepath.Join(dir, "runs"),
+		Env:             []string{"EXTRA=configured"},
+		EntrypointRoots: []string{roots},
+	})
+
+	for _, codeRef := range []string{outside, "file://" + filepath.Join(roots, "escape.sh"), filepath.Join(roots, "..", "train.sh")} {
+		job := models.TrainingJob{ID: uuid.New(), CodeRef: codeRef}
+		if _, err := executor.Execute(context.Background(), job, discardSink{}); err == nil || !strings.Contains(err.Error(), "outside the entrypoint roots") {
+			t.Fatalf("expected %s to be refused, got %v", codeRef, err)
+		}
+	}
+
+	res, err := executor.Execute(context.Background(), models.TrainingJob{ID: uuid.New(), CodeRef: "file://" + inside}, discardSink{})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	out, err := os.ReadFile(strings.TrimPrefix(res.ArtifactURI, "file://"))
+	if err != nil || string(out) != "secret= extra=configured" {
+		t.Fatalf("run should see only the allowlisted and configured environment, got %q %v", out, err)
+	}
+
+	if _, err := runner.NewExecutor(runner.ExecutorLocal, runner.ProcessConfig{}); err == nil {
+		t.Fatalf("expected the local executor to require entrypoint roots")
+	}
+}
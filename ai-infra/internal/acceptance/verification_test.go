@@ -0,0 +1,249 @@
+package acceptance
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/runner"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/storage"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+func TestRecordedVerification(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	signer := newTestSigner(t)
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), signer)
+	root := t.TempDir()
+	weights := []byte("model weights")
+	sum := sha256.Sum256(weights)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/artifacts/model.bin", "/private/model.bin":
+			_, _ = w.Write(weights)
+		case "/artifacts/moved.bin":
+			http.Redirect(w, r, "/private/model.bin", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer origin.Close()
+	svc.SetArtifactFetcher(storage.NewURIFetcher([]string{root}, []string{origin.URL + "/artifacts/"}, 5*time.Second))
+	router := httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev"}, svc, memStore).Router()
+
+	path := filepath.Join(root, "model.bin")
+	if err := os.WriteFile(path, weights, 0o644); err != nil {
+		t.Fatalf("write artifact: %v", err)
+	}
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{CodeRef: "git://repo@verify", ContainerDigest: "sha256:789"})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	manifestID := "manifest-sig-1"
+	register := func(uri, checksum string) models.ModelArtifact {
+		t.Helper()
+		artifact, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
+			TrainingJobID: job.ID, ArtifactURI: uri, Checksum: checksum, ManifestSignatureID: &manifestID,
+		})
+		if err != nil {
+			t.Fatalf("register artifact: %v", err)
+		}
+		return artifact
+	}
+	verify := func(id, body string) service.VerificationResult {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ai-infra/models/"+id+"/verify", bytes.NewBufferString(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("verify %s: unexpected status %d: %s", body, rec.Code, rec.Body.String())
+		}
+		var res service.VerificationResult
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil || res.Verification == nil {
+			t.Fatalf("decode verify: %v %s", err, rec.Body.String())
+		}
+		return res
+	}
+	checkOf := func(v *models.ArtifactVerification, name string) models.VerificationCheck {
+		t.Helper()
+		for _, c := range v.Checks {
+			if c.Name == name {
+				return c
+			}
+		}
+		t.Fatalf("verification has no %s check: %+v", name, v.Checks)
+		return models.VerificationCheck{}
+	}
+
+	good := register("file://"+path, "sha256:"+hex.EncodeToString(sum[:]))
+	res := verify(good.ID.String(), `{"checksum":true,"manifestSignature":true,"requestedBy":"qa"}`)
+	v := res.Verification
+	if !res.OK || v.Status != models.VerificationPassed || v.RequestedBy != "qa" || v.CompletedAt == nil {
+		t.Fatalf("expected a passed verification: %+v", v)
+	}
+	if c := checkOf(v, models.CheckSignature); c.Status != models.CheckPassed {
+		t.Fatalf("unexpected signature check: %+v", c)
+	}
+	if c := checkOf(v, models.CheckChecksum); c.Status != models.CheckPassed || c.Actual != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected checksum check: %+v", c)
+	}
+	if c := checkOf(v, models.CheckManifestSignature); c.Status != models.CheckSkipped || c.Expected != manifestID {
+		t.Fatalf("unexpected manifest check: %+v", c)
+	}
+	// The record is signed by the service's signer over its envelope hash.
+	digest, _ := hex.DecodeString(*v.SignatureHash)
+	sig, _ := base64.StdEncoding.DecodeString(*v.Signature)
+	pub := signer.(*signing.Ed25519Signer).PublicKey()
+	if *v.SignerID != signer.SignerID() || *v.SignatureVersion != signing.EnvelopeVersion || !ed25519.Verify(pub, digest, sig) {
+		t.Fatalf("verification is not signed: %+v", v)
+	}
+
+	altered := register("file://"+path, "0000")
+	res = verify(altered.ID.String(), `{"checksum":true}`)
+	if c := checkOf(res.Verification, models.CheckChecksum); res.OK || res.Verification.Status != models.VerificationFailed || c.Status != models.CheckFailed {
+		t.Fatalf("expected a checksum mismatch to fail: %+v", res.Verification)
+	}
+	hosted := register(origin.URL+"/artifacts/model.bin", hex.EncodeToString(sum[:]))
+	if res = verify(hosted.ID.String(), `{"checksum":true}`); !res.OK || checkOf(res.Verification, models.CheckChecksum).Status != models.CheckPassed {
+		t.Fatalf("expected an artifact under an allowed prefix to verify: %+v", res.Verification)
+	}
+	// Locations outside the roots and prefixes, including redirects out of a prefix, are
+	// never read, and a requested checksum that cannot be checked fails the verification.
+	outside := register("file:///etc/hostname", "0000")
+	private := register(origin.URL+"/private/model.bin", hex.EncodeToString(sum[:]))
+	moved := register(origin.URL+"/artifacts/moved.bin", hex.EncodeToString(sum[:]))
+	remote := register("s3://bucket/model.pt", "0000")
+	for _, a := range []models.ModelArtifact{outside, private, moved, remote} {
+		res = verify(a.ID.String(), `{"checksum":true}`)
+		if c := checkOf(res.Verification, models.CheckChecksum); res.OK || res.Verification.Status != models.VerificationFailed || c.Status != models.CheckFailed || c.Actual != "" {
+			t.Fatalf("expected an unreadable uri to fail the checksum: %+v", res.Verification)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ai-infra/verifications/"+v.ID.String(), nil))
+	var stored models.ArtifactVerification
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&stored) != nil || stored.Status != models.VerificationPassed || stored.SignatureHash == nil {
+		t.Fatalf("get verification: %d %+v", rec.Code, stored)
+	}
+	verify(good.ID.String(), ``)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ai-infra/models/"+good.ID.String()+"/verifications", nil))
+	var listed []models.ArtifactVerification
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&listed) != nil || len(listed) != 2 || listed[1].ID != v.ID {
+		t.Fatalf("list verifications: %d %+v", rec.Code, listed)
+	}
+}
+
+func TestVerificationReproducesTraining(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	router := httpserver.New(config.Config{AllowDebugToken: true, DebugToken: "dev"}, svc, memStore).Router()
+
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{
+		CodeRef:         "git://repo@repro",
+		ContainerDigest: "sha256:abc",
+		Hyperparams:     json.RawMessage(`{"lr":0.01}`),
+		Seed:            42,
+	})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if processed, err := runner.ProcessNextJob(ctx, svc); err != nil || !processed {
+		t.Fatalf("train: %v %v", processed, err)
+	}
+	arts, err := memStore.ListArtifacts(ctx, store.ListArtifactsFilter{TrainingJobID: &job.ID})
+	if err != nil || len(arts) != 1 {
+		t.Fatalf("list artifacts: %+v %v", arts, err)
+	}
+	artifact := arts[0]
+
+	reproduce := func(id, token string, want int) *models.ArtifactVerification {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/ai-infra/models/"+id+"/verify", bytes.NewBufferString(`{"reproduce":true}`))
+		if token != "" {
+			req.Header.Set("X-Debug-Token", token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("reproduce: expected %d, got %d: %s", want, rec.Code, rec.Body.String())
+		}
+		var res service.VerificationResult
+		if want == http.StatusAccepted {
+			if err := json.NewDecoder(rec.Body).Decode(&res); err != nil || res.Verification == nil || res.Verification.ReproJobID == nil {
+				t.Fatalf("decode reproduce: %v %s", err, rec.Body.String())
+			}
+		}
+		return res.Verification
+	}
+	reproduce(artifact.ID.String(), "", http.StatusUnauthorized)
+
+	pending := reproduce(artifact.ID.String(), "dev", http.StatusAccepted)
+	if pending.Status != models.VerificationPending || pending.Signature != nil {
+		t.Fatalf("expected a pending, unsigned verification: %+v", pending)
+	}
+	repro, err := svc.GetTrainingJob(ctx, *pending.ReproJobID)
+	if err != nil || repro.ReproducesArtifactID == nil || *repro.ReproducesArtifactID != artifact.ID ||
+		repro.Seed != job.Seed || repro.CodeRef != job.CodeRef || string(repro.Hyperparams) != string(job.Hyperparams) {
+		t.Fatalf("unexpected reproduction job: %+v %v", repro, err)
+	}
+	if processed, err := runner.ProcessNextJob(ctx, svc); err != nil || !processed {
+		t.Fatalf("reproduce: %v %v", processed, err)
+	}
+	done, err := svc.GetVerification(ctx, pending.ID)
+	if err != nil || done.Status != models.VerificationPassed || done.Signature == nil {
+		t.Fatalf("expected the reproduction to pass: %+v %v", done, err)
+	}
+	for _, c := range done.Checks {
+		if c.Name == models.CheckReproduction && (c.Status != models.CheckPassed || c.Actual != artifact.Checksum) {
+			t.Fatalf("unexpected reproduction check: %+v", c)
+		}
+	}
+	if repro, _ = svc.GetTrainingJob(ctx, repro.ID); repro.Status != models.JobSucceeded {
+		t.Fatalf("expected the reproduction job to succeed, got %s", repro.Status)
+	}
+	if arts, _ := memStore.ListArtifacts(ctx, store.ListArtifactsFilter{TrainingJobID: &repro.ID}); len(arts) != 0 {
+		t.Fatalf("a reproduction must not register an artifact: %+v", arts)
+	}
+
+	// An artifact whose checksum the training run does not reproduce fails.
+	other, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{TrainingJobID: job.ID, ArtifactURI: "s3://bucket/other.pt", Checksum: "0000"})
+	if err != nil {
+		t.Fatalf("register artifact: %v", err)
+	}
+	pending = reproduce(other.ID.String(), "dev", http.StatusAccepted)
+	if processed, err := runner.ProcessNextJob(ctx, svc); err != nil || !processed {
+		t.Fatalf("reproduce: %v %v", processed, err)
+	}
+	if done, err = svc.GetVerification(ctx, pending.ID); err != nil || done.Status != models.VerificationFailed {
+		t.Fatalf("expected the mismatched reproduction to fail: %+v %v", done, err)
+	}
+
+	// A reproduction job that never finishes fails its verification.
+	pending = reproduce(artifact.ID.String(), "dev", http.StatusAccepted)
+	if _, err := svc.CancelTrainingJob(ctx, *pending.ReproJobID, service.CancelJobRequest{Reason: "not needed"}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	done, err = svc.GetVerification(ctx, pending.ID)
+	if err != nil || done.Status != models.VerificationFailed || done.Signature == nil {
+		t.Fatalf("expected the cancelled reproduction to fail: %+v %v", done, err)
+	}
+}
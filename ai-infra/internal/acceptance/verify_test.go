@@ -0,0 +1,95 @@
+package acceptance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/httpserver"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+func TestVerifyModelEndpoint(t *testing.T) {
+	ctx := context.Background()
+	memStore := store.NewMemoryStore()
+	svc := service.New(memStore, sentinel.NewStaticClient(0.5), newTestSigner(t))
+	router := httpserver.New(config.Config{}, svc, memStore).Router()
+
+	job, err := svc.CreateTrainingJob(ctx, service.TrainingJobRequest{
+		CodeRef:         "git://repo@verify",
+		ContainerDigest: "sha256:456",
+	})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	artifact, err := svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
+		TrainingJobID: job.ID,
+		ArtifactURI:   "s3://bucket/model.pt",
+		Checksum:      "sum-1",
+		Metadata:      json.RawMessage(`{ "framework": "torch", "score": 0.90 }`),
+	})
+	if err != nil {
+		t.Fatalf("register artifact: %v", err)
+	}
+	if artifact.SignatureHash == "" || artifact.SignatureVersion == "" || artifact.SignedAt == nil {
+		t.Fatalf("envelope hash not stored: %+v", artifact)
+	}
+	if _, err := svc.PromoteArtifact(ctx, service.PromotionRequest{
+		ArtifactID:  artifact.ID,
+		Environment: "staging",
+		Evaluation:  json.RawMessage(`{"quality":0.95}`),
+		RequestedBy: "qa",
+	}); err != nil {
+		t.Fatalf("promote artifact: %v", err)
+	}
+
+	verify := func(id string) service.VerificationResult {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ai-infra/models/"+id+"/verify", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body.String())
+		}
+		var res service.VerificationResult
+		if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+			t.Fatalf("decode verify: %v", err)
+		}
+		return res
+	}
+
+	res := verify(artifact.ID.String())
+	if !res.OK || !res.Artifact.SignatureOK || len(res.Promotions) != 1 || !res.Promotions[0].SignatureOK {
+		t.Fatalf("expected verified artifact and promotion: %+v", res)
+	}
+
+	// A record whose signed fields were altered after signing must fail the hash check.
+	tampered, err := memStore.CreateArtifact(ctx, store.ArtifactInput{
+		TrainingJobID:    artifact.TrainingJobID,
+		ArtifactURI:      artifact.ArtifactURI,
+		Checksum:         "sum-2",
+		Metadata:         artifact.Metadata,
+		SignerID:         artifact.SignerID,
+		Signature:        artifact.Signature,
+		SignatureHash:    artifact.SignatureHash,
+		SignatureVersion: artifact.SignatureVersion,
+		SignedAt:         artifact.SignedAt,
+	})
+	if err != nil {
+		t.Fatalf("create tampered artifact: %v", err)
+	}
+	res = verify(tampered.ID.String())
+	if res.OK || res.Artifact.HashOK {
+		t.Fatalf("expected tampered artifact to fail verification: %+v", res)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ai-infra/models/"+job.ID.String()+"/verify", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown artifact, got %d", rec.Code)
+	}
+}
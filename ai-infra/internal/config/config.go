@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,39 +14,148 @@ type Config struct {
 	SignerKeyB64     string
 	SignerID         string
 	KMSEndpoint      string
+	SignerPublicKeys string
+	KernelAPIURL     string
 	SentinelMinScore float64
 	SentinelURL      string
 	AllowDebugToken  bool
 	DebugToken       string
+	// CanaryInterval is how often the canary controller checks for canaries whose window
+	// has ended.
+	CanaryInterval time.Duration
+	// ReasoningGraphURL enables publishing artifact lineage to the Reasoning Graph.
+	ReasoningGraphURL   string
+	ReasoningGraphToken string
+	// RunnerExecutor selects how the training runner executes jobs: simulated (default),
+	// local or container.
+	RunnerExecutor string
+	RunnerWorkDir  string
+	RunnerImage    string
+	RunnerTimeout  time.Duration
+	// RunnerEntrypointRoots are the directories the local executor may run codeRefs
+	// from; it refuses to start without one.
+	RunnerEntrypointRoots []string
+	// Training job lifecycle: attempts per job, worker lease length, the first retry
+	// delay (doubling per attempt) and how often abandoned jobs are reaped.
+	JobMaxAttempts    int
+	JobLease          time.Duration
+	JobBackoff        time.Duration
+	JobReaperInterval time.Duration
+	// ArtifactRoots are the directories whose file:// artifacts checksum verification may
+	// read, in addition to the runner work directory.
+	ArtifactRoots []string
+	// ArtifactURLPrefixes are the http(s) URL prefixes checksum verification may fetch
+	// artifacts from; with none, http(s) artifacts are not fetched.
+	ArtifactURLPrefixes []string
+	// BlobBackend selects where uploaded artifact content is stored: local (default, under
+	// BlobDir) or s3 (BlobS3Bucket, optionally an S3-compatible BlobS3Endpoint).
+	BlobBackend    string
+	BlobDir        string
+	BlobS3Bucket   string
+	BlobS3Prefix   string
+	BlobS3Endpoint string
+	BlobMaxBytes   int64
+	// PublicURL is the externally reachable base URL of the service, used in download
+	// URLs of the local blob backend; BlobURLSecret keys their signatures.
+	PublicURL     string
+	BlobURLSecret string
+	// BlobGCInterval is how often unreferenced blobs older than BlobGCGrace are deleted;
+	// zero disables the collector.
+	BlobGCInterval time.Duration
+	BlobGCGrace    time.Duration
+	// RequireManifestSignatures refuses promotions unless the artifact carries a verified
+	// Kernel manifest signature; it needs KERNEL_API_URL and defaults to on in production.
+	RequireManifestSignatures bool
+	// RequireDatasetVersions refuses training jobs whose datasetRefs name anything other
+	// than a registered dataset version (datasetVersionId).
+	RequireDatasetVersions bool
 }
 
 const (
 	defaultAddr             = ":8061"
 	defaultSignerID         = "ai-infra-dev"
 	defaultSentinelMinScore = 0.8
+	defaultCanaryIntervalS  = 30
+	defaultJobMaxAttempts   = 3
+	defaultJobLeaseS        = 60
+	defaultJobBackoffS      = 30
+	defaultJobReaperS       = 30
+	defaultBlobBackend      = "local"
+	defaultBlobMaxBytes     = 10 << 30
+	defaultBlobGCS          = 3600
+	defaultBlobGCGraceS     = 86400
 )
 
 func Load() (Config, error) {
 	cfg := Config{
-		Addr:             getEnv("AI_INFRA_ADDR", defaultAddr),
-		DatabaseURL:      firstNonEmpty(os.Getenv("AI_INFRA_DATABASE_URL"), os.Getenv("DATABASE_URL")),
-		SignerKeyB64:     os.Getenv("AI_INFRA_SIGNER_KEY_B64"),
-		SignerID:         getEnv("AI_INFRA_SIGNER_ID", defaultSignerID),
-		KMSEndpoint:      os.Getenv("AI_INFRA_KMS_ENDPOINT"),
-		SentinelMinScore: getFloat("AI_INFRA_MIN_PROMO_SCORE", defaultSentinelMinScore),
-		SentinelURL:      os.Getenv("AI_INFRA_SENTINEL_URL"),
-		AllowDebugToken:  getBool("AI_INFRA_ALLOW_DEBUG_TOKEN", false),
-		DebugToken:       os.Getenv("AI_INFRA_DEBUG_TOKEN"),
+		Addr:                   getEnv("AI_INFRA_ADDR", defaultAddr),
+		DatabaseURL:            firstNonEmpty(os.Getenv("AI_INFRA_DATABASE_URL"), os.Getenv("DATABASE_URL")),
+		SignerKeyB64:           os.Getenv("AI_INFRA_SIGNER_KEY_B64"),
+		SignerID:               getEnv("AI_INFRA_SIGNER_ID", defaultSignerID),
+		KMSEndpoint:            os.Getenv("AI_INFRA_KMS_ENDPOINT"),
+		SignerPublicKeys:       os.Getenv("AI_INFRA_SIGNER_PUBLIC_KEYS"),
+		KernelAPIURL:           os.Getenv("KERNEL_API_URL"),
+		SentinelMinScore:       getFloat("AI_INFRA_MIN_PROMO_SCORE", defaultSentinelMinScore),
+		SentinelURL:            os.Getenv("AI_INFRA_SENTINEL_URL"),
+		AllowDebugToken:        getBool("AI_INFRA_ALLOW_DEBUG_TOKEN", false),
+		DebugToken:             os.Getenv("AI_INFRA_DEBUG_TOKEN"),
+		CanaryInterval:         time.Duration(getInt("AI_INFRA_CANARY_INTERVAL_SECONDS", defaultCanaryIntervalS)) * time.Second,
+		ReasoningGraphURL:      os.Getenv("AI_INFRA_REASONING_GRAPH_URL"),
+		ReasoningGraphToken:    os.Getenv("AI_INFRA_REASONING_GRAPH_TOKEN"),
+		RunnerExecutor:         os.Getenv("AI_INFRA_RUNNER_EXECUTOR"),
+		RunnerWorkDir:          os.Getenv("AI_INFRA_RUNNER_WORKDIR"),
+		RunnerImage:            os.Getenv("AI_INFRA_RUNNER_IMAGE"),
+		RunnerTimeout:          time.Duration(getInt("AI_INFRA_RUNNER_TIMEOUT_SECONDS", 0)) * time.Second,
+		RunnerEntrypointRoots:  getList("AI_INFRA_RUNNER_ENTRYPOINT_ROOTS"),
+		JobMaxAttempts:         getInt("AI_INFRA_JOB_MAX_ATTEMPTS", defaultJobMaxAttempts),
+		JobLease:               time.Duration(getInt("AI_INFRA_JOB_LEASE_SECONDS", defaultJobLeaseS)) * time.Second,
+		JobBackoff:             time.Duration(getInt("AI_INFRA_JOB_BACKOFF_SECONDS", defaultJobBackoffS)) * time.Second,
+		JobReaperInterval:      time.Duration(getInt("AI_INFRA_JOB_REAPER_INTERVAL_SECONDS", defaultJobReaperS)) * time.Second,
+		ArtifactRoots:          getList("AI_INFRA_ARTIFACT_ROOTS"),
+		ArtifactURLPrefixes:    getList("AI_INFRA_ARTIFACT_URL_PREFIXES"),
+		BlobBackend:            getEnv("AI_INFRA_BLOB_BACKEND", defaultBlobBackend),
+		BlobDir:                os.Getenv("AI_INFRA_BLOB_DIR"),
+		BlobS3Bucket:           os.Getenv("AI_INFRA_BLOB_S3_BUCKET"),
+		BlobS3Prefix:           os.Getenv("AI_INFRA_BLOB_S3_PREFIX"),
+		BlobS3Endpoint:         os.Getenv("AI_INFRA_BLOB_S3_ENDPOINT"),
+		BlobMaxBytes:           int64(getInt("AI_INFRA_BLOB_MAX_BYTES", defaultBlobMaxBytes)),
+		PublicURL:              os.Getenv("AI_INFRA_PUBLIC_URL"),
+		BlobURLSecret:          os.Getenv("AI_INFRA_BLOB_URL_SECRET"),
+		BlobGCInterval:         time.Duration(getInt("AI_INFRA_BLOB_GC_INTERVAL_SECONDS", defaultBlobGCS)) * time.Second,
+		BlobGCGrace:            time.Duration(getInt("AI_INFRA_BLOB_GC_GRACE_SECONDS", defaultBlobGCGraceS)) * time.Second,
+		RequireDatasetVersions: getBool("AI_INFRA_REQUIRE_DATASET_VERSIONS", false),
 	}
 	nodeEnv := os.Getenv("NODE_ENV")
+	cfg.RequireManifestSignatures = getBool("AI_INFRA_REQUIRE_MANIFEST_SIGNATURES", nodeEnv == "production")
 	if cfg.DatabaseURL == "" {
 		return Config{}, fmt.Errorf("DATABASE_URL or AI_INFRA_DATABASE_URL required")
 	}
-	if cfg.KMSEndpoint == "" && cfg.SignerKeyB64 == "" {
-		return Config{}, fmt.Errorf("AI_INFRA_SIGNER_KEY_B64 required when AI_INFRA_KMS_ENDPOINT unset")
+	if cfg.KernelAPIURL == "" && cfg.KMSEndpoint == "" && cfg.SignerKeyB64 == "" {
+		return Config{}, fmt.Errorf("AI_INFRA_SIGNER_KEY_B64 required when KERNEL_API_URL and AI_INFRA_KMS_ENDPOINT are unset")
 	}
-	if nodeEnv == "production" && cfg.KMSEndpoint == "" {
-		return Config{}, fmt.Errorf("AI_INFRA_KMS_ENDPOINT (or KMS_ENDPOINT) required in production")
+	if cfg.CanaryInterval <= 0 {
+		return Config{}, fmt.Errorf("AI_INFRA_CANARY_INTERVAL_SECONDS must be positive")
+	}
+	if cfg.JobMaxAttempts <= 0 || cfg.JobLease <= 0 || cfg.JobReaperInterval <= 0 {
+		return Config{}, fmt.Errorf("AI_INFRA_JOB_MAX_ATTEMPTS, AI_INFRA_JOB_LEASE_SECONDS and AI_INFRA_JOB_REAPER_INTERVAL_SECONDS must be positive")
+	}
+	switch cfg.BlobBackend {
+	case "local":
+	case "s3":
+		if cfg.BlobS3Bucket == "" {
+			return Config{}, fmt.Errorf("AI_INFRA_BLOB_S3_BUCKET required for the s3 blob backend")
+		}
+	default:
+		return Config{}, fmt.Errorf("unknown AI_INFRA_BLOB_BACKEND %q (use local or s3)", cfg.BlobBackend)
+	}
+	if cfg.BlobMaxBytes <= 0 || cfg.BlobGCInterval < 0 || cfg.BlobGCGrace < 0 {
+		return Config{}, fmt.Errorf("AI_INFRA_BLOB_MAX_BYTES must be positive and the blob GC settings not negative")
+	}
+	if nodeEnv == "production" && cfg.KernelAPIURL == "" && cfg.KMSEndpoint == "" {
+		return Config{}, fmt.Errorf("KERNEL_API_URL or AI_INFRA_KMS_ENDPOINT required in production")
+	}
+	if cfg.RequireManifestSignatures && cfg.KernelAPIURL == "" {
+		return Config{}, fmt.Errorf("KERNEL_API_URL required to verify manifest signatures (AI_INFRA_REQUIRE_MANIFEST_SIGNATURES, on by default in production)")
 	}
 	return cfg, nil
 }
@@ -65,6 +176,17 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
+// getList splits a comma-separated variable, dropping empty entries.
+func getList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func getFloat(key string, fallback float64) float64 {
 	if v := os.Getenv(key); v != "" {
 		if f, err := strconv.ParseFloat(v, 64); err == nil {
@@ -74,6 +196,15 @@ func getFloat(key string, fallback float64) float64 {
 	return fallback
 }
 
+func getInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if i, err := strconv.Atoi(v); err == nil {
+			return i
+		}
+	}
+	return fallback
+}
+
 func getBool(key string, fallback bool) bool {
 	if v := os.Getenv(key); v != "" {
 		if b, err := strconv.ParseBool(v); err == nil {
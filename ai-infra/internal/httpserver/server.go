@@ -3,8 +3,12 @@ package httpserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -14,27 +18,37 @@ import (
 	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/storage"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/metrics"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 type Server struct {
 	cfg     config.Config
 	service *service.Service
 	store   store.Store
+
+	metrics     *metrics.Registry
+	httpMetrics *metrics.HTTPMetrics
 }
 
 func New(cfg config.Config, svc *service.Service, store store.Store) *Server {
-	return &Server{cfg: cfg, service: svc, store: store}
+	reg := metrics.NewRegistry()
+	return &Server{cfg: cfg, service: svc, store: store, metrics: reg, httpMetrics: metrics.NewHTTPMetrics(reg, "ai-infra")}
 }
 
 func (s *Server) Router() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(tracing.Middleware)
+	r.Use(s.httpMetrics.Middleware)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
+	r.Use(requestTimeout(30 * time.Second))
 
 	r.Get("/health", s.handleHealth)
+	r.Handle("/metrics", s.metrics.Handler())
 
 	r.Route("/ai-infra", func(r chi.Router) {
 		r.Group(func(r chi.Router) {
@@ -42,9 +56,34 @@ func (s *Server) Router() http.Handler {
 			r.Post("/train", s.handleTrain)
 			r.Post("/register", s.handleRegister)
 			r.Post("/promote", s.handlePromote)
+			r.Post("/models/{id}/canary", s.handleStartCanary)
+			r.Post("/promotions/{id}/observations", s.handleCanaryObservations)
+			r.Post("/environments/{env}/rollback", s.handleRollbackEnvironment)
+			r.Post("/lineage/{id}/publish", s.handlePublishLineage)
+			r.Post("/jobs/{id}/cancel", s.handleCancelJob)
+			r.Post("/blobs", s.handleUploadBlob)
+			r.Post("/blobs/gc", s.handleCollectBlobs)
+			r.Get("/blobs/{sha256}/url", s.handleBlobURL)
+			r.Get("/models/{id}/download-url", s.handleArtifactURL)
+			r.Post("/datasets", s.handleRegisterDataset)
+			r.Post("/datasets/{id}/revoke", s.handleRevokeDataset)
 		})
 		r.Get("/models", s.handleListModels)
 		r.Get("/models/{id}", s.handleGetModel)
+		r.Post("/models/{id}/verify", s.handleVerifyModel)
+		r.Get("/models/{id}/verifications", s.handleListVerifications)
+		r.Get("/verifications/{id}", s.handleGetVerification)
+		r.Get("/promotions/{id}", s.handleGetPromotion)
+		r.Get("/environments/{env}", s.handleGetEnvironment)
+		r.Get("/lineage/{id}", s.handleGetLineage)
+		r.Get("/jobs", s.handleListJobs)
+		r.Get("/jobs/{id}", s.handleGetJob)
+		r.Get("/jobs/{id}/logs", s.handleJobLogs)
+		r.Get("/jobs/{id}/metrics", s.handleJobMetrics)
+		r.Get("/blobs/{sha256}", s.handleGetBlob)
+		r.Get("/blobs/{sha256}/content", s.handleBlobContent)
+		r.Get("/datasets", s.handleListDatasets)
+		r.Get("/datasets/{id}", s.handleGetDataset)
 	})
 
 	return r
@@ -72,6 +111,9 @@ type trainRequest struct {
 	Hyperparams     json.RawMessage `json:"hyperparams"`
 	DatasetRefs     json.RawMessage `json:"datasetRefs"`
 	Seed            int64           `json:"seed"`
+	// MaxAttempts and MaxRuntimeSeconds override the job policy; zero keeps the default.
+	MaxAttempts       int `json:"maxAttempts"`
+	MaxRuntimeSeconds int `json:"maxRuntimeSeconds"`
 }
 
 func (s *Server) handleTrain(w http.ResponseWriter, r *http.Request) {
@@ -81,11 +123,13 @@ func (s *Server) handleTrain(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	job, err := s.service.CreateTrainingJob(r.Context(), service.TrainingJobRequest{
-		CodeRef:         req.CodeRef,
-		ContainerDigest: req.ContainerDigest,
-		Hyperparams:     req.Hyperparams,
-		DatasetRefs:     req.DatasetRefs,
-		Seed:            req.Seed,
+		CodeRef:           req.CodeRef,
+		ContainerDigest:   req.ContainerDigest,
+		Hyperparams:       req.Hyperparams,
+		DatasetRefs:       req.DatasetRefs,
+		Seed:              req.Seed,
+		MaxAttempts:       req.MaxAttempts,
+		MaxRuntimeSeconds: req.MaxRuntimeSeconds,
 	})
 	if err != nil {
 		respondError(w, http.StatusBadRequest, err.Error())
@@ -95,11 +139,12 @@ func (s *Server) handleTrain(w http.ResponseWriter, r *http.Request) {
 }
 
 type registerRequest struct {
-	TrainingJobID       string          `json:"trainingJobId"`
-	ArtifactURI         string          `json:"artifactUri"`
-	Checksum            string          `json:"checksum"`
-	Metadata            json.RawMessage `json:"metadata"`
-	ManifestSignatureID *string         `json:"manifestSignatureId"`
+	TrainingJobID       string                  `json:"trainingJobId"`
+	ArtifactURI         string                  `json:"artifactUri"`
+	Checksum            string                  `json:"checksum"`
+	Metadata            json.RawMessage         `json:"metadata"`
+	ManifestSignatureID *string                 `json:"manifestSignatureId"`
+	Parents             []models.ArtifactParent `json:"parents"`
 }
 
 func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
@@ -119,8 +164,13 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 		Checksum:            req.Checksum,
 		Metadata:            req.Metadata,
 		ManifestSignatureID: req.ManifestSignatureID,
+		Parents:             req.Parents,
 	})
 	if err != nil {
+		if errors.Is(err, service.ErrKernelSign) {
+			respondError(w, http.StatusBadGateway, err.Error())
+			return
+		}
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -128,10 +178,11 @@ func (s *Server) handleRegister(w http.ResponseWriter, r *http.Request) {
 }
 
 type promoteRequest struct {
-	ArtifactID  string          `json:"artifactId"`
-	Environment string          `json:"environment"`
-	Evaluation  json.RawMessage `json:"evaluation"`
-	RequestedBy string          `json:"requestedBy"`
+	ArtifactID  string               `json:"artifactId"`
+	Environment string               `json:"environment"`
+	Evaluation  json.RawMessage      `json:"evaluation"`
+	RequestedBy string               `json:"requestedBy"`
+	Canary      *models.CanaryPolicy `json:"canary"`
 }
 
 func (s *Server) handlePromote(w http.ResponseWriter, r *http.Request) {
@@ -150,14 +201,484 @@ func (s *Server) handlePromote(w http.ResponseWriter, r *http.Request) {
 		Environment: req.Environment,
 		Evaluation:  req.Evaluation,
 		RequestedBy: req.RequestedBy,
+		Canary:      req.Canary,
 	})
 	if err != nil {
+		respondError(w, promotionErrorStatus(err), err.Error())
+		return
+	}
+	status := http.StatusOK
+	if promo.Status == models.PromotionCanary {
+		status = http.StatusAccepted
+	}
+	respondJSON(w, status, promo)
+}
+
+type startCanaryRequest struct {
+	Environment string          `json:"environment"`
+	Evaluation  json.RawMessage `json:"evaluation"`
+	RequestedBy string          `json:"requestedBy"`
+	models.CanaryPolicy
+}
+
+// handleStartCanary promotes an artifact as a canary. The response is 202 with the
+// promotion in the canary state, or 200 when SentinelNet rejected it.
+func (s *Server) handleStartCanary(w http.ResponseWriter, r *http.Request) {
+	artifactID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req startCanaryRequest
+	if err := decodeJSON(w, r, &req); err != nil {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	policy := req.CanaryPolicy
+	promo, err := s.service.PromoteArtifact(r.Context(), service.PromotionRequest{
+		ArtifactID:  artifactID,
+		Environment: req.Environment,
+		Evaluation:  req.Evaluation,
+		RequestedBy: req.RequestedBy,
+		Canary:      &policy,
+	})
+	if err != nil {
+		respondError(w, promotionErrorStatus(err), err.Error())
+		return
+	}
+	status := http.StatusOK
+	if promo.Status == models.PromotionCanary {
+		status = http.StatusAccepted
+	}
+	respondJSON(w, status, promo)
+}
+
+type observationsRequest struct {
+	Observations []service.CanaryObservationInput `json:"observations"`
+}
+
+func (s *Server) handleCanaryObservations(w http.ResponseWriter, r *http.Request) {
+	promotionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req observationsRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	promo, err := s.service.RecordCanaryObservations(r.Context(), promotionID, req.Observations)
+	if err != nil {
+		respondError(w, promotionErrorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, promo)
+}
+
+func (s *Server) handleGetPromotion(w http.ResponseWriter, r *http.Request) {
+	promotionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	promo, err := s.service.GetPromotion(r.Context(), promotionID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "promotion not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 	respondJSON(w, http.StatusOK, promo)
 }
 
+func (s *Server) handleGetEnvironment(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+	env, err := s.service.GetEnvironment(r.Context(), chi.URLParam(r, "env"), limit)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "environment not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, env)
+}
+
+type rollbackRequest struct {
+	TargetPromotionID string `json:"targetPromotionId"`
+	Reason            string `json:"reason"`
+	RequestedBy       string `json:"requestedBy"`
+}
+
+// handleRollbackEnvironment restores the previous artifact of an environment. A rollback
+// SentinelNet denies is returned with status 200 and the promotion in status rejected.
+func (s *Server) handleRollbackEnvironment(w http.ResponseWriter, r *http.Request) {
+	var req rollbackRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(w, r, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	in := service.RollbackRequest{Reason: req.Reason, RequestedBy: req.RequestedBy}
+	if req.TargetPromotionID != "" {
+		id, err := uuid.Parse(req.TargetPromotionID)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "invalid targetPromotionId")
+			return
+		}
+		in.TargetPromotionID = &id
+	}
+	promo, err := s.service.RollbackEnvironment(r.Context(), chi.URLParam(r, "env"), in)
+	if err != nil {
+		respondError(w, promotionErrorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, promo)
+}
+
+// lineageOptions reads the depth and direction query parameters of a lineage request.
+func lineageOptions(r *http.Request) service.LineageOptions {
+	opts := service.LineageOptions{Direction: r.URL.Query().Get("direction")}
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		if depth, err := strconv.Atoi(depthStr); err == nil {
+			opts.Depth = depth
+		}
+	}
+	return opts
+}
+
+func (s *Server) handleGetLineage(w http.ResponseWriter, r *http.Request) {
+	artifactID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	lineage, err := s.service.GetLineage(r.Context(), artifactID, lineageOptions(r))
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "artifact not found")
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, lineage)
+}
+
+// handlePublishLineage writes an artifact's lineage to the Reasoning Graph. It returns 503
+// when no Reasoning Graph is configured and 502 when a write to it fails.
+func (s *Server) handlePublishLineage(w http.ResponseWriter, r *http.Request) {
+	artifactID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	published, err := s.service.PublishLineage(r.Context(), artifactID, lineageOptions(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			respondError(w, http.StatusNotFound, "artifact not found")
+		case errors.Is(err, service.ErrReasoningGraphDisabled):
+			respondError(w, http.StatusServiceUnavailable, err.Error())
+		case errors.Is(err, service.ErrPublishFailed):
+			respondError(w, http.StatusBadGateway, err.Error())
+		default:
+			respondError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, published)
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := store.ListTrainingJobsFilter{
+		Status:   models.JobStatus(query.Get("status")),
+		WorkerID: query.Get("workerId"),
+		CodeRef:  query.Get("codeRef"),
+	}
+	for _, bound := range []struct {
+		param string
+		dst   **time.Time
+	}{{"createdAfter", &filter.CreatedAfter}, {"createdBefore", &filter.CreatedBefore}} {
+		if v := query.Get(bound.param); v != "" {
+			ts, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "invalid "+bound.param+": use RFC 3339")
+				return
+			}
+			*bound.dst = &ts
+		}
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			filter.Limit = limit
+		}
+	}
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil {
+			filter.Offset = offset
+		}
+	}
+	jobs, err := s.service.ListTrainingJobs(r.Context(), filter)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if jobs == nil {
+		jobs = []models.TrainingJob{}
+	}
+	respondJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	jobID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	job, err := s.service.GetTrainingJob(r.Context(), jobID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "training job not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, job)
+}
+
+const (
+	// logFollowPoll is how often a log stream checks for new output.
+	logFollowPoll = 500 * time.Millisecond
+	// logFollowKeepalive is the longest a log stream stays silent.
+	logFollowKeepalive = 15 * time.Second
+)
+
+// requestTimeout bounds the context of each request to d, except for log streams and blob
+// transfers, which last until they complete or the client goes away.
+func requestTimeout(d time.Duration) func(http.Handler) http.Handler {
+	timeout := middleware.Timeout(d)
+	return func(next http.Handler) http.Handler {
+		bounded := timeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if unbounded(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			bounded.ServeHTTP(w, r)
+		})
+	}
+}
+
+// unbounded reports whether r streams for as long as it takes: followed logs and blob
+// uploads and downloads.
+func unbounded(r *http.Request) bool {
+	if follow, _ := strconv.ParseBool(r.URL.Query().Get("follow")); follow {
+		return true
+	}
+	return (r.Method == http.MethodPost && r.URL.Path == "/ai-infra/blobs") ||
+		(r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/ai-infra/blobs/") && strings.HasSuffix(r.URL.Path, "/content"))
+}
+
+// jobOutputPage reads the job id and the after/limit pagination parameters of a logs or
+// metrics request. The Last-Event-ID header of a resumed stream takes precedence over
+// ?after=.
+func jobOutputPage(r *http.Request) (uuid.UUID, int64, int, error) {
+	jobID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, 0, 0, errors.New("invalid id")
+	}
+	var after int64
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("after")
+	}
+	if v != "" {
+		after, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || after < 0 {
+			return uuid.Nil, 0, 0, errors.New("invalid after")
+		}
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	return jobID, after, limit, nil
+}
+
+// handleJobLogs returns a training job's log chunks after ?after= (a seq). With
+// ?follow=true it streams them as Server-Sent Events instead: each chunk is sent with
+// "id: <seq>", "event: log" and the chunk JSON as data, and once the job has finished and
+// its output is drained an "end" event carries the job. Streams resume with Last-Event-ID.
+func (s *Server) handleJobLogs(w http.ResponseWriter, r *http.Request) {
+	jobID, after, limit, err := jobOutputPage(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if follow, _ := strconv.ParseBool(r.URL.Query().Get("follow")); follow {
+		s.followJobLogs(w, r, jobID, after)
+		return
+	}
+	chunks, err := s.service.ListJobLogs(r.Context(), jobID, after, limit)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "training job not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if chunks == nil {
+		chunks = []models.JobLogChunk{}
+	}
+	respondJSON(w, http.StatusOK, chunks)
+}
+
+func (s *Server) followJobLogs(w http.ResponseWriter, r *http.Request, jobID uuid.UUID, after int64) {
+	ctx := r.Context()
+	if _, err := s.service.GetTrainingJob(ctx, jobID); err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "training job not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	lastWrite := time.Now()
+	send := func(format string, args ...interface{}) bool {
+		lastWrite = time.Now()
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !send(": connected\n\n") {
+		return
+	}
+	ticker := time.NewTicker(logFollowPoll)
+	defer ticker.Stop()
+	for {
+		// Read the status before the logs: the runner stores a run's last output before it
+		// finishes the job, so a finished job has nothing left once its logs are drained.
+		job, err := s.service.GetTrainingJob(ctx, jobID)
+		if err != nil {
+			return
+		}
+		chunks, err := s.service.ListJobLogs(ctx, jobID, after, 0)
+		if err != nil {
+			return
+		}
+		if len(chunks) > 0 {
+			var b strings.Builder
+			for _, chunk := range chunks {
+				data, _ := json.Marshal(chunk)
+				fmt.Fprintf(&b, "id: %d\nevent: log\ndata: %s\n\n", chunk.Seq, data)
+			}
+			if !send("%s", b.String()) {
+				return
+			}
+			after = chunks[len(chunks)-1].Seq
+			continue
+		}
+		if job.Status.Terminal() {
+			data, _ := json.Marshal(job)
+			send("event: end\ndata: %s\n\n", data)
+			return
+		}
+		if time.Since(lastWrite) >= logFollowKeepalive && !send(": keepalive\n\n") {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleJobMetrics returns a training job's metrics samples after ?after= (a seq).
+func (s *Server) handleJobMetrics(w http.ResponseWriter, r *http.Request) {
+	jobID, after, limit, err := jobOutputPage(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	points, err := s.service.ListJobMetrics(r.Context(), jobID, after, limit)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "training job not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if points == nil {
+		points = []models.JobMetricPoint{}
+	}
+	respondJSON(w, http.StatusOK, points)
+}
+
+// handleCancelJob cancels a queued or running training job. Cancelling a finished job
+// returns 409.
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	jobID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req service.CancelJobRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(w, r, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	job, err := s.service.CancelTrainingJob(r.Context(), jobID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			respondError(w, http.StatusNotFound, "training job not found")
+		case errors.Is(err, service.ErrJobFinished), errors.Is(err, store.ErrJobConflict):
+			respondError(w, http.StatusConflict, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, job)
+}
+
+// promotionErrorStatus maps promotion and canary errors to HTTP statuses; anything else
+// is a bad request.
+func promotionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrCanaryInProgress), errors.Is(err, service.ErrNotInCanary), errors.Is(err, store.ErrStatusConflict),
+		errors.Is(err, service.ErrNoActiveModel), errors.Is(err, service.ErrNoRollbackTarget), errors.Is(err, service.ErrManifestSignature):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrKernelSign):
+		return http.StatusBadGateway
+	}
+	return http.StatusBadRequest
+}
+
 func (s *Server) handleListModels(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	filter := store.ListArtifactsFilter{}
@@ -224,24 +745,331 @@ func (s *Server) handleGetModel(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *Server) writeAuth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if s.cfg.AllowDebugToken {
-			if token := r.Header.Get("X-Debug-Token"); token != "" && token == s.cfg.DebugToken {
-				next.ServeHTTP(w, r)
-				return
-			}
-			respondError(w, http.StatusUnauthorized, "debug token required")
+// verifyRequest selects the checks of POST /models/{id}/verify; an empty body only checks
+// signatures.
+type verifyRequest struct {
+	Checksum          bool   `json:"checksum"`
+	ManifestSignature bool   `json:"manifestSignature"`
+	Reproduce         bool   `json:"reproduce"`
+	RequestedBy       string `json:"requestedBy"`
+}
+
+// handleVerifyModel verifies an artifact and records the outcome. Reproducing the training
+// run queues a job, so it requires write access and returns 202 with the pending
+// verification.
+func (s *Server) handleVerifyModel(w http.ResponseWriter, r *http.Request) {
+	artifactID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req verifyRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(w, r, &req); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		if r.TLS == nil {
-			respondError(w, http.StatusUnauthorized, "mtls required")
+	}
+	if req.Reproduce && !s.authorizeWrite(w, r) {
+		return
+	}
+	result, err := s.service.VerifyAndRecord(r.Context(), artifactID, service.VerifyRequest{
+		Checksum:          req.Checksum,
+		ManifestSignature: req.ManifestSignature,
+		Reproduce:         req.Reproduce,
+		RequestedBy:       req.RequestedBy,
+	})
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "artifact not found")
 			return
 		}
-		next.ServeHTTP(w, r)
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	status := http.StatusOK
+	if result.Verification != nil && result.Verification.Status == models.VerificationPending {
+		status = http.StatusAccepted
+	}
+	respondJSON(w, status, result)
+}
+
+func (s *Server) handleListVerifications(w http.ResponseWriter, r *http.Request) {
+	artifactID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			limit = l
+		}
+	}
+	verifications, err := s.service.ListVerifications(r.Context(), artifactID, limit)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "artifact not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if verifications == nil {
+		verifications = []models.ArtifactVerification{}
+	}
+	respondJSON(w, http.StatusOK, verifications)
+}
+
+func (s *Server) handleGetVerification(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	v, err := s.service.GetVerification(r.Context(), id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "verification not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, v)
+}
+
+// handleUploadBlob stores the raw request body as artifact content. The optional
+// ?sha256= rejects a body with another hash.
+func (s *Server) handleUploadBlob(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, s.maxBlobBytes())
+	defer body.Close()
+	blob, err := s.service.UploadBlob(r.Context(), body, r.URL.Query().Get("sha256"))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			respondError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("blob exceeds %d bytes", tooLarge.Limit))
+		case errors.Is(err, service.ErrBlobStoreDisabled):
+			respondError(w, http.StatusServiceUnavailable, err.Error())
+		case errors.Is(err, storage.ErrChecksumMismatch), errors.Is(err, storage.ErrInvalidSHA256):
+			respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	respondJSON(w, http.StatusCreated, blob)
+}
+
+func (s *Server) maxBlobBytes() int64 {
+	if s.cfg.BlobMaxBytes > 0 {
+		return s.cfg.BlobMaxBytes
+	}
+	return 10 << 30
+}
+
+func (s *Server) handleGetBlob(w http.ResponseWriter, r *http.Request) {
+	blob, err := s.service.GetBlob(r.Context(), chi.URLParam(r, "sha256"))
+	if err != nil {
+		respondError(w, blobErrorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, blob)
+}
+
+// handleBlobURL returns a download URL for a blob (?ttlSeconds=, default 900).
+func (s *Server) handleBlobURL(w http.ResponseWriter, r *http.Request) {
+	link, err := s.service.PresignBlob(r.Context(), chi.URLParam(r, "sha256"), downloadTTL(r))
+	if err != nil {
+		respondError(w, blobErrorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, link)
+}
+
+// handleArtifactURL returns a download URL for an artifact held by the blob store.
+func (s *Server) handleArtifactURL(w http.ResponseWriter, r *http.Request) {
+	artifactID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	link, err := s.service.PresignArtifact(r.Context(), artifactID, downloadTTL(r))
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "artifact not found")
+			return
+		}
+		respondError(w, blobErrorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, link)
+}
+
+func downloadTTL(r *http.Request) time.Duration {
+	ttl, _ := strconv.Atoi(r.URL.Query().Get("ttlSeconds"))
+	return time.Duration(ttl) * time.Second
+}
+
+// handleBlobContent serves a blob for a download URL issued by the local blob backend;
+// the URL's signature is its only credential.
+func (s *Server) handleBlobContent(w http.ResponseWriter, r *http.Request) {
+	content, blob, err := s.service.OpenPresignedBlob(r.Context(), chi.URLParam(r, "sha256"), r.URL.Query())
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvalidSignature):
+			respondError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, storage.ErrPresignUnsupported):
+			respondError(w, http.StatusNotFound, err.Error())
+		default:
+			respondError(w, blobErrorStatus(err), err.Error())
+		}
+		return
+	}
+	defer content.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(blob.Size, 10))
+	w.Header().Set("ETag", `"`+blob.SHA256+`"`)
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+// handleCollectBlobs deletes unreferenced blobs older than the configured grace period
+// (?dryRun=true only reports them).
+func (s *Server) handleCollectBlobs(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	result, err := s.service.CollectBlobGarbage(r.Context(), time.Now(), s.cfg.BlobGCGrace, dryRun)
+	if err != nil {
+		respondError(w, blobErrorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+func blobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrBlobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrBlobStoreDisabled):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, service.ErrNotInBlobStore):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) handleRegisterDataset(w http.ResponseWriter, r *http.Request) {
+	var req service.DatasetVersionRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	v, err := s.service.RegisterDatasetVersion(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, store.ErrDatasetVersionExists) {
+			respondError(w, http.StatusConflict, err.Error())
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, v)
+}
+
+func (s *Server) handleListDatasets(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := store.ListDatasetVersionsFilter{Name: q.Get("name"), Status: q.Get("status")}
+	if limitStr := q.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil {
+			filter.Limit = l
+		}
+	}
+	if offsetStr := q.Get("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil {
+			filter.Offset = o
+		}
+	}
+	versions, err := s.service.ListDatasetVersions(r.Context(), filter)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if versions == nil {
+		versions = []models.DatasetVersion{}
+	}
+	respondJSON(w, http.StatusOK, versions)
+}
+
+func (s *Server) handleGetDataset(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	v, err := s.service.GetDatasetVersion(r.Context(), id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "dataset version not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, v)
+}
+
+// handleRevokeDataset revokes a dataset version and returns the artifacts it flagged.
+func (s *Server) handleRevokeDataset(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req service.DatasetRevokeRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := s.service.RevokeDatasetVersion(r.Context(), id, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			respondError(w, http.StatusNotFound, "dataset version not found")
+		case errors.Is(err, store.ErrDatasetRevoked):
+			respondError(w, http.StatusConflict, err.Error())
+		default:
+			respondError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) writeAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authorizeWrite(w, r) {
+			next.ServeHTTP(w, r)
+		}
 	})
 }
 
+// authorizeWrite checks that r may change state, responding 401 when it may not.
+func (s *Server) authorizeWrite(w http.ResponseWriter, r *http.Request) bool {
+	if s.cfg.AllowDebugToken {
+		if token := r.Header.Get("X-Debug-Token"); token != "" && token == s.cfg.DebugToken {
+			return true
+		}
+		respondError(w, http.StatusUnauthorized, "debug token required")
+		return false
+	}
+	if r.TLS == nil {
+		respondError(w, http.StatusUnauthorized, "mtls required")
+		return false
+	}
+	return true
+}
+
 func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
 	defer r.Body.Close()
 	return json.NewDecoder(r.Body).Decode(v)
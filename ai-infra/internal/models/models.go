@@ -14,9 +14,70 @@ type TrainingJob struct {
 	Hyperparams     json.RawMessage `json:"hyperparams"`
 	DatasetRefs     json.RawMessage `json:"datasetRefs"`
 	Seed            int64           `json:"seed"`
-	Status          string          `json:"status"`
-	CreatedAt       time.Time       `json:"createdAt"`
-	UpdatedAt       time.Time       `json:"updatedAt"`
+	Status          JobStatus       `json:"status"`
+	// Attempts counts the runs started so far; a failed run is retried until it
+	// reaches MaxAttempts.
+	Attempts          int `json:"attempts"`
+	MaxAttempts       int `json:"maxAttempts"`
+	MaxRuntimeSeconds int `json:"maxRuntimeSeconds,omitempty"`
+	// WorkerID and LeaseExpiresAt identify the worker running the job and how long
+	// its lease lasts without a heartbeat.
+	WorkerID       string     `json:"workerId,omitempty"`
+	LeaseExpiresAt *time.Time `json:"leaseExpiresAt,omitempty"`
+	HeartbeatAt    *time.Time `json:"heartbeatAt,omitempty"`
+	// NextAttemptAt delays a retried job until its backoff has passed.
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+	// ReproducesArtifactID marks a job that re-runs the training of an artifact to check
+	// that it reproduces; its output is compared rather than registered.
+	ReproducesArtifactID *uuid.UUID `json:"reproducesArtifactId,omitempty"`
+	CreatedAt            time.Time  `json:"createdAt"`
+	UpdatedAt            time.Time  `json:"updatedAt"`
+}
+
+// JobStatus is the lifecycle state of a training job.
+type JobStatus string
+
+// Training job statuses. Queued jobs are claimed by a worker and run; a failed run or an
+// expired lease returns the job to queued until its attempts are used up.
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+	JobTimedOut  JobStatus = "timed_out"
+)
+
+var jobTransitions = map[JobStatus][]JobStatus{
+	JobQueued:  {JobRunning, JobCancelled},
+	JobRunning: {JobQueued, JobSucceeded, JobFailed, JobCancelled, JobTimedOut},
+}
+
+// Valid reports whether s is a known job status.
+func (s JobStatus) Valid() bool {
+	switch s {
+	case JobQueued, JobRunning, JobSucceeded, JobFailed, JobCancelled, JobTimedOut:
+		return true
+	}
+	return false
+}
+
+// Terminal reports whether a job in status s can no longer change.
+func (s JobStatus) Terminal() bool {
+	return s.Valid() && len(jobTransitions[s]) == 0
+}
+
+// CanTransition reports whether a job may move from s to next.
+func (s JobStatus) CanTransition(next JobStatus) bool {
+	for _, allowed := range jobTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
 }
 
 type ModelArtifact struct {
@@ -27,8 +88,17 @@ type ModelArtifact struct {
 	Metadata            json.RawMessage `json:"metadata"`
 	SignerID            string          `json:"signerId"`
 	Signature           string          `json:"signature"`
+	SignatureHash       string          `json:"signatureHash,omitempty"`
+	SignatureVersion    string          `json:"signatureVersion,omitempty"`
+	SignedAt            *time.Time      `json:"signedAt,omitempty"`
 	ManifestSignatureID *string         `json:"manifestSignatureId,omitempty"`
-	CreatedAt           time.Time       `json:"createdAt"`
+	// Parents are the artifacts this one was derived from. They are part of the signed
+	// payload; list endpoints leave them empty.
+	Parents []ArtifactParent `json:"parents,omitempty"`
+	// Flags mark provenance problems found after registration. They are not signed; list
+	// endpoints leave them empty.
+	Flags     []ArtifactFlag `json:"flags,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
 }
 
 type ModelPromotion struct {
@@ -42,5 +112,273 @@ type ModelPromotion struct {
 	PromotedAt       *time.Time      `json:"promotedAt,omitempty"`
 	Signature        *string         `json:"signature,omitempty"`
 	SignerID         *string         `json:"signerId,omitempty"`
-	CreatedAt        time.Time       `json:"createdAt"`
+	SignatureHash    *string         `json:"signatureHash,omitempty"`
+	SignatureVersion *string         `json:"signatureVersion,omitempty"`
+	SignedAt         *time.Time      `json:"signedAt,omitempty"`
+	Canary           *Canary         `json:"canary,omitempty"`
+	Rollback         *Rollback       `json:"rollback,omitempty"`
+	// ManifestSignatureID references the Kernel signature over the promotion manifest; it
+	// is set on promotions applied while a Kernel is configured.
+	ManifestSignatureID *string   `json:"manifestSignatureId,omitempty"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+// Promotion statuses. A promotion with a canary policy waits in PromotionCanary until the
+// canary controller applies it or rolls it back.
+const (
+	PromotionPending    = "pending"
+	PromotionCanary     = "canary"
+	PromotionApplied    = "applied"
+	PromotionRejected   = "rejected"
+	PromotionRolledBack = "rolled_back"
+)
+
+// Canary arms an observation can be reported for.
+const (
+	ArmCanary    = "canary"
+	ArmIncumbent = "incumbent"
+)
+
+// CanaryPolicy configures a canary rollout. MetricSignals are compared between the canary
+// and the incumbent artifact of the environment; a relative drop beyond RollbackThreshold
+// on any of them rolls the promotion back. RollbackThreshold is a pointer so an explicit 0
+// (roll back on any drop) is distinguishable from an omitted threshold.
+type CanaryPolicy struct {
+	CanaryPercent     float64  `json:"canaryPercent"`
+	WindowMinutes     int      `json:"windowMinutes"`
+	RollbackThreshold *float64 `json:"rollbackThreshold,omitempty"`
+	MetricSignals     []string `json:"metricSignals,omitempty"`
+	LowerIsBetter     []string `json:"lowerIsBetter,omitempty"`
+	MinSamples        int      `json:"minSamples"`
+}
+
+// CanaryArm aggregates the observations reported for one arm.
+type CanaryArm struct {
+	Samples int                `json:"samples"`
+	Means   map[string]float64 `json:"means,omitempty"`
+}
+
+// CanaryComparison is the canary-versus-incumbent result for one metric.
+type CanaryComparison struct {
+	Metric       string  `json:"metric"`
+	Canary       float64 `json:"canary"`
+	Incumbent    float64 `json:"incumbent"`
+	RelativeDrop float64 `json:"relativeDrop"`
+	Regressed    bool    `json:"regressed"`
+}
+
+// CanaryEvent is one entry of a canary's history.
+type CanaryEvent struct {
+	At     time.Time `json:"at"`
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Canary is the canary state and history recorded on a promotion.
+type Canary struct {
+	Policy               CanaryPolicy       `json:"policy"`
+	IncumbentArtifactID  *uuid.UUID         `json:"incumbentArtifactId,omitempty"`
+	IncumbentPromotionID *uuid.UUID         `json:"incumbentPromotionId,omitempty"`
+	StartedAt            time.Time          `json:"startedAt"`
+	EndsAt               time.Time          `json:"endsAt"`
+	Outcome              string             `json:"outcome,omitempty"`
+	Reason               string             `json:"reason,omitempty"`
+	CanaryArm            *CanaryArm         `json:"canaryArm,omitempty"`
+	IncumbentArm         *CanaryArm         `json:"incumbentArm,omitempty"`
+	Comparisons          []CanaryComparison `json:"comparisons,omitempty"`
+	DecidedAt            *time.Time         `json:"decidedAt,omitempty"`
+	History              []CanaryEvent      `json:"history"`
+}
+
+// Training run output streams.
+const (
+	LogStdout = "stdout"
+	LogStderr = "stderr"
+)
+
+// JobLogChunk is a piece of a training run's output. Seq numbers a job's chunks in the
+// order they were written, across attempts.
+type JobLogChunk struct {
+	JobID     uuid.UUID `json:"jobId"`
+	Seq       int64     `json:"seq"`
+	Attempt   int       `json:"attempt"`
+	Stream    string    `json:"stream"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// JobMetricPoint is one metrics sample reported by a training run at a step, e.g. loss
+// and throughput. Seq numbers a job's samples like JobLogChunk.Seq.
+type JobMetricPoint struct {
+	JobID      uuid.UUID          `json:"jobId"`
+	Seq        int64              `json:"seq"`
+	Attempt    int                `json:"attempt"`
+	Step       int64              `json:"step"`
+	Metrics    map[string]float64 `json:"metrics"`
+	RecordedAt time.Time          `json:"recordedAt"`
+}
+
+// CanaryObservation is one metrics sample reported by serving for a canary arm.
+type CanaryObservation struct {
+	PromotionID uuid.UUID          `json:"promotionId"`
+	Arm         string             `json:"arm"`
+	Metrics     map[string]float64 `json:"metrics"`
+	ObservedAt  time.Time          `json:"observedAt"`
+}
+
+// ArtifactVerification records a check of an artifact against its signatures, its
+// content and, when requested, a reproduction of its training run. It is signed once
+// every check has finished.
+type ArtifactVerification struct {
+	ID          uuid.UUID           `json:"id"`
+	ArtifactID  uuid.UUID           `json:"artifactId"`
+	Status      string              `json:"status"`
+	Checks      []VerificationCheck `json:"checks"`
+	ReproJobID  *uuid.UUID          `json:"reproJobId,omitempty"`
+	RequestedBy string              `json:"requestedBy,omitempty"`
+	CompletedAt *time.Time          `json:"completedAt,omitempty"`
+	// Signature fields are set when the verification completes.
+	Signature        *string    `json:"signature,omitempty"`
+	SignerID         *string    `json:"signerId,omitempty"`
+	SignatureHash    *string    `json:"signatureHash,omitempty"`
+	SignatureVersion *string    `json:"signatureVersion,omitempty"`
+	SignedAt         *time.Time `json:"signedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
+
+// VerificationCheck is the outcome of one check of an artifact verification.
+type VerificationCheck struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// Verification statuses. A verification waits in VerificationPending while its
+// reproduction job runs.
+const (
+	VerificationPending = "pending"
+	VerificationPassed  = "passed"
+	VerificationFailed  = "failed"
+)
+
+// Verification checks and their statuses. A skipped check could not be performed and
+// does not fail the verification.
+const (
+	CheckSignature         = "signature"
+	CheckChecksum          = "checksum"
+	CheckManifestSignature = "manifest_signature"
+	CheckReproduction      = "reproduction"
+
+	CheckPassed  = "passed"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+	CheckPending = "pending"
+)
+
+// Rollback is recorded on the promotion that restored an environment's previous artifact.
+type Rollback struct {
+	RolledBackPromotionID uuid.UUID `json:"rolledBackPromotionId"`
+	FromArtifactID        uuid.UUID `json:"fromArtifactId"`
+	RestoredPromotionID   uuid.UUID `json:"restoredPromotionId"`
+	Reason                string    `json:"reason,omitempty"`
+}
+
+// Artifact derivation relations.
+const (
+	RelationDerived      = "derived"
+	RelationFineTune     = "fine_tune"
+	RelationDistillation = "distillation"
+	RelationQuantization = "quantization"
+	RelationMerge        = "merge"
+	RelationRetrain      = "retrain"
+)
+
+// ArtifactParent names an artifact another one was derived from.
+type ArtifactParent struct {
+	ArtifactID uuid.UUID `json:"artifactId"`
+	Relation   string    `json:"relation"`
+}
+
+// ArtifactLink is one parent-to-child derivation edge.
+type ArtifactLink struct {
+	ParentID uuid.UUID `json:"parentId"`
+	ChildID  uuid.UUID `json:"childId"`
+	Relation string    `json:"relation"`
+}
+
+// DatasetRef is one dataset a training job consumed. A reference to a registered dataset
+// version carries its DatasetVersionID, with the name, version and manifest hash of that
+// version as ID, Version and Checksum.
+type DatasetRef struct {
+	ID               string     `json:"id"`
+	Version          string     `json:"version,omitempty"`
+	Checksum         string     `json:"checksum,omitempty"`
+	URI              string     `json:"uri,omitempty"`
+	DatasetVersionID *uuid.UUID `json:"datasetVersionId,omitempty"`
+}
+
+// Dataset version statuses. Training jobs cannot reference a revoked version.
+const (
+	DatasetActive  = "active"
+	DatasetRevoked = "revoked"
+)
+
+// DatasetVersion is a registered, immutable version of a dataset. ManifestHash is the
+// sha256 of its manifest, so a reference to the version pins the content of every file.
+type DatasetVersion struct {
+	ID           uuid.UUID     `json:"id"`
+	Name         string        `json:"name"`
+	Version      string        `json:"version"`
+	URI          string        `json:"uri,omitempty"`
+	Manifest     []DatasetFile `json:"manifest"`
+	ManifestHash string        `json:"manifestHash"`
+	License      string        `json:"license"`
+	// PIIFlags name the kinds of personal data the dataset contains; empty means none.
+	PIIFlags  []string `json:"piiFlags"`
+	Status    string   `json:"status"`
+	CreatedBy string   `json:"createdBy,omitempty"`
+	// Revocation fields are set once the version is revoked.
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	RevokedBy        string     `json:"revokedBy,omitempty"`
+	RevocationReason string     `json:"revocationReason,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
+
+// DatasetFile is one file of a dataset version's manifest.
+type DatasetFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Artifact flag reasons.
+const (
+	FlagDatasetRevoked = "dataset_revoked"
+)
+
+// ArtifactFlag marks an artifact whose provenance became suspect, such as one trained on
+// a dataset version that was revoked.
+type ArtifactFlag struct {
+	Reason           string     `json:"reason"`
+	DatasetVersionID *uuid.UUID `json:"datasetVersionId,omitempty"`
+	Detail           string     `json:"detail,omitempty"`
+	FlaggedAt        time.Time  `json:"flaggedAt"`
+}
+
+// ManifestSignature is a Kernel signature over an artifact or promotion manifest, as
+// returned by Kernel POST /kernel/sign. ManifestHash is the hex sha256 of the canonical
+// manifest the Kernel signed.
+type ManifestSignature struct {
+	ID           string    `json:"id"`
+	ManifestID   string    `json:"manifestId"`
+	SubjectType  string    `json:"subjectType"`
+	SubjectID    uuid.UUID `json:"subjectId"`
+	ManifestHash string    `json:"manifestHash"`
+	SignerID     string    `json:"signerId"`
+	Signature    string    `json:"signature"`
+	Version      string    `json:"version"`
+	SignedAt     time.Time `json:"signedAt"`
+	CreatedAt    time.Time `json:"createdAt"`
 }
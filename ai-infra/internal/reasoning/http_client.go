@@ -0,0 +1,124 @@
+package reasoning
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+// Node is a Reasoning Graph node to create.
+type Node struct {
+	Type     string          `json:"type"`
+	Payload  json.RawMessage `json:"payload"`
+	Author   string          `json:"author"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// Edge is a Reasoning Graph edge to create between two existing nodes.
+type Edge struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Type     string          `json:"type"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// Client writes nodes and edges to the Reasoning Graph.
+type Client interface {
+	CreateNode(ctx context.Context, node Node) (string, error)
+	CreateEdge(ctx context.Context, edge Edge) (string, error)
+}
+
+type HTTPClientConfig struct {
+	BaseURL string
+	// Token is sent as a bearer token; the Reasoning Graph accepts Kernel-signed JWTs
+	// with the reasoning:write scope. Leave it empty when the client authenticates
+	// with mTLS.
+	Token      string
+	Timeout    time.Duration
+	HTTPClient *http.Client
+}
+
+// HTTPClient calls the Reasoning Graph HTTP API. Writes are not retried because the
+// Reasoning Graph assigns node ids and a retried create would duplicate the node.
+type HTTPClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+	timeout time.Duration
+}
+
+func NewHTTPClient(cfg HTTPClientConfig) (*HTTPClient, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("reasoning graph base url required")
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &HTTPClient{
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		token:   cfg.Token,
+		client:  tracing.WrapClient(client),
+		timeout: timeout,
+	}, nil
+}
+
+// CreateNode creates node and returns its id.
+func (c *HTTPClient) CreateNode(ctx context.Context, node Node) (string, error) {
+	var resp struct {
+		NodeID string `json:"nodeId"`
+	}
+	if err := c.post(ctx, "/reason/node", node, &resp); err != nil {
+		return "", fmt.Errorf("reasoning graph create node: %w", err)
+	}
+	return resp.NodeID, nil
+}
+
+// CreateEdge creates edge and returns its id.
+func (c *HTTPClient) CreateEdge(ctx context.Context, edge Edge) (string, error) {
+	var resp struct {
+		EdgeID string `json:"edgeId"`
+	}
+	if err := c.post(ctx, "/reason/edge", edge, &resp); err != nil {
+		return "", fmt.Errorf("reasoning graph create edge: %w", err)
+	}
+	return resp.EdgeID, nil
+}
+
+func (c *HTTPClient) post(ctx context.Context, path string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+)
+
+// Executor runs a claimed training job and reports the artifact it produced. The local
+// executor runs the job's entrypoint as a subprocess and the container executor runs its
+// image; a Kubernetes executor would submit a Job and wait for it the same way.
+type Executor interface {
+	// Name identifies the executor in artifact metadata.
+	Name() string
+	// Execute trains job and returns its artifact, reporting the run's output and
+	// metrics to sink as they are produced. It must honour ctx cancellation.
+	Execute(ctx context.Context, job models.TrainingJob, sink Sink) (Result, error)
+}
+
+// Result is the artifact a training run produced. Checksum is the hex sha256 of the
+// artifact content.
+type Result struct {
+	ArtifactURI string
+	Checksum    string
+	// Metadata is stored on the registered artifact alongside the executor name.
+	Metadata map[string]interface{}
+}
+
+// Executor names accepted by NewExecutor.
+const (
+	ExecutorSimulated = "simulated"
+	ExecutorLocal     = "local"
+	ExecutorContainer = "container"
+)
+
+// NewExecutor returns the executor called name. An empty name selects the simulated
+// executor. The local executor needs at least one entrypoint root.
+func NewExecutor(name string, cfg ProcessConfig) (Executor, error) {
+	switch name {
+	case "", ExecutorSimulated:
+		return SimulatedExecutor{}, nil
+	case ExecutorLocal:
+		if len(cfg.EntrypointRoots) == 0 {
+			return nil, fmt.Errorf("local training executor requires entrypoint roots")
+		}
+		return NewLocalExecutor(cfg), nil
+	case ExecutorContainer:
+		return NewContainerExecutor(cfg), nil
+	}
+	return nil, fmt.Errorf("unknown training executor %q", name)
+}
+
+// SimulatedExecutor trains nothing: the checksum is derived from the job definition and
+// the artifact URI is a placeholder. It keeps train→register→promote flows fast in local
+// development and CI.
+type SimulatedExecutor struct{}
+
+func (SimulatedExecutor) Name() string { return ExecutorSimulated }
+
+func (SimulatedExecutor) Execute(ctx context.Context, job models.TrainingJob, sink Sink) (Result, error) {
+	checksum, err := ComputeArtifactChecksum(job)
+	if err != nil {
+		return Result{}, err
+	}
+	sink.Log(models.LogStdout, []byte(fmt.Sprintf("simulated training run of job %s: checksum %s\n", job.ID, checksum)))
+	return Result{
+		ArtifactURI: fmt.Sprintf("s3://ai-infra-dev/artifacts/%s.model", job.ID),
+		Checksum:    checksum,
+	}, nil
+}
+
+// Environment variables a training entrypoint receives.
+const (
+	EnvJobID       = "AI_INFRA_JOB_ID"
+	EnvCodeRef     = "AI_INFRA_CODE_REF"
+	EnvHyperparams = "AI_INFRA_HYPERPARAMS"
+	EnvDatasetRefs = "AI_INFRA_DATASET_REFS"
+	EnvSeed        = "AI_INFRA_SEED"
+	EnvOutputDir   = "AI_INFRA_OUTPUT_DIR"
+	EnvOutputPath  = "AI_INFRA_OUTPUT_PATH"
+	// EnvHyperparamPrefix prefixes one variable per scalar top-level hyperparameter,
+	// e.g. AI_INFRA_HP_LR=0.01.
+	EnvHyperparamPrefix = "AI_INFRA_HP_"
+)
+
+var envNameUnsafe = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// jobEnv returns the environment of a training run writing its artifact to outputPath.
+func jobEnv(job models.TrainingJob, outputDir, outputPath string) ([]string, error) {
+	hyper, err := canonicalJSON(job.Hyperparams, "{}")
+	if err != nil {
+		return nil, fmt.Errorf("hyperparams: %w", err)
+	}
+	datasets, err := canonicalJSON(job.DatasetRefs, "[]")
+	if err != nil {
+		return nil, fmt.Errorf("datasetRefs: %w", err)
+	}
+	env := []string{
+		EnvJobID + "=" + job.ID.String(),
+		EnvCodeRef + "=" + job.CodeRef,
+		EnvHyperparams + "=" + hyper,
+		EnvDatasetRefs + "=" + datasets,
+		EnvSeed + "=" + strconv.FormatInt(job.Seed, 10),
+		EnvOutputDir + "=" + outputDir,
+		EnvOutputPath + "=" + outputPath,
+	}
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(hyper), &params); err != nil {
+		return env, nil // not an object; only the JSON form is passed
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		raw := strings.TrimSpace(string(params[name]))
+		if raw == "" || raw[0] == '{' || raw[0] == '[' || raw == "null" {
+			continue
+		}
+		value := raw
+		var s string
+		if json.Unmarshal(params[name], &s) == nil {
+			value = s
+		}
+		env = append(env, EnvHyperparamPrefix+envNameUnsafe.ReplaceAllString(strings.ToUpper(name), "_")+"="+value)
+	}
+	return env, nil
+}
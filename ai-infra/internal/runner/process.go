@@ -0,0 +1,283 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+)
+
+const (
+	artifactFileName = "model"
+	logFileName      = "train.log"
+	// containerOutputDir is where the job directory is mounted inside a training container.
+	containerOutputDir = "/ai-infra/output"
+	logTailBytes       = 2048
+	// containerRemoveTimeout bounds removing a job's container when its run is cancelled.
+	containerRemoveTimeout = 30 * time.Second
+)
+
+// ProcessConfig configures the executors that run training as a child process.
+type ProcessConfig struct {
+	// WorkDir holds one directory per job with its artifact and log. Defaults to
+	// ai-infra-runs under the system temp directory.
+	WorkDir string
+	// Timeout bounds a single training run; zero means no limit.
+	Timeout time.Duration
+	// Env is added to the environment of every run. A local run otherwise inherits only
+	// the variables in inheritedEnv, so service credentials never reach job code.
+	Env []string
+	// EntrypointRoots are the directories the local executor runs codeRefs from; a
+	// codeRef resolving outside all of them is refused.
+	EntrypointRoots []string
+	// ContainerRuntime is the CLI the container executor invokes (default docker).
+	ContainerRuntime string
+	// ContainerImage is the repository the container executor runs a job's
+	// containerDigest from, e.g. registry.example.com/trainer.
+	ContainerImage string
+}
+
+// processExecutor runs a training command, captures its output to the job's log file and
+// hashes the artifact it leaves in the job directory.
+type processExecutor struct {
+	name    string
+	cfg     ProcessConfig
+	command func(ctx context.Context, job models.TrainingJob, dir string) (*exec.Cmd, error)
+}
+
+// NewLocalExecutor returns an executor that runs a job's codeRef as a local program. The
+// codeRef must be a file:// URI or a path to an executable entrypoint (a script with a
+// shebang works) under one of cfg.EntrypointRoots; it receives the AI_INFRA_* variables
+// and must write its artifact to $AI_INFRA_OUTPUT_PATH.
+func NewLocalExecutor(cfg ProcessConfig) Executor {
+	e := &processExecutor{name: ExecutorLocal, cfg: withProcessDefaults(cfg)}
+	e.command = func(ctx context.Context, job models.TrainingJob, dir string) (*exec.Cmd, error) {
+		entrypoint, err := localEntrypoint(job.CodeRef, e.cfg.EntrypointRoots)
+		if err != nil {
+			return nil, err
+		}
+		env, err := jobEnv(job, dir, filepath.Join(dir, artifactFileName))
+		if err != nil {
+			return nil, err
+		}
+		cmd := exec.CommandContext(ctx, entrypoint)
+		cmd.Dir = dir
+		cmd.Env = append(append(baseEnv(), e.cfg.Env...), env...)
+		return cmd, nil
+	}
+	return e
+}
+
+// NewContainerExecutor returns an executor that runs a job's image, ContainerImage pinned
+// to the job's containerDigest, with the job directory mounted at /ai-infra/output. The
+// image's entrypoint receives the same variables as a local run. The container is named
+// ai-infra-<job id>; cancelling or timing out the run removes it with `rm -f`, since
+// killing the runtime CLI alone leaves the container running.
+func NewContainerExecutor(cfg ProcessConfig) Executor {
+	e := &processExecutor{name: ExecutorContainer, cfg: withProcessDefaults(cfg)}
+	e.command = func(ctx context.Context, job models.TrainingJob, dir string) (*exec.Cmd, error) {
+		if e.cfg.ContainerImage == "" {
+			return nil, fmt.Errorf("container executor requires an image repository")
+		}
+		if !strings.HasPrefix(job.ContainerDigest, "sha256:") {
+			return nil, fmt.Errorf("containerDigest %q is not a sha256 digest", job.ContainerDigest)
+		}
+		env, err := jobEnv(job, containerOutputDir, containerOutputDir+"/"+artifactFileName)
+		if err != nil {
+			return nil, err
+		}
+		name := containerName(job)
+		// A container left by an earlier attempt on this host would block the name.
+		e.removeContainer(name)
+		args := []string{"run", "--rm", "--name", name, "-v", dir + ":" + containerOutputDir}
+		for _, kv := range append(append([]string(nil), e.cfg.Env...), env...) {
+			args = append(args, "-e", kv)
+		}
+		args = append(args, e.cfg.ContainerImage+"@"+job.ContainerDigest)
+		cmd := exec.CommandContext(ctx, e.cfg.ContainerRuntime, args...)
+		cmd.Cancel = func() error {
+			e.removeContainer(name)
+			return cmd.Process.Kill()
+		}
+		return cmd, nil
+	}
+	return e
+}
+
+func containerName(job models.TrainingJob) string {
+	return "ai-infra-" + job.ID.String()
+}
+
+// removeContainer force-removes (and so stops) a container, ignoring one that does not
+// exist.
+func (e *processExecutor) removeContainer(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), containerRemoveTimeout)
+	defer cancel()
+	_ = exec.CommandContext(ctx, e.cfg.ContainerRuntime, "rm", "-f", name).Run()
+}
+
+// DefaultWorkDir is the directory process executors keep job runs in when
+// ProcessConfig.WorkDir is unset.
+func DefaultWorkDir() string {
+	return filepath.Join(os.TempDir(), "ai-infra-runs")
+}
+
+func withProcessDefaults(cfg ProcessConfig) ProcessConfig {
+	if cfg.WorkDir == "" {
+		cfg.WorkDir = DefaultWorkDir()
+	}
+	if cfg.ContainerRuntime == "" {
+		cfg.ContainerRuntime = "docker"
+	}
+	return cfg
+}
+
+// inheritedEnv lists the service's environment variables a local run inherits.
+var inheritedEnv = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TZ", "TMPDIR"}
+
+func baseEnv() []string {
+	var env []string
+	for _, name := range inheritedEnv {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return env
+}
+
+// localEntrypoint resolves codeRef, following symlinks, and refuses it unless it lies
+// under one of roots.
+func localEntrypoint(codeRef string, roots []string) (string, error) {
+	path := strings.TrimPrefix(codeRef, "file://")
+	if path == "" || strings.Contains(path, "://") {
+		return "", fmt.Errorf("local executor cannot run codeRef %q: use a file:// URI or a path", codeRef)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return "", fmt.Errorf("local executor cannot run codeRef %q: %w", codeRef, err)
+	}
+	for _, root := range roots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("local executor cannot run codeRef %q: it is outside the entrypoint roots", codeRef)
+}
+
+func (e *processExecutor) Name() string { return e.name }
+
+func (e *processExecutor) Execute(ctx context.Context, job models.TrainingJob, sink Sink) (Result, error) {
+	dir, err := filepath.Abs(filepath.Join(e.cfg.WorkDir, job.ID.String()))
+	if err != nil {
+		return Result{}, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Result{}, fmt.Errorf("create job directory: %w", err)
+	}
+	artifactPath := filepath.Join(dir, artifactFileName)
+	if err := os.Remove(artifactPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Result{}, fmt.Errorf("remove stale artifact: %w", err)
+	}
+	logPath := filepath.Join(dir, logFileName)
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return Result{}, fmt.Errorf("create job log: %w", err)
+	}
+	defer logFile.Close()
+
+	if e.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.Timeout)
+		defer cancel()
+	}
+	cmd, err := e.command(ctx, job, dir)
+	if err != nil {
+		return Result{}, err
+	}
+	tail := &tailBuffer{max: logTailBytes}
+	out := &lockedWriter{w: io.MultiWriter(logFile, tail)}
+	stdout, stderr := newStreamWriter(models.LogStdout, sink), newStreamWriter(models.LogStderr, sink)
+	cmd.Stdout = io.MultiWriter(out, stdout)
+	cmd.Stderr = io.MultiWriter(out, stderr)
+	cmd.WaitDelay = 5 * time.Second
+
+	started := time.Now()
+	runErr := cmd.Run()
+	duration := time.Since(started)
+	stdout.Close()
+	stderr.Close()
+	if runErr != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return Result{}, fmt.Errorf("training run exceeded %s", e.cfg.Timeout)
+		}
+		return Result{}, fmt.Errorf("training run failed: %v: %s", runErr, tail.String())
+	}
+	checksum, size, err := hashFile(artifactPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return Result{}, fmt.Errorf("training run wrote no artifact to %s", artifactPath)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("hash artifact: %w", err)
+	}
+	return Result{
+		ArtifactURI: "file://" + artifactPath,
+		Checksum:    checksum,
+		Metadata: map[string]interface{}{
+			"sizeBytes":  size,
+			"durationMs": duration.Milliseconds(),
+			"logPath":    logPath,
+		},
+	}, nil
+}
+
+// hashFile returns the hex sha256 and size of the file at path.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// tailBuffer keeps the last max bytes written to it for error messages.
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	return strings.TrimSpace(string(t.buf))
+}
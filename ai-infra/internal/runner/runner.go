@@ -1,7 +1,6 @@
 package runner
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -10,8 +9,8 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"sort"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,48 +18,86 @@ import (
 	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/canonical"
 )
 
 type Config struct {
 	PollInterval time.Duration
 	Logger       *log.Logger
+	// Executor trains claimed jobs; the simulated executor is used when nil.
+	Executor Executor
+	// WorkerID identifies this worker in job leases; it defaults to <hostname>-<pid>.
+	WorkerID string
+	// HeartbeatInterval is how often a running job's lease is renewed; it defaults to a
+	// third of the service's lease duration.
+	HeartbeatInterval time.Duration
+	// LogFlushInterval is how often a run's buffered logs and metrics are sent to the
+	// service (default 1s).
+	LogFlushInterval time.Duration
 }
 
-// RunWorker continuously polls for queued training jobs and executes them until ctx is cancelled.
-func RunWorker(ctx context.Context, svc *service.Service, st store.Store, cfg Config) {
-	interval := cfg.PollInterval
-	if interval <= 0 {
-		interval = 2 * time.Second
+func (cfg Config) withDefaults(svc *service.Service) Config {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.New(os.Stdout, "[trainer] ", log.LstdFlags)
+	}
+	if cfg.Executor == nil {
+		cfg.Executor = SimulatedExecutor{}
+	}
+	if cfg.WorkerID == "" {
+		host, _ := os.Hostname()
+		cfg.WorkerID = fmt.Sprintf("%s-%d", host, os.Getpid())
 	}
-	logger := cfg.Logger
-	if logger == nil {
-		logger = log.New(os.Stdout, "[trainer] ", log.LstdFlags)
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = svc.JobPolicy().LeaseDuration / 3
 	}
+	if cfg.LogFlushInterval <= 0 {
+		cfg.LogFlushInterval = time.Second
+	}
+	return cfg
+}
 
+// RunWorker continuously polls for queued training jobs and executes them until ctx is cancelled.
+func RunWorker(ctx context.Context, svc *service.Service, cfg Config) {
+	cfg = cfg.withDefaults(svc)
 	for {
 		if ctx.Err() != nil {
 			return
 		}
-		processed, err := ProcessNextJob(ctx, svc, st)
+		processed, err := ExecuteNextJob(ctx, svc, cfg)
 		if err != nil {
-			logger.Printf("process training job: %v", err)
+			cfg.Logger.Printf("process training job: %v", err)
 		}
 		if !processed {
 			select {
 			case <-ctx.Done():
 				return
-			case <-time.After(interval):
+			case <-time.After(cfg.PollInterval):
 			}
 		}
 	}
 }
 
-// ProcessNextJob claims, executes, and finalizes a single training job, returning whether work was done.
-func ProcessNextJob(ctx context.Context, svc *service.Service, st store.Store) (bool, error) {
+// ProcessNextJob claims and finalizes a single training job with the simulated executor,
+// returning whether work was done.
+func ProcessNextJob(ctx context.Context, svc *service.Service) (bool, error) {
+	return ExecuteNextJob(ctx, svc, Config{})
+}
+
+// ExecuteNextJob claims a single training job, runs it with the configured executor while
+// heartbeating its lease and streaming its logs and metrics to the service, and registers
+// the artifact it produced with the run's final metrics, returning whether work was done.
+// A reproduction job's output is checked against the artifact it reproduces instead. A
+// failed run is handed back to the service for retry; a run that outlives the job's max
+// runtime times out, and a cancelled job's run is stopped.
+func ExecuteNextJob(ctx context.Context, svc *service.Service, cfg Config) (bool, error) {
+	cfg = cfg.withDefaults(svc)
 	if ctx.Err() != nil {
 		return false, ctx.Err()
 	}
-	job, err := st.ClaimNextTrainingJob(ctx)
+	job, err := svc.ClaimTrainingJob(ctx, cfg.WorkerID)
 	if errors.Is(err, store.ErrNotFound) {
 		return false, nil
 	}
@@ -68,39 +105,128 @@ func ProcessNextJob(ctx context.Context, svc *service.Service, st store.Store) (
 		return false, err
 	}
 
-	checksum, err := ComputeArtifactChecksum(job)
-	if err != nil {
-		_ = markJob(ctx, st, job.ID, "failed")
-		return true, err
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if job.MaxRuntimeSeconds > 0 && job.StartedAt != nil {
+		var cancelDeadline context.CancelFunc
+		runCtx, cancelDeadline = context.WithDeadline(runCtx, job.StartedAt.Add(time.Duration(job.MaxRuntimeSeconds)*time.Second))
+		defer cancelDeadline()
+	}
+	sink := newJobSink(job)
+	lost := make(chan error, 1)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		heartbeat(runCtx, svc, job.ID, cfg, cancel, lost)
+	}()
+	go func() {
+		defer wg.Done()
+		flushOutput(runCtx, svc, sink, cfg)
+	}()
+	result, runErr := cfg.Executor.Execute(runCtx, job, sink)
+	timedOut := errors.Is(runCtx.Err(), context.DeadlineExceeded)
+	cancel()
+	wg.Wait()
+	// The run's last output is stored before the job finishes, so a client following the
+	// logs sees all of it before the job leaves running.
+	if err := sink.flush(ctx, svc); err != nil {
+		cfg.Logger.Printf("store output of job %s: %v", job.ID, err)
+	}
+
+	select {
+	case err := <-lost:
+		// Cancelled or taken over: the job is no longer ours to finish.
+		return true, fmt.Errorf("job %s: %w", job.ID, err)
+	default:
+	}
+	if timedOut {
+		_, err := svc.TimeOutTrainingJob(ctx, job.ID, cfg.WorkerID)
+		return true, errors.Join(fmt.Errorf("job %s exceeded its max runtime of %ds", job.ID, job.MaxRuntimeSeconds), err)
+	}
+	if runErr != nil {
+		runErr = fmt.Errorf("%s executor: job %s: %w", cfg.Executor.Name(), job.ID, runErr)
+		_, err := svc.FailTrainingJob(ctx, job.ID, cfg.WorkerID, runErr)
+		return true, errors.Join(runErr, err)
 	}
 
-	artifactURI := fmt.Sprintf("s3://ai-infra-dev/artifacts/%s.model", job.ID)
-	metaPayload := map[string]interface{}{
-		"runner":      "local",
-		"status":      "completed",
-		"completedAt": time.Now().UTC().Format(time.RFC3339Nano),
+	if job.ReproducesArtifactID != nil {
+		// A reproduction's output is compared with the artifact it reproduces, not registered.
+		if _, err := svc.CompleteReproduction(ctx, job.ID, cfg.WorkerID, result.Checksum); err != nil {
+			return true, fmt.Errorf("complete reproduction job %s: %w", job.ID, err)
+		}
+		return true, nil
+	}
+
+	metaPayload := map[string]interface{}{}
+	for k, v := range result.Metadata {
+		metaPayload[k] = v
+	}
+	metaPayload["runner"] = cfg.Executor.Name()
+	metaPayload["workerId"] = cfg.WorkerID
+	metaPayload["attempt"] = job.Attempts
+	metaPayload["status"] = string(models.JobSucceeded)
+	metaPayload["completedAt"] = time.Now().UTC().Format(time.RFC3339Nano)
+	if metrics, step, ok := sink.finalMetrics(); ok {
+		metaPayload["metrics"] = metrics
+		metaPayload["metricsStep"] = step
 	}
 	metaBytes, _ := json.Marshal(metaPayload)
 
 	_, err = svc.RegisterArtifact(ctx, service.RegisterArtifactRequest{
 		TrainingJobID: job.ID,
-		ArtifactURI:   artifactURI,
-		Checksum:      checksum,
+		ArtifactURI:   result.ArtifactURI,
+		Checksum:      result.Checksum,
 		Metadata:      json.RawMessage(metaBytes),
 	})
 	if err != nil {
-		_ = markJob(ctx, st, job.ID, "failed")
-		return true, err
+		_, failErr := svc.FailTrainingJob(ctx, job.ID, cfg.WorkerID, fmt.Errorf("register artifact: %w", err))
+		return true, errors.Join(err, failErr)
 	}
-	if _, err := st.UpdateTrainingJobStatus(ctx, job.ID, "completed"); err != nil {
+	if _, err := svc.SucceedTrainingJob(ctx, job.ID, cfg.WorkerID); err != nil {
 		return true, err
 	}
 	return true, nil
 }
 
-func markJob(ctx context.Context, st store.Store, id uuid.UUID, status string) error {
-	_, err := st.UpdateTrainingJobStatus(ctx, id, status)
-	return err
+// heartbeat renews the lease on a running job until ctx ends. When the lease is lost it
+// reports the error on lost and cancels the run.
+func heartbeat(ctx context.Context, svc *service.Service, id uuid.UUID, cfg Config, cancel context.CancelFunc, lost chan<- error) {
+	ticker := time.NewTicker(cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job, err := svc.HeartbeatTrainingJob(ctx, id, cfg.WorkerID)
+			if errors.Is(err, service.ErrLeaseLost) {
+				lost <- fmt.Errorf("%w (job is %s)", err, job.Status)
+				cancel()
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				cfg.Logger.Printf("heartbeat job %s: %v", id, err)
+			}
+		}
+	}
+}
+
+// flushOutput sends a run's buffered output to the service every LogFlushInterval until
+// ctx ends.
+func flushOutput(ctx context.Context, svc *service.Service, sink *jobSink, cfg Config) {
+	ticker := time.NewTicker(cfg.LogFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sink.flush(ctx, svc); err != nil && ctx.Err() == nil {
+				cfg.Logger.Printf("store output of job %s: %v", sink.jobID, err)
+			}
+		}
+	}
 }
 
 // ComputeArtifactChecksum returns the deterministic checksum for a job definition.
@@ -122,68 +248,9 @@ func canonicalJSON(raw json.RawMessage, fallback string) (string, error) {
 	if len(raw) == 0 {
 		raw = json.RawMessage(fallback)
 	}
-	var v interface{}
-	if err := json.Unmarshal(raw, &v); err != nil {
-		return "", err
-	}
-	buf := &bytes.Buffer{}
-	if err := encodeCanonical(buf, v); err != nil {
+	b, err := canonical.Transform(raw)
+	if err != nil {
 		return "", err
 	}
-	return buf.String(), nil
-}
-
-func encodeCanonical(buf *bytes.Buffer, v interface{}) error {
-	switch val := v.(type) {
-	case map[string]interface{}:
-		keys := make([]string, 0, len(val))
-		for k := range val {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		buf.WriteByte('{')
-		for i, k := range keys {
-			if i > 0 {
-				buf.WriteByte(',')
-			}
-			keyBytes, err := json.Marshal(k)
-			if err != nil {
-				return err
-			}
-			buf.Write(keyBytes)
-			buf.WriteByte(':')
-			if err := encodeCanonical(buf, val[k]); err != nil {
-				return err
-			}
-		}
-		buf.WriteByte('}')
-	case []interface{}:
-		buf.WriteByte('[')
-		for i, elem := range val {
-			if i > 0 {
-				buf.WriteByte(',')
-			}
-			if err := encodeCanonical(buf, elem); err != nil {
-				return err
-			}
-		}
-		buf.WriteByte(']')
-	case nil:
-		buf.WriteString("null")
-	case json.Number:
-		buf.WriteString(val.String())
-	case string, float64, bool:
-		b, err := json.Marshal(val)
-		if err != nil {
-			return err
-		}
-		buf.Write(b)
-	default:
-		b, err := json.Marshal(val)
-		if err != nil {
-			return err
-		}
-		buf.Write(b)
-	}
-	return nil
+	return string(b), nil
 }
@@ -0,0 +1,234 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/service"
+)
+
+// MetricLinePrefix marks a line of a run's stdout that reports metrics. The rest of the
+// line is a JSON object of numbers whose "step" field is the training step, e.g.
+//
+//	AI_INFRA_METRIC {"step":100,"loss":0.42,"throughput":1850}
+const MetricLinePrefix = "AI_INFRA_METRIC "
+
+const (
+	// logChunkBytes bounds how much consecutive output of a stream is sent as one chunk.
+	logChunkBytes = 16 << 10
+	// maxLineBytes bounds the partial line kept while looking for metric lines.
+	maxLineBytes = 64 << 10
+)
+
+// Sink receives a training run's output while it runs. Implementations must be safe for
+// concurrent use and must not retain p.
+type Sink interface {
+	// Log records output the run wrote to stream (stdout or stderr).
+	Log(stream string, p []byte)
+	// Metric records a metrics sample taken at a training step.
+	Metric(step int64, metrics map[string]float64)
+}
+
+// streamWriter forwards a run's output stream to a sink. On stdout it also reports the
+// metric lines it sees.
+type streamWriter struct {
+	stream  string
+	sink    Sink
+	metrics bool
+	line    []byte
+}
+
+func newStreamWriter(stream string, sink Sink) *streamWriter {
+	return &streamWriter{stream: stream, sink: sink, metrics: stream == models.LogStdout}
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.sink.Log(w.stream, p)
+	if !w.metrics {
+		return len(p), nil
+	}
+	w.line = append(w.line, p...)
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+		if i < 0 {
+			break
+		}
+		w.parseLine(w.line[:i])
+		w.line = w.line[i+1:]
+	}
+	if len(w.line) > maxLineBytes {
+		w.line = w.line[:0] // too long to be a metric line
+	}
+	return len(p), nil
+}
+
+// Close reports a metric line the run ended without terminating.
+func (w *streamWriter) Close() error {
+	if w.metrics && len(w.line) > 0 {
+		w.parseLine(w.line)
+	}
+	w.line = nil
+	return nil
+}
+
+// parseLine reports line to the sink if it is a well-formed metric line; anything else is
+// only logged.
+func (w *streamWriter) parseLine(line []byte) {
+	rest, ok := bytes.CutPrefix(bytes.TrimRight(line, "\r"), []byte(MetricLinePrefix))
+	if !ok {
+		return
+	}
+	var values map[string]float64
+	if err := json.Unmarshal(rest, &values); err != nil {
+		return
+	}
+	var step int64
+	if v, ok := values["step"]; ok {
+		step = int64(v)
+		delete(values, "step")
+	}
+	for name, v := range values {
+		if strings.TrimSpace(name) == "" || math.IsNaN(v) || math.IsInf(v, 0) {
+			delete(values, name)
+		}
+	}
+	if len(values) > 0 {
+		w.sink.Metric(step, values)
+	}
+}
+
+// lockedWriter serializes writes from a run's stdout and stderr copiers.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// jobSink buffers a run's output and sends it to the service in batches. It keeps the
+// latest value of each metric for the artifact metadata. Log content is stored as text,
+// so chunks are cut on rune boundaries, a rune split across writes is held until its
+// remaining bytes arrive and invalid UTF-8 is replaced with U+FFFD.
+type jobSink struct {
+	jobID   uuid.UUID
+	attempt int
+
+	mu      sync.Mutex
+	logs    []models.JobLogChunk
+	partial map[string][]byte
+	points  []models.JobMetricPoint
+	final   map[string]float64
+	step    int64
+	reports bool
+}
+
+func newJobSink(job models.TrainingJob) *jobSink {
+	return &jobSink{jobID: job.ID, attempt: job.Attempts, final: map[string]float64{}, partial: map[string][]byte{}}
+}
+
+func (s *jobSink) Log(stream string, p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p = append(s.partial[stream], p...)
+	tail := incompleteRune(p)
+	s.partial[stream] = append([]byte(nil), p[len(p)-tail:]...)
+	text := strings.ToValidUTF8(string(p[:len(p)-tail]), "\uFFFD")
+	for len(text) > 0 {
+		n := len(s.logs)
+		if n == 0 || s.logs[n-1].Stream != stream || len(s.logs[n-1].Content) >= logChunkBytes {
+			s.logs = append(s.logs, models.JobLogChunk{Attempt: s.attempt, Stream: stream, CreatedAt: time.Now().UTC()})
+			n++
+		}
+		take := min(logChunkBytes-len(s.logs[n-1].Content), len(text))
+		for take < len(text) && !utf8.RuneStart(text[take]) {
+			take--
+		}
+		if take == 0 {
+			// The next rune does not fit in what is left of this chunk.
+			s.logs = append(s.logs, models.JobLogChunk{Attempt: s.attempt, Stream: stream, CreatedAt: time.Now().UTC()})
+			continue
+		}
+		s.logs[n-1].Content += text[:take]
+		text = text[take:]
+	}
+}
+
+// incompleteRune returns the length of the UTF-8 sequence p ends in when it is cut
+// short, so a later write may complete it, and 0 otherwise.
+func incompleteRune(p []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		if utf8.RuneStart(p[len(p)-i]) {
+			if utf8.FullRune(p[len(p)-i:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+func (s *jobSink) Metric(step int64, metrics map[string]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := make(map[string]float64, len(metrics))
+	for name, v := range metrics {
+		copied[name] = v
+		s.final[name] = v
+	}
+	s.step, s.reports = step, true
+	s.points = append(s.points, models.JobMetricPoint{Attempt: s.attempt, Step: step, Metrics: copied, RecordedAt: time.Now().UTC()})
+}
+
+// flush sends the buffered output to the service. Output that fails to send is dropped
+// rather than held, so a struggling store cannot grow the worker's memory.
+func (s *jobSink) flush(ctx context.Context, svc *service.Service) error {
+	s.mu.Lock()
+	logs, points := s.logs, s.points
+	s.logs, s.points = nil, nil
+	s.mu.Unlock()
+	var errs []error
+	if len(logs) > 0 {
+		if _, err := svc.AppendJobLogs(ctx, s.jobID, logs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(points) > 0 {
+		if _, err := svc.RecordJobMetrics(ctx, s.jobID, points); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// finalMetrics returns the latest value of each metric the run reported and the step of
+// its last sample; ok is false when it reported none.
+func (s *jobSink) finalMetrics() (metrics map[string]float64, step int64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.reports {
+		return nil, 0, false
+	}
+	metrics = make(map[string]float64, len(s.final))
+	for name, v := range s.final {
+		metrics[name] = v
+	}
+	return metrics, s.step, true
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 type HTTPClientConfig struct {
@@ -38,6 +40,7 @@ func NewHTTPClient(cfg HTTPClientConfig) (*HTTPClient, error) {
 	if client == nil {
 		client = &http.Client{Timeout: 10 * time.Second}
 	}
+	client = tracing.WrapClient(client)
 	timeout := cfg.Timeout
 	if timeout <= 0 {
 		timeout = 5 * time.Second
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/storage"
+)
+
+const (
+	defaultDownloadURLTTL = 15 * time.Minute
+	// maxDownloadURLTTL is the longest expiry S3 accepts for a presigned URL.
+	maxDownloadURLTTL = 7 * 24 * time.Hour
+	// blobGCBatch bounds the URIs checked against the registry per query.
+	blobGCBatch = 500
+)
+
+var (
+	// ErrBlobStoreDisabled is returned by blob operations when no blob store is configured.
+	ErrBlobStoreDisabled = errors.New("artifact blob store not configured")
+	// ErrNotInBlobStore is returned for download URLs of artifacts stored elsewhere.
+	ErrNotInBlobStore = errors.New("artifact is not held by the blob store")
+)
+
+// SetBlobStore configures where uploaded artifact content is stored. Registering an
+// artifact with a URI of the store then requires the blob to exist and match the checksum.
+func (s *Service) SetBlobStore(bs storage.BlobStore) {
+	s.blobs = bs
+}
+
+// UploadBlob stores artifact content and returns its content address. A non-empty
+// wantSHA256 rejects content with another hash.
+func (s *Service) UploadBlob(ctx context.Context, r io.Reader, wantSHA256 string) (storage.Blob, error) {
+	if s.blobs == nil {
+		return storage.Blob{}, ErrBlobStoreDisabled
+	}
+	if wantSHA256 != "" {
+		sum, err := storage.NormalizeSHA256(wantSHA256)
+		if err != nil {
+			return storage.Blob{}, err
+		}
+		wantSHA256 = sum
+	}
+	blob, err := s.blobs.Put(ctx, r, wantSHA256)
+	if err != nil {
+		return storage.Blob{}, err
+	}
+	s.emitAudit(ctx, "registry.blob.uploaded", map[string]interface{}{
+		"sha256": blob.SHA256,
+		"size":   blob.Size,
+		"uri":    blob.URI,
+	})
+	return blob, nil
+}
+
+// GetBlob returns the blob stored under a sha256.
+func (s *Service) GetBlob(ctx context.Context, sum string) (storage.Blob, error) {
+	if s.blobs == nil {
+		return storage.Blob{}, ErrBlobStoreDisabled
+	}
+	sum, err := storage.NormalizeSHA256(sum)
+	if err != nil {
+		return storage.Blob{}, storage.ErrBlobNotFound
+	}
+	return s.blobs.Stat(ctx, sum)
+}
+
+// DownloadURL is a presigned URL that downloads a blob without credentials.
+type DownloadURL struct {
+	SHA256    string    `json:"sha256"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// PresignBlob returns a download URL for a blob valid for ttl (default 15 minutes,
+// at most 7 days).
+func (s *Service) PresignBlob(ctx context.Context, sum string, ttl time.Duration) (DownloadURL, error) {
+	blob, err := s.GetBlob(ctx, sum)
+	if err != nil {
+		return DownloadURL{}, err
+	}
+	if ttl <= 0 {
+		ttl = defaultDownloadURLTTL
+	}
+	if ttl > maxDownloadURLTTL {
+		ttl = maxDownloadURLTTL
+	}
+	expires := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	u, err := s.blobs.PresignGet(ctx, blob.SHA256, ttl)
+	if err != nil {
+		return DownloadURL{}, err
+	}
+	return DownloadURL{SHA256: blob.SHA256, URL: u, ExpiresAt: expires}, nil
+}
+
+// PresignArtifact returns a download URL for an artifact held by the blob store.
+func (s *Service) PresignArtifact(ctx context.Context, id uuid.UUID, ttl time.Duration) (DownloadURL, error) {
+	artifact, err := s.store.GetArtifact(ctx, id)
+	if err != nil {
+		return DownloadURL{}, err
+	}
+	if s.blobs == nil {
+		return DownloadURL{}, ErrBlobStoreDisabled
+	}
+	sum, ok := s.blobs.ParseURI(artifact.ArtifactURI)
+	if !ok {
+		return DownloadURL{}, ErrNotInBlobStore
+	}
+	return s.PresignBlob(ctx, sum, ttl)
+}
+
+// OpenPresignedBlob opens a blob for a download URL the service issued itself. Stores
+// whose URLs point at the backend return storage.ErrPresignUnsupported.
+func (s *Service) OpenPresignedBlob(ctx context.Context, sum string, query url.Values) (io.ReadCloser, storage.Blob, error) {
+	opener, ok := s.blobs.(storage.PresignedOpener)
+	if !ok {
+		return nil, storage.Blob{}, storage.ErrPresignUnsupported
+	}
+	return opener.OpenPresigned(ctx, sum, query)
+}
+
+// checkBlobArtifact rejects registering a blob store URI that holds no blob or whose
+// content hashes to something other than checksum, and returns the URI to store: the
+// store's canonical form for blob URIs, so garbage collection finds the reference, and
+// uri unchanged otherwise. Other URIs are not checked.
+func (s *Service) checkBlobArtifact(ctx context.Context, uri, checksum string) (string, error) {
+	if s.blobs == nil {
+		return uri, nil
+	}
+	sum, ok := s.blobs.ParseURI(uri)
+	if !ok {
+		return uri, nil
+	}
+	if normalizeChecksum(checksum) != sum {
+		return "", fmt.Errorf("%w: artifact %s has sha256 %s, not %s", storage.ErrChecksumMismatch, uri, sum, checksum)
+	}
+	if _, err := s.blobs.Stat(ctx, sum); err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return "", fmt.Errorf("artifact blob %s has not been uploaded", sum)
+		}
+		return "", err
+	}
+	return s.blobs.URI(sum), nil
+}
+
+// BlobGCResult reports a garbage collection of the blob store.
+type BlobGCResult struct {
+	Scanned    int            `json:"scanned"`
+	Deleted    []storage.Blob `json:"deleted"`
+	FreedBytes int64          `json:"freedBytes"`
+	DryRun     bool           `json:"dryRun"`
+}
+
+// CollectBlobGarbage deletes blobs no artifact references. Blobs younger than grace are
+// kept so content uploaded ahead of its registration survives; with dryRun nothing is
+// deleted.
+func (s *Service) CollectBlobGarbage(ctx context.Context, now time.Time, grace time.Duration, dryRun bool) (BlobGCResult, error) {
+	if s.blobs == nil {
+		return BlobGCResult{}, ErrBlobStoreDisabled
+	}
+	blobs, err := s.blobs.List(ctx)
+	if err != nil {
+		return BlobGCResult{}, err
+	}
+	result := BlobGCResult{Scanned: len(blobs), Deleted: []storage.Blob{}, DryRun: dryRun}
+	var candidates []storage.Blob
+	for _, blob := range blobs {
+		if now.Sub(blob.CreatedAt) >= grace {
+			candidates = append(candidates, blob)
+		}
+	}
+	for start := 0; start < len(candidates); start += blobGCBatch {
+		batch := candidates[start:min(start+blobGCBatch, len(candidates))]
+		uris := make([]string, len(batch))
+		for i, blob := range batch {
+			uris[i] = blob.URI
+		}
+		referenced, err := s.store.ReferencedArtifactURIs(ctx, uris)
+		if err != nil {
+			return result, err
+		}
+		for _, blob := range batch {
+			if referenced[blob.URI] {
+				continue
+			}
+			if !dryRun {
+				if err := s.blobs.Delete(ctx, blob.SHA256); err != nil && !errors.Is(err, storage.ErrBlobNotFound) {
+					return result, err
+				}
+			}
+			result.Deleted = append(result.Deleted, blob)
+			result.FreedBytes += blob.Size
+		}
+	}
+	if !dryRun && len(result.Deleted) > 0 {
+		deleted := make([]string, len(result.Deleted))
+		for i, blob := range result.Deleted {
+			deleted[i] = blob.SHA256
+		}
+		s.emitAudit(ctx, "registry.blob.collected", map[string]interface{}{
+			"sha256":     deleted,
+			"freedBytes": result.FreedBytes,
+		})
+	}
+	return result, nil
+}
+
+// RunBlobGC collects unreferenced blobs older than grace every interval until ctx is
+// cancelled.
+func (s *Service) RunBlobGC(ctx context.Context, interval, grace time.Duration) {
+	log.Printf("[blobs] garbage collector starting (interval=%s grace=%s)", interval, grace)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[blobs] garbage collector stopped")
+			return
+		case <-ticker.C:
+			result, err := s.CollectBlobGarbage(ctx, time.Now(), grace, false)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("[blobs] collect: %v", err)
+			}
+			if len(result.Deleted) > 0 {
+				log.Printf("[blobs] deleted %d unreferenced blobs (%d bytes)", len(result.Deleted), result.FreedBytes)
+			}
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+// Canary policy defaults applied to zero fields of a requested policy.
+const (
+	defaultCanaryPercent     = 5
+	defaultCanaryWindow      = 60
+	defaultRollbackThreshold = 0.02
+	defaultCanaryMinSamples  = 5
+)
+
+// canaryController is recorded as the actor of canary decisions.
+const canaryController = "canary-controller"
+
+var (
+	// ErrCanaryInProgress is returned when a canary is already running for the environment.
+	ErrCanaryInProgress = errors.New("a canary is already running for this environment")
+	// ErrNotInCanary is returned when observations are reported for a promotion that is
+	// not (or no longer) in the canary state.
+	ErrNotInCanary = errors.New("promotion is not in canary")
+)
+
+// normalizeCanaryPolicy fills policy defaults and validates the result.
+func normalizeCanaryPolicy(p models.CanaryPolicy) (models.CanaryPolicy, error) {
+	if p.CanaryPercent == 0 {
+		p.CanaryPercent = defaultCanaryPercent
+	}
+	if p.WindowMinutes == 0 {
+		p.WindowMinutes = defaultCanaryWindow
+	}
+	if p.RollbackThreshold == nil {
+		threshold := float64(defaultRollbackThreshold)
+		p.RollbackThreshold = &threshold
+	}
+	if p.MinSamples == 0 {
+		p.MinSamples = defaultCanaryMinSamples
+	}
+	switch {
+	case p.CanaryPercent <= 0 || p.CanaryPercent > 100:
+		return p, fmt.Errorf("canaryPercent must be in (0, 100]")
+	case p.WindowMinutes < 0:
+		return p, fmt.Errorf("windowMinutes must be positive")
+	case *p.RollbackThreshold < 0:
+		return p, fmt.Errorf("rollbackThreshold must not be negative")
+	case p.MinSamples < 0:
+		return p, fmt.Errorf("minSamples must be positive")
+	}
+	return p, nil
+}
+
+// startCanary builds the initial canary state for promo, using the environment's latest
+// applied promotion as the incumbent.
+func (s *Service) startCanary(ctx context.Context, promo models.ModelPromotion, policy models.CanaryPolicy) (*models.Canary, error) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	canary := &models.Canary{
+		Policy:    policy,
+		StartedAt: now,
+		EndsAt:    now.Add(time.Duration(policy.WindowMinutes) * time.Minute),
+	}
+	detail := "no incumbent"
+	incumbent, err := s.store.LatestAppliedPromotion(ctx, promo.Environment)
+	switch {
+	case err == nil:
+		canary.IncumbentArtifactID = &incumbent.ArtifactID
+		canary.IncumbentPromotionID = &incumbent.ID
+		detail = "incumbent artifact " + incumbent.ArtifactID.String()
+	case !errors.Is(err, store.ErrNotFound):
+		return nil, err
+	}
+	canary.History = []models.CanaryEvent{{At: now, Event: "started", Detail: detail}}
+	return canary, nil
+}
+
+// checkNoCanary returns ErrCanaryInProgress when environment already has a running canary.
+// It only fails fast: two concurrent starts can both pass it, and the store's one canary
+// per environment constraint (store.ErrCanaryExists) decides between them.
+func (s *Service) checkNoCanary(ctx context.Context, environment string) error {
+	running, err := s.store.ListPromotionsByStatus(ctx, models.PromotionCanary)
+	if err != nil {
+		return err
+	}
+	for _, promo := range running {
+		if promo.Environment == environment {
+			return ErrCanaryInProgress
+		}
+	}
+	return nil
+}
+
+// CanaryObservationInput is one metrics sample for a canary arm.
+type CanaryObservationInput struct {
+	Arm        string             `json:"arm"`
+	Metrics    map[string]float64 `json:"metrics"`
+	ObservedAt *time.Time         `json:"observedAt,omitempty"`
+}
+
+// RecordCanaryObservations stores metric samples for a running canary. When both arms
+// already have MinSamples and a metric signal has regressed, the promotion is rolled back
+// immediately instead of waiting for the window to end.
+func (s *Service) RecordCanaryObservations(ctx context.Context, promotionID uuid.UUID, in []CanaryObservationInput) (models.ModelPromotion, error) {
+	if len(in) == 0 {
+		return models.ModelPromotion{}, fmt.Errorf("observations required")
+	}
+	for i, obs := range in {
+		if obs.Arm != models.ArmCanary && obs.Arm != models.ArmIncumbent {
+			return models.ModelPromotion{}, fmt.Errorf("observations[%d]: arm must be %q or %q", i, models.ArmCanary, models.ArmIncumbent)
+		}
+		if len(obs.Metrics) == 0 {
+			return models.ModelPromotion{}, fmt.Errorf("observations[%d]: metrics required", i)
+		}
+	}
+	promo, err := s.store.GetPromotion(ctx, promotionID)
+	if err != nil {
+		return models.ModelPromotion{}, err
+	}
+	if promo.Status != models.PromotionCanary || promo.Canary == nil {
+		return promo, ErrNotInCanary
+	}
+	if promo.Canary.IncumbentArtifactID == nil {
+		for i, obs := range in {
+			if obs.Arm == models.ArmIncumbent {
+				return promo, fmt.Errorf("observations[%d]: promotion has no incumbent", i)
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, obs := range in {
+		observedAt := now
+		if obs.ObservedAt != nil {
+			observedAt = obs.ObservedAt.UTC()
+		}
+		if err := s.store.AddCanaryObservation(ctx, models.CanaryObservation{
+			PromotionID: promotionID,
+			Arm:         obs.Arm,
+			Metrics:     obs.Metrics,
+			ObservedAt:  observedAt,
+		}); err != nil {
+			return promo, err
+		}
+	}
+	decided, _, err := s.evaluateCanary(ctx, promo, false)
+	if errors.Is(err, store.ErrStatusConflict) {
+		// The controller decided the canary concurrently.
+		return s.store.GetPromotion(ctx, promotionID)
+	}
+	return decided, err
+}
+
+// GetPromotion returns a promotion. For a running canary the arm aggregates and metric
+// comparisons are computed from the observations so far; they are persisted only when
+// the canary is decided.
+func (s *Service) GetPromotion(ctx context.Context, id uuid.UUID) (models.ModelPromotion, error) {
+	promo, err := s.store.GetPromotion(ctx, id)
+	if err != nil || promo.Status != models.PromotionCanary || promo.Canary == nil {
+		return promo, err
+	}
+	observations, err := s.store.ListCanaryObservations(ctx, id)
+	if err != nil {
+		return promo, err
+	}
+	canary := *promo.Canary
+	canary.CanaryArm, canary.IncumbentArm = aggregateArms(observations)
+	canary.Comparisons = compareArms(canary.Policy, canary.CanaryArm, canary.IncumbentArm)
+	promo.Canary = &canary
+	return promo, nil
+}
+
+// EvaluateCanaries decides every running canary whose window has ended by now and
+// returns the decided promotions. A failure on one canary is logged and does not stop
+// the others.
+func (s *Service) EvaluateCanaries(ctx context.Context, now time.Time) ([]models.ModelPromotion, error) {
+	running, err := s.store.ListPromotionsByStatus(ctx, models.PromotionCanary)
+	if err != nil {
+		return nil, err
+	}
+	var decided []models.ModelPromotion
+	for _, promo := range running {
+		if promo.Canary == nil || now.Before(promo.Canary.EndsAt) {
+			continue
+		}
+		updated, ok, err := s.evaluateCanary(ctx, promo, true)
+		if err != nil {
+			if !errors.Is(err, store.ErrStatusConflict) {
+				log.Printf("[canary] evaluate promotion %s: %v", promo.ID, err)
+			}
+			continue
+		}
+		if ok {
+			decided = append(decided, updated)
+		}
+	}
+	return decided, nil
+}
+
+// RunCanaryController evaluates running canaries every interval until ctx is done.
+func (s *Service) RunCanaryController(ctx context.Context, interval time.Duration) {
+	log.Printf("[canary] controller starting (interval=%s)", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[canary] controller stopped")
+			return
+		case <-ticker.C:
+			if _, err := s.EvaluateCanaries(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				log.Printf("[canary] evaluate: %v", err)
+			}
+		}
+	}
+}
+
+// evaluateCanary compares the arms of a running canary and decides it when a metric has
+// regressed or, if final, when its window has ended. It reports whether a decision was
+// made.
+//
+// The controller fails safe: at the end of the window a canary without MinSamples on
+// either arm, or without observations for a requested metric signal, is rolled back.
+func (s *Service) evaluateCanary(ctx context.Context, promo models.ModelPromotion, final bool) (models.ModelPromotion, bool, error) {
+	observations, err := s.store.ListCanaryObservations(ctx, promo.ID)
+	if err != nil {
+		return promo, false, err
+	}
+	canary := *promo.Canary
+	policy := canary.Policy
+	canary.CanaryArm, canary.IncumbentArm = aggregateArms(observations)
+	canary.Comparisons = compareArms(policy, canary.CanaryArm, canary.IncumbentArm)
+	hasIncumbent := canary.IncumbentArtifactID != nil
+
+	outcome, reason := "", ""
+	enough := canary.CanaryArm.Samples >= policy.MinSamples &&
+		(!hasIncumbent || canary.IncumbentArm.Samples >= policy.MinSamples)
+	if enough {
+		for _, c := range canary.Comparisons {
+			if c.Regressed {
+				outcome = models.PromotionRolledBack
+				reason = fmt.Sprintf("%s dropped %.4f relative to the incumbent (threshold %.4f)", c.Metric, c.RelativeDrop, rollbackThreshold(policy))
+				break
+			}
+		}
+	}
+	if outcome == "" && final {
+		switch {
+		case canary.CanaryArm.Samples < policy.MinSamples:
+			outcome = models.PromotionRolledBack
+			reason = fmt.Sprintf("canary arm has %d of %d required samples", canary.CanaryArm.Samples, policy.MinSamples)
+		case hasIncumbent && canary.IncumbentArm.Samples < policy.MinSamples:
+			outcome = models.PromotionRolledBack
+			reason = fmt.Sprintf("incumbent arm has %d of %d required samples", canary.IncumbentArm.Samples, policy.MinSamples)
+		default:
+			if metric := missingSignal(policy, canary.CanaryArm, canary.IncumbentArm, hasIncumbent); metric != "" {
+				outcome = models.PromotionRolledBack
+				reason = fmt.Sprintf("no observations for metric %s", metric)
+			} else {
+				outcome = models.PromotionApplied
+				reason = "no regression over the canary window"
+			}
+		}
+	}
+	if outcome == "" {
+		return promo, false, nil
+	}
+	updated, err := s.decideCanary(ctx, promo, canary, outcome, reason)
+	if err != nil {
+		return promo, false, err
+	}
+	return updated, true, nil
+}
+
+// decideCanary records the outcome of a canary and signs the resulting promotion record,
+// whose payload carries the canary state, history and comparisons. A canary whose
+// artifact's manifest signature no longer verifies is rolled back instead of applied.
+func (s *Service) decideCanary(ctx context.Context, promo models.ModelPromotion, canary models.Canary, outcome, reason string) (models.ModelPromotion, error) {
+	if outcome == models.PromotionApplied {
+		artifact, err := s.store.GetArtifact(ctx, promo.ArtifactID)
+		if err != nil {
+			return promo, err
+		}
+		err = s.checkArtifactManifest(ctx, artifact, promo.Environment, canaryController)
+		switch {
+		case errors.Is(err, ErrManifestSignature):
+			outcome, reason = models.PromotionRolledBack, err.Error()
+		case err != nil:
+			return promo, err
+		}
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	event := "promoted"
+	if outcome == models.PromotionRolledBack {
+		event = "rolled_back"
+	}
+	canary.Outcome = outcome
+	canary.Reason = reason
+	canary.DecidedAt = &now
+	canary.History = append(append([]models.CanaryEvent(nil), canary.History...),
+		models.CanaryEvent{At: now, Event: event, Detail: reason})
+	promo.Canary = &canary
+	if outcome == models.PromotionApplied {
+		if err := s.signPromotionManifest(ctx, &promo); err != nil {
+			return promo, err
+		}
+	}
+
+	signed, err := s.signEnvelope(ctx, signing.EnvelopeTypePromotion, promotionPayload(promo))
+	if err != nil {
+		return promo, fmt.Errorf("sign promotion: %w", err)
+	}
+	update := store.PromotionStatusUpdate{
+		ID:               promo.ID,
+		FromStatus:       models.PromotionCanary,
+		Status:           outcome,
+		SentinelDecision: promo.SentinelDecision,
+		PromotedBy:       promo.PromotedBy,
+		Canary:           &canary,
+	}
+	signed.apply(&update)
+	if outcome == models.PromotionApplied {
+		update.PromotedAt = &signed.signedAt
+		update.ManifestSignatureID = promo.ManifestSignatureID
+	}
+	updated, err := s.store.UpdatePromotionStatus(ctx, update)
+	if err != nil {
+		return promo, err
+	}
+	payload := map[string]interface{}{
+		"promotionId":   updated.ID.String(),
+		"artifactId":    updated.ArtifactID.String(),
+		"environment":   updated.Environment,
+		"decidedBy":     canaryController,
+		"reason":        reason,
+		"comparisons":   canary.Comparisons,
+		"signatureHash": updated.SignatureHash,
+	}
+	if canary.IncumbentArtifactID != nil {
+		payload["incumbentArtifactId"] = canary.IncumbentArtifactID.String()
+	}
+	if updated.ManifestSignatureID != nil {
+		payload["manifestSignatureId"] = *updated.ManifestSignatureID
+	}
+	s.emitAudit(ctx, "registry.promotion."+outcome, payload)
+	return updated, nil
+}
+
+// aggregateArms averages the observed metrics of each arm.
+func aggregateArms(observations []models.CanaryObservation) (canaryArm, incumbentArm *models.CanaryArm) {
+	type acc struct {
+		samples int
+		sums    map[string]float64
+		counts  map[string]int
+	}
+	arms := map[string]*acc{
+		models.ArmCanary:    {sums: map[string]float64{}, counts: map[string]int{}},
+		models.ArmIncumbent: {sums: map[string]float64{}, counts: map[string]int{}},
+	}
+	for _, obs := range observations {
+		a, ok := arms[obs.Arm]
+		if !ok {
+			continue
+		}
+		a.samples++
+		for metric, v := range obs.Metrics {
+			a.sums[metric] += v
+			a.counts[metric]++
+		}
+	}
+	build := func(a *acc) *models.CanaryArm {
+		arm := &models.CanaryArm{Samples: a.samples}
+		if len(a.sums) > 0 {
+			arm.Means = make(map[string]float64, len(a.sums))
+			for metric, sum := range a.sums {
+				arm.Means[metric] = sum / float64(a.counts[metric])
+			}
+		}
+		return arm
+	}
+	return build(arms[models.ArmCanary]), build(arms[models.ArmIncumbent])
+}
+
+// rollbackThreshold returns the policy's threshold, or the default for a policy that did
+// not go through normalizeCanaryPolicy.
+func rollbackThreshold(policy models.CanaryPolicy) float64 {
+	if policy.RollbackThreshold == nil {
+		return defaultRollbackThreshold
+	}
+	return *policy.RollbackThreshold
+}
+
+// compareArms compares the canary and incumbent means of each metric signal (every metric
+// both arms reported when the policy names none). The relative drop is positive when the
+// canary is worse; when the incumbent mean is 0 the absolute difference is used.
+func compareArms(policy models.CanaryPolicy, canaryArm, incumbentArm *models.CanaryArm) []models.CanaryComparison {
+	metrics := policy.MetricSignals
+	if len(metrics) == 0 {
+		for metric := range canaryArm.Means {
+			if _, ok := incumbentArm.Means[metric]; ok {
+				metrics = append(metrics, metric)
+			}
+		}
+		sort.Strings(metrics)
+	}
+	lower := make(map[string]bool, len(policy.LowerIsBetter))
+	for _, metric := range policy.LowerIsBetter {
+		lower[metric] = true
+	}
+	threshold := rollbackThreshold(policy)
+
+	var comparisons []models.CanaryComparison
+	for _, metric := range metrics {
+		can, okCan := canaryArm.Means[metric]
+		inc, okInc := incumbentArm.Means[metric]
+		if !okCan || !okInc {
+			continue
+		}
+		drop := inc - can
+		if lower[metric] {
+			drop = can - inc
+		}
+		if inc != 0 {
+			drop /= math.Abs(inc)
+		}
+		comparisons = append(comparisons, models.CanaryComparison{
+			Metric:       metric,
+			Canary:       can,
+			Incumbent:    inc,
+			RelativeDrop: drop,
+			Regressed:    drop > threshold,
+		})
+	}
+	return comparisons
+}
+
+// missingSignal returns the first policy metric signal an arm has no observations for.
+func missingSignal(policy models.CanaryPolicy, canaryArm, incumbentArm *models.CanaryArm, hasIncumbent bool) string {
+	for _, metric := range policy.MetricSignals {
+		if _, ok := canaryArm.Means[metric]; !ok {
+			return metric
+		}
+		if _, ok := incumbentArm.Means[metric]; hasIncumbent && !ok {
+			return metric
+		}
+	}
+	return ""
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/storage"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/canonical"
+)
+
+// ErrDatasetVersionRequired is returned for a training job dataset reference without a
+// datasetVersionId when dataset versions are required.
+var ErrDatasetVersionRequired = errors.New("dataset reference must name a datasetVersionId")
+
+// RequireDatasetVersions makes training jobs refuse dataset references that do not name a
+// registered dataset version. Reproductions of earlier jobs are not affected.
+func (s *Service) RequireDatasetVersions(require bool) {
+	s.requireDatasetVersions = require
+}
+
+// DatasetVersionRequest registers a dataset version. The manifest lists every file of
+// the version with its sha256; a version cannot change once registered.
+type DatasetVersionRequest struct {
+	Name     string               `json:"name"`
+	Version  string               `json:"version"`
+	URI      string               `json:"uri"`
+	Manifest []models.DatasetFile `json:"manifest"`
+	License  string               `json:"license"`
+	// PIIFlags name the kinds of personal data in the dataset, e.g. "email".
+	PIIFlags  []string `json:"piiFlags"`
+	CreatedBy string   `json:"createdBy"`
+}
+
+// RegisterDatasetVersion validates and stores a dataset version with the hash of its
+// manifest. Registering a name and version twice fails with store.ErrDatasetVersionExists.
+func (s *Service) RegisterDatasetVersion(ctx context.Context, req DatasetVersionRequest) (models.DatasetVersion, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Version = strings.TrimSpace(req.Version)
+	req.License = strings.TrimSpace(req.License)
+	if req.Name == "" || req.Version == "" || req.License == "" {
+		return models.DatasetVersion{}, fmt.Errorf("name, version and license required")
+	}
+	manifest, err := normalizeManifest(req.Manifest)
+	if err != nil {
+		return models.DatasetVersion{}, err
+	}
+	hash, err := manifestHash(manifest)
+	if err != nil {
+		return models.DatasetVersion{}, err
+	}
+	created, err := s.store.CreateDatasetVersion(ctx, models.DatasetVersion{
+		ID:           uuid.New(),
+		Name:         req.Name,
+		Version:      req.Version,
+		URI:          req.URI,
+		Manifest:     manifest,
+		ManifestHash: hash,
+		License:      req.License,
+		PIIFlags:     normalizePIIFlags(req.PIIFlags),
+		CreatedBy:    req.CreatedBy,
+	})
+	if err != nil {
+		return models.DatasetVersion{}, err
+	}
+	s.emitAudit(ctx, "registry.dataset.registered", map[string]interface{}{
+		"datasetVersionId": created.ID.String(),
+		"name":             created.Name,
+		"version":          created.Version,
+		"manifestHash":     created.ManifestHash,
+		"files":            len(created.Manifest),
+		"license":          created.License,
+		"piiFlags":         created.PIIFlags,
+		"createdBy":        created.CreatedBy,
+	})
+	return created, nil
+}
+
+// normalizeManifest checks that a manifest names each file once with a valid sha256 and
+// returns it sorted by path.
+func normalizeManifest(files []models.DatasetFile) ([]models.DatasetFile, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("manifest must list at least one file")
+	}
+	out := make([]models.DatasetFile, 0, len(files))
+	seen := make(map[string]bool, len(files))
+	for i, f := range files {
+		f.Path = strings.TrimSpace(f.Path)
+		if f.Path == "" {
+			return nil, fmt.Errorf("manifest[%d] requires a path", i)
+		}
+		if seen[f.Path] {
+			return nil, fmt.Errorf("manifest lists %s twice", f.Path)
+		}
+		seen[f.Path] = true
+		sum, err := storage.NormalizeSHA256(f.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("manifest[%d]: %w", i, err)
+		}
+		f.SHA256 = sum
+		if f.Size < 0 {
+			return nil, fmt.Errorf("manifest[%d] has a negative size", i)
+		}
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Path < out[j].Path
+	})
+	return out, nil
+}
+
+// manifestHash is the hex sha256 of a normalized manifest's canonical (RFC 8785) JSON
+// encoding, so other services can recompute it from the manifest alone.
+func manifestHash(files []models.DatasetFile) (string, error) {
+	raw, err := canonical.Marshal(files)
+	if err != nil {
+		return "", fmt.Errorf("encode manifest: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func normalizePIIFlags(flags []string) []string {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, flag := range flags {
+		flag = strings.ToLower(strings.TrimSpace(flag))
+		if flag == "" || seen[flag] {
+			continue
+		}
+		seen[flag] = true
+		out = append(out, flag)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// GetDatasetVersion returns a registered dataset version.
+func (s *Service) GetDatasetVersion(ctx context.Context, id uuid.UUID) (models.DatasetVersion, error) {
+	return s.store.GetDatasetVersion(ctx, id)
+}
+
+// ListDatasetVersions returns dataset versions, newest first.
+func (s *Service) ListDatasetVersions(ctx context.Context, filter store.ListDatasetVersionsFilter) ([]models.DatasetVersion, error) {
+	if filter.Status != "" && filter.Status != models.DatasetActive && filter.Status != models.DatasetRevoked {
+		return nil, fmt.Errorf("invalid status %q", filter.Status)
+	}
+	return s.store.ListDatasetVersions(ctx, filter)
+}
+
+// DatasetRevokeRequest revokes a dataset version.
+type DatasetRevokeRequest struct {
+	Reason    string `json:"reason"`
+	RevokedBy string `json:"revokedBy"`
+}
+
+// DatasetRevocationResult is a revoked dataset version and the artifacts flagged because
+// they were trained on it.
+type DatasetRevocationResult struct {
+	Dataset          models.DatasetVersion `json:"dataset"`
+	FlaggedArtifacts []uuid.UUID           `json:"flaggedArtifacts"`
+}
+
+// RevokeDatasetVersion revokes a dataset version so no new training job can reference it,
+// and flags every artifact trained on it. Artifacts registered later from jobs that
+// referenced it are flagged when they are registered.
+func (s *Service) RevokeDatasetVersion(ctx context.Context, id uuid.UUID, req DatasetRevokeRequest) (DatasetRevocationResult, error) {
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return DatasetRevocationResult{}, fmt.Errorf("reason required")
+	}
+	if req.RevokedBy == "" {
+		req.RevokedBy = "ai-infra"
+	}
+	v, flagged, err := s.store.RevokeDatasetVersion(ctx, store.DatasetRevocation{
+		ID:        id,
+		RevokedBy: req.RevokedBy,
+		Reason:    req.Reason,
+		RevokedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return DatasetRevocationResult{}, err
+	}
+	if flagged == nil {
+		flagged = []uuid.UUID{}
+	}
+	s.emitAudit(ctx, "registry.dataset.revoked", map[string]interface{}{
+		"datasetVersionId": v.ID.String(),
+		"name":             v.Name,
+		"version":          v.Version,
+		"reason":           v.RevocationReason,
+		"revokedBy":        v.RevokedBy,
+		"flaggedArtifacts": flagged,
+	})
+	return DatasetRevocationResult{Dataset: v, FlaggedArtifacts: flagged}, nil
+}
+
+// resolveDatasetRefs parses the datasetRefs of a training job and resolves entries naming
+// a datasetVersionId into that version's name, version, manifest hash and uri. Unknown
+// and revoked versions are refused. The returned datasetRefs carry the resolved entries,
+// so the job's deterministic checksum covers the manifest hash.
+func (s *Service) resolveDatasetRefs(ctx context.Context, raw json.RawMessage) (json.RawMessage, []models.DatasetRef, error) {
+	refs, err := parseDatasetRefs(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []json.RawMessage
+	for i, ref := range refs {
+		if ref.DatasetVersionID == nil {
+			continue
+		}
+		v, err := s.store.GetDatasetVersion(ctx, *ref.DatasetVersionID)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, fmt.Errorf("datasetRefs[%d]: dataset version %s not found", i, *ref.DatasetVersionID)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if v.Status == models.DatasetRevoked {
+			return nil, nil, fmt.Errorf("datasetRefs[%d]: %w: %s@%s", i, store.ErrDatasetRevoked, v.Name, v.Version)
+		}
+		refs[i] = models.DatasetRef{ID: v.Name, Version: v.Version, Checksum: v.ManifestHash, URI: v.URI, DatasetVersionID: &v.ID}
+		if entries == nil {
+			if err := json.Unmarshal(raw, &entries); err != nil {
+				return nil, nil, err
+			}
+		}
+		if entries[i], err = json.Marshal(refs[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if entries == nil {
+		return raw, refs, nil
+	}
+	resolved, err := json.Marshal(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resolved, refs, nil
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+const defaultEnvironmentHistory = 50
+
+var (
+	// ErrNoActiveModel is returned when rolling back an environment with no applied promotion.
+	ErrNoActiveModel = errors.New("environment has no active model")
+	// ErrNoRollbackTarget is returned when an environment has no earlier artifact to restore.
+	ErrNoRollbackTarget = errors.New("no previous artifact to roll back to")
+)
+
+// EnvironmentStatus is the live model of an environment and its promotion history.
+type EnvironmentStatus struct {
+	Environment     string                  `json:"environment"`
+	ActiveArtifact  *models.ModelArtifact   `json:"activeArtifact"`
+	ActivePromotion *models.ModelPromotion  `json:"activePromotion"`
+	History         []models.ModelPromotion `json:"history"`
+}
+
+// GetEnvironment returns the artifact live in environment, i.e. the one from its latest
+// applied promotion, with up to limit promotions of history (newest first). It returns
+// store.ErrNotFound for an environment nothing was ever promoted to.
+func (s *Service) GetEnvironment(ctx context.Context, environment string, limit int) (EnvironmentStatus, error) {
+	if limit <= 0 {
+		limit = defaultEnvironmentHistory
+	}
+	history, err := s.store.ListPromotionsByEnvironment(ctx, environment, limit)
+	if err != nil {
+		return EnvironmentStatus{}, err
+	}
+	if len(history) == 0 {
+		return EnvironmentStatus{}, store.ErrNotFound
+	}
+	status := EnvironmentStatus{Environment: environment, History: history}
+	active, err := s.store.LatestAppliedPromotion(ctx, environment)
+	if errors.Is(err, store.ErrNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return EnvironmentStatus{}, err
+	}
+	artifact, err := s.store.GetArtifact(ctx, active.ArtifactID)
+	if err != nil {
+		return EnvironmentStatus{}, err
+	}
+	status.ActivePromotion = &active
+	status.ActiveArtifact = &artifact
+	return status, nil
+}
+
+// RollbackRequest restores an earlier artifact in an environment.
+type RollbackRequest struct {
+	// TargetPromotionID selects the applied promotion whose artifact is restored. By
+	// default it is the newest earlier applied promotion of a different artifact.
+	TargetPromotionID *uuid.UUID `json:"targetPromotionId,omitempty"`
+	Reason            string     `json:"reason"`
+	RequestedBy       string     `json:"requestedBy"`
+}
+
+// RollbackEnvironment restores the previous artifact of environment. The restored
+// artifact must pass the same manifest signature check as a promotion and is checked with
+// SentinelNet against the evaluation it was originally promoted
+// with; if allowed, the live promotion is marked rolled_back and a new signed promotion
+// carrying the rollback record is applied. A denied rollback is recorded as a rejected
+// promotion and leaves the environment unchanged.
+//
+// Repeated rollbacks walk back through the environment's history: the default target of
+// a rollback promotion is looked up from before the promotion it restored, so two
+// artifacts never alternate.
+func (s *Service) RollbackEnvironment(ctx context.Context, environment string, req RollbackRequest) (models.ModelPromotion, error) {
+	if environment == "" {
+		return models.ModelPromotion{}, fmt.Errorf("environment required")
+	}
+	if req.RequestedBy == "" {
+		req.RequestedBy = "ai-infra"
+	}
+	if err := s.checkNoCanary(ctx, environment); err != nil {
+		return models.ModelPromotion{}, err
+	}
+	current, err := s.store.LatestAppliedPromotion(ctx, environment)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return models.ModelPromotion{}, ErrNoActiveModel
+		}
+		return models.ModelPromotion{}, err
+	}
+	target, err := s.rollbackTarget(ctx, current, req.TargetPromotionID)
+	if err != nil {
+		return models.ModelPromotion{}, err
+	}
+	restored, err := s.store.GetArtifact(ctx, target.ArtifactID)
+	if err != nil {
+		return models.ModelPromotion{}, err
+	}
+	if err := s.checkArtifactManifest(ctx, restored, environment, req.RequestedBy); err != nil {
+		return models.ModelPromotion{}, err
+	}
+
+	decision, err := s.checkSentinel(ctx, target.ArtifactID, environment, target.Evaluation)
+	if err != nil {
+		return models.ModelPromotion{}, err
+	}
+	rollback := &models.Rollback{
+		RolledBackPromotionID: current.ID,
+		FromArtifactID:        current.ArtifactID,
+		RestoredPromotionID:   target.ID,
+		Reason:                req.Reason,
+	}
+	promo, err := s.store.CreatePromotion(ctx, store.PromotionInput{
+		ArtifactID:  target.ArtifactID,
+		Environment: environment,
+		Status:      models.PromotionPending,
+		Evaluation:  target.Evaluation,
+		Rollback:    rollback,
+	})
+	if err != nil {
+		return models.ModelPromotion{}, err
+	}
+	update := store.PromotionStatusUpdate{
+		ID:               promo.ID,
+		Status:           models.PromotionRejected,
+		SentinelDecision: sentinel.MarshalDecision(decision),
+		PromotedBy:       req.RequestedBy,
+	}
+	auditPayload := map[string]interface{}{
+		"promotionId":           promo.ID.String(),
+		"artifactId":            promo.ArtifactID.String(),
+		"environment":           environment,
+		"requestedBy":           req.RequestedBy,
+		"reason":                req.Reason,
+		"rolledBackPromotionId": current.ID.String(),
+		"fromArtifactId":        current.ArtifactID.String(),
+		"sentinelDecision":      update.SentinelDecision,
+	}
+	if !decision.Allowed {
+		updated, err := s.store.UpdatePromotionStatus(ctx, update)
+		if err != nil {
+			return promo, err
+		}
+		s.emitAudit(ctx, "registry.environment.rollback_rejected", auditPayload)
+		return updated, nil
+	}
+
+	// Retire the live promotion first: the conditional update makes a concurrent rollback
+	// of the same promotion fail with ErrStatusConflict.
+	if _, err := s.store.UpdatePromotionStatus(ctx, retireUpdate(current, models.PromotionApplied, models.PromotionRolledBack)); err != nil {
+		return promo, err
+	}
+	promo.PromotedBy = req.RequestedBy
+	updated, err := s.applyRollback(ctx, promo, update)
+	if err != nil {
+		if _, restoreErr := s.store.UpdatePromotionStatus(ctx, retireUpdate(current, models.PromotionRolledBack, models.PromotionApplied)); restoreErr != nil {
+			log.Printf("[rollback] restore promotion %s after failed rollback: %v", current.ID, restoreErr)
+		}
+		return promo, err
+	}
+	auditPayload["signatureHash"] = updated.SignatureHash
+	if updated.ManifestSignatureID != nil {
+		auditPayload["manifestSignatureId"] = *updated.ManifestSignatureID
+	}
+	s.emitAudit(ctx, "registry.environment.rolled_back", auditPayload)
+	return updated, nil
+}
+
+// rollbackTarget returns the applied promotion whose artifact a rollback of current restores.
+func (s *Service) rollbackTarget(ctx context.Context, current models.ModelPromotion, targetID *uuid.UUID) (models.ModelPromotion, error) {
+	if targetID != nil {
+		target, err := s.store.GetPromotion(ctx, *targetID)
+		if err != nil {
+			return models.ModelPromotion{}, err
+		}
+		if target.Environment != current.Environment || target.Status != models.PromotionApplied {
+			return models.ModelPromotion{}, fmt.Errorf("targetPromotionId must be an applied promotion of environment %s", current.Environment)
+		}
+		if target.ArtifactID == current.ArtifactID {
+			return models.ModelPromotion{}, fmt.Errorf("targetPromotionId promotes the artifact that is already live")
+		}
+		return target, nil
+	}
+	// Search the promotions older than the live one or, when the live one is itself a
+	// rollback, older than the promotion it restored.
+	anchor := current.ID
+	if current.Rollback != nil {
+		anchor = current.Rollback.RestoredPromotionID
+	}
+	history, err := s.store.ListPromotionsByEnvironment(ctx, current.Environment, 0)
+	if err != nil {
+		return models.ModelPromotion{}, err
+	}
+	older := false
+	for _, promo := range history {
+		if promo.ID == anchor {
+			older = true
+			continue
+		}
+		if older && promo.Status == models.PromotionApplied && promo.ArtifactID != current.ArtifactID {
+			return promo, nil
+		}
+	}
+	return models.ModelPromotion{}, ErrNoRollbackTarget
+}
+
+// applyRollback signs the rollback promotion and applies it.
+func (s *Service) applyRollback(ctx context.Context, promo models.ModelPromotion, update store.PromotionStatusUpdate) (models.ModelPromotion, error) {
+	if err := s.signPromotionManifest(ctx, &promo); err != nil {
+		return promo, err
+	}
+	signed, err := s.signEnvelope(ctx, signing.EnvelopeTypePromotion, promotionPayload(promo))
+	if err != nil {
+		return promo, fmt.Errorf("sign promotion: %w", err)
+	}
+	update.Status = models.PromotionApplied
+	signed.apply(&update)
+	update.PromotedAt = &signed.signedAt
+	update.ManifestSignatureID = promo.ManifestSignatureID
+	return s.store.UpdatePromotionStatus(ctx, update)
+}
+
+// retireUpdate changes the status of a signed promotion from one status to another while
+// keeping its decision, signature and timestamps.
+func retireUpdate(p models.ModelPromotion, from, to string) store.PromotionStatusUpdate {
+	return store.PromotionStatusUpdate{
+		ID:               p.ID,
+		FromStatus:       from,
+		Status:           to,
+		SentinelDecision: p.SentinelDecision,
+		PromotedBy:       p.PromotedBy,
+		PromotedAt:       p.PromotedAt,
+		Signature:        p.Signature,
+		SignerID:         p.SignerID,
+		SignatureHash:    p.SignatureHash,
+		SignatureVersion: p.SignatureVersion,
+		SignedAt:         p.SignedAt,
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+)
+
+const (
+	maxJobLogChunkBytes    = 64 << 10
+	defaultJobOutputLimit  = 500
+	maxJobOutputLimit      = 5000
+	maxJobMetricsPerSample = 64
+)
+
+// AppendJobLogs stores output of a training job's run. A chunk without a stream is
+// stdout; chunks without a time are stamped now.
+func (s *Service) AppendJobLogs(ctx context.Context, id uuid.UUID, chunks []models.JobLogChunk) ([]models.JobLogChunk, error) {
+	now := time.Now().UTC()
+	for i := range chunks {
+		chunk := &chunks[i]
+		if chunk.Stream == "" {
+			chunk.Stream = models.LogStdout
+		}
+		if chunk.Stream != models.LogStdout && chunk.Stream != models.LogStderr {
+			return nil, fmt.Errorf("unknown log stream %q", chunk.Stream)
+		}
+		if chunk.Content == "" || len(chunk.Content) > maxJobLogChunkBytes {
+			return nil, fmt.Errorf("log chunk must hold 1 to %d bytes", maxJobLogChunkBytes)
+		}
+		if chunk.CreatedAt.IsZero() {
+			chunk.CreatedAt = now
+		}
+	}
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	return s.store.AppendJobLogs(ctx, id, chunks)
+}
+
+// RecordJobMetrics stores metrics samples of a training job's run. Samples without a
+// time are stamped now.
+func (s *Service) RecordJobMetrics(ctx context.Context, id uuid.UUID, points []models.JobMetricPoint) ([]models.JobMetricPoint, error) {
+	now := time.Now().UTC()
+	for i := range points {
+		point := &points[i]
+		if len(point.Metrics) == 0 || len(point.Metrics) > maxJobMetricsPerSample {
+			return nil, fmt.Errorf("metrics sample must hold 1 to %d metrics", maxJobMetricsPerSample)
+		}
+		for name, value := range point.Metrics {
+			if strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("metric name required")
+			}
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				return nil, fmt.Errorf("metric %q is not a finite number", name)
+			}
+		}
+		if point.RecordedAt.IsZero() {
+			point.RecordedAt = now
+		}
+	}
+	if len(points) == 0 {
+		return nil, nil
+	}
+	return s.store.AppendJobMetrics(ctx, id, points)
+}
+
+// ListJobLogs returns a training job's log chunks after afterSeq in order. The limit
+// defaults to 500 and is capped at 5000.
+func (s *Service) ListJobLogs(ctx context.Context, id uuid.UUID, afterSeq int64, limit int) ([]models.JobLogChunk, error) {
+	if _, err := s.store.GetTrainingJob(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.store.ListJobLogs(ctx, id, afterSeq, jobOutputLimit(limit))
+}
+
+// ListJobMetrics returns a training job's metrics samples after afterSeq in order, with
+// the same limits as ListJobLogs.
+func (s *Service) ListJobMetrics(ctx context.Context, id uuid.UUID, afterSeq int64, limit int) ([]models.JobMetricPoint, error) {
+	if _, err := s.store.GetTrainingJob(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.store.ListJobMetrics(ctx, id, afterSeq, jobOutputLimit(limit))
+}
+
+func jobOutputLimit(limit int) int {
+	if limit <= 0 {
+		return defaultJobOutputLimit
+	}
+	if limit > maxJobOutputLimit {
+		return maxJobOutputLimit
+	}
+	return limit
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+// Job policy defaults applied to zero fields of a configured policy.
+const (
+	defaultJobMaxAttempts = 3
+	defaultJobLease       = time.Minute
+	defaultJobBackoff     = 30 * time.Second
+	defaultJobBackoffMax  = 30 * time.Minute
+	defaultJobListLimit   = 50
+	maxJobListLimit       = 500
+)
+
+var (
+	// ErrLeaseLost is returned to a worker whose job was cancelled, finished or handed to
+	// another worker; the worker must stop running it.
+	ErrLeaseLost = errors.New("training job lease lost")
+	// ErrJobFinished is returned when cancelling a job that already finished.
+	ErrJobFinished = errors.New("training job already finished")
+)
+
+// JobPolicy configures training job leases and retries. A failed run is retried after
+// BackoffBase, doubling per attempt up to BackoffMax, until the job's MaxAttempts runs
+// have been made.
+type JobPolicy struct {
+	MaxAttempts   int
+	LeaseDuration time.Duration
+	BackoffBase   time.Duration
+	BackoffMax    time.Duration
+}
+
+func normalizeJobPolicy(p JobPolicy) JobPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultJobMaxAttempts
+	}
+	if p.LeaseDuration <= 0 {
+		p.LeaseDuration = defaultJobLease
+	}
+	if p.BackoffBase <= 0 {
+		p.BackoffBase = defaultJobBackoff
+	}
+	if p.BackoffMax < p.BackoffBase {
+		p.BackoffMax = defaultJobBackoffMax
+		if p.BackoffMax < p.BackoffBase {
+			p.BackoffMax = p.BackoffBase
+		}
+	}
+	return p
+}
+
+// SetJobPolicy configures training job leases and retries; zero fields keep their
+// defaults.
+func (s *Service) SetJobPolicy(p JobPolicy) {
+	s.jobs = normalizeJobPolicy(p)
+}
+
+// JobPolicy returns the training job policy in effect.
+func (s *Service) JobPolicy() JobPolicy {
+	return s.jobs
+}
+
+// backoff returns the delay before the retry following a job's attempts-th run.
+func (p JobPolicy) backoff(attempts int) time.Duration {
+	delay := p.BackoffBase
+	for i := 1; i < attempts && delay < p.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > p.BackoffMax {
+		delay = p.BackoffMax
+	}
+	return delay
+}
+
+// GetTrainingJob returns a training job.
+func (s *Service) GetTrainingJob(ctx context.Context, id uuid.UUID) (models.TrainingJob, error) {
+	return s.store.GetTrainingJob(ctx, id)
+}
+
+// ListTrainingJobs returns training jobs matching filter, newest first. The limit
+// defaults to 50 and is capped at 500.
+func (s *Service) ListTrainingJobs(ctx context.Context, filter store.ListTrainingJobsFilter) ([]models.TrainingJob, error) {
+	if filter.Status != "" && !filter.Status.Valid() {
+		return nil, fmt.Errorf("unknown job status %q", filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultJobListLimit
+	}
+	if filter.Limit > maxJobListLimit {
+		filter.Limit = maxJobListLimit
+	}
+	return s.store.ListTrainingJobs(ctx, filter)
+}
+
+// ClaimTrainingJob leases the next runnable queued job to workerID. It returns
+// store.ErrNotFound when no job is ready.
+func (s *Service) ClaimTrainingJob(ctx context.Context, workerID string) (models.TrainingJob, error) {
+	if workerID == "" {
+		return models.TrainingJob{}, fmt.Errorf("workerId required")
+	}
+	return s.store.ClaimNextTrainingJob(ctx, workerID, s.jobs.LeaseDuration)
+}
+
+// HeartbeatTrainingJob renews the lease of workerID on a running job. ErrLeaseLost tells
+// the worker to abandon the run.
+func (s *Service) HeartbeatTrainingJob(ctx context.Context, id uuid.UUID, workerID string) (models.TrainingJob, error) {
+	job, err := s.store.HeartbeatTrainingJob(ctx, id, workerID, s.jobs.LeaseDuration)
+	if errors.Is(err, store.ErrJobConflict) {
+		return job, ErrLeaseLost
+	}
+	return job, err
+}
+
+// SucceedTrainingJob records that workerID finished a job successfully.
+func (s *Service) SucceedTrainingJob(ctx context.Context, id uuid.UUID, workerID string) (models.TrainingJob, error) {
+	return s.finishRun(ctx, id, workerID, models.JobSucceeded, "")
+}
+
+// TimeOutTrainingJob records that a run exceeded the job's max runtime. Timed out jobs are
+// not retried.
+func (s *Service) TimeOutTrainingJob(ctx context.Context, id uuid.UUID, workerID string) (models.TrainingJob, error) {
+	return s.finishRun(ctx, id, workerID, models.JobTimedOut, "run exceeded max runtime")
+}
+
+// FailTrainingJob records a failed run. The job is requeued with backoff while it has
+// attempts left and fails otherwise.
+func (s *Service) FailTrainingJob(ctx context.Context, id uuid.UUID, workerID string, cause error) (models.TrainingJob, error) {
+	job, err := s.store.GetTrainingJob(ctx, id)
+	if err != nil {
+		return models.TrainingJob{}, err
+	}
+	return s.retryOrFail(ctx, job, workerID, cause.Error(), time.Now(), nil)
+}
+
+// retryOrFail requeues a job with backoff, or fails it once its attempts are used up.
+// A non-nil leaseExpiredBefore makes the transition conditional on the lease still being
+// expired at that time.
+func (s *Service) retryOrFail(ctx context.Context, job models.TrainingJob, workerID, cause string, now time.Time, leaseExpiredBefore *time.Time) (models.TrainingJob, error) {
+	status := models.JobFailed
+	var next *time.Time
+	if job.Attempts < job.MaxAttempts {
+		status = models.JobQueued
+		at := now.Add(s.jobs.backoff(job.Attempts)).UTC()
+		next = &at
+	}
+	return s.endRun(ctx, store.TrainingJobTransition{
+		ID:                 job.ID,
+		FromStatus:         models.JobRunning,
+		WorkerID:           workerID,
+		Status:             status,
+		Error:              cause,
+		NextAttemptAt:      next,
+		LeaseExpiredBefore: leaseExpiredBefore,
+	})
+}
+
+func (s *Service) finishRun(ctx context.Context, id uuid.UUID, workerID string, status models.JobStatus, cause string) (models.TrainingJob, error) {
+	return s.endRun(ctx, store.TrainingJobTransition{
+		ID:         id,
+		FromStatus: models.JobRunning,
+		WorkerID:   workerID,
+		Status:     status,
+		Error:      cause,
+	})
+}
+
+// endRun moves a running job out of running. A conflict means the worker no longer holds
+// the job and is reported as ErrLeaseLost.
+func (s *Service) endRun(ctx context.Context, in store.TrainingJobTransition) (models.TrainingJob, error) {
+	job, err := s.store.TransitionTrainingJob(ctx, in)
+	if errors.Is(err, store.ErrJobConflict) {
+		return job, ErrLeaseLost
+	}
+	if err == nil && in.Status.Terminal() && in.Status != models.JobSucceeded {
+		s.failReproduction(ctx, job)
+	}
+	return job, err
+}
+
+// CancelJobRequest cancels a queued or running training job.
+type CancelJobRequest struct {
+	Reason      string `json:"reason"`
+	RequestedBy string `json:"requestedBy"`
+}
+
+// CancelTrainingJob cancels a queued or running job. A running job's worker learns of the
+// cancellation at its next heartbeat and stops the run. Finished jobs return
+// ErrJobFinished.
+func (s *Service) CancelTrainingJob(ctx context.Context, id uuid.UUID, req CancelJobRequest) (models.TrainingJob, error) {
+	if req.RequestedBy == "" {
+		req.RequestedBy = "ai-infra"
+	}
+	cause := "cancelled by " + req.RequestedBy
+	if req.Reason != "" {
+		cause += ": " + req.Reason
+	}
+	// Retry when the job changes status (e.g. is claimed) between the read and the update.
+	for attempt := 0; attempt < 3; attempt++ {
+		job, err := s.store.GetTrainingJob(ctx, id)
+		if err != nil {
+			return models.TrainingJob{}, err
+		}
+		if !job.Status.CanTransition(models.JobCancelled) {
+			return job, ErrJobFinished
+		}
+		job, err = s.store.TransitionTrainingJob(ctx, store.TrainingJobTransition{
+			ID:         id,
+			FromStatus: job.Status,
+			Status:     models.JobCancelled,
+			Error:      cause,
+		})
+		if errors.Is(err, store.ErrJobConflict) {
+			continue
+		}
+		if err == nil {
+			s.failReproduction(ctx, job)
+		}
+		return job, err
+	}
+	return models.TrainingJob{}, store.ErrJobConflict
+}
+
+// ReapTrainingJobs recovers running jobs whose worker stopped heartbeating: a job past its
+// max runtime (plus one lease of grace) times out, and a job with an expired lease is
+// requeued, or failed once its attempts are used up. It returns the jobs it changed.
+func (s *Service) ReapTrainingJobs(ctx context.Context, now time.Time) ([]models.TrainingJob, error) {
+	running, err := s.store.ListTrainingJobs(ctx, store.ListTrainingJobsFilter{Status: models.JobRunning})
+	if err != nil {
+		return nil, err
+	}
+	var reaped []models.TrainingJob
+	for _, job := range running {
+		var (
+			updated models.TrainingJob
+			err     error
+		)
+		switch {
+		case job.MaxRuntimeSeconds > 0 && job.StartedAt != nil &&
+			now.After(job.StartedAt.Add(time.Duration(job.MaxRuntimeSeconds)*time.Second+s.jobs.LeaseDuration)):
+			updated, err = s.finishRun(ctx, job.ID, job.WorkerID, models.JobTimedOut, "run exceeded max runtime")
+		case job.LeaseExpiresAt != nil && now.After(*job.LeaseExpiresAt):
+			// Conditional on the lease still being expired: the worker may heartbeat
+			// between the listing and the transition.
+			updated, err = s.retryOrFail(ctx, job, job.WorkerID, fmt.Sprintf("lease of worker %s expired", job.WorkerID), now, &now)
+		default:
+			continue
+		}
+		if errors.Is(err, ErrLeaseLost) {
+			continue // the worker heartbeated, finished or was cancelled in the meantime
+		}
+		if err != nil {
+			return reaped, err
+		}
+		reaped = append(reaped, updated)
+	}
+	return reaped, nil
+}
+
+// RunJobReaper reaps abandoned training jobs every interval until ctx is cancelled.
+func (s *Service) RunJobReaper(ctx context.Context, interval time.Duration) {
+	log.Printf("[jobs] reaper starting (interval=%s)", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[jobs] reaper stopped")
+			return
+		case <-ticker.C:
+			reaped, err := s.ReapTrainingJobs(ctx, time.Now())
+			if err != nil && ctx.Err() == nil {
+				log.Printf("[jobs] reap: %v", err)
+			}
+			for _, job := range reaped {
+				log.Printf("[jobs] job %s is %s after attempt %d: %s", job.ID, job.Status, job.Attempts, job.LastError)
+			}
+		}
+	}
+}
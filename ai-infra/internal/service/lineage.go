@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/reasoning"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+const (
+	defaultLineageDepth = 10
+	maxLineageDepth     = 50
+	maxLineageNodes     = 500
+)
+
+// Lineage directions.
+const (
+	LineageAncestors   = "ancestors"
+	LineageDescendants = "descendants"
+	LineageBoth        = "both"
+)
+
+// Lineage node kinds.
+const (
+	LineageKindArtifact    = "artifact"
+	LineageKindTrainingJob = "training_job"
+	LineageKindDataset     = "dataset"
+	LineageKindEnvironment = "environment"
+)
+
+// Lineage edge relations besides the artifact derivation relations.
+const (
+	RelationTrainedOn  = "trained_on"
+	RelationProduced   = "produced"
+	RelationPromotedTo = "promoted_to"
+)
+
+var (
+	// ErrReasoningGraphDisabled is returned by PublishLineage without a Reasoning Graph client.
+	ErrReasoningGraphDisabled = errors.New("reasoning graph not configured")
+	// ErrPublishFailed wraps Reasoning Graph write errors from PublishLineage.
+	ErrPublishFailed = errors.New("publish lineage")
+)
+
+var artifactRelations = map[string]bool{
+	models.RelationDerived:      true,
+	models.RelationFineTune:     true,
+	models.RelationDistillation: true,
+	models.RelationQuantization: true,
+	models.RelationMerge:        true,
+	models.RelationRetrain:      true,
+}
+
+// SetReasoningGraph configures the client PublishLineage writes to.
+func (s *Service) SetReasoningGraph(c reasoning.Client) {
+	s.reasoning = c
+}
+
+// LineageOptions bounds a lineage query. Depth counts derivation hops from the requested
+// artifact in each direction.
+type LineageOptions struct {
+	Depth     int
+	Direction string
+}
+
+// LineageNode is an artifact, training job, dataset or environment in a lineage graph.
+// Artifact nodes carry the verification of their signature; Depth is the number of
+// derivation hops from the requested artifact, negative for ancestors.
+type LineageNode struct {
+	ID          string                `json:"id"`
+	Kind        string                `json:"kind"`
+	Depth       int                   `json:"depth"`
+	Artifact    *models.ModelArtifact `json:"artifact,omitempty"`
+	Signature   *SignatureCheck       `json:"signature,omitempty"`
+	TrainingJob *models.TrainingJob   `json:"trainingJob,omitempty"`
+	Dataset     *models.DatasetRef    `json:"dataset,omitempty"`
+}
+
+// LineageEdge points from a source to what was made from it: parent to child artifact,
+// dataset to training job, training job to artifact and artifact to the environments
+// it is applied in. Promotion edges carry the verification of the promotion signature.
+type LineageEdge struct {
+	From        string          `json:"from"`
+	To          string          `json:"to"`
+	Relation    string          `json:"relation"`
+	PromotionID *uuid.UUID      `json:"promotionId,omitempty"`
+	Signature   *SignatureCheck `json:"signature,omitempty"`
+}
+
+// Lineage is the ancestor and descendant graph of an artifact. OK reports whether every
+// signature in the graph verified; Truncated is set when the depth or node limit cut the
+// graph short.
+type Lineage struct {
+	ArtifactID uuid.UUID     `json:"artifactId"`
+	OK         bool          `json:"ok"`
+	Truncated  bool          `json:"truncated"`
+	Nodes      []LineageNode `json:"nodes"`
+	Edges      []LineageEdge `json:"edges"`
+}
+
+// GetLineage walks the derivation graph of an artifact up through its parents and down
+// through the artifacts derived from it, adding the training job, datasets and applied
+// promotions of every artifact it reaches.
+func (s *Service) GetLineage(ctx context.Context, id uuid.UUID, opts LineageOptions) (Lineage, error) {
+	if opts.Depth <= 0 {
+		opts.Depth = defaultLineageDepth
+	}
+	if opts.Depth > maxLineageDepth {
+		opts.Depth = maxLineageDepth
+	}
+	switch opts.Direction {
+	case "":
+		opts.Direction = LineageBoth
+	case LineageAncestors, LineageDescendants, LineageBoth:
+	default:
+		return Lineage{}, fmt.Errorf("direction must be %s, %s or %s", LineageAncestors, LineageDescendants, LineageBoth)
+	}
+	root, err := s.store.GetArtifact(ctx, id)
+	if err != nil {
+		return Lineage{}, err
+	}
+	b := &lineageBuilder{
+		svc:     s,
+		lineage: Lineage{ArtifactID: id, OK: true, Nodes: []LineageNode{}, Edges: []LineageEdge{}},
+		nodes:   map[string]bool{},
+		edges:   map[string]bool{},
+		jobs:    map[uuid.UUID]bool{},
+	}
+	if err := b.addArtifact(ctx, root, 0); err != nil {
+		return Lineage{}, err
+	}
+	if opts.Direction != LineageDescendants {
+		if err := b.walk(ctx, id, opts.Depth, -1); err != nil {
+			return Lineage{}, err
+		}
+	}
+	if opts.Direction != LineageAncestors {
+		if err := b.walk(ctx, id, opts.Depth, 1); err != nil {
+			return Lineage{}, err
+		}
+	}
+	return b.lineage, nil
+}
+
+type lineageBuilder struct {
+	svc     *Service
+	lineage Lineage
+	nodes   map[string]bool
+	edges   map[string]bool
+	jobs    map[uuid.UUID]bool
+}
+
+// walk follows parents (step -1) or children (step 1) breadth first from root.
+func (b *lineageBuilder) walk(ctx context.Context, root uuid.UUID, depth, step int) error {
+	frontier := []uuid.UUID{root}
+	for level := 1; len(frontier) > 0; level++ {
+		var next []uuid.UUID
+		for _, id := range frontier {
+			var (
+				links []models.ArtifactLink
+				err   error
+			)
+			if step < 0 {
+				links, err = b.svc.store.ListArtifactParents(ctx, id)
+			} else {
+				links, err = b.svc.store.ListArtifactChildren(ctx, id)
+			}
+			if err != nil {
+				return err
+			}
+			for _, link := range links {
+				other := link.ChildID
+				if step < 0 {
+					other = link.ParentID
+				}
+				if !b.nodes[artifactNodeID(other)] {
+					if level > depth || len(b.lineage.Nodes) >= maxLineageNodes {
+						b.lineage.Truncated = true
+						continue
+					}
+					artifact, err := b.svc.store.GetArtifact(ctx, other)
+					if err != nil {
+						return err
+					}
+					if err := b.addArtifact(ctx, artifact, level*step); err != nil {
+						return err
+					}
+					next = append(next, other)
+				}
+				b.addEdge(LineageEdge{From: artifactNodeID(link.ParentID), To: artifactNodeID(link.ChildID), Relation: link.Relation})
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+// addArtifact adds an artifact node with its training job, datasets and applied
+// promotions.
+func (b *lineageBuilder) addArtifact(ctx context.Context, artifact models.ModelArtifact, depth int) error {
+	check := b.svc.checkEnvelope(artifact.ID, signing.EnvelopeTypeArtifact, artifactPayload(artifact),
+		artifact.SignerID, artifact.Signature, artifact.SignatureHash, artifact.SignatureVersion, artifact.SignedAt)
+	b.lineage.OK = b.lineage.OK && check.HashOK && check.SignatureOK
+	nodeID := artifactNodeID(artifact.ID)
+	b.addNode(LineageNode{ID: nodeID, Kind: LineageKindArtifact, Depth: depth, Artifact: &artifact, Signature: &check})
+
+	jobNodeID := LineageKindTrainingJob + ":" + artifact.TrainingJobID.String()
+	if !b.jobs[artifact.TrainingJobID] {
+		b.jobs[artifact.TrainingJobID] = true
+		job, err := b.svc.store.GetTrainingJob(ctx, artifact.TrainingJobID)
+		if err != nil {
+			return err
+		}
+		b.addNode(LineageNode{ID: jobNodeID, Kind: LineageKindTrainingJob, Depth: depth, TrainingJob: &job})
+		datasets, err := b.svc.store.ListTrainingJobDatasets(ctx, job.ID)
+		if err != nil {
+			return err
+		}
+		for i := range datasets {
+			ds := datasets[i]
+			dsNodeID := datasetNodeID(ds)
+			b.addNode(LineageNode{ID: dsNodeID, Kind: LineageKindDataset, Depth: depth, Dataset: &ds})
+			b.addEdge(LineageEdge{From: dsNodeID, To: jobNodeID, Relation: RelationTrainedOn})
+		}
+	}
+	b.addEdge(LineageEdge{From: jobNodeID, To: nodeID, Relation: RelationProduced})
+
+	promotions, err := b.svc.store.ListPromotionsByArtifact(ctx, artifact.ID)
+	if err != nil {
+		return err
+	}
+	for _, promo := range promotions {
+		if promo.Status != models.PromotionApplied || promo.Signature == nil {
+			continue
+		}
+		promoCheck := b.svc.checkEnvelope(promo.ID, signing.EnvelopeTypePromotion, promotionPayload(promo),
+			deref(promo.SignerID), *promo.Signature, deref(promo.SignatureHash), deref(promo.SignatureVersion), promo.SignedAt)
+		b.lineage.OK = b.lineage.OK && promoCheck.HashOK && promoCheck.SignatureOK
+		envNodeID := LineageKindEnvironment + ":" + promo.Environment
+		b.addNode(LineageNode{ID: envNodeID, Kind: LineageKindEnvironment, Depth: depth})
+		promoID := promo.ID
+		b.addEdge(LineageEdge{From: nodeID, To: envNodeID, Relation: RelationPromotedTo, PromotionID: &promoID, Signature: &promoCheck})
+	}
+	return nil
+}
+
+func (b *lineageBuilder) addNode(node LineageNode) {
+	if b.nodes[node.ID] {
+		return
+	}
+	b.nodes[node.ID] = true
+	b.lineage.Nodes = append(b.lineage.Nodes, node)
+}
+
+func (b *lineageBuilder) addEdge(edge LineageEdge) {
+	key := edge.From + "|" + edge.To + "|" + edge.Relation
+	if edge.PromotionID != nil {
+		key += "|" + edge.PromotionID.String()
+	}
+	if b.edges[key] {
+		return
+	}
+	b.edges[key] = true
+	b.lineage.Edges = append(b.lineage.Edges, edge)
+}
+
+func artifactNodeID(id uuid.UUID) string {
+	return LineageKindArtifact + ":" + id.String()
+}
+
+func datasetNodeID(ds models.DatasetRef) string {
+	if ds.Version == "" {
+		return LineageKindDataset + ":" + ds.ID
+	}
+	return LineageKindDataset + ":" + ds.ID + "@" + ds.Version
+}
+
+// PublishedLineage maps the nodes of a published lineage graph to Reasoning Graph node ids.
+type PublishedLineage struct {
+	ArtifactID uuid.UUID         `json:"artifactId"`
+	Nodes      map[string]string `json:"nodes"`
+	Edges      int               `json:"edges"`
+}
+
+// PublishLineage writes the lineage of an artifact to the Reasoning Graph: every node
+// becomes an observation node and every edge a causes edge labelled with its relation.
+// Each call creates new Reasoning Graph nodes.
+func (s *Service) PublishLineage(ctx context.Context, id uuid.UUID, opts LineageOptions) (PublishedLineage, error) {
+	if s.reasoning == nil {
+		return PublishedLineage{}, ErrReasoningGraphDisabled
+	}
+	lineage, err := s.GetLineage(ctx, id, opts)
+	if err != nil {
+		return PublishedLineage{}, err
+	}
+	published := PublishedLineage{ArtifactID: id, Nodes: map[string]string{}}
+	for _, node := range lineage.Nodes {
+		payload, err := json.Marshal(node)
+		if err != nil {
+			return published, err
+		}
+		metadata, _ := json.Marshal(map[string]interface{}{"origin": "ai-infra", "lineageId": node.ID, "kind": node.Kind})
+		nodeID, err := s.reasoning.CreateNode(ctx, reasoning.Node{
+			Type:     "observation",
+			Payload:  payload,
+			Author:   "service:ai-infra",
+			Metadata: metadata,
+		})
+		if err != nil {
+			return published, fmt.Errorf("%w: %v", ErrPublishFailed, err)
+		}
+		published.Nodes[node.ID] = nodeID
+	}
+	for _, edge := range lineage.Edges {
+		metadata, _ := json.Marshal(map[string]interface{}{"relation": edge.Relation, "promotionId": edge.PromotionID})
+		if _, err := s.reasoning.CreateEdge(ctx, reasoning.Edge{
+			From:     published.Nodes[edge.From],
+			To:       published.Nodes[edge.To],
+			Type:     "causes",
+			Metadata: metadata,
+		}); err != nil {
+			return published, fmt.Errorf("%w: %v", ErrPublishFailed, err)
+		}
+		published.Edges++
+	}
+	s.emitAudit(ctx, "registry.lineage.published", map[string]interface{}{
+		"artifactId": id.String(),
+		"nodes":      published.Nodes,
+		"edges":      published.Edges,
+		"truncated":  lineage.Truncated,
+	})
+	return published, nil
+}
+
+// normalizeParents validates the parents of a new artifact, defaults their relation and
+// sorts them by artifact id so the signed payload does not depend on request order.
+func (s *Service) normalizeParents(ctx context.Context, id uuid.UUID, parents []models.ArtifactParent) ([]models.ArtifactParent, error) {
+	if len(parents) == 0 {
+		return nil, nil
+	}
+	out := make([]models.ArtifactParent, 0, len(parents))
+	seen := map[uuid.UUID]bool{}
+	for _, parent := range parents {
+		if parent.ArtifactID == uuid.Nil || parent.ArtifactID == id {
+			return nil, fmt.Errorf("invalid parent artifactId")
+		}
+		if seen[parent.ArtifactID] {
+			return nil, fmt.Errorf("duplicate parent %s", parent.ArtifactID)
+		}
+		seen[parent.ArtifactID] = true
+		if parent.Relation == "" {
+			parent.Relation = models.RelationDerived
+		}
+		if !artifactRelations[parent.Relation] {
+			return nil, fmt.Errorf("unknown parent relation %q", parent.Relation)
+		}
+		if _, err := s.store.GetArtifact(ctx, parent.ArtifactID); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return nil, fmt.Errorf("parent artifact %s not found", parent.ArtifactID)
+			}
+			return nil, err
+		}
+		out = append(out, parent)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ArtifactID.String() < out[j].ArtifactID.String()
+	})
+	return out, nil
+}
+
+// parseDatasetRefs reads the datasetRefs of a training job request into dataset rows.
+// Each entry is either a dataset id string, an object with an id and optional version,
+// checksum and uri, or an object naming a registered datasetVersionId.
+func parseDatasetRefs(raw json.RawMessage) ([]models.DatasetRef, error) {
+	if len(raw) == 0 || strings.TrimSpace(string(raw)) == "null" {
+		return nil, nil
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("datasetRefs must be an array")
+	}
+	refs := make([]models.DatasetRef, 0, len(entries))
+	for i, entry := range entries {
+		var ref models.DatasetRef
+		var id string
+		if err := json.Unmarshal(entry, &id); err == nil {
+			ref.ID = id
+		} else if err := json.Unmarshal(entry, &ref); err != nil {
+			return nil, fmt.Errorf("datasetRefs[%d] must be a string or an object", i)
+		}
+		if ref.ID == "" && ref.DatasetVersionID == nil {
+			return nil, fmt.Errorf("datasetRefs[%d] requires an id or datasetVersionId", i)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
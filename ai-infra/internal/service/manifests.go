@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+var (
+	// ErrManifestSignature is returned by PromoteArtifact when the artifact's Kernel
+	// manifest signature is missing or does not verify.
+	ErrManifestSignature = errors.New("artifact manifest signature not verified")
+	// ErrKernelSign wraps Kernel errors while signing a manifest.
+	ErrKernelSign = errors.New("kernel manifest signing failed")
+)
+
+// SetKernelManifests makes the service obtain Kernel signatures over artifact and
+// promotion manifests. Registered artifacts then get a manifest signature from client,
+// and promotion requires the artifact's signature to verify against keys, the Kernel's
+// published signer keys.
+func (s *Service) SetKernelManifests(client signing.KernelClient, keys signing.Verifier) {
+	s.kernel = client
+	s.kernelKeys = keys
+}
+
+// RequireManifestSignatures makes promotion refuse every artifact when no Kernel is
+// configured to verify its manifest signature, instead of skipping the check.
+func (s *Service) RequireManifestSignatures(require bool) {
+	s.requireManifests = require
+}
+
+// checkArtifactManifest gates putting an artifact live in environment: with a Kernel, or
+// when manifest signatures are required, the artifact's manifest signature must verify.
+// Refusals emit registry.promotion.refused and wrap ErrManifestSignature.
+func (s *Service) checkArtifactManifest(ctx context.Context, artifact models.ModelArtifact, environment, requestedBy string) error {
+	if s.kernel == nil && !s.requireManifests {
+		return nil
+	}
+	_, err := s.verifyArtifactManifest(ctx, artifact)
+	if errors.Is(err, ErrManifestSignature) {
+		s.emitAudit(ctx, "registry.promotion.refused", map[string]interface{}{
+			"artifactId":  artifact.ID.String(),
+			"environment": environment,
+			"requestedBy": requestedBy,
+			"reason":      err.Error(),
+		})
+	}
+	return err
+}
+
+// artifactManifest is the manifest the Kernel signs for an artifact: the artifact record
+// and the training run that produced it. It is rebuilt from stored records when a
+// promotion verifies the signature, so it may only use persisted fields. The seed is a
+// string because canonical JSON numbers cannot hold every int64.
+func artifactManifest(a models.ModelArtifact, job models.TrainingJob) map[string]interface{} {
+	datasetRefs := job.DatasetRefs
+	if len(datasetRefs) == 0 {
+		datasetRefs = []byte("[]")
+	}
+	manifest := map[string]interface{}{
+		"id":          "ai-infra:artifact:" + a.ID.String(),
+		"type":        signing.ManifestTypeArtifact,
+		"version":     signing.ManifestVersion,
+		"artifactId":  a.ID.String(),
+		"artifactUri": a.ArtifactURI,
+		"checksum":    a.Checksum,
+		"metadata":    defaultJSON(a.Metadata),
+		"trainingJob": map[string]interface{}{
+			"id":              job.ID.String(),
+			"codeRef":         job.CodeRef,
+			"containerDigest": job.ContainerDigest,
+			"hyperparams":     defaultJSON(job.Hyperparams),
+			"datasetRefs":     datasetRefs,
+			"seed":            strconv.FormatInt(job.Seed, 10),
+		},
+	}
+	if len(a.Parents) > 0 {
+		manifest["parents"] = a.Parents
+	}
+	return manifest
+}
+
+// promotionManifest is the manifest the Kernel signs for an applied promotion: its
+// envelope payload plus the manifest signature of the promoted artifact.
+func promotionManifest(p models.ModelPromotion, artifact models.ModelArtifact) map[string]interface{} {
+	manifest := promotionPayload(p)
+	manifest["id"] = "ai-infra:promotion:" + p.ID.String()
+	manifest["type"] = signing.ManifestTypePromotion
+	manifest["version"] = signing.ManifestVersion
+	manifest["artifactManifestSignatureId"] = nil
+	if artifact.ManifestSignatureID != nil {
+		manifest["artifactManifestSignatureId"] = *artifact.ManifestSignatureID
+	}
+	return manifest
+}
+
+// signManifest asks the Kernel to sign manifest and stores the returned signature for the
+// subject it describes.
+func (s *Service) signManifest(ctx context.Context, subjectType string, subjectID uuid.UUID, manifest map[string]interface{}) (_ models.ManifestSignature, err error) {
+	ctx, span := tracing.Start(ctx, "kernel.sign_manifest")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttribute("manifest.type", subjectType)
+
+	digest, err := signing.ManifestHash(manifest)
+	if err != nil {
+		return models.ManifestSignature{}, err
+	}
+	returned, err := s.kernel.Sign(ctx, manifest, signing.ManifestVersion)
+	if err != nil {
+		return models.ManifestSignature{}, fmt.Errorf("%w: %v", ErrKernelSign, err)
+	}
+	ms := models.ManifestSignature{
+		ID:           returned.ID,
+		ManifestID:   returned.ManifestID,
+		SubjectType:  subjectType,
+		SubjectID:    subjectID,
+		ManifestHash: hex.EncodeToString(digest),
+		SignerID:     returned.SignerID,
+		Signature:    returned.Signature,
+		Version:      returned.Version,
+		SignedAt:     returned.Ts.UTC(),
+	}
+	if ms.ID == "" {
+		ms.ID = ms.ManifestID
+	}
+	if ms.ID == "" || ms.SignerID == "" || ms.Signature == "" {
+		return models.ManifestSignature{}, fmt.Errorf("%w: incomplete signature in Kernel response", ErrKernelSign)
+	}
+	if ms.Version == "" {
+		ms.Version = signing.ManifestVersion
+	}
+	if ms.SignedAt.IsZero() {
+		ms.SignedAt = time.Now().UTC()
+	}
+	span.SetAttribute("signing.signer_id", ms.SignerID)
+	return s.store.CreateManifestSignature(ctx, ms)
+}
+
+// signArtifactManifest obtains the Kernel manifest signature for an artifact about to be
+// registered.
+func (s *Service) signArtifactManifest(ctx context.Context, artifact models.ModelArtifact) (models.ManifestSignature, error) {
+	job, err := s.store.GetTrainingJob(ctx, artifact.TrainingJobID)
+	if errors.Is(err, store.ErrNotFound) {
+		return models.ManifestSignature{}, fmt.Errorf("training job %s not found", artifact.TrainingJobID)
+	}
+	if err != nil {
+		return models.ManifestSignature{}, err
+	}
+	return s.signManifest(ctx, signing.ManifestTypeArtifact, artifact.ID, artifactManifest(artifact, job))
+}
+
+// signPromotionManifest obtains the Kernel manifest signature for a promotion about to be
+// applied and records it on promo. It does nothing without a Kernel.
+func (s *Service) signPromotionManifest(ctx context.Context, promo *models.ModelPromotion) error {
+	if s.kernel == nil {
+		return nil
+	}
+	artifact, err := s.store.GetArtifact(ctx, promo.ArtifactID)
+	if err != nil {
+		return err
+	}
+	promo.ManifestSignatureID = nil
+	ms, err := s.signManifest(ctx, signing.ManifestTypePromotion, promo.ID, promotionManifest(*promo, artifact))
+	if err != nil {
+		return fmt.Errorf("sign promotion manifest: %w", err)
+	}
+	promo.ManifestSignatureID = &ms.ID
+	return nil
+}
+
+// verifyArtifactManifest checks that an artifact references a manifest signature the
+// Kernel issued for it, that the manifest rebuilt from the stored artifact and training
+// job still has the signed hash, and that the signature verifies against the Kernel's
+// keys. Verification failures wrap ErrManifestSignature.
+func (s *Service) verifyArtifactManifest(ctx context.Context, artifact models.ModelArtifact) (models.ManifestSignature, error) {
+	if s.kernelKeys == nil {
+		return models.ManifestSignature{}, fmt.Errorf("%w: no Kernel configured to verify manifest signatures", ErrManifestSignature)
+	}
+	if artifact.ManifestSignatureID == nil {
+		return models.ManifestSignature{}, fmt.Errorf("%w: artifact %s has no manifest signature", ErrManifestSignature, artifact.ID)
+	}
+	ms, err := s.store.GetManifestSignature(ctx, *artifact.ManifestSignatureID)
+	if errors.Is(err, store.ErrNotFound) {
+		return models.ManifestSignature{}, fmt.Errorf("%w: manifest signature %s was not issued through this registry", ErrManifestSignature, *artifact.ManifestSignatureID)
+	}
+	if err != nil {
+		return models.ManifestSignature{}, err
+	}
+	if ms.SubjectType != signing.ManifestTypeArtifact || ms.SubjectID != artifact.ID {
+		return ms, fmt.Errorf("%w: manifest signature %s belongs to %s %s", ErrManifestSignature, ms.ID, ms.SubjectType, ms.SubjectID)
+	}
+	if ms.Version != signing.ManifestVersion {
+		return ms, fmt.Errorf("%w: unsupported manifest version %q", ErrManifestSignature, ms.Version)
+	}
+	job, err := s.store.GetTrainingJob(ctx, artifact.TrainingJobID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ms, fmt.Errorf("%w: training job %s not found", ErrManifestSignature, artifact.TrainingJobID)
+	}
+	if err != nil {
+		return ms, err
+	}
+	digest, err := signing.ManifestHash(artifactManifest(artifact, job))
+	if err != nil {
+		return ms, err
+	}
+	if hex.EncodeToString(digest) != ms.ManifestHash {
+		return ms, fmt.Errorf("%w: artifact no longer matches its signed manifest", ErrManifestSignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(ms.Signature)
+	if err != nil {
+		return ms, fmt.Errorf("%w: decode signature: %v", ErrManifestSignature, err)
+	}
+	if err := s.kernelKeys.Verify(ms.SignerID, digest, sig); err != nil {
+		return ms, fmt.Errorf("%w: %v", ErrManifestSignature, err)
+	}
+	return ms, nil
+}
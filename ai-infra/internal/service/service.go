@@ -2,32 +2,65 @@ package service
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/reasoning"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/sentinel"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/storage"
 	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 type Service struct {
 	store    store.Store
 	sentinel sentinel.Client
 	signer   signing.Signer
+	verifier signing.Verifier
+	auditor  Auditor
+	// reasoning receives published lineage graphs; nil disables publishing.
+	reasoning reasoning.Client
+	jobs      JobPolicy
+	// fetcher reads artifact content for checksum verification; nil skips that check.
+	fetcher storage.Fetcher
+	// blobs holds uploaded artifact content; nil disables uploads.
+	blobs storage.BlobStore
+	// kernel signs artifact and promotion manifests and kernelKeys verifies those
+	// signatures; without a Kernel no manifests are signed or required.
+	kernel     signing.KernelClient
+	kernelKeys signing.Verifier
+	// requireManifests refuses promotions when no Kernel can verify manifest signatures.
+	requireManifests bool
+	// requireDatasetVersions refuses training jobs with free-form dataset references.
+	requireDatasetVersions bool
 }
 
 func New(store store.Store, sentinel sentinel.Client, signer signing.Signer) *Service {
-	return &Service{
+	svc := &Service{
 		store:    store,
 		sentinel: sentinel,
 		signer:   signer,
+		jobs:     normalizeJobPolicy(JobPolicy{}),
 	}
+	if local, ok := signer.(*signing.Ed25519Signer); ok {
+		ring := signing.NewKeyRing()
+		ring.Add(local.SignerID(), local.PublicKey())
+		svc.verifier = ring
+	}
+	return svc
+}
+
+// SetVerifier configures the keys used by VerifyArtifact. A local Ed25519 signer's own
+// key is used when none is set.
+func (s *Service) SetVerifier(v signing.Verifier) {
+	s.verifier = v
 }
 
 type TrainingJobRequest struct {
@@ -36,65 +69,134 @@ type TrainingJobRequest struct {
 	Hyperparams     json.RawMessage `json:"hyperparams"`
 	DatasetRefs     json.RawMessage `json:"datasetRefs"`
 	Seed            int64           `json:"seed"`
+	// MaxAttempts bounds how often a failed run is retried; it defaults to the job
+	// policy. MaxRuntimeSeconds, when set, times out longer runs.
+	MaxAttempts       int `json:"maxAttempts"`
+	MaxRuntimeSeconds int `json:"maxRuntimeSeconds"`
 }
 
 func (s *Service) CreateTrainingJob(ctx context.Context, req TrainingJobRequest) (models.TrainingJob, error) {
 	if req.CodeRef == "" || req.ContainerDigest == "" {
 		return models.TrainingJob{}, fmt.Errorf("codeRef and containerDigest required")
 	}
+	datasetRefs, datasets, err := s.resolveDatasetRefs(ctx, req.DatasetRefs)
+	if err != nil {
+		return models.TrainingJob{}, err
+	}
+	if s.requireDatasetVersions {
+		for i, ref := range datasets {
+			if ref.DatasetVersionID == nil {
+				return models.TrainingJob{}, fmt.Errorf("datasetRefs[%d]: %w", i, ErrDatasetVersionRequired)
+			}
+		}
+	}
+	if req.MaxAttempts < 0 || req.MaxRuntimeSeconds < 0 {
+		return models.TrainingJob{}, fmt.Errorf("maxAttempts and maxRuntimeSeconds must not be negative")
+	}
+	if req.MaxAttempts == 0 {
+		req.MaxAttempts = s.jobs.MaxAttempts
+	}
 	if req.Seed == 0 {
 		req.Seed = time.Now().UnixNano()
 	}
 	return s.store.CreateTrainingJob(ctx, store.TrainingJobInput{
-		CodeRef:         req.CodeRef,
-		ContainerDigest: req.ContainerDigest,
-		Hyperparams:     req.Hyperparams,
-		DatasetRefs:     req.DatasetRefs,
-		Seed:            req.Seed,
-		Status:          "queued",
+		CodeRef:           req.CodeRef,
+		ContainerDigest:   req.ContainerDigest,
+		Hyperparams:       req.Hyperparams,
+		DatasetRefs:       datasetRefs,
+		Datasets:          datasets,
+		Seed:              req.Seed,
+		Status:            models.JobQueued,
+		MaxAttempts:       req.MaxAttempts,
+		MaxRuntimeSeconds: req.MaxRuntimeSeconds,
 	})
 }
 
 type RegisterArtifactRequest struct {
-	TrainingJobID       uuid.UUID       `json:"trainingJobId"`
-	ArtifactURI         string          `json:"artifactUri"`
-	Checksum            string          `json:"checksum"`
-	Metadata            json.RawMessage `json:"metadata"`
-	ManifestSignatureID *string         `json:"manifestSignatureId"`
+	TrainingJobID uuid.UUID       `json:"trainingJobId"`
+	ArtifactURI   string          `json:"artifactUri"`
+	Checksum      string          `json:"checksum"`
+	Metadata      json.RawMessage `json:"metadata"`
+	// ManifestSignatureID references a Kernel manifest signature obtained elsewhere. It
+	// must be empty when the service signs manifests through the Kernel itself.
+	ManifestSignatureID *string `json:"manifestSignatureId"`
+	// Parents are the artifacts this one was derived from; the relation defaults to
+	// "derived".
+	Parents []models.ArtifactParent `json:"parents"`
 }
 
 func (s *Service) RegisterArtifact(ctx context.Context, req RegisterArtifactRequest) (models.ModelArtifact, error) {
 	if req.TrainingJobID == uuid.Nil || req.ArtifactURI == "" || req.Checksum == "" {
 		return models.ModelArtifact{}, fmt.Errorf("trainingJobId, artifactUri, and checksum required")
 	}
-	payload := map[string]interface{}{
-		"trainingJobId": req.TrainingJobID.String(),
-		"artifactUri":   req.ArtifactURI,
-		"checksum":      req.Checksum,
-	}
-	if len(req.Metadata) > 0 {
-		payload["metadata"] = json.RawMessage(req.Metadata)
+	if s.kernel != nil && req.ManifestSignatureID != nil {
+		return models.ModelArtifact{}, fmt.Errorf("manifestSignatureId is obtained from the Kernel on registration")
 	}
-	serialized, err := json.Marshal(payload)
+	uri, err := s.checkBlobArtifact(ctx, req.ArtifactURI, req.Checksum)
 	if err != nil {
 		return models.ModelArtifact{}, err
 	}
-	hash := sha256.Sum256(serialized)
-	sigBytes, err := s.signer.Sign(ctx, hash[:])
+	req.ArtifactURI = uri
+	id := uuid.New()
+	parents, err := s.normalizeParents(ctx, id, req.Parents)
 	if err != nil {
-		return models.ModelArtifact{}, fmt.Errorf("sign artifact: %w", err)
+		return models.ModelArtifact{}, err
 	}
-	signature := base64.StdEncoding.EncodeToString(sigBytes)
-
-	return s.store.CreateArtifact(ctx, store.ArtifactInput{
+	artifact := models.ModelArtifact{
+		ID:                  id,
 		TrainingJobID:       req.TrainingJobID,
 		ArtifactURI:         req.ArtifactURI,
 		Checksum:            req.Checksum,
-		Metadata:            req.Metadata,
-		SignerID:            s.signer.SignerID(),
-		Signature:           signature,
+		Metadata:            defaultJSON(req.Metadata),
 		ManifestSignatureID: req.ManifestSignatureID,
+		Parents:             parents,
+	}
+	if s.kernel != nil {
+		ms, err := s.signArtifactManifest(ctx, artifact)
+		if err != nil {
+			return models.ModelArtifact{}, fmt.Errorf("sign artifact manifest: %w", err)
+		}
+		artifact.ManifestSignatureID = &ms.ID
+	}
+	signed, err := s.signEnvelope(ctx, signing.EnvelopeTypeArtifact, artifactPayload(artifact))
+	if err != nil {
+		return models.ModelArtifact{}, fmt.Errorf("sign artifact: %w", err)
+	}
+
+	created, err := s.store.CreateArtifact(ctx, store.ArtifactInput{
+		ID:                  artifact.ID,
+		TrainingJobID:       artifact.TrainingJobID,
+		ArtifactURI:         artifact.ArtifactURI,
+		Checksum:            artifact.Checksum,
+		Metadata:            artifact.Metadata,
+		SignerID:            signed.envelope.SignerID,
+		Signature:           signed.signature,
+		SignatureHash:       signed.hash,
+		SignatureVersion:    signed.envelope.Version,
+		SignedAt:            &signed.signedAt,
+		ManifestSignatureID: artifact.ManifestSignatureID,
+		Parents:             artifact.Parents,
 	})
+	if err != nil {
+		return models.ModelArtifact{}, err
+	}
+	auditPayload := map[string]interface{}{
+		"artifactId":          created.ID.String(),
+		"trainingJobId":       created.TrainingJobID.String(),
+		"artifactUri":         created.ArtifactURI,
+		"checksum":            created.Checksum,
+		"signerId":            created.SignerID,
+		"signatureHash":       created.SignatureHash,
+		"manifestSignatureId": created.ManifestSignatureID,
+	}
+	if len(created.Parents) > 0 {
+		auditPayload["parents"] = created.Parents
+	}
+	if len(created.Flags) > 0 {
+		auditPayload["flags"] = created.Flags
+	}
+	s.emitAudit(ctx, "registry.artifact.registered", auditPayload)
+	return created, nil
 }
 
 type PromotionRequest struct {
@@ -102,6 +204,9 @@ type PromotionRequest struct {
 	Environment string          `json:"environment"`
 	Evaluation  json.RawMessage `json:"evaluation"`
 	RequestedBy string          `json:"requestedBy"`
+	// Canary, when set, rolls the artifact out as a canary: an allowed promotion enters
+	// the canary state and is applied or rolled back by the canary controller.
+	Canary *models.CanaryPolicy `json:"canary,omitempty"`
 }
 
 func (s *Service) PromoteArtifact(ctx context.Context, req PromotionRequest) (models.ModelPromotion, error) {
@@ -111,10 +216,25 @@ func (s *Service) PromoteArtifact(ctx context.Context, req PromotionRequest) (mo
 	if req.RequestedBy == "" {
 		req.RequestedBy = "ai-infra"
 	}
-	// Ensure artifact exists
-	if _, err := s.store.GetArtifact(ctx, req.ArtifactID); err != nil {
+	var policy models.CanaryPolicy
+	if req.Canary != nil {
+		var err error
+		if policy, err = normalizeCanaryPolicy(*req.Canary); err != nil {
+			return models.ModelPromotion{}, err
+		}
+	}
+	artifact, err := s.store.GetArtifact(ctx, req.ArtifactID)
+	if err != nil {
+		return models.ModelPromotion{}, err
+	}
+	if err := s.checkArtifactManifest(ctx, artifact, req.Environment, req.RequestedBy); err != nil {
 		return models.ModelPromotion{}, err
 	}
+	if req.Canary != nil {
+		if err := s.checkNoCanary(ctx, req.Environment); err != nil {
+			return models.ModelPromotion{}, err
+		}
+	}
 	promo, err := s.store.CreatePromotion(ctx, store.PromotionInput{
 		ArtifactID:  req.ArtifactID,
 		Environment: req.Environment,
@@ -125,59 +245,97 @@ func (s *Service) PromoteArtifact(ctx context.Context, req PromotionRequest) (mo
 		return models.ModelPromotion{}, err
 	}
 
-	decision := sentinel.Decision{Allowed: true, PolicyID: "sentinel-allow", Reason: "default"}
-	if s.sentinel != nil {
-		var eval map[string]float64
-		_ = json.Unmarshal(req.Evaluation, &eval)
-		decision, err = s.sentinel.Check(ctx, sentinel.Request{
-			ArtifactID:  req.ArtifactID.String(),
-			Environment: req.Environment,
-			Evaluation:  eval,
-		})
-		if err != nil {
-			return promo, err
-		}
+	decision, err := s.checkSentinel(ctx, req.ArtifactID, req.Environment, req.Evaluation)
+	if err != nil {
+		return promo, err
 	}
 
 	status := "applied"
 	if !decision.Allowed {
 		status = "rejected"
 	}
-	var signature *string
-	var signerID *string
-	var promotedAt *time.Time
-	if decision.Allowed {
-		payload := map[string]interface{}{
-			"artifactId":  req.ArtifactID.String(),
-			"environment": req.Environment,
-			"evaluation":  json.RawMessage(req.Evaluation),
-			"requestedBy": req.RequestedBy,
+	update := store.PromotionStatusUpdate{
+		ID:               promo.ID,
+		Status:           status,
+		SentinelDecision: sentinel.MarshalDecision(decision),
+		PromotedBy:       req.RequestedBy,
+	}
+	eventType := "registry.promotion." + status
+	if decision.Allowed && req.Canary != nil {
+		// Signed once the canary controller decides the outcome.
+		update.Status = models.PromotionCanary
+		update.Canary, err = s.startCanary(ctx, promo, policy)
+		if err != nil {
+			return promo, err
+		}
+		eventType = "registry.canary.started"
+	} else if decision.Allowed {
+		promo.PromotedBy = req.RequestedBy
+		if err := s.signPromotionManifest(ctx, &promo); err != nil {
+			return promo, err
 		}
-		bytes, _ := json.Marshal(payload)
-		hash := sha256.Sum256(bytes)
-		sigBytes, err := s.signer.Sign(ctx, hash[:])
+		signed, err := s.signEnvelope(ctx, signing.EnvelopeTypePromotion, promotionPayload(promo))
 		if err != nil {
 			return promo, fmt.Errorf("sign promotion: %w", err)
 		}
-		sigStr := base64.StdEncoding.EncodeToString(sigBytes)
-		signature = &sigStr
-		id := s.signer.SignerID()
-		signerID = &id
-		now := time.Now().UTC()
-		promotedAt = &now
+		signed.apply(&update)
+		update.PromotedAt = &signed.signedAt
+		update.ManifestSignatureID = promo.ManifestSignatureID
 	}
 
-	updated, err := s.store.UpdatePromotionStatus(ctx, store.PromotionStatusUpdate{
-		ID:               promo.ID,
-		Status:           status,
-		SentinelDecision: sentinel.MarshalDecision(decision),
-		PromotedBy:       req.RequestedBy,
-		PromotedAt:       promotedAt,
-		Signature:        signature,
-		SignerID:         signerID,
-	})
+	updated, err := s.store.UpdatePromotionStatus(ctx, update)
+	if errors.Is(err, store.ErrCanaryExists) {
+		// Another canary for the environment started first; the unique index on canary
+		// promotions decides, so this one is rejected rather than left pending.
+		if _, rejectErr := s.store.UpdatePromotionStatus(ctx, store.PromotionStatusUpdate{
+			ID:               promo.ID,
+			Status:           models.PromotionRejected,
+			SentinelDecision: update.SentinelDecision,
+			PromotedBy:       req.RequestedBy,
+			FromStatus:       models.PromotionPending,
+		}); rejectErr != nil {
+			log.Printf("[canary] reject promotion %s after canary conflict: %v", promo.ID, rejectErr)
+		}
+		return promo, ErrCanaryInProgress
+	}
 	if err != nil {
 		return promo, err
 	}
+	payload := map[string]interface{}{
+		"promotionId":      updated.ID.String(),
+		"artifactId":       updated.ArtifactID.String(),
+		"environment":      updated.Environment,
+		"requestedBy":      req.RequestedBy,
+		"sentinelDecision": updated.SentinelDecision,
+		"signatureHash":    updated.SignatureHash,
+	}
+	if updated.ManifestSignatureID != nil {
+		payload["manifestSignatureId"] = *updated.ManifestSignatureID
+	}
+	if updated.Canary != nil {
+		payload["canary"] = updated.Canary
+	}
+	s.emitAudit(ctx, eventType, payload)
 	return updated, nil
 }
+
+// checkSentinel asks SentinelNet whether artifactID may be promoted to environment. Without
+// a client every promotion is allowed.
+func (s *Service) checkSentinel(ctx context.Context, artifactID uuid.UUID, environment string, evaluation json.RawMessage) (sentinel.Decision, error) {
+	if s.sentinel == nil {
+		return sentinel.Decision{Allowed: true, PolicyID: "sentinel-allow", Reason: "default"}, nil
+	}
+	var eval map[string]float64
+	_ = json.Unmarshal(evaluation, &eval)
+	ctx, span := tracing.Start(ctx, "sentinel.check")
+	defer span.End()
+	span.SetAttribute("promotion.environment", environment)
+	decision, err := s.sentinel.Check(ctx, sentinel.Request{
+		ArtifactID:  artifactID.String(),
+		Environment: environment,
+		Evaluation:  eval,
+	})
+	span.SetAttribute("sentinel.allowed", decision.Allowed)
+	span.RecordError(err)
+	return decision, err
+}
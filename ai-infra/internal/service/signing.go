@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+type signedEnvelope struct {
+	envelope  signing.Envelope
+	hash      string
+	signature string
+	signedAt  time.Time
+}
+
+// signEnvelope wraps payload in a signing envelope and signs its canonical hash. KMS and
+// Kernel signers only learn their key id from the response, so the envelope is rebuilt
+// and re-signed once if the reported signer id differs from the one that was embedded.
+func (s *Service) signEnvelope(ctx context.Context, typ string, payload map[string]interface{}) (_ signedEnvelope, err error) {
+	ctx, span := tracing.Start(ctx, "signing.sign")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttribute("signing.envelope_type", typ)
+
+	signedAt := time.Now().UTC().Truncate(time.Microsecond)
+	signerID := s.signer.SignerID()
+	for attempt := 0; attempt < 2; attempt++ {
+		env := signing.NewEnvelope(typ, signerID, signedAt, payload)
+		digest, err := env.Hash()
+		if err != nil {
+			return signedEnvelope{}, err
+		}
+		var (
+			sig      []byte
+			reported string
+		)
+		if ds, ok := s.signer.(signing.DocumentSigner); ok {
+			sig, reported, err = ds.SignDocument(ctx, env)
+		} else {
+			sig, err = s.signer.Sign(ctx, digest)
+			reported = s.signer.SignerID()
+		}
+		if err != nil {
+			return signedEnvelope{}, err
+		}
+		span.SetAttribute("signing.signer_id", reported)
+		if reported != "" && reported != signerID {
+			signerID = reported
+			continue
+		}
+		return signedEnvelope{
+			envelope:  env,
+			hash:      hex.EncodeToString(digest),
+			signature: base64.StdEncoding.EncodeToString(sig),
+			signedAt:  signedAt,
+		}, nil
+	}
+	return signedEnvelope{}, fmt.Errorf("signer id changed while signing (now %q)", signerID)
+}
+
+// apply records the signature on a promotion status update.
+func (e signedEnvelope) apply(update *store.PromotionStatusUpdate) {
+	update.Signature = &e.signature
+	update.SignerID = &e.envelope.SignerID
+	update.SignatureHash = &e.hash
+	update.SignatureVersion = &e.envelope.Version
+	update.SignedAt = &e.signedAt
+}
+
+func defaultJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage("{}")
+	}
+	return raw
+}
+
+// artifactPayload is the envelope payload for a model artifact. It is rebuilt from the
+// stored record on verification, so it may only use persisted fields. Artifacts without
+// parents omit the parents key so their payload is unchanged.
+func artifactPayload(a models.ModelArtifact) map[string]interface{} {
+	payload := map[string]interface{}{
+		"artifactId":          a.ID.String(),
+		"trainingJobId":       a.TrainingJobID.String(),
+		"artifactUri":         a.ArtifactURI,
+		"checksum":            a.Checksum,
+		"metadata":            defaultJSON(a.Metadata),
+		"manifestSignatureId": nil,
+	}
+	if a.ManifestSignatureID != nil {
+		payload["manifestSignatureId"] = *a.ManifestSignatureID
+	}
+	if len(a.Parents) > 0 {
+		payload["parents"] = a.Parents
+	}
+	return payload
+}
+
+// promotionPayload is the envelope payload for a signed promotion. Promotions decided by
+// the canary controller also carry their canary state, environment rollbacks their
+// rollback record and promotions signed through the Kernel their manifest signature;
+// others omit those keys so their payload is unchanged.
+func promotionPayload(p models.ModelPromotion) map[string]interface{} {
+	payload := map[string]interface{}{
+		"promotionId": p.ID.String(),
+		"artifactId":  p.ArtifactID.String(),
+		"environment": p.Environment,
+		"evaluation":  defaultJSON(p.Evaluation),
+		"requestedBy": p.PromotedBy,
+	}
+	if p.Canary != nil {
+		payload["canary"] = p.Canary
+	}
+	if p.Rollback != nil {
+		payload["rollback"] = p.Rollback
+	}
+	if p.ManifestSignatureID != nil {
+		payload["manifestSignatureId"] = *p.ManifestSignatureID
+	}
+	return payload
+}
+
+// SignatureCheck is the verification outcome for one signed record.
+type SignatureCheck struct {
+	ID               uuid.UUID `json:"id"`
+	Type             string    `json:"type"`
+	SignerID         string    `json:"signerId"`
+	SignatureVersion string    `json:"signatureVersion,omitempty"`
+	StoredHash       string    `json:"storedHash,omitempty"`
+	ComputedHash     string    `json:"computedHash,omitempty"`
+	HashOK           bool      `json:"hashOk"`
+	SignatureOK      bool      `json:"signatureOk"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// VerificationResult reports whether an artifact and its applied promotions still match
+// their signatures. Verification is the signed record of a recorded verification.
+type VerificationResult struct {
+	ArtifactID   uuid.UUID                    `json:"artifactId"`
+	OK           bool                         `json:"ok"`
+	Artifact     SignatureCheck               `json:"artifact"`
+	Promotions   []SignatureCheck             `json:"promotions"`
+	Verification *models.ArtifactVerification `json:"verification,omitempty"`
+}
+
+// VerifyArtifact re-derives the signing envelopes of an artifact and its signed
+// promotions from stored data, compares them with the stored hashes and checks each
+// signature against the configured verifier keys.
+func (s *Service) VerifyArtifact(ctx context.Context, id uuid.UUID) (VerificationResult, error) {
+	artifact, err := s.store.GetArtifact(ctx, id)
+	if err != nil {
+		return VerificationResult{}, err
+	}
+	promotions, err := s.store.ListPromotionsByArtifact(ctx, id)
+	if err != nil {
+		return VerificationResult{}, err
+	}
+
+	result := VerificationResult{ArtifactID: id, Promotions: []SignatureCheck{}}
+	result.Artifact = s.checkEnvelope(artifact.ID, signing.EnvelopeTypeArtifact, artifactPayload(artifact),
+		artifact.SignerID, artifact.Signature, artifact.SignatureHash, artifact.SignatureVersion, artifact.SignedAt)
+	result.OK = result.Artifact.HashOK && result.Artifact.SignatureOK
+	for _, promo := range promotions {
+		if promo.Signature == nil {
+			continue // rejected, pending and canary promotions are not signed
+		}
+		check := s.checkEnvelope(promo.ID, signing.EnvelopeTypePromotion, promotionPayload(promo),
+			deref(promo.SignerID), *promo.Signature, deref(promo.SignatureHash), deref(promo.SignatureVersion), promo.SignedAt)
+		result.OK = result.OK && check.HashOK && check.SignatureOK
+		result.Promotions = append(result.Promotions, check)
+	}
+	return result, nil
+}
+
+func (s *Service) checkEnvelope(id uuid.UUID, typ string, payload map[string]interface{}, signerID, signature, storedHash, version string, signedAt *time.Time) SignatureCheck {
+	check := SignatureCheck{
+		ID:               id,
+		Type:             typ,
+		SignerID:         signerID,
+		SignatureVersion: version,
+		StoredHash:       storedHash,
+	}
+	if version != signing.EnvelopeVersion || signedAt == nil {
+		check.Error = fmt.Sprintf("unsupported signature version %q", version)
+		return check
+	}
+	env := signing.NewEnvelope(typ, signerID, *signedAt, payload)
+	digest, err := env.Hash()
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.ComputedHash = hex.EncodeToString(digest)
+	check.HashOK = check.ComputedHash == storedHash
+	if !check.HashOK {
+		check.Error = "envelope hash mismatch"
+		return check
+	}
+	if s.verifier == nil {
+		check.Error = "no verification keys configured"
+		return check
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		check.Error = fmt.Sprintf("decode signature: %v", err)
+		return check
+	}
+	if err := s.verifier.Verify(signerID, digest, sig); err != nil {
+		if errors.Is(err, signing.ErrUnknownSigner) {
+			check.Error = err.Error()
+		} else {
+			check.Error = "signature does not match envelope hash"
+		}
+		return check
+	}
+	check.SignatureOK = true
+	return check
+}
+
+func deref(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/signing"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/storage"
+	"github.com/ILLUVRSE/Main/ai-infra/internal/store"
+)
+
+const (
+	defaultVerificationListLimit = 50
+	maxVerificationListLimit     = 500
+)
+
+// SetArtifactFetcher configures how checksum verification reads artifact content. A
+// requested checksum check fails when none is set.
+func (s *Service) SetArtifactFetcher(f storage.Fetcher) {
+	s.fetcher = f
+}
+
+// VerifyRequest selects the checks of a recorded verification beyond the signature
+// check, which always runs.
+type VerifyRequest struct {
+	// Checksum fetches the artifact and compares its sha256 with the registered checksum.
+	Checksum bool `json:"checksum"`
+	// ManifestSignature checks the Kernel manifest signature the artifact references.
+	ManifestSignature bool `json:"manifestSignature"`
+	// Reproduce re-runs the artifact's training job and compares the checksum of its
+	// output; the verification stays pending until that job finishes.
+	Reproduce   bool   `json:"reproduce"`
+	RequestedBy string `json:"requestedBy"`
+}
+
+// VerifyAndRecord verifies an artifact's signatures and the checks selected by req and
+// records the outcome as a signed verification. With req.Reproduce the returned
+// verification is pending until its reproduction job finishes.
+func (s *Service) VerifyAndRecord(ctx context.Context, id uuid.UUID, req VerifyRequest) (VerificationResult, error) {
+	result, err := s.VerifyArtifact(ctx, id)
+	if err != nil {
+		return VerificationResult{}, err
+	}
+	artifact, err := s.store.GetArtifact(ctx, id)
+	if err != nil {
+		return VerificationResult{}, err
+	}
+	if req.RequestedBy == "" {
+		req.RequestedBy = "ai-infra"
+	}
+	v := models.ArtifactVerification{
+		ID:          uuid.New(),
+		ArtifactID:  id,
+		Status:      models.VerificationPending,
+		Checks:      []models.VerificationCheck{signatureCheck(result)},
+		RequestedBy: req.RequestedBy,
+	}
+	if req.Checksum {
+		v.Checks = append(v.Checks, s.checksumCheck(ctx, artifact))
+	}
+	if req.ManifestSignature {
+		v.Checks = append(v.Checks, s.manifestSignatureCheck(ctx, artifact))
+	}
+
+	var original models.TrainingJob
+	if req.Reproduce {
+		original, err = s.store.GetTrainingJob(ctx, artifact.TrainingJobID)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			v.Checks = append(v.Checks, models.VerificationCheck{Name: models.CheckReproduction, Status: models.CheckSkipped,
+				Detail: fmt.Sprintf("training job %s not found", artifact.TrainingJobID)})
+			req.Reproduce = false
+		case err != nil:
+			return VerificationResult{}, err
+		default:
+			jobID := uuid.New()
+			v.ReproJobID = &jobID
+			v.Checks = append(v.Checks, models.VerificationCheck{Name: models.CheckReproduction, Status: models.CheckPending,
+				Expected: artifact.Checksum, Detail: fmt.Sprintf("waiting for training job %s", jobID)})
+		}
+	}
+
+	v, err = s.store.CreateVerification(ctx, v)
+	if err != nil {
+		return VerificationResult{}, err
+	}
+	if req.Reproduce {
+		// The verification exists before its job, so a worker finishing the job always
+		// finds it.
+		if _, err := s.createReproductionJob(ctx, *v.ReproJobID, original, artifact.ID); err != nil {
+			setCheck(&v, models.VerificationCheck{Name: models.CheckReproduction, Status: models.CheckFailed,
+				Expected: artifact.Checksum, Detail: fmt.Sprintf("create reproduction job: %v", err)})
+			if _, completeErr := s.completeVerification(ctx, v); completeErr != nil {
+				err = errors.Join(err, completeErr)
+			}
+			return VerificationResult{}, fmt.Errorf("create reproduction job: %w", err)
+		}
+	} else if v, err = s.completeVerification(ctx, v); err != nil {
+		return VerificationResult{}, err
+	}
+	result.Verification = &v
+	result.OK = verificationOK(v)
+	return result, nil
+}
+
+// createReproductionJob queues a copy of original's training run under id. It fails if
+// the run used a dataset version that has since been revoked.
+func (s *Service) createReproductionJob(ctx context.Context, id uuid.UUID, original models.TrainingJob, artifactID uuid.UUID) (models.TrainingJob, error) {
+	datasetRefs, datasets, err := s.resolveDatasetRefs(ctx, original.DatasetRefs)
+	if err != nil {
+		return models.TrainingJob{}, err
+	}
+	return s.store.CreateTrainingJob(ctx, store.TrainingJobInput{
+		ID:                   id,
+		CodeRef:              original.CodeRef,
+		ContainerDigest:      original.ContainerDigest,
+		Hyperparams:          original.Hyperparams,
+		DatasetRefs:          datasetRefs,
+		Datasets:             datasets,
+		Seed:                 original.Seed,
+		Status:               models.JobQueued,
+		MaxAttempts:          original.MaxAttempts,
+		MaxRuntimeSeconds:    original.MaxRuntimeSeconds,
+		ReproducesArtifactID: &artifactID,
+	})
+}
+
+// CompleteReproduction records that workerID finished a reproduction job whose output
+// has the given checksum, and completes the verification waiting on it.
+func (s *Service) CompleteReproduction(ctx context.Context, id uuid.UUID, workerID, checksum string) (models.ArtifactVerification, error) {
+	job, err := s.SucceedTrainingJob(ctx, id, workerID)
+	if err != nil {
+		return models.ArtifactVerification{}, err
+	}
+	if job.ReproducesArtifactID == nil {
+		return models.ArtifactVerification{}, fmt.Errorf("training job %s is not a reproduction", id)
+	}
+	v, err := s.store.GetVerificationByReproJob(ctx, id)
+	if err != nil {
+		return models.ArtifactVerification{}, err
+	}
+	artifact, err := s.store.GetArtifact(ctx, *job.ReproducesArtifactID)
+	if err != nil {
+		return models.ArtifactVerification{}, err
+	}
+	check := models.VerificationCheck{Name: models.CheckReproduction, Status: models.CheckPassed,
+		Expected: artifact.Checksum, Actual: checksum}
+	if normalizeChecksum(checksum) != normalizeChecksum(artifact.Checksum) {
+		check.Status = models.CheckFailed
+		check.Detail = fmt.Sprintf("training job %s produced a different artifact", id)
+	}
+	setCheck(&v, check)
+	return s.completeVerification(ctx, v)
+}
+
+// failReproduction completes the verification waiting on a reproduction job that ended
+// without succeeding. Errors are logged: the job's own transition already happened.
+func (s *Service) failReproduction(ctx context.Context, job models.TrainingJob) {
+	if job.ReproducesArtifactID == nil {
+		return
+	}
+	v, err := s.store.GetVerificationByReproJob(ctx, job.ID)
+	if err == nil {
+		detail := fmt.Sprintf("training job %s is %s", job.ID, job.Status)
+		if job.LastError != "" {
+			detail += ": " + job.LastError
+		}
+		setCheck(&v, models.VerificationCheck{Name: models.CheckReproduction, Status: models.CheckFailed, Detail: detail})
+		_, err = s.completeVerification(ctx, v)
+	}
+	if err != nil && !errors.Is(err, store.ErrVerificationCompleted) {
+		log.Printf("[verify] complete verification for reproduction job %s: %v", job.ID, err)
+	}
+}
+
+// GetVerification returns a recorded verification.
+func (s *Service) GetVerification(ctx context.Context, id uuid.UUID) (models.ArtifactVerification, error) {
+	return s.store.GetVerification(ctx, id)
+}
+
+// ListVerifications returns an artifact's verifications, newest first. The limit defaults
+// to 50 and is capped at 500.
+func (s *Service) ListVerifications(ctx context.Context, artifactID uuid.UUID, limit int) ([]models.ArtifactVerification, error) {
+	if _, err := s.store.GetArtifact(ctx, artifactID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultVerificationListLimit
+	}
+	if limit > maxVerificationListLimit {
+		limit = maxVerificationListLimit
+	}
+	return s.store.ListVerificationsByArtifact(ctx, artifactID, limit)
+}
+
+// completeVerification decides a verification from its checks, signs it and stores it.
+func (s *Service) completeVerification(ctx context.Context, v models.ArtifactVerification) (models.ArtifactVerification, error) {
+	v.Status = models.VerificationPassed
+	if !verificationOK(v) {
+		v.Status = models.VerificationFailed
+	}
+	completedAt := time.Now().UTC().Truncate(time.Microsecond)
+	v.CompletedAt = &completedAt
+	signed, err := s.signEnvelope(ctx, signing.EnvelopeTypeVerification, verificationPayload(v))
+	if err != nil {
+		return models.ArtifactVerification{}, fmt.Errorf("sign verification: %w", err)
+	}
+	v.Signature = &signed.signature
+	v.SignerID = &signed.envelope.SignerID
+	v.SignatureHash = &signed.hash
+	v.SignatureVersion = &signed.envelope.Version
+	v.SignedAt = &signed.signedAt
+	completed, err := s.store.CompleteVerification(ctx, v)
+	if err != nil {
+		return models.ArtifactVerification{}, err
+	}
+	auditPayload := map[string]interface{}{
+		"verificationId": completed.ID.String(),
+		"artifactId":     completed.ArtifactID.String(),
+		"status":         completed.Status,
+		"checks":         completed.Checks,
+		"signerId":       signed.envelope.SignerID,
+		"signatureHash":  signed.hash,
+	}
+	if completed.ReproJobID != nil {
+		auditPayload["reproJobId"] = completed.ReproJobID.String()
+	}
+	s.emitAudit(ctx, "registry.artifact.verified", auditPayload)
+	return completed, nil
+}
+
+// verificationPayload is the envelope payload for a completed verification.
+func verificationPayload(v models.ArtifactVerification) map[string]interface{} {
+	payload := map[string]interface{}{
+		"verificationId": v.ID.String(),
+		"artifactId":     v.ArtifactID.String(),
+		"status":         v.Status,
+		"checks":         v.Checks,
+		"requestedBy":    v.RequestedBy,
+		"completedAt":    signing.FormatSignedAt(*v.CompletedAt),
+		"reproJobId":     nil,
+	}
+	if v.ReproJobID != nil {
+		payload["reproJobId"] = v.ReproJobID.String()
+	}
+	return payload
+}
+
+// signatureCheck summarizes the signature checks of result as one verification check.
+func signatureCheck(result VerificationResult) models.VerificationCheck {
+	check := models.VerificationCheck{
+		Name:     models.CheckSignature,
+		Status:   models.CheckPassed,
+		Expected: result.Artifact.StoredHash,
+		Actual:   result.Artifact.ComputedHash,
+	}
+	if result.OK {
+		return check
+	}
+	check.Status = models.CheckFailed
+	var problems []string
+	for _, c := range append([]SignatureCheck{result.Artifact}, result.Promotions...) {
+		if c.Error != "" {
+			problems = append(problems, fmt.Sprintf("%s %s: %s", c.Type, c.ID, c.Error))
+		}
+	}
+	check.Detail = strings.Join(problems, "; ")
+	return check
+}
+
+// checksumCheck fetches an artifact and compares its sha256 with the registered checksum.
+// The check was asked for, so an artifact that cannot be read, including one at a URI
+// the fetcher refuses, fails it rather than letting the verification pass unchecked.
+func (s *Service) checksumCheck(ctx context.Context, artifact models.ModelArtifact) models.VerificationCheck {
+	check := models.VerificationCheck{Name: models.CheckChecksum, Expected: artifact.Checksum}
+	if s.fetcher == nil {
+		check.Status, check.Detail = models.CheckFailed, "no artifact fetcher configured"
+		return check
+	}
+	sum, _, err := storage.Checksum(ctx, s.fetcher, artifact.ArtifactURI)
+	switch {
+	case errors.Is(err, storage.ErrUnsupportedURI):
+		check.Status, check.Detail = models.CheckFailed, fmt.Sprintf("artifact cannot be checked: %v", err)
+	case err != nil:
+		check.Status, check.Detail = models.CheckFailed, err.Error()
+	case normalizeChecksum(sum) != normalizeChecksum(artifact.Checksum):
+		check.Status, check.Actual, check.Detail = models.CheckFailed, sum, "artifact content does not match its checksum"
+	default:
+		check.Status, check.Actual = models.CheckPassed, sum
+	}
+	return check
+}
+
+// manifestSignatureCheck verifies the Kernel manifest signature an artifact references.
+// Without a Kernel the signature cannot be looked up, so the check is skipped and the
+// reference is only covered by the artifact's own signed payload.
+func (s *Service) manifestSignatureCheck(ctx context.Context, artifact models.ModelArtifact) models.VerificationCheck {
+	check := models.VerificationCheck{Name: models.CheckManifestSignature, Status: models.CheckSkipped}
+	if artifact.ManifestSignatureID != nil {
+		check.Expected = *artifact.ManifestSignatureID
+	}
+	if s.kernel == nil {
+		check.Detail = "no Kernel configured; the id is covered by the artifact signature"
+		if artifact.ManifestSignatureID == nil {
+			check.Detail = "artifact has no manifest signature"
+		}
+		return check
+	}
+	ms, err := s.verifyArtifactManifest(ctx, artifact)
+	switch {
+	case errors.Is(err, ErrManifestSignature):
+		check.Status, check.Detail = models.CheckFailed, err.Error()
+	case err != nil:
+		check.Status, check.Detail = models.CheckFailed, fmt.Sprintf("verify manifest signature: %v", err)
+	default:
+		check.Status, check.Actual, check.Detail = models.CheckPassed, ms.ManifestHash, "signed by Kernel signer "+ms.SignerID
+	}
+	return check
+}
+
+func setCheck(v *models.ArtifactVerification, check models.VerificationCheck) {
+	for i := range v.Checks {
+		if v.Checks[i].Name == check.Name {
+			v.Checks[i] = check
+			return
+		}
+	}
+	v.Checks = append(v.Checks, check)
+}
+
+func verificationOK(v models.ArtifactVerification) bool {
+	for _, c := range v.Checks {
+		if c.Status == models.CheckFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeChecksum makes hex checksums with and without a "sha256:" prefix comparable.
+func normalizeChecksum(sum string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(sum), "sha256:"))
+}
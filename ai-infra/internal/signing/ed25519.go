@@ -36,3 +36,8 @@ func (s *Ed25519Signer) Sign(ctx context.Context, payload []byte) ([]byte, error
 func (s *Ed25519Signer) SignerID() string {
 	return s.signerID
 }
+
+// PublicKey returns the verification key matching this signer.
+func (s *Ed25519Signer) PublicKey() ed25519.PublicKey {
+	return s.privateKey.Public().(ed25519.PublicKey)
+}
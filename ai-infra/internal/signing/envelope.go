@@ -0,0 +1,86 @@
+package signing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/ILLUVRSE/Main/shared/canonical"
+)
+
+// EnvelopeVersion identifies the signing envelope layout below. Bump it whenever a
+// field is added, removed or its encoding changes.
+const EnvelopeVersion = "ai-infra.signing.v1"
+
+// AlgorithmEd25519 is the only signature algorithm ai-infra signers produce.
+const AlgorithmEd25519 = "Ed25519"
+
+const (
+	EnvelopeTypeArtifact     = "model_artifact"
+	EnvelopeTypePromotion    = "model_promotion"
+	EnvelopeTypeVerification = "artifact_verification"
+)
+
+// Envelope is the document whose hash ai-infra signs for artifacts, promotions and
+// verifications:
+//
+//	{"algorithm":"Ed25519","payload":{...},"signedAt":"<RFC3339 UTC, µs>","signerId":"...","type":"model_artifact","version":"ai-infra.signing.v1"}
+//
+// The hash is sha256 over the RFC 8785 (JCS) canonical form of the envelope, so any
+// party holding the stored record can rebuild the exact signed bytes. Payload must be
+// JSON-compatible (maps, strings, numbers, json.RawMessage).
+type Envelope struct {
+	Version   string      `json:"version"`
+	Type      string      `json:"type"`
+	Algorithm string      `json:"algorithm"`
+	SignerID  string      `json:"signerId"`
+	SignedAt  string      `json:"signedAt"`
+	Payload   interface{} `json:"payload"`
+}
+
+// NewEnvelope builds a current-version envelope. signedAt is truncated to microseconds so
+// it survives a Postgres TIMESTAMPTZ round trip unchanged.
+func NewEnvelope(typ, signerID string, signedAt time.Time, payload interface{}) Envelope {
+	return Envelope{
+		Version:   EnvelopeVersion,
+		Type:      typ,
+		Algorithm: AlgorithmEd25519,
+		SignerID:  signerID,
+		SignedAt:  FormatSignedAt(signedAt),
+		Payload:   payload,
+	}
+}
+
+// FormatSignedAt renders a signing timestamp the way envelopes carry it.
+func FormatSignedAt(t time.Time) string {
+	return t.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
+
+// Canonical returns the JCS bytes of the envelope.
+func (e Envelope) Canonical() ([]byte, error) {
+	b, err := canonical.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalize envelope: %w", err)
+	}
+	return b, nil
+}
+
+// Hash returns sha256(canonical(envelope)), the digest that is signed.
+func (e Envelope) Hash() ([]byte, error) {
+	b, err := e.Canonical()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	return sum[:], nil
+}
+
+// HashHex is Hash encoded as lowercase hex, the form stored next to signatures.
+func (e Envelope) HashHex() (string, error) {
+	h, err := e.Hash()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h), nil
+}
@@ -0,0 +1,34 @@
+package signing
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEnvelopeCanonicalIsIndependentOfClientFormatting(t *testing.T) {
+	signedAt := time.Date(2025, 1, 10, 12, 0, 10, 123456789, time.UTC)
+	a := NewEnvelope(EnvelopeTypeArtifact, "signer-1", signedAt, map[string]interface{}{
+		"checksum": "abc",
+		"metadata": json.RawMessage(`{"version": "2.2", "framework":"torch", "score": 0.90}`),
+	})
+	b := NewEnvelope(EnvelopeTypeArtifact, "signer-1", signedAt, map[string]interface{}{
+		"metadata": json.RawMessage(`{"score":0.9,"framework":"torch","version":"2.2"}`),
+		"checksum": "abc",
+	})
+
+	got, err := a.Canonical()
+	if err != nil {
+		t.Fatalf("canonical: %v", err)
+	}
+	want := `{"algorithm":"Ed25519","payload":{"checksum":"abc","metadata":{"framework":"torch","score":0.9,"version":"2.2"}},"signedAt":"2025-01-10T12:00:10.123456Z","signerId":"signer-1","type":"model_artifact","version":"ai-infra.signing.v1"}`
+	if string(got) != want {
+		t.Fatalf("want %s\n got %s", want, got)
+	}
+
+	ha, _ := a.HashHex()
+	hb, _ := b.HashHex()
+	if ha != hb {
+		t.Fatalf("hash differs across formatting: %s vs %s", ha, hb)
+	}
+}
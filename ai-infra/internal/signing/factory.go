@@ -6,12 +6,18 @@ import (
 	"github.com/ILLUVRSE/Main/ai-infra/internal/config"
 )
 
-func NewSignerFromConfig(cfg config.Config) (Signer, error) {
+// NewSignerFromConfig prefers the Kernel (the signing authority) when a client is
+// given, then a KMS endpoint, then a local Ed25519 key.
+func NewSignerFromConfig(cfg config.Config, kernel KernelClient) (Signer, error) {
+	if kernel != nil {
+		return NewKernelSigner(kernel, cfg.SignerID), nil
+	}
 	if cfg.KMSEndpoint != "" {
 		return NewKMSSigner(KMSSignerConfig{
 			Endpoint: cfg.KMSEndpoint,
 			Timeout:  5 * time.Second,
 			Retries:  2,
+			SignerID: cfg.SignerID,
 		})
 	}
 	return NewEd25519SignerFromB64(cfg.SignerKeyB64, cfg.SignerID)
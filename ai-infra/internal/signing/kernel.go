@@ -0,0 +1,85 @@
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+)
+
+// ErrDigestSigningUnsupported is returned by KernelSigner.Sign: the Kernel signs
+// documents (sha256 of their canonical JSON), not caller-supplied digests.
+var ErrDigestSigningUnsupported = errors.New("kernel signer signs documents, not raw digests")
+
+// DocumentSigner signs sha256(JCS(doc)) and reports the signer that produced it.
+type DocumentSigner interface {
+	SignDocument(ctx context.Context, doc interface{}) ([]byte, string, error)
+}
+
+// KernelClient is the subset of the Kernel API used for signing.
+type KernelClient interface {
+	Sign(ctx context.Context, manifest interface{}, version string) (kernelclient.ManifestSignature, error)
+}
+
+// KernelSigner requests signatures from Kernel POST /kernel/sign. Because the Kernel
+// hashes the canonical manifest, signing an Envelope yields a signature over exactly
+// Envelope.Hash().
+type KernelSigner struct {
+	client KernelClient
+
+	mu       sync.RWMutex
+	signerID string
+}
+
+func NewKernelSigner(client KernelClient, signerID string) *KernelSigner {
+	return &KernelSigner{client: client, signerID: signerID}
+}
+
+func (k *KernelSigner) SignDocument(ctx context.Context, doc interface{}) ([]byte, string, error) {
+	ms, err := k.client.Sign(ctx, doc, EnvelopeVersion)
+	if err != nil {
+		return nil, "", fmt.Errorf("kernel sign: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(ms.Signature)
+	if err != nil {
+		return nil, "", fmt.Errorf("kernel sign: decode signature: %w", err)
+	}
+	k.mu.Lock()
+	k.signerID = ms.SignerID
+	k.mu.Unlock()
+	return sig, ms.SignerID, nil
+}
+
+func (k *KernelSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	return nil, ErrDigestSigningUnsupported
+}
+
+func (k *KernelSigner) SignerID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.signerID
+}
+
+// KeyRingFromKernel builds a KeyRing from the Kernel's published Ed25519 signer keys.
+func KeyRingFromKernel(ctx context.Context, client KernelSigners) (*KeyRing, error) {
+	signers, err := client.Signers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ring := NewKeyRing()
+	for _, sk := range signers {
+		if sk.Algorithm != "" && sk.Algorithm != AlgorithmEd25519 {
+			continue
+		}
+		pub, err := base64.StdEncoding.DecodeString(sk.PublicKey)
+		if err != nil || len(pub) != ed25519.PublicKeySize {
+			continue
+		}
+		ring.Add(sk.SignerID, ed25519.PublicKey(pub))
+	}
+	return ring, nil
+}
@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 type KMSSignerConfig struct {
@@ -17,6 +19,8 @@ type KMSSignerConfig struct {
 	HTTPClient *http.Client
 	Timeout    time.Duration
 	Retries    int
+	// SignerID seeds SignerID() before the first response reports the KMS key id.
+	SignerID string
 }
 
 type KMSSigner struct {
@@ -39,6 +43,7 @@ func NewKMSSigner(cfg KMSSignerConfig) (*KMSSigner, error) {
 			Timeout: 10 * time.Second,
 		}
 	}
+	client = tracing.WrapClient(client)
 	timeout := cfg.Timeout
 	if timeout <= 0 {
 		timeout = 5 * time.Second
@@ -53,6 +58,7 @@ func NewKMSSigner(cfg KMSSignerConfig) (*KMSSigner, error) {
 		client:   client,
 		timeout:  timeout,
 		retries:  retries,
+		signerID: cfg.SignerID,
 	}, nil
 }
 
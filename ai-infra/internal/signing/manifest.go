@@ -0,0 +1,92 @@
+package signing
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ILLUVRSE/Main/shared/canonical"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+)
+
+// ManifestVersion identifies the layout of the artifact and promotion manifests ai-infra
+// asks the Kernel to sign. Bump it whenever a manifest field is added, removed or its
+// encoding changes.
+const ManifestVersion = "ai-infra.manifest.v1"
+
+const (
+	ManifestTypeArtifact  = "artifact"
+	ManifestTypePromotion = "promotion"
+)
+
+// ManifestHash returns sha256(canonical(manifest)), the digest the Kernel signs for
+// POST /kernel/sign.
+func ManifestHash(manifest interface{}) ([]byte, error) {
+	b, err := canonical.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalize manifest: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return sum[:], nil
+}
+
+// KernelSigners lists the Kernel's published signer keys.
+type KernelSigners interface {
+	Signers(ctx context.Context) ([]kernelclient.SignerKey, error)
+}
+
+// KernelKeys is a Verifier backed by the Kernel's published signer keys. Keys are fetched
+// on first use and refetched when a signature names an unknown signer, at most once per
+// refresh interval, so rotated Kernel keys are picked up without a restart.
+type KernelKeys struct {
+	client   KernelSigners
+	interval time.Duration
+	timeout  time.Duration
+
+	mu        sync.Mutex
+	ring      *KeyRing
+	fetchedAt time.Time
+}
+
+func NewKernelKeys(client KernelSigners, interval time.Duration) *KernelKeys {
+	return &KernelKeys{client: client, interval: interval, timeout: 5 * time.Second}
+}
+
+func (k *KernelKeys) Verify(signerID string, digest, signature []byte) error {
+	ring, err := k.keys(false)
+	if err != nil {
+		return err
+	}
+	err = ring.Verify(signerID, digest, signature)
+	if !errors.Is(err, ErrUnknownSigner) {
+		return err
+	}
+	if ring, err = k.keys(true); err != nil {
+		return err
+	}
+	return ring.Verify(signerID, digest, signature)
+}
+
+// keys returns the cached key ring, fetching it when there is none or, with refresh, when
+// the last fetch is older than the refresh interval.
+func (k *KernelKeys) keys(refresh bool) (*KeyRing, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.ring != nil && (!refresh || time.Since(k.fetchedAt) < k.interval) {
+		return k.ring, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
+	defer cancel()
+	ring, err := KeyRingFromKernel(ctx, k.client)
+	if err != nil {
+		if k.ring != nil {
+			return k.ring, nil
+		}
+		return nil, fmt.Errorf("fetch kernel signer keys: %w", err)
+	}
+	k.ring, k.fetchedAt = ring, time.Now()
+	return ring, nil
+}
@@ -0,0 +1,85 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownSigner    = errors.New("unknown signer")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+// Verifier checks a signature over an envelope digest for a given signer.
+type Verifier interface {
+	Verify(signerID string, digest, signature []byte) error
+}
+
+// KeyRing is a Verifier backed by known Ed25519 public keys.
+type KeyRing struct {
+	mu   sync.RWMutex
+	keys map[string]ed25519.PublicKey
+}
+
+func NewKeyRing() *KeyRing {
+	return &KeyRing{keys: map[string]ed25519.PublicKey{}}
+}
+
+func (k *KeyRing) Add(signerID string, pub ed25519.PublicKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[signerID] = pub
+}
+
+// Merge copies every key of other into k.
+func (k *KeyRing) Merge(other *KeyRing) {
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for id, pub := range other.keys {
+		k.keys[id] = pub
+	}
+}
+
+// ParseKeyRing reads "signerId=base64PublicKey" pairs separated by commas
+// (AI_INFRA_SIGNER_PUBLIC_KEYS).
+func ParseKeyRing(spec string) (*KeyRing, error) {
+	ring := NewKeyRing()
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, b64, ok := strings.Cut(part, "=")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid signer key entry %q", part)
+		}
+		pub, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, fmt.Errorf("decode public key for %s: %w", id, err)
+		}
+		if len(pub) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key size for %s", id)
+		}
+		ring.Add(id, ed25519.PublicKey(pub))
+	}
+	return ring, nil
+}
+
+func (k *KeyRing) Verify(signerID string, digest, signature []byte) error {
+	k.mu.RLock()
+	pub, ok := k.keys[signerID]
+	k.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSigner, signerID)
+	}
+	if !ed25519.Verify(pub, digest, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrBlobNotFound is returned for content a blob store does not hold.
+	ErrBlobNotFound = errors.New("blob not found")
+	// ErrChecksumMismatch is returned when content does not hash to the expected sha256.
+	ErrChecksumMismatch = errors.New("content does not match checksum")
+	// ErrInvalidSHA256 is returned for checksums that are not 64 hex digits.
+	ErrInvalidSHA256 = errors.New("invalid sha256")
+	// ErrPresignUnsupported is returned by stores that cannot issue download URLs.
+	ErrPresignUnsupported = errors.New("blob store cannot issue download urls")
+)
+
+// Blob is content held by a blob store under its sha256.
+type Blob struct {
+	SHA256    string    `json:"sha256"`
+	Size      int64     `json:"size"`
+	URI       string    `json:"uri"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BlobStore holds artifact content addressed by its sha256, so identical uploads are
+// stored once and a blob's URI pins its content.
+type BlobStore interface {
+	// Put streams r into the store and returns the blob it hashed to. When wantSHA256 is
+	// set, content hashing to anything else is discarded with ErrChecksumMismatch.
+	Put(ctx context.Context, r io.Reader, wantSHA256 string) (Blob, error)
+	Stat(ctx context.Context, sum string) (Blob, error)
+	Open(ctx context.Context, sum string) (io.ReadCloser, error)
+	Delete(ctx context.Context, sum string) error
+	List(ctx context.Context) ([]Blob, error)
+	// URI returns the artifact URI of a blob; ParseURI reverses it for URIs of this store.
+	URI(sum string) string
+	ParseURI(uri string) (sum string, ok bool)
+	// PresignGet returns a URL that downloads a blob without credentials for ttl.
+	PresignGet(ctx context.Context, sum string, ttl time.Duration) (string, error)
+}
+
+// PresignedOpener is implemented by blob stores whose download URLs are served by
+// ai-infra itself rather than by the storage backend.
+type PresignedOpener interface {
+	// OpenPresigned opens a blob if query carries a valid, unexpired download signature.
+	OpenPresigned(ctx context.Context, sum string, query url.Values) (io.ReadCloser, Blob, error)
+}
+
+// NormalizeSHA256 returns sum as 64 lowercase hex digits, accepting a "sha256:" prefix.
+func NormalizeSHA256(sum string) (string, error) {
+	sum = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(sum), "sha256:"))
+	if len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("%w %q", ErrInvalidSHA256, sum)
+	}
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", fmt.Errorf("%w %q", ErrInvalidSHA256, sum)
+	}
+	return sum, nil
+}
+
+// validSum reports whether sum is already a normalized sha256.
+func validSum(sum string) bool {
+	n, err := NormalizeSHA256(sum)
+	return err == nil && n == sum
+}
+
+// copyHashed copies r to w and returns the hex sha256 and length of what it copied.
+func copyHashed(w io.Writer, r io.Reader) (string, int64, error) {
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), r)
+	if err != nil {
+		return "", n, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// checkWant compares a hashed upload with the checksum the client expected.
+func checkWant(sum, want string) error {
+	if want != "" && sum != want {
+		return fmt.Errorf("%w: got sha256 %s, want %s", ErrChecksumMismatch, sum, want)
+	}
+	return nil
+}
+
+// WithBlobs returns a fetcher that reads URIs of blobs from the blob store and hands
+// every other URI to next (which may be nil).
+func WithBlobs(blobs BlobStore, next Fetcher) Fetcher {
+	return blobFetcher{blobs: blobs, next: next}
+}
+
+type blobFetcher struct {
+	blobs BlobStore
+	next  Fetcher
+}
+
+func (f blobFetcher) Open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if sum, ok := f.blobs.ParseURI(uri); ok {
+		return f.blobs.Open(ctx, sum)
+	}
+	if f.next == nil {
+		return nil, fmt.Errorf("%w: %s is not in the blob store", ErrUnsupportedURI, uri)
+	}
+	return f.next.Open(ctx, uri)
+}
@@ -0,0 +1,132 @@
+// Package storage holds model artifact content by hash and reads artifacts from the
+// locations their URIs name.
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedURI is returned for artifact URIs a fetcher cannot read.
+var ErrUnsupportedURI = errors.New("unsupported artifact uri")
+
+// Fetcher opens the content of an artifact URI.
+type Fetcher interface {
+	Open(ctx context.Context, uri string) (io.ReadCloser, error)
+}
+
+// URIFetcher reads file:// artifacts under FileRoots and http(s):// artifacts under
+// HTTPPrefixes. Other URIs are refused, so a registered URI cannot be used to probe the
+// service's filesystem or make it request internal addresses. A prefix matches URLs
+// with its scheme and host whose path starts with the prefix path; end it with "/" to
+// allow only what is below a directory. Redirects are followed only to allowed URLs.
+type URIFetcher struct {
+	FileRoots    []string
+	HTTPPrefixes []string
+	HTTPClient   *http.Client
+}
+
+// NewURIFetcher returns a fetcher reading files under roots and HTTP URLs under prefixes
+// with a client that gives up after timeout.
+func NewURIFetcher(roots, prefixes []string, timeout time.Duration) *URIFetcher {
+	return &URIFetcher{FileRoots: roots, HTTPPrefixes: prefixes, HTTPClient: &http.Client{Timeout: timeout}}
+}
+
+func (f *URIFetcher) Open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedURI, err)
+	}
+	switch u.Scheme {
+	case "file":
+		return f.openFile(u.Path)
+	case "http", "https":
+		if !f.allowedURL(u) {
+			return nil, fmt.Errorf("%w: %s is outside the artifact url prefixes", ErrUnsupportedURI, u.Redacted())
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, err
+		}
+		client := http.DefaultClient
+		if f.HTTPClient != nil {
+			client = f.HTTPClient
+		}
+		restricted := *client
+		restricted.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if !f.allowedURL(req.URL) {
+				return fmt.Errorf("%w: redirect to %s is outside the artifact url prefixes", ErrUnsupportedURI, req.URL.Redacted())
+			}
+			return nil
+		}
+		resp, err := restricted.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetch artifact: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("fetch artifact: status %d", resp.StatusCode)
+		}
+		return resp.Body, nil
+	}
+	return nil, fmt.Errorf("%w: no fetcher for scheme %q", ErrUnsupportedURI, u.Scheme)
+}
+
+// allowedURL reports whether u is under one of the HTTP prefixes.
+func (f *URIFetcher) allowedURL(u *url.URL) bool {
+	if u.User != nil {
+		return false
+	}
+	for _, prefix := range f.HTTPPrefixes {
+		p, err := url.Parse(prefix)
+		if err != nil || p.Host == "" {
+			continue
+		}
+		if u.Scheme == p.Scheme && strings.EqualFold(u.Host, p.Host) && strings.HasPrefix(u.Path, p.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *URIFetcher) openFile(path string) (io.ReadCloser, error) {
+	path = filepath.Clean(path)
+	for _, root := range f.FileRoots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return os.Open(path)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s is outside the artifact roots", ErrUnsupportedURI, path)
+}
+
+// Checksum returns the hex sha256 and size of the artifact at uri.
+func Checksum(ctx context.Context, f Fetcher, uri string) (string, int64, error) {
+	r, err := f.Open(ctx, uri)
+	if err != nil {
+		return "", 0, err
+	}
+	defer r.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", 0, fmt.Errorf("read artifact: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// LocalBlobStore keeps blobs on the local filesystem as <Root>/sha256/<ab>/<sum>, where
+// <ab> are the first two hex digits. Uploads are written under <Root>/uploads and renamed
+// into place once hashed, so a blob file is never partially written.
+type LocalBlobStore struct {
+	root string
+	urls *URLSigner
+}
+
+// NewLocalBlobStore creates the store's directories under root. urls signs download URLs
+// served by ai-infra; without it PresignGet returns ErrPresignUnsupported.
+func NewLocalBlobStore(root string, urls *URLSigner) (*LocalBlobStore, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{filepath.Join(root, "sha256"), filepath.Join(root, "uploads")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create blob dir: %w", err)
+		}
+	}
+	return &LocalBlobStore{root: root, urls: urls}, nil
+}
+
+// Root returns the directory holding the store.
+func (s *LocalBlobStore) Root() string {
+	return s.root
+}
+
+func (s *LocalBlobStore) path(sum string) string {
+	return filepath.Join(s.root, "sha256", sum[:2], sum)
+}
+
+func (s *LocalBlobStore) Put(ctx context.Context, r io.Reader, wantSHA256 string) (Blob, error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.root, "uploads"), "blob-*")
+	if err != nil {
+		return Blob{}, fmt.Errorf("create upload: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed into place
+	sum, _, err := copyHashed(tmp, r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Blob{}, fmt.Errorf("write upload: %w", err)
+	}
+	if err := checkWant(sum, wantSHA256); err != nil {
+		return Blob{}, err
+	}
+	final := s.path(sum)
+	if _, err := os.Stat(final); err == nil {
+		// Already stored: restart its garbage collection grace period, since the caller
+		// is about to register it.
+		now := time.Now()
+		if err := os.Chtimes(final, now, now); err != nil {
+			return Blob{}, fmt.Errorf("touch blob: %w", err)
+		}
+		return s.Stat(ctx, sum)
+	}
+	if err := os.MkdirAll(filepath.Dir(final), 0o755); err != nil {
+		return Blob{}, fmt.Errorf("create blob dir: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return Blob{}, fmt.Errorf("store blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), final); err != nil {
+		return Blob{}, fmt.Errorf("store blob: %w", err)
+	}
+	return s.Stat(ctx, sum)
+}
+
+func (s *LocalBlobStore) Stat(ctx context.Context, sum string) (Blob, error) {
+	if !validSum(sum) {
+		return Blob{}, ErrBlobNotFound
+	}
+	info, err := os.Stat(s.path(sum))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Blob{}, ErrBlobNotFound
+	}
+	if err != nil {
+		return Blob{}, fmt.Errorf("stat blob: %w", err)
+	}
+	return s.blob(sum, info), nil
+}
+
+func (s *LocalBlobStore) blob(sum string, info fs.FileInfo) Blob {
+	return Blob{SHA256: sum, Size: info.Size(), URI: s.URI(sum), CreatedAt: info.ModTime().UTC()}
+}
+
+func (s *LocalBlobStore) Open(ctx context.Context, sum string) (io.ReadCloser, error) {
+	if !validSum(sum) {
+		return nil, ErrBlobNotFound
+	}
+	f, err := os.Open(s.path(sum))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open blob: %w", err)
+	}
+	return f, nil
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, sum string) error {
+	if !validSum(sum) {
+		return ErrBlobNotFound
+	}
+	err := os.Remove(s.path(sum))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
+func (s *LocalBlobStore) List(ctx context.Context) ([]Blob, error) {
+	var blobs []Blob
+	err := filepath.WalkDir(filepath.Join(s.root, "sha256"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		sum := d.Name()
+		if !validSum(sum) {
+			return nil // not a blob
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, s.blob(sum, info))
+		return ctx.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list blobs: %w", err)
+	}
+	return blobs, nil
+}
+
+func (s *LocalBlobStore) URI(sum string) string {
+	return "file://" + s.path(sum)
+}
+
+func (s *LocalBlobStore) ParseURI(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	sum, err := NormalizeSHA256(filepath.Base(u.Path))
+	if err != nil || filepath.Clean(u.Path) != s.path(sum) {
+		return "", false
+	}
+	return sum, true
+}
+
+func (s *LocalBlobStore) PresignGet(ctx context.Context, sum string, ttl time.Duration) (string, error) {
+	if s.urls == nil {
+		return "", ErrPresignUnsupported
+	}
+	return s.urls.Sign(sum, time.Now().Add(ttl)), nil
+}
+
+func (s *LocalBlobStore) OpenPresigned(ctx context.Context, sum string, query url.Values) (io.ReadCloser, Blob, error) {
+	if s.urls == nil {
+		return nil, Blob{}, ErrPresignUnsupported
+	}
+	if err := s.urls.Verify(sum, query, time.Now()); err != nil {
+		return nil, Blob{}, err
+	}
+	blob, err := s.Stat(ctx, sum)
+	if err != nil {
+		return nil, Blob{}, err
+	}
+	rc, err := s.Open(ctx, sum)
+	return rc, blob, err
+}
+
+// ErrInvalidSignature is returned for download URLs with a bad or expired signature.
+var ErrInvalidSignature = errors.New("invalid or expired download signature")
+
+// URLSigner issues download URLs for blobs served by ai-infra, authenticated by an
+// HMAC-SHA256 of the blob and expiry:
+//
+//	<BaseURL>/<sha256>/content?expires=<unix seconds>&signature=<hex hmac>
+type URLSigner struct {
+	Key     []byte
+	BaseURL string
+}
+
+// Sign returns a download URL for a blob that is valid until expires.
+func (u *URLSigner) Sign(sum string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	q := url.Values{"expires": {exp}, "signature": {u.mac(sum, exp)}}
+	return u.BaseURL + "/" + sum + "/content?" + q.Encode()
+}
+
+// Verify checks the expires and signature parameters of a download URL.
+func (u *URLSigner) Verify(sum string, query url.Values, now time.Time) error {
+	exp := query.Get("expires")
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() > expires {
+		return ErrInvalidSignature
+	}
+	sig, err := hex.DecodeString(query.Get("signature"))
+	want, _ := hex.DecodeString(u.mac(sum, exp))
+	if err != nil || !hmac.Equal(sig, want) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (u *URLSigner) mac(sum, expires string) string {
+	m := hmac.New(sha256.New, u.Key)
+	m.Write([]byte(sum + "\n" + expires))
+	return hex.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Config selects the bucket of an S3BlobStore. Endpoint points the store at an
+// S3-compatible service (MinIO, Ceph, ...) and switches to path-style addressing.
+type S3Config struct {
+	Bucket   string
+	Prefix   string
+	Endpoint string
+}
+
+// S3BlobStore keeps blobs in an S3 bucket as <Prefix>/sha256/<sum>. The hash of an
+// upload is only known once it has been read, so uploads are spooled to a temporary file
+// and sent to their content address afterwards.
+type S3BlobStore struct {
+	bucket   string
+	prefix   string
+	client   *s3.Client
+	uploader *manager.Uploader
+	presign  *s3.PresignClient
+}
+
+// NewS3BlobStore creates an S3BlobStore. Region and credentials come from the standard
+// AWS environment (AWS_REGION, AWS_ACCESS_KEY_ID/SECRET, AWS_PROFILE, ...).
+func NewS3BlobStore(ctx context.Context, cfg S3Config) (*S3BlobStore, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("bucket required")
+	}
+	awsCfg, err := awsConfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3BlobStore{
+		bucket:   cfg.Bucket,
+		prefix:   strings.Trim(cfg.Prefix, "/"),
+		client:   client,
+		uploader: manager.NewUploader(client),
+		presign:  s3.NewPresignClient(client),
+	}, nil
+}
+
+// maxS3CopySize is the largest object S3 copies in a single CopyObject request.
+const maxS3CopySize = 5 << 30
+
+func (s *S3BlobStore) key(sum string) string {
+	return path.Join(s.prefix, "sha256", sum)
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, r io.Reader, wantSHA256 string) (Blob, error) {
+	tmp, err := os.CreateTemp("", "ai-infra-blob-*")
+	if err != nil {
+		return Blob{}, fmt.Errorf("create upload: %w", err)
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+	sum, size, err := copyHashed(tmp, r)
+	if err != nil {
+		return Blob{}, fmt.Errorf("write upload: %w", err)
+	}
+	if err := checkWant(sum, wantSHA256); err != nil {
+		return Blob{}, err
+	}
+	_, err = s.Stat(ctx, sum)
+	switch {
+	case err == nil && size <= maxS3CopySize:
+		// Already stored: copy it onto itself to restart its garbage collection grace
+		// period, since the caller is about to register it. Larger objects cannot be
+		// copied in one request and are uploaded again below.
+		if _, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:               aws.String(s.bucket),
+			Key:                  aws.String(s.key(sum)),
+			CopySource:           aws.String(s.bucket + "/" + s.key(sum)),
+			MetadataDirective:    s3types.MetadataDirectiveReplace,
+			ContentType:          aws.String("application/octet-stream"),
+			ServerSideEncryption: s3types.ServerSideEncryptionAes256,
+		}); err != nil {
+			return Blob{}, s3Error("touch blob", err)
+		}
+		return s.Stat(ctx, sum)
+	case err != nil && !errors.Is(err, ErrBlobNotFound):
+		return Blob{}, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return Blob{}, fmt.Errorf("rewind upload: %w", err)
+	}
+	if _, err := s.uploader.Upload(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(s.bucket),
+		Key:                  aws.String(s.key(sum)),
+		Body:                 tmp,
+		ContentType:          aws.String("application/octet-stream"),
+		ServerSideEncryption: s3types.ServerSideEncryptionAes256,
+	}); err != nil {
+		return Blob{}, fmt.Errorf("s3 upload failed: %w", err)
+	}
+	return s.Stat(ctx, sum)
+}
+
+func (s *S3BlobStore) Stat(ctx context.Context, sum string) (Blob, error) {
+	if !validSum(sum) {
+		return Blob{}, ErrBlobNotFound
+	}
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.key(sum))})
+	if err != nil {
+		return Blob{}, s3Error("stat blob", err)
+	}
+	blob := Blob{SHA256: sum, Size: aws.ToInt64(out.ContentLength), URI: s.URI(sum)}
+	if out.LastModified != nil {
+		blob.CreatedAt = out.LastModified.UTC()
+	}
+	return blob, nil
+}
+
+func (s *S3BlobStore) Open(ctx context.Context, sum string) (io.ReadCloser, error) {
+	if !validSum(sum) {
+		return nil, ErrBlobNotFound
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.key(sum))})
+	if err != nil {
+		return nil, s3Error("open blob", err)
+	}
+	return out.Body, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, sum string) error {
+	if !validSum(sum) {
+		return ErrBlobNotFound
+	}
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.key(sum))}); err != nil {
+		return s3Error("delete blob", err)
+	}
+	return nil
+}
+
+func (s *S3BlobStore) List(ctx context.Context) ([]Blob, error) {
+	var blobs []Blob
+	pager := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.key("") + "/"),
+	})
+	for pager.HasMorePages() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list blobs: %w", err)
+		}
+		for _, obj := range page.Contents {
+			sum := path.Base(aws.ToString(obj.Key))
+			if !validSum(sum) {
+				continue
+			}
+			blob := Blob{SHA256: sum, Size: aws.ToInt64(obj.Size), URI: s.URI(sum)}
+			if obj.LastModified != nil {
+				blob.CreatedAt = obj.LastModified.UTC()
+			}
+			blobs = append(blobs, blob)
+		}
+	}
+	return blobs, nil
+}
+
+func (s *S3BlobStore) URI(sum string) string {
+	return "s3://" + s.bucket + "/" + s.key(sum)
+}
+
+func (s *S3BlobStore) ParseURI(uri string) (string, bool) {
+	key, ok := strings.CutPrefix(uri, "s3://"+s.bucket+"/")
+	if !ok {
+		return "", false
+	}
+	sum := path.Base(key)
+	if !validSum(sum) || key != s.key(sum) {
+		return "", false
+	}
+	return sum, true
+}
+
+func (s *S3BlobStore) PresignGet(ctx context.Context, sum string, ttl time.Duration) (string, error) {
+	req, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(s.key(sum))},
+		s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("presign blob: %w", err)
+	}
+	return req.URL, nil
+}
+
+// s3Error maps missing objects to ErrBlobNotFound.
+func s3Error(op string, err error) error {
+	var (
+		notFound *s3types.NotFound
+		noSuch   *s3types.NoSuchKey
+	)
+	if errors.As(err, &notFound) || errors.As(err, &noSuch) {
+		return ErrBlobNotFound
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"sort"
 	"sync"
 	"time"
@@ -14,17 +15,34 @@ import (
 )
 
 type MemoryStore struct {
-	mu         sync.RWMutex
-	jobs       map[uuid.UUID]models.TrainingJob
-	artifacts  map[uuid.UUID]models.ModelArtifact
-	promotions map[uuid.UUID]models.ModelPromotion
+	mu           sync.RWMutex
+	jobs         map[uuid.UUID]models.TrainingJob
+	artifacts    map[uuid.UUID]models.ModelArtifact
+	promotions   map[uuid.UUID]models.ModelPromotion
+	observations map[uuid.UUID][]models.CanaryObservation
+	links        []models.ArtifactLink
+	datasets     map[uuid.UUID][]models.DatasetRef
+	jobLogs      map[uuid.UUID][]models.JobLogChunk
+	jobMetrics   map[uuid.UUID][]models.JobMetricPoint
+	verifies     map[uuid.UUID]models.ArtifactVerification
+	dsVersions   map[uuid.UUID]models.DatasetVersion
+	flags        map[uuid.UUID][]models.ArtifactFlag
+	manifests    map[string]models.ManifestSignature
 }
 
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{
-		jobs:       map[uuid.UUID]models.TrainingJob{},
-		artifacts:  map[uuid.UUID]models.ModelArtifact{},
-		promotions: map[uuid.UUID]models.ModelPromotion{},
+		jobs:         map[uuid.UUID]models.TrainingJob{},
+		artifacts:    map[uuid.UUID]models.ModelArtifact{},
+		promotions:   map[uuid.UUID]models.ModelPromotion{},
+		observations: map[uuid.UUID][]models.CanaryObservation{},
+		datasets:     map[uuid.UUID][]models.DatasetRef{},
+		jobLogs:      map[uuid.UUID][]models.JobLogChunk{},
+		jobMetrics:   map[uuid.UUID][]models.JobMetricPoint{},
+		verifies:     map[uuid.UUID]models.ArtifactVerification{},
+		dsVersions:   map[uuid.UUID]models.DatasetVersion{},
+		flags:        map[uuid.UUID][]models.ArtifactFlag{},
+		manifests:    map[string]models.ManifestSignature{},
 	}
 }
 
@@ -41,22 +59,49 @@ func (m *MemoryStore) CreateTrainingJob(ctx context.Context, in TrainingJobInput
 	}
 	now := time.Now().UTC()
 	job := models.TrainingJob{
-		ID:              in.ID,
-		CodeRef:         in.CodeRef,
-		ContainerDigest: in.ContainerDigest,
-		Hyperparams:     copyJSON(in.Hyperparams, "{}"),
-		DatasetRefs:     copyJSON(in.DatasetRefs, "[]"),
-		Seed:            in.Seed,
-		Status:          in.Status,
-		CreatedAt:       now,
-		UpdatedAt:       now,
+		ID:                in.ID,
+		CodeRef:           in.CodeRef,
+		ContainerDigest:   in.ContainerDigest,
+		Hyperparams:       copyJSON(in.Hyperparams, "{}"),
+		DatasetRefs:       copyJSON(in.DatasetRefs, "[]"),
+		Seed:              in.Seed,
+		Status:            in.Status,
+		MaxAttempts:       in.MaxAttempts,
+		MaxRuntimeSeconds: in.MaxRuntimeSeconds,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if in.ReproducesArtifactID != nil {
+		id := *in.ReproducesArtifactID
+		job.ReproducesArtifactID = &id
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	for _, ds := range in.Datasets {
+		if ds.DatasetVersionID == nil {
+			continue
+		}
+		v, ok := m.dsVersions[*ds.DatasetVersionID]
+		if !ok {
+			return models.TrainingJob{}, fmt.Errorf("dataset version %s: %w", *ds.DatasetVersionID, ErrNotFound)
+		}
+		if v.Status == models.DatasetRevoked {
+			return models.TrainingJob{}, fmt.Errorf("%w: %s", ErrDatasetRevoked, v.ID)
+		}
+	}
 	m.jobs[job.ID] = job
+	if len(in.Datasets) > 0 {
+		m.datasets[job.ID] = append([]models.DatasetRef(nil), in.Datasets...)
+	}
 	return job, nil
 }
 
+func (m *MemoryStore) ListTrainingJobDatasets(ctx context.Context, trainingJobID uuid.UUID) ([]models.DatasetRef, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]models.DatasetRef(nil), m.datasets[trainingJobID]...), nil
+}
+
 func (m *MemoryStore) GetTrainingJob(ctx context.Context, id uuid.UUID) (models.TrainingJob, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -67,16 +112,57 @@ func (m *MemoryStore) GetTrainingJob(ctx context.Context, id uuid.UUID) (models.
 	return job, nil
 }
 
-func (m *MemoryStore) ClaimNextTrainingJob(ctx context.Context) (models.TrainingJob, error) {
+func (m *MemoryStore) ListTrainingJobs(ctx context.Context, filter ListTrainingJobsFilter) ([]models.TrainingJob, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var jobs []models.TrainingJob
+	for _, job := range m.jobs {
+		if filter.Status != "" && job.Status != filter.Status {
+			continue
+		}
+		if filter.WorkerID != "" && job.WorkerID != filter.WorkerID {
+			continue
+		}
+		if filter.CodeRef != "" && job.CodeRef != filter.CodeRef {
+			continue
+		}
+		if filter.CreatedAfter != nil && job.CreatedAt.Before(*filter.CreatedAfter) {
+			continue
+		}
+		if filter.CreatedBefore != nil && !job.CreatedAt.Before(*filter.CreatedBefore) {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID.String() < jobs[j].ID.String()
+	})
+	if filter.Offset > 0 {
+		if filter.Offset >= len(jobs) {
+			return nil, nil
+		}
+		jobs = jobs[filter.Offset:]
+	}
+	if filter.Limit > 0 && len(jobs) > filter.Limit {
+		jobs = jobs[:filter.Limit]
+	}
+	return jobs, nil
+}
+
+func (m *MemoryStore) ClaimNextTrainingJob(ctx context.Context, workerID string, lease time.Duration) (models.TrainingJob, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	now := time.Now().UTC()
 	var (
 		selectedID uuid.UUID
 		selected   models.TrainingJob
 		found      bool
 	)
 	for id, job := range m.jobs {
-		if job.Status != "queued" {
+		if job.Status != models.JobQueued || (job.NextAttemptAt != nil && job.NextAttemptAt.After(now)) {
 			continue
 		}
 		if !found || job.CreatedAt.Before(selected.CreatedAt) {
@@ -88,26 +174,68 @@ func (m *MemoryStore) ClaimNextTrainingJob(ctx context.Context) (models.Training
 	if !found {
 		return models.TrainingJob{}, ErrNotFound
 	}
-	now := time.Now().UTC()
-	selected.Status = "running"
+	expires := now.Add(lease)
+	selected.Status = models.JobRunning
+	selected.Attempts++
+	selected.WorkerID = workerID
+	selected.LeaseExpiresAt = &expires
+	selected.HeartbeatAt = &now
+	selected.StartedAt = &now
+	selected.NextAttemptAt = nil
 	selected.UpdatedAt = now
 	m.jobs[selectedID] = selected
 	return selected, nil
 }
 
-func (m *MemoryStore) UpdateTrainingJobStatus(ctx context.Context, id uuid.UUID, status string) (models.TrainingJob, error) {
+func (m *MemoryStore) HeartbeatTrainingJob(ctx context.Context, id uuid.UUID, workerID string, lease time.Duration) (models.TrainingJob, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	job, ok := m.jobs[id]
 	if !ok {
 		return models.TrainingJob{}, ErrNotFound
 	}
-	job.Status = status
-	job.UpdatedAt = time.Now().UTC()
+	if job.Status != models.JobRunning || job.WorkerID != workerID {
+		return job, ErrJobConflict
+	}
+	now := time.Now().UTC()
+	expires := now.Add(lease)
+	job.LeaseExpiresAt = &expires
+	job.HeartbeatAt = &now
+	job.UpdatedAt = now
 	m.jobs[id] = job
 	return job, nil
 }
 
+func (m *MemoryStore) TransitionTrainingJob(ctx context.Context, in TrainingJobTransition) (models.TrainingJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[in.ID]
+	if !ok {
+		return models.TrainingJob{}, ErrNotFound
+	}
+	if job.Status != in.FromStatus || (in.WorkerID != "" && job.WorkerID != in.WorkerID) {
+		return job, ErrJobConflict
+	}
+	if in.LeaseExpiredBefore != nil && (job.LeaseExpiresAt == nil || !job.LeaseExpiresAt.Before(*in.LeaseExpiredBefore)) {
+		return job, ErrJobConflict
+	}
+	now := time.Now().UTC()
+	job.Status = in.Status
+	job.WorkerID = ""
+	job.LeaseExpiresAt = nil
+	job.NextAttemptAt = in.NextAttemptAt
+	job.FinishedAt = nil
+	if in.Status.Terminal() {
+		job.FinishedAt = &now
+	}
+	if in.Error != "" {
+		job.LastError = in.Error
+	}
+	job.UpdatedAt = now
+	m.jobs[in.ID] = job
+	return job, nil
+}
+
 func (m *MemoryStore) CreateArtifact(ctx context.Context, in ArtifactInput) (models.ModelArtifact, error) {
 	if in.ID == uuid.Nil {
 		in.ID = uuid.New()
@@ -123,15 +251,76 @@ func (m *MemoryStore) CreateArtifact(ctx context.Context, in ArtifactInput) (mod
 		Metadata:            copyJSON(in.Metadata, "{}"),
 		SignerID:            in.SignerID,
 		Signature:           in.Signature,
+		SignatureHash:       in.SignatureHash,
+		SignatureVersion:    in.SignatureVersion,
+		SignedAt:            in.SignedAt,
 		ManifestSignatureID: in.ManifestSignatureID,
 		CreatedAt:           time.Now().UTC(),
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.artifacts[artifact.ID] = artifact
+	for _, parent := range in.Parents {
+		m.links = append(m.links, models.ArtifactLink{ParentID: parent.ArtifactID, ChildID: artifact.ID, Relation: parent.Relation})
+	}
+	artifact.Parents = append([]models.ArtifactParent(nil), in.Parents...)
+	for _, ds := range m.datasets[artifact.TrainingJobID] {
+		if ds.DatasetVersionID == nil {
+			continue
+		}
+		if v := m.dsVersions[*ds.DatasetVersionID]; v.Status == models.DatasetRevoked {
+			flag := datasetRevokedFlag(v)
+			flag.FlaggedAt = artifact.CreatedAt
+			m.flags[artifact.ID] = append(m.flags[artifact.ID], flag)
+		}
+	}
+	artifact.Flags = append([]models.ArtifactFlag(nil), m.flags[artifact.ID]...)
 	return artifact, nil
 }
 
+func (m *MemoryStore) ReferencedArtifactURIs(ctx context.Context, uris []string) (map[string]bool, error) {
+	wanted := make(map[string]bool, len(uris))
+	for _, uri := range uris {
+		wanted[uri] = true
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	referenced := make(map[string]bool)
+	for _, artifact := range m.artifacts {
+		if wanted[artifact.ArtifactURI] {
+			referenced[artifact.ArtifactURI] = true
+		}
+	}
+	return referenced, nil
+}
+
+func (m *MemoryStore) ListArtifactParents(ctx context.Context, artifactID uuid.UUID) ([]models.ArtifactLink, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var links []models.ArtifactLink
+	for _, link := range m.links {
+		if link.ChildID == artifactID {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].ParentID.String() < links[j].ParentID.String()
+	})
+	return links, nil
+}
+
+func (m *MemoryStore) ListArtifactChildren(ctx context.Context, parentID uuid.UUID) ([]models.ArtifactLink, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var links []models.ArtifactLink
+	for _, link := range m.links {
+		if link.ParentID == parentID {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
 func (m *MemoryStore) ListArtifacts(ctx context.Context, filter ListArtifactsFilter) ([]models.ModelArtifact, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -175,6 +364,15 @@ func (m *MemoryStore) GetArtifact(ctx context.Context, id uuid.UUID) (models.Mod
 	if !ok {
 		return models.ModelArtifact{}, ErrNotFound
 	}
+	for _, link := range m.links {
+		if link.ChildID == id {
+			artifact.Parents = append(artifact.Parents, models.ArtifactParent{ArtifactID: link.ParentID, Relation: link.Relation})
+		}
+	}
+	sort.Slice(artifact.Parents, func(i, j int) bool {
+		return artifact.Parents[i].ArtifactID.String() < artifact.Parents[j].ArtifactID.String()
+	})
+	artifact.Flags = append([]models.ArtifactFlag(nil), m.flags[id]...)
 	return artifact, nil
 }
 
@@ -190,12 +388,90 @@ func (m *MemoryStore) CreatePromotion(ctx context.Context, in PromotionInput) (m
 		Evaluation:  copyJSON(in.Evaluation, "{}"),
 		CreatedAt:   time.Now().UTC(),
 	}
+	if in.Rollback != nil {
+		rollback := *in.Rollback
+		promo.Rollback = &rollback
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.promotions[promo.ID] = promo
 	return promo, nil
 }
 
+func (m *MemoryStore) GetPromotion(ctx context.Context, id uuid.UUID) (models.ModelPromotion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	promo, ok := m.promotions[id]
+	if !ok {
+		return models.ModelPromotion{}, ErrNotFound
+	}
+	return promo, nil
+}
+
+func (m *MemoryStore) ListPromotionsByStatus(ctx context.Context, status string) ([]models.ModelPromotion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var promotions []models.ModelPromotion
+	for _, promo := range m.promotions {
+		if promo.Status == status {
+			promotions = append(promotions, promo)
+		}
+	}
+	sort.Slice(promotions, func(i, j int) bool {
+		return promotions[i].CreatedAt.Before(promotions[j].CreatedAt)
+	})
+	return promotions, nil
+}
+
+func (m *MemoryStore) LatestAppliedPromotion(ctx context.Context, environment string) (models.ModelPromotion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var (
+		latest models.ModelPromotion
+		found  bool
+	)
+	for _, promo := range m.promotions {
+		if promo.Environment != environment || promo.Status != "applied" {
+			continue
+		}
+		if !found || promotedAfter(promo, latest) {
+			latest, found = promo, true
+		}
+	}
+	if !found {
+		return models.ModelPromotion{}, ErrNotFound
+	}
+	return latest, nil
+}
+
+func (m *MemoryStore) ListPromotionsByEnvironment(ctx context.Context, environment string, limit int) ([]models.ModelPromotion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var promotions []models.ModelPromotion
+	for _, promo := range m.promotions {
+		if promo.Environment == environment {
+			promotions = append(promotions, promo)
+		}
+	}
+	sort.Slice(promotions, func(i, j int) bool {
+		return promotions[i].CreatedAt.After(promotions[j].CreatedAt)
+	})
+	if limit > 0 && len(promotions) > limit {
+		promotions = promotions[:limit]
+	}
+	return promotions, nil
+}
+
+func promotedAfter(a, b models.ModelPromotion) bool {
+	switch {
+	case a.PromotedAt != nil && b.PromotedAt != nil && !a.PromotedAt.Equal(*b.PromotedAt):
+		return a.PromotedAt.After(*b.PromotedAt)
+	case (a.PromotedAt == nil) != (b.PromotedAt == nil):
+		return a.PromotedAt != nil
+	}
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
 func (m *MemoryStore) ListPromotionsByArtifact(ctx context.Context, artifactID uuid.UUID) ([]models.ModelPromotion, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -220,6 +496,16 @@ func (m *MemoryStore) UpdatePromotionStatus(ctx context.Context, in PromotionSta
 	if !ok {
 		return models.ModelPromotion{}, ErrNotFound
 	}
+	if in.FromStatus != "" && promo.Status != in.FromStatus {
+		return models.ModelPromotion{}, ErrStatusConflict
+	}
+	if in.Status == models.PromotionCanary && promo.Status != models.PromotionCanary {
+		for _, other := range m.promotions {
+			if other.ID != promo.ID && other.Environment == promo.Environment && other.Status == models.PromotionCanary {
+				return models.ModelPromotion{}, ErrCanaryExists
+			}
+		}
+	}
 	promo.Status = in.Status
 	if len(in.SentinelDecision) > 0 {
 		promo.SentinelDecision = copyJSON(in.SentinelDecision, "{}")
@@ -236,10 +522,314 @@ func (m *MemoryStore) UpdatePromotionStatus(ctx context.Context, in PromotionSta
 		id := *in.SignerID
 		promo.SignerID = &id
 	}
+	if in.SignatureHash != nil {
+		h := *in.SignatureHash
+		promo.SignatureHash = &h
+	}
+	if in.SignatureVersion != nil {
+		v := *in.SignatureVersion
+		promo.SignatureVersion = &v
+	}
+	if in.SignedAt != nil {
+		promo.SignedAt = in.SignedAt
+	}
+	if in.Canary != nil {
+		promo.Canary = copyCanary(in.Canary)
+	}
+	if in.ManifestSignatureID != nil {
+		id := *in.ManifestSignatureID
+		promo.ManifestSignatureID = &id
+	}
 	m.promotions[in.ID] = promo
 	return promo, nil
 }
 
+// copyCanary deep-copies canary state so callers cannot mutate stored records.
+func copyCanary(c *models.Canary) *models.Canary {
+	b, _ := json.Marshal(c)
+	out := &models.Canary{}
+	_ = json.Unmarshal(b, out)
+	return out
+}
+
+func (m *MemoryStore) AddCanaryObservation(ctx context.Context, obs models.CanaryObservation) error {
+	metrics := make(map[string]float64, len(obs.Metrics))
+	for k, v := range obs.Metrics {
+		metrics[k] = v
+	}
+	obs.Metrics = metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observations[obs.PromotionID] = append(m.observations[obs.PromotionID], obs)
+	return nil
+}
+
+func (m *MemoryStore) ListCanaryObservations(ctx context.Context, promotionID uuid.UUID) ([]models.CanaryObservation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	observations := make([]models.CanaryObservation, len(m.observations[promotionID]))
+	copy(observations, m.observations[promotionID])
+	return observations, nil
+}
+
+func (m *MemoryStore) AppendJobLogs(ctx context.Context, jobID uuid.UUID, chunks []models.JobLogChunk) ([]models.JobLogChunk, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.jobs[jobID]; !ok {
+		return nil, ErrNotFound
+	}
+	seq := int64(len(m.jobLogs[jobID]))
+	stored := make([]models.JobLogChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		seq++
+		chunk.JobID, chunk.Seq = jobID, seq
+		stored = append(stored, chunk)
+	}
+	m.jobLogs[jobID] = append(m.jobLogs[jobID], stored...)
+	return stored, nil
+}
+
+func (m *MemoryStore) ListJobLogs(ctx context.Context, jobID uuid.UUID, afterSeq int64, limit int) ([]models.JobLogChunk, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return pageAfter(m.jobLogs[jobID], afterSeq, limit), nil
+}
+
+func (m *MemoryStore) AppendJobMetrics(ctx context.Context, jobID uuid.UUID, points []models.JobMetricPoint) ([]models.JobMetricPoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.jobs[jobID]; !ok {
+		return nil, ErrNotFound
+	}
+	seq := int64(len(m.jobMetrics[jobID]))
+	stored := make([]models.JobMetricPoint, 0, len(points))
+	for _, point := range points {
+		metrics := make(map[string]float64, len(point.Metrics))
+		for k, v := range point.Metrics {
+			metrics[k] = v
+		}
+		seq++
+		point.JobID, point.Seq, point.Metrics = jobID, seq, metrics
+		stored = append(stored, point)
+	}
+	m.jobMetrics[jobID] = append(m.jobMetrics[jobID], stored...)
+	return stored, nil
+}
+
+func (m *MemoryStore) ListJobMetrics(ctx context.Context, jobID uuid.UUID, afterSeq int64, limit int) ([]models.JobMetricPoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return pageAfter(m.jobMetrics[jobID], afterSeq, limit), nil
+}
+
+// pageAfter returns up to limit (0 means all) entries of a job's seq-numbered rows after
+// afterSeq. Rows are stored in seq order starting at 1.
+func pageAfter[T any](rows []T, afterSeq int64, limit int) []T {
+	if afterSeq < 0 {
+		afterSeq = 0
+	}
+	if afterSeq >= int64(len(rows)) {
+		return nil
+	}
+	rows = rows[afterSeq:]
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return append([]T(nil), rows...)
+}
+
+func copyVerification(v models.ArtifactVerification) models.ArtifactVerification {
+	v.Checks = append([]models.VerificationCheck{}, v.Checks...)
+	return v
+}
+
+func (m *MemoryStore) CreateVerification(ctx context.Context, v models.ArtifactVerification) (models.ArtifactVerification, error) {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	v.CreatedAt = time.Now().UTC()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.verifies[v.ID] = copyVerification(v)
+	return copyVerification(v), nil
+}
+
+func (m *MemoryStore) GetVerification(ctx context.Context, id uuid.UUID) (models.ArtifactVerification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.verifies[id]
+	if !ok {
+		return models.ArtifactVerification{}, ErrNotFound
+	}
+	return copyVerification(v), nil
+}
+
+func (m *MemoryStore) GetVerificationByReproJob(ctx context.Context, jobID uuid.UUID) (models.ArtifactVerification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, v := range m.verifies {
+		if v.ReproJobID != nil && *v.ReproJobID == jobID {
+			return copyVerification(v), nil
+		}
+	}
+	return models.ArtifactVerification{}, ErrNotFound
+}
+
+func (m *MemoryStore) ListVerificationsByArtifact(ctx context.Context, artifactID uuid.UUID, limit int) ([]models.ArtifactVerification, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var verifications []models.ArtifactVerification
+	for _, v := range m.verifies {
+		if v.ArtifactID == artifactID {
+			verifications = append(verifications, copyVerification(v))
+		}
+	}
+	sort.Slice(verifications, func(i, j int) bool {
+		return verifications[i].CreatedAt.After(verifications[j].CreatedAt)
+	})
+	if limit > 0 && len(verifications) > limit {
+		verifications = verifications[:limit]
+	}
+	return verifications, nil
+}
+
+func (m *MemoryStore) CompleteVerification(ctx context.Context, v models.ArtifactVerification) (models.ArtifactVerification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.verifies[v.ID]
+	if !ok {
+		return models.ArtifactVerification{}, ErrNotFound
+	}
+	if existing.Status != models.VerificationPending {
+		return models.ArtifactVerification{}, ErrVerificationCompleted
+	}
+	existing.Status = v.Status
+	existing.Checks = v.Checks
+	existing.CompletedAt = v.CompletedAt
+	existing.Signature = v.Signature
+	existing.SignerID = v.SignerID
+	existing.SignatureHash = v.SignatureHash
+	existing.SignatureVersion = v.SignatureVersion
+	existing.SignedAt = v.SignedAt
+	m.verifies[v.ID] = copyVerification(existing)
+	return copyVerification(existing), nil
+}
+
+func copyDatasetVersion(v models.DatasetVersion) models.DatasetVersion {
+	v.Manifest = append([]models.DatasetFile{}, v.Manifest...)
+	v.PIIFlags = append([]string{}, v.PIIFlags...)
+	return v
+}
+
+func (m *MemoryStore) CreateDatasetVersion(ctx context.Context, v models.DatasetVersion) (models.DatasetVersion, error) {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	v.Status = models.DatasetActive
+	v.CreatedAt = time.Now().UTC()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.dsVersions {
+		if existing.Name == v.Name && existing.Version == v.Version {
+			return models.DatasetVersion{}, ErrDatasetVersionExists
+		}
+	}
+	m.dsVersions[v.ID] = copyDatasetVersion(v)
+	return copyDatasetVersion(v), nil
+}
+
+func (m *MemoryStore) GetDatasetVersion(ctx context.Context, id uuid.UUID) (models.DatasetVersion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.dsVersions[id]
+	if !ok {
+		return models.DatasetVersion{}, ErrNotFound
+	}
+	return copyDatasetVersion(v), nil
+}
+
+func (m *MemoryStore) ListDatasetVersions(ctx context.Context, filter ListDatasetVersionsFilter) ([]models.DatasetVersion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var versions []models.DatasetVersion
+	for _, v := range m.dsVersions {
+		if filter.Name != "" && v.Name != filter.Name {
+			continue
+		}
+		if filter.Status != "" && v.Status != filter.Status {
+			continue
+		}
+		versions = append(versions, copyDatasetVersion(v))
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].CreatedAt.After(versions[j].CreatedAt)
+	})
+	start := min(max(filter.Offset, 0), len(versions))
+	end := min(start+normalizeLimit(filter.Limit), len(versions))
+	return versions[start:end], nil
+}
+
+func (m *MemoryStore) RevokeDatasetVersion(ctx context.Context, in DatasetRevocation) (models.DatasetVersion, []uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.dsVersions[in.ID]
+	if !ok {
+		return models.DatasetVersion{}, nil, ErrNotFound
+	}
+	if v.Status == models.DatasetRevoked {
+		return models.DatasetVersion{}, nil, ErrDatasetRevoked
+	}
+	revokedAt := in.RevokedAt
+	v.Status = models.DatasetRevoked
+	v.RevokedAt = &revokedAt
+	v.RevokedBy = in.RevokedBy
+	v.RevocationReason = in.Reason
+	m.dsVersions[v.ID] = v
+
+	trainedOn := map[uuid.UUID]bool{}
+	for jobID, datasets := range m.datasets {
+		for _, ds := range datasets {
+			if ds.DatasetVersionID != nil && *ds.DatasetVersionID == v.ID {
+				trainedOn[jobID] = true
+			}
+		}
+	}
+	flag := datasetRevokedFlag(v)
+	flag.FlaggedAt = revokedAt
+	var flagged []uuid.UUID
+	for _, artifact := range m.artifacts {
+		if trainedOn[artifact.TrainingJobID] {
+			m.flags[artifact.ID] = append(m.flags[artifact.ID], flag)
+			flagged = append(flagged, artifact.ID)
+		}
+	}
+	sort.Slice(flagged, func(i, j int) bool {
+		return flagged[i].String() < flagged[j].String()
+	})
+	return copyDatasetVersion(v), flagged, nil
+}
+
+func (m *MemoryStore) CreateManifestSignature(ctx context.Context, ms models.ManifestSignature) (models.ManifestSignature, error) {
+	ms.CreatedAt = time.Now().UTC()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.manifests[ms.ID]; ok {
+		return models.ManifestSignature{}, fmt.Errorf("insert manifest signature: %s already stored", ms.ID)
+	}
+	m.manifests[ms.ID] = ms
+	return ms, nil
+}
+
+func (m *MemoryStore) GetManifestSignature(ctx context.Context, id string) (models.ManifestSignature, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ms, ok := m.manifests[id]
+	if !ok {
+		return models.ManifestSignature{}, ErrNotFound
+	}
+	return ms, nil
+}
+
 func (m *MemoryStore) Ping(ctx context.Context) error { return nil }
 
 // Ensures imports used (base64) for gofmt.
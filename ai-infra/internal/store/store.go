@@ -9,23 +9,101 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 
 	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
 )
 
 var ErrNotFound = errors.New("not found")
 
+// ErrStatusConflict is returned by UpdatePromotionStatus when FromStatus is set and the
+// promotion is no longer in that status (another replica decided it first).
+var ErrStatusConflict = errors.New("promotion status changed")
+
+// ErrCanaryExists is returned by UpdatePromotionStatus when moving a promotion to canary
+// while another promotion of the same environment is in canary.
+var ErrCanaryExists = errors.New("environment already has a canary promotion")
+
+// canaryEnvironmentIndex is the partial unique index (migration 012) that allows one canary
+// promotion per environment.
+const canaryEnvironmentIndex = "uq_model_promotions_canary_environment"
+
+// ErrJobConflict is returned when a training job is no longer in the status (or leased by
+// the worker) an update expected.
+var ErrJobConflict = errors.New("training job status changed")
+
+const trainingJobColumns = `id, code_ref, container_digest, hyperparams, dataset_refs, seed, status, attempts, max_attempts, max_runtime_seconds, worker_id, lease_expires_at, heartbeat_at, next_attempt_at, started_at, finished_at, last_error, reproduces_artifact_id, created_at, updated_at`
+
+// ErrVerificationCompleted is returned when completing a verification that already
+// completed.
+var ErrVerificationCompleted = errors.New("verification already completed")
+
+// ErrDatasetVersionExists is returned when registering a dataset name and version twice.
+var ErrDatasetVersionExists = errors.New("dataset version already registered")
+
+// ErrDatasetRevoked is returned when a training job references a revoked dataset version
+// or a revoked version is revoked again.
+var ErrDatasetRevoked = errors.New("dataset version revoked")
+
+const datasetVersionColumns = `id, name, version, uri, manifest, manifest_hash, license, pii_flags, status, created_by, revoked_at, revoked_by, revocation_reason, created_at`
+
+const manifestSignatureColumns = `id, manifest_id, subject_type, subject_id, manifest_hash, signer_id, signature, version, signed_at, created_at`
+
+const verificationColumns = `id, artifact_id, status, checks, repro_job_id, requested_by, completed_at, signature, signer_id, signature_hash, signature_version, signed_at, created_at`
+
+const promotionColumns = `id, artifact_id, environment, status, evaluation, sentinel_decision, promoted_by, promoted_at, signature, signer_id, signature_hash, signature_version, signed_at, canary, rollback, manifest_signature_id, created_at`
+
 type Store interface {
 	CreateTrainingJob(ctx context.Context, in TrainingJobInput) (models.TrainingJob, error)
 	GetTrainingJob(ctx context.Context, id uuid.UUID) (models.TrainingJob, error)
-	ClaimNextTrainingJob(ctx context.Context) (models.TrainingJob, error)
-	UpdateTrainingJobStatus(ctx context.Context, id uuid.UUID, status string) (models.TrainingJob, error)
+	ListTrainingJobs(ctx context.Context, filter ListTrainingJobsFilter) ([]models.TrainingJob, error)
+	ClaimNextTrainingJob(ctx context.Context, workerID string, lease time.Duration) (models.TrainingJob, error)
+	HeartbeatTrainingJob(ctx context.Context, id uuid.UUID, workerID string, lease time.Duration) (models.TrainingJob, error)
+	TransitionTrainingJob(ctx context.Context, in TrainingJobTransition) (models.TrainingJob, error)
 	CreateArtifact(ctx context.Context, in ArtifactInput) (models.ModelArtifact, error)
 	ListArtifacts(ctx context.Context, filter ListArtifactsFilter) ([]models.ModelArtifact, error)
 	GetArtifact(ctx context.Context, id uuid.UUID) (models.ModelArtifact, error)
+	// ReferencedArtifactURIs returns which of uris some artifact was registered with.
+	ReferencedArtifactURIs(ctx context.Context, uris []string) (map[string]bool, error)
+	ListArtifactParents(ctx context.Context, artifactID uuid.UUID) ([]models.ArtifactLink, error)
+	ListArtifactChildren(ctx context.Context, parentID uuid.UUID) ([]models.ArtifactLink, error)
+	ListTrainingJobDatasets(ctx context.Context, trainingJobID uuid.UUID) ([]models.DatasetRef, error)
+	// CreateDatasetVersion returns ErrDatasetVersionExists if the name and version are taken.
+	CreateDatasetVersion(ctx context.Context, v models.DatasetVersion) (models.DatasetVersion, error)
+	GetDatasetVersion(ctx context.Context, id uuid.UUID) (models.DatasetVersion, error)
+	ListDatasetVersions(ctx context.Context, filter ListDatasetVersionsFilter) ([]models.DatasetVersion, error)
+	// RevokeDatasetVersion revokes an active dataset version and flags every artifact
+	// trained on it, returning the flagged artifacts. It returns ErrDatasetRevoked if the
+	// version was already revoked.
+	RevokeDatasetVersion(ctx context.Context, in DatasetRevocation) (models.DatasetVersion, []uuid.UUID, error)
+	CreateManifestSignature(ctx context.Context, ms models.ManifestSignature) (models.ManifestSignature, error)
+	GetManifestSignature(ctx context.Context, id string) (models.ManifestSignature, error)
 	CreatePromotion(ctx context.Context, in PromotionInput) (models.ModelPromotion, error)
+	GetPromotion(ctx context.Context, id uuid.UUID) (models.ModelPromotion, error)
 	ListPromotionsByArtifact(ctx context.Context, artifactID uuid.UUID) ([]models.ModelPromotion, error)
+	ListPromotionsByStatus(ctx context.Context, status string) ([]models.ModelPromotion, error)
+	LatestAppliedPromotion(ctx context.Context, environment string) (models.ModelPromotion, error)
+	ListPromotionsByEnvironment(ctx context.Context, environment string, limit int) ([]models.ModelPromotion, error)
+	// UpdatePromotionStatus returns ErrCanaryExists if the environment already has a canary.
 	UpdatePromotionStatus(ctx context.Context, in PromotionStatusUpdate) (models.ModelPromotion, error)
+	AddCanaryObservation(ctx context.Context, obs models.CanaryObservation) error
+	ListCanaryObservations(ctx context.Context, promotionID uuid.UUID) ([]models.CanaryObservation, error)
+	// AppendJobLogs stores chunks of a job's output, numbering them after the job's
+	// previous chunks, and returns them with their Seq.
+	AppendJobLogs(ctx context.Context, jobID uuid.UUID, chunks []models.JobLogChunk) ([]models.JobLogChunk, error)
+	// ListJobLogs returns up to limit (0 means all) of a job's chunks after afterSeq, in order.
+	ListJobLogs(ctx context.Context, jobID uuid.UUID, afterSeq int64, limit int) ([]models.JobLogChunk, error)
+	AppendJobMetrics(ctx context.Context, jobID uuid.UUID, points []models.JobMetricPoint) ([]models.JobMetricPoint, error)
+	ListJobMetrics(ctx context.Context, jobID uuid.UUID, afterSeq int64, limit int) ([]models.JobMetricPoint, error)
+	CreateVerification(ctx context.Context, v models.ArtifactVerification) (models.ArtifactVerification, error)
+	GetVerification(ctx context.Context, id uuid.UUID) (models.ArtifactVerification, error)
+	// GetVerificationByReproJob returns the verification waiting on a reproduction job.
+	GetVerificationByReproJob(ctx context.Context, jobID uuid.UUID) (models.ArtifactVerification, error)
+	// ListVerificationsByArtifact returns up to limit (0 means all) verifications, newest first.
+	ListVerificationsByArtifact(ctx context.Context, artifactID uuid.UUID, limit int) ([]models.ArtifactVerification, error)
+	// CompleteVerification stores the final status, checks and signature of a pending
+	// verification. It returns ErrVerificationCompleted if it already completed.
+	CompleteVerification(ctx context.Context, v models.ArtifactVerification) (models.ArtifactVerification, error)
 	Ping(ctx context.Context) error
 }
 
@@ -44,7 +122,42 @@ type TrainingJobInput struct {
 	Hyperparams     json.RawMessage
 	DatasetRefs     json.RawMessage
 	Seed            int64
-	Status          string
+	Status          models.JobStatus
+	// Datasets are DatasetRefs parsed into rows for lineage queries. Creating the job
+	// fails with ErrDatasetRevoked if one references a revoked dataset version.
+	Datasets          []models.DatasetRef
+	MaxAttempts       int
+	MaxRuntimeSeconds int
+	// ReproducesArtifactID marks a job re-running the training of an artifact.
+	ReproducesArtifactID *uuid.UUID
+}
+
+// TrainingJobTransition moves a running or queued job to another status. It only applies
+// while the job is in FromStatus and, when WorkerID is set, leased by that worker;
+// otherwise it fails with ErrJobConflict. Leaving running clears the lease.
+type TrainingJobTransition struct {
+	ID         uuid.UUID
+	FromStatus models.JobStatus
+	WorkerID   string
+	Status     models.JobStatus
+	// Error replaces the job's last error when set.
+	Error string
+	// NextAttemptAt delays the next claim of a requeued job.
+	NextAttemptAt *time.Time
+	// LeaseExpiredBefore, when set, only transitions a job whose lease expired before
+	// it, so a worker that heartbeated since the job was read keeps it.
+	LeaseExpiredBefore *time.Time
+}
+
+// ListTrainingJobsFilter selects training jobs, newest first. A zero Limit returns all.
+type ListTrainingJobsFilter struct {
+	Status        models.JobStatus
+	WorkerID      string
+	CodeRef       string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Limit         int
+	Offset        int
 }
 
 type ArtifactInput struct {
@@ -55,7 +168,11 @@ type ArtifactInput struct {
 	Metadata            json.RawMessage
 	SignerID            string
 	Signature           string
+	SignatureHash       string
+	SignatureVersion    string
+	SignedAt            *time.Time
 	ManifestSignatureID *string
+	Parents             []models.ArtifactParent
 }
 
 type PromotionInput struct {
@@ -64,16 +181,26 @@ type PromotionInput struct {
 	Environment string
 	Status      string
 	Evaluation  json.RawMessage
+	Rollback    *models.Rollback
 }
 
 type PromotionStatusUpdate struct {
-	ID               uuid.UUID
+	ID uuid.UUID
+	// FromStatus, when set, makes the update conditional on the current status.
+	FromStatus       string
 	Status           string
 	SentinelDecision json.RawMessage
 	PromotedBy       string
 	PromotedAt       *time.Time
 	Signature        *string
 	SignerID         *string
+	SignatureHash    *string
+	SignatureVersion *string
+	SignedAt         *time.Time
+	// Canary replaces the stored canary state when set.
+	Canary *models.Canary
+	// ManifestSignatureID replaces the stored Kernel manifest signature reference when set.
+	ManifestSignatureID *string
 }
 
 type ListArtifactsFilter struct {
@@ -83,6 +210,36 @@ type ListArtifactsFilter struct {
 	Offset        int
 }
 
+// ListDatasetVersionsFilter selects dataset versions, newest first.
+type ListDatasetVersionsFilter struct {
+	Name   string
+	Status string
+	Limit  int
+	Offset int
+}
+
+// DatasetRevocation revokes a dataset version.
+type DatasetRevocation struct {
+	ID        uuid.UUID
+	RevokedBy string
+	Reason    string
+	RevokedAt time.Time
+}
+
+// datasetRevokedFlag is the flag raised on an artifact trained on a revoked version.
+func datasetRevokedFlag(v models.DatasetVersion) models.ArtifactFlag {
+	id := v.ID
+	flag := models.ArtifactFlag{
+		Reason:           models.FlagDatasetRevoked,
+		DatasetVersionID: &id,
+		Detail:           fmt.Sprintf("trained on revoked dataset %s@%s", v.Name, v.Version),
+	}
+	if v.RevocationReason != "" {
+		flag.Detail += ": " + v.RevocationReason
+	}
+	return flag
+}
+
 type rowScanner interface {
 	Scan(dest ...interface{}) error
 }
@@ -99,6 +256,15 @@ func scanTrainingJob(row rowScanner) (models.TrainingJob, error) {
 		job         models.TrainingJob
 		hyperparams []byte
 		datasetRefs []byte
+		maxRuntime  sql.NullInt64
+		workerID    sql.NullString
+		lease       sql.NullTime
+		heartbeat   sql.NullTime
+		nextAttempt sql.NullTime
+		startedAt   sql.NullTime
+		finishedAt  sql.NullTime
+		lastError   sql.NullString
+		reproduces  uuid.NullUUID
 	)
 	if err := row.Scan(
 		&job.ID,
@@ -108,6 +274,17 @@ func scanTrainingJob(row rowScanner) (models.TrainingJob, error) {
 		&datasetRefs,
 		&job.Seed,
 		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&maxRuntime,
+		&workerID,
+		&lease,
+		&heartbeat,
+		&nextAttempt,
+		&startedAt,
+		&finishedAt,
+		&lastError,
+		&reproduces,
 		&job.CreatedAt,
 		&job.UpdatedAt,
 	); err != nil {
@@ -115,13 +292,36 @@ func scanTrainingJob(row rowScanner) (models.TrainingJob, error) {
 	}
 	job.Hyperparams = append(json.RawMessage(nil), hyperparams...)
 	job.DatasetRefs = append(json.RawMessage(nil), datasetRefs...)
+	job.MaxRuntimeSeconds = int(maxRuntime.Int64)
+	job.WorkerID = workerID.String
+	job.LeaseExpiresAt = nullTime(lease)
+	job.HeartbeatAt = nullTime(heartbeat)
+	job.NextAttemptAt = nullTime(nextAttempt)
+	job.StartedAt = nullTime(startedAt)
+	job.FinishedAt = nullTime(finishedAt)
+	job.LastError = lastError.String
+	if reproduces.Valid {
+		id := reproduces.UUID
+		job.ReproducesArtifactID = &id
+	}
 	return job, nil
 }
 
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
+
 func scanArtifact(row rowScanner) (models.ModelArtifact, error) {
 	var (
 		artifact models.ModelArtifact
 		metadata []byte
+		sigHash  sql.NullString
+		sigVer   sql.NullString
+		signedAt sql.NullTime
 		manifest sql.NullString
 	)
 	if err := row.Scan(
@@ -132,12 +332,21 @@ func scanArtifact(row rowScanner) (models.ModelArtifact, error) {
 		&metadata,
 		&artifact.SignerID,
 		&artifact.Signature,
+		&sigHash,
+		&sigVer,
+		&signedAt,
 		&manifest,
 		&artifact.CreatedAt,
 	); err != nil {
 		return models.ModelArtifact{}, err
 	}
 	artifact.Metadata = append(json.RawMessage(nil), metadata...)
+	artifact.SignatureHash = sigHash.String
+	artifact.SignatureVersion = sigVer.String
+	if signedAt.Valid {
+		t := signedAt.Time
+		artifact.SignedAt = &t
+	}
 	if manifest.Valid {
 		artifact.ManifestSignatureID = &manifest.String
 	}
@@ -152,6 +361,12 @@ func scanPromotion(row rowScanner) (models.ModelPromotion, error) {
 		promotedAt sql.NullTime
 		signature  sql.NullString
 		signer     sql.NullString
+		sigHash    sql.NullString
+		sigVer     sql.NullString
+		signedAt   sql.NullTime
+		canary     []byte
+		rollback   []byte
+		manifest   sql.NullString
 	)
 	if err := row.Scan(
 		&promo.ID,
@@ -164,10 +379,28 @@ func scanPromotion(row rowScanner) (models.ModelPromotion, error) {
 		&promotedAt,
 		&signature,
 		&signer,
+		&sigHash,
+		&sigVer,
+		&signedAt,
+		&canary,
+		&rollback,
+		&manifest,
 		&promo.CreatedAt,
 	); err != nil {
 		return models.ModelPromotion{}, err
 	}
+	if len(canary) > 0 && string(canary) != "null" {
+		promo.Canary = &models.Canary{}
+		if err := json.Unmarshal(canary, promo.Canary); err != nil {
+			return models.ModelPromotion{}, fmt.Errorf("decode canary: %w", err)
+		}
+	}
+	if len(rollback) > 0 && string(rollback) != "null" {
+		promo.Rollback = &models.Rollback{}
+		if err := json.Unmarshal(rollback, promo.Rollback); err != nil {
+			return models.ModelPromotion{}, fmt.Errorf("decode rollback: %w", err)
+		}
+	}
 	promo.Evaluation = append(json.RawMessage(nil), evalBytes...)
 	if len(sentinel) > 0 {
 		promo.SentinelDecision = append(json.RawMessage(nil), sentinel...)
@@ -184,6 +417,22 @@ func scanPromotion(row rowScanner) (models.ModelPromotion, error) {
 		v := signer.String
 		promo.SignerID = &v
 	}
+	if sigHash.Valid {
+		v := sigHash.String
+		promo.SignatureHash = &v
+	}
+	if sigVer.Valid {
+		v := sigVer.String
+		promo.SignatureVersion = &v
+	}
+	if signedAt.Valid {
+		t := signedAt.Time
+		promo.SignedAt = &t
+	}
+	if manifest.Valid {
+		v := manifest.String
+		promo.ManifestSignatureID = &v
+	}
 	return promo, nil
 }
 
@@ -191,24 +440,92 @@ func (s *PGStore) CreateTrainingJob(ctx context.Context, in TrainingJobInput) (m
 	if in.ID == uuid.Nil {
 		in.ID = uuid.New()
 	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.TrainingJob{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO training_jobs (id, code_ref, container_digest, hyperparams, dataset_refs, seed, status)
-		VALUES ($1,$2,$3,$4,$5,$6,$7)
-		RETURNING id, code_ref, container_digest, hyperparams, dataset_refs, seed, status, created_at, updated_at
-	`
-	row := s.db.QueryRowContext(ctx, query, in.ID, in.CodeRef, in.ContainerDigest, ensureJSON(in.Hyperparams, "{}"), ensureJSON(in.DatasetRefs, "[]"), in.Seed, in.Status)
+		INSERT INTO training_jobs (id, code_ref, container_digest, hyperparams, dataset_refs, seed, status, max_attempts, max_runtime_seconds, reproduces_artifact_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		RETURNING ` + trainingJobColumns
+	var maxRuntime sql.NullInt64
+	if in.MaxRuntimeSeconds > 0 {
+		maxRuntime = sql.NullInt64{Int64: int64(in.MaxRuntimeSeconds), Valid: true}
+	}
+	row := tx.QueryRowContext(ctx, query, in.ID, in.CodeRef, in.ContainerDigest, ensureJSON(in.Hyperparams, "{}"), ensureJSON(in.DatasetRefs, "[]"), in.Seed, in.Status, in.MaxAttempts, maxRuntime, in.ReproducesArtifactID)
 	job, err := scanTrainingJob(row)
 	if err != nil {
 		return models.TrainingJob{}, fmt.Errorf("insert training job: %w", err)
 	}
+	// Share-locking the referenced versions orders the job after a concurrent revocation,
+	// which would otherwise miss the job's artifacts.
+	const versionQuery = `SELECT status FROM dataset_versions WHERE id = $1 FOR SHARE`
+	const datasetQuery = `
+		INSERT INTO training_job_datasets (training_job_id, position, dataset_id, version, checksum, uri, dataset_version_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+	`
+	for i, ds := range in.Datasets {
+		if ds.DatasetVersionID != nil {
+			var status string
+			if err := tx.QueryRowContext(ctx, versionQuery, *ds.DatasetVersionID).Scan(&status); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return models.TrainingJob{}, fmt.Errorf("dataset version %s: %w", *ds.DatasetVersionID, ErrNotFound)
+				}
+				return models.TrainingJob{}, fmt.Errorf("lock dataset version: %w", err)
+			}
+			if status == models.DatasetRevoked {
+				return models.TrainingJob{}, fmt.Errorf("%w: %s", ErrDatasetRevoked, *ds.DatasetVersionID)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, datasetQuery, job.ID, i, ds.ID, nullString(ds.Version), nullString(ds.Checksum), nullString(ds.URI), ds.DatasetVersionID); err != nil {
+			return models.TrainingJob{}, fmt.Errorf("insert training job dataset: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.TrainingJob{}, fmt.Errorf("commit training job: %w", err)
+	}
 	return job, nil
 }
 
-func (s *PGStore) GetTrainingJob(ctx context.Context, id uuid.UUID) (models.TrainingJob, error) {
+// ListTrainingJobDatasets returns the datasets of a training job in request order.
+func (s *PGStore) ListTrainingJobDatasets(ctx context.Context, trainingJobID uuid.UUID) ([]models.DatasetRef, error) {
 	const query = `
-		SELECT id, code_ref, container_digest, hyperparams, dataset_refs, seed, status, created_at, updated_at
-		FROM training_jobs WHERE id=$1
+		SELECT dataset_id, COALESCE(version, ''), COALESCE(checksum, ''), COALESCE(uri, ''), dataset_version_id
+		FROM training_job_datasets
+		WHERE training_job_id = $1
+		ORDER BY position
 	`
+	rows, err := s.db.QueryContext(ctx, query, trainingJobID)
+	if err != nil {
+		return nil, fmt.Errorf("list training job datasets: %w", err)
+	}
+	defer rows.Close()
+
+	var datasets []models.DatasetRef
+	for rows.Next() {
+		var (
+			ds        models.DatasetRef
+			versionID uuid.NullUUID
+		)
+		if err := rows.Scan(&ds.ID, &ds.Version, &ds.Checksum, &ds.URI, &versionID); err != nil {
+			return nil, fmt.Errorf("scan training job dataset: %w", err)
+		}
+		if versionID.Valid {
+			id := versionID.UUID
+			ds.DatasetVersionID = &id
+		}
+		datasets = append(datasets, ds)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate training job datasets: %w", err)
+	}
+	return datasets, nil
+}
+
+func (s *PGStore) GetTrainingJob(ctx context.Context, id uuid.UUID) (models.TrainingJob, error) {
+	query := `SELECT ` + trainingJobColumns + ` FROM training_jobs WHERE id=$1`
 	job, err := scanTrainingJob(s.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -219,7 +536,67 @@ func (s *PGStore) GetTrainingJob(ctx context.Context, id uuid.UUID) (models.Trai
 	return job, nil
 }
 
-func (s *PGStore) ClaimNextTrainingJob(ctx context.Context) (models.TrainingJob, error) {
+func (s *PGStore) ListTrainingJobs(ctx context.Context, filter ListTrainingJobsFilter) ([]models.TrainingJob, error) {
+	query := `SELECT ` + trainingJobColumns + ` FROM training_jobs WHERE 1=1`
+	args := []interface{}{}
+	argPos := 1
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argPos)
+		args = append(args, filter.Status)
+		argPos++
+	}
+	if filter.WorkerID != "" {
+		query += fmt.Sprintf(" AND worker_id = $%d", argPos)
+		args = append(args, filter.WorkerID)
+		argPos++
+	}
+	if filter.CodeRef != "" {
+		query += fmt.Sprintf(" AND code_ref = $%d", argPos)
+		args = append(args, filter.CodeRef)
+		argPos++
+	}
+	if filter.CreatedAfter != nil {
+		query += fmt.Sprintf(" AND created_at >= $%d", argPos)
+		args = append(args, *filter.CreatedAfter)
+		argPos++
+	}
+	if filter.CreatedBefore != nil {
+		query += fmt.Sprintf(" AND created_at < $%d", argPos)
+		args = append(args, *filter.CreatedBefore)
+		argPos++
+	}
+	query += " ORDER BY created_at DESC, id"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argPos)
+		args = append(args, filter.Limit)
+		argPos++
+	}
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argPos)
+		args = append(args, filter.Offset)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list training jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.TrainingJob
+	for rows.Next() {
+		job, err := scanTrainingJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan training job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate training jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// ClaimNextTrainingJob leases the oldest queued job whose backoff has passed to workerID.
+func (s *PGStore) ClaimNextTrainingJob(ctx context.Context, workerID string, lease time.Duration) (models.TrainingJob, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return models.TrainingJob{}, fmt.Errorf("begin tx: %w", err)
@@ -228,7 +605,7 @@ func (s *PGStore) ClaimNextTrainingJob(ctx context.Context) (models.TrainingJob,
 
 	const selectQueued = `
 		SELECT id FROM training_jobs
-		WHERE status='queued'
+		WHERE status='queued' AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
 		ORDER BY created_at
 		FOR UPDATE SKIP LOCKED
 		LIMIT 1
@@ -241,13 +618,14 @@ func (s *PGStore) ClaimNextTrainingJob(ctx context.Context) (models.TrainingJob,
 		return models.TrainingJob{}, fmt.Errorf("select queued job: %w", err)
 	}
 
-	const claimQuery = `
+	claimQuery := `
 		UPDATE training_jobs
-		SET status='running', updated_at=NOW()
+		SET status='running', attempts=attempts+1, worker_id=$2,
+			lease_expires_at=NOW() + $3 * INTERVAL '1 millisecond', heartbeat_at=NOW(),
+			started_at=NOW(), next_attempt_at=NULL, updated_at=NOW()
 		WHERE id=$1
-		RETURNING id, code_ref, container_digest, hyperparams, dataset_refs, seed, status, created_at, updated_at
-	`
-	job, err := scanTrainingJob(tx.QueryRowContext(ctx, claimQuery, jobID))
+		RETURNING ` + trainingJobColumns
+	job, err := scanTrainingJob(tx.QueryRowContext(ctx, claimQuery, jobID, workerID, lease.Milliseconds()))
 	if err != nil {
 		return models.TrainingJob{}, fmt.Errorf("claim job: %w", err)
 	}
@@ -257,40 +635,208 @@ func (s *PGStore) ClaimNextTrainingJob(ctx context.Context) (models.TrainingJob,
 	return job, nil
 }
 
-func (s *PGStore) UpdateTrainingJobStatus(ctx context.Context, id uuid.UUID, status string) (models.TrainingJob, error) {
-	const query = `
+// HeartbeatTrainingJob extends the lease of a running job held by workerID. It fails with
+// ErrJobConflict once the job was cancelled, finished or leased to another worker.
+func (s *PGStore) HeartbeatTrainingJob(ctx context.Context, id uuid.UUID, workerID string, lease time.Duration) (models.TrainingJob, error) {
+	query := `
 		UPDATE training_jobs
-		SET status=$2, updated_at=NOW()
-		WHERE id=$1
-		RETURNING id, code_ref, container_digest, hyperparams, dataset_refs, seed, status, created_at, updated_at
-	`
-	job, err := scanTrainingJob(s.db.QueryRowContext(ctx, query, id, status))
+		SET lease_expires_at=NOW() + $3 * INTERVAL '1 millisecond', heartbeat_at=NOW(), updated_at=NOW()
+		WHERE id=$1 AND status='running' AND worker_id=$2
+		RETURNING ` + trainingJobColumns
+	job, err := scanTrainingJob(s.db.QueryRowContext(ctx, query, id, workerID, lease.Milliseconds()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.jobConflict(ctx, id)
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return models.TrainingJob{}, ErrNotFound
-		}
-		return models.TrainingJob{}, fmt.Errorf("update training job: %w", err)
+		return models.TrainingJob{}, fmt.Errorf("heartbeat training job: %w", err)
 	}
 	return job, nil
 }
 
+func (s *PGStore) TransitionTrainingJob(ctx context.Context, in TrainingJobTransition) (models.TrainingJob, error) {
+	query := `
+		UPDATE training_jobs
+		SET status=$4, worker_id=NULL, lease_expires_at=NULL, next_attempt_at=$5,
+			finished_at=CASE WHEN $6 THEN NOW() ELSE NULL END,
+			last_error=COALESCE($7, last_error), updated_at=NOW()
+		WHERE id=$1 AND status=$2 AND ($3 = '' OR worker_id=$3)
+			AND ($8::timestamptz IS NULL OR lease_expires_at < $8)
+		RETURNING ` + trainingJobColumns
+	job, err := scanTrainingJob(s.db.QueryRowContext(ctx, query, in.ID, in.FromStatus, in.WorkerID,
+		in.Status, in.NextAttemptAt, in.Status.Terminal(), nullString(in.Error), in.LeaseExpiredBefore))
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.jobConflict(ctx, in.ID)
+	}
+	if err != nil {
+		return models.TrainingJob{}, fmt.Errorf("transition training job: %w", err)
+	}
+	return job, nil
+}
+
+// jobConflict returns the current job with ErrJobConflict, or ErrNotFound if it is gone.
+func (s *PGStore) jobConflict(ctx context.Context, id uuid.UUID) (models.TrainingJob, error) {
+	job, err := s.GetTrainingJob(ctx, id)
+	if err != nil {
+		return models.TrainingJob{}, err
+	}
+	return job, ErrJobConflict
+}
+
 func (s *PGStore) CreateArtifact(ctx context.Context, in ArtifactInput) (models.ModelArtifact, error) {
 	if in.ID == uuid.Nil {
 		in.ID = uuid.New()
 	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ModelArtifact{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO model_artifacts (id, training_job_id, artifact_uri, checksum, metadata, signer_id, signature, manifest_signature_id)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
-		RETURNING id, training_job_id, artifact_uri, checksum, metadata, signer_id, signature, manifest_signature_id, created_at
+		INSERT INTO model_artifacts (id, training_job_id, artifact_uri, checksum, metadata, signer_id, signature, signature_hash, signature_version, signed_at, manifest_signature_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		RETURNING id, training_job_id, artifact_uri, checksum, metadata, signer_id, signature, signature_hash, signature_version, signed_at, manifest_signature_id, created_at
 	`
-	row := s.db.QueryRowContext(ctx, query, in.ID, in.TrainingJobID, in.ArtifactURI, in.Checksum, ensureJSON(in.Metadata, "{}"), in.SignerID, in.Signature, in.ManifestSignatureID)
+	row := tx.QueryRowContext(ctx, query, in.ID, in.TrainingJobID, in.ArtifactURI, in.Checksum, ensureJSON(in.Metadata, "{}"), in.SignerID, in.Signature, nullString(in.SignatureHash), nullString(in.SignatureVersion), in.SignedAt, in.ManifestSignatureID)
 	artifact, err := scanArtifact(row)
 	if err != nil {
 		return models.ModelArtifact{}, fmt.Errorf("insert model artifact: %w", err)
 	}
+	const parentQuery = `
+		INSERT INTO artifact_parents (artifact_id, parent_id, relation)
+		VALUES ($1,$2,$3)
+	`
+	for _, parent := range in.Parents {
+		if _, err := tx.ExecContext(ctx, parentQuery, artifact.ID, parent.ArtifactID, parent.Relation); err != nil {
+			return models.ModelArtifact{}, fmt.Errorf("insert artifact parent: %w", err)
+		}
+	}
+	flags, err := flagRevokedDatasets(ctx, tx, artifact.ID, artifact.TrainingJobID)
+	if err != nil {
+		return models.ModelArtifact{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.ModelArtifact{}, fmt.Errorf("commit model artifact: %w", err)
+	}
+	artifact.Parents = in.Parents
+	artifact.Flags = flags
 	return artifact, nil
 }
 
+// flagRevokedDatasets flags a new artifact trained on dataset versions that are already
+// revoked. The versions are share-locked so a concurrent revocation either commits first
+// and is seen here, or waits and then sees the artifact.
+func flagRevokedDatasets(ctx context.Context, tx *sql.Tx, artifactID, trainingJobID uuid.UUID) ([]models.ArtifactFlag, error) {
+	query := `
+		SELECT ` + datasetVersionColumns + `
+		FROM dataset_versions
+		WHERE id IN (SELECT dataset_version_id FROM training_job_datasets WHERE training_job_id = $1)
+		ORDER BY id
+		FOR SHARE
+	`
+	rows, err := tx.QueryContext(ctx, query, trainingJobID)
+	if err != nil {
+		return nil, fmt.Errorf("lock training job datasets: %w", err)
+	}
+	var revoked []models.DatasetVersion
+	for rows.Next() {
+		v, err := scanDatasetVersion(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan dataset version: %w", err)
+		}
+		if v.Status == models.DatasetRevoked {
+			revoked = append(revoked, v)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate dataset versions: %w", err)
+	}
+	var flags []models.ArtifactFlag
+	for _, v := range revoked {
+		flag := datasetRevokedFlag(v)
+		const flagQuery = `
+			INSERT INTO artifact_flags (artifact_id, reason, dataset_version_id, detail)
+			VALUES ($1,$2,$3,$4)
+			RETURNING flagged_at
+		`
+		if err := tx.QueryRowContext(ctx, flagQuery, artifactID, flag.Reason, flag.DatasetVersionID, nullString(flag.Detail)).Scan(&flag.FlaggedAt); err != nil {
+			return nil, fmt.Errorf("flag artifact: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+// ListArtifactParents returns the artifacts artifactID was derived from.
+func (s *PGStore) ReferencedArtifactURIs(ctx context.Context, uris []string) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	if len(uris) == 0 {
+		return referenced, nil
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT artifact_uri FROM model_artifacts WHERE artifact_uri = ANY($1)`, pq.Array(uris))
+	if err != nil {
+		return nil, fmt.Errorf("query artifact uris: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var uri string
+		if err := rows.Scan(&uri); err != nil {
+			return nil, fmt.Errorf("scan artifact uri: %w", err)
+		}
+		referenced[uri] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate artifact uris: %w", err)
+	}
+	return referenced, nil
+}
+
+func (s *PGStore) ListArtifactParents(ctx context.Context, artifactID uuid.UUID) ([]models.ArtifactLink, error) {
+	const query = `
+		SELECT parent_id, artifact_id, relation FROM artifact_parents
+		WHERE artifact_id = $1
+		ORDER BY parent_id
+	`
+	return s.queryArtifactLinks(ctx, query, artifactID)
+}
+
+// ListArtifactChildren returns the artifacts derived from parentID.
+func (s *PGStore) ListArtifactChildren(ctx context.Context, parentID uuid.UUID) ([]models.ArtifactLink, error) {
+	const query = `
+		SELECT parent_id, artifact_id, relation FROM artifact_parents
+		WHERE parent_id = $1
+		ORDER BY created_at, artifact_id
+	`
+	return s.queryArtifactLinks(ctx, query, parentID)
+}
+
+func (s *PGStore) queryArtifactLinks(ctx context.Context, query string, id uuid.UUID) ([]models.ArtifactLink, error) {
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("list artifact links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []models.ArtifactLink
+	for rows.Next() {
+		var link models.ArtifactLink
+		if err := rows.Scan(&link.ParentID, &link.ChildID, &link.Relation); err != nil {
+			return nil, fmt.Errorf("scan artifact link: %w", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate artifact links: %w", err)
+	}
+	return links, nil
+}
+
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
 func normalizeLimit(limit int) int {
 	if limit <= 0 {
 		return 50
@@ -303,7 +849,7 @@ func normalizeLimit(limit int) int {
 
 func (s *PGStore) ListArtifacts(ctx context.Context, filter ListArtifactsFilter) ([]models.ModelArtifact, error) {
 	query := `
-		SELECT id, training_job_id, artifact_uri, checksum, metadata, signer_id, signature, manifest_signature_id, created_at
+		SELECT id, training_job_id, artifact_uri, checksum, metadata, signer_id, signature, signature_hash, signature_version, signed_at, manifest_signature_id, created_at
 		FROM model_artifacts
 		WHERE 1=1
 	`
@@ -355,7 +901,7 @@ func (s *PGStore) ListArtifacts(ctx context.Context, filter ListArtifactsFilter)
 
 func (s *PGStore) GetArtifact(ctx context.Context, id uuid.UUID) (models.ModelArtifact, error) {
 	const query = `
-		SELECT id, training_job_id, artifact_uri, checksum, metadata, signer_id, signature, manifest_signature_id, created_at
+		SELECT id, training_job_id, artifact_uri, checksum, metadata, signer_id, signature, signature_hash, signature_version, signed_at, manifest_signature_id, created_at
 		FROM model_artifacts
 		WHERE id = $1
 	`
@@ -366,19 +912,76 @@ func (s *PGStore) GetArtifact(ctx context.Context, id uuid.UUID) (models.ModelAr
 		}
 		return models.ModelArtifact{}, fmt.Errorf("get artifact: %w", err)
 	}
+	links, err := s.ListArtifactParents(ctx, id)
+	if err != nil {
+		return models.ModelArtifact{}, err
+	}
+	artifact.Parents = parentsOf(links)
+	if artifact.Flags, err = s.listArtifactFlags(ctx, id); err != nil {
+		return models.ModelArtifact{}, err
+	}
 	return artifact, nil
 }
 
+func (s *PGStore) listArtifactFlags(ctx context.Context, artifactID uuid.UUID) ([]models.ArtifactFlag, error) {
+	const query = `
+		SELECT reason, dataset_version_id, COALESCE(detail, ''), flagged_at
+		FROM artifact_flags
+		WHERE artifact_id = $1
+		ORDER BY flagged_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, artifactID)
+	if err != nil {
+		return nil, fmt.Errorf("list artifact flags: %w", err)
+	}
+	defer rows.Close()
+
+	var flags []models.ArtifactFlag
+	for rows.Next() {
+		var (
+			flag      models.ArtifactFlag
+			versionID uuid.NullUUID
+		)
+		if err := rows.Scan(&flag.Reason, &versionID, &flag.Detail, &flag.FlaggedAt); err != nil {
+			return nil, fmt.Errorf("scan artifact flag: %w", err)
+		}
+		if versionID.Valid {
+			id := versionID.UUID
+			flag.DatasetVersionID = &id
+		}
+		flags = append(flags, flag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate artifact flags: %w", err)
+	}
+	return flags, nil
+}
+
+func parentsOf(links []models.ArtifactLink) []models.ArtifactParent {
+	var parents []models.ArtifactParent
+	for _, link := range links {
+		parents = append(parents, models.ArtifactParent{ArtifactID: link.ParentID, Relation: link.Relation})
+	}
+	return parents
+}
+
 func (s *PGStore) CreatePromotion(ctx context.Context, in PromotionInput) (models.ModelPromotion, error) {
 	if in.ID == uuid.Nil {
 		in.ID = uuid.New()
 	}
+	var rollback sql.NullString
+	if in.Rollback != nil {
+		b, err := json.Marshal(in.Rollback)
+		if err != nil {
+			return models.ModelPromotion{}, fmt.Errorf("encode rollback: %w", err)
+		}
+		rollback = nullString(string(b))
+	}
 	query := `
-		INSERT INTO model_promotions (id, artifact_id, environment, status, evaluation)
-		VALUES ($1,$2,$3,$4,$5)
-		RETURNING id, artifact_id, environment, status, evaluation, sentinel_decision, promoted_by, promoted_at, signature, signer_id, created_at
-	`
-	row := s.db.QueryRowContext(ctx, query, in.ID, in.ArtifactID, in.Environment, in.Status, ensureJSON(in.Evaluation, "{}"))
+		INSERT INTO model_promotions (id, artifact_id, environment, status, evaluation, rollback)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING ` + promotionColumns
+	row := s.db.QueryRowContext(ctx, query, in.ID, in.ArtifactID, in.Environment, in.Status, ensureJSON(in.Evaluation, "{}"), rollback)
 	promo, err := scanPromotion(row)
 	if err != nil {
 		return models.ModelPromotion{}, fmt.Errorf("insert promotion: %w", err)
@@ -386,14 +989,75 @@ func (s *PGStore) CreatePromotion(ctx context.Context, in PromotionInput) (model
 	return promo, nil
 }
 
+func (s *PGStore) GetPromotion(ctx context.Context, id uuid.UUID) (models.ModelPromotion, error) {
+	query := `SELECT ` + promotionColumns + ` FROM model_promotions WHERE id = $1`
+	promo, err := scanPromotion(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ModelPromotion{}, ErrNotFound
+		}
+		return models.ModelPromotion{}, fmt.Errorf("get promotion: %w", err)
+	}
+	return promo, nil
+}
+
 func (s *PGStore) ListPromotionsByArtifact(ctx context.Context, artifactID uuid.UUID) ([]models.ModelPromotion, error) {
 	const query = `
-		SELECT id, artifact_id, environment, status, evaluation, sentinel_decision, promoted_by, promoted_at, signature, signer_id, created_at
+		SELECT ` + promotionColumns + `
 		FROM model_promotions
 		WHERE artifact_id = $1
 		ORDER BY created_at DESC
 	`
-	rows, err := s.db.QueryContext(ctx, query, artifactID)
+	return s.queryPromotions(ctx, query, artifactID)
+}
+
+// ListPromotionsByStatus returns the promotions in status, oldest first.
+func (s *PGStore) ListPromotionsByStatus(ctx context.Context, status string) ([]models.ModelPromotion, error) {
+	const query = `
+		SELECT ` + promotionColumns + `
+		FROM model_promotions
+		WHERE status = $1
+		ORDER BY created_at
+	`
+	return s.queryPromotions(ctx, query, status)
+}
+
+// LatestAppliedPromotion returns the most recently applied promotion for environment.
+func (s *PGStore) LatestAppliedPromotion(ctx context.Context, environment string) (models.ModelPromotion, error) {
+	query := `
+		SELECT ` + promotionColumns + `
+		FROM model_promotions
+		WHERE environment = $1 AND status = 'applied'
+		ORDER BY promoted_at DESC NULLS LAST, created_at DESC
+		LIMIT 1
+	`
+	promo, err := scanPromotion(s.db.QueryRowContext(ctx, query, environment))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ModelPromotion{}, ErrNotFound
+		}
+		return models.ModelPromotion{}, fmt.Errorf("latest applied promotion: %w", err)
+	}
+	return promo, nil
+}
+
+// ListPromotionsByEnvironment returns the promotions for environment, newest first. A
+// limit of 0 or less returns all of them.
+func (s *PGStore) ListPromotionsByEnvironment(ctx context.Context, environment string, limit int) ([]models.ModelPromotion, error) {
+	query := `
+		SELECT ` + promotionColumns + `
+		FROM model_promotions
+		WHERE environment = $1
+		ORDER BY created_at DESC
+	`
+	if limit > 0 {
+		return s.queryPromotions(ctx, query+` LIMIT $2`, environment, limit)
+	}
+	return s.queryPromotions(ctx, query, environment)
+}
+
+func (s *PGStore) queryPromotions(ctx context.Context, query string, args ...interface{}) ([]models.ModelPromotion, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("list promotions: %w", err)
 	}
@@ -414,6 +1078,14 @@ func (s *PGStore) ListPromotionsByArtifact(ctx context.Context, artifactID uuid.
 }
 
 func (s *PGStore) UpdatePromotionStatus(ctx context.Context, in PromotionStatusUpdate) (models.ModelPromotion, error) {
+	var canary sql.NullString
+	if in.Canary != nil {
+		b, err := json.Marshal(in.Canary)
+		if err != nil {
+			return models.ModelPromotion{}, fmt.Errorf("encode canary: %w", err)
+		}
+		canary = nullString(string(b))
+	}
 	query := `
 		UPDATE model_promotions
 		SET status=$2,
@@ -421,21 +1093,564 @@ func (s *PGStore) UpdatePromotionStatus(ctx context.Context, in PromotionStatusU
 		    promoted_by=$4,
 		    promoted_at=$5,
 		    signature=$6,
-		    signer_id=$7
-		WHERE id=$1
-		RETURNING id, artifact_id, environment, status, evaluation, sentinel_decision, promoted_by, promoted_at, signature, signer_id, created_at
-	`
-	row := s.db.QueryRowContext(ctx, query, in.ID, in.Status, in.SentinelDecision, in.PromotedBy, in.PromotedAt, in.Signature, in.SignerID)
+		    signer_id=$7,
+		    signature_hash=$8,
+		    signature_version=$9,
+		    signed_at=$10,
+		    canary=COALESCE($11, canary),
+		    manifest_signature_id=COALESCE($13, manifest_signature_id)
+		WHERE id=$1 AND ($12 = '' OR status=$12)
+		RETURNING ` + promotionColumns
+	row := s.db.QueryRowContext(ctx, query, in.ID, in.Status, in.SentinelDecision, in.PromotedBy, in.PromotedAt, in.Signature, in.SignerID, in.SignatureHash, in.SignatureVersion, in.SignedAt, canary, in.FromStatus, in.ManifestSignatureID)
 	promo, err := scanPromotion(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			if in.FromStatus != "" {
+				if _, getErr := s.GetPromotion(ctx, in.ID); getErr == nil {
+					return models.ModelPromotion{}, ErrStatusConflict
+				}
+			}
 			return models.ModelPromotion{}, ErrNotFound
 		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == canaryEnvironmentIndex {
+			return models.ModelPromotion{}, ErrCanaryExists
+		}
 		return models.ModelPromotion{}, fmt.Errorf("update promotion status: %w", err)
 	}
 	return promo, nil
 }
 
+func (s *PGStore) AddCanaryObservation(ctx context.Context, obs models.CanaryObservation) error {
+	metrics, err := json.Marshal(obs.Metrics)
+	if err != nil {
+		return fmt.Errorf("encode canary metrics: %w", err)
+	}
+	const query = `
+		INSERT INTO canary_observations (promotion_id, arm, metrics, observed_at)
+		VALUES ($1,$2,$3,$4)
+	`
+	if _, err := s.db.ExecContext(ctx, query, obs.PromotionID, obs.Arm, metrics, obs.ObservedAt); err != nil {
+		return fmt.Errorf("insert canary observation: %w", err)
+	}
+	return nil
+}
+
+func (s *PGStore) ListCanaryObservations(ctx context.Context, promotionID uuid.UUID) ([]models.CanaryObservation, error) {
+	const query = `
+		SELECT promotion_id, arm, metrics, observed_at
+		FROM canary_observations
+		WHERE promotion_id = $1
+		ORDER BY observed_at, id
+	`
+	rows, err := s.db.QueryContext(ctx, query, promotionID)
+	if err != nil {
+		return nil, fmt.Errorf("list canary observations: %w", err)
+	}
+	defer rows.Close()
+
+	var observations []models.CanaryObservation
+	for rows.Next() {
+		var (
+			obs     models.CanaryObservation
+			metrics []byte
+		)
+		if err := rows.Scan(&obs.PromotionID, &obs.Arm, &metrics, &obs.ObservedAt); err != nil {
+			return nil, fmt.Errorf("scan canary observation: %w", err)
+		}
+		if err := json.Unmarshal(metrics, &obs.Metrics); err != nil {
+			return nil, fmt.Errorf("decode canary metrics: %w", err)
+		}
+		observations = append(observations, obs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate canary observations: %w", err)
+	}
+	return observations, nil
+}
+
+// lockJobForAppend locks a training job row so concurrent appends number their rows in
+// order, and returns the highest seq already stored in table for the job.
+func lockJobForAppend(ctx context.Context, tx *sql.Tx, jobID uuid.UUID, table string) (int64, error) {
+	var id uuid.UUID
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM training_jobs WHERE id = $1 FOR UPDATE`, jobID).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrNotFound
+		}
+		return 0, fmt.Errorf("lock training job: %w", err)
+	}
+	var last int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) FROM `+table+` WHERE job_id = $1`, jobID).Scan(&last); err != nil {
+		return 0, fmt.Errorf("read %s seq: %w", table, err)
+	}
+	return last, nil
+}
+
+func (s *PGStore) AppendJobLogs(ctx context.Context, jobID uuid.UUID, chunks []models.JobLogChunk) ([]models.JobLogChunk, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	seq, err := lockJobForAppend(ctx, tx, jobID, "training_job_logs")
+	if err != nil {
+		return nil, err
+	}
+	const query = `
+		INSERT INTO training_job_logs (job_id, seq, attempt, stream, content, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6)
+	`
+	stored := make([]models.JobLogChunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		seq++
+		chunk.JobID, chunk.Seq = jobID, seq
+		if _, err := tx.ExecContext(ctx, query, chunk.JobID, chunk.Seq, chunk.Attempt, chunk.Stream, chunk.Content, chunk.CreatedAt); err != nil {
+			return nil, fmt.Errorf("insert job log: %w", err)
+		}
+		stored = append(stored, chunk)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit job logs: %w", err)
+	}
+	return stored, nil
+}
+
+func (s *PGStore) ListJobLogs(ctx context.Context, jobID uuid.UUID, afterSeq int64, limit int) ([]models.JobLogChunk, error) {
+	query := `
+		SELECT job_id, seq, attempt, stream, content, created_at
+		FROM training_job_logs
+		WHERE job_id = $1 AND seq > $2
+		ORDER BY seq
+	`
+	args := []interface{}{jobID, afterSeq}
+	if limit > 0 {
+		query += " LIMIT $3"
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list job logs: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []models.JobLogChunk
+	for rows.Next() {
+		var chunk models.JobLogChunk
+		if err := rows.Scan(&chunk.JobID, &chunk.Seq, &chunk.Attempt, &chunk.Stream, &chunk.Content, &chunk.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan job log: %w", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate job logs: %w", err)
+	}
+	return chunks, nil
+}
+
+func (s *PGStore) AppendJobMetrics(ctx context.Context, jobID uuid.UUID, points []models.JobMetricPoint) ([]models.JobMetricPoint, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+	seq, err := lockJobForAppend(ctx, tx, jobID, "training_job_metrics")
+	if err != nil {
+		return nil, err
+	}
+	const query = `
+		INSERT INTO training_job_metrics (job_id, seq, attempt, step, metrics, recorded_at)
+		VALUES ($1,$2,$3,$4,$5,$6)
+	`
+	stored := make([]models.JobMetricPoint, 0, len(points))
+	for _, point := range points {
+		metrics, err := json.Marshal(point.Metrics)
+		if err != nil {
+			return nil, fmt.Errorf("encode job metrics: %w", err)
+		}
+		seq++
+		point.JobID, point.Seq = jobID, seq
+		if _, err := tx.ExecContext(ctx, query, point.JobID, point.Seq, point.Attempt, point.Step, metrics, point.RecordedAt); err != nil {
+			return nil, fmt.Errorf("insert job metrics: %w", err)
+		}
+		stored = append(stored, point)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit job metrics: %w", err)
+	}
+	return stored, nil
+}
+
+func (s *PGStore) ListJobMetrics(ctx context.Context, jobID uuid.UUID, afterSeq int64, limit int) ([]models.JobMetricPoint, error) {
+	query := `
+		SELECT job_id, seq, attempt, step, metrics, recorded_at
+		FROM training_job_metrics
+		WHERE job_id = $1 AND seq > $2
+		ORDER BY seq
+	`
+	args := []interface{}{jobID, afterSeq}
+	if limit > 0 {
+		query += " LIMIT $3"
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list job metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var points []models.JobMetricPoint
+	for rows.Next() {
+		var (
+			point   models.JobMetricPoint
+			metrics []byte
+		)
+		if err := rows.Scan(&point.JobID, &point.Seq, &point.Attempt, &point.Step, &metrics, &point.RecordedAt); err != nil {
+			return nil, fmt.Errorf("scan job metrics: %w", err)
+		}
+		if err := json.Unmarshal(metrics, &point.Metrics); err != nil {
+			return nil, fmt.Errorf("decode job metrics: %w", err)
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate job metrics: %w", err)
+	}
+	return points, nil
+}
+
+func scanVerification(row rowScanner) (models.ArtifactVerification, error) {
+	var (
+		v           models.ArtifactVerification
+		checks      []byte
+		reproJob    uuid.NullUUID
+		requestedBy sql.NullString
+		completedAt sql.NullTime
+		signature   sql.NullString
+		signer      sql.NullString
+		sigHash     sql.NullString
+		sigVer      sql.NullString
+		signedAt    sql.NullTime
+	)
+	if err := row.Scan(&v.ID, &v.ArtifactID, &v.Status, &checks, &reproJob, &requestedBy, &completedAt,
+		&signature, &signer, &sigHash, &sigVer, &signedAt, &v.CreatedAt); err != nil {
+		return models.ArtifactVerification{}, err
+	}
+	if err := json.Unmarshal(checks, &v.Checks); err != nil {
+		return models.ArtifactVerification{}, fmt.Errorf("decode verification checks: %w", err)
+	}
+	if reproJob.Valid {
+		id := reproJob.UUID
+		v.ReproJobID = &id
+	}
+	v.RequestedBy = requestedBy.String
+	v.CompletedAt = nullTime(completedAt)
+	v.Signature = nullStringPtr(signature)
+	v.SignerID = nullStringPtr(signer)
+	v.SignatureHash = nullStringPtr(sigHash)
+	v.SignatureVersion = nullStringPtr(sigVer)
+	v.SignedAt = nullTime(signedAt)
+	return v, nil
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func (s *PGStore) CreateVerification(ctx context.Context, v models.ArtifactVerification) (models.ArtifactVerification, error) {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	checks, err := json.Marshal(v.Checks)
+	if err != nil {
+		return models.ArtifactVerification{}, fmt.Errorf("encode verification checks: %w", err)
+	}
+	query := `
+		INSERT INTO artifact_verifications (id, artifact_id, status, checks, repro_job_id, requested_by)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING ` + verificationColumns
+	created, err := scanVerification(s.db.QueryRowContext(ctx, query, v.ID, v.ArtifactID, v.Status, checks, v.ReproJobID, nullString(v.RequestedBy)))
+	if err != nil {
+		return models.ArtifactVerification{}, fmt.Errorf("insert verification: %w", err)
+	}
+	return created, nil
+}
+
+func (s *PGStore) GetVerification(ctx context.Context, id uuid.UUID) (models.ArtifactVerification, error) {
+	return s.getVerification(ctx, `SELECT `+verificationColumns+` FROM artifact_verifications WHERE id = $1`, id)
+}
+
+func (s *PGStore) GetVerificationByReproJob(ctx context.Context, jobID uuid.UUID) (models.ArtifactVerification, error) {
+	return s.getVerification(ctx, `SELECT `+verificationColumns+` FROM artifact_verifications WHERE repro_job_id = $1`, jobID)
+}
+
+func (s *PGStore) getVerification(ctx context.Context, query string, arg interface{}) (models.ArtifactVerification, error) {
+	v, err := scanVerification(s.db.QueryRowContext(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ArtifactVerification{}, ErrNotFound
+		}
+		return models.ArtifactVerification{}, fmt.Errorf("get verification: %w", err)
+	}
+	return v, nil
+}
+
+func (s *PGStore) ListVerificationsByArtifact(ctx context.Context, artifactID uuid.UUID, limit int) ([]models.ArtifactVerification, error) {
+	query := `
+		SELECT ` + verificationColumns + `
+		FROM artifact_verifications
+		WHERE artifact_id = $1
+		ORDER BY created_at DESC
+	`
+	args := []interface{}{artifactID}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list verifications: %w", err)
+	}
+	defer rows.Close()
+
+	var verifications []models.ArtifactVerification
+	for rows.Next() {
+		v, err := scanVerification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan verification: %w", err)
+		}
+		verifications = append(verifications, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate verifications: %w", err)
+	}
+	return verifications, nil
+}
+
+func (s *PGStore) CompleteVerification(ctx context.Context, v models.ArtifactVerification) (models.ArtifactVerification, error) {
+	checks, err := json.Marshal(v.Checks)
+	if err != nil {
+		return models.ArtifactVerification{}, fmt.Errorf("encode verification checks: %w", err)
+	}
+	query := `
+		UPDATE artifact_verifications
+		SET status = $2, checks = $3, completed_at = $4, signature = $5, signer_id = $6,
+			signature_hash = $7, signature_version = $8, signed_at = $9
+		WHERE id = $1 AND status = 'pending'
+		RETURNING ` + verificationColumns
+	updated, err := scanVerification(s.db.QueryRowContext(ctx, query, v.ID, v.Status, checks, v.CompletedAt,
+		v.Signature, v.SignerID, v.SignatureHash, v.SignatureVersion, v.SignedAt))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, getErr := s.GetVerification(ctx, v.ID); getErr == nil {
+				return models.ArtifactVerification{}, ErrVerificationCompleted
+			}
+			return models.ArtifactVerification{}, ErrNotFound
+		}
+		return models.ArtifactVerification{}, fmt.Errorf("complete verification: %w", err)
+	}
+	return updated, nil
+}
+
+func scanDatasetVersion(row rowScanner) (models.DatasetVersion, error) {
+	var (
+		v         models.DatasetVersion
+		uri       sql.NullString
+		manifest  []byte
+		createdBy sql.NullString
+		revokedAt sql.NullTime
+		revokedBy sql.NullString
+		reason    sql.NullString
+	)
+	if err := row.Scan(&v.ID, &v.Name, &v.Version, &uri, &manifest, &v.ManifestHash, &v.License, pq.Array(&v.PIIFlags),
+		&v.Status, &createdBy, &revokedAt, &revokedBy, &reason, &v.CreatedAt); err != nil {
+		return models.DatasetVersion{}, err
+	}
+	if err := json.Unmarshal(manifest, &v.Manifest); err != nil {
+		return models.DatasetVersion{}, fmt.Errorf("decode dataset manifest: %w", err)
+	}
+	if v.PIIFlags == nil {
+		v.PIIFlags = []string{}
+	}
+	v.URI = uri.String
+	v.CreatedBy = createdBy.String
+	v.RevokedAt = nullTime(revokedAt)
+	v.RevokedBy = revokedBy.String
+	v.RevocationReason = reason.String
+	return v, nil
+}
+
+func (s *PGStore) CreateDatasetVersion(ctx context.Context, v models.DatasetVersion) (models.DatasetVersion, error) {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	manifest, err := json.Marshal(v.Manifest)
+	if err != nil {
+		return models.DatasetVersion{}, fmt.Errorf("encode dataset manifest: %w", err)
+	}
+	if v.PIIFlags == nil {
+		v.PIIFlags = []string{}
+	}
+	query := `
+		INSERT INTO dataset_versions (id, name, version, uri, manifest, manifest_hash, license, pii_flags, status, created_by)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		ON CONFLICT (name, version) DO NOTHING
+		RETURNING ` + datasetVersionColumns
+	created, err := scanDatasetVersion(s.db.QueryRowContext(ctx, query, v.ID, v.Name, v.Version, nullString(v.URI), manifest, v.ManifestHash,
+		v.License, pq.Array(v.PIIFlags), models.DatasetActive, nullString(v.CreatedBy)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DatasetVersion{}, ErrDatasetVersionExists
+		}
+		return models.DatasetVersion{}, fmt.Errorf("insert dataset version: %w", err)
+	}
+	return created, nil
+}
+
+func (s *PGStore) GetDatasetVersion(ctx context.Context, id uuid.UUID) (models.DatasetVersion, error) {
+	query := `SELECT ` + datasetVersionColumns + ` FROM dataset_versions WHERE id = $1`
+	v, err := scanDatasetVersion(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DatasetVersion{}, ErrNotFound
+		}
+		return models.DatasetVersion{}, fmt.Errorf("get dataset version: %w", err)
+	}
+	return v, nil
+}
+
+func (s *PGStore) ListDatasetVersions(ctx context.Context, filter ListDatasetVersionsFilter) ([]models.DatasetVersion, error) {
+	query := `SELECT ` + datasetVersionColumns + ` FROM dataset_versions WHERE 1=1`
+	args := []interface{}{}
+	if filter.Name != "" {
+		args = append(args, filter.Name)
+		query += fmt.Sprintf(" AND name = $%d", len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	args = append(args, normalizeLimit(filter.Limit))
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d", len(args))
+	if filter.Offset > 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list dataset versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []models.DatasetVersion
+	for rows.Next() {
+		v, err := scanDatasetVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan dataset version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate dataset versions: %w", err)
+	}
+	return versions, nil
+}
+
+func (s *PGStore) RevokeDatasetVersion(ctx context.Context, in DatasetRevocation) (models.DatasetVersion, []uuid.UUID, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.DatasetVersion{}, nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE dataset_versions
+		SET status = 'revoked', revoked_at = $2, revoked_by = $3, revocation_reason = $4
+		WHERE id = $1 AND status = 'active'
+		RETURNING ` + datasetVersionColumns
+	v, err := scanDatasetVersion(tx.QueryRowContext(ctx, query, in.ID, in.RevokedAt, nullString(in.RevokedBy), nullString(in.Reason)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, getErr := s.GetDatasetVersion(ctx, in.ID); getErr == nil {
+				return models.DatasetVersion{}, nil, ErrDatasetRevoked
+			}
+			return models.DatasetVersion{}, nil, ErrNotFound
+		}
+		return models.DatasetVersion{}, nil, fmt.Errorf("revoke dataset version: %w", err)
+	}
+	flag := datasetRevokedFlag(v)
+	const flagQuery = `
+		INSERT INTO artifact_flags (artifact_id, reason, dataset_version_id, detail, flagged_at)
+		SELECT DISTINCT a.id, $2, $1, $3, $4
+		FROM model_artifacts a
+		JOIN training_job_datasets d ON d.training_job_id = a.training_job_id
+		WHERE d.dataset_version_id = $1
+		ON CONFLICT (artifact_id, dataset_version_id) WHERE dataset_version_id IS NOT NULL DO NOTHING
+		RETURNING artifact_id
+	`
+	rows, err := tx.QueryContext(ctx, flagQuery, v.ID, flag.Reason, nullString(flag.Detail), in.RevokedAt)
+	if err != nil {
+		return models.DatasetVersion{}, nil, fmt.Errorf("flag artifacts: %w", err)
+	}
+	var flagged []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return models.DatasetVersion{}, nil, fmt.Errorf("scan flagged artifact: %w", err)
+		}
+		flagged = append(flagged, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return models.DatasetVersion{}, nil, fmt.Errorf("iterate flagged artifacts: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return models.DatasetVersion{}, nil, fmt.Errorf("commit dataset revocation: %w", err)
+	}
+	return v, flagged, nil
+}
+
+func (s *PGStore) CreateManifestSignature(ctx context.Context, ms models.ManifestSignature) (models.ManifestSignature, error) {
+	query := `
+		INSERT INTO manifest_signatures (id, manifest_id, subject_type, subject_id, manifest_hash, signer_id, signature, version, signed_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		RETURNING ` + manifestSignatureColumns
+	created, err := scanManifestSignature(s.db.QueryRowContext(ctx, query, ms.ID, ms.ManifestID, ms.SubjectType, ms.SubjectID,
+		ms.ManifestHash, ms.SignerID, ms.Signature, nullString(ms.Version), ms.SignedAt))
+	if err != nil {
+		return models.ManifestSignature{}, fmt.Errorf("insert manifest signature: %w", err)
+	}
+	return created, nil
+}
+
+func (s *PGStore) GetManifestSignature(ctx context.Context, id string) (models.ManifestSignature, error) {
+	query := `SELECT ` + manifestSignatureColumns + ` FROM manifest_signatures WHERE id = $1`
+	ms, err := scanManifestSignature(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ManifestSignature{}, ErrNotFound
+		}
+		return models.ManifestSignature{}, fmt.Errorf("get manifest signature: %w", err)
+	}
+	return ms, nil
+}
+
+func scanManifestSignature(row rowScanner) (models.ManifestSignature, error) {
+	var (
+		ms      models.ManifestSignature
+		version sql.NullString
+	)
+	if err := row.Scan(&ms.ID, &ms.ManifestID, &ms.SubjectType, &ms.SubjectID, &ms.ManifestHash, &ms.SignerID,
+		&ms.Signature, &version, &ms.SignedAt, &ms.CreatedAt); err != nil {
+		return models.ManifestSignature{}, err
+	}
+	ms.Version = version.String
+	return ms, nil
+}
+
 func (s *PGStore) Ping(ctx context.Context) error {
 	if err := s.db.PingContext(ctx); err != nil {
 		return fmt.Errorf("db ping: %w", err)
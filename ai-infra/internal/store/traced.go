@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/ai-infra/internal/models"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+// WithTracing wraps s so every call records a "db.<Method>" span.
+func WithTracing(s Store) Store {
+	return &tracedStore{next: s}
+}
+
+type tracedStore struct {
+	next Store
+}
+
+func traced[T any](ctx context.Context, op string, fn func(context.Context) (T, error)) (T, error) {
+	ctx, span := tracing.Start(ctx, "db."+op)
+	defer span.End()
+	span.SetAttribute("db.system", "postgresql")
+	v, err := fn(ctx)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedStore) CreateTrainingJob(ctx context.Context, in TrainingJobInput) (models.TrainingJob, error) {
+	return traced(ctx, "CreateTrainingJob", func(ctx context.Context) (models.TrainingJob, error) {
+		return t.next.CreateTrainingJob(ctx, in)
+	})
+}
+
+func (t *tracedStore) GetTrainingJob(ctx context.Context, id uuid.UUID) (models.TrainingJob, error) {
+	return traced(ctx, "GetTrainingJob", func(ctx context.Context) (models.TrainingJob, error) {
+		return t.next.GetTrainingJob(ctx, id)
+	})
+}
+
+func (t *tracedStore) ListTrainingJobs(ctx context.Context, filter ListTrainingJobsFilter) ([]models.TrainingJob, error) {
+	return traced(ctx, "ListTrainingJobs", func(ctx context.Context) ([]models.TrainingJob, error) {
+		return t.next.ListTrainingJobs(ctx, filter)
+	})
+}
+
+func (t *tracedStore) ClaimNextTrainingJob(ctx context.Context, workerID string, lease time.Duration) (models.TrainingJob, error) {
+	return traced(ctx, "ClaimNextTrainingJob", func(ctx context.Context) (models.TrainingJob, error) {
+		return t.next.ClaimNextTrainingJob(ctx, workerID, lease)
+	})
+}
+
+func (t *tracedStore) HeartbeatTrainingJob(ctx context.Context, id uuid.UUID, workerID string, lease time.Duration) (models.TrainingJob, error) {
+	return traced(ctx, "HeartbeatTrainingJob", func(ctx context.Context) (models.TrainingJob, error) {
+		return t.next.HeartbeatTrainingJob(ctx, id, workerID, lease)
+	})
+}
+
+func (t *tracedStore) TransitionTrainingJob(ctx context.Context, in TrainingJobTransition) (models.TrainingJob, error) {
+	return traced(ctx, "TransitionTrainingJob", func(ctx context.Context) (models.TrainingJob, error) {
+		return t.next.TransitionTrainingJob(ctx, in)
+	})
+}
+
+func (t *tracedStore) CreateArtifact(ctx context.Context, in ArtifactInput) (models.ModelArtifact, error) {
+	return traced(ctx, "CreateArtifact", func(ctx context.Context) (models.ModelArtifact, error) {
+		return t.next.CreateArtifact(ctx, in)
+	})
+}
+
+func (t *tracedStore) ListArtifacts(ctx context.Context, filter ListArtifactsFilter) ([]models.ModelArtifact, error) {
+	return traced(ctx, "ListArtifacts", func(ctx context.Context) ([]models.ModelArtifact, error) {
+		return t.next.ListArtifacts(ctx, filter)
+	})
+}
+
+func (t *tracedStore) GetArtifact(ctx context.Context, id uuid.UUID) (models.ModelArtifact, error) {
+	return traced(ctx, "GetArtifact", func(ctx context.Context) (models.ModelArtifact, error) {
+		return t.next.GetArtifact(ctx, id)
+	})
+}
+
+func (t *tracedStore) ReferencedArtifactURIs(ctx context.Context, uris []string) (map[string]bool, error) {
+	return traced(ctx, "ReferencedArtifactURIs", func(ctx context.Context) (map[string]bool, error) {
+		return t.next.ReferencedArtifactURIs(ctx, uris)
+	})
+}
+
+func (t *tracedStore) ListArtifactParents(ctx context.Context, artifactID uuid.UUID) ([]models.ArtifactLink, error) {
+	return traced(ctx, "ListArtifactParents", func(ctx context.Context) ([]models.ArtifactLink, error) {
+		return t.next.ListArtifactParents(ctx, artifactID)
+	})
+}
+
+func (t *tracedStore) ListArtifactChildren(ctx context.Context, parentID uuid.UUID) ([]models.ArtifactLink, error) {
+	return traced(ctx, "ListArtifactChildren", func(ctx context.Context) ([]models.ArtifactLink, error) {
+		return t.next.ListArtifactChildren(ctx, parentID)
+	})
+}
+
+func (t *tracedStore) ListTrainingJobDatasets(ctx context.Context, trainingJobID uuid.UUID) ([]models.DatasetRef, error) {
+	return traced(ctx, "ListTrainingJobDatasets", func(ctx context.Context) ([]models.DatasetRef, error) {
+		return t.next.ListTrainingJobDatasets(ctx, trainingJobID)
+	})
+}
+
+func (t *tracedStore) CreateDatasetVersion(ctx context.Context, v models.DatasetVersion) (models.DatasetVersion, error) {
+	return traced(ctx, "CreateDatasetVersion", func(ctx context.Context) (models.DatasetVersion, error) {
+		return t.next.CreateDatasetVersion(ctx, v)
+	})
+}
+
+func (t *tracedStore) GetDatasetVersion(ctx context.Context, id uuid.UUID) (models.DatasetVersion, error) {
+	return traced(ctx, "GetDatasetVersion", func(ctx context.Context) (models.DatasetVersion, error) {
+		return t.next.GetDatasetVersion(ctx, id)
+	})
+}
+
+func (t *tracedStore) ListDatasetVersions(ctx context.Context, filter ListDatasetVersionsFilter) ([]models.DatasetVersion, error) {
+	return traced(ctx, "ListDatasetVersions", func(ctx context.Context) ([]models.DatasetVersion, error) {
+		return t.next.ListDatasetVersions(ctx, filter)
+	})
+}
+
+func (t *tracedStore) RevokeDatasetVersion(ctx context.Context, in DatasetRevocation) (models.DatasetVersion, []uuid.UUID, error) {
+	var flagged []uuid.UUID
+	v, err := traced(ctx, "RevokeDatasetVersion", func(ctx context.Context) (models.DatasetVersion, error) {
+		v, ids, err := t.next.RevokeDatasetVersion(ctx, in)
+		flagged = ids
+		return v, err
+	})
+	return v, flagged, err
+}
+
+func (t *tracedStore) CreateManifestSignature(ctx context.Context, ms models.ManifestSignature) (models.ManifestSignature, error) {
+	return traced(ctx, "CreateManifestSignature", func(ctx context.Context) (models.ManifestSignature, error) {
+		return t.next.CreateManifestSignature(ctx, ms)
+	})
+}
+
+func (t *tracedStore) GetManifestSignature(ctx context.Context, id string) (models.ManifestSignature, error) {
+	return traced(ctx, "GetManifestSignature", func(ctx context.Context) (models.ManifestSignature, error) {
+		return t.next.GetManifestSignature(ctx, id)
+	})
+}
+
+func (t *tracedStore) CreatePromotion(ctx context.Context, in PromotionInput) (models.ModelPromotion, error) {
+	return traced(ctx, "CreatePromotion", func(ctx context.Context) (models.ModelPromotion, error) {
+		return t.next.CreatePromotion(ctx, in)
+	})
+}
+
+func (t *tracedStore) GetPromotion(ctx context.Context, id uuid.UUID) (models.ModelPromotion, error) {
+	return traced(ctx, "GetPromotion", func(ctx context.Context) (models.ModelPromotion, error) {
+		return t.next.GetPromotion(ctx, id)
+	})
+}
+
+func (t *tracedStore) ListPromotionsByStatus(ctx context.Context, status string) ([]models.ModelPromotion, error) {
+	return traced(ctx, "ListPromotionsByStatus", func(ctx context.Context) ([]models.ModelPromotion, error) {
+		return t.next.ListPromotionsByStatus(ctx, status)
+	})
+}
+
+func (t *tracedStore) LatestAppliedPromotion(ctx context.Context, environment string) (models.ModelPromotion, error) {
+	return traced(ctx, "LatestAppliedPromotion", func(ctx context.Context) (models.ModelPromotion, error) {
+		return t.next.LatestAppliedPromotion(ctx, environment)
+	})
+}
+
+func (t *tracedStore) ListPromotionsByEnvironment(ctx context.Context, environment string, limit int) ([]models.ModelPromotion, error) {
+	return traced(ctx, "ListPromotionsByEnvironment", func(ctx context.Context) ([]models.ModelPromotion, error) {
+		return t.next.ListPromotionsByEnvironment(ctx, environment, limit)
+	})
+}
+
+func (t *tracedStore) ListPromotionsByArtifact(ctx context.Context, artifactID uuid.UUID) ([]models.ModelPromotion, error) {
+	return traced(ctx, "ListPromotionsByArtifact", func(ctx context.Context) ([]models.ModelPromotion, error) {
+		return t.next.ListPromotionsByArtifact(ctx, artifactID)
+	})
+}
+
+func (t *tracedStore) UpdatePromotionStatus(ctx context.Context, in PromotionStatusUpdate) (models.ModelPromotion, error) {
+	return traced(ctx, "UpdatePromotionStatus", func(ctx context.Context) (models.ModelPromotion, error) {
+		return t.next.UpdatePromotionStatus(ctx, in)
+	})
+}
+
+func (t *tracedStore) AddCanaryObservation(ctx context.Context, obs models.CanaryObservation) error {
+	_, err := traced(ctx, "AddCanaryObservation", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, t.next.AddCanaryObservation(ctx, obs)
+	})
+	return err
+}
+
+func (t *tracedStore) ListCanaryObservations(ctx context.Context, promotionID uuid.UUID) ([]models.CanaryObservation, error) {
+	return traced(ctx, "ListCanaryObservations", func(ctx context.Context) ([]models.CanaryObservation, error) {
+		return t.next.ListCanaryObservations(ctx, promotionID)
+	})
+}
+
+func (t *tracedStore) AppendJobLogs(ctx context.Context, jobID uuid.UUID, chunks []models.JobLogChunk) ([]models.JobLogChunk, error) {
+	return traced(ctx, "AppendJobLogs", func(ctx context.Context) ([]models.JobLogChunk, error) {
+		return t.next.AppendJobLogs(ctx, jobID, chunks)
+	})
+}
+
+func (t *tracedStore) ListJobLogs(ctx context.Context, jobID uuid.UUID, afterSeq int64, limit int) ([]models.JobLogChunk, error) {
+	return traced(ctx, "ListJobLogs", func(ctx context.Context) ([]models.JobLogChunk, error) {
+		return t.next.ListJobLogs(ctx, jobID, afterSeq, limit)
+	})
+}
+
+func (t *tracedStore) AppendJobMetrics(ctx context.Context, jobID uuid.UUID, points []models.JobMetricPoint) ([]models.JobMetricPoint, error) {
+	return traced(ctx, "AppendJobMetrics", func(ctx context.Context) ([]models.JobMetricPoint, error) {
+		return t.next.AppendJobMetrics(ctx, jobID, points)
+	})
+}
+
+func (t *tracedStore) ListJobMetrics(ctx context.Context, jobID uuid.UUID, afterSeq int64, limit int) ([]models.JobMetricPoint, error) {
+	return traced(ctx, "ListJobMetrics", func(ctx context.Context) ([]models.JobMetricPoint, error) {
+		return t.next.ListJobMetrics(ctx, jobID, afterSeq, limit)
+	})
+}
+
+func (t *tracedStore) CreateVerification(ctx context.Context, v models.ArtifactVerification) (models.ArtifactVerification, error) {
+	return traced(ctx, "CreateVerification", func(ctx context.Context) (models.ArtifactVerification, error) {
+		return t.next.CreateVerification(ctx, v)
+	})
+}
+
+func (t *tracedStore) GetVerification(ctx context.Context, id uuid.UUID) (models.ArtifactVerification, error) {
+	return traced(ctx, "GetVerification", func(ctx context.Context) (models.ArtifactVerification, error) {
+		return t.next.GetVerification(ctx, id)
+	})
+}
+
+func (t *tracedStore) GetVerificationByReproJob(ctx context.Context, jobID uuid.UUID) (models.ArtifactVerification, error) {
+	return traced(ctx, "GetVerificationByReproJob", func(ctx context.Context) (models.ArtifactVerification, error) {
+		return t.next.GetVerificationByReproJob(ctx, jobID)
+	})
+}
+
+func (t *tracedStore) ListVerificationsByArtifact(ctx context.Context, artifactID uuid.UUID, limit int) ([]models.ArtifactVerification, error) {
+	return traced(ctx, "ListVerificationsByArtifact", func(ctx context.Context) ([]models.ArtifactVerification, error) {
+		return t.next.ListVerificationsByArtifact(ctx, artifactID, limit)
+	})
+}
+
+func (t *tracedStore) CompleteVerification(ctx context.Context, v models.ArtifactVerification) (models.ArtifactVerification, error) {
+	return traced(ctx, "CompleteVerification", func(ctx context.Context) (models.ArtifactVerification, error) {
+		return t.next.CompleteVerification(ctx, v)
+	})
+}
+
+func (t *tracedStore) Ping(ctx context.Context) error {
+	_, err := traced(ctx, "Ping", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, t.next.Ping(ctx)
+	})
+	return err
+}
@@ -0,0 +1,16 @@
+-- ai-infra/sql/migrations/002_signing_envelope.sql
+-- Store the canonical signing envelope hash, version and timestamp next to each signature.
+
+BEGIN;
+
+ALTER TABLE model_artifacts
+    ADD COLUMN IF NOT EXISTS signature_hash TEXT,
+    ADD COLUMN IF NOT EXISTS signature_version TEXT,
+    ADD COLUMN IF NOT EXISTS signed_at TIMESTAMPTZ;
+
+ALTER TABLE model_promotions
+    ADD COLUMN IF NOT EXISTS signature_hash TEXT,
+    ADD COLUMN IF NOT EXISTS signature_version TEXT,
+    ADD COLUMN IF NOT EXISTS signed_at TIMESTAMPTZ;
+
+COMMIT;
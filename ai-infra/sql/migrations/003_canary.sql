@@ -0,0 +1,25 @@
+-- ai-infra/sql/migrations/003_canary.sql
+-- Canary rollouts: canary state/history on each promotion and the metric observations the
+-- canary controller compares between the canary and the incumbent artifact.
+
+BEGIN;
+
+ALTER TABLE model_promotions
+    ADD COLUMN IF NOT EXISTS canary JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_model_promotions_canary
+    ON model_promotions (created_at)
+    WHERE status = 'canary';
+
+CREATE TABLE IF NOT EXISTS canary_observations (
+    id BIGSERIAL PRIMARY KEY,
+    promotion_id UUID NOT NULL REFERENCES model_promotions(id) ON DELETE CASCADE,
+    arm TEXT NOT NULL CHECK (arm IN ('canary', 'incumbent')),
+    metrics JSONB NOT NULL,
+    observed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_canary_observations_promotion
+    ON canary_observations (promotion_id, observed_at);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- ai-infra/sql/migrations/004_environment_rollback.sql
+-- Environment rollbacks: the rollback record on the promotion that restored an earlier
+-- artifact, and an index for the per-environment history behind GET /ai-infra/environments/{env}.
+
+BEGIN;
+
+ALTER TABLE model_promotions
+    ADD COLUMN IF NOT EXISTS rollback JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_model_promotions_environment
+    ON model_promotions (environment, created_at DESC);
+
+COMMIT;
@@ -0,0 +1,47 @@
+-- ai-infra/sql/migrations/005_lineage.sql
+-- Lineage: the artifacts each artifact was derived from and the datasets each training job
+-- consumed, as rows behind GET /ai-infra/lineage/{id}.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS artifact_parents (
+    artifact_id UUID NOT NULL REFERENCES model_artifacts(id) ON DELETE CASCADE,
+    parent_id UUID NOT NULL REFERENCES model_artifacts(id),
+    relation TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (artifact_id, parent_id),
+    CHECK (artifact_id <> parent_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifact_parents_parent
+    ON artifact_parents (parent_id, created_at);
+
+CREATE TABLE IF NOT EXISTS training_job_datasets (
+    training_job_id UUID NOT NULL REFERENCES training_jobs(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    dataset_id TEXT NOT NULL,
+    version TEXT,
+    checksum TEXT,
+    uri TEXT,
+    PRIMARY KEY (training_job_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_training_job_datasets_dataset
+    ON training_job_datasets (dataset_id, version);
+
+-- Backfill dataset rows from the dataset_refs of existing jobs: string entries are dataset
+-- ids, object entries need an id.
+INSERT INTO training_job_datasets (training_job_id, position, dataset_id, version, checksum, uri)
+SELECT j.id,
+       (e.ordinality - 1)::INT,
+       CASE WHEN jsonb_typeof(e.value) = 'string' THEN e.value #>> '{}' ELSE e.value ->> 'id' END,
+       CASE WHEN jsonb_typeof(e.value) = 'object' THEN e.value ->> 'version' END,
+       CASE WHEN jsonb_typeof(e.value) = 'object' THEN e.value ->> 'checksum' END,
+       CASE WHEN jsonb_typeof(e.value) = 'object' THEN e.value ->> 'uri' END
+FROM training_jobs j
+CROSS JOIN LATERAL jsonb_array_elements(j.dataset_refs) WITH ORDINALITY AS e(value, ordinality)
+WHERE jsonb_typeof(j.dataset_refs) = 'array'
+  AND (jsonb_typeof(e.value) = 'string' OR (jsonb_typeof(e.value) = 'object' AND e.value ? 'id'))
+ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,36 @@
+-- ai-infra/sql/migrations/006_job_lifecycle.sql
+-- Training job lifecycle: attempts and retry backoff, worker leases renewed by heartbeats,
+-- a per-job max runtime, and the queued/running/succeeded/failed/cancelled/timed_out
+-- state machine.
+
+BEGIN;
+
+ALTER TABLE training_jobs
+    ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS max_attempts INT NOT NULL DEFAULT 3,
+    ADD COLUMN IF NOT EXISTS max_runtime_seconds INT,
+    ADD COLUMN IF NOT EXISTS worker_id TEXT,
+    ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS started_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS finished_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS last_error TEXT;
+
+-- The runner used to mark finished jobs "completed".
+UPDATE training_jobs SET status = 'succeeded', finished_at = updated_at WHERE status = 'completed';
+UPDATE training_jobs SET finished_at = updated_at WHERE status = 'failed' AND finished_at IS NULL;
+
+ALTER TABLE training_jobs DROP CONSTRAINT IF EXISTS training_jobs_status_check;
+ALTER TABLE training_jobs ADD CONSTRAINT training_jobs_status_check
+    CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled', 'timed_out'));
+
+-- Workers claim the oldest queued job whose backoff has elapsed.
+CREATE INDEX IF NOT EXISTS idx_training_jobs_claim
+    ON training_jobs (status, next_attempt_at, created_at);
+
+-- The reaper scans running jobs for expired leases.
+CREATE INDEX IF NOT EXISTS idx_training_jobs_lease
+    ON training_jobs (lease_expires_at) WHERE status = 'running';
+
+COMMIT;
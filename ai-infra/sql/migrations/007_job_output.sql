@@ -0,0 +1,27 @@
+-- ai-infra/sql/migrations/007_job_output.sql
+-- Training job output: log chunks and metrics samples reported while a job runs, each
+-- numbered per job so clients page and resume streams by seq.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS training_job_logs (
+    job_id UUID NOT NULL REFERENCES training_jobs(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL,
+    attempt INT NOT NULL,
+    stream TEXT NOT NULL CHECK (stream IN ('stdout', 'stderr')),
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS training_job_metrics (
+    job_id UUID NOT NULL REFERENCES training_jobs(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL,
+    attempt INT NOT NULL,
+    step BIGINT NOT NULL,
+    metrics JSONB NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, seq)
+);
+
+COMMIT;
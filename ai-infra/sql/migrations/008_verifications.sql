@@ -0,0 +1,34 @@
+-- ai-infra/sql/migrations/008_verifications.sql
+-- Recorded artifact verifications: the outcome of each check, signed once every check has
+-- finished, and the training jobs that reproduce an artifact for them.
+
+BEGIN;
+
+ALTER TABLE training_jobs
+    ADD COLUMN IF NOT EXISTS reproduces_artifact_id UUID REFERENCES model_artifacts(id);
+
+-- repro_job_id has no foreign key: the verification is created before its reproduction
+-- job so a worker finishing the job always finds it.
+CREATE TABLE IF NOT EXISTS artifact_verifications (
+    id UUID PRIMARY KEY,
+    artifact_id UUID NOT NULL REFERENCES model_artifacts(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('pending', 'passed', 'failed')),
+    checks JSONB NOT NULL,
+    repro_job_id UUID,
+    requested_by TEXT,
+    completed_at TIMESTAMPTZ,
+    signature TEXT,
+    signer_id TEXT,
+    signature_hash TEXT,
+    signature_version TEXT,
+    signed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifact_verifications_artifact
+    ON artifact_verifications (artifact_id, created_at DESC);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_artifact_verifications_repro_job
+    ON artifact_verifications (repro_job_id) WHERE repro_job_id IS NOT NULL;
+
+COMMIT;
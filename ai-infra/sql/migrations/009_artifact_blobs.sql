@@ -0,0 +1,10 @@
+-- ai-infra/sql/migrations/009_artifact_blobs.sql
+-- Artifact content uploaded to the blob store is addressed by sha256; blob garbage
+-- collection looks up which blob URIs artifacts still reference.
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_model_artifacts_artifact_uri
+    ON model_artifacts (artifact_uri);
+
+COMMIT;
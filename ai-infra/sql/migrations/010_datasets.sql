@@ -0,0 +1,49 @@
+-- ai-infra/sql/migrations/010_datasets.sql
+-- Dataset registry: immutable dataset versions with a manifest of file hashes, license and
+-- PII flags, training job references to them, and the flags raised on artifacts trained on
+-- a version that was later revoked.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS dataset_versions (
+    id UUID PRIMARY KEY,
+    name TEXT NOT NULL,
+    version TEXT NOT NULL,
+    uri TEXT,
+    manifest JSONB NOT NULL,
+    manifest_hash TEXT NOT NULL,
+    license TEXT NOT NULL,
+    pii_flags TEXT[] NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'revoked')),
+    created_by TEXT,
+    revoked_at TIMESTAMPTZ,
+    revoked_by TEXT,
+    revocation_reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (name, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dataset_versions_name
+    ON dataset_versions (name, created_at DESC);
+
+ALTER TABLE training_job_datasets
+    ADD COLUMN IF NOT EXISTS dataset_version_id UUID REFERENCES dataset_versions(id);
+
+CREATE INDEX IF NOT EXISTS idx_training_job_datasets_dataset_version
+    ON training_job_datasets (dataset_version_id) WHERE dataset_version_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS artifact_flags (
+    artifact_id UUID NOT NULL REFERENCES model_artifacts(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    dataset_version_id UUID REFERENCES dataset_versions(id),
+    detail TEXT,
+    flagged_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifact_flags_artifact
+    ON artifact_flags (artifact_id, flagged_at);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_artifact_flags_dataset_version
+    ON artifact_flags (artifact_id, dataset_version_id) WHERE dataset_version_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- ai-infra/sql/migrations/011_manifest_signatures.sql
+-- Kernel manifest signatures: the signatures ai-infra obtains from Kernel POST /kernel/sign
+-- over artifact and promotion manifests, and the reference from applied promotions to
+-- theirs. model_artifacts.manifest_signature_id keeps no foreign key because artifacts
+-- registered before this migration may reference signatures ai-infra never stored.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS manifest_signatures (
+    id TEXT PRIMARY KEY,
+    manifest_id TEXT NOT NULL,
+    subject_type TEXT NOT NULL CHECK (subject_type IN ('artifact', 'promotion')),
+    subject_id UUID NOT NULL,
+    manifest_hash TEXT NOT NULL,
+    signer_id TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    version TEXT,
+    signed_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_manifest_signatures_subject
+    ON manifest_signatures (subject_type, subject_id);
+
+ALTER TABLE model_promotions
+    ADD COLUMN IF NOT EXISTS manifest_signature_id TEXT REFERENCES manifest_signatures(id);
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- ai-infra/sql/migrations/012_single_canary_per_environment.sql
+-- At most one canary promotion per environment. Concurrent canary starts used to race
+-- between listing the running canaries and moving the new promotion to canary; the
+-- partial unique index makes the second transition fail instead.
+
+BEGIN;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_model_promotions_canary_environment
+    ON model_promotions (environment)
+    WHERE status = 'canary';
+
+COMMIT;
@@ -17,6 +17,7 @@ import (
 	"github.com/ILLUVRSE/Main/eval-engine/internal/ingestion"
 	httpserver "github.com/ILLUVRSE/Main/eval-engine/internal/ingestion/httpserver"
 	"github.com/ILLUVRSE/Main/eval-engine/internal/store"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 func main() {
@@ -24,6 +25,12 @@ func main() {
 	if err != nil {
 		log.Fatalf("load config: %v", err)
 	}
+	tracer, err := tracing.FromEnv("eval-ingestion")
+	if err != nil {
+		log.Fatalf("tracing init: %v", err)
+	}
+	tracing.SetDefault(tracer)
+	defer tracer.Close()
 	db, err := sql.Open("postgres", cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("open db: %v", err)
@@ -16,6 +16,7 @@ import (
 	allochttp "github.com/ILLUVRSE/Main/eval-engine/internal/allocator/httpserver"
 	"github.com/ILLUVRSE/Main/eval-engine/internal/config"
 	"github.com/ILLUVRSE/Main/eval-engine/internal/store"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 func main() {
@@ -24,6 +25,12 @@ func main() {
 		log.Fatalf("load config: %v", err)
 	}
 
+	tracer, err := tracing.FromEnv("resource-allocator")
+	if err != nil {
+		log.Fatalf("tracing init: %v", err)
+	}
+	tracing.SetDefault(tracer)
+	defer tracer.Close()
 	db, err := sql.Open("postgres", cfg.DatabaseURL)
 	if err != nil {
 		log.Fatalf("open db: %v", err)
@@ -2,19 +2,28 @@ package main
 
 import (
 	"database/sql"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/ILLUVRSE/Main/eval-engine/internal/api"
 	"github.com/ILLUVRSE/Main/eval-engine/internal/service"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+	"github.com/ILLUVRSE/Main/shared/metrics"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	_ "github.com/lib/pq"
 )
 
 func main() {
+	tracer, err := tracing.FromEnv("eval-engine")
+	if err != nil {
+		log.Fatalf("Failed to configure tracing: %v", err)
+	}
+	tracing.SetDefault(tracer)
+	defer tracer.Close()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8050"
@@ -39,10 +48,22 @@ func main() {
 	}
 
 	svc := service.NewPromotionService(db, financeURL, reasoningURL)
+	if os.Getenv("KERNEL_API_URL") != "" {
+		kernel, err := kernelclient.New(kernelclient.ConfigFromEnv("eval-engine"))
+		if err != nil {
+			log.Fatalf("Failed to configure Kernel client: %v", err)
+		}
+		svc.SetAuditor(kernel)
+	} else {
+		log.Printf("Warning: KERNEL_API_URL not set; audit events are written to the local audit_events table")
+	}
 	handler := api.NewPromotionHandler(svc)
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
+	r.Use(tracing.Middleware)
+	r.Use(metrics.NewHTTPMetrics(metrics.Default, "eval-engine").Middleware)
+	r.Handle("/metrics", metrics.Default.Handler())
 	handler.RegisterRoutes(r)
 
 	log.Printf("Eval Engine listening on port %s", port)
@@ -10,23 +10,32 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/ILLUVRSE/Main/eval-engine/internal/allocator"
+	"github.com/ILLUVRSE/Main/shared/metrics"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 type Server struct {
 	service *allocator.Service
+
+	metrics     *metrics.Registry
+	httpMetrics *metrics.HTTPMetrics
 }
 
 func New(service *allocator.Service) *Server {
-	return &Server{service: service}
+	reg := metrics.NewRegistry()
+	return &Server{service: service, metrics: reg, httpMetrics: metrics.NewHTTPMetrics(reg, "resource-allocator")}
 }
 
 func (s *Server) Router() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(tracing.Middleware)
+	r.Use(s.httpMetrics.Middleware)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
 
+	r.Handle("/metrics", s.metrics.Handler())
 	r.Post("/alloc/request", s.handleRequest)
 	r.Post("/alloc/approve", s.handleApprove)
 	r.Post("/alloc/reject", s.handleReject)
@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 
 	"github.com/ILLUVRSE/Main/eval-engine/internal/ingestion"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 type Client struct {
@@ -21,7 +22,7 @@ type Client struct {
 func New(baseURL string) *Client {
 	return &Client{
 		baseURL: baseURL,
-		client:  &http.Client{Timeout: 5 * time.Second},
+		client:  tracing.WrapClient(&http.Client{Timeout: 5 * time.Second}),
 	}
 }
 
@@ -12,17 +12,25 @@ import (
 
 	"github.com/ILLUVRSE/Main/eval-engine/internal/ingestion"
 	"github.com/ILLUVRSE/Main/eval-engine/internal/store"
+	"github.com/ILLUVRSE/Main/shared/metrics"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 type Server struct {
 	service *ingestion.Service
 	store   store.Store
+
+	metrics     *metrics.Registry
+	httpMetrics *metrics.HTTPMetrics
 }
 
 func New(service *ingestion.Service, store store.Store) *Server {
+	reg := metrics.NewRegistry()
 	return &Server{
-		service: service,
-		store:   store,
+		service:     service,
+		store:       store,
+		metrics:     reg,
+		httpMetrics: metrics.NewHTTPMetrics(reg, "eval-ingestion"),
 	}
 }
 
@@ -30,10 +38,15 @@ func (s *Server) Router() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(tracing.Middleware)
+	r.Use(s.httpMetrics.Middleware)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
 
+	r.Handle("/metrics", s.metrics.Handler())
 	r.Post("/eval/submit", s.handleSubmit)
+	r.Get("/eval/reports/{id}", s.handleGetReport)
+	r.Get("/eval/promotions/{id}", s.handleGetPromotion)
 	r.Get("/eval/agent/{id}/score", s.handleGetScore)
 	r.Get("/eval/scoreboard", s.handleScoreboard)
 	r.Post("/eval/promote", s.handlePromote)
@@ -44,12 +57,16 @@ func (s *Server) Router() http.Handler {
 }
 
 type submitRequest struct {
+	ID         *uuid.UUID      `json:"id"`
 	AgentID    string          `json:"agentId"`
 	DivisionID string          `json:"divisionId"`
 	Metrics    json.RawMessage `json:"metrics"`
+	MetricSet  json.RawMessage `json:"metricSet"`
 	Source     string          `json:"source"`
 	Tags       json.RawMessage `json:"tags"`
 	TS         *time.Time      `json:"timestamp"`
+	// KernelAuditID is set when the Kernel forwards a report it has audited.
+	KernelAuditID string `json:"kernelAuditId"`
 }
 
 func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
@@ -62,14 +79,23 @@ func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
 	if req.TS != nil {
 		ts = req.TS.UTC()
 	}
-	result, err := s.service.SubmitReport(r.Context(), ingestion.SubmitReportInput{
-		AgentID:    req.AgentID,
-		DivisionID: req.DivisionID,
-		Metrics:    req.Metrics,
-		Source:     req.Source,
-		Tags:       req.Tags,
-		TS:         ts,
-	})
+	metrics := req.Metrics
+	if len(metrics) == 0 {
+		metrics = req.MetricSet
+	}
+	in := ingestion.SubmitReportInput{
+		AgentID:       req.AgentID,
+		DivisionID:    req.DivisionID,
+		Metrics:       metrics,
+		Source:        req.Source,
+		Tags:          req.Tags,
+		TS:            ts,
+		KernelAuditID: req.KernelAuditID,
+	}
+	if req.ID != nil {
+		in.ID = *req.ID
+	}
+	result, err := s.service.SubmitReport(r.Context(), in)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
@@ -84,6 +110,42 @@ func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, resp)
 }
 
+func (s *Server) handleGetReport(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid report id")
+		return
+	}
+	report, err := s.service.GetEvalReport(r.Context(), id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "report not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, report)
+}
+
+func (s *Server) handleGetPromotion(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid promotion id")
+		return
+	}
+	event, err := s.service.GetPromotionEvent(r.Context(), id)
+	if err != nil {
+		if err == store.ErrNotFound {
+			respondError(w, http.StatusNotFound, "promotion not found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, event)
+}
+
 func (s *Server) handleGetScore(w http.ResponseWriter, r *http.Request) {
 	agentID := chi.URLParam(r, "id")
 	score, err := s.service.GetAgentScore(r.Context(), agentID)
@@ -52,12 +52,17 @@ func New(store store.Store, alloc AllocatorClient, cfg ServiceConfig) *Service {
 }
 
 type SubmitReportInput struct {
+	// ID is optional; the Kernel passes its own eval id so both records share it.
+	ID         uuid.UUID
 	AgentID    string
 	DivisionID string
 	Metrics    json.RawMessage
 	Source     string
 	Tags       json.RawMessage
 	TS         time.Time
+	// KernelAuditID is the Kernel audit event recording the submission; it is stored on
+	// the report and on any promotion the report triggers.
+	KernelAuditID string
 }
 
 type SubmitReportResult struct {
@@ -78,11 +83,13 @@ func (s *Service) SubmitReport(ctx context.Context, in SubmitReportInput) (Submi
 	}
 
 	report, err := s.store.InsertEvalReport(ctx, store.EvalReportInput{
-		AgentID:   in.AgentID,
-		MetricSet: in.Metrics,
-		Source:    in.Source,
-		Tags:      in.Tags,
-		TS:        in.TS,
+		ID:            in.ID,
+		AgentID:       in.AgentID,
+		MetricSet:     in.Metrics,
+		Source:        in.Source,
+		Tags:          in.Tags,
+		TS:            in.TS,
+		KernelAuditID: in.KernelAuditID,
 	})
 	if err != nil {
 		return SubmitReportResult{}, err
@@ -106,13 +113,14 @@ func (s *Service) SubmitReport(ctx context.Context, in SubmitReportInput) (Submi
 	var promotion *models.PromotionEvent
 	if score.Score >= s.cfg.PromotionThreshold {
 		event, err := s.createPromotion(ctx, PromotionInput{
-			AgentID:     in.AgentID,
-			Action:      "promote",
-			Rationale:   fmt.Sprintf("score %.2f >= threshold %.2f", score.Score, s.cfg.PromotionThreshold),
-			Confidence:  score.Confidence,
-			RequestedBy: "eval-engine",
-			Pool:        s.cfg.DefaultPool,
-			Delta:       s.cfg.DefaultDelta,
+			AgentID:       in.AgentID,
+			Action:        "promote",
+			Rationale:     fmt.Sprintf("score %.2f >= threshold %.2f", score.Score, s.cfg.PromotionThreshold),
+			Confidence:    score.Confidence,
+			RequestedBy:   "eval-engine",
+			Pool:          s.cfg.DefaultPool,
+			Delta:         s.cfg.DefaultDelta,
+			KernelAuditID: in.KernelAuditID,
 		})
 		if err != nil {
 			return SubmitReportResult{}, fmt.Errorf("create promotion: %w", err)
@@ -183,6 +191,8 @@ type PromotionInput struct {
 	RequestedBy string
 	Pool        string
 	Delta       int
+	// KernelAuditID optionally links the promotion to a Kernel audit event.
+	KernelAuditID string
 }
 
 func (s *Service) CreateManualPromotion(ctx context.Context, in PromotionInput) (models.PromotionEvent, error) {
@@ -191,12 +201,13 @@ func (s *Service) CreateManualPromotion(ctx context.Context, in PromotionInput)
 
 func (s *Service) createPromotion(ctx context.Context, in PromotionInput) (models.PromotionEvent, error) {
 	event, err := s.store.CreatePromotionEvent(ctx, store.PromotionInput{
-		AgentID:     in.AgentID,
-		Action:      in.Action,
-		Rationale:   in.Rationale,
-		Confidence:  in.Confidence,
-		Status:      "pending",
-		RequestedBy: in.RequestedBy,
+		AgentID:       in.AgentID,
+		Action:        in.Action,
+		Rationale:     in.Rationale,
+		Confidence:    in.Confidence,
+		Status:        "pending",
+		RequestedBy:   in.RequestedBy,
+		KernelAuditID: in.KernelAuditID,
 	})
 	if err != nil {
 		return models.PromotionEvent{}, err
@@ -220,6 +231,14 @@ func (s *Service) createPromotion(ctx context.Context, in PromotionInput) (model
 	return event, nil
 }
 
+func (s *Service) GetEvalReport(ctx context.Context, id uuid.UUID) (models.EvalReport, error) {
+	return s.store.GetEvalReport(ctx, id)
+}
+
+func (s *Service) GetPromotionEvent(ctx context.Context, id uuid.UUID) (models.PromotionEvent, error) {
+	return s.store.GetPromotionEvent(ctx, id)
+}
+
 func (s *Service) GetAgentScore(ctx context.Context, agentID string) (models.AgentScore, error) {
 	return s.store.GetAgentScore(ctx, agentID)
 }
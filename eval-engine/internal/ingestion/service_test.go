@@ -0,0 +1,39 @@
+package ingestion_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ILLUVRSE/Main/eval-engine/internal/ingestion"
+	"github.com/ILLUVRSE/Main/eval-engine/internal/store"
+)
+
+func TestSubmitReportLinksKernelAuditEvent(t *testing.T) {
+	svc := ingestion.New(store.NewMemoryStore(), nil, ingestion.ServiceConfig{PromotionThreshold: 0.8})
+	id := uuid.New()
+
+	res, err := svc.SubmitReport(context.Background(), ingestion.SubmitReportInput{
+		ID:            id,
+		AgentID:       "agent-1",
+		Metrics:       json.RawMessage(`{"accuracy":0.9,"recall":0.95}`),
+		KernelAuditID: "audit-42",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, id, res.Report.ID)
+	assert.Equal(t, "audit-42", res.Report.KernelAuditID)
+	assert.InDelta(t, 0.93, res.Score.Score, 0.001)
+	require.NotNil(t, res.Promotion)
+	assert.Equal(t, "audit-42", res.Promotion.KernelAuditID)
+
+	report, err := svc.GetEvalReport(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "audit-42", report.KernelAuditID)
+	promotion, err := svc.GetPromotionEvent(context.Background(), res.Promotion.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "audit-42", promotion.KernelAuditID)
+}
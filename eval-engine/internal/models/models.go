@@ -8,13 +8,14 @@ import (
 )
 
 type EvalReport struct {
-	ID        uuid.UUID       `json:"id"`
-	AgentID   string          `json:"agentId"`
-	MetricSet json.RawMessage `json:"metricSet"`
-	Source    string          `json:"source,omitempty"`
-	Tags      json.RawMessage `json:"tags,omitempty"`
-	TS        time.Time       `json:"ts"`
-	CreatedAt time.Time       `json:"createdAt"`
+	ID            uuid.UUID       `json:"id"`
+	AgentID       string          `json:"agentId"`
+	MetricSet     json.RawMessage `json:"metricSet"`
+	Source        string          `json:"source,omitempty"`
+	Tags          json.RawMessage `json:"tags,omitempty"`
+	TS            time.Time       `json:"ts"`
+	KernelAuditID string          `json:"kernelAuditId,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
 }
 
 type AgentScore struct {
@@ -36,6 +37,7 @@ type PromotionEvent struct {
 	Status              string     `json:"status"`
 	RequestedBy         string     `json:"requestedBy"`
 	AllocationRequestID *uuid.UUID `json:"allocationRequestId,omitempty"`
+	KernelAuditID       string     `json:"kernelAuditId,omitempty"`
 	CreatedAt           time.Time  `json:"createdAt"`
 }
 
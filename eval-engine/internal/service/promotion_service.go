@@ -10,13 +10,21 @@ import (
 	"time"
 
 	"github.com/ILLUVRSE/Main/eval-engine/internal/model"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 	"github.com/google/uuid"
 )
 
+// Auditor appends events to the Kernel audit log (*kernelclient.Client satisfies it).
+type Auditor interface {
+	AppendAudit(ctx context.Context, eventType string, payload, metadata interface{}) (kernelclient.AuditEvent, error)
+}
+
 type PromotionService struct {
-	db              *sql.DB
-	financeURL      string
-	reasoningURL    string
+	db           *sql.DB
+	financeURL   string
+	reasoningURL string
+	auditor      Auditor
 }
 
 func NewPromotionService(db *sql.DB, financeURL, reasoningURL string) *PromotionService {
@@ -47,7 +55,14 @@ type AllocationRequest struct {
 	AuditContext   map[string]interface{} `json:"audit_context"`
 }
 
-func (s *PromotionService) Promote(ctx context.Context, req PromotionRequest) (*model.Promotion, error) {
+func (s *PromotionService) Promote(ctx context.Context, req PromotionRequest) (_ *model.Promotion, err error) {
+	ctx, span := tracing.Start(ctx, "eval.promote")
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	span.SetAttribute("artifact.id", req.ArtifactID)
+
 	// 1. Idempotency Check
 	if s.db != nil && req.IdempotencyKey != "" {
 		var existingID string
@@ -164,9 +179,22 @@ func (s *PromotionService) Allocate(ctx context.Context, req AllocationRequest)
 	return alloc, nil
 }
 
+// SetAuditor sends audit events to the Kernel, the authoritative audit log. Without
+// one, events fall back to the local audit_events table (development only).
+func (s *PromotionService) SetAuditor(a Auditor) {
+	s.auditor = a
+}
+
 // Helpers
 
 func (s *PromotionService) emitAuditEvent(ctx context.Context, eventType string, payload interface{}) error {
+	if s.auditor != nil {
+		_, err := s.auditor.AppendAudit(ctx, eventType, payload, map[string]interface{}{
+			"origin": "eval-engine",
+			"actor":  "service:eval-engine",
+		})
+		return err
+	}
 	if s.db == nil {
 		return nil
 	}
@@ -206,7 +234,7 @@ func (s *PromotionService) recordReasoningEvent(ctx context.Context, p *model.Pr
 	req, _ := http.NewRequestWithContext(ctx, "POST", s.reasoningURL+"/nodes", bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := tracing.WrapClient(&http.Client{Timeout: 5 * time.Second})
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
@@ -226,7 +254,7 @@ func (s *PromotionService) callFinanceAllocation(ctx context.Context, req Alloca
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Idempotency-Key", req.IdempotencyKey)
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := tracing.WrapClient(&http.Client{Timeout: 5 * time.Second})
 	resp, err := client.Do(httpReq)
 	if err != nil {
 		return err
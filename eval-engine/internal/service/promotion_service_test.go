@@ -11,6 +11,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ILLUVRSE/Main/eval-engine/internal/model"
 	"github.com/ILLUVRSE/Main/eval-engine/internal/service"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -163,3 +164,40 @@ func TestPromoteFlow_AllocationFailure(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+type recordingAuditor struct {
+	events []string
+}
+
+func (r *recordingAuditor) AppendAudit(ctx context.Context, eventType string, payload, metadata interface{}) (kernelclient.AuditEvent, error) {
+	r.events = append(r.events, eventType)
+	return kernelclient.AuditEvent{EventType: eventType}, nil
+}
+
+func TestPromoteEmitsAuditToKernel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	svc := service.NewPromotionService(db, "", "")
+	auditor := &recordingAuditor{}
+	svc.SetAuditor(auditor)
+
+	mock.ExpectQuery("SELECT id, status FROM promotions").WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO promotions").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE promotions SET status").WillReturnResult(sqlmock.NewResult(1, 1))
+	// No INSERT INTO audit_events: the Kernel is the audit sink.
+
+	_, err = svc.Promote(context.Background(), service.PromotionRequest{
+		RequestID:      "req-k",
+		ArtifactID:     "artifact-k",
+		IdempotencyKey: "idem-k",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"allocation.requested", "promotion.created"}, auditor.events)
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -45,13 +45,14 @@ func (m *MemoryStore) InsertEvalReport(ctx context.Context, in EvalReportInput)
 		in.ID = uuid.New()
 	}
 	report := models.EvalReport{
-		ID:        in.ID,
-		AgentID:   in.AgentID,
-		MetricSet: copyJSON(in.MetricSet),
-		Source:    in.Source,
-		Tags:      copyJSON(in.Tags),
-		TS:        in.TS,
-		CreatedAt: time.Now().UTC(),
+		ID:            in.ID,
+		AgentID:       in.AgentID,
+		MetricSet:     copyJSON(in.MetricSet),
+		Source:        in.Source,
+		Tags:          copyJSON(in.Tags),
+		TS:            in.TS,
+		KernelAuditID: in.KernelAuditID,
+		CreatedAt:     time.Now().UTC(),
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -59,6 +60,16 @@ func (m *MemoryStore) InsertEvalReport(ctx context.Context, in EvalReportInput)
 	return report, nil
 }
 
+func (m *MemoryStore) GetEvalReport(ctx context.Context, id uuid.UUID) (models.EvalReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	report, ok := m.reports[id]
+	if !ok {
+		return models.EvalReport{}, ErrNotFound
+	}
+	return report, nil
+}
+
 func (m *MemoryStore) UpsertAgentScore(ctx context.Context, in AgentScoreInput) (models.AgentScore, error) {
 	score := models.AgentScore{
 		AgentID:    in.AgentID,
@@ -115,14 +126,15 @@ func (m *MemoryStore) CreatePromotionEvent(ctx context.Context, in PromotionInpu
 		in.ID = uuid.New()
 	}
 	event := models.PromotionEvent{
-		ID:          in.ID,
-		AgentID:     in.AgentID,
-		Action:      in.Action,
-		Rationale:   in.Rationale,
-		Confidence:  in.Confidence,
-		Status:      in.Status,
-		RequestedBy: in.RequestedBy,
-		CreatedAt:   time.Now().UTC(),
+		ID:            in.ID,
+		AgentID:       in.AgentID,
+		Action:        in.Action,
+		Rationale:     in.Rationale,
+		Confidence:    in.Confidence,
+		Status:        in.Status,
+		RequestedBy:   in.RequestedBy,
+		KernelAuditID: in.KernelAuditID,
+		CreatedAt:     time.Now().UTC(),
 	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -130,6 +142,16 @@ func (m *MemoryStore) CreatePromotionEvent(ctx context.Context, in PromotionInpu
 	return event, nil
 }
 
+func (m *MemoryStore) GetPromotionEvent(ctx context.Context, id uuid.UUID) (models.PromotionEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	event, ok := m.promotions[id]
+	if !ok {
+		return models.PromotionEvent{}, ErrNotFound
+	}
+	return event, nil
+}
+
 func (m *MemoryStore) LinkPromotionAllocation(ctx context.Context, promotionID, allocationID uuid.UUID) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -17,10 +17,12 @@ var ErrNotFound = errors.New("not found")
 
 type Store interface {
 	InsertEvalReport(ctx context.Context, in EvalReportInput) (models.EvalReport, error)
+	GetEvalReport(ctx context.Context, id uuid.UUID) (models.EvalReport, error)
 	UpsertAgentScore(ctx context.Context, in AgentScoreInput) (models.AgentScore, error)
 	GetAgentScore(ctx context.Context, agentID string) (models.AgentScore, error)
 	ListTopAgentScores(ctx context.Context, divisionID string, limit int) ([]models.AgentScore, error)
 	CreatePromotionEvent(ctx context.Context, in PromotionInput) (models.PromotionEvent, error)
+	GetPromotionEvent(ctx context.Context, id uuid.UUID) (models.PromotionEvent, error)
 	LinkPromotionAllocation(ctx context.Context, promotionID, allocationID uuid.UUID) error
 	CreateAllocationRequest(ctx context.Context, in AllocationInput) (models.AllocationRequest, error)
 	UpdateAllocationStatus(ctx context.Context, in AllocationStatusUpdate) (models.AllocationRequest, error)
@@ -40,12 +42,13 @@ func NewPGStore(db *sql.DB) *PGStore {
 }
 
 type EvalReportInput struct {
-	ID        uuid.UUID
-	AgentID   string
-	MetricSet json.RawMessage
-	Source    string
-	Tags      json.RawMessage
-	TS        time.Time
+	ID            uuid.UUID
+	AgentID       string
+	MetricSet     json.RawMessage
+	Source        string
+	Tags          json.RawMessage
+	TS            time.Time
+	KernelAuditID string
 }
 
 type AgentScoreInput struct {
@@ -59,13 +62,14 @@ type AgentScoreInput struct {
 }
 
 type PromotionInput struct {
-	ID          uuid.UUID
-	AgentID     string
-	Action      string
-	Rationale   string
-	Confidence  float64
-	Status      string
-	RequestedBy string
+	ID            uuid.UUID
+	AgentID       string
+	Action        string
+	Rationale     string
+	Confidence    float64
+	Status        string
+	RequestedBy   string
+	KernelAuditID string
 }
 
 type AllocationInput struct {
@@ -138,25 +142,54 @@ func (s *PGStore) InsertEvalReport(ctx context.Context, in EvalReportInput) (mod
 		in.ID = uuid.New()
 	}
 	query := `
-		INSERT INTO eval_reports (id, agent_id, metric_set, source, tags, ts)
-		VALUES ($1,$2,$3,$4,$5,$6)
+		INSERT INTO eval_reports (id, agent_id, metric_set, source, tags, ts, kernel_audit_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
 		RETURNING created_at
 	`
 	var createdAt time.Time
-	if err := s.db.QueryRowContext(ctx, query, in.ID, in.AgentID, ensureJSON(in.MetricSet), in.Source, ensureJSON(in.Tags), in.TS).Scan(&createdAt); err != nil {
+	if err := s.db.QueryRowContext(ctx, query, in.ID, in.AgentID, ensureJSON(in.MetricSet), in.Source, ensureJSON(in.Tags), in.TS, nullIfEmpty(in.KernelAuditID)).Scan(&createdAt); err != nil {
 		return models.EvalReport{}, fmt.Errorf("insert eval report: %w", err)
 	}
 	return models.EvalReport{
-		ID:        in.ID,
-		AgentID:   in.AgentID,
-		MetricSet: ensureJSON(in.MetricSet),
-		Source:    in.Source,
-		Tags:      ensureJSON(in.Tags),
-		TS:        in.TS,
-		CreatedAt: createdAt,
+		ID:            in.ID,
+		AgentID:       in.AgentID,
+		MetricSet:     ensureJSON(in.MetricSet),
+		Source:        in.Source,
+		Tags:          ensureJSON(in.Tags),
+		TS:            in.TS,
+		KernelAuditID: in.KernelAuditID,
+		CreatedAt:     createdAt,
 	}, nil
 }
 
+func (s *PGStore) GetEvalReport(ctx context.Context, id uuid.UUID) (models.EvalReport, error) {
+	const query = `
+		SELECT agent_id, metric_set, source, tags, ts, kernel_audit_id, created_at
+		FROM eval_reports
+		WHERE id=$1
+	`
+	var (
+		report      models.EvalReport
+		metricSet   []byte
+		source      sql.NullString
+		tags        []byte
+		kernelAudit sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&report.AgentID, &metricSet, &source, &tags, &report.TS, &kernelAudit, &report.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.EvalReport{}, ErrNotFound
+		}
+		return models.EvalReport{}, fmt.Errorf("get eval report: %w", err)
+	}
+	report.ID = id
+	report.MetricSet = append(json.RawMessage(nil), metricSet...)
+	report.Tags = append(json.RawMessage(nil), tags...)
+	report.Source = source.String
+	report.KernelAuditID = kernelAudit.String
+	return report, nil
+}
+
 func (s *PGStore) UpsertAgentScore(ctx context.Context, in AgentScoreInput) (models.AgentScore, error) {
 	query := `
 		INSERT INTO agent_scores (agent_id, division_id, score, components, confidence, window, computed_at)
@@ -253,26 +286,59 @@ func (s *PGStore) CreatePromotionEvent(ctx context.Context, in PromotionInput) (
 		in.ID = uuid.New()
 	}
 	query := `
-		INSERT INTO promotion_events (id, agent_id, action, rationale, confidence, status, requested_by)
-		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		INSERT INTO promotion_events (id, agent_id, action, rationale, confidence, status, requested_by, kernel_audit_id)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
 		RETURNING created_at
 	`
 	var created time.Time
-	if err := s.db.QueryRowContext(ctx, query, in.ID, in.AgentID, in.Action, in.Rationale, in.Confidence, in.Status, in.RequestedBy).Scan(&created); err != nil {
+	if err := s.db.QueryRowContext(ctx, query, in.ID, in.AgentID, in.Action, in.Rationale, in.Confidence, in.Status, in.RequestedBy, nullIfEmpty(in.KernelAuditID)).Scan(&created); err != nil {
 		return models.PromotionEvent{}, fmt.Errorf("insert promotion event: %w", err)
 	}
 	return models.PromotionEvent{
-		ID:          in.ID,
-		AgentID:     in.AgentID,
-		Action:      in.Action,
-		Rationale:   in.Rationale,
-		Confidence:  in.Confidence,
-		Status:      in.Status,
-		RequestedBy: in.RequestedBy,
-		CreatedAt:   created,
+		ID:            in.ID,
+		AgentID:       in.AgentID,
+		Action:        in.Action,
+		Rationale:     in.Rationale,
+		Confidence:    in.Confidence,
+		Status:        in.Status,
+		RequestedBy:   in.RequestedBy,
+		KernelAuditID: in.KernelAuditID,
+		CreatedAt:     created,
 	}, nil
 }
 
+func (s *PGStore) GetPromotionEvent(ctx context.Context, id uuid.UUID) (models.PromotionEvent, error) {
+	const query = `
+		SELECT agent_id, action, rationale, confidence, status, requested_by, allocation_request_id, kernel_audit_id, created_at
+		FROM promotion_events
+		WHERE id=$1
+	`
+	var (
+		event       models.PromotionEvent
+		rationale   sql.NullString
+		requestedBy sql.NullString
+		allocation  sql.NullString
+		kernelAudit sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&event.AgentID, &event.Action, &rationale, &event.Confidence, &event.Status, &requestedBy, &allocation, &kernelAudit, &event.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PromotionEvent{}, ErrNotFound
+		}
+		return models.PromotionEvent{}, fmt.Errorf("get promotion event: %w", err)
+	}
+	event.ID = id
+	event.Rationale = rationale.String
+	event.RequestedBy = requestedBy.String
+	event.KernelAuditID = kernelAudit.String
+	if allocation.Valid {
+		if aID, err := uuid.Parse(allocation.String); err == nil {
+			event.AllocationRequestID = &aID
+		}
+	}
+	return event, nil
+}
+
 func (s *PGStore) LinkPromotionAllocation(ctx context.Context, promotionID, allocationID uuid.UUID) error {
 	query := `UPDATE promotion_events SET allocation_request_id=$1 WHERE id=$2`
 	res, err := s.db.ExecContext(ctx, query, allocationID, promotionID)
@@ -0,0 +1,49 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ILLUVRSE/Main/eval-engine/internal/store"
+)
+
+func TestPGStoreReadsKernelAuditID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	pg := store.NewPGStore(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	reportID := uuid.New()
+	mock.ExpectQuery("SELECT agent_id, metric_set, source, tags, ts, kernel_audit_id, created_at\\s+FROM eval_reports").
+		WithArgs(reportID).
+		WillReturnRows(sqlmock.NewRows([]string{"agent_id", "metric_set", "source", "tags", "ts", "kernel_audit_id", "created_at"}).
+			AddRow("agent-1", []byte(`{"accuracy":0.9}`), nil, []byte(`{}`), now, "audit-42", now))
+	report, err := pg.GetEvalReport(ctx, reportID)
+	require.NoError(t, err)
+	assert.Equal(t, "audit-42", report.KernelAuditID)
+	assert.JSONEq(t, `{"accuracy":0.9}`, string(report.MetricSet))
+
+	promotionID, allocationID := uuid.New(), uuid.New()
+	mock.ExpectQuery("SELECT agent_id, action, rationale, confidence, status, requested_by, allocation_request_id, kernel_audit_id, created_at\\s+FROM promotion_events").
+		WithArgs(promotionID).
+		WillReturnRows(sqlmock.NewRows([]string{"agent_id", "action", "rationale", "confidence", "status", "requested_by", "allocation_request_id", "kernel_audit_id", "created_at"}).
+			AddRow("agent-1", "promote", "score above threshold", 0.9, "pending", nil, allocationID.String(), "audit-42", now))
+	promotion, err := pg.GetPromotionEvent(ctx, promotionID)
+	require.NoError(t, err)
+	assert.Equal(t, "audit-42", promotion.KernelAuditID)
+	require.NotNil(t, promotion.AllocationRequestID)
+	assert.Equal(t, allocationID, *promotion.AllocationRequestID)
+
+	mock.ExpectQuery("FROM promotion_events").WillReturnRows(sqlmock.NewRows([]string{"agent_id"}))
+	_, err = pg.GetPromotionEvent(ctx, uuid.New())
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
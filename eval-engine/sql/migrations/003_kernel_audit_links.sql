@@ -0,0 +1,15 @@
+-- eval-engine/sql/migrations/003_kernel_audit_links.sql
+-- Links eval reports and the promotions they trigger to the Kernel audit event that
+-- recorded the submission (POST /kernel/eval forwards kernelAuditId).
+
+BEGIN;
+
+ALTER TABLE eval_reports
+    ADD COLUMN IF NOT EXISTS kernel_audit_id TEXT;
+
+ALTER TABLE promotion_events
+    ADD COLUMN IF NOT EXISTS kernel_audit_id TEXT;
+
+CREATE INDEX IF NOT EXISTS eval_reports_kernel_audit_idx ON eval_reports(kernel_audit_id);
+
+COMMIT;
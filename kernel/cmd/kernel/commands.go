@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/config"
+	"github.com/ILLUVRSE/Main/kernel/internal/keys"
+)
+
+// runCommand dispatches CLI subcommands (e.g. "kernel audit restore") and returns the
+// process exit code. main only starts the server when no subcommand is given.
+func runCommand(args []string) int {
+	switch {
+	case len(args) >= 2 && args[0] == "audit" && args[1] == "restore":
+		return runAuditRestore(args[2:])
+	case len(args) >= 2 && args[0] == "config" && args[1] == "print":
+		return runConfigPrint(args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\nusage:\n  kernel                 start the server\n  kernel audit restore   rebuild audit_events from the archive\n  kernel config print    show the effective configuration (secrets redacted)\n", strings.Join(args, " "))
+		return 2
+	}
+}
+
+// runConfigPrint implements "kernel config print": it resolves the configuration the
+// server would start with, prints it with secrets redacted, and exits 1 if it would be
+// rejected at startup.
+func runConfigPrint(args []string) int {
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	path := fs.String("config", "", "config file (default $"+config.EnvConfigFile+")")
+	format := fs.String("format", "yaml", "output format: yaml or json")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	cfg, err := config.Resolve(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config print: %v\n", err)
+		return 1
+	}
+
+	var out []byte
+	switch *format {
+	case "yaml":
+		out, err = yaml.Marshal(cfg.Redacted())
+	case "json":
+		out, err = json.MarshalIndent(cfg.Redacted(), "", "  ")
+		out = append(out, '\n')
+	default:
+		fmt.Fprintf(os.Stderr, "config print: unknown format %q\n", *format)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config print: %v\n", err)
+		return 1
+	}
+	os.Stdout.Write(out)
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "config print: configuration is invalid:\n%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runAuditRestore implements "kernel audit restore".
+//
+// Exit codes: 0 restored/verified cleanly, 1 usage or runtime error, 3 the archive has a
+// broken link (the verified prefix is restored unless -force is given).
+func runAuditRestore(args []string) int {
+	cfg, err := config.Resolve("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit restore: %v\n", err)
+		return 1
+	}
+
+	fs := flag.NewFlagSet("audit restore", flag.ContinueOnError)
+	source := fs.String("source", "s3", "archive source: s3, dir or kafka")
+	dir := fs.String("dir", "", "local archive directory (source=dir)")
+	bucket := fs.String("bucket", cfg.Streamer.S3Bucket, "S3 bucket (source=s3)")
+	prefix := fs.String("prefix", cfg.Streamer.S3Prefix, "S3 key prefix (source=s3)")
+	brokers := fs.String("brokers", strings.Join(cfg.Streamer.KafkaBrokers, ","), "comma-separated Kafka brokers (source=kafka)")
+	topic := fs.String("topic", cfg.Streamer.KafkaTopic, "Kafka topic (source=kafka)")
+	keysRef := fs.String("keys", "", "signer public keys: path or URL of a /kernel/security/status JSON document")
+	force := fs.Bool("force", false, "restore past the first broken link")
+	dryRun := fs.Bool("dry-run", false, "verify only; do not write to Postgres")
+	timeout := fs.Duration("timeout", 30*time.Minute, "overall timeout")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if *keysRef == "" {
+		fmt.Fprintln(os.Stderr, "audit restore: -keys is required to verify signatures")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	reg, err := loadKeyRegistry(ctx, *keysRef)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit restore: load keys: %v\n", err)
+		return 1
+	}
+
+	var src audit.ArchiveSource
+	switch *source {
+	case "dir":
+		if *dir == "" {
+			fmt.Fprintln(os.Stderr, "audit restore: -dir required for source=dir")
+			return 1
+		}
+		src = &audit.DirArchiveSource{Dir: *dir}
+	case "s3":
+		s3src, err := audit.NewS3ArchiveSource(ctx, *bucket, *prefix)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "audit restore: %v\n", err)
+			return 1
+		}
+		src = s3src
+	case "kafka":
+		list := make([]string, 0)
+		for _, b := range strings.Split(*brokers, ",") {
+			if b = strings.TrimSpace(b); b != "" {
+				list = append(list, b)
+			}
+		}
+		src = &audit.KafkaArchiveSource{Brokers: list, Topic: *topic}
+	default:
+		fmt.Fprintf(os.Stderr, "audit restore: unknown source %q\n", *source)
+		return 1
+	}
+
+	var db *sql.DB
+	if !*dryRun {
+		if cfg.Store.DatabaseURL == "" {
+			fmt.Fprintln(os.Stderr, "audit restore: DATABASE_URL required (or use -dry-run)")
+			return 1
+		}
+		db, err = sql.Open("postgres", cfg.Store.DatabaseURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "audit restore: open postgres: %v\n", err)
+			return 1
+		}
+		defer db.Close()
+	}
+
+	archived, err := src.ReadEvents(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit restore: read archive: %v\n", err)
+		return 1
+	}
+
+	report, err := audit.Restore(ctx, db, reg, archived, audit.RestoreOptions{Force: *force, DryRun: *dryRun})
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit restore: %v\n", err)
+		return 1
+	}
+	if report.Broken != nil {
+		fmt.Fprintf(os.Stderr, "audit restore: chain broken at event %s (%s): %s\n", report.Broken.EventID, report.Broken.Kind, report.Broken.Detail)
+		return 3
+	}
+	return 0
+}
+
+// loadKeyRegistry builds a key registry from a file path or URL containing either
+// { "signers": [KeyInfo...] } (the /kernel/security/status shape) or a bare [KeyInfo...].
+func loadKeyRegistry(ctx context.Context, ref string) (*keys.Registry, error) {
+	var b []byte
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET %s: HTTP %d", ref, resp.StatusCode)
+		}
+		if b, err = io.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if b, err = os.ReadFile(ref); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc struct {
+		Signers []keys.KeyInfo `json:"signers"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil || doc.Signers == nil {
+		if err := json.Unmarshal(b, &doc.Signers); err != nil {
+			return nil, fmt.Errorf("parse keys document: %w", err)
+		}
+	}
+	if len(doc.Signers) == 0 {
+		return nil, errors.New("no signers in keys document")
+	}
+
+	reg := keys.NewRegistry()
+	for _, ki := range doc.Signers {
+		pub, err := base64.StdEncoding.DecodeString(ki.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("signer %s: invalid public key: %w", ki.SignerId, err)
+		}
+		reg.AddSigner(ki.SignerId, pub, ki.Algorithm)
+	}
+	return reg, nil
+}
@@ -7,7 +7,6 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -15,13 +14,19 @@ import (
 	"github.com/go-chi/chi/v5"
 	_ "github.com/lib/pq"
 
+	"github.com/ILLUVRSE/Main/kernel/internal/allocation"
 	"github.com/ILLUVRSE/Main/kernel/internal/audit"
 	"github.com/ILLUVRSE/Main/kernel/internal/auth"
 	"github.com/ILLUVRSE/Main/kernel/internal/config"
 	"github.com/ILLUVRSE/Main/kernel/internal/handlers"
 	"github.com/ILLUVRSE/Main/kernel/internal/keys"
+	"github.com/ILLUVRSE/Main/kernel/internal/ratelimit"
 	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/kernel/internal/signjobs"
+	"github.com/ILLUVRSE/Main/kernel/internal/telemetry"
 	tlsutil "github.com/ILLUVRSE/Main/kernel/internal/tls"
+	"github.com/ILLUVRSE/Main/shared/evalclient"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 // AppContext holds shared dependencies passed to handlers.
@@ -30,6 +35,14 @@ type AppContext struct {
 	DB     *sql.DB
 	Signer signer.Signer
 	Store  audit.Store
+	// SignJobs backs the asynchronous /kernel/sign/jobs routes.
+	SignJobs signjobs.Store
+	// Allocations records /kernel/allocate requests and drives them through the Resource Allocator.
+	Allocations *allocation.Service
+	// EvalIngestion, when set, receives the reports posted to /kernel/eval.
+	EvalIngestion *evalclient.Ingestion
+	// AuditFeed serves /kernel/audit/stream.
+	AuditFeed *audit.Hub
 	// Registry is intentionally not required by handlers but available for other subsystems.
 	Registry *keys.Registry
 }
@@ -37,14 +50,29 @@ type AppContext struct {
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
-	// Load configuration
-	cfg := config.LoadFromEnv()
+	// Subcommands (e.g. "kernel audit restore") run and exit without starting the server.
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	// Load configuration: KERNEL_CONFIG file (optional) overlaid by environment variables.
+	cfg, err := config.LoadFromEnv()
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	// Tracing: TRACING_EXPORTER=json|otlp writes spans to TRACING_FILE.
+	tracer, err := tracing.FromEnv("kernel")
+	if err != nil {
+		log.Fatalf("failed to initialize tracing: %v", err)
+	}
+	tracing.SetDefault(tracer)
 
 	// Database (optional)
 	var db *sql.DB
-	if cfg.DatabaseURL != "" {
+	if cfg.Store.DatabaseURL != "" {
 		var err error
-		db, err = sql.Open("postgres", cfg.DatabaseURL)
+		db, err = sql.Open("postgres", cfg.Store.DatabaseURL)
 		if err != nil {
 			log.Fatalf("failed to open postgres: %v", err)
 		}
@@ -58,38 +86,66 @@ func main() {
 
 	// Signer: prefer KMS in prod; fallback to local signer for dev/testing
 	var signClient signer.Signer
-	if cfg.RequireKMS {
-		// In production we require a configured KMS endpoint.
-		if cfg.KMSEndpoint == "" {
-			log.Fatalf("REQUIRE_KMS=true but KMS_ENDPOINT not configured")
-		}
-		ks, err := signer.NewKMSSigner(cfg.KMSEndpoint, cfg.RequireKMS)
+	kmsCfg := signer.KMSSignerConfig{
+		Endpoint:     cfg.Signer.KMSEndpoint,
+		SignerID:     cfg.Signer.SignerID,
+		BearerToken:  cfg.Signer.BearerToken,
+		Timeout:      time.Duration(cfg.Signer.TimeoutMS) * time.Millisecond,
+		MTLSCertPath: cfg.Signer.MTLSCertPath,
+		MTLSKeyPath:  cfg.Signer.MTLSKeyPath,
+		MTLSCAPath:   cfg.Signer.MTLSCAPath,
+		RequireKMS:   cfg.Signer.RequireKMS,
+	}
+	if cfg.Signer.RequireKMS {
+		// Validate guarantees a KMS endpoint when KMS is required.
+		ks, err := signer.NewKMSSignerFromConfig(kmsCfg)
 		if err != nil {
 			log.Fatalf("failed to initialize KMS signer: %v", err)
 		}
 		signClient = ks
 	} else {
 		// Try to use KMS if an endpoint is set; otherwise fall back to local signer for dev.
-		if cfg.KMSEndpoint != "" {
-			ks, err := signer.NewKMSSigner(cfg.KMSEndpoint, cfg.RequireKMS)
+		if cfg.Signer.KMSEndpoint != "" {
+			ks, err := signer.NewKMSSignerFromConfig(kmsCfg)
 			if err == nil && ks != nil {
 				signClient = ks
-				log.Printf("KMS signer configured (endpoint=%s)", cfg.KMSEndpoint)
+				log.Printf("KMS signer configured (endpoint=%s)", cfg.Signer.KMSEndpoint)
 			} else {
 				log.Printf("KMS signer not available: %v — falling back to local signer (dev only)", err)
-				signClient = signer.NewLocalSigner(cfg.LocalSignerID)
+				signClient = signer.NewLocalSigner(cfg.Signer.LocalSignerID)
 			}
 		} else {
-			signClient = signer.NewLocalSigner(cfg.LocalSignerID)
+			signClient = signer.NewLocalSigner(cfg.Signer.LocalSignerID)
 		}
 	}
 
+	// Signing latency/errors are recorded per backend for /metrics.
+	signClient = telemetry.InstrumentSigner(signClient, telemetry.SignerBackend(signClient))
+
 	// Store: Postgres-backed store when DB present, otherwise local file store for dev
 	var store audit.Store
 	if db != nil {
 		store = audit.NewPGStore(db)
 	} else {
-		store = audit.NewFileStore("./archive")
+		store = audit.NewFileStore(cfg.Store.ArchiveDir)
+	}
+
+	// Audit retention policy (PG only): per-eventType retention and sampling evaluated at append time.
+	var retentionPolicy *audit.RetentionPolicy
+	if pgStore, ok := store.(*audit.PGStore); ok {
+		defaultRetention := time.Duration(cfg.Store.RetentionDays) * 24 * time.Hour
+		rules, err := audit.ParseRetentionRules(cfg.Store.RetentionRules)
+		if err != nil {
+			log.Fatalf("invalid AUDIT_RETENTION_RULES: %v", err)
+		}
+		if defaultRetention > 0 || len(rules) > 0 {
+			retentionPolicy = &audit.RetentionPolicy{
+				Default: audit.RetentionRule{Retention: defaultRetention},
+				Rules:   rules,
+			}
+			pgStore.SetRetentionPolicy(retentionPolicy)
+			log.Printf("audit retention policy configured (default=%s rules=%d)", defaultRetention, len(rules))
+		}
 	}
 
 	// Key registry - register the signer public key so auditors can discover it
@@ -103,34 +159,115 @@ func main() {
 		}
 	}
 
+	// Asynchronous signing jobs: Postgres-backed when DB present so replicas share the queue.
+	var signJobs signjobs.Store = signjobs.NewMemoryStore()
+	if db != nil {
+		signJobs = signjobs.NewPGStore(db)
+	}
+
+	instrumented := telemetry.InstrumentStore(store)
+
+	// Eval reports and allocations are forwarded to eval-engine when its services are configured.
+	ee := cfg.EvalEngine
+	evalCfg := func(baseURL string) evalclient.Config {
+		return evalclient.Config{
+			BaseURL:     baseURL,
+			BearerToken: ee.Token,
+			Service:     "kernel",
+			Timeout:     time.Duration(ee.TimeoutMS) * time.Millisecond,
+		}
+	}
+	var evalIngestion *evalclient.Ingestion
+	if ee.IngestionURL != "" {
+		if evalIngestion, err = evalclient.NewIngestion(evalCfg(ee.IngestionURL)); err != nil {
+			log.Fatalf("eval ingestion client: %v", err)
+		}
+	}
+	var allocator *evalclient.Allocator
+	if ee.AllocatorURL != "" {
+		if allocator, err = evalclient.NewAllocator(evalCfg(ee.AllocatorURL)); err != nil {
+			log.Fatalf("resource allocator client: %v", err)
+		}
+	}
+	allocations := allocation.NewService(allocation.StoreFor(db), allocator, instrumented, signClient, allocation.Config{
+		ManualApproval:    cfg.EvalEngine.ManualAllocationApproval,
+		ReconcileInterval: time.Duration(cfg.EvalEngine.ReconcileIntervalSeconds) * time.Second,
+	})
+	ctxAllocations, allocationsCancel := context.WithCancel(context.Background())
+	go func() {
+		if err := allocations.Run(ctxAllocations); err != nil && err != context.Canceled {
+			log.Printf("[allocation] reconciler exited with error: %v", err)
+		}
+	}()
+
+	// Live audit feed: subscribers are woken by Postgres NOTIFY (any replica) or, for the
+	// file store, by in-process appends; the hub's poll covers missed notifications.
+	af := cfg.AuditFeed
+	auditFeed := audit.NewHub(store.(audit.Feed), audit.HubConfig{
+		MaxSubscribers: af.MaxSubscribers,
+		PollInterval:   time.Duration(af.PollIntervalMS) * time.Millisecond,
+		BatchSize:      af.BatchSize,
+	})
+	ctxFeed, feedCancel := context.WithCancel(context.Background())
+	switch st := store.(type) {
+	case *audit.PGStore:
+		go func() {
+			if err := audit.ListenPG(ctxFeed, cfg.Store.DatabaseURL, auditFeed.Notify); err != nil && err != context.Canceled {
+				log.Printf("[audit.feed] listener exited with error: %v", err)
+			}
+		}()
+	case *audit.FileStore:
+		st.SetOnAppend(func(*audit.AuditEvent) { auditFeed.Notify() })
+	}
+	go func() {
+		if err := auditFeed.Run(ctxFeed); err != nil && err != context.Canceled {
+			log.Printf("[audit.feed] exited with error: %v", err)
+		}
+	}()
+
 	app := &AppContext{
-		Config:   cfg,
-		DB:       db,
-		Signer:   signClient,
-		Store:    store,
-		Registry: reg,
+		Config:        cfg,
+		DB:            db,
+		Signer:        signClient,
+		Store:         instrumented,
+		SignJobs:      signJobs,
+		Allocations:   allocations,
+		EvalIngestion: evalIngestion,
+		AuditFeed:     auditFeed,
+		Registry:      reg,
 	}
 
+	sj := cfg.SignJobs
+	signJobWorker := signjobs.NewWorker(signJobs, signClient, app.Store, signjobs.WorkerConfig{
+		Concurrency:         sj.Workers,
+		PollInterval:        time.Duration(sj.PollIntervalMS) * time.Millisecond,
+		Lease:               time.Duration(sj.LeaseSeconds) * time.Second,
+		CallbackSecret:      []byte(sj.CallbackSecret),
+		CallbackHosts:       sj.CallbackHosts,
+		CallbackTimeout:     time.Duration(sj.CallbackTimeoutSeconds) * time.Second,
+		CallbackMaxAttempts: sj.CallbackMaxAttempts,
+		CallbackBackoff:     time.Duration(sj.CallbackBackoffSeconds) * time.Second,
+	})
+	ctxSignJobs, signJobsCancel := context.WithCancel(context.Background())
+	go func() {
+		if err := signJobWorker.Run(ctxSignJobs); err != nil && err != context.Canceled {
+			log.Printf("[signjobs] worker exited with error: %v", err)
+		}
+	}()
+
 	// --- Audit streamer wiring (DB-first durable pipeline) ---
 	var (
 		streamerCancel context.CancelFunc
+		prunerCancel   context.CancelFunc
 	)
 	// Only start streamer when we have Postgres (durable DB) and required infra configured.
 	if db != nil {
-		kafkaBrokersEnv := strings.TrimSpace(os.Getenv("KAFKA_BROKERS"))
-		kafkaTopic := strings.TrimSpace(os.Getenv("KAFKA_TOPIC"))
-		s3Bucket := strings.TrimSpace(os.Getenv("S3_BUCKET"))
-		s3Prefix := strings.TrimSpace(os.Getenv("S3_PREFIX")) // optional
-
-		if kafkaBrokersEnv != "" && kafkaTopic != "" && s3Bucket != "" {
-			rawBrokers := strings.Split(kafkaBrokersEnv, ",")
-			brokers := make([]string, 0, len(rawBrokers))
-			for _, b := range rawBrokers {
-				b = strings.TrimSpace(b)
-				if b != "" {
-					brokers = append(brokers, b)
-				}
-			}
+		sc := cfg.Streamer
+		if sc.Enabled() {
+			brokers := sc.KafkaBrokers
+			kafkaTopic := sc.KafkaTopic
+			s3Bucket := sc.S3Bucket
+			s3Prefix := sc.S3Prefix
 
 			kafkaCfg := audit.KafkaProducerConfig{
 				Brokers:     brokers,
@@ -149,24 +286,9 @@ func main() {
 			}
 			log.Printf("s3 archiver initialized (bucket=%s prefix=%s)", s3Bucket, s3Prefix)
 
-			batchSize := 10
-			if v := strings.TrimSpace(os.Getenv("STREAM_BATCH_SIZE")); v != "" {
-				if n, err := strconv.Atoi(v); err == nil && n > 0 {
-					batchSize = n
-				}
-			}
-			maxConcurrency := 5
-			if v := strings.TrimSpace(os.Getenv("STREAM_MAX_CONCURRENCY")); v != "" {
-				if n, err := strconv.Atoi(v); err == nil && n > 0 {
-					maxConcurrency = n
-				}
-			}
-			pollInterval := 3 * time.Second
-			if v := strings.TrimSpace(os.Getenv("STREAM_POLL_INTERVAL_SECONDS")); v != "" {
-				if n, err := strconv.Atoi(v); err == nil && n > 0 {
-					pollInterval = time.Duration(n) * time.Second
-				}
-			}
+			batchSize := sc.BatchSize
+			maxConcurrency := sc.MaxConcurrency
+			pollInterval := time.Duration(sc.PollIntervalSeconds) * time.Second
 
 			pgStore, ok := store.(*audit.PGStore)
 			if !ok {
@@ -178,6 +300,7 @@ func main() {
 					MaxConcurrency: maxConcurrency,
 				}
 				streamer := audit.NewStreamer(pgStore, producer, archiver, streamerCfg)
+				streamer.SetObserver(telemetry.StreamObserver)
 
 				ctxStr, cancel := context.WithCancel(context.Background())
 				streamerCancel = cancel
@@ -196,46 +319,118 @@ func main() {
 		log.Println("no postgres configured; audit streamer disabled (requires durable DB)")
 	}
 
+	// --- Audit retention enforcement (prunes archived, expired rows behind signed checkpoints) ---
+	if pgStore, ok := store.(*audit.PGStore); ok && retentionPolicy != nil {
+		pruneInterval := time.Duration(cfg.Store.PruneIntervalSeconds) * time.Second
+		pruner := audit.NewPruner(pgStore, signClient, audit.PrunerConfig{Interval: pruneInterval})
+		ctxPrune, cancel := context.WithCancel(context.Background())
+		prunerCancel = cancel
+		go func() {
+			if err := pruner.Run(ctxPrune); err != nil && err != context.Canceled {
+				log.Printf("[audit.pruner] exited with error: %v", err)
+			}
+		}()
+		log.Printf("audit retention pruner started (interval=%s)", pruneInterval)
+	}
+
 	// Router and middleware
 	r := chi.NewRouter()
 
+	// Tracing and Prometheus instrumentation run first so auth rejections are recorded too.
+	r.Use(tracing.Middleware)
+	r.Use(telemetry.NewHTTPMetrics().Middleware)
+
 	// Auth middleware (mTLS / OIDC extraction)
 	r.Use(auth.NewMiddleware(cfg))
 
 	// --- OIDC / JWKS wiring (from cfg) ---
-	jwksURL := strings.TrimSpace(cfg.JWKSURL)
-	jwksTTLSeconds := cfg.JWKSCacheTTLSeconds
-	oidcIssuer := strings.TrimSpace(cfg.OIDCIssuer)
-	oidcAudience := strings.TrimSpace(cfg.OIDCAudience)
+	jwksURL := cfg.OIDC.JWKSURL
+	jwksTTLSeconds := cfg.OIDC.JWKSCacheTTLSeconds
+	oidcIssuer := cfg.OIDC.Issuer
+	oidcAudience := cfg.OIDC.Audience
 
-	// prepare jwks variable and metrics stop func so they are visible below
+	// prepare jwks variable and stop funcs so they are visible below
 	var jwks *auth.JWKSCache
-	var jwksMetricsStop func()
-
-	if jwksURL != "" {
-		// Small health probe so we log meaningful errors early (non-fatal).
-		client := &http.Client{Timeout: 2 * time.Second}
-		if resp, err := client.Get(jwksURL); err != nil {
-			log.Printf("warning: JWKS URL %s not reachable right now: %v (middleware will still be installed)", jwksURL, err)
-		} else {
-			_ = resp.Body.Close()
-			if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-				log.Printf("warning: JWKS URL %s returned HTTP %d (middleware will still be installed)", jwksURL, resp.StatusCode)
+	var jwksMetricsStop, jwksRefresherStop func()
+	jwksFile := cfg.OIDC.JWKSFile
+
+	if jwksURL != "" || jwksFile != "" {
+		if jwksURL != "" {
+			// Small health probe so we log meaningful errors early (non-fatal).
+			client := &http.Client{Timeout: 2 * time.Second}
+			if resp, err := client.Get(jwksURL); err != nil {
+				log.Printf("warning: JWKS URL %s not reachable right now: %v (middleware will still be installed)", jwksURL, err)
+			} else {
+				_ = resp.Body.Close()
+				if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+					log.Printf("warning: JWKS URL %s returned HTTP %d (middleware will still be installed)", jwksURL, resp.StatusCode)
+				}
 			}
 		}
 
-		jwks = auth.NewJWKSCache(jwksURL, time.Duration(jwksTTLSeconds)*time.Second)
+		var err error
+		jwks, err = auth.NewJWKSCacheFromConfig(auth.JWKSConfig{
+			URL:                jwksURL,
+			File:               jwksFile,
+			TTL:                time.Duration(jwksTTLSeconds) * time.Second,
+			MinRefreshInterval: time.Duration(cfg.OIDC.JWKSMinRefreshSeconds) * time.Second,
+			MaxStaleness:       time.Duration(cfg.OIDC.JWKSMaxStalenessSeconds) * time.Second,
+		})
+		if err != nil {
+			log.Fatalf("jwks init: %v", err)
+		}
+		jwksRefresherStop = jwks.StartRefresher()
 
 		// Start JWKS metrics updater
 		jwksMetricsStop = auth.StartJWKSMetricsUpdater(jwks, 15*time.Second)
 		log.Printf("JWKS metrics updater started (interval=15s)")
 
 		r.Use(auth.OIDCMiddleware(jwks, oidcIssuer, oidcAudience))
-		log.Printf("OIDC middleware configured (jwks=%s issuer=%s audience=%s ttl=%ds)", jwksURL, oidcIssuer, oidcAudience, jwksTTLSeconds)
+		log.Printf("OIDC middleware configured (jwks=%s file=%s issuer=%s audience=%s ttl=%ds)", jwksURL, jwksFile, oidcIssuer, oidcAudience, jwksTTLSeconds)
 	} else {
-		log.Println("OIDC JWKS_URL not configured in cfg; skipping OIDC middleware (roles will not be validated)")
+		log.Println("OIDC JWKS_URL/JWKS_FILE not configured in cfg; skipping OIDC middleware (roles will not be validated)")
+	}
+
+	// Per-principal rate limits; after auth/OIDC so the caller's principal is known.
+	if cfg.RateLimit.Enabled {
+		var limiter ratelimit.Limiter = ratelimit.NewMemoryLimiter()
+		if cfg.RateLimit.Backend == config.RateLimitPostgres {
+			limiter = ratelimit.NewPGLimiter(db)
+		}
+		rules := make([]ratelimit.Rule, 0, len(cfg.RateLimit.Routes))
+		for _, rt := range cfg.RateLimit.Routes {
+			method, path, _ := strings.Cut(rt.Route, " ")
+			rules = append(rules, ratelimit.Rule{
+				Method: method,
+				Path:   path,
+				Limit:  ratelimit.Limit{Rate: rt.RatePerSecond, Burst: rt.Burst},
+				KeyBy:  rt.KeyBy,
+			})
+		}
+		rl := ratelimit.NewMiddleware(limiter, rules)
+		rl.SetBreachRecorder(func(ctx context.Context, b ratelimit.Breach) {
+			ev := &audit.AuditEvent{
+				EventType: "ratelimit.exceeded",
+				Payload: map[string]interface{}{
+					"route":             b.Route,
+					"principal":         b.Principal,
+					"ratePerSecond":     b.Limit.Rate,
+					"burst":             b.Limit.Burst,
+					"retryAfterSeconds": b.RetryAfter.Seconds(),
+				},
+				Ts: time.Now().UTC(),
+			}
+			if err := app.Store.AppendAuditEvent(ctx, ev, signClient); err != nil {
+				log.Printf("[ratelimit] audit breach for %s %s: %v", b.Route, b.Principal, err)
+			}
+		})
+		r.Use(rl.Handler)
+		log.Printf("rate limiting enabled (backend=%s routes=%d)", cfg.RateLimit.Backend, len(rules))
 	}
 
+	// Prometheus scrape endpoint (request, signing, audit, streamer and JWKS metrics)
+	r.Handle("/metrics", telemetry.Registry.Handler())
+
 	// Mount the security/status endpoint for key registry and jwks metrics
 	r.Get("/kernel/security/status", reg.StatusHandler())
 	r.Get("/kernel/security/jwks_metrics", handlers.JWKSStatusHandler(jwks))
@@ -251,14 +446,17 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Close audit feed subscriptions as soon as shutdown starts so open streams do not
+	// hold up draining.
+	srv.RegisterOnShutdown(feedCancel)
 
 	// --- TLS / mTLS setup using cfg paths ---
-	certPath := strings.TrimSpace(cfg.TLSCertPath)
-	keyPath := strings.TrimSpace(cfg.TLSKeyPath)
-	clientCAPath := strings.TrimSpace(cfg.TLSClientCAPath)
+	certPath := cfg.TLS.CertPath
+	keyPath := cfg.TLS.KeyPath
+	clientCAPath := cfg.TLS.ClientCAPath
 
 	if certPath != "" && keyPath != "" {
-		tlsCfg, err := tlsutil.NewTLSConfigFromFiles(certPath, keyPath, clientCAPath, cfg.RequireMTLS)
+		tlsCfg, err := tlsutil.NewTLSConfigFromFiles(certPath, keyPath, clientCAPath, cfg.TLS.RequireMTLS)
 		if err != nil {
 			log.Fatalf("failed to initialize TLS config: %v", err)
 		}
@@ -294,6 +492,13 @@ func main() {
 		log.Fatalf("shutdown error: %v", err)
 	}
 
+	if prunerCancel != nil {
+		prunerCancel()
+	}
+	// Jobs interrupted mid-signature are reclaimed by another replica once their lease expires.
+	signJobsCancel()
+	allocationsCancel()
+
 	// Cancel streamer if started and give it a short grace period to finish.
 	if streamerCancel != nil {
 		streamerCancel()
@@ -302,12 +507,19 @@ func main() {
 		<-shutdownWait.C
 	}
 
-	// Stop JWKS metrics updater if started
+	// Stop JWKS refresher and metrics updater if started
+	if jwksRefresherStop != nil {
+		jwksRefresherStop()
+	}
 	if jwksMetricsStop != nil {
 		jwksMetricsStop()
 		log.Println("JWKS metrics updater stopped")
 	}
 
+	if err := tracer.Close(); err != nil {
+		log.Printf("trace exporter close: %v", err)
+	}
+
 	if db != nil {
 		_ = db.Close()
 	}
@@ -0,0 +1,222 @@
+// Package allocation keeps the Kernel's record of resource allocation requests
+// (POST /kernel/allocate) and drives them through the eval-engine Resource Allocator.
+//
+// The Kernel record is created pending, forwarded to /alloc/request and, unless manual
+// approval is configured, approved through /alloc/approve, where SentinelNet decides
+// between applied and rejected. Every status change, whether seen in a response or
+// picked up later by the Reconciler polling GET /alloc/{id}, is written back to the
+// record and appended to the audit log as allocation.<status>. A request the allocator
+// cannot be reached for stays pending and the Reconciler files it later. The Kernel
+// approves as Approver, never as the requester.
+package allocation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Allocation statuses. pending, applied and rejected mirror the allocator; failed means
+// the allocator refused to file the request.
+const (
+	StatusPending  = "pending"
+	StatusApplied  = "applied"
+	StatusRejected = "rejected"
+	StatusFailed   = "failed"
+)
+
+var (
+	// ErrNotFound is returned when no allocation has the requested id.
+	ErrNotFound = errors.New("allocation not found")
+	// ErrExists is returned when creating a record whose id is already taken.
+	ErrExists = errors.New("allocation already exists")
+	// ErrInvalidID is returned for ids that are empty or contain a path separator.
+	ErrInvalidID = errors.New("invalid allocation id")
+)
+
+// ValidID reports whether id may name a record. Ids are client-chosen and FileStore
+// joins them into a file path, so path separators are refused.
+func ValidID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`)
+}
+
+// Record is the Kernel's view of one allocation request.
+type Record struct {
+	Id                 string          `json:"id,omitempty"`
+	DivisionId         string          `json:"divisionId"`
+	EntityId           string          `json:"entityId,omitempty"`
+	PromotionId        string          `json:"promotionId,omitempty"`
+	CPU                int             `json:"cpu,omitempty"`
+	GPU                int             `json:"gpu,omitempty"`
+	MemoryMB           int             `json:"memoryMB,omitempty"`
+	Pool               string          `json:"pool,omitempty"`
+	Delta              int             `json:"delta,omitempty"`
+	Requester          string          `json:"requester,omitempty"`
+	Status             string          `json:"status,omitempty"` // pending|applied|rejected|failed
+	Reason             string          `json:"reason,omitempty"`
+	AllocatorRequestId string          `json:"allocatorRequestId,omitempty"`
+	SentinelDecision   json.RawMessage `json:"sentinelDecision,omitempty"`
+	AppliedBy          string          `json:"appliedBy,omitempty"`
+	AppliedAt          *time.Time      `json:"appliedAt,omitempty"`
+	Error              string          `json:"error,omitempty"`
+	CreatedAt          time.Time       `json:"createdAt,omitempty"`
+	UpdatedAt          time.Time       `json:"updatedAt,omitempty"`
+}
+
+// Terminal reports whether the record can no longer change.
+func (r *Record) Terminal() bool {
+	return r.Status == StatusApplied || r.Status == StatusRejected || r.Status == StatusFailed
+}
+
+// AgentId is the id the allocator books the resources against: the entity when one
+// is named, otherwise the division.
+func (r *Record) AgentId() string {
+	if r.EntityId != "" {
+		return r.EntityId
+	}
+	return r.DivisionId
+}
+
+// Store persists allocation records.
+type Store interface {
+	// Create inserts a new record, returning ErrExists if its id is taken.
+	Create(ctx context.Context, r *Record) error
+	// Transition replaces the stored record with r only if its stored status is still
+	// from, and reports whether it did. Concurrent reconciliations use it so exactly
+	// one of them records a status change.
+	Transition(ctx context.Context, r *Record, from string) (bool, error)
+	// Get returns the record or ErrNotFound.
+	Get(ctx context.Context, id string) (*Record, error)
+	// ListPending returns up to limit pending records that have reached the allocator
+	// or still have to be filed with it (those with a pool), oldest first.
+	ListPending(ctx context.Context, limit int) ([]*Record, error)
+}
+
+// FileStore keeps one JSON file per allocation under a directory. It is the
+// development backend used when no database is configured.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore returns a FileStore writing under dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+func (f *FileStore) path(id string) string {
+	return filepath.Join(f.dir, fmt.Sprintf("%s.json", id))
+}
+
+func (f *FileStore) Create(ctx context.Context, r *Record) error {
+	if !ValidID(r.Id) {
+		return ErrInvalidID
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := os.Stat(f.path(r.Id)); err == nil {
+		return ErrExists
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return f.write(r)
+}
+
+func (f *FileStore) Transition(ctx context.Context, r *Record, from string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cur, err := f.read(r.Id)
+	if err != nil {
+		return false, err
+	}
+	if cur.Status != from {
+		return false, nil
+	}
+	return true, f.write(r)
+}
+
+func (f *FileStore) Get(ctx context.Context, id string) (*Record, error) {
+	if !ValidID(id) {
+		return nil, ErrNotFound
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.read(id)
+}
+
+// write and read must be called with f.mu held.
+func (f *FileStore) write(r *Record) error {
+	if !ValidID(r.Id) {
+		return ErrInvalidID
+	}
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path(r.Id), b, 0o644)
+}
+
+func (f *FileStore) read(id string) (*Record, error) {
+	b, err := os.ReadFile(f.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Record
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("decode allocation %s: %w", id, err)
+	}
+	return &r, nil
+}
+
+func (f *FileStore) ListPending(ctx context.Context, limit int) ([]*Record, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	paths, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []*Record
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var r Record
+		if json.Unmarshal(b, &r) != nil {
+			continue
+		}
+		if r.Status == StatusPending && (r.AllocatorRequestId != "" || r.Pool != "") {
+			out = append(out, &r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// DefaultDir is where FileStore keeps records when no database is configured.
+const DefaultDir = "./data/allocations"
+
+// StoreFor returns a PGStore on db, or a FileStore under DefaultDir when db is nil.
+func StoreFor(db *sql.DB) Store {
+	if db != nil {
+		return NewPGStore(db)
+	}
+	return NewFileStore(DefaultDir)
+}
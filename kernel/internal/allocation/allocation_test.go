@@ -0,0 +1,379 @@
+package allocation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/evalclient"
+)
+
+// fakeAllocator mimics the eval-engine allocator routes. Requests for the "blocked"
+// pool are rejected by its SentinelNet check.
+type fakeAllocator struct {
+	mu       sync.Mutex
+	records  map[string]*evalclient.Allocation
+	approves int
+	down     bool
+	// approvers records the approvedBy of every approval.
+	approvers []string
+}
+
+func newFakeAllocator(t *testing.T) (*fakeAllocator, *evalclient.Allocator) {
+	f := &fakeAllocator{records: map[string]*evalclient.Allocation{}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	client, err := evalclient.NewAllocator(evalclient.Config{BaseURL: srv.URL, Retries: -1})
+	if err != nil {
+		t.Fatalf("NewAllocator: %v", err)
+	}
+	return f, client
+}
+
+func (f *fakeAllocator) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	switch {
+	case r.URL.Path == "/alloc/request" && body["pool"] == "unknown":
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "unknown pool"})
+	case r.URL.Path == "/alloc/request":
+		id := fmt.Sprintf("alloc-%d", len(f.records)+1)
+		f.records[id] = &evalclient.Allocation{ID: id, AgentID: body["agentId"].(string), Pool: body["pool"].(string), Status: evalclient.AllocationPending}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"requestId": id, "status": "pending"})
+	case r.URL.Path == "/alloc/approve":
+		rec := f.records[body["requestId"].(string)]
+		f.approves++
+		f.approvers = append(f.approvers, body["approvedBy"].(string))
+		f.decide(rec, body["approvedBy"].(string))
+		_ = json.NewEncoder(w).Encode(rec)
+	case strings.HasPrefix(r.URL.Path, "/alloc/"):
+		rec, ok := f.records[strings.TrimPrefix(r.URL.Path, "/alloc/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "not found"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(rec)
+	}
+}
+
+func (f *fakeAllocator) snapshot(id string) evalclient.Allocation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.records[id]
+}
+
+func (f *fakeAllocator) decide(rec *evalclient.Allocation, by string) {
+	rec.Status = evalclient.AllocationApplied
+	rec.SentinelDecision = json.RawMessage(`{"allowed":true,"policyId":"sentinel-allow"}`)
+	if rec.Pool == "blocked" {
+		rec.Status = evalclient.AllocationRejected
+		rec.SentinelDecision = json.RawMessage(`{"allowed":false,"policyId":"pool-quota"}`)
+	}
+	now := time.Now().UTC()
+	rec.AppliedBy, rec.AppliedAt = &by, &now
+}
+
+// eventLog records the audit event types appended through it.
+type eventLog struct {
+	audit.Store
+	mu    sync.Mutex
+	types []string
+}
+
+func (l *eventLog) AppendAuditEvent(ctx context.Context, ev *audit.AuditEvent, s signer.Signer) error {
+	l.mu.Lock()
+	l.types = append(l.types, ev.EventType)
+	l.mu.Unlock()
+	return l.Store.AppendAuditEvent(ctx, ev, s)
+}
+
+func (l *eventLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.types, ",")
+}
+
+func newService(t *testing.T, alloc *evalclient.Allocator, cfg Config) (*Service, *eventLog) {
+	events := &eventLog{Store: audit.NewFileStore(t.TempDir())}
+	return NewService(NewFileStore(t.TempDir()), alloc, events, signer.NewLocalSigner("alloc-test"), cfg), events
+}
+
+func TestSubmitForwardsAndApproves(t *testing.T) {
+	ctx := context.Background()
+	_, client := newFakeAllocator(t)
+	svc, events := newService(t, client, Config{})
+
+	rec := &Record{Id: "a1", DivisionId: "div-1", Pool: "gpus-us-east", Delta: 2, Requester: "cn:ai-infra"}
+	if err := svc.Submit(ctx, rec); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if rec.Status != StatusApplied || rec.AllocatorRequestId != "alloc-1" || rec.AppliedBy != Approver || len(rec.SentinelDecision) == 0 {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+	blocked := &Record{Id: "a2", EntityId: "agent-9", Pool: "blocked", Delta: 1}
+	if err := svc.Submit(ctx, blocked); err != nil || blocked.Status != StatusRejected {
+		t.Fatalf("blocked pool: %+v %v", blocked, err)
+	}
+	if got := events.String(); got != "allocation.requested,allocation.applied,allocation.requested,allocation.rejected" {
+		t.Fatalf("unexpected audit trail %s", got)
+	}
+	stored, err := svc.Get(ctx, "a1")
+	if err != nil || stored.Status != StatusApplied {
+		t.Fatalf("Get: %+v %v", stored, err)
+	}
+	if _, err := svc.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestManualApprovalIsReconciled(t *testing.T) {
+	ctx := context.Background()
+	fake, client := newFakeAllocator(t)
+	svc, events := newService(t, client, Config{ManualApproval: true})
+
+	rec := &Record{Id: "m1", DivisionId: "div-1", Pool: "cpu", Delta: 4}
+	if err := svc.Submit(ctx, rec); err != nil || rec.Status != StatusPending || rec.AllocatorRequestId == "" {
+		t.Fatalf("Submit: %+v %v", rec, err)
+	}
+	if n, err := svc.RunOnce(ctx); n != 0 || err != nil {
+		t.Fatalf("nothing should change before the operator decides: %d %v", n, err)
+	}
+
+	fake.mu.Lock()
+	fake.decide(fake.records[rec.AllocatorRequestId], "operator")
+	approves := fake.approves
+	fake.mu.Unlock()
+	if approves != 0 {
+		t.Fatalf("the Kernel must not approve under manual approval")
+	}
+
+	if n, err := svc.RunOnce(ctx); n != 1 || err != nil {
+		t.Fatalf("RunOnce: %d %v", n, err)
+	}
+	got, _ := svc.Get(ctx, "m1")
+	if got.Status != StatusApplied || got.AppliedBy != "operator" {
+		t.Fatalf("status change not reconciled: %+v", got)
+	}
+	if n, _ := svc.RunOnce(ctx); n != 0 {
+		t.Fatalf("applied records must not be reconciled again")
+	}
+	if got := events.String(); got != "allocation.requested,allocation.applied" {
+		t.Fatalf("unexpected audit trail %s", got)
+	}
+}
+
+func TestStaleReconcileDoesNotAuditTwice(t *testing.T) {
+	ctx := context.Background()
+	fake, client := newFakeAllocator(t)
+	svc, events := newService(t, client, Config{ManualApproval: true})
+
+	rec := &Record{Id: "s1", DivisionId: "div-1", Pool: "cpu", Delta: 1}
+	if err := svc.Submit(ctx, rec); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	fake.mu.Lock()
+	fake.decide(fake.records[rec.AllocatorRequestId], "operator")
+	fake.mu.Unlock()
+
+	// Two callers holding the same pending copy race to apply the decision; only the
+	// first transition may be audited.
+	stale := *rec
+	var wg sync.WaitGroup
+	for _, r := range []*Record{rec, &stale} {
+		wg.Add(1)
+		go func(r *Record) {
+			defer wg.Done()
+			if err := svc.apply(ctx, r, fake.snapshot(r.AllocatorRequestId)); err != nil {
+				t.Errorf("apply: %v", err)
+			}
+		}(r)
+	}
+	wg.Wait()
+	if rec.Status != StatusApplied || stale.Status != StatusApplied {
+		t.Fatalf("both copies should end applied: %s %s", rec.Status, stale.Status)
+	}
+	if got := events.String(); got != "allocation.requested,allocation.applied" {
+		t.Fatalf("unexpected audit trail %s", got)
+	}
+}
+
+func TestSubmitStaysPendingWhileAllocatorDown(t *testing.T) {
+	ctx := context.Background()
+	fake, client := newFakeAllocator(t)
+	fake.down = true
+	svc, events := newService(t, client, Config{})
+
+	rec := &Record{Id: "d1", DivisionId: "div-1", Pool: "cpu", Delta: 1, Requester: "cn:ai-infra"}
+	if err := svc.Submit(ctx, rec); err != nil || rec.Status != StatusPending || rec.AllocatorRequestId != "" {
+		t.Fatalf("expected a pending record, got %+v %v", rec, err)
+	}
+	if n, err := svc.RunOnce(ctx); n != 0 || err != nil {
+		t.Fatalf("nothing can change while the allocator is down: %d %v", n, err)
+	}
+
+	fake.mu.Lock()
+	fake.down = false
+	fake.mu.Unlock()
+	if n, err := svc.RunOnce(ctx); n != 1 || err != nil {
+		t.Fatalf("RunOnce: %d %v", n, err)
+	}
+	got, _ := svc.Get(ctx, "d1")
+	if got.Status != StatusApplied || got.AllocatorRequestId == "" || got.AppliedBy != Approver {
+		t.Fatalf("pending record not filed by the Reconciler: %+v", got)
+	}
+	fake.mu.Lock()
+	approvers := strings.Join(fake.approvers, ",")
+	fake.mu.Unlock()
+	if approvers != Approver {
+		t.Fatalf("expected one approval as %q, got %q", Approver, approvers)
+	}
+	if got := events.String(); got != "allocation.requested,allocation.applied" {
+		t.Fatalf("unexpected audit trail %s", got)
+	}
+}
+
+func TestSubmitFailsWhenAllocatorRefuses(t *testing.T) {
+	_, client := newFakeAllocator(t)
+	svc, events := newService(t, client, Config{})
+
+	rec := &Record{Id: "f1", DivisionId: "div-1", Pool: "unknown", Delta: 1}
+	err := svc.Submit(context.Background(), rec)
+	if !errors.Is(err, ErrAllocator) || rec.Status != StatusFailed || rec.Error == "" {
+		t.Fatalf("expected a failed record, got %+v %v", rec, err)
+	}
+	if got := events.String(); got != "allocation.requested,allocation.failed" {
+		t.Fatalf("unexpected audit trail %s", got)
+	}
+}
+
+func TestSubmitRefusesExistingID(t *testing.T) {
+	ctx := context.Background()
+	fake, client := newFakeAllocator(t)
+	svc, _ := newService(t, client, Config{})
+
+	if err := svc.Submit(ctx, &Record{Id: "x1", DivisionId: "div-1", Pool: "cpu", Delta: 1}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	again := &Record{Id: "x1", DivisionId: "div-1", Pool: "cpu", Delta: 8}
+	if err := svc.Submit(ctx, again); !errors.Is(err, ErrExists) {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	stored, err := svc.Get(ctx, "x1")
+	if err != nil || stored.Status != StatusApplied || stored.Delta != 1 || stored.AllocatorRequestId != "alloc-1" {
+		t.Fatalf("existing record changed: %+v %v", stored, err)
+	}
+	fake.mu.Lock()
+	filed := len(fake.records)
+	fake.mu.Unlock()
+	if filed != 1 {
+		t.Fatalf("a reused id must not file another allocator request, got %d", filed)
+	}
+	for _, id := range []string{"../x1", `..\x1`, ""} {
+		if err := svc.Submit(ctx, &Record{Id: id, DivisionId: "div-1"}); !errors.Is(err, ErrInvalidID) {
+			t.Fatalf("id %q: expected ErrInvalidID, got %v", id, err)
+		}
+	}
+}
+
+func TestRecordOnlyWithoutAllocator(t *testing.T) {
+	svc, _ := newService(t, nil, Config{})
+	rec := &Record{Id: "r1", DivisionId: "div-1", CPU: 2}
+	if err := svc.Submit(context.Background(), rec); err != nil || rec.Status != StatusPending {
+		t.Fatalf("Submit: %+v %v", rec, err)
+	}
+	if svc.Forwarding() {
+		t.Fatalf("no allocator configured")
+	}
+}
+
+func TestPGStoreListPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	payload, _ := json.Marshal(&Record{Id: "p1", DivisionId: "div-1", Status: StatusPending, AllocatorRequestId: "alloc-7"})
+	mock.ExpectQuery("SELECT id, payload FROM allocations\\s+WHERE status = 'pending'").
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payload"}).AddRow("p1", payload))
+
+	recs, err := NewPGStore(db).ListPending(context.Background(), 10)
+	if err != nil || len(recs) != 1 || recs[0].AllocatorRequestId != "alloc-7" {
+		t.Fatalf("ListPending: %+v %v", recs, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPGStoreTransitionIsConditional(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	rec := &Record{Id: "p1", Status: StatusApplied, AllocatorRequestId: "alloc-7"}
+	mock.ExpectExec("UPDATE allocations SET status = \\$2.*WHERE id = \\$1 AND status = \\$6").
+		WithArgs("p1", StatusApplied, sqlmock.AnyArg(), sqlmock.AnyArg(), "alloc-7", StatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE allocations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	store := NewPGStore(db)
+	if ok, err := store.Transition(context.Background(), rec, StatusPending); !ok || err != nil {
+		t.Fatalf("first Transition: %t %v", ok, err)
+	}
+	if ok, err := store.Transition(context.Background(), rec, StatusPending); ok || err != nil {
+		t.Fatalf("second Transition should lose: %t %v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPGStoreCreateIsInsertOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO allocations .*ON CONFLICT \\(id\\) DO NOTHING").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO allocations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	store := NewPGStore(db)
+	rec := &Record{Id: "p1", DivisionId: "div-1", Status: StatusPending}
+	if err := store.Create(context.Background(), rec); err != nil {
+		t.Fatalf("first Create: %v", err)
+	}
+	if err := store.Create(context.Background(), rec); !errors.Is(err, ErrExists) {
+		t.Fatalf("second Create: expected ErrExists, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
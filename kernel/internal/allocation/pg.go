@@ -0,0 +1,116 @@
+package allocation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// PGStore keeps allocation records in the allocations table. The payload column holds
+// the full record; the other columns exist for querying.
+type PGStore struct {
+	db *sql.DB
+}
+
+// NewPGStore returns a PGStore on db.
+func NewPGStore(db *sql.DB) *PGStore {
+	return &PGStore{db: db}
+}
+
+func (p *PGStore) Create(ctx context.Context, r *Record) error {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	q := `
+		INSERT INTO allocations (id, division_id, cpu, gpu, memory_mb, requester, status, reason, created_at, updated_at, payload, allocator_request_id, pool, delta)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,NULLIF($12, ''),$13,$14)
+		ON CONFLICT (id) DO NOTHING
+	`
+	res, err := p.db.ExecContext(ctx, q,
+		r.Id, r.DivisionId, r.CPU, r.GPU, r.MemoryMB, r.Requester,
+		r.Status, r.Reason, r.CreatedAt, r.UpdatedAt, payload,
+		r.AllocatorRequestId, r.Pool, r.Delta,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrExists
+	}
+	return nil
+}
+
+func (p *PGStore) Transition(ctx context.Context, r *Record, from string) (bool, error) {
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return false, err
+	}
+	res, err := p.db.ExecContext(ctx, `
+		UPDATE allocations SET status = $2, updated_at = $3, payload = $4, allocator_request_id = NULLIF($5, '')
+		WHERE id = $1 AND status = $6`,
+		r.Id, r.Status, r.UpdatedAt, payload, r.AllocatorRequestId, from,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (p *PGStore) Get(ctx context.Context, id string) (*Record, error) {
+	var payload []byte
+	err := p.db.QueryRowContext(ctx, `SELECT payload FROM allocations WHERE id = $1`, id).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRecord(id, payload)
+}
+
+func (p *PGStore) ListPending(ctx context.Context, limit int) ([]*Record, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, payload FROM allocations
+		WHERE status = 'pending' AND (allocator_request_id IS NOT NULL OR COALESCE(pool, '') <> '')
+		ORDER BY created_at
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*Record
+	for rows.Next() {
+		var (
+			id      string
+			payload []byte
+		)
+		if err := rows.Scan(&id, &payload); err != nil {
+			return nil, err
+		}
+		r, err := decodeRecord(id, payload)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func decodeRecord(id string, payload []byte) (*Record, error) {
+	var r Record
+	if err := json.Unmarshal(payload, &r); err != nil {
+		return nil, fmt.Errorf("decode allocation %s: %w", id, err)
+	}
+	return &r, nil
+}
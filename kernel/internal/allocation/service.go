@@ -0,0 +1,316 @@
+package allocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/evalclient"
+)
+
+// ErrAllocator wraps failures to reach or be accepted by the Resource Allocator.
+var ErrAllocator = errors.New("resource allocator")
+
+// Approver is the identity the Kernel approves allocator requests under. The requester
+// named in a request is never used, so nobody approves their own request.
+const Approver = "kernel"
+
+// Config tunes a Service.
+type Config struct {
+	// ManualApproval leaves requests pending at the allocator for an operator instead
+	// of approving them on the Kernel's behalf.
+	ManualApproval bool
+	// ReconcileInterval is how often Run polls the allocator for pending records.
+	ReconcileInterval time.Duration
+	// ReconcileBatch caps the records reconciled per pass.
+	ReconcileBatch int
+}
+
+// Service records allocation requests and drives them through the allocator. With a
+// nil allocator it only records them, as the Kernel did before the allocator existed.
+type Service struct {
+	store     Store
+	allocator *evalclient.Allocator
+	audit     audit.Store
+	signer    signer.Signer
+	cfg       Config
+	now       func() time.Time
+}
+
+// NewService builds a Service. allocator may be nil.
+func NewService(store Store, allocator *evalclient.Allocator, as audit.Store, s signer.Signer, cfg Config) *Service {
+	if cfg.ReconcileInterval <= 0 {
+		cfg.ReconcileInterval = 30 * time.Second
+	}
+	if cfg.ReconcileBatch <= 0 {
+		cfg.ReconcileBatch = 100
+	}
+	return &Service{store: store, allocator: allocator, audit: as, signer: s, cfg: cfg, now: time.Now}
+}
+
+// Forwarding reports whether requests are sent to the allocator.
+func (svc *Service) Forwarding() bool {
+	return svc.allocator != nil
+}
+
+// Submit persists a new pending record, audits allocation.requested and, when an
+// allocator is configured, files it there (see file). It returns ErrInvalidID for an
+// unusable id and ErrExists when the id is already taken, leaving the stored record
+// untouched; otherwise rec reflects what was stored.
+func (svc *Service) Submit(ctx context.Context, rec *Record) error {
+	if !ValidID(rec.Id) {
+		return ErrInvalidID
+	}
+	now := svc.now().UTC()
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = now
+	}
+	rec.UpdatedAt = now
+	rec.Status = StatusPending
+	if err := svc.store.Create(ctx, rec); err != nil {
+		if errors.Is(err, ErrExists) {
+			return err
+		}
+		return fmt.Errorf("persist allocation: %w", err)
+	}
+	if err := svc.record(ctx, "allocation.requested", "", rec); err != nil {
+		return err
+	}
+	if svc.allocator == nil {
+		return nil
+	}
+	return svc.file(ctx, rec)
+}
+
+// file forwards a pending record to the allocator and, unless ManualApproval, approves
+// it. A request the allocator refuses ends failed and file returns an error wrapping
+// ErrAllocator. When the allocator cannot be reached the record stays pending and the
+// Reconciler files it again; a failed approval is likewise retried by the Reconciler.
+func (svc *Service) file(ctx context.Context, rec *Record) error {
+	ticket, err := svc.allocator.RequestAllocation(ctx, evalclient.AllocationRequest{
+		PromotionID: rec.PromotionId,
+		AgentID:     rec.AgentId(),
+		Pool:        rec.Pool,
+		Delta:       rec.Delta,
+		Reason:      rec.Reason,
+		RequestedBy: rec.Requester,
+	})
+	if err != nil {
+		if !refused(err) {
+			log.Printf("[allocation] file %s: %v (will retry)", rec.Id, err)
+			return nil
+		}
+		next := *rec
+		next.Status = StatusFailed
+		next.Error = err.Error()
+		next.UpdatedAt = svc.now().UTC()
+		if cerr := svc.commit(ctx, rec, &next); cerr != nil {
+			return cerr
+		}
+		return fmt.Errorf("%w: %v", ErrAllocator, err)
+	}
+	next := *rec
+	next.AllocatorRequestId = ticket.RequestID
+	next.UpdatedAt = svc.now().UTC()
+	ok, err := svc.store.Transition(ctx, &next, StatusPending)
+	if err != nil {
+		return fmt.Errorf("persist allocation: %w", err)
+	}
+	if !ok {
+		log.Printf("[allocation] %s changed while filing it (allocator %s)", rec.Id, ticket.RequestID)
+		return nil
+	}
+	*rec = next
+	if svc.cfg.ManualApproval {
+		return nil
+	}
+	a, err := svc.allocator.Approve(ctx, rec.AllocatorRequestId, Approver)
+	if err != nil {
+		log.Printf("[allocation] approve %s (allocator %s): %v", rec.Id, rec.AllocatorRequestId, err)
+		return nil
+	}
+	return svc.apply(ctx, rec, a)
+}
+
+// refused reports whether an allocator error is the allocator turning the request down
+// (a 4xx other than 429) rather than a failure worth retrying.
+func refused(err error) bool {
+	var apiErr *evalclient.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 &&
+		!errors.Is(err, evalclient.ErrUnavailable)
+}
+
+// Get returns the record, first reconciling it with the allocator when it is still
+// pending there. A reconciliation failure is logged and the stored record returned.
+func (svc *Service) Get(ctx context.Context, id string) (*Record, error) {
+	rec, err := svc.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.Reconcile(ctx, rec); err != nil {
+		log.Printf("[allocation] reconcile %s: %v", rec.Id, err)
+	}
+	return rec, nil
+}
+
+// Reconcile brings rec up to date with the allocator's record, approving it if it is
+// still pending and approval is automatic. rec is updated in place.
+func (svc *Service) Reconcile(ctx context.Context, rec *Record) error {
+	if svc.allocator == nil || rec.AllocatorRequestId == "" || rec.Terminal() {
+		return nil
+	}
+	// Another reconciliation may already have moved the record on.
+	if latest, err := svc.store.Get(ctx, rec.Id); err == nil {
+		*rec = *latest
+		if rec.Terminal() {
+			return nil
+		}
+	}
+
+	a, err := svc.allocator.Get(ctx, rec.AllocatorRequestId)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAllocator, err)
+	}
+	if a.Status == evalclient.AllocationPending && !svc.cfg.ManualApproval {
+		if a, err = svc.allocator.Approve(ctx, rec.AllocatorRequestId, Approver); err != nil {
+			return fmt.Errorf("%w: %v", ErrAllocator, err)
+		}
+	}
+	return svc.apply(ctx, rec, a)
+}
+
+// apply copies the allocator's outcome into rec, persisting and auditing it (see commit)
+// when the status changed.
+func (svc *Service) apply(ctx context.Context, rec *Record, a evalclient.Allocation) error {
+	if a.Status == "" || a.Status == rec.Status {
+		return nil
+	}
+	next := *rec
+	next.Status = a.Status
+	next.SentinelDecision = a.SentinelDecision
+	next.AppliedAt = a.AppliedAt
+	if a.AppliedBy != nil {
+		next.AppliedBy = *a.AppliedBy
+	}
+	next.UpdatedAt = svc.now().UTC()
+	return svc.commit(ctx, rec, &next)
+}
+
+// commit stores next in place of rec if the stored status is still rec's and audits the
+// change as allocation.<status>. When a concurrent Get or Reconciler pass got there
+// first, rec is reloaded instead and nothing is audited.
+func (svc *Service) commit(ctx context.Context, rec, next *Record) error {
+	ok, err := svc.store.Transition(ctx, next, rec.Status)
+	if err != nil {
+		return fmt.Errorf("persist allocation: %w", err)
+	}
+	if !ok {
+		latest, err := svc.store.Get(ctx, rec.Id)
+		if err != nil {
+			return fmt.Errorf("reload allocation: %w", err)
+		}
+		*rec = *latest
+		return nil
+	}
+	from := rec.Status
+	*rec = *next
+	return svc.record(ctx, "allocation."+rec.Status, from, rec)
+}
+
+// record appends an audit event describing rec; from is the previous status, if any.
+func (svc *Service) record(ctx context.Context, eventType, from string, rec *Record) error {
+	payload := map[string]interface{}{
+		"allocationId": rec.Id,
+		"divisionId":   rec.DivisionId,
+		"cpu":          rec.CPU,
+		"gpu":          rec.GPU,
+		"memoryMB":     rec.MemoryMB,
+		"requester":    rec.Requester,
+		"status":       rec.Status,
+		"reason":       rec.Reason,
+	}
+	optional := map[string]string{
+		"entityId":           rec.EntityId,
+		"promotionId":        rec.PromotionId,
+		"pool":               rec.Pool,
+		"allocatorRequestId": rec.AllocatorRequestId,
+		"appliedBy":          rec.AppliedBy,
+		"error":              rec.Error,
+		"previousStatus":     from,
+	}
+	for k, v := range optional {
+		if v != "" {
+			payload[k] = v
+		}
+	}
+	if rec.Delta != 0 {
+		payload["delta"] = rec.Delta
+	}
+	if len(rec.SentinelDecision) > 0 {
+		payload["sentinelDecision"] = rec.SentinelDecision
+	}
+	ev := &audit.AuditEvent{
+		EventType: eventType,
+		Payload:   payload,
+		Ts:        time.Now().UTC(),
+	}
+	if err := svc.audit.AppendAuditEvent(ctx, ev, svc.signer); err != nil {
+		return fmt.Errorf("append audit event: %w", err)
+	}
+	return nil
+}
+
+// Run reconciles pending records every ReconcileInterval until ctx is cancelled.
+func (svc *Service) Run(ctx context.Context) error {
+	if svc.allocator == nil {
+		return nil
+	}
+	log.Printf("[allocation] reconciler starting (interval=%s manualApproval=%t)", svc.cfg.ReconcileInterval, svc.cfg.ManualApproval)
+	defer log.Printf("[allocation] reconciler stopped")
+	t := time.NewTicker(svc.cfg.ReconcileInterval)
+	defer t.Stop()
+	for {
+		if _, err := svc.RunOnce(ctx); err != nil {
+			log.Printf("[allocation] %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce reconciles one batch of pending records, filing those the allocator could not
+// be reached for, and reports how many changed status. Failures on individual records
+// are logged so one bad record cannot stall the rest.
+func (svc *Service) RunOnce(ctx context.Context) (int, error) {
+	pending, err := svc.store.ListPending(ctx, svc.cfg.ReconcileBatch)
+	if err != nil {
+		return 0, fmt.Errorf("list pending allocations: %w", err)
+	}
+	changed := 0
+	for _, rec := range pending {
+		if ctx.Err() != nil {
+			return changed, ctx.Err()
+		}
+		var err error
+		if rec.AllocatorRequestId == "" {
+			err = svc.file(ctx, rec)
+		} else {
+			err = svc.Reconcile(ctx, rec)
+		}
+		if err != nil {
+			log.Printf("[allocation] reconcile %s: %v", rec.Id, err)
+			continue
+		}
+		if rec.Status != StatusPending {
+			changed++
+		}
+	}
+	return changed, nil
+}
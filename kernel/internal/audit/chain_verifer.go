@@ -11,13 +11,16 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/ILLUVRSE/Main/kernel/internal/canonical"
 	"github.com/ILLUVRSE/Main/kernel/internal/keys"
 )
 
 // VerifyChain walks the audit_events table in chronological order and verifies:
-//   - hash correctness: hash == SHA256(canonical(payload) || prevHashBytes)
+//   - hash correctness: hash == SHA256(canonical(payload) || prevHashBytes), with the
+//     canonicalization named by the event's hash_scheme (legacy for events appended
+//     before JCS)
 //   - signature correctness: Ed25519 verify using signer public key from registry
+//   - linkage: prevHash equals the hash of the previous event, or the gap is bridged
+//     by signed audit_checkpoints left behind by the retention pruner
 //
 // Returns nil on success or an error describing the first problem encountered.
 func VerifyChain(ctx context.Context, db *sql.DB, reg *keys.Registry) error {
@@ -28,7 +31,12 @@ func VerifyChain(ctx context.Context, db *sql.DB, reg *keys.Registry) error {
 		return errors.New("key registry is nil")
 	}
 
-	q := `SELECT id, event_type, payload, prev_hash, hash, signature, signer_id FROM audit_events ORDER BY ts ASC`
+	checkpoints, err := loadCheckpoints(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	q := `SELECT id, event_type, payload, prev_hash, hash, signature, signer_id, hash_scheme FROM audit_events ORDER BY ts ASC`
 	rows, err := db.QueryContext(ctx, q)
 	if err != nil {
 		return fmt.Errorf("query audit_events: %w", err)
@@ -43,15 +51,24 @@ func VerifyChain(ctx context.Context, db *sql.DB, reg *keys.Registry) error {
 		hashHex   string
 		signB64   string
 		signerId  string
+		scheme    string
 	)
 
 	index := 0
+	lastHash := ""
 	for rows.Next() {
 		index++
-		if err := rows.Scan(&idStr, &eventType, &payloadB, &prevHash, &hashHex, &signB64, &signerId); err != nil {
+		if err := rows.Scan(&idStr, &eventType, &payloadB, &prevHash, &hashHex, &signB64, &signerId, &scheme); err != nil {
 			return fmt.Errorf("scan row %d: %w", index, err)
 		}
 
+		// Linkage: prevHash must point at the previous kept event, possibly across pruned ranges.
+		if prevHash.String != lastHash {
+			if err := bridgeCheckpoints(checkpoints, reg, lastHash, prevHash.String); err != nil {
+				return fmt.Errorf("chain break before event %s: %w", idStr, err)
+			}
+		}
+
 		// Unmarshal payload JSON into interface{} so canonicalization matches original
 		var payload interface{}
 		if err := json.Unmarshal(payloadB, &payload); err != nil {
@@ -60,7 +77,7 @@ func VerifyChain(ctx context.Context, db *sql.DB, reg *keys.Registry) error {
 		}
 
 		// Canonicalize payload
-		canon, err := canonical.MarshalCanonical(payload)
+		canon, err := CanonicalPayload(scheme, payload)
 		if err != nil {
 			return fmt.Errorf("canonicalize payload for event %s: %w", idStr, err)
 		}
@@ -86,27 +103,62 @@ func VerifyChain(ctx context.Context, db *sql.DB, reg *keys.Registry) error {
 		}
 
 		// Verify signature using signer public key from registry
-		ki, ok := reg.GetSigner(signerId)
+		if err := verifySignature(reg, signerId, sum[:], signB64); err != nil {
+			return fmt.Errorf("event %s: %w", idStr, err)
+		}
+		lastHash = hashHex
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return nil
+}
+
+// bridgeCheckpoints follows signed checkpoints from the hash of the last kept event (from)
+// until it reaches the prevHash of the next kept event (to), verifying each checkpoint.
+func bridgeCheckpoints(checkpoints map[string]*AuditCheckpoint, reg *keys.Registry, from, to string) error {
+	cur := from
+	for hops := 0; hops <= len(checkpoints); hops++ {
+		cp, ok := checkpoints[cur]
 		if !ok {
-			return fmt.Errorf("unknown signer %s for event %s", signerId, idStr)
+			return fmt.Errorf("prevHash=%s does not follow %q and no checkpoint covers the gap", to, from)
 		}
-		pubBytes, err := base64.StdEncoding.DecodeString(ki.PublicKey)
+		digest, err := cp.ComputeHash()
 		if err != nil {
-			return fmt.Errorf("invalid public key for signer %s: %w", signerId, err)
+			return err
 		}
-		sigBytes, err := base64.StdEncoding.DecodeString(signB64)
-		if err != nil {
-			return fmt.Errorf("invalid signature encoding for event %s: %w", idStr, err)
+		if hex.EncodeToString(digest) != cp.Hash {
+			return fmt.Errorf("hash mismatch for checkpoint %s", cp.ID)
 		}
-
-		if !ed25519.Verify(ed25519.PublicKey(pubBytes), sum[:], sigBytes) {
-			return fmt.Errorf("signature verification failed for event %s with signer %s", idStr, signerId)
+		if err := verifySignature(reg, cp.SignerId, digest, cp.Signature); err != nil {
+			return fmt.Errorf("checkpoint %s: %w", cp.ID, err)
+		}
+		cur = cp.LastHash
+		if cur == to {
+			return nil
 		}
 	}
+	return fmt.Errorf("checkpoints starting at %q do not reach prevHash=%s", from, to)
+}
 
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("rows iteration error: %w", err)
+// verifySignature checks an Ed25519 signature over digest using the signer's registered key.
+func verifySignature(reg *keys.Registry, signerId string, digest []byte, signB64 string) error {
+	ki, ok := reg.GetSigner(signerId)
+	if !ok {
+		return fmt.Errorf("unknown signer %s", signerId)
+	}
+	pubBytes, err := base64.StdEncoding.DecodeString(ki.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key for signer %s: %w", signerId, err)
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(signB64)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pubBytes), digest, sigBytes) {
+		return fmt.Errorf("signature verification failed with signer %s", signerId)
 	}
-
 	return nil
 }
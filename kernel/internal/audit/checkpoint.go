@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/canonical"
+)
+
+// AuditCheckpoint is a signed tombstone covering a contiguous range of pruned audit events.
+// PrevHash is the prevHash of the first pruned event and LastHash is the hash of the last
+// pruned event, so a verifier can bridge from the last kept event before the range to the
+// first kept event after it.
+type AuditCheckpoint struct {
+	ID           string    `json:"id"`
+	FirstEventID string    `json:"firstEventId"`
+	LastEventID  string    `json:"lastEventId"`
+	FirstTs      time.Time `json:"firstTs"`
+	LastTs       time.Time `json:"lastTs"`
+	EventCount   int       `json:"eventCount"`
+	PrevHash     string    `json:"prevHash"`
+	LastHash     string    `json:"lastHash"`
+	ArchiveKeys  []string  `json:"archiveKeys"`
+	Hash         string    `json:"hash,omitempty"`
+	Signature    string    `json:"signature,omitempty"`
+	SignerId     string    `json:"signerId,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ComputeHash returns SHA256(canonical(body)) where body is every checkpoint field
+// except hash, signature and signerId. Timestamps are truncated to microseconds so the
+// digest survives a round-trip through Postgres TIMESTAMPTZ.
+func (c *AuditCheckpoint) ComputeHash() ([]byte, error) {
+	keys := make([]interface{}, 0, len(c.ArchiveKeys))
+	for _, k := range c.ArchiveKeys {
+		keys = append(keys, k)
+	}
+	body := map[string]interface{}{
+		"id":           c.ID,
+		"firstEventId": c.FirstEventID,
+		"lastEventId":  c.LastEventID,
+		"firstTs":      c.FirstTs.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		"lastTs":       c.LastTs.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		"eventCount":   c.EventCount,
+		"prevHash":     c.PrevHash,
+		"lastHash":     c.LastHash,
+		"archiveKeys":  keys,
+		"createdAt":    c.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	}
+	canon, err := canonical.MarshalCanonical(body)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalize checkpoint: %w", err)
+	}
+	return HashBytes(canon), nil
+}
+
+// loadCheckpoints returns all audit checkpoints keyed by prevHash.
+func loadCheckpoints(ctx context.Context, db *sql.DB) (map[string]*AuditCheckpoint, error) {
+	q := `
+		SELECT id, first_event_id, last_event_id, first_ts, last_ts, event_count,
+		       prev_hash, last_hash, archive_keys, hash, signature, signer_id, created_at
+		FROM audit_checkpoints
+	`
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("query audit_checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]*AuditCheckpoint)
+	for rows.Next() {
+		var (
+			cp       AuditCheckpoint
+			prevHash sql.NullString
+			keysB    []byte
+		)
+		if err := rows.Scan(&cp.ID, &cp.FirstEventID, &cp.LastEventID, &cp.FirstTs, &cp.LastTs, &cp.EventCount,
+			&prevHash, &cp.LastHash, &keysB, &cp.Hash, &cp.Signature, &cp.SignerId, &cp.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan checkpoint: %w", err)
+		}
+		cp.PrevHash = prevHash.String
+		if len(keysB) > 0 {
+			if err := json.Unmarshal(keysB, &cp.ArchiveKeys); err != nil {
+				return nil, fmt.Errorf("unmarshal archive keys for checkpoint %s: %w", cp.ID, err)
+			}
+		}
+		if _, dup := out[cp.PrevHash]; dup {
+			return nil, fmt.Errorf("multiple checkpoints start at prevHash %q", cp.PrevHash)
+		}
+		c := cp
+		out[cp.PrevHash] = &c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Feed is implemented by stores that can replay the audit chain in append order. Each
+// appended event is given a Seq that increases along the chain; feed subscribers resume
+// from the last Seq they saw (the SSE Last-Event-ID).
+type Feed interface {
+	// EventsSince returns up to limit events with Seq greater than after, in Seq order.
+	EventsSince(ctx context.Context, after int64, limit int) ([]*AuditEvent, error)
+	// HeadSeq returns the Seq of the latest event, or 0 when the chain is empty.
+	HeadSeq(ctx context.Context) (int64, error)
+}
+
+var (
+	// ErrTooManySubscribers is returned by Subscribe when MaxSubscribers are connected.
+	ErrTooManySubscribers = errors.New("too many audit feed subscribers")
+	// ErrFeedClosed is returned by Next once the hub has stopped.
+	ErrFeedClosed = errors.New("audit feed closed")
+)
+
+// HubConfig configures a Hub. Zero fields select the defaults.
+type HubConfig struct {
+	// MaxSubscribers bounds concurrent subscriptions (default 100).
+	MaxSubscribers int
+	// PollInterval wakes subscribers even without a notification, covering appends whose
+	// notification was lost, e.g. while the LISTEN connection reconnects (default 5s).
+	PollInterval time.Duration
+	// BatchSize is the number of events read per store query (default 100).
+	BatchSize int
+}
+
+// Hub fans new audit events out to feed subscribers.
+//
+// Subscribers are not pushed events: each one reads from the Feed at its own cursor and
+// the hub only wakes them when the chain may have grown. Wake-ups coalesce, so a slow
+// subscriber never holds memory for events it has not consumed and never delays the
+// others; it simply reads more on its next call.
+type Hub struct {
+	feed Feed
+	cfg  HubConfig
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewHub builds a Hub over feed.
+func NewHub(feed Feed, cfg HubConfig) *Hub {
+	if cfg.MaxSubscribers <= 0 {
+		cfg.MaxSubscribers = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Hub{
+		feed: feed,
+		cfg:  cfg,
+		subs: map[*Subscription]struct{}{},
+		done: make(chan struct{}),
+	}
+}
+
+// Notify wakes every subscriber. It never blocks.
+func (h *Hub) Notify() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run wakes subscribers every PollInterval until ctx is done, then closes the hub so
+// open subscriptions end (call it before draining HTTP connections on shutdown).
+func (h *Hub) Run(ctx context.Context) error {
+	log.Printf("[audit.feed] starting (poll=%s, maxSubscribers=%d)", h.cfg.PollInterval, h.cfg.MaxSubscribers)
+	defer log.Printf("[audit.feed] stopped")
+	defer h.once.Do(func() { close(h.done) })
+
+	t := time.NewTicker(h.cfg.PollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			h.Notify()
+		}
+	}
+}
+
+// Subscribers returns the number of open subscriptions.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Subscribe opens a subscription positioned after Seq after; a negative after starts at
+// the current head, so only events appended from now on are delivered. A non-empty
+// eventTypes restricts delivery to those event types. Close the subscription when done.
+func (h *Hub) Subscribe(ctx context.Context, after int64, eventTypes []string) (*Subscription, error) {
+	if after < 0 {
+		head, err := h.feed.HeadSeq(ctx)
+		if err != nil {
+			return nil, err
+		}
+		after = head
+	}
+	s := &Subscription{hub: h, cursor: after, wake: make(chan struct{}, 1)}
+	if len(eventTypes) > 0 {
+		s.types = make(map[string]bool, len(eventTypes))
+		for _, t := range eventTypes {
+			s.types[t] = true
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subs) >= h.cfg.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	h.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Subscription is one subscriber's position in the audit chain.
+type Subscription struct {
+	hub    *Hub
+	cursor int64
+	types  map[string]bool
+	wake   chan struct{}
+}
+
+// Cursor returns the Seq of the last event read, including events skipped by the filter.
+func (s *Subscription) Cursor() int64 {
+	return s.cursor
+}
+
+// Next returns the next events after the cursor that match the filter, waiting up to
+// wait for them to be appended. It returns no events and a nil error when wait elapses.
+func (s *Subscription) Next(ctx context.Context, wait time.Duration) ([]*AuditEvent, error) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		events, err := s.hub.feed.EventsSince(ctx, s.cursor, s.hub.cfg.BatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) > 0 {
+			s.cursor = events[len(events)-1].Seq
+			if out := s.filter(events); len(out) > 0 {
+				return out, nil
+			}
+			continue
+		}
+		select {
+		case <-s.wake:
+		case <-timer.C:
+			return nil, nil
+		case <-s.hub.done:
+			return nil, ErrFeedClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Subscription) filter(events []*AuditEvent) []*AuditEvent {
+	if s.types == nil {
+		return events
+	}
+	out := events[:0]
+	for _, ev := range events {
+		if s.types[ev.EventType] {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// Close releases the subscription.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	delete(s.hub.subs, s)
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+)
+
+func TestFileStoreFeedAndHub(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(t.TempDir())
+	hub := NewHub(store, HubConfig{MaxSubscribers: 2, BatchSize: 2})
+	store.SetOnAppend(func(*AuditEvent) { hub.Notify() })
+	s := signer.NewLocalSigner("feed-test")
+	appendEvent := func(eventType string) *AuditEvent {
+		ev := &AuditEvent{EventType: eventType, Payload: map[string]interface{}{"t": eventType}}
+		if err := store.AppendAuditEvent(ctx, ev, s); err != nil {
+			t.Fatalf("AppendAuditEvent: %v", err)
+		}
+		return ev
+	}
+
+	for i, typ := range []string{"a", "b", "a"} {
+		if ev := appendEvent(typ); ev.Seq != int64(i+1) {
+			t.Fatalf("expected seq %d, got %d", i+1, ev.Seq)
+		}
+	}
+	if head, _ := store.HeadSeq(ctx); head != 3 {
+		t.Fatalf("expected head 3, got %d", head)
+	}
+	events, err := store.EventsSince(ctx, 1, 10)
+	if err != nil || len(events) != 2 || events[0].EventType != "b" || events[1].Seq != 3 {
+		t.Fatalf("EventsSince: %+v %v", events, err)
+	}
+
+	// A replaying subscriber reads past the batch size and skips filtered events.
+	replay, err := hub.Subscribe(ctx, 0, []string{"a"})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	got, err := replay.Next(ctx, time.Second)
+	if err != nil || len(got) != 1 || got[0].Seq != 1 {
+		t.Fatalf("first batch: %+v %v", got, err)
+	}
+	if got, _ = replay.Next(ctx, time.Second); len(got) != 1 || got[0].Seq != 3 || replay.Cursor() != 3 {
+		t.Fatalf("second batch: %+v cursor=%d", got, replay.Cursor())
+	}
+
+	// A tailing subscriber starts at the head and is woken by the next append.
+	tail, err := hub.Subscribe(ctx, -1, nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, err := hub.Subscribe(ctx, -1, nil); !errors.Is(err, ErrTooManySubscribers) {
+		t.Fatalf("expected ErrTooManySubscribers, got %v", err)
+	}
+	if got, err := tail.Next(ctx, 10*time.Millisecond); got != nil || err != nil {
+		t.Fatalf("expected an idle wait, got %+v %v", got, err)
+	}
+	done := make(chan []*AuditEvent)
+	go func() {
+		got, _ := tail.Next(ctx, 5*time.Second)
+		done <- got
+	}()
+	time.Sleep(20 * time.Millisecond)
+	appendEvent("c")
+	select {
+	case got := <-done:
+		if len(got) != 1 || got[0].EventType != "c" || got[0].Seq != 4 {
+			t.Fatalf("tail: %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("subscriber was not woken by the append")
+	}
+
+	replay.Close()
+	tail.Close()
+	if n := hub.Subscribers(); n != 0 {
+		t.Fatalf("expected no subscribers, got %d", n)
+	}
+}
+
+func TestHubRunClosesSubscriptions(t *testing.T) {
+	hub := NewHub(NewFileStore(t.TempDir()), HubConfig{PollInterval: time.Millisecond})
+	sub, err := hub.Subscribe(context.Background(), -1, nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer sub.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_ = hub.Run(ctx)
+	if _, err := sub.Next(context.Background(), time.Second); !errors.Is(err, ErrFeedClosed) {
+		t.Fatalf("expected ErrFeedClosed, got %v", err)
+	}
+}
+
+func TestPGStoreEventsSinceStopsAtRecentGap(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	cols := []string{"seq", "id", "event_type", "payload", "prev_hash", "hash", "signature", "signer_id", "ts", "metadata", "hash_scheme"}
+	old := time.Now().Add(-time.Minute)
+	mock.ExpectQuery("SELECT seq, id, event_type").
+		WithArgs(int64(4), 10).
+		WillReturnRows(sqlmock.NewRows(cols).
+			// seq 5 was rolled back long ago: skipped.
+			AddRow(6, "e6", "x", []byte(`{"n":6}`), "", "h6", "sig", "k", old, []byte("null"), "jcs").
+			AddRow(7, "e7", "x", []byte(`{"n":7}`), "h6", "h7", "sig", "k", old, nil, "jcs").
+			// seq 8 may still be committing: stop before 9.
+			AddRow(9, "e9", "x", []byte(`{"n":9}`), "h7", "h9", "sig", "k", time.Now(), nil, "jcs"))
+
+	events, err := NewPGStore(db).EventsSince(context.Background(), 4, 10)
+	if err != nil {
+		t.Fatalf("EventsSince: %v", err)
+	}
+	if len(events) != 2 || events[0].Seq != 6 || events[1].ID != "e7" || events[0].Metadata != nil {
+		t.Fatalf("unexpected events %+v", events)
+	}
+	if p, ok := events[1].Payload.(map[string]interface{}); !ok || p["n"] != float64(7) {
+		t.Fatalf("payload not decoded: %#v", events[1].Payload)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
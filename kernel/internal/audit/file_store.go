@@ -1,6 +1,7 @@
 package audit
 
 import (
+	"bufio"
 	"context"
 	"encoding/base64"
 	"encoding/hex"
@@ -9,6 +10,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ILLUVRSE/Main/kernel/internal/canonical"
@@ -17,8 +21,13 @@ import (
 
 // FileStore is a simple file-backed store for dev/testing.
 // It archives audit events as JSON files and keeps a head.hash file for the latest head.
+// chain.idx lists event ids in append order (line n holds the event with Seq n) and
+// head.seq holds the latest Seq.
 type FileStore struct {
 	dir string
+
+	mu       sync.Mutex
+	onAppend func(*AuditEvent)
 }
 
 // NewFileStore returns a new FileStore and ensures the archive directory exists.
@@ -29,6 +38,12 @@ func NewFileStore(dir string) *FileStore {
 
 func (f *FileStore) Ping(ctx context.Context) error { return nil }
 
+// SetOnAppend registers fn to be called after each successful append, e.g. to wake
+// audit feed subscribers. Call before the store is used.
+func (f *FileStore) SetOnAppend(fn func(*AuditEvent)) {
+	f.onAppend = fn
+}
+
 func (f *FileStore) InsertManifestSignature(ctx context.Context, ms *ManifestSignature) error {
 	if ms.ID == "" {
 		ms.ID = NewUUID()
@@ -44,7 +59,21 @@ func (f *FileStore) InsertManifestSignature(ctx context.Context, ms *ManifestSig
 // AppendAuditEvent canonicalizes payload, computes prev/hash, requests a signature
 // from signer.Signer, and writes the event JSON and head.hash to the archive directory.
 func (f *FileStore) AppendAuditEvent(ctx context.Context, ev *AuditEvent, s signer.Signer) error {
+	if err := f.append(ev, s); err != nil {
+		return err
+	}
+	if f.onAppend != nil {
+		f.onAppend(ev)
+	}
+	return nil
+}
+
+func (f *FileStore) append(ev *AuditEvent, s signer.Signer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	// canonicalize payload
+	ev.HashScheme = HashSchemeJCS
 	canon, err := canonical.MarshalCanonical(ev.Payload)
 	if err != nil {
 		return fmt.Errorf("canonicalize payload: %w", err)
@@ -82,6 +111,7 @@ func (f *FileStore) AppendAuditEvent(ctx context.Context, ev *AuditEvent, s sign
 	if ev.Ts.IsZero() {
 		ev.Ts = time.Now().UTC()
 	}
+	ev.Seq = f.readSeq() + 1
 
 	// persist event to file
 	b, _ := json.MarshalIndent(ev, "", "  ")
@@ -90,7 +120,21 @@ func (f *FileStore) AppendAuditEvent(ctx context.Context, ev *AuditEvent, s sign
 		return fmt.Errorf("write audit file: %w", err)
 	}
 
-	// update head.hash
+	// append to chain.idx, then advance head.seq and head.hash
+	idx, err := os.OpenFile(filepath.Join(f.dir, "chain.idx"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open chain.idx: %w", err)
+	}
+	_, err = idx.WriteString(ev.ID + "\n")
+	if cerr := idx.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write chain.idx: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(f.dir, "head.seq"), []byte(strconv.FormatInt(ev.Seq, 10)), 0o644); err != nil {
+		return fmt.Errorf("write head.seq: %w", err)
+	}
 	if err := os.WriteFile(filepath.Join(f.dir, "head.hash"), []byte(ev.Hash), 0o644); err != nil {
 		return fmt.Errorf("write head.hash: %w", err)
 	}
@@ -98,6 +142,65 @@ func (f *FileStore) AppendAuditEvent(ctx context.Context, ev *AuditEvent, s sign
 	return nil
 }
 
+// readSeq returns the latest Seq, or 0 before the first sequenced append.
+func (f *FileStore) readSeq() int64 {
+	b, err := os.ReadFile(filepath.Join(f.dir, "head.seq"))
+	if err != nil {
+		return 0
+	}
+	seq, _ := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	return seq
+}
+
+// HeadSeq returns the Seq of the latest event, or 0 when none has been appended.
+func (f *FileStore) HeadSeq(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.readSeq(), nil
+}
+
+// EventsSince returns up to limit events with Seq greater than after, read through
+// chain.idx.
+func (f *FileStore) EventsSince(ctx context.Context, after int64, limit int) ([]*AuditEvent, error) {
+	f.mu.Lock()
+	ids, err := f.readIndex(after, limit)
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*AuditEvent, 0, len(ids))
+	for _, id := range ids {
+		ev, err := f.GetAuditEvent(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("read audit event %s: %w", id, err)
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func (f *FileStore) readIndex(after int64, limit int) ([]string, error) {
+	file, err := os.Open(filepath.Join(f.dir, "chain.idx"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var ids []string
+	var seq int64
+	sc := bufio.NewScanner(file)
+	for sc.Scan() && len(ids) < limit {
+		seq++
+		if seq > after {
+			ids = append(ids, sc.Text())
+		}
+	}
+	return ids, sc.Err()
+}
+
 func (f *FileStore) readHead() string {
 	b, err := os.ReadFile(filepath.Join(f.dir, "head.hash"))
 	if err != nil {
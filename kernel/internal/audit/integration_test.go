@@ -57,6 +57,8 @@ func TestIntegration_DurablePipeline(t *testing.T) {
 	migrations := []string{
 		"../../sql/migrations/001_init.sql",
 		"../../sql/migrations/002_audit_pipeline.sql",
+		"../../sql/migrations/002_audit_sampling.sql",
+		"../../sql/migrations/007_audit_checkpoints.sql",
 	}
 	for _, m := range migrations {
 		b, err := os.ReadFile(m)
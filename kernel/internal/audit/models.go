@@ -21,6 +21,7 @@ type ManifestSignature struct {
 // AuditEvent is the canonical audit record stored in the audit log.
 type AuditEvent struct {
 	ID        string      `json:"id,omitempty"`
+	Seq       int64       `json:"seq,omitempty"` // position in the chain, assigned on append
 	EventType string      `json:"eventType"`
 	Payload   interface{} `json:"payload"`
 	PrevHash  string      `json:"prevHash,omitempty"`
@@ -29,6 +30,10 @@ type AuditEvent struct {
 	SignerId  string      `json:"signerId,omitempty"`
 	Ts        time.Time   `json:"ts"`
 	Metadata  interface{} `json:"metadata,omitempty"`
+	// HashScheme is the canonicalization Hash covers: HashSchemeJCS for new events,
+	// HashSchemeLegacy (or empty, in archives written before schemes were recorded)
+	// for events appended before the kernel switched to JCS.
+	HashScheme string `json:"hashScheme,omitempty"`
 }
 
 // ErrNotFound is returned when a requested audit resource cannot be located.
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NotifyChannel is the Postgres channel the audit_events insert trigger
+// (migration 011_audit_feed.sql) notifies with the new row's seq.
+const NotifyChannel = "audit_events"
+
+// seqGapGrace is how long EventsSince waits for a missing seq before skipping it. seq is
+// taken from a sequence at insert, so a concurrent append can commit a lower seq after a
+// higher one is already visible; a rolled back append leaves a permanent gap.
+const seqGapGrace = 2 * time.Second
+
+// EventsSince returns up to limit events with seq greater than after, in seq order. It
+// stops before a recent gap in seq so an append still in flight is not skipped.
+func (p *PGStore) EventsSince(ctx context.Context, after int64, limit int) ([]*AuditEvent, error) {
+	q := `
+		SELECT seq, id, event_type, payload, prev_hash, hash, signature, signer_id, ts, metadata, hash_scheme
+		FROM audit_events
+		WHERE seq > $1
+		ORDER BY seq ASC
+		LIMIT $2
+	`
+	rows, err := p.db.QueryContext(ctx, q, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query audit feed: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*AuditEvent, 0)
+	next := after + 1
+	for rows.Next() {
+		var (
+			ev                      AuditEvent
+			payloadBytes, metaBytes []byte
+		)
+		if err := rows.Scan(&ev.Seq, &ev.ID, &ev.EventType, &payloadBytes, &ev.PrevHash, &ev.Hash, &ev.Signature, &ev.SignerId, &ev.Ts, &metaBytes, &ev.HashScheme); err != nil {
+			return nil, fmt.Errorf("scan audit feed row: %w", err)
+		}
+		if ev.Seq != next && time.Since(ev.Ts) < seqGapGrace {
+			break
+		}
+		if len(payloadBytes) > 0 {
+			if err := json.Unmarshal(payloadBytes, &ev.Payload); err != nil {
+				ev.Payload = string(payloadBytes)
+			}
+		}
+		if len(metaBytes) > 0 && string(metaBytes) != "null" {
+			if err := json.Unmarshal(metaBytes, &ev.Metadata); err != nil {
+				ev.Metadata = string(metaBytes)
+			}
+		}
+		events = append(events, &ev)
+		next = ev.Seq + 1
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows err: %w", err)
+	}
+	return events, nil
+}
+
+// HeadSeq returns the highest seq in audit_events, or 0 when it is empty.
+func (p *PGStore) HeadSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	if err := p.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(seq), 0) FROM audit_events`).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("query audit head seq: %w", err)
+	}
+	return seq, nil
+}
+
+// ListenPG calls notify for every audit_events insert, from any replica, until ctx is
+// done. notify is also called after the listener reconnects, since notifications sent
+// while it was disconnected are lost.
+func ListenPG(ctx context.Context, dsn string, notify func()) error {
+	l := pq.NewListener(dsn, time.Second, 30*time.Second, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("[audit.feed] listener: %v", err)
+		}
+	})
+	defer l.Close()
+	if err := l.Listen(NotifyChannel); err != nil {
+		return fmt.Errorf("listen %s: %w", NotifyChannel, err)
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.Notify:
+			notify()
+		case <-ping.C:
+			go func() { _ = l.Ping() }()
+		}
+	}
+}
@@ -15,7 +15,8 @@ import (
 
 // PGStore persists audit and signature records into Postgres.
 type PGStore struct {
-	db *sql.DB
+	db        *sql.DB
+	retention *RetentionPolicy
 }
 
 // NewPGStore constructs a Postgres-backed store.
@@ -23,6 +24,12 @@ func NewPGStore(db *sql.DB) *PGStore {
 	return &PGStore{db: db}
 }
 
+// SetRetentionPolicy configures the policy used to populate sampled and
+// retention_expires_at for newly appended events. A nil policy keeps events forever.
+func (p *PGStore) SetRetentionPolicy(rp *RetentionPolicy) {
+	p.retention = rp
+}
+
 // Ping verifies connectivity to Postgres.
 func (p *PGStore) Ping(ctx context.Context) error {
 	return p.db.PingContext(ctx)
@@ -45,11 +52,16 @@ func (p *PGStore) InsertManifestSignature(ctx context.Context, ms *ManifestSigna
 	return err
 }
 
+// queryRower is the part of *sql.DB and *sql.Tx that appending an event needs.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // lastHash returns the latest hash from audit_events or empty string if none.
-func (p *PGStore) lastHash(ctx context.Context) (string, error) {
+func lastHash(ctx context.Context, db queryRower) (string, error) {
 	var h sql.NullString
 	q := `SELECT hash FROM audit_events ORDER BY ts DESC LIMIT 1`
-	if err := p.db.QueryRowContext(ctx, q).Scan(&h); err != nil {
+	if err := db.QueryRowContext(ctx, q).Scan(&h); err != nil {
 		if err == sql.ErrNoRows {
 			return "", nil
 		}
@@ -64,14 +76,21 @@ func (p *PGStore) lastHash(ctx context.Context) (string, error) {
 // AppendAuditEvent canonicalizes payload, computes hash (sha256(canonical||prevHashBytes)),
 // requests a signature from signer, and persists the event into Postgres.
 func (p *PGStore) AppendAuditEvent(ctx context.Context, ev *AuditEvent, s signer.Signer) error {
+	return p.appendAuditEvent(ctx, p.db, ev, s)
+}
+
+// appendAuditEvent appends ev through db, which may be a transaction the caller commits
+// together with the change ev records.
+func (p *PGStore) appendAuditEvent(ctx context.Context, db queryRower, ev *AuditEvent, s signer.Signer) error {
 	// Canonicalize payload
+	ev.HashScheme = HashSchemeJCS
 	canon, err := canonical.MarshalCanonical(ev.Payload)
 	if err != nil {
 		return fmt.Errorf("canonicalize payload: %w", err)
 	}
 
 	// Get prevHash
-	prev, err := p.lastHash(ctx)
+	prev, err := lastHash(ctx, db)
 	if err != nil {
 		return fmt.Errorf("fetch last hash: %w", err)
 	}
@@ -107,6 +126,13 @@ func (p *PGStore) AppendAuditEvent(ctx context.Context, ev *AuditEvent, s signer
 		ev.Ts = time.Now().UTC()
 	}
 
+	// Evaluate retention/sampling for this event type
+	sampled, expiresAt := p.retention.Evaluate(ev.EventType, ev.Ts)
+	var retentionExpiresAt sql.NullTime
+	if expiresAt != nil {
+		retentionExpiresAt = sql.NullTime{Time: *expiresAt, Valid: true}
+	}
+
 	// Marshal payload and metadata for JSONB insertion
 	payloadJSON, err := json.Marshal(ev.Payload)
 	if err != nil {
@@ -125,10 +151,11 @@ func (p *PGStore) AppendAuditEvent(ctx context.Context, ev *AuditEvent, s signer
 	// Insert into audit_events
 	q := `
 		INSERT INTO audit_events
-		  (id, event_type, payload, prev_hash, hash, signature, signer_id, ts, metadata)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		  (id, event_type, payload, prev_hash, hash, signature, signer_id, ts, metadata, sampled, retention_expires_at, hash_scheme)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+		RETURNING seq
 	`
-	_, err = p.db.ExecContext(ctx, q,
+	err = db.QueryRowContext(ctx, q,
 		ev.ID,
 		ev.EventType,
 		payloadJSON,
@@ -138,7 +165,10 @@ func (p *PGStore) AppendAuditEvent(ctx context.Context, ev *AuditEvent, s signer
 		ev.SignerId,
 		ev.Ts,
 		metadataJSON,
-	)
+		sampled,
+		retentionExpiresAt,
+		ev.HashScheme,
+	).Scan(&ev.Seq)
 	if err != nil {
 		return fmt.Errorf("insert audit_event: %w", err)
 	}
@@ -148,15 +178,15 @@ func (p *PGStore) AppendAuditEvent(ctx context.Context, ev *AuditEvent, s signer
 
 // GetAuditEvent fetches an AuditEvent by id and unmarshals JSON fields.
 func (p *PGStore) GetAuditEvent(ctx context.Context, id string) (*AuditEvent, error) {
-	q := `SELECT id, event_type, payload, prev_hash, hash, signature, signer_id, ts, metadata FROM audit_events WHERE id=$1`
+	q := `SELECT id, event_type, payload, prev_hash, hash, signature, signer_id, ts, metadata, hash_scheme FROM audit_events WHERE id=$1`
 	row := p.db.QueryRowContext(ctx, q, id)
 
 	var (
-		idv, eventType, prevHash, hashStr, signature, signerId string
-		payloadBytes, metaBytes                                []byte
-		ts                                                     time.Time
+		idv, eventType, prevHash, hashStr, signature, signerId, scheme string
+		payloadBytes, metaBytes                                        []byte
+		ts                                                             time.Time
 	)
-	if err := row.Scan(&idv, &eventType, &payloadBytes, &prevHash, &hashStr, &signature, &signerId, &ts, &metaBytes); err != nil {
+	if err := row.Scan(&idv, &eventType, &payloadBytes, &prevHash, &hashStr, &signature, &signerId, &ts, &metaBytes, &scheme); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -179,15 +209,16 @@ func (p *PGStore) GetAuditEvent(ctx context.Context, id string) (*AuditEvent, er
 	}
 
 	ev := &AuditEvent{
-		ID:        idv,
-		EventType: eventType,
-		Payload:   payload,
-		PrevHash:  prevHash,
-		Hash:      hashStr,
-		Signature: signature,
-		SignerId:  signerId,
-		Ts:        ts,
-		Metadata:  metadata,
+		ID:         idv,
+		EventType:  eventType,
+		Payload:    payload,
+		PrevHash:   prevHash,
+		Hash:       hashStr,
+		Signature:  signature,
+		SignerId:   signerId,
+		Ts:         ts,
+		Metadata:   metadata,
+		HashScheme: scheme,
 	}
 	return ev, nil
 }
@@ -217,7 +248,7 @@ func (p *PGStore) FetchPendingEventsForStreaming(ctx context.Context, batchSize
 	}()
 
 	q := `
-	SELECT id, event_type, payload, prev_hash, hash, signature, signer_id, ts, metadata
+	SELECT id, event_type, payload, prev_hash, hash, signature, signer_id, ts, metadata, hash_scheme
 	FROM audit_events
 	WHERE stream_status IN ('pending','retry')
 	ORDER BY ts ASC
@@ -234,11 +265,11 @@ func (p *PGStore) FetchPendingEventsForStreaming(ctx context.Context, batchSize
 	events := make([]*AuditEvent, 0)
 	for rows.Next() {
 		var (
-			idv, eventType, prevHash, hashStr, signature, signerId string
-			payloadBytes, metaBytes                                []byte
-			ts                                                     time.Time
+			idv, eventType, prevHash, hashStr, signature, signerId, scheme string
+			payloadBytes, metaBytes                                        []byte
+			ts                                                             time.Time
 		)
-		if err := rows.Scan(&idv, &eventType, &payloadBytes, &prevHash, &hashStr, &signature, &signerId, &ts, &metaBytes); err != nil {
+		if err := rows.Scan(&idv, &eventType, &payloadBytes, &prevHash, &hashStr, &signature, &signerId, &ts, &metaBytes, &scheme); err != nil {
 			return nil, fmt.Errorf("scan pending row: %w", err)
 		}
 
@@ -257,15 +288,16 @@ func (p *PGStore) FetchPendingEventsForStreaming(ctx context.Context, batchSize
 		}
 
 		ev := &AuditEvent{
-			ID:        idv,
-			EventType: eventType,
-			Payload:   payload,
-			PrevHash:  prevHash,
-			Hash:      hashStr,
-			Signature: signature,
-			SignerId:  signerId,
-			Ts:        ts,
-			Metadata:  metadata,
+			ID:         idv,
+			EventType:  eventType,
+			Payload:    payload,
+			PrevHash:   prevHash,
+			Hash:       hashStr,
+			Signature:  signature,
+			SignerId:   signerId,
+			Ts:         ts,
+			Metadata:   metadata,
+			HashScheme: scheme,
 		}
 		events = append(events, ev)
 		ids = append(ids, idv)
@@ -306,6 +338,20 @@ func (p *PGStore) FetchPendingEventsForStreaming(ctx context.Context, batchSize
 	return events, nil
 }
 
+// StreamBacklog counts events waiting to be streamed (pending or retry) and events that
+// exhausted their stream attempts (failed, i.e. dead letters).
+func (p *PGStore) StreamBacklog(ctx context.Context) (pending, failed int64, err error) {
+	q := `
+		SELECT count(*) FILTER (WHERE stream_status IN ('pending','retry')),
+		       count(*) FILTER (WHERE stream_status = 'failed')
+		FROM audit_events
+	`
+	if err := p.db.QueryRowContext(ctx, q).Scan(&pending, &failed); err != nil {
+		return 0, 0, fmt.Errorf("stream backlog: %w", err)
+	}
+	return pending, failed, nil
+}
+
 // MarkEventStreamResult records the outcome of streaming/archival for an event.
 // - eventID: id of the audit_event row.
 // - archivedKey: optional S3 object key (sql.NullString). If valid and success==true this will be persisted.
@@ -0,0 +1,245 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+)
+
+// PrunerConfig configures the retention enforcement job.
+type PrunerConfig struct {
+	// BatchSize bounds how many expired events are pruned per pass.
+	BatchSize int
+
+	// Interval between passes.
+	Interval time.Duration
+}
+
+// Pruner deletes audit events whose retention has expired. Only events that were
+// archived (s3_object_key present, stream_status='complete') are eligible, and the
+// current chain head is never pruned. Every contiguous pruned range is replaced by a
+// signed AuditCheckpoint so VerifyChain can still verify the remaining chain.
+type Pruner struct {
+	store  *PGStore
+	signer signer.Signer
+	cfg    PrunerConfig
+}
+
+// NewPruner constructs a Pruner. If cfg fields are zero, sensible defaults are used.
+func NewPruner(store *PGStore, s signer.Signer, cfg PrunerConfig) *Pruner {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+	return &Pruner{store: store, signer: s, cfg: cfg}
+}
+
+// pruneCandidate is an expired, archived audit_events row.
+type pruneCandidate struct {
+	id         string
+	prevHash   string
+	hash       string
+	ts         time.Time
+	archiveKey string
+}
+
+// Run prunes expired events every Interval until ctx is cancelled.
+func (p *Pruner) Run(ctx context.Context) error {
+	log.Printf("[audit.pruner] starting (batch=%d, interval=%s)", p.cfg.BatchSize, p.cfg.Interval)
+	defer log.Printf("[audit.pruner] stopped")
+
+	for {
+		n, cps, err := p.PruneOnce(ctx)
+		if err != nil {
+			log.Printf("[audit.pruner] prune: %v", err)
+		} else if n > 0 {
+			log.Printf("[audit.pruner] pruned %d events into %d checkpoints", n, len(cps))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.cfg.Interval):
+		}
+	}
+}
+
+// PruneOnce deletes up to BatchSize expired events, writing one signed checkpoint per
+// contiguous range, and records the deletion as an "audit.retention.pruned" event in
+// the same transaction.
+// It returns the number of deleted events and the checkpoints written.
+func (p *Pruner) PruneOnce(ctx context.Context) (int, []*AuditCheckpoint, error) {
+	db := p.store.db
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	q := `
+	SELECT id, prev_hash, hash, ts, s3_object_key
+	FROM audit_events
+	WHERE retention_expires_at IS NOT NULL
+	  AND retention_expires_at < now()
+	  AND s3_object_key IS NOT NULL
+	  AND stream_status = 'complete'
+	  AND id <> (SELECT id FROM audit_events ORDER BY ts DESC LIMIT 1)
+	ORDER BY ts ASC
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.QueryContext(ctx, q, p.cfg.BatchSize)
+	if err != nil {
+		return 0, nil, fmt.Errorf("select expired events: %w", err)
+	}
+	cands := make([]pruneCandidate, 0)
+	for rows.Next() {
+		var (
+			c        pruneCandidate
+			prevHash sql.NullString
+		)
+		if err := rows.Scan(&c.id, &prevHash, &c.hash, &c.ts, &c.archiveKey); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("scan expired row: %w", err)
+		}
+		c.prevHash = prevHash.String
+		cands = append(cands, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("rows err: %w", err)
+	}
+
+	if len(cands) == 0 {
+		if err := tx.Commit(); err != nil {
+			return 0, nil, fmt.Errorf("commit empty select: %w", err)
+		}
+		tx = nil
+		return 0, nil, nil
+	}
+
+	now := time.Now().UTC()
+	checkpoints := make([]*AuditCheckpoint, 0)
+	for _, run := range groupPruneRuns(cands) {
+		cp, err := p.newCheckpoint(run, now)
+		if err != nil {
+			return 0, nil, err
+		}
+		keysJSON, err := json.Marshal(cp.ArchiveKeys)
+		if err != nil {
+			return 0, nil, fmt.Errorf("marshal archive keys: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO audit_checkpoints
+			  (id, first_event_id, last_event_id, first_ts, last_ts, event_count,
+			   prev_hash, last_hash, archive_keys, hash, signature, signer_id, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		`, cp.ID, cp.FirstEventID, cp.LastEventID, cp.FirstTs, cp.LastTs, cp.EventCount,
+			cp.PrevHash, cp.LastHash, keysJSON, cp.Hash, cp.Signature, cp.SignerId, cp.CreatedAt)
+		if err != nil {
+			return 0, nil, fmt.Errorf("insert checkpoint: %w", err)
+		}
+		// Delete one-by-one to avoid depending on driver-specific array helpers.
+		for _, c := range run {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM audit_events WHERE id = $1`, c.id); err != nil {
+				return 0, nil, fmt.Errorf("delete event %s: %w", c.id, err)
+			}
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+
+	// Deletions must themselves be audited, in the same transaction so events are never
+	// gone without a record of it.
+	ranges := make([]interface{}, 0, len(checkpoints))
+	for _, cp := range checkpoints {
+		ranges = append(ranges, map[string]interface{}{
+			"checkpointId": cp.ID,
+			"firstEventId": cp.FirstEventID,
+			"lastEventId":  cp.LastEventID,
+			"eventCount":   cp.EventCount,
+			"lastHash":     cp.LastHash,
+		})
+	}
+	ev := &AuditEvent{
+		EventType: "audit.retention.pruned",
+		Payload: map[string]interface{}{
+			"eventCount":  len(cands),
+			"checkpoints": ranges,
+		},
+		Ts: now,
+	}
+	if err := p.store.appendAuditEvent(ctx, tx, ev, p.signer); err != nil {
+		return 0, nil, fmt.Errorf("append prune audit event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("commit prune: %w", err)
+	}
+	tx = nil
+
+	return len(cands), checkpoints, nil
+}
+
+// newCheckpoint builds and signs the checkpoint covering run.
+func (p *Pruner) newCheckpoint(run []pruneCandidate, now time.Time) (*AuditCheckpoint, error) {
+	first, last := run[0], run[len(run)-1]
+	cp := &AuditCheckpoint{
+		ID:           NewUUID(),
+		FirstEventID: first.id,
+		LastEventID:  last.id,
+		FirstTs:      first.ts.UTC().Truncate(time.Microsecond),
+		LastTs:       last.ts.UTC().Truncate(time.Microsecond),
+		EventCount:   len(run),
+		PrevHash:     first.prevHash,
+		LastHash:     last.hash,
+		ArchiveKeys:  make([]string, 0, len(run)),
+		CreatedAt:    now.Truncate(time.Microsecond),
+	}
+	for _, c := range run {
+		cp.ArchiveKeys = append(cp.ArchiveKeys, c.archiveKey)
+	}
+	hash, err := cp.ComputeHash()
+	if err != nil {
+		return nil, err
+	}
+	sig, signerId, err := p.signer.Sign(hash)
+	if err != nil {
+		return nil, fmt.Errorf("sign checkpoint: %w", err)
+	}
+	cp.Hash = hex.EncodeToString(hash)
+	cp.Signature = base64.StdEncoding.EncodeToString(sig)
+	cp.SignerId = signerId
+	return cp, nil
+}
+
+// groupPruneRuns splits ts-ordered candidates into runs that are contiguous in the
+// hash chain, i.e. each event's prevHash is the hash of the preceding event in the run.
+// A kept event between two candidates breaks the linkage and starts a new run.
+func groupPruneRuns(cands []pruneCandidate) [][]pruneCandidate {
+	var runs [][]pruneCandidate
+	var cur []pruneCandidate
+	for _, c := range cands {
+		if len(cur) > 0 && c.prevHash != cur[len(cur)-1].hash {
+			runs = append(runs, cur)
+			cur = nil
+		}
+		cur = append(cur, c)
+	}
+	if len(cur) > 0 {
+		runs = append(runs, cur)
+	}
+	return runs
+}
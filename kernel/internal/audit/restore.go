@@ -0,0 +1,247 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/keys"
+)
+
+// RestoreOptions controls Restore.
+type RestoreOptions struct {
+	// Force restores every archived event even past the first broken link.
+	Force bool
+
+	// DryRun verifies the archive and reports without writing to Postgres.
+	DryRun bool
+}
+
+// RestoreIssue describes a gap, fork or tampered event found while replaying an archive.
+type RestoreIssue struct {
+	Index   int    `json:"index"`
+	EventID string `json:"eventId,omitempty"`
+	Kind    string `json:"kind"` // gap|fork|hash_mismatch|signature|duplicate
+	Detail  string `json:"detail"`
+}
+
+// RestoreReport summarizes a restore run.
+type RestoreReport struct {
+	Archived int            `json:"archived"`
+	Verified int            `json:"verified"`
+	Restored int            `json:"restored"`
+	HeadHash string         `json:"headHash,omitempty"`
+	Broken   *RestoreIssue  `json:"firstBroken,omitempty"`
+	Issues   []RestoreIssue `json:"issues,omitempty"`
+	DryRun   bool           `json:"dryRun,omitempty"`
+	Forced   bool           `json:"forced,omitempty"`
+}
+
+// ErrTableNotEmpty is returned when Restore targets a non-empty audit_events table.
+var ErrTableNotEmpty = errors.New("audit_events is not empty")
+
+// Restore replays archived events into an empty audit_events table. Events are ordered by
+// following prevHash links from the genesis event; each event's hash and signature are
+// verified against reg. Without opts.Force only the verified prefix up to the first broken
+// link is inserted. Restored rows are marked stream_status='complete' so the streamer does
+// not re-publish them.
+func Restore(ctx context.Context, db *sql.DB, reg *keys.Registry, archived []ArchivedEvent, opts RestoreOptions) (*RestoreReport, error) {
+	if db == nil && !opts.DryRun {
+		return nil, errors.New("db is nil")
+	}
+	if reg == nil {
+		return nil, errors.New("key registry is nil")
+	}
+
+	report := &RestoreReport{Archived: len(archived), DryRun: opts.DryRun, Forced: opts.Force}
+	ordered, issues := orderArchivedChain(archived)
+	report.Issues = append(report.Issues, issues...)
+
+	// Verify in chain order; remember the first broken position.
+	brokenAt := -1
+	prev := ""
+	for i, ae := range ordered {
+		ev := ae.Event
+		var issue *RestoreIssue
+		if ev.PrevHash != prev {
+			issue = &RestoreIssue{Index: i, EventID: ev.ID, Kind: "gap",
+				Detail: fmt.Sprintf("prevHash=%s does not follow %q", ev.PrevHash, prev)}
+		} else if err := verifyArchivedEvent(reg, ev); err != nil {
+			kind := "signature"
+			if errors.Is(err, errHashMismatch) {
+				kind = "hash_mismatch"
+			}
+			issue = &RestoreIssue{Index: i, EventID: ev.ID, Kind: kind, Detail: err.Error()}
+		}
+		if issue != nil {
+			report.Issues = append(report.Issues, *issue)
+			if brokenAt < 0 {
+				brokenAt = i
+				report.Broken = issue
+			}
+		} else if brokenAt < 0 {
+			report.Verified++
+		}
+		prev = ev.Hash
+	}
+
+	toRestore := ordered
+	if brokenAt >= 0 && !opts.Force {
+		toRestore = ordered[:brokenAt]
+	}
+	if len(toRestore) > 0 {
+		report.HeadHash = toRestore[len(toRestore)-1].Event.Hash
+	}
+	if opts.DryRun || len(toRestore) == 0 {
+		return report, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return report, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM audit_events`).Scan(&count); err != nil {
+		return report, fmt.Errorf("count audit_events: %w", err)
+	}
+	if count > 0 {
+		return report, fmt.Errorf("%w (%d rows)", ErrTableNotEmpty, count)
+	}
+
+	for _, ae := range toRestore {
+		ev := ae.Event
+		payloadJSON, err := json.Marshal(ev.Payload)
+		if err != nil {
+			return report, fmt.Errorf("marshal payload for %s: %w", ev.ID, err)
+		}
+		metadataJSON, err := json.Marshal(ev.Metadata)
+		if err != nil {
+			return report, fmt.Errorf("marshal metadata for %s: %w", ev.ID, err)
+		}
+		var key sql.NullString
+		if ae.Key != "" {
+			key = sql.NullString{String: ae.Key, Valid: true}
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO audit_events
+			  (id, event_type, payload, prev_hash, hash, signature, signer_id, ts, metadata,
+			   s3_object_key, s3_archived_at, stream_status, hash_scheme)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10, CASE WHEN $10::text IS NULL THEN NULL ELSE now() END, 'complete', $11)
+		`, ev.ID, ev.EventType, payloadJSON, ev.PrevHash, ev.Hash, ev.Signature, ev.SignerId, ev.Ts, metadataJSON, key, hashScheme(ev))
+		if err != nil {
+			return report, fmt.Errorf("insert audit_event %s: %w", ev.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("commit restore: %w", err)
+	}
+	tx = nil
+	report.Restored = len(toRestore)
+	return report, nil
+}
+
+var errHashMismatch = errors.New("hash mismatch")
+
+// hashScheme returns the scheme an archived event was hashed under.
+func hashScheme(ev *AuditEvent) string {
+	if ev.HashScheme == "" {
+		return HashSchemeLegacy
+	}
+	return ev.HashScheme
+}
+
+// verifyArchivedEvent recomputes hash = SHA256(canonical(payload) || prevHashBytes) under
+// the event's hash scheme and verifies the Ed25519 signature over it.
+func verifyArchivedEvent(reg *keys.Registry, ev *AuditEvent) error {
+	canon, err := CanonicalPayload(ev.HashScheme, ev.Payload)
+	if err != nil {
+		return fmt.Errorf("canonicalize payload: %w", err)
+	}
+	concat := append([]byte{}, canon...)
+	if ev.PrevHash != "" {
+		prevBytes, err := hex.DecodeString(ev.PrevHash)
+		if err != nil {
+			return fmt.Errorf("decode prevHash: %w", err)
+		}
+		concat = append(concat, prevBytes...)
+	}
+	sum := HashBytes(concat)
+	if computed := hex.EncodeToString(sum); computed != ev.Hash {
+		return fmt.Errorf("%w: computed=%s stored=%s", errHashMismatch, computed, ev.Hash)
+	}
+	return verifySignature(reg, ev.SignerId, sum, ev.Signature)
+}
+
+// orderArchivedChain orders archived events by following prevHash links from the genesis
+// event (empty prevHash). Duplicate copies of the same event are dropped. When a segment
+// ends before every event is consumed, a gap is reported and ordering resumes from the
+// oldest remaining event so forced restores still see every event.
+func orderArchivedChain(archived []ArchivedEvent) ([]ArchivedEvent, []RestoreIssue) {
+	var issues []RestoreIssue
+
+	// dedupe by id (Kafka may hold retried produces of the same event)
+	idx := make(map[string]int, len(archived))
+	uniq := make([]ArchivedEvent, 0, len(archived))
+	for _, ae := range archived {
+		if i, ok := idx[ae.Event.ID]; ok {
+			if uniq[i].Event.Hash != ae.Event.Hash {
+				issues = append(issues, RestoreIssue{Index: -1, EventID: ae.Event.ID, Kind: "duplicate",
+					Detail: fmt.Sprintf("conflicting archived copies (hash %s vs %s)", uniq[i].Event.Hash, ae.Event.Hash)})
+			}
+			if uniq[i].Key == "" {
+				uniq[i].Key = ae.Key
+			}
+			continue
+		}
+		idx[ae.Event.ID] = len(uniq)
+		uniq = append(uniq, ae)
+	}
+
+	sort.SliceStable(uniq, func(i, j int) bool { return uniq[i].Event.Ts.Before(uniq[j].Event.Ts) })
+	byPrev := make(map[string][]int)
+	for i, ae := range uniq {
+		byPrev[ae.Event.PrevHash] = append(byPrev[ae.Event.PrevHash], i)
+	}
+
+	used := make([]bool, len(uniq))
+	ordered := make([]ArchivedEvent, 0, len(uniq))
+	cur := ""
+	for len(ordered) < len(uniq) {
+		next := -1
+		var candidates []int
+		for _, i := range byPrev[cur] {
+			if !used[i] {
+				candidates = append(candidates, i)
+			}
+		}
+		if len(candidates) > 0 {
+			next = candidates[0]
+			if len(candidates) > 1 {
+				issues = append(issues, RestoreIssue{Index: len(ordered), EventID: uniq[next].Event.ID, Kind: "fork",
+					Detail: fmt.Sprintf("%d archived events share prevHash %q", len(candidates), cur)})
+			}
+		} else {
+			for i := range uniq {
+				if !used[i] {
+					next = i
+					break
+				}
+			}
+		}
+		used[next] = true
+		ordered = append(ordered, uniq[next])
+		cur = uniq[next].Event.Hash
+	}
+	return ordered, issues
+}
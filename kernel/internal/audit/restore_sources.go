@@ -0,0 +1,220 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/segmentio/kafka-go"
+)
+
+// ArchivedEvent is an audit event read back from an archive, together with the
+// archive location it came from (S3 object key or relative file path; empty for Kafka).
+type ArchivedEvent struct {
+	Event *AuditEvent
+	Key   string
+}
+
+// ArchiveSource reads archived canonical audit envelopes for restore.
+type ArchiveSource interface {
+	ReadEvents(ctx context.Context) ([]ArchivedEvent, error)
+}
+
+// ParseArchivedEnvelope decodes a canonical envelope as written by S3Archiver and the
+// streamer ({ id, eventType, payload, prevHash, hash, signature, signerId, ts, metadata }).
+// Numbers are decoded as json.Number so the payload re-canonicalizes to the same bytes.
+func ParseArchivedEnvelope(b []byte) (*AuditEvent, error) {
+	var ev AuditEvent
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&ev); err != nil {
+		return nil, fmt.Errorf("decode envelope: %w", err)
+	}
+	if ev.ID == "" || ev.Hash == "" {
+		return nil, fmt.Errorf("envelope missing id or hash")
+	}
+	return &ev, nil
+}
+
+// DirArchiveSource reads envelopes from a local directory tree (an S3 sync of the
+// archive, or a FileStore directory). FileStore's manifest_signature_*.json files are
+// skipped; every other *.json file must decode as an audit envelope, and one that does
+// not fails the read, since it may be a truncated or hand-edited event.
+type DirArchiveSource struct {
+	Dir string
+}
+
+// ReadEvents implements ArchiveSource.
+func (d *DirArchiveSource) ReadEvents(ctx context.Context) ([]ArchivedEvent, error) {
+	out := make([]ArchivedEvent, 0)
+	err := filepath.WalkDir(d.Dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(p, ".json") || strings.HasPrefix(entry.Name(), "manifest_signature_") {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", p, err)
+		}
+		ev, err := ParseArchivedEnvelope(b)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		rel, _ := filepath.Rel(d.Dir, p)
+		out = append(out, ArchivedEvent{Event: ev, Key: filepath.ToSlash(rel)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// S3ArchiveSource reads envelopes under s3://<bucket>/<prefix>/audit/.
+type S3ArchiveSource struct {
+	bucket string
+	prefix string
+	client *s3.Client
+}
+
+// NewS3ArchiveSource creates an S3ArchiveSource using the default AWS credential chain.
+func NewS3ArchiveSource(ctx context.Context, bucket, prefix string) (*S3ArchiveSource, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("bucket required")
+	}
+	cfg, err := awsConfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load aws config: %w", err)
+	}
+	return &S3ArchiveSource{bucket: bucket, prefix: prefix, client: s3.NewFromConfig(cfg)}, nil
+}
+
+// ReadEvents implements ArchiveSource.
+func (s *S3ArchiveSource) ReadEvents(ctx context.Context) ([]ArchivedEvent, error) {
+	listPrefix := path.Join(s.prefix, "audit") + "/"
+	pager := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(listPrefix),
+	})
+
+	out := make([]ArchivedEvent, 0)
+	for pager.HasMorePages() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list s3 objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			if !strings.HasSuffix(key, ".json") {
+				continue
+			}
+			resp, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+			if err != nil {
+				return nil, fmt.Errorf("get s3 object %s: %w", key, err)
+			}
+			b, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("read s3 object %s: %w", key, err)
+			}
+			ev, err := ParseArchivedEnvelope(b)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			out = append(out, ArchivedEvent{Event: ev, Key: key})
+		}
+	}
+	return out, nil
+}
+
+// KafkaArchiveSource reads every message currently on the audit topic, across all
+// partitions, from the first retained offset up to the high watermark.
+type KafkaArchiveSource struct {
+	Brokers []string
+	Topic   string
+}
+
+// ReadEvents implements ArchiveSource.
+func (k *KafkaArchiveSource) ReadEvents(ctx context.Context) ([]ArchivedEvent, error) {
+	if len(k.Brokers) == 0 || k.Topic == "" {
+		return nil, fmt.Errorf("kafka: brokers and topic required")
+	}
+	conn, err := kafka.DialContext(ctx, "tcp", k.Brokers[0])
+	if err != nil {
+		return nil, fmt.Errorf("kafka dial: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(k.Topic)
+	_ = conn.Close()
+	if err != nil {
+		return nil, fmt.Errorf("kafka read partitions: %w", err)
+	}
+
+	out := make([]ArchivedEvent, 0)
+	for _, p := range partitions {
+		evs, err := k.readPartition(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, evs...)
+	}
+	return out, nil
+}
+
+func (k *KafkaArchiveSource) readPartition(ctx context.Context, partition int) ([]ArchivedEvent, error) {
+	leader, err := kafka.DialLeader(ctx, "tcp", k.Brokers[0], k.Topic, partition)
+	if err != nil {
+		return nil, fmt.Errorf("kafka dial leader (partition %d): %w", partition, err)
+	}
+	first, lerr := leader.ReadFirstOffset()
+	last, rerr := leader.ReadLastOffset()
+	_ = leader.Close()
+	if lerr != nil || rerr != nil {
+		return nil, fmt.Errorf("kafka read offsets (partition %d): %v %v", partition, lerr, rerr)
+	}
+	if last <= first {
+		return nil, nil
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   k.Brokers,
+		Topic:     k.Topic,
+		Partition: partition,
+		MinBytes:  1,
+		MaxBytes:  10e6,
+	})
+	defer r.Close()
+	if err := r.SetOffset(first); err != nil {
+		return nil, fmt.Errorf("kafka set offset (partition %d): %w", partition, err)
+	}
+
+	out := make([]ArchivedEvent, 0, last-first)
+	for {
+		msg, err := r.ReadMessage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("kafka read (partition %d): %w", partition, err)
+		}
+		ev, err := ParseArchivedEnvelope(msg.Value)
+		if err != nil {
+			return nil, fmt.Errorf("partition %d offset %d: %w", partition, msg.Offset, err)
+		}
+		out = append(out, ArchivedEvent{Event: ev})
+		if msg.Offset >= last-1 {
+			return out, nil
+		}
+	}
+}
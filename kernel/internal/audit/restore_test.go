@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/keys"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+)
+
+// writeArchive appends n events through a FileStore, whose audit_<id>.json files use the
+// same envelope fields as the S3 archive.
+func writeArchive(t *testing.T, dir string, s signer.Signer, n int) []*AuditEvent {
+	t.Helper()
+	fstore := NewFileStore(dir)
+	base := time.Now().UTC()
+	out := make([]*AuditEvent, 0, n)
+	for i := 0; i < n; i++ {
+		ev := &AuditEvent{
+			EventType: "restore.test",
+			Payload:   map[string]interface{}{"seq": string(rune('a' + i))},
+			Ts:        base.Add(time.Duration(i) * time.Second),
+		}
+		if err := fstore.AppendAuditEvent(context.Background(), ev, s); err != nil {
+			t.Fatalf("AppendAuditEvent: %v", err)
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+func TestRestoreDryRunVerifiesArchive(t *testing.T) {
+	dir := t.TempDir()
+	s := signer.NewLocalSigner("restore-signer")
+	reg := keys.NewRegistry()
+	reg.AddSigner("restore-signer", s.PublicKey(), "Ed25519")
+	events := writeArchive(t, dir, s, 3)
+
+	archived, err := (&DirArchiveSource{Dir: dir}).ReadEvents(context.Background())
+	if err != nil {
+		t.Fatalf("ReadEvents: %v", err)
+	}
+	// Shuffle order to make sure restore orders by chain linkage.
+	archived[0], archived[2] = archived[2], archived[0]
+
+	report, err := Restore(context.Background(), nil, reg, archived, RestoreOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if report.Verified != 3 || report.Broken != nil {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if report.HeadHash != events[2].Hash {
+		t.Fatalf("head hash: want %s got %s", events[2].Hash, report.HeadHash)
+	}
+}
+
+func TestRestoreStopsAtFirstBrokenLink(t *testing.T) {
+	dir := t.TempDir()
+	s := signer.NewLocalSigner("restore-signer")
+	reg := keys.NewRegistry()
+	reg.AddSigner("restore-signer", s.PublicKey(), "Ed25519")
+	events := writeArchive(t, dir, s, 3)
+
+	// Tamper with the second event's payload.
+	path := filepath.Join(dir, "audit_"+events[1].ID+".json")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read archive file: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(string(b), `"seq": "b"`, `"seq": "tampered"`, 1)), 0o644); err != nil {
+		t.Fatalf("write archive file: %v", err)
+	}
+
+	archived, err := (&DirArchiveSource{Dir: dir}).ReadEvents(context.Background())
+	if err != nil {
+		t.Fatalf("ReadEvents: %v", err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT count").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO audit_events").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	report, err := Restore(context.Background(), db, reg, archived, RestoreOptions{})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if report.Restored != 1 || report.Broken == nil || report.Broken.EventID != events[1].ID || report.Broken.Kind != "hash_mismatch" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDirArchiveSourceRefusesStrayJSON(t *testing.T) {
+	dir := t.TempDir()
+	s := signer.NewLocalSigner("restore-signer")
+	writeArchive(t, dir, s, 2)
+	if err := os.WriteFile(filepath.Join(dir, "manifest_signature_m1.json"), []byte(`{"manifestId":"m1"}`), 0o644); err != nil {
+		t.Fatalf("write manifest signature: %v", err)
+	}
+
+	archived, err := (&DirArchiveSource{Dir: dir}).ReadEvents(context.Background())
+	if err != nil || len(archived) != 2 {
+		t.Fatalf("manifest signatures should be skipped: %d events, %v", len(archived), err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "notes.json"), []byte(`{"comment":"not an event"}`), 0o644); err != nil {
+		t.Fatalf("write stray file: %v", err)
+	}
+	if _, err := (&DirArchiveSource{Dir: dir}).ReadEvents(context.Background()); err == nil || !strings.Contains(err.Error(), "notes.json") {
+		t.Fatalf("expected stray JSON to fail the read, got %v", err)
+	}
+}
+
+func TestRestoreRefusesNonEmptyTable(t *testing.T) {
+	dir := t.TempDir()
+	s := signer.NewLocalSigner("restore-signer")
+	reg := keys.NewRegistry()
+	reg.AddSigner("restore-signer", s.PublicKey(), "Ed25519")
+	writeArchive(t, dir, s, 1)
+
+	archived, err := (&DirArchiveSource{Dir: dir}).ReadEvents(context.Background())
+	if err != nil {
+		t.Fatalf("ReadEvents: %v", err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT count").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	mock.ExpectRollback()
+
+	if _, err := Restore(context.Background(), db, reg, archived, RestoreOptions{}); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Fatalf("expected ErrTableNotEmpty, got %v", err)
+	}
+}
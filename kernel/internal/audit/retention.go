@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionRule controls how long events of a given eventType are kept in Postgres.
+type RetentionRule struct {
+	// Retention is how long the row is kept after its ts. Zero means keep forever.
+	Retention time.Duration
+
+	// SampleRate is the fraction (0..1] of events kept for the full Retention.
+	// Selected events are marked sampled=true; the others keep sampled=false and
+	// expire immediately, so the pruner removes them as soon as they have been
+	// archived. Zero (or >= 1) disables sampling for the event type.
+	SampleRate float64
+}
+
+// RetentionPolicy maps eventTypes to retention rules. It is evaluated once per event
+// at append time and the result is persisted in audit_events.sampled and
+// audit_events.retention_expires_at.
+type RetentionPolicy struct {
+	// Default applies to event types without an explicit rule.
+	Default RetentionRule
+
+	// Rules holds per-eventType overrides.
+	Rules map[string]RetentionRule
+
+	// Random returns a value in [0,1). Defaults to math/rand; tests may override it.
+	Random func() float64
+}
+
+// Evaluate returns the sampled flag and expiry for an event of eventType created at ts.
+// sampled is true only for an event a sampling rule selected to keep; events the rule
+// drops get sampled=false and an expiry of ts. A nil expiry means the event never
+// expires.
+func (p *RetentionPolicy) Evaluate(eventType string, ts time.Time) (sampled bool, expiresAt *time.Time) {
+	if p == nil {
+		return false, nil
+	}
+	rule, ok := p.Rules[eventType]
+	if !ok {
+		rule = p.Default
+	}
+
+	if rule.SampleRate > 0 && rule.SampleRate < 1 {
+		rnd := p.Random
+		if rnd == nil {
+			rnd = rand.Float64
+		}
+		if rnd() >= rule.SampleRate {
+			exp := ts
+			return false, &exp
+		}
+		sampled = true
+	}
+
+	if rule.Retention <= 0 {
+		return sampled, nil
+	}
+	exp := ts.Add(rule.Retention)
+	return sampled, &exp
+}
+
+// ParseRetentionRules parses a comma-separated list of per-eventType rules of the form
+//
+//	eventType:retention[:sampleRate]
+//
+// e.g. "agent.heartbeat:720h:0.1,system.healthcheck:24h". Retention uses
+// time.ParseDuration syntax; "0" keeps events forever.
+func ParseRetentionRules(spec string) (map[string]RetentionRule, error) {
+	rules := make(map[string]RetentionRule)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid retention rule %q", item)
+		}
+		var rule RetentionRule
+		if d := strings.TrimSpace(parts[1]); d != "0" {
+			dur, err := time.ParseDuration(d)
+			if err != nil {
+				return nil, fmt.Errorf("invalid retention for %s: %w", parts[0], err)
+			}
+			if dur < 0 {
+				return nil, fmt.Errorf("invalid retention for %s: negative duration", parts[0])
+			}
+			rule.Retention = dur
+		}
+		if len(parts) == 3 {
+			rate, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64)
+			if err != nil || rate < 0 || rate > 1 {
+				return nil, fmt.Errorf("invalid sample rate for %s: %q", parts[0], parts[2])
+			}
+			rule.SampleRate = rate
+		}
+		rules[strings.TrimSpace(parts[0])] = rule
+	}
+	return rules, nil
+}
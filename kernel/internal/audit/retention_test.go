@@ -0,0 +1,316 @@
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/canonical"
+	"github.com/ILLUVRSE/Main/kernel/internal/keys"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+)
+
+func TestRetentionPolicyEvaluate(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := &RetentionPolicy{
+		Default: RetentionRule{Retention: 90 * 24 * time.Hour},
+		Rules: map[string]RetentionRule{
+			"agent.heartbeat": {Retention: time.Hour, SampleRate: 0.1},
+			"manifest.signed": {},
+		},
+		Random: func() float64 { return 0.5 },
+	}
+
+	sampled, exp := p.Evaluate("eval.submitted", ts)
+	if sampled || exp == nil || !exp.Equal(ts.Add(90*24*time.Hour)) {
+		t.Fatalf("default rule: sampled=%v exp=%v", sampled, exp)
+	}
+
+	sampled, exp = p.Evaluate("manifest.signed", ts)
+	if sampled || exp != nil {
+		t.Fatalf("keep-forever rule: sampled=%v exp=%v", sampled, exp)
+	}
+
+	// 0.5 >= 0.1 so the heartbeat is dropped from the sample and expires immediately
+	sampled, exp = p.Evaluate("agent.heartbeat", ts)
+	if sampled || exp == nil || !exp.Equal(ts) {
+		t.Fatalf("sampled-out heartbeat: sampled=%v exp=%v", sampled, exp)
+	}
+
+	p.Random = func() float64 { return 0.05 }
+	sampled, exp = p.Evaluate("agent.heartbeat", ts)
+	if !sampled || exp == nil || !exp.Equal(ts.Add(time.Hour)) {
+		t.Fatalf("sampled-in heartbeat: sampled=%v exp=%v", sampled, exp)
+	}
+
+	var nilPolicy *RetentionPolicy
+	if sampled, exp := nilPolicy.Evaluate("x", ts); sampled || exp != nil {
+		t.Fatalf("nil policy should keep forever")
+	}
+}
+
+func TestParseRetentionRules(t *testing.T) {
+	rules, err := ParseRetentionRules("agent.heartbeat:720h:0.1, system.healthcheck:24h,manifest.signed:0")
+	if err != nil {
+		t.Fatalf("ParseRetentionRules: %v", err)
+	}
+	if got := rules["agent.heartbeat"]; got.Retention != 720*time.Hour || got.SampleRate != 0.1 {
+		t.Fatalf("agent.heartbeat rule: %+v", got)
+	}
+	if got := rules["system.healthcheck"]; got.Retention != 24*time.Hour || got.SampleRate != 0 {
+		t.Fatalf("system.healthcheck rule: %+v", got)
+	}
+	if got, ok := rules["manifest.signed"]; !ok || got.Retention != 0 {
+		t.Fatalf("manifest.signed rule: %+v", got)
+	}
+
+	for _, bad := range []string{"noretention", "x:abc", "x:1h:2", ":1h"} {
+		if _, err := ParseRetentionRules(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestGroupPruneRuns(t *testing.T) {
+	cands := []pruneCandidate{
+		{id: "a", prevHash: "", hash: "h1"},
+		{id: "b", prevHash: "h1", hash: "h2"},
+		// h3 is kept, so c starts a new run
+		{id: "c", prevHash: "h3", hash: "h4"},
+	}
+	runs := groupPruneRuns(cands)
+	if len(runs) != 2 || len(runs[0]) != 2 || len(runs[1]) != 1 || runs[1][0].id != "c" {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+}
+
+// chainEvent is a signed audit event row as VerifyChain reads it.
+type chainEvent struct {
+	id, prevHash, hash, signature, scheme string
+	payload                               []byte
+	ts                                    time.Time
+}
+
+func buildChain(t *testing.T, s signer.Signer, n int) []chainEvent {
+	t.Helper()
+	out := make([]chainEvent, 0, n)
+	prev := ""
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		payload := map[string]interface{}{"seq": string(rune('a' + i))}
+		ev := signChainEvent(t, s, prev, HashSchemeJCS, payload, base.Add(time.Duration(i)*time.Minute))
+		out = append(out, ev)
+		prev = ev.hash
+	}
+	return out
+}
+
+// signChainEvent hashes payload after prev under scheme and signs it.
+func signChainEvent(t *testing.T, s signer.Signer, prev, scheme string, payload map[string]interface{}, ts time.Time) chainEvent {
+	t.Helper()
+	canon, err := CanonicalPayload(scheme, payload)
+	if err != nil {
+		t.Fatalf("canonicalize: %v", err)
+	}
+	concat := append([]byte{}, canon...)
+	if prev != "" {
+		pb, _ := hex.DecodeString(prev)
+		concat = append(concat, pb...)
+	}
+	sum := HashBytes(concat)
+	sig, _, err := s.Sign(sum)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	pb, _ := json.Marshal(payload)
+	return chainEvent{
+		id:        NewUUID(),
+		prevHash:  prev,
+		hash:      hex.EncodeToString(sum),
+		signature: base64.StdEncoding.EncodeToString(sig),
+		scheme:    scheme,
+		payload:   pb,
+		ts:        ts,
+	}
+}
+
+func expectChain(mock sqlmock.Sqlmock, cps []*AuditCheckpoint, events []chainEvent, signerID string) {
+	cpRows := sqlmock.NewRows([]string{"id", "first_event_id", "last_event_id", "first_ts", "last_ts", "event_count",
+		"prev_hash", "last_hash", "archive_keys", "hash", "signature", "signer_id", "created_at"})
+	for _, cp := range cps {
+		kb, _ := json.Marshal(cp.ArchiveKeys)
+		cpRows.AddRow(cp.ID, cp.FirstEventID, cp.LastEventID, cp.FirstTs, cp.LastTs, cp.EventCount,
+			cp.PrevHash, cp.LastHash, kb, cp.Hash, cp.Signature, cp.SignerId, cp.CreatedAt)
+	}
+	mock.ExpectQuery("FROM audit_checkpoints").WillReturnRows(cpRows)
+
+	evRows := sqlmock.NewRows([]string{"id", "event_type", "payload", "prev_hash", "hash", "signature", "signer_id", "hash_scheme"})
+	for _, ev := range events {
+		evRows.AddRow(ev.id, "test.event", ev.payload, ev.prevHash, ev.hash, ev.signature, signerID, ev.scheme)
+	}
+	mock.ExpectQuery("FROM audit_events").WillReturnRows(evRows)
+}
+
+func TestVerifyChainAcrossHashSchemeUpgrade(t *testing.T) {
+	s := signer.NewLocalSigner("upgrade-signer")
+	reg := keys.NewRegistry()
+	reg.AddSigner("upgrade-signer", s.PublicKey(), "Ed25519")
+
+	// The legacy canonicalization HTML-escapes strings and JCS does not, so these
+	// payloads hash differently under the two schemes.
+	payload := map[string]interface{}{"note": "a<b & c", "score": 0.5}
+	legacy, _ := canonical.MarshalLegacy(payload)
+	jcs, _ := canonical.MarshalCanonical(payload)
+	if string(legacy) == string(jcs) {
+		t.Fatalf("payload does not distinguish the schemes: %s", jcs)
+	}
+
+	// Two events written before the JCS upgrade, then one after it.
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	e0 := signChainEvent(t, s, "", HashSchemeLegacy, payload, base)
+	e1 := signChainEvent(t, s, e0.hash, HashSchemeLegacy, payload, base.Add(time.Minute))
+	e2 := signChainEvent(t, s, e1.hash, HashSchemeJCS, payload, base.Add(2*time.Minute))
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectChain(mock, nil, []chainEvent{e0, e1, e2}, "upgrade-signer")
+	if err := VerifyChain(context.Background(), db, reg); err != nil {
+		t.Fatalf("VerifyChain across the upgrade: %v", err)
+	}
+
+	// Recomputing a legacy event's hash with JCS must not verify.
+	db2, mock2, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db2.Close()
+	relabeled := e0
+	relabeled.scheme = HashSchemeJCS
+	expectChain(mock2, nil, []chainEvent{relabeled, e1, e2}, "upgrade-signer")
+	if err := VerifyChain(context.Background(), db2, reg); err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+		t.Fatalf("expected a hash mismatch, got %v", err)
+	}
+}
+
+func TestPruneOnceAuditsInsideTransaction(t *testing.T) {
+	s := signer.NewLocalSigner("retention-signer")
+	chain := buildChain(t, s, 3)
+	expectPrune := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		rows := sqlmock.NewRows([]string{"id", "prev_hash", "hash", "ts", "s3_object_key"})
+		for i, e := range chain[:2] {
+			rows.AddRow(e.id, e.prevHash, e.hash, e.ts, fmt.Sprintf("audit/%d.json", i))
+		}
+		mock.ExpectQuery("SELECT id, prev_hash, hash, ts, s3_object_key").WillReturnRows(rows)
+		mock.ExpectExec("INSERT INTO audit_checkpoints").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM audit_events").WithArgs(chain[0].id).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM audit_events").WithArgs(chain[1].id).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT hash FROM audit_events").
+			WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow(chain[2].hash))
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectPrune(mock)
+	mock.ExpectQuery("INSERT INTO audit_events").
+		WithArgs(sqlmock.AnyArg(), "audit.retention.pruned", sqlmock.AnyArg(), chain[2].hash, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), HashSchemeJCS).
+		WillReturnRows(sqlmock.NewRows([]string{"seq"}).AddRow(4))
+	mock.ExpectCommit()
+	n, cps, err := NewPruner(NewPGStore(db), s, PrunerConfig{}).PruneOnce(context.Background())
+	if err != nil || n != 2 || len(cps) != 1 {
+		t.Fatalf("PruneOnce: %d %d %v", n, len(cps), err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// When the event cannot be recorded the deletion is rolled back.
+	db2, mock2, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db2.Close()
+	expectPrune(mock2)
+	mock2.ExpectQuery("INSERT INTO audit_events").WillReturnError(fmt.Errorf("disk full"))
+	mock2.ExpectRollback()
+	if n, _, err := NewPruner(NewPGStore(db2), s, PrunerConfig{}).PruneOnce(context.Background()); err == nil || n != 0 {
+		t.Fatalf("expected the prune to fail, got %d %v", n, err)
+	}
+	if err := mock2.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyChainBridgesPrunedRange(t *testing.T) {
+	s := signer.NewLocalSigner("retention-signer")
+	reg := keys.NewRegistry()
+	reg.AddSigner("retention-signer", s.PublicKey(), "Ed25519")
+
+	chain := buildChain(t, s, 5)
+	pruner := &Pruner{signer: s}
+	now := time.Now().UTC()
+
+	// prune the genesis event and events 2..3; keep 1 and 4
+	cpGenesis, err := pruner.newCheckpoint([]pruneCandidate{
+		{id: chain[0].id, prevHash: chain[0].prevHash, hash: chain[0].hash, ts: chain[0].ts, archiveKey: "audit/0.json"},
+	}, now)
+	if err != nil {
+		t.Fatalf("newCheckpoint: %v", err)
+	}
+	cpMiddle, err := pruner.newCheckpoint([]pruneCandidate{
+		{id: chain[2].id, prevHash: chain[2].prevHash, hash: chain[2].hash, ts: chain[2].ts, archiveKey: "audit/2.json"},
+		{id: chain[3].id, prevHash: chain[3].prevHash, hash: chain[3].hash, ts: chain[3].ts, archiveKey: "audit/3.json"},
+	}, now)
+	if err != nil {
+		t.Fatalf("newCheckpoint: %v", err)
+	}
+	kept := []chainEvent{chain[1], chain[4]}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+	expectChain(mock, []*AuditCheckpoint{cpGenesis, cpMiddle}, kept, "retention-signer")
+	if err := VerifyChain(context.Background(), db, reg); err != nil {
+		t.Fatalf("VerifyChain with checkpoints: %v", err)
+	}
+
+	// Without the middle checkpoint the gap must be reported.
+	db2, mock2, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db2.Close()
+	expectChain(mock2, []*AuditCheckpoint{cpGenesis}, kept, "retention-signer")
+	err = VerifyChain(context.Background(), db2, reg)
+	if err == nil || !strings.Contains(err.Error(), "chain break") {
+		t.Fatalf("expected chain break error, got %v", err)
+	}
+
+	// A tampered checkpoint must fail verification.
+	tampered := *cpMiddle
+	tampered.EventCount = 1
+	db3, mock3, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db3.Close()
+	expectChain(mock3, []*AuditCheckpoint{cpGenesis, &tampered}, kept, "retention-signer")
+	if err := VerifyChain(context.Background(), db3, reg); err == nil {
+		t.Fatalf("expected tampered checkpoint to fail verification")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 
+	"github.com/ILLUVRSE/Main/kernel/internal/canonical"
 	"github.com/ILLUVRSE/Main/kernel/internal/signer"
 )
 
@@ -24,6 +26,25 @@ type Store interface {
 	Ping(ctx context.Context) error
 }
 
+// Hash schemes: the canonicalization an audit event's hash was computed over.
+const (
+	HashSchemeLegacy = "legacy" // canonical.MarshalLegacy, events appended before JCS
+	HashSchemeJCS    = "jcs"    // canonical.MarshalCanonical (RFC 8785)
+)
+
+// CanonicalPayload canonicalizes an event payload under its hash scheme. An empty
+// scheme is legacy: only events from before the JCS switch lack one.
+func CanonicalPayload(scheme string, payload interface{}) ([]byte, error) {
+	switch scheme {
+	case HashSchemeJCS:
+		return canonical.MarshalCanonical(payload)
+	case HashSchemeLegacy, "":
+		return canonical.MarshalLegacy(payload)
+	default:
+		return nil, fmt.Errorf("unknown hash scheme %q", scheme)
+	}
+}
+
 // HashBytes computes the SHA-256 digest bytes for input data.
 func HashBytes(b []byte) []byte {
 	h := sha256.Sum256(b)
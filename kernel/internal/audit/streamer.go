@@ -30,6 +30,16 @@ type StreamerConfig struct {
 	MaxConcurrency int
 }
 
+// StreamObserver receives streamer progress for instrumentation (see
+// kernel/internal/telemetry).
+type StreamObserver interface {
+	// StreamAttempt is called once per processed event with the produce+archive outcome.
+	StreamAttempt(success bool)
+	// StreamBacklog reports rows waiting to stream (pending/retry) and rows that exhausted
+	// their attempts (failed).
+	StreamBacklog(pending, failed int64)
+}
+
 // Streamer implements a durable DB-first audit event streamer that:
 //   - selects pending audit_events using SELECT ... FOR UPDATE SKIP LOCKED
 //   - claims them (stream_status -> in_progress and increments attempts)
@@ -40,6 +50,7 @@ type Streamer struct {
 	producer Producer
 	archiver Archiver
 	cfg      StreamerConfig
+	observer StreamObserver
 	// internal
 	wg sync.WaitGroup
 }
@@ -63,6 +74,11 @@ func NewStreamer(store *PGStore, producer Producer, archiver Archiver, cfg Strea
 	}
 }
 
+// SetObserver installs a StreamObserver. Call before Run.
+func (s *Streamer) SetObserver(o StreamObserver) {
+	s.observer = o
+}
+
 // Run starts the streamer loop and blocks until ctx is cancelled. It's safe to run
 // in a goroutine if you want non-blocking behavior. The streamer will continue to
 // poll for pending work and process batches concurrently up to MaxConcurrency.
@@ -86,6 +102,14 @@ func (s *Streamer) Run(ctx context.Context) error {
 		default:
 		}
 
+		if s.observer != nil {
+			if pending, failed, err := s.store.StreamBacklog(ctx); err != nil {
+				log.Printf("[audit.streamer] backlog stats: %v", err)
+			} else {
+				s.observer.StreamBacklog(pending, failed)
+			}
+		}
+
 		events, err := s.store.FetchPendingEventsForStreaming(ctx, s.cfg.BatchSize)
 		if err != nil {
 			log.Printf("[audit.streamer] fetch pending: %v", err)
@@ -116,7 +140,11 @@ func (s *Streamer) Run(ctx context.Context) error {
 					<-sem
 					s.wg.Done()
 				}()
-				if err := s.processEvent(ctx, ev); err != nil {
+				err := s.processEvent(ctx, ev)
+				if s.observer != nil {
+					s.observer.StreamAttempt(err == nil)
+				}
+				if err != nil {
 					// processEvent already marks DB result; just log
 					log.Printf("[audit.streamer] process event %s error: %v", ev.ID, err)
 				}
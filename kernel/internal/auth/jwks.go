@@ -7,79 +7,256 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
-// JWKSCache fetches and caches a JWKS document. It provides thread-safe lookups and
-// automatically refreshes when the TTL expires or on cache miss.
+// ErrKeyNotFound is returned by GetKey when no key with the requested kid is known,
+// even after a (rate-limited) refetch.
+var ErrKeyNotFound = errors.New("key not found")
+
+// JWKSConfig configures a JWKSCache. Zero values take the defaults noted per field.
+type JWKSConfig struct {
+	// URL is the JWKS endpoint. Optional when File is set.
+	URL string
+	// File is a local JWKS document. Without URL it is the key source (air-gapped
+	// deployments) and is re-read on every refresh; with URL it only seeds the cache
+	// so tokens validate while the IdP is unreachable at startup.
+	File string
+	// TTL is the refresh interval when the response carries no Cache-Control max-age
+	// (default 300s).
+	TTL time.Duration
+	// MinRefreshInterval rate-limits refetches triggered by an unknown kid and floors
+	// max-age and retry intervals (default 10s).
+	MinRefreshInterval time.Duration
+	// MaxStaleness is how long the last good keyset keeps being served after its
+	// refresh interval passes while fetches fail (default 24h).
+	MaxStaleness time.Duration
+	// HTTPClient fetches URL (default: 5s timeout).
+	HTTPClient *http.Client
+}
+
+// JWKSCache fetches and caches a JWKS document. It provides thread-safe lookups,
+// refreshes when the keyset expires (TTL or the IdP's Cache-Control max-age, revalidated
+// with ETag/Last-Modified), refetches on an unknown kid at most once per
+// MinRefreshInterval, and keeps serving the last good keyset for up to MaxStaleness
+// while fetches fail. StartRefresher keeps the keyset fresh in the background.
 type JWKSCache struct {
-	url string
-	ttl time.Duration
+	url  string
+	file string
+	ttl  time.Duration
+
+	minRefresh   time.Duration
+	maxStaleness time.Duration
+	client       *http.Client
 
-	mu        sync.RWMutex
-	keys      map[string]crypto.PublicKey
-	lastFetch time.Time
-	lastErr   error
-	client    *http.Client
+	// refreshMu serializes fetches so concurrent kid misses share one request.
+	refreshMu   sync.Mutex
+	lastAttempt time.Time
+
+	mu           sync.RWMutex
+	keys         map[string]crypto.PublicKey
+	lastFetch    time.Time
+	expiresAt    time.Time
+	etag         string
+	lastModified string
+	lastErr      error
 }
 
-// NewJWKSCache constructs a JWKSCache, performs an initial fetch (best-effort),
-// and returns the instance. Callers may call Refresh() to force a refresh.
+// NewJWKSCache constructs a JWKSCache for jwksURL, performs an initial fetch
+// (best-effort), and returns the instance. Callers may call Refresh() to force a refresh.
 func NewJWKSCache(jwksURL string, ttl time.Duration) *JWKSCache {
-	if ttl <= 0 {
-		ttl = 300 * time.Second
+	j, err := NewJWKSCacheFromConfig(JWKSConfig{URL: jwksURL, TTL: ttl})
+	if err != nil {
+		log.Printf("[jwks] initial jwks fetch failed: %v", err)
+	}
+	return j
+}
+
+// NewJWKSCacheFromConfig constructs a JWKSCache and loads the initial keyset. A URL
+// fetch failure is logged and not returned (the cache retries later); a missing source
+// or an unreadable File is returned as an error, with the cache still usable.
+func NewJWKSCacheFromConfig(cfg JWKSConfig) (*JWKSCache, error) {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 300 * time.Second
+	}
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = 10 * time.Second
+	}
+	if cfg.MaxStaleness <= 0 {
+		cfg.MaxStaleness = 24 * time.Hour
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 5 * time.Second}
 	}
 	j := &JWKSCache{
-		url:    jwksURL,
-		ttl:    ttl,
-		keys:   make(map[string]crypto.PublicKey),
-		client: &http.Client{Timeout: 5 * time.Second},
+		url:          strings.TrimSpace(cfg.URL),
+		file:         strings.TrimSpace(cfg.File),
+		ttl:          cfg.TTL,
+		minRefresh:   cfg.MinRefreshInterval,
+		maxStaleness: cfg.MaxStaleness,
+		client:       cfg.HTTPClient,
+		keys:         make(map[string]crypto.PublicKey),
 	}
-	// best-effort initial fetch
-	if err := j.Refresh(); err != nil {
-		log.Printf("[jwks] initial jwks fetch failed: %v", err)
+	if j.url == "" && j.file == "" {
+		return j, errors.New("jwks: url or file required")
 	}
-	return j
+	if j.file != "" {
+		if err := j.loadFile(); err != nil {
+			j.setLastError(err)
+			return j, err
+		}
+	}
+	if j.url != "" {
+		if err := j.Refresh(); err != nil {
+			log.Printf("[jwks] initial jwks fetch failed: %v", err)
+		}
+	}
+	return j, nil
 }
 
-// Refresh forces a reload of the JWKS from the configured URL. It returns an error
-// if the fetch or parse fails. Success updates the in-memory map and timestamps.
+// Refresh forces a reload of the JWKS from the configured source. It returns an error
+// if the fetch or parse fails; the previous keyset is kept in that case.
 func (j *JWKSCache) Refresh() error {
-	if j.url == "" {
-		return errors.New("jwks url empty")
+	j.refreshMu.Lock()
+	defer j.refreshMu.Unlock()
+	return j.refreshLocked()
+}
+
+// refreshLocked fetches the keyset; the caller holds refreshMu.
+func (j *JWKSCache) refreshLocked() error {
+	j.lastAttempt = time.Now()
+	var err error
+	if j.url != "" {
+		err = j.fetchURL()
+	} else if j.file != "" {
+		err = j.loadFile()
+	} else {
+		err = errors.New("jwks url empty")
 	}
-	req, err := http.NewRequest("GET", j.url, nil)
 	if err != nil {
 		j.setLastError(err)
+	}
+	return err
+}
+
+func (j *JWKSCache) fetchURL() error {
+	req, err := http.NewRequest("GET", j.url, nil)
+	if err != nil {
 		return err
 	}
 	// small user-agent so remote servers have context
 	req.Header.Set("User-Agent", "ILLUVRSE-JWKS-Cache/1.0")
+	j.mu.RLock()
+	if j.etag != "" {
+		req.Header.Set("If-None-Match", j.etag)
+	}
+	if j.lastModified != "" {
+		req.Header.Set("If-Modified-Since", j.lastModified)
+	}
+	j.mu.RUnlock()
 
 	resp, err := j.client.Do(req)
 	if err != nil {
-		j.setLastError(err)
 		return err
 	}
 	defer resp.Body.Close()
 
+	now := time.Now().UTC()
+	interval := j.refreshInterval(resp.Header.Get("Cache-Control"))
+
+	if resp.StatusCode == http.StatusNotModified {
+		j.mu.Lock()
+		j.lastFetch = now
+		j.expiresAt = now.Add(interval)
+		j.lastErr = nil
+		j.mu.Unlock()
+		return nil
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		err := errors.New("jwks fetch returned status " + resp.Status)
-		j.setLastError(err)
+		return errors.New("jwks fetch returned status " + resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	newKeys, err := parseJWKS(body)
+	if err != nil {
 		return err
 	}
 
+	j.mu.Lock()
+	j.keys = newKeys
+	j.lastFetch = now
+	j.expiresAt = now.Add(interval)
+	j.etag = resp.Header.Get("ETag")
+	j.lastModified = resp.Header.Get("Last-Modified")
+	j.lastErr = nil
+	j.mu.Unlock()
+
+	log.Printf("[jwks] refreshed %d keys from %s (next refresh in %s)", len(newKeys), j.url, interval)
+	return nil
+}
+
+func (j *JWKSCache) loadFile() error {
+	body, err := os.ReadFile(j.file)
+	if err != nil {
+		return fmt.Errorf("jwks: read %s: %w", j.file, err)
+	}
+	newKeys, err := parseJWKS(body)
+	if err != nil {
+		return fmt.Errorf("jwks: parse %s: %w", j.file, err)
+	}
+	now := time.Now().UTC()
+	j.mu.Lock()
+	j.keys = newKeys
+	j.lastFetch = now
+	j.expiresAt = now.Add(j.ttl)
+	j.lastErr = nil
+	j.mu.Unlock()
+	log.Printf("[jwks] loaded %d keys from %s", len(newKeys), j.file)
+	return nil
+}
+
+// refreshInterval honours Cache-Control max-age (floored at the minimum refresh
+// interval; no-cache/no-store revalidate at that floor) and falls back to the TTL.
+func (j *JWKSCache) refreshInterval(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return j.minRefresh
+		case strings.HasPrefix(directive, "max-age="):
+			secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err != nil || secs < 0 {
+				continue
+			}
+			d := time.Duration(secs) * time.Second
+			if d < j.minRefresh {
+				d = j.minRefresh
+			}
+			return d
+		}
+	}
+	return j.ttl
+}
+
+// parseJWKS decodes the RSA keys of a JWKS document, skipping unusable entries.
+func parseJWKS(body []byte) (map[string]crypto.PublicKey, error) {
 	var doc struct {
 		Keys []map[string]interface{} `json:"keys"`
 	}
-	dec := json.NewDecoder(resp.Body)
-	if err := dec.Decode(&doc); err != nil {
-		j.setLastError(err)
-		return err
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
 	}
 
 	newKeys := make(map[string]crypto.PublicKey)
@@ -124,51 +301,120 @@ func (j *JWKSCache) Refresh() error {
 		}
 		newKeys[kid] = pub
 	}
-
-	j.mu.Lock()
-	j.keys = newKeys
-	j.lastFetch = time.Now().UTC()
-	j.lastErr = nil
-	j.mu.Unlock()
-
-	log.Printf("[jwks] refreshed %d keys from %s (ttl=%s)", len(newKeys), j.url, j.ttl)
-	return nil
+	return newKeys, nil
 }
 
-// GetKey returns the public key for the given kid. If the key isn't present and
-// the cache has expired, it triggers a Refresh and retries once.
+// GetKey returns the public key for the given kid. An expired keyset is refreshed
+// first (falling back to the stale keyset within MaxStaleness if the fetch fails);
+// an unknown kid triggers a refetch at most once per MinRefreshInterval.
 func (j *JWKSCache) GetKey(kid string) (crypto.PublicKey, error) {
-	// fast read lock
 	j.mu.RLock()
-	// if unexpired and key present, return
-	if time.Since(j.lastFetch) <= j.ttl {
-		if k, ok := j.keys[kid]; ok {
-			j.mu.RUnlock()
+	expired := time.Now().After(j.expiresAt)
+	k, ok := j.keys[kid]
+	j.mu.RUnlock()
+	if ok && !expired {
+		return k, nil
+	}
+
+	if expired {
+		j.refreshMu.Lock()
+		// another caller may have refreshed while we waited
+		if j.isExpired() {
+			if err := j.refreshLocked(); err != nil && !j.usable() {
+				j.refreshMu.Unlock()
+				return nil, err
+			}
+		}
+		j.refreshMu.Unlock()
+		if k, ok := j.lookup(kid); ok {
 			return k, nil
 		}
 	}
-	j.mu.RUnlock()
 
-	// If cache still fresh but key missing, try a best-effort background refresh with short timeout.
-	// We'll do an immediate refresh (synchronous) for correctness.
-	if err := j.Refresh(); err != nil {
-		// return best-known error
-		j.mu.RLock()
-		le := j.lastErr
-		j.mu.RUnlock()
-		if le != nil {
-			return nil, le
+	// unknown kid: the IdP may have rotated keys; refetch unless we did so recently.
+	j.refreshMu.Lock()
+	if time.Since(j.lastAttempt) >= j.minRefresh {
+		if err := j.refreshLocked(); err != nil {
+			log.Printf("[jwks] refetch for unknown kid=%s failed: %v", kid, err)
 		}
-		// fallback error
+	}
+	j.refreshMu.Unlock()
+
+	if k, ok := j.lookup(kid); ok {
+		return k, nil
+	}
+	if err := j.LastError(); err != nil && !j.usable() {
 		return nil, err
 	}
+	return nil, fmt.Errorf("jwks: kid %s: %w", kid, ErrKeyNotFound)
+}
 
+func (j *JWKSCache) lookup(kid string) (crypto.PublicKey, bool) {
 	j.mu.RLock()
 	defer j.mu.RUnlock()
-	if k, ok := j.keys[kid]; ok {
-		return k, nil
+	if !j.usableLocked() {
+		return nil, false
+	}
+	k, ok := j.keys[kid]
+	return k, ok
+}
+
+func (j *JWKSCache) isExpired() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return time.Now().After(j.expiresAt)
+}
+
+// usable reports whether the keyset may still be served: fresh, or expired for no
+// longer than MaxStaleness.
+func (j *JWKSCache) usable() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.usableLocked()
+}
+
+func (j *JWKSCache) usableLocked() bool {
+	return !j.lastFetch.IsZero() && time.Since(j.expiresAt) <= j.maxStaleness
+}
+
+// StartRefresher refreshes the keyset in the background whenever it expires, retrying
+// failures with exponential backoff (from MinRefreshInterval, capped at the TTL).
+// It returns a stop function.
+func (j *JWKSCache) StartRefresher() (stop func()) {
+	stopCh := make(chan struct{})
+	go func() {
+		backoff := j.minRefresh
+		for {
+			j.mu.RLock()
+			wait := time.Until(j.expiresAt)
+			j.mu.RUnlock()
+			if j.LastError() != nil {
+				wait = backoff
+			}
+			if wait < 0 {
+				wait = 0
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-stopCh:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			if err := j.Refresh(); err != nil {
+				if backoff *= 2; backoff > j.ttl {
+					backoff = j.ttl
+				}
+				log.Printf("[jwks] background refresh failed (retry in %s): %v", backoff, err)
+				continue
+			}
+			backoff = j.minRefresh
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stopCh) })
 	}
-	return nil, errors.New("key not found")
 }
 
 // LastFetch returns the last successful fetch time for diagnostics.
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestJWK(t *testing.T) (map[string]interface{}, string) {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, kid, err := makeJWK(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwk, kid
+}
+
+func TestJWKSKidMissRefetchIsRateLimited(t *testing.T) {
+	jwk1, kid1 := newTestJWK(t)
+	jwk2, kid2 := newTestJWK(t)
+
+	var mu sync.Mutex
+	current := []map[string]interface{}{jwk1}
+	var fetches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		mu.Lock()
+		b, _ := makeJWKSJSON(current)
+		mu.Unlock()
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	cache, err := NewJWKSCacheFromConfig(JWKSConfig{URL: srv.URL, MinRefreshInterval: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetKey(kid1); err != nil {
+		t.Fatalf("kid1: %v", err)
+	}
+
+	// IdP rotates; the keyset is still fresh per max-age, but an unknown kid refetches.
+	mu.Lock()
+	current = []map[string]interface{}{jwk1, jwk2}
+	mu.Unlock()
+	time.Sleep(60 * time.Millisecond)
+	if _, err := cache.GetKey(kid2); err != nil {
+		t.Fatalf("kid2 after rotation: %v", err)
+	}
+	if got := atomic.LoadInt32(&fetches); got != 2 {
+		t.Fatalf("expected 2 fetches, got %d", got)
+	}
+
+	// Repeated unknown kids within the interval do not hit the IdP.
+	for i := 0; i < 5; i++ {
+		if _, err := cache.GetKey("unknown"); !errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("expected ErrKeyNotFound, got %v", err)
+		}
+	}
+	if got := atomic.LoadInt32(&fetches); got != 2 {
+		t.Fatalf("unknown kids within the interval should not refetch, got %d fetches", got)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := cache.GetKey("unknown"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	if got := atomic.LoadInt32(&fetches); got != 3 {
+		t.Fatalf("expected one refetch once the interval passed, got %d fetches", got)
+	}
+}
+
+func TestJWKSConditionalRefreshAndStaleWhileError(t *testing.T) {
+	jwk, kid := newTestJWK(t)
+	body, _ := makeJWKSJSON([]map[string]interface{}{jwk})
+
+	var failing atomic.Bool
+	var notModified int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "idp down", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	cache, err := NewJWKSCacheFromConfig(JWKSConfig{
+		URL:                srv.URL,
+		MinRefreshInterval: 20 * time.Millisecond,
+		MaxStaleness:       200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := cache.GetKey(kid); err != nil {
+		t.Fatalf("revalidated key: %v", err)
+	}
+	if atomic.LoadInt32(&notModified) != 1 {
+		t.Fatalf("expected a conditional request answered with 304")
+	}
+
+	failing.Store(true)
+	time.Sleep(30 * time.Millisecond)
+	if _, err := cache.GetKey(kid); err != nil {
+		t.Fatalf("stale keyset should be served while the IdP fails: %v", err)
+	}
+	if cache.LastError() == nil {
+		t.Fatalf("fetch error should be recorded")
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if _, err := cache.GetKey(kid); err == nil {
+		t.Fatalf("keyset older than max staleness must not be served")
+	}
+}
+
+func TestJWKSLoadFromFile(t *testing.T) {
+	jwk1, kid1 := newTestJWK(t)
+	jwk2, kid2 := newTestJWK(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	b, _ := makeJWKSJSON([]map[string]interface{}{jwk1})
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewJWKSCacheFromConfig(JWKSConfig{File: path, MinRefreshInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetKey(kid1); err != nil {
+		t.Fatalf("kid1 from file: %v", err)
+	}
+
+	b, _ = makeJWKSJSON([]map[string]interface{}{jwk1, jwk2})
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, err := cache.GetKey(kid2); err != nil {
+		t.Fatalf("kid2 after file update: %v", err)
+	}
+
+	if _, err := NewJWKSCacheFromConfig(JWKSConfig{File: filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Fatalf("expected error for missing jwks file")
+	}
+}
+
+func TestJWKSStartRefresher(t *testing.T) {
+	jwk, kid := newTestJWK(t)
+	body, _ := makeJWKSJSON([]map[string]interface{}{jwk})
+	var fetches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			http.Error(w, "not yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	cache, _ := NewJWKSCacheFromConfig(JWKSConfig{URL: srv.URL, TTL: time.Hour, MinRefreshInterval: 10 * time.Millisecond})
+	stop := cache.StartRefresher()
+	defer stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for cache.LastFetch().IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatalf("background refresher did not recover from the initial failure")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, err := cache.GetKey(kid); err != nil {
+		t.Fatalf("key after background refresh: %v", err)
+	}
+}
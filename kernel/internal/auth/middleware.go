@@ -47,8 +47,13 @@ func FromContext(ctx context.Context) *AuthInfo {
 	return nil
 }
 
+// ContextWithAuthInfo returns ctx carrying ai (as the auth middleware stores it).
+func ContextWithAuthInfo(ctx context.Context, ai *AuthInfo) context.Context {
+	return context.WithValue(ctx, ctxKeyAuthInfo, ai)
+}
+
 // NewMiddleware returns an HTTP middleware that enforces the minimal auth policy:
-// - If cfg.RequireMTLS == true, a peer certificate must be presented (TLS termination must pass through client certs).
+// - If cfg.TLS.RequireMTLS == true, a peer certificate must be presented (TLS termination must pass through client certs).
 // - It extracts the peer cert CN (if present) and any Bearer token into the request context for downstream use.
 //
 // NOTE: This middleware does NOT perform OIDC token validation or role mapping. It only extracts auth info.
@@ -59,7 +64,7 @@ func NewMiddleware(cfg *config.Config) func(next http.Handler) http.Handler {
 			ai := &AuthInfo{}
 
 			// mTLS: require client cert if configured
-			if cfg.RequireMTLS {
+			if cfg.TLS.RequireMTLS {
 				// r.TLS may be nil if server not configured for TLS; in production TLS termination will supply TLS info.
 				if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
 					http.Error(w, "mTLS required", http.StatusUnauthorized)
@@ -85,11 +90,10 @@ func NewMiddleware(cfg *config.Config) func(next http.Handler) http.Handler {
 			// Structured debug: show what was extracted (no secrets)
 			tokenPresent := ai.BearerToken != ""
 			log.Printf("[auth] principal extracted peer_cn=%q token_present=%v require_mtls=%v",
-				ai.PeerCN, tokenPresent, cfg.RequireMTLS)
+				ai.PeerCN, tokenPresent, cfg.TLS.RequireMTLS)
 
 			// place AuthInfo into context for downstream use
-			ctx := context.WithValue(r.Context(), ctxKeyAuthInfo, ai)
-			r = r.WithContext(ctx)
+			r = r.WithContext(ContextWithAuthInfo(r.Context(), ai))
 
 			next.ServeHTTP(w, r)
 		})
@@ -246,8 +246,7 @@ func OIDCMiddleware(jwks *JWKSCache, issuer, audience string) func(next http.Han
 			ai := FromContext(r.Context())
 			if ai == nil {
 				ai = &AuthInfo{}
-				ctx := context.WithValue(r.Context(), ctxKeyAuthInfo, ai)
-				r = r.WithContext(ctx)
+				r = r.WithContext(ContextWithAuthInfo(r.Context(), ai))
 			}
 			// Prefer Bearer token from AuthInfo or header
 			token := ai.BearerToken
@@ -263,7 +262,7 @@ func OIDCMiddleware(jwks *JWKSCache, issuer, audience string) func(next http.Han
 			}
 
 			// Validate token and log failures for diagnosis.
-			_, roles, err := ValidateJWT(r.Context(), token, jwks, issuer, audience)
+			claims, roles, err := ValidateJWT(r.Context(), token, jwks, issuer, audience)
 			if err != nil {
 				var jerr error
 				if jwks != nil {
@@ -275,6 +274,8 @@ func OIDCMiddleware(jwks *JWKSCache, issuer, audience string) func(next http.Han
 			}
 
 			ai.Roles = roles
+			ai.Subject, _ = claims["sub"].(string)
+			ai.Issuer, _ = claims["iss"].(string)
 			next.ServeHTTP(w, r)
 		})
 	}
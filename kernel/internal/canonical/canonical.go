@@ -5,22 +5,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+
+	jcs "github.com/ILLUVRSE/Main/shared/canonical"
 )
 
-// MarshalCanonical returns deterministic JSON bytes for an arbitrary JSON-like value.
-// Rules:
-// - Objects (map[string]interface{}): keys sorted lexicographically.
+// MarshalCanonical returns the RFC 8785 (JCS) canonical JSON bytes for an arbitrary
+// JSON-like value. It delegates to the shared implementation so audit hashes and
+// manifest signatures match the other Go services byte-for-byte:
+// - Objects: members sorted by UTF-16 code units of their names.
 // - Arrays: order preserved.
-// - Numbers/strings/booleans/null: encoded consistently using encoding/json for primitives where appropriate.
+// - Numbers: ECMAScript double formatting (json.Number text is re-serialized, e.g. 4.50 -> 4.5).
+// - Strings: minimal escaping, no HTML escaping.
 func MarshalCanonical(v interface{}) ([]byte, error) {
+	return jcs.Marshal(v)
+}
+
+// MarshalLegacy returns the canonical JSON the kernel used before JCS. Audit events
+// hashed with it keep verifying after the upgrade. Rules:
+// - Objects (map[string]interface{}): keys sorted bytewise.
+// - Arrays: order preserved.
+// - Numbers: json.Number text as is, float64 as encoding/json writes it.
+// - Strings: encoding/json escaping, including HTML characters.
+func MarshalLegacy(v interface{}) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := encode(&buf, v); err != nil {
+	if err := encodeLegacy(&buf, v); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-func encode(buf *bytes.Buffer, v interface{}) error {
+func encodeLegacy(buf *bytes.Buffer, v interface{}) error {
 	switch vv := v.(type) {
 	case nil:
 		buf.WriteString("null")
@@ -31,10 +45,8 @@ func encode(buf *bytes.Buffer, v interface{}) error {
 			buf.WriteString("false")
 		}
 	case json.Number:
-		// Preserve textual representation (useful to keep determinism for numbers)
 		buf.WriteString(vv.String())
 	case float64:
-		// Fallback for numeric values unmarshaled without UseNumber.
 		b, _ := json.Marshal(vv)
 		buf.Write(b)
 	case string:
@@ -46,13 +58,12 @@ func encode(buf *bytes.Buffer, v interface{}) error {
 			if i > 0 {
 				buf.WriteByte(',')
 			}
-			if err := encode(buf, elem); err != nil {
+			if err := encodeLegacy(buf, elem); err != nil {
 				return err
 			}
 		}
 		buf.WriteByte(']')
 	case map[string]interface{}:
-		// Sort keys for deterministic ordering
 		keys := make([]string, 0, len(vv))
 		for k := range vv {
 			keys = append(keys, k)
@@ -64,17 +75,16 @@ func encode(buf *bytes.Buffer, v interface{}) error {
 			if i > 0 {
 				buf.WriteByte(',')
 			}
-			// key as JSON string
 			kb, _ := json.Marshal(k)
 			buf.Write(kb)
 			buf.WriteByte(':')
-			if err := encode(buf, vv[k]); err != nil {
+			if err := encodeLegacy(buf, vv[k]); err != nil {
 				return err
 			}
 		}
 		buf.WriteByte('}')
 	default:
-		// Fallback: marshal then re-decode into interface{} with UseNumber and encode recursively.
+		// Marshal then re-decode into interface{} with UseNumber and encode recursively.
 		b, err := json.Marshal(vv)
 		if err != nil {
 			return fmt.Errorf("canonical marshal fallback: %w", err)
@@ -85,7 +95,7 @@ func encode(buf *bytes.Buffer, v interface{}) error {
 		if err := dec.Decode(&tmp); err != nil {
 			return fmt.Errorf("canonical decode fallback: %w", err)
 		}
-		return encode(buf, tmp)
+		return encodeLegacy(buf, tmp)
 	}
 	return nil
 }
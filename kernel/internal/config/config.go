@@ -1,78 +1,661 @@
-// package config provides a minimal environment-backed configuration loader
-// used by the kernel bootstrap (cmd/kernel/main.go).
+// package config loads the kernel's typed configuration: defaults, then an optional
+// YAML/JSON file (KERNEL_CONFIG), then environment variable overrides, followed by strict
+// validation. cmd/kernel refuses to start when Validate fails and "kernel config print"
+// shows the effective configuration with secrets redacted.
 package config
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
-// Config holds the small set of runtime config values used by main.go.
-// Keep this intentionally minimal — we can expand later.
+// EnvConfigFile names the environment variable holding the config file path.
+const EnvConfigFile = "KERNEL_CONFIG"
+
+// EnvProduction is the Env value that enables production guardrails.
+const EnvProduction = "production"
+
+const redacted = "REDACTED"
+
+// Config is the kernel's runtime configuration. Each field lists the environment
+// variable that overrides it.
 type Config struct {
-	DatabaseURL              string // DATABASE_URL
-	RequireKMS               bool   // REQUIRE_KMS
-	KMSEndpoint              string // KMS_ENDPOINT
-	LocalSignerID            string // LOCAL_SIGNER_ID (fallback signer)
-	RequireMTLS              bool   // REQUIRE_MTLS
-	ListenAddr               string // LISTEN_ADDR (default :8080)
+	Env        string `yaml:"env" json:"env"`               // NODE_ENV (default development)
+	ListenAddr string `yaml:"listenAddr" json:"listenAddr"` // LISTEN_ADDR (default :8080)
+
+	Signer     SignerConfig     `yaml:"signer" json:"signer"`
+	Store      StoreConfig      `yaml:"store" json:"store"`
+	Streamer   StreamerConfig   `yaml:"streamer" json:"streamer"`
+	TLS        TLSConfig        `yaml:"tls" json:"tls"`
+	OIDC       OIDCConfig       `yaml:"oidc" json:"oidc"`
+	RBAC       RBACConfig       `yaml:"rbac" json:"rbac"`
+	RateLimit  RateLimitConfig  `yaml:"rateLimit" json:"rateLimit"`
+	SignJobs   SignJobsConfig   `yaml:"signJobs" json:"signJobs"`
+	EvalEngine EvalEngineConfig `yaml:"evalEngine" json:"evalEngine"`
+	AuditFeed  AuditFeedConfig  `yaml:"auditFeed" json:"auditFeed"`
+}
+
+// SignerConfig selects the signing backend: KMS when KMSEndpoint is set, otherwise
+// the local Ed25519 signer (development only).
+type SignerConfig struct {
+	RequireKMS    bool   `yaml:"requireKms" json:"requireKms"`       // REQUIRE_KMS
+	KMSEndpoint   string `yaml:"kmsEndpoint" json:"kmsEndpoint"`     // KMS_ENDPOINT
+	SignerID      string `yaml:"signerId" json:"signerId"`           // SIGNER_ID (default kernel-signer-kms)
+	LocalSignerID string `yaml:"localSignerId" json:"localSignerId"` // LOCAL_SIGNER_ID (default local-signer-1)
+	BearerToken   string `yaml:"bearerToken" json:"bearerToken"`     // KMS_BEARER_TOKEN (secret)
+	TimeoutMS     int    `yaml:"timeoutMs" json:"timeoutMs"`         // KMS_TIMEOUT_MS (default 5000)
+	MTLSCertPath  string `yaml:"mtlsCertPath" json:"mtlsCertPath"`   // KMS_MTLS_CERT_PATH
+	MTLSKeyPath   string `yaml:"mtlsKeyPath" json:"mtlsKeyPath"`     // KMS_MTLS_KEY_PATH
+	MTLSCAPath    string `yaml:"mtlsCaPath" json:"mtlsCaPath"`       // KMS_MTLS_CA_PATH
+	BatchMaxItems int    `yaml:"batchMaxItems" json:"batchMaxItems"` // SIGN_BATCH_MAX_ITEMS (default 100)
+}
+
+// StoreConfig configures the audit store: Postgres when DatabaseURL is set, otherwise
+// a file store under ArchiveDir.
+type StoreConfig struct {
+	DatabaseURL          string `yaml:"databaseUrl" json:"databaseUrl"`                   // DATABASE_URL (password is secret)
+	ArchiveDir           string `yaml:"archiveDir" json:"archiveDir"`                     // AUDIT_ARCHIVE_DIR (default ./archive)
+	RetentionDays        int    `yaml:"retentionDays" json:"retentionDays"`               // AUDIT_RETENTION_DAYS (0 keeps forever)
+	RetentionRules       string `yaml:"retentionRules" json:"retentionRules"`             // AUDIT_RETENTION_RULES
+	PruneIntervalSeconds int    `yaml:"pruneIntervalSeconds" json:"pruneIntervalSeconds"` // AUDIT_PRUNE_INTERVAL_SECONDS (default 3600)
+}
+
+// StreamerConfig configures the Kafka + S3 audit streamer. It runs when brokers, topic
+// and bucket are all set (and Postgres is configured).
+type StreamerConfig struct {
+	KafkaBrokers        []string `yaml:"kafkaBrokers" json:"kafkaBrokers"`               // KAFKA_BROKERS (comma-separated)
+	KafkaTopic          string   `yaml:"kafkaTopic" json:"kafkaTopic"`                   // KAFKA_TOPIC
+	S3Bucket            string   `yaml:"s3Bucket" json:"s3Bucket"`                       // S3_BUCKET
+	S3Prefix            string   `yaml:"s3Prefix" json:"s3Prefix"`                       // S3_PREFIX
+	BatchSize           int      `yaml:"batchSize" json:"batchSize"`                     // STREAM_BATCH_SIZE (default 10)
+	MaxConcurrency      int      `yaml:"maxConcurrency" json:"maxConcurrency"`           // STREAM_MAX_CONCURRENCY (default 5)
+	PollIntervalSeconds int      `yaml:"pollIntervalSeconds" json:"pollIntervalSeconds"` // STREAM_POLL_INTERVAL_SECONDS (default 3)
+}
+
+// Enabled reports whether the streamer is fully configured.
+func (s StreamerConfig) Enabled() bool {
+	return len(s.KafkaBrokers) > 0 && s.KafkaTopic != "" && s.S3Bucket != ""
+}
+
+// TLSConfig configures the server certificate and client certificate verification.
+type TLSConfig struct {
+	CertPath     string `yaml:"certPath" json:"certPath"`         // TLS_CERT_PATH
+	KeyPath      string `yaml:"keyPath" json:"keyPath"`           // TLS_KEY_PATH
+	ClientCAPath string `yaml:"clientCaPath" json:"clientCaPath"` // TLS_CLIENT_CA_PATH
+	RequireMTLS  bool   `yaml:"requireMtls" json:"requireMtls"`   // REQUIRE_MTLS
+}
+
+// OIDCConfig configures bearer token validation.
+type OIDCConfig struct {
+	Issuer                  string `yaml:"issuer" json:"issuer"`                                   // OIDC_ISSUER
+	Audience                string `yaml:"audience" json:"audience"`                               // OIDC_AUDIENCE
+	JWKSURL                 string `yaml:"jwksUrl" json:"jwksUrl"`                                 // JWKS_URL
+	JWKSFile                string `yaml:"jwksFile" json:"jwksFile"`                               // JWKS_FILE (air-gapped; seeds the cache when JWKS_URL is also set)
+	JWKSCacheTTLSeconds     int    `yaml:"jwksCacheTtlSeconds" json:"jwksCacheTtlSeconds"`         // JWKS_CACHE_TTL_SECONDS (default 300)
+	JWKSMinRefreshSeconds   int    `yaml:"jwksMinRefreshSeconds" json:"jwksMinRefreshSeconds"`     // JWKS_MIN_REFRESH_SECONDS (default 10): rate limit for unknown-kid refetches
+	JWKSMaxStalenessSeconds int    `yaml:"jwksMaxStalenessSeconds" json:"jwksMaxStalenessSeconds"` // JWKS_MAX_STALENESS_SECONDS (default 86400): serve the last good keyset this long while fetches fail
+}
+
+// Enabled reports whether a JWKS source is configured.
+func (o OIDCConfig) Enabled() bool {
+	return o.JWKSURL != "" || o.JWKSFile != ""
+}
+
+// RBACConfig controls role checks on mutating endpoints.
+type RBACConfig struct {
+	// Enforce requires authenticated principals with the documented roles (RBAC_ENFORCE).
+	// Unset means enforce only when Env is production.
+	Enforce *bool `yaml:"enforce,omitempty" json:"enforce,omitempty"`
+}
+
+// Rate limit backends.
+const (
+	RateLimitMemory   = "memory"
+	RateLimitPostgres = "postgres"
+)
+
+// RateLimitConfig configures per-principal token buckets on Kernel routes.
+type RateLimitConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"` // RATE_LIMIT_ENABLED (default true)
+	Backend string `yaml:"backend" json:"backend"` // RATE_LIMIT_BACKEND: memory (default, per replica) or postgres (shared)
+	// Routes replaces the defaults when set. RATE_LIMIT_ROUTES overrides it with
+	// comma-separated "METHOD /path=rate:burst[:keyBy]" entries.
+	Routes []RouteLimitConfig `yaml:"routes" json:"routes"`
+}
+
+// RouteLimitConfig is one route's bucket.
+type RouteLimitConfig struct {
+	Route         string  `yaml:"route" json:"route"`                     // "METHOD /path", e.g. "POST /kernel/sign"
+	RatePerSecond float64 `yaml:"ratePerSecond" json:"ratePerSecond"`     // refill rate
+	Burst         int     `yaml:"burst" json:"burst"`                     // bucket size
+	KeyBy         string  `yaml:"keyBy,omitempty" json:"keyBy,omitempty"` // principal (default) or role
+}
+
+// SignJobsConfig configures asynchronous signing jobs (POST /kernel/sign/jobs). Jobs are
+// kept in Postgres when store.databaseUrl is set, otherwise in memory.
+type SignJobsConfig struct {
+	Workers                int      `yaml:"workers" json:"workers"`                               // SIGN_JOBS_WORKERS (default 2)
+	PollIntervalMS         int      `yaml:"pollIntervalMs" json:"pollIntervalMs"`                 // SIGN_JOBS_POLL_INTERVAL_MS (default 1000)
+	LeaseSeconds           int      `yaml:"leaseSeconds" json:"leaseSeconds"`                     // SIGN_JOBS_LEASE_SECONDS (default 600): longest signing attempt, including HSM approval waits
+	RequireApproval        bool     `yaml:"requireApproval" json:"requireApproval"`               // SIGN_JOBS_REQUIRE_APPROVAL: every job waits for an operator decision
+	CallbackSecret         string   `yaml:"callbackSecret" json:"callbackSecret"`                 // SIGN_JOBS_CALLBACK_SECRET (secret): HMAC key; callbacks are refused without it
+	CallbackHosts          []string `yaml:"callbackHosts" json:"callbackHosts"`                   // SIGN_JOBS_CALLBACK_HOSTS (comma-separated host or host:port): the only callback targets; callbacks are refused without it
+	CallbackMaxAttempts    int      `yaml:"callbackMaxAttempts" json:"callbackMaxAttempts"`       // SIGN_JOBS_CALLBACK_MAX_ATTEMPTS (default 8)
+	CallbackTimeoutSeconds int      `yaml:"callbackTimeoutSeconds" json:"callbackTimeoutSeconds"` // SIGN_JOBS_CALLBACK_TIMEOUT_SECONDS (default 10)
+	CallbackBackoffSeconds int      `yaml:"callbackBackoffSeconds" json:"callbackBackoffSeconds"` // SIGN_JOBS_CALLBACK_BACKOFF_SECONDS (default 5, doubled per attempt)
+}
+
+// EvalEngineConfig points the kernel at the eval-engine services it fronts. Without an
+// AllocatorURL, POST /kernel/allocate only records requests; without an IngestionURL,
+// POST /kernel/eval only records reports.
+type EvalEngineConfig struct {
+	IngestionURL             string `yaml:"ingestionUrl" json:"ingestionUrl"`                         // EVAL_INGESTION_URL
+	AllocatorURL             string `yaml:"allocatorUrl" json:"allocatorUrl"`                         // RESOURCE_ALLOCATOR_URL
+	Token                    string `yaml:"token" json:"token"`                                       // EVAL_ENGINE_TOKEN (secret)
+	TimeoutMS                int    `yaml:"timeoutMs" json:"timeoutMs"`                               // EVAL_ENGINE_TIMEOUT_MS (default 5000)
+	ManualAllocationApproval bool   `yaml:"manualAllocationApproval" json:"manualAllocationApproval"` // ALLOCATION_MANUAL_APPROVAL: leave requests pending at the allocator for an operator
+	ReconcileIntervalSeconds int    `yaml:"reconcileIntervalSeconds" json:"reconcileIntervalSeconds"` // ALLOCATION_RECONCILE_INTERVAL_SECONDS (default 30)
+}
+
+// AuditFeedConfig configures GET /kernel/audit/stream. Subscribers are woken by appends
+// (Postgres LISTEN/NOTIFY or in-process for the file store) and, as a fallback, every
+// PollIntervalMS; a subscriber that cannot take an event within WriteTimeoutMS is
+// disconnected and resumes with Last-Event-ID.
+type AuditFeedConfig struct {
+	MaxSubscribers   int `yaml:"maxSubscribers" json:"maxSubscribers"`     // AUDIT_FEED_MAX_SUBSCRIBERS (default 100)
+	PollIntervalMS   int `yaml:"pollIntervalMs" json:"pollIntervalMs"`     // AUDIT_FEED_POLL_INTERVAL_MS (default 5000)
+	HeartbeatSeconds int `yaml:"heartbeatSeconds" json:"heartbeatSeconds"` // AUDIT_FEED_HEARTBEAT_SECONDS (default 15)
+	WriteTimeoutMS   int `yaml:"writeTimeoutMs" json:"writeTimeoutMs"`     // AUDIT_FEED_WRITE_TIMEOUT_MS (default 10000)
+	BatchSize        int `yaml:"batchSize" json:"batchSize"`               // AUDIT_FEED_BATCH_SIZE (default 100): events read per store query
+}
+
+// Default returns the configuration used before the file and environment are applied.
+func Default() *Config {
+	return &Config{
+		Env:        "development",
+		ListenAddr: ":8080",
+		Signer: SignerConfig{
+			SignerID:      "kernel-signer-kms",
+			LocalSignerID: "local-signer-1",
+			TimeoutMS:     5000,
+			BatchMaxItems: 100,
+		},
+		Store: StoreConfig{
+			ArchiveDir:           "./archive",
+			PruneIntervalSeconds: 3600,
+		},
+		Streamer: StreamerConfig{
+			BatchSize:           10,
+			MaxConcurrency:      5,
+			PollIntervalSeconds: 3,
+		},
+		OIDC: OIDCConfig{
+			JWKSCacheTTLSeconds:     300,
+			JWKSMinRefreshSeconds:   10,
+			JWKSMaxStalenessSeconds: 86400,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Backend: RateLimitMemory,
+			Routes: []RouteLimitConfig{
+				{Route: "POST /kernel/sign", RatePerSecond: 10, Burst: 20},
+				{Route: "POST /kernel/sign/batch", RatePerSecond: 2, Burst: 5},
+				{Route: "POST /kernel/sign/jobs", RatePerSecond: 10, Burst: 20},
+				{Route: "POST /kernel/audit", RatePerSecond: 50, Burst: 100},
+			},
+		},
+		SignJobs: SignJobsConfig{
+			Workers:                2,
+			PollIntervalMS:         1000,
+			LeaseSeconds:           600,
+			CallbackMaxAttempts:    8,
+			CallbackTimeoutSeconds: 10,
+			CallbackBackoffSeconds: 5,
+		},
+		EvalEngine: EvalEngineConfig{
+			TimeoutMS:                5000,
+			ReconcileIntervalSeconds: 30,
+		},
+		AuditFeed: AuditFeedConfig{
+			MaxSubscribers:   100,
+			PollIntervalMS:   5000,
+			HeartbeatSeconds: 15,
+			WriteTimeoutMS:   10000,
+			BatchSize:        100,
+		},
+	}
+}
+
+// Load resolves the configuration from path (KERNEL_CONFIG when empty) and the
+// environment and validates it.
+func Load(path string) (*Config, error) {
+	cfg, err := Resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFromEnv is Load with the file named by KERNEL_CONFIG.
+func LoadFromEnv() (*Config, error) {
+	return Load("")
+}
+
+// Resolve applies defaults, the config file and environment overrides without
+// validating the result. Unknown file keys and malformed values are errors.
+func Resolve(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv(EnvConfigFile)
+	}
+	cfg := Default()
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if cfg.RBAC.Enforce == nil {
+		enforce := cfg.IsProduction()
+		cfg.RBAC.Enforce = &enforce
+	}
+	return cfg, nil
+}
+
+// loadFile decodes a YAML (or JSON, a YAML subset) document over c.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: read %s: %w", path, err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides fields from set environment variables, rejecting malformed values.
+func (c *Config) applyEnv() error {
+	e := &envOverlay{}
+	e.str(&c.Env, "NODE_ENV")
+	e.str(&c.ListenAddr, "LISTEN_ADDR")
+
+	e.boolean(&c.Signer.RequireKMS, "REQUIRE_KMS")
+	e.str(&c.Signer.KMSEndpoint, "KMS_ENDPOINT")
+	e.str(&c.Signer.SignerID, "SIGNER_ID")
+	e.str(&c.Signer.LocalSignerID, "LOCAL_SIGNER_ID")
+	e.str(&c.Signer.BearerToken, "KMS_BEARER_TOKEN")
+	e.integer(&c.Signer.TimeoutMS, "KMS_TIMEOUT_MS")
+	e.str(&c.Signer.MTLSCertPath, "KMS_MTLS_CERT_PATH")
+	e.str(&c.Signer.MTLSKeyPath, "KMS_MTLS_KEY_PATH")
+	e.str(&c.Signer.MTLSCAPath, "KMS_MTLS_CA_PATH")
+	e.integer(&c.Signer.BatchMaxItems, "SIGN_BATCH_MAX_ITEMS")
+
+	e.str(&c.Store.DatabaseURL, "DATABASE_URL")
+	e.str(&c.Store.ArchiveDir, "AUDIT_ARCHIVE_DIR")
+	e.integer(&c.Store.RetentionDays, "AUDIT_RETENTION_DAYS")
+	e.str(&c.Store.RetentionRules, "AUDIT_RETENTION_RULES")
+	e.integer(&c.Store.PruneIntervalSeconds, "AUDIT_PRUNE_INTERVAL_SECONDS")
+
+	e.list(&c.Streamer.KafkaBrokers, "KAFKA_BROKERS")
+	e.str(&c.Streamer.KafkaTopic, "KAFKA_TOPIC")
+	e.str(&c.Streamer.S3Bucket, "S3_BUCKET")
+	e.str(&c.Streamer.S3Prefix, "S3_PREFIX")
+	e.integer(&c.Streamer.BatchSize, "STREAM_BATCH_SIZE")
+	e.integer(&c.Streamer.MaxConcurrency, "STREAM_MAX_CONCURRENCY")
+	e.integer(&c.Streamer.PollIntervalSeconds, "STREAM_POLL_INTERVAL_SECONDS")
+
+	e.str(&c.TLS.CertPath, "TLS_CERT_PATH")
+	e.str(&c.TLS.KeyPath, "TLS_KEY_PATH")
+	e.str(&c.TLS.ClientCAPath, "TLS_CLIENT_CA_PATH")
+	e.boolean(&c.TLS.RequireMTLS, "REQUIRE_MTLS")
+
+	e.str(&c.OIDC.Issuer, "OIDC_ISSUER")
+	e.str(&c.OIDC.Audience, "OIDC_AUDIENCE")
+	e.str(&c.OIDC.JWKSURL, "JWKS_URL")
+	e.str(&c.OIDC.JWKSFile, "JWKS_FILE")
+	e.integer(&c.OIDC.JWKSCacheTTLSeconds, "JWKS_CACHE_TTL_SECONDS")
+	e.integer(&c.OIDC.JWKSMinRefreshSeconds, "JWKS_MIN_REFRESH_SECONDS")
+	e.integer(&c.OIDC.JWKSMaxStalenessSeconds, "JWKS_MAX_STALENESS_SECONDS")
+
+	e.boolean(&c.RateLimit.Enabled, "RATE_LIMIT_ENABLED")
+	e.str(&c.RateLimit.Backend, "RATE_LIMIT_BACKEND")
+	if v, ok := e.lookup("RATE_LIMIT_ROUTES"); ok {
+		routes, err := parseRouteLimits(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("RATE_LIMIT_ROUTES: %w", err))
+		} else {
+			c.RateLimit.Routes = routes
+		}
+	}
+
+	e.integer(&c.SignJobs.Workers, "SIGN_JOBS_WORKERS")
+	e.integer(&c.SignJobs.PollIntervalMS, "SIGN_JOBS_POLL_INTERVAL_MS")
+	e.integer(&c.SignJobs.LeaseSeconds, "SIGN_JOBS_LEASE_SECONDS")
+	e.boolean(&c.SignJobs.RequireApproval, "SIGN_JOBS_REQUIRE_APPROVAL")
+	e.str(&c.SignJobs.CallbackSecret, "SIGN_JOBS_CALLBACK_SECRET")
+	e.list(&c.SignJobs.CallbackHosts, "SIGN_JOBS_CALLBACK_HOSTS")
+	e.integer(&c.SignJobs.CallbackMaxAttempts, "SIGN_JOBS_CALLBACK_MAX_ATTEMPTS")
+	e.integer(&c.SignJobs.CallbackTimeoutSeconds, "SIGN_JOBS_CALLBACK_TIMEOUT_SECONDS")
+	e.integer(&c.SignJobs.CallbackBackoffSeconds, "SIGN_JOBS_CALLBACK_BACKOFF_SECONDS")
+
+	e.str(&c.EvalEngine.IngestionURL, "EVAL_INGESTION_URL")
+	e.str(&c.EvalEngine.AllocatorURL, "RESOURCE_ALLOCATOR_URL")
+	e.str(&c.EvalEngine.Token, "EVAL_ENGINE_TOKEN")
+	e.integer(&c.EvalEngine.TimeoutMS, "EVAL_ENGINE_TIMEOUT_MS")
+	e.boolean(&c.EvalEngine.ManualAllocationApproval, "ALLOCATION_MANUAL_APPROVAL")
+	e.integer(&c.EvalEngine.ReconcileIntervalSeconds, "ALLOCATION_RECONCILE_INTERVAL_SECONDS")
 
-	// OIDC / JWKS
-	OIDCIssuer           string // OIDC_ISSUER
-	OIDCAudience         string // OIDC_AUDIENCE
-	JWKSURL              string // JWKS_URL
-	JWKSCacheTTLSeconds  int    // JWKS_CACHE_TTL_SECONDS (default 300)
+	e.integer(&c.AuditFeed.MaxSubscribers, "AUDIT_FEED_MAX_SUBSCRIBERS")
+	e.integer(&c.AuditFeed.PollIntervalMS, "AUDIT_FEED_POLL_INTERVAL_MS")
+	e.integer(&c.AuditFeed.HeartbeatSeconds, "AUDIT_FEED_HEARTBEAT_SECONDS")
+	e.integer(&c.AuditFeed.WriteTimeoutMS, "AUDIT_FEED_WRITE_TIMEOUT_MS")
+	e.integer(&c.AuditFeed.BatchSize, "AUDIT_FEED_BATCH_SIZE")
 
-	// TLS file paths (optional; main.go reads env directly today, but we keep here for consistency)
-	TLSCertPath     string // TLS_CERT_PATH
-	TLSKeyPath      string // TLS_KEY_PATH
-	TLSClientCAPath string // TLS_CLIENT_CA_PATH
+	if v, ok := e.lookup("RBAC_ENFORCE"); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("RBAC_ENFORCE: invalid boolean %q", v))
+		} else {
+			c.RBAC.Enforce = &b
+		}
+	}
+	return errors.Join(e.errs...)
 }
 
-// LoadFromEnv reads config values from environment variables and returns a Config pointer.
-func LoadFromEnv() *Config {
-	cfg := &Config{
-		DatabaseURL:   os.Getenv("DATABASE_URL"),
-		KMSEndpoint:   os.Getenv("KMS_ENDPOINT"),
-		LocalSignerID: os.Getenv("LOCAL_SIGNER_ID"),
-		ListenAddr:    os.Getenv("LISTEN_ADDR"),
+// IsProduction reports whether production guardrails apply.
+func (c *Config) IsProduction() bool {
+	return c != nil && c.Env == EnvProduction
+}
 
-		OIDCIssuer:          os.Getenv("OIDC_ISSUER"),
-		OIDCAudience:        os.Getenv("OIDC_AUDIENCE"),
-		JWKSURL:             os.Getenv("JWKS_URL"),
-		TLSCertPath:         os.Getenv("TLS_CERT_PATH"),
-		TLSKeyPath:          os.Getenv("TLS_KEY_PATH"),
-		TLSClientCAPath:     os.Getenv("TLS_CLIENT_CA_PATH"),
+// EnforceRBAC reports whether handlers must check principals and roles.
+func (c *Config) EnforceRBAC() bool {
+	if c == nil {
+		return false
 	}
+	if c.RBAC.Enforce == nil {
+		return c.IsProduction()
+	}
+	return *c.RBAC.Enforce
+}
 
-	// sensible defaults
-	if cfg.LocalSignerID == "" {
-		cfg.LocalSignerID = "local-signer-1"
+// Validate reports every invalid value and every unsafe combination. In production the
+// kernel must sign through KMS, persist audit to Postgres and enforce RBAC.
+func (c *Config) Validate() error {
+	var errs []error
+	bad := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
 	}
-	if cfg.ListenAddr == "" {
-		cfg.ListenAddr = ":8080"
+
+	if c.ListenAddr == "" {
+		bad("listenAddr must not be empty")
+	}
+	positive := map[string]int{
+		"signer.timeoutMs":                    c.Signer.TimeoutMS,
+		"signer.batchMaxItems":                c.Signer.BatchMaxItems,
+		"store.pruneIntervalSeconds":          c.Store.PruneIntervalSeconds,
+		"streamer.batchSize":                  c.Streamer.BatchSize,
+		"streamer.maxConcurrency":             c.Streamer.MaxConcurrency,
+		"streamer.pollIntervalSeconds":        c.Streamer.PollIntervalSeconds,
+		"oidc.jwksCacheTtlSeconds":            c.OIDC.JWKSCacheTTLSeconds,
+		"oidc.jwksMinRefreshSeconds":          c.OIDC.JWKSMinRefreshSeconds,
+		"oidc.jwksMaxStalenessSeconds":        c.OIDC.JWKSMaxStalenessSeconds,
+		"signJobs.workers":                    c.SignJobs.Workers,
+		"signJobs.pollIntervalMs":             c.SignJobs.PollIntervalMS,
+		"signJobs.leaseSeconds":               c.SignJobs.LeaseSeconds,
+		"signJobs.callbackMaxAttempts":        c.SignJobs.CallbackMaxAttempts,
+		"signJobs.callbackTimeoutSeconds":     c.SignJobs.CallbackTimeoutSeconds,
+		"signJobs.callbackBackoffSeconds":     c.SignJobs.CallbackBackoffSeconds,
+		"evalEngine.timeoutMs":                c.EvalEngine.TimeoutMS,
+		"evalEngine.reconcileIntervalSeconds": c.EvalEngine.ReconcileIntervalSeconds,
+		"auditFeed.maxSubscribers":            c.AuditFeed.MaxSubscribers,
+		"auditFeed.pollIntervalMs":            c.AuditFeed.PollIntervalMS,
+		"auditFeed.heartbeatSeconds":          c.AuditFeed.HeartbeatSeconds,
+		"auditFeed.writeTimeoutMs":            c.AuditFeed.WriteTimeoutMS,
+		"auditFeed.batchSize":                 c.AuditFeed.BatchSize,
+	}
+	for _, name := range sortedKeys(positive) {
+		if positive[name] <= 0 {
+			bad("%s must be positive, got %d", name, positive[name])
+		}
+	}
+	if c.Store.RetentionDays < 0 {
+		bad("store.retentionDays must not be negative, got %d", c.Store.RetentionDays)
+	}
+
+	if c.SignJobs.LeaseSeconds > 0 && c.SignJobs.LeaseSeconds*1000 <= c.Signer.TimeoutMS {
+		bad("signJobs.leaseSeconds (%d) must exceed signer.timeoutMs (%d) or jobs are reclaimed mid-signature", c.SignJobs.LeaseSeconds, c.Signer.TimeoutMS)
 	}
 
-	// JWKS cache TTL default
-	cfg.JWKSCacheTTLSeconds = 300
-	if v := os.Getenv("JWKS_CACHE_TTL_SECONDS"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cfg.JWKSCacheTTLSeconds = n
+	for _, svc := range [][2]string{
+		{"evalEngine.ingestionUrl", c.EvalEngine.IngestionURL},
+		{"evalEngine.allocatorUrl", c.EvalEngine.AllocatorURL},
+	} {
+		if svc[1] == "" {
+			continue
+		}
+		if parsed, err := url.Parse(svc[1]); err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			bad("%s must be an absolute http(s) URL, got %q", svc[0], svc[1])
 		}
 	}
 
-	// booleans parsed permissively; default false
-	if v := os.Getenv("REQUIRE_KMS"); v != "" {
-		if b, err := strconv.ParseBool(v); err == nil {
-			cfg.RequireKMS = b
+	if c.Signer.RequireKMS && c.Signer.KMSEndpoint == "" {
+		bad("signer.requireKms is set but signer.kmsEndpoint is empty")
+	}
+	if (c.Signer.MTLSCertPath == "") != (c.Signer.MTLSKeyPath == "") {
+		bad("signer.mtlsCertPath and signer.mtlsKeyPath must be set together")
+	}
+	if (c.TLS.CertPath == "") != (c.TLS.KeyPath == "") {
+		bad("tls.certPath and tls.keyPath must be set together")
+	}
+	if c.TLS.RequireMTLS && c.TLS.CertPath == "" {
+		bad("tls.requireMtls needs tls.certPath and tls.keyPath (the kernel terminates TLS itself)")
+	}
+
+	s := c.Streamer
+	partial := len(s.KafkaBrokers) > 0 || s.KafkaTopic != "" || s.S3Bucket != ""
+	if partial && !s.Enabled() {
+		bad("streamer needs kafkaBrokers, kafkaTopic and s3Bucket together")
+	}
+	if s.Enabled() && c.Store.DatabaseURL == "" {
+		bad("streamer requires store.databaseUrl (the durable DB-first pipeline)")
+	}
+
+	if rl := c.RateLimit; rl.Enabled {
+		switch rl.Backend {
+		case RateLimitMemory:
+		case RateLimitPostgres:
+			if c.Store.DatabaseURL == "" {
+				bad("rateLimit.backend postgres requires store.databaseUrl")
+			}
+		default:
+			bad("rateLimit.backend must be %q or %q, got %q", RateLimitMemory, RateLimitPostgres, rl.Backend)
+		}
+		seen := map[string]bool{}
+		for i, rt := range rl.Routes {
+			method, path, ok := strings.Cut(rt.Route, " ")
+			if !ok || method == "" || !strings.HasPrefix(path, "/") {
+				bad("rateLimit.routes[%d].route must be \"METHOD /path\", got %q", i, rt.Route)
+			}
+			if seen[rt.Route] {
+				bad("rateLimit.routes[%d]: duplicate route %q", i, rt.Route)
+			}
+			seen[rt.Route] = true
+			if rt.RatePerSecond <= 0 || rt.Burst < 1 {
+				bad("rateLimit.routes[%d] (%s) needs ratePerSecond > 0 and burst >= 1", i, rt.Route)
+			}
+			if rt.KeyBy != "" && rt.KeyBy != "principal" && rt.KeyBy != "role" {
+				bad("rateLimit.routes[%d].keyBy must be principal or role, got %q", i, rt.KeyBy)
+			}
 		}
 	}
-	if v := os.Getenv("REQUIRE_MTLS"); v != "" {
-		if b, err := strconv.ParseBool(v); err == nil {
-			cfg.RequireMTLS = b
+
+	if c.IsProduction() {
+		if !c.Signer.RequireKMS {
+			bad("production requires signer.requireKms (the local signer is development only)")
+		}
+		if c.Store.DatabaseURL == "" {
+			bad("production requires store.databaseUrl (the file store is development only)")
+		}
+		if !c.EnforceRBAC() {
+			bad("production requires rbac.enforce")
+		}
+		if c.OIDC.Enabled() && (c.OIDC.Issuer == "" || c.OIDC.Audience == "") {
+			bad("production requires oidc.issuer and oidc.audience when JWKS is configured")
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	return cfg
+var dsnPassword = regexp.MustCompile(`(password=)(\S+)`)
+
+// Redacted returns a copy of c with secrets replaced, suitable for printing.
+func (c *Config) Redacted() *Config {
+	out := *c
+	out.Streamer.KafkaBrokers = append([]string(nil), c.Streamer.KafkaBrokers...)
+	out.RateLimit.Routes = append([]RouteLimitConfig(nil), c.RateLimit.Routes...)
+	out.SignJobs.CallbackHosts = append([]string(nil), c.SignJobs.CallbackHosts...)
+	if out.Signer.BearerToken != "" {
+		out.Signer.BearerToken = redacted
+	}
+	if out.SignJobs.CallbackSecret != "" {
+		out.SignJobs.CallbackSecret = redacted
+	}
+	if out.EvalEngine.Token != "" {
+		out.EvalEngine.Token = redacted
+	}
+	if dsn := out.Store.DatabaseURL; dsn != "" {
+		if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), redacted)
+			}
+			q := u.Query()
+			if q.Has("password") {
+				q.Set("password", redacted)
+				u.RawQuery = q.Encode()
+			}
+			out.Store.DatabaseURL = u.String()
+		} else {
+			out.Store.DatabaseURL = dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+		}
+	}
+	return &out
 }
 
+// envOverlay applies set environment variables and collects parse errors.
+type envOverlay struct {
+	errs []error
+}
+
+func (e *envOverlay) lookup(key string) (string, bool) {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return "", false
+	}
+	v = strings.TrimSpace(v)
+	return v, v != ""
+}
+
+func (e *envOverlay) str(dst *string, key string) {
+	if v, ok := e.lookup(key); ok {
+		*dst = v
+	}
+}
+
+func (e *envOverlay) integer(dst *int, key string) {
+	v, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid integer %q", key, v))
+		return
+	}
+	*dst = n
+}
+
+func (e *envOverlay) boolean(dst *bool, key string) {
+	v, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid boolean %q", key, v))
+		return
+	}
+	*dst = b
+}
+
+func (e *envOverlay) list(dst *[]string, key string) {
+	v, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	*dst = out
+}
+
+// parseRouteLimits parses "METHOD /path=rate:burst[:keyBy]" entries separated by commas.
+func parseRouteLimits(v string) ([]RouteLimitConfig, error) {
+	var out []RouteLimitConfig
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("entry %q: want METHOD /path=rate:burst[:keyBy]", entry)
+		}
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("entry %q: want rate:burst[:keyBy]", entry)
+		}
+		rate, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: invalid rate: %w", entry, err)
+		}
+		burst, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("entry %q: invalid burst: %w", entry, err)
+		}
+		rl := RouteLimitConfig{Route: strings.TrimSpace(route), RatePerSecond: rate, Burst: burst}
+		if len(parts) == 3 {
+			rl.KeyBy = parts[2]
+		}
+		out = append(out, rl)
+	}
+	return out, nil
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
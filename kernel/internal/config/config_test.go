@@ -0,0 +1,189 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearEnv unsets every variable applyEnv reads so the host environment cannot leak in.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		EnvConfigFile, "NODE_ENV", "LISTEN_ADDR", "REQUIRE_KMS", "KMS_ENDPOINT", "SIGNER_ID",
+		"LOCAL_SIGNER_ID", "KMS_BEARER_TOKEN", "KMS_TIMEOUT_MS", "KMS_MTLS_CERT_PATH",
+		"KMS_MTLS_KEY_PATH", "KMS_MTLS_CA_PATH", "SIGN_BATCH_MAX_ITEMS", "DATABASE_URL", "AUDIT_ARCHIVE_DIR",
+		"AUDIT_RETENTION_DAYS", "AUDIT_RETENTION_RULES", "AUDIT_PRUNE_INTERVAL_SECONDS",
+		"KAFKA_BROKERS", "KAFKA_TOPIC", "S3_BUCKET", "S3_PREFIX", "STREAM_BATCH_SIZE",
+		"STREAM_MAX_CONCURRENCY", "STREAM_POLL_INTERVAL_SECONDS", "TLS_CERT_PATH", "TLS_KEY_PATH",
+		"TLS_CLIENT_CA_PATH", "REQUIRE_MTLS", "OIDC_ISSUER", "OIDC_AUDIENCE", "JWKS_URL",
+		"JWKS_FILE", "JWKS_CACHE_TTL_SECONDS", "JWKS_MIN_REFRESH_SECONDS",
+		"JWKS_MAX_STALENESS_SECONDS", "RBAC_ENFORCE", "RATE_LIMIT_ENABLED", "RATE_LIMIT_BACKEND",
+		"RATE_LIMIT_ROUTES", "SIGN_JOBS_WORKERS", "SIGN_JOBS_POLL_INTERVAL_MS", "SIGN_JOBS_LEASE_SECONDS",
+		"SIGN_JOBS_REQUIRE_APPROVAL", "SIGN_JOBS_CALLBACK_SECRET", "SIGN_JOBS_CALLBACK_MAX_ATTEMPTS",
+		"SIGN_JOBS_CALLBACK_TIMEOUT_SECONDS", "SIGN_JOBS_CALLBACK_BACKOFF_SECONDS",
+		"EVAL_INGESTION_URL", "RESOURCE_ALLOCATOR_URL", "EVAL_ENGINE_TOKEN", "EVAL_ENGINE_TIMEOUT_MS",
+		"ALLOCATION_MANUAL_APPROVAL", "ALLOCATION_RECONCILE_INTERVAL_SECONDS",
+		"AUDIT_FEED_MAX_SUBSCRIBERS", "AUDIT_FEED_POLL_INTERVAL_MS", "AUDIT_FEED_HEARTBEAT_SECONDS",
+		"AUDIT_FEED_WRITE_TIMEOUT_MS", "AUDIT_FEED_BATCH_SIZE",
+	} {
+		if v, ok := os.LookupEnv(key); ok {
+			os.Unsetenv(key)
+			t.Cleanup(func() { os.Setenv(key, v) })
+		}
+	}
+}
+
+func writeFile(t *testing.T, name, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileWithEnvOverlay(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "kernel.yaml", `
+listenAddr: ":9090"
+signer:
+  kmsEndpoint: https://kms.internal
+  requireKms: true
+store:
+  databaseUrl: postgres://kernel:secret@db/kernel
+streamer:
+  kafkaBrokers: [k1:9092]
+  kafkaTopic: audit
+  s3Bucket: audit-archive
+  batchSize: 50
+`)
+	t.Setenv("KAFKA_BROKERS", "k1:9092, k2:9092")
+	t.Setenv("STREAM_BATCH_SIZE", "25")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.ListenAddr != ":9090" || cfg.Signer.KMSEndpoint != "https://kms.internal" {
+		t.Fatalf("file values not applied: %+v", cfg)
+	}
+	if cfg.Streamer.BatchSize != 25 || len(cfg.Streamer.KafkaBrokers) != 2 || cfg.Streamer.KafkaBrokers[1] != "k2:9092" {
+		t.Fatalf("env should override the file: %+v", cfg.Streamer)
+	}
+	if cfg.Streamer.MaxConcurrency != 5 || cfg.OIDC.JWKSCacheTTLSeconds != 300 {
+		t.Fatalf("defaults not kept for unset fields: %+v", cfg)
+	}
+	if cfg.EnforceRBAC() {
+		t.Fatalf("rbac should default to off outside production")
+	}
+
+	// JSON is accepted as well (empty variables count as unset).
+	t.Setenv("KAFKA_BROKERS", "")
+	jsonPath := writeFile(t, "kernel.json", `{"listenAddr": ":7070", "tls": {"requireMtls": false}}`)
+	if cfg, err := Load(jsonPath); err != nil || cfg.ListenAddr != ":7070" {
+		t.Fatalf("json config: %v %+v", err, cfg)
+	}
+}
+
+func TestLoadRejectsMalformedValues(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("STREAM_BATCH_SIZE", "ten")
+	t.Setenv("REQUIRE_MTLS", "maybe")
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "STREAM_BATCH_SIZE") || !strings.Contains(err.Error(), "REQUIRE_MTLS") {
+		t.Fatalf("expected both malformed variables to be reported, got %v", err)
+	}
+
+	clearEnv(t)
+	path := writeFile(t, "kernel.yaml", "signer:\n  kmsEndpiont: https://typo\n")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "kmsEndpiont") {
+		t.Fatalf("expected unknown key to be rejected, got %v", err)
+	}
+
+	t.Setenv("STREAM_POLL_INTERVAL_SECONDS", "0")
+	t.Setenv("KAFKA_TOPIC", "audit")
+	_, err = Load("")
+	if err == nil || !strings.Contains(err.Error(), "streamer.pollIntervalSeconds") || !strings.Contains(err.Error(), "kafkaBrokers, kafkaTopic and s3Bucket") {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	clearEnv(t)
+	t.Setenv("RESOURCE_ALLOCATOR_URL", "allocator:8052")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "evalEngine.allocatorUrl") {
+		t.Fatalf("expected a relative allocator url to be rejected, got %v", err)
+	}
+}
+
+func TestRateLimitRoutesFromEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("RATE_LIMIT_ROUTES", "POST /kernel/sign=2.5:5, POST /kernel/audit=100:200:role")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	routes := cfg.RateLimit.Routes
+	if len(routes) != 2 || routes[0].RatePerSecond != 2.5 || routes[0].Burst != 5 || routes[1].KeyBy != "role" {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+
+	t.Setenv("RATE_LIMIT_ROUTES", "POST /kernel/sign=fast:5")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "RATE_LIMIT_ROUTES") {
+		t.Fatalf("expected malformed routes to be rejected, got %v", err)
+	}
+
+	t.Setenv("RATE_LIMIT_ROUTES", "")
+	t.Setenv("RATE_LIMIT_BACKEND", "postgres")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "rateLimit.backend postgres requires store.databaseUrl") {
+		t.Fatalf("expected postgres backend without a database to be rejected, got %v", err)
+	}
+}
+
+func TestProductionGuardrails(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("NODE_ENV", "production")
+	t.Setenv("RBAC_ENFORCE", "false")
+	_, err := Load("")
+	if err == nil {
+		t.Fatalf("expected production with the local signer to be refused")
+	}
+	for _, want := range []string{"signer.requireKms", "store.databaseUrl", "rbac.enforce"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+
+	clearEnv(t)
+	t.Setenv("NODE_ENV", "production")
+	t.Setenv("REQUIRE_KMS", "true")
+	t.Setenv("KMS_ENDPOINT", "https://kms.internal")
+	t.Setenv("DATABASE_URL", "postgres://kernel@db/kernel")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("safe production config rejected: %v", err)
+	}
+	if !cfg.EnforceRBAC() {
+		t.Fatalf("rbac should be enforced by default in production")
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Signer.BearerToken = "tok"
+	cfg.SignJobs.CallbackSecret = "hmac"
+	cfg.EvalEngine.Token = "eval-tok"
+	cfg.Store.DatabaseURL = "postgres://kernel:hunter2@db:5432/kernel?sslmode=disable"
+	r := cfg.Redacted()
+	if r.Signer.BearerToken != redacted || r.SignJobs.CallbackSecret != redacted || r.EvalEngine.Token != redacted || strings.Contains(r.Store.DatabaseURL, "hunter2") || !strings.Contains(r.Store.DatabaseURL, "kernel:REDACTED@db:5432") {
+		t.Fatalf("secrets not redacted: %+v", r)
+	}
+	if cfg.Signer.BearerToken != "tok" {
+		t.Fatalf("Redacted must not modify the original")
+	}
+
+	cfg.Store.DatabaseURL = "host=db user=kernel password=hunter2 dbname=kernel"
+	if got := cfg.Redacted().Store.DatabaseURL; strings.Contains(got, "hunter2") {
+		t.Fatalf("key=value DSN password not redacted: %s", got)
+	}
+}
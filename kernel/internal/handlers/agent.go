@@ -25,8 +25,8 @@ type AgentProfile map[string]interface{}
 // Creates an agent (id optional). Production: require Operator or SuperAdmin.
 func handleAgentPost(cfg *config.Config, db *sql.DB, s signer.Signer, store audit.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Production RBAC: Operator or SuperAdmin
-		if os.Getenv("NODE_ENV") == "production" {
+		// RBAC (enforced in production): Operator or SuperAdmin
+		if cfg.EnforceRBAC() {
 			ai := auth.FromContext(r.Context())
 			if ai == nil {
 				http.Error(w, "unauthenticated", http.StatusUnauthorized)
@@ -93,8 +93,8 @@ func handleAgentPost(cfg *config.Config, db *sql.DB, s signer.Signer, store audi
 // Returns a minimal agent state. Production: require authenticated principal.
 func handleAgentGet(cfg *config.Config, db *sql.DB, store audit.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Production: require authenticated principal
-		if os.Getenv("NODE_ENV") == "production" {
+		// RBAC (enforced in production): require authenticated principal
+		if cfg.EnforceRBAC() {
 			ai := auth.FromContext(r.Context())
 			if ai == nil {
 				http.Error(w, "unauthenticated", http.StatusUnauthorized)
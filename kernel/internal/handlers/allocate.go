@@ -1,132 +1,170 @@
 package handlers
 
 import (
-	"context"
-	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
-	"os"
-	"path/filepath"
-	"time"
 
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/allocation"
 	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/auth"
 	"github.com/ILLUVRSE/Main/kernel/internal/config"
-	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/kernel/internal/ratelimit"
 )
 
-// AllocationRequest is the minimal request model for /kernel/allocate
-type AllocationRequest struct {
-	Id         string    `json:"id,omitempty"`
-	DivisionId string    `json:"divisionId"`
-	CPU        int       `json:"cpu,omitempty"`
-	GPU        int       `json:"gpu,omitempty"`
-	MemoryMB   int       `json:"memoryMB,omitempty"`
-	Requester  string    `json:"requester,omitempty"`
-	Status     string    `json:"status,omitempty"` // pending|applied|rejected
-	Reason     string    `json:"reason,omitempty"`
-	CreatedAt  time.Time `json:"createdAt,omitempty"`
-	UpdatedAt  time.Time `json:"updatedAt,omitempty"`
-}
+// AllocationRequest is the request and record model for /kernel/allocate.
+type AllocationRequest = allocation.Record
 
 // POST /kernel/allocate
-// Accepts an AllocationRequest, persists it, and emits an audit event.
-func handleAllocatePost(cfg *config.Config, db *sql.DB, s signer.Signer, store audit.Store) http.HandlerFunc {
+// Accepts an AllocationRequest, persists it, emits allocation.requested and, when the
+// Resource Allocator is configured, forwards it there (see internal/allocation).
+// Response: 202 with the record (still pending, without an allocatorRequestId, when the
+// allocator cannot be reached; the Reconciler files it later); 409 when the id is already
+// taken; 502 with the failed record when the allocator refuses the request.
+// Production: service principals, SuperAdmin or DivisionLead.
+func handleAllocatePost(cfg *config.Config, allocs *allocation.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req AllocationRequest
+		if !authorizeAllocate(cfg, w, r) {
+			return
+		}
+		// The snake_case keys are the shape kernel/openapi.yaml documents for
+		// allocator-style requests.
+		var body struct {
+			AllocationRequest
+			EntityIdSnake    string `json:"entity_id"`
+			RequestedBy      string `json:"requestedBy"`
+			RequestedBySnake string `json:"requested_by"`
+		}
 		dec := json.NewDecoder(r.Body)
 		dec.UseNumber()
-		if err := dec.Decode(&req); err != nil {
+		if err := dec.Decode(&body); err != nil {
 			http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		req := body.AllocationRequest
+		req.EntityId = firstNonEmpty(req.EntityId, body.EntityIdSnake)
+		req.Requester = firstNonEmpty(req.Requester, body.RequestedBy, body.RequestedBySnake)
 		// Basic validation
-		if req.DivisionId == "" {
-			http.Error(w, "divisionId required", http.StatusBadRequest)
+		if req.DivisionId == "" && req.EntityId == "" {
+			http.Error(w, "divisionId or entityId required", http.StatusBadRequest)
+			return
+		}
+		if req.Pool == "" {
+			req.Pool, req.Delta = poolFromResources(&req)
+		}
+		if allocs.Forwarding() && (req.Pool == "" || req.Delta == 0) {
+			http.Error(w, "pool and delta required (or exactly one of cpu, gpu, memoryMB)", http.StatusBadRequest)
 			return
 		}
+		if req.Requester == "" {
+			req.Requester = ratelimit.PrincipalKey(auth.FromContext(r.Context()), r, ratelimit.KeyByPrincipal)
+		}
 
-		// ensure id and timestamps
+		// ensure id; the service sets status and timestamps
 		if req.Id == "" {
 			req.Id = audit.NewUUID()
 		}
-		now := time.Now().UTC()
-		if req.CreatedAt.IsZero() {
-			req.CreatedAt = now
-		}
-		req.UpdatedAt = now
-		if req.Status == "" {
-			req.Status = "pending"
-		}
+		req.AllocatorRequestId = ""
+		req.SentinelDecision = nil
+		req.AppliedBy = ""
+		req.AppliedAt = nil
+		req.Error = ""
 
-		// persist
-		if db != nil {
-			if err := insertAllocationToDB(r.Context(), db, &req); err != nil {
-				http.Error(w, "db persist error: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-		} else {
-			if err := writeAllocationToFile(&req); err != nil {
-				http.Error(w, "file persist error: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
+		err := allocs.Submit(r.Context(), &req)
+		switch {
+		case errors.Is(err, allocation.ErrInvalidID):
+			http.Error(w, "id must not contain path separators", http.StatusBadRequest)
+			return
+		case errors.Is(err, allocation.ErrExists):
+			http.Error(w, "allocation "+req.Id+" already exists", http.StatusConflict)
+			return
+		case errors.Is(err, allocation.ErrAllocator):
+			writeJSON(w, http.StatusBadGateway, req)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		// emit audit event
-		aev := &audit.AuditEvent{
-			EventType: "allocation.requested",
-			Payload: map[string]interface{}{
-				"allocationId": req.Id,
-				"divisionId":   req.DivisionId,
-				"cpu":          req.CPU,
-				"gpu":          req.GPU,
-				"memoryMB":     req.MemoryMB,
-				"requester":    req.Requester,
-				"status":       req.Status,
-				"reason":       req.Reason,
-			},
-			Ts: time.Now().UTC(),
+		w.Header().Set("Location", "/kernel/allocate/"+req.Id)
+		writeJSON(w, http.StatusAccepted, allocationResponse(&req))
+	}
+}
+
+// GET /kernel/allocate/{id}
+// Returns the Kernel record, reconciled with the allocator while it is pending. The
+// reconciliation may approve the request, so the caller is authorized as for POST.
+func handleAllocateGet(cfg *config.Config, allocs *allocation.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAllocate(cfg, w, r) {
+			return
+		}
+		rec, err := allocs.Get(r.Context(), chi.URLParam(r, "id"))
+		if errors.Is(err, allocation.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
 		}
-		if err := store.AppendAuditEvent(r.Context(), aev, s); err != nil {
-			http.Error(w, "append audit event: "+err.Error(), http.StatusInternalServerError)
+		if err != nil {
+			http.Error(w, "get allocation: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		writeJSON(w, http.StatusOK, allocationResponse(rec))
+	}
+}
 
-		writeJSON(w, http.StatusAccepted, map[string]interface{}{
-			"allocationId": req.Id,
-			"status":       req.Status,
-		})
+// authorizeAllocate applies the allocation RBAC rule (enforced in production): service
+// principals (mTLS peer CN), SuperAdmin or DivisionLead. It writes the error response and
+// returns false when the caller is refused.
+func authorizeAllocate(cfg *config.Config, w http.ResponseWriter, r *http.Request) bool {
+	if !cfg.EnforceRBAC() {
+		return true
+	}
+	ai := auth.FromContext(r.Context())
+	if ai == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return false
+	}
+	if ai.PeerCN == "" && !auth.HasAnyRole(ai, auth.RoleSuperAdmin, auth.RoleDivisionLead) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
 	}
+	return true
 }
 
-// persistence helpers
+// allocationResponse is the record plus the allocationId field earlier clients read.
+func allocationResponse(rec *AllocationRequest) interface{} {
+	return struct {
+		AllocationId string `json:"allocationId"`
+		*AllocationRequest
+	}{rec.Id, rec}
+}
 
-func writeAllocationToFile(a *AllocationRequest) error {
-	dir := "./data/allocations"
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
+// poolFromResources maps the legacy cpu/gpu/memoryMB shape onto an allocator pool and
+// delta when exactly one resource is requested.
+func poolFromResources(req *AllocationRequest) (string, int) {
+	pool, delta, n := "", 0, 0
+	for _, res := range []struct {
+		pool  string
+		delta int
+	}{{"cpu", req.CPU}, {"gpu", req.GPU}, {"memory", req.MemoryMB}} {
+		if res.delta != 0 {
+			pool, delta = res.pool, res.delta
+			n++
+		}
 	}
-	path := filepath.Join(dir, fmt.Sprintf("%s.json", a.Id))
-	b, err := json.MarshalIndent(a, "", "  ")
-	if err != nil {
-		return err
+	if n != 1 {
+		return "", 0
 	}
-	return os.WriteFile(path, b, 0o644)
+	return pool, delta
 }
 
-func insertAllocationToDB(ctx context.Context, db *sql.DB, a *AllocationRequest) error {
-	payload, err := json.Marshal(a)
-	if err != nil {
-		return err
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
 	}
-	q := `
-		INSERT INTO allocations (id, division_id, cpu, gpu, memory_mb, requester, status, reason, created_at, updated_at, payload)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
-		ON CONFLICT (id) DO UPDATE SET cpu = EXCLUDED.cpu, gpu = EXCLUDED.gpu, memory_mb = EXCLUDED.memory_mb, requester = EXCLUDED.requester, status = EXCLUDED.status, reason = EXCLUDED.reason, updated_at = EXCLUDED.updated_at, payload = EXCLUDED.payload
-	`
-	_, err = db.ExecContext(ctx, q,
-		a.Id, a.DivisionId, a.CPU, a.GPU, a.MemoryMB, a.Requester,
-		a.Status, a.Reason, a.CreatedAt, a.UpdatedAt, payload,
-	)
-	return err
+	return ""
 }
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/allocation"
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/auth"
+	"github.com/ILLUVRSE/Main/kernel/internal/config"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/evalclient"
+)
+
+func TestAllocateForwardsAndReconciles(t *testing.T) {
+	var approved bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/alloc/request":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["pool"] != "gpu" || body["delta"] != float64(2) || body["agentId"] != "div-1" {
+				t.Errorf("unexpected allocator request %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"requestId":"alloc-1","status":"pending"}`))
+		case "/alloc/approve":
+			http.Error(w, `{"error":"sentinel unavailable"}`, http.StatusBadRequest)
+		case "/alloc/alloc-1":
+			if approved {
+				_, _ = w.Write([]byte(`{"id":"alloc-1","status":"applied","appliedBy":"operator"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"alloc-1","status":"pending"}`))
+		}
+	}))
+	defer srv.Close()
+
+	client, err := evalclient.NewAllocator(evalclient.Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewAllocator: %v", err)
+	}
+	svc := allocation.NewService(allocation.NewFileStore(t.TempDir()), client,
+		audit.NewFileStore(t.TempDir()), signer.NewLocalSigner("s"), allocation.Config{ManualApproval: true})
+	cfg := config.Default()
+	r := chi.NewRouter()
+	r.Post("/kernel/allocate", handleAllocatePost(cfg, svc))
+	r.Get("/kernel/allocate/{id}", handleAllocateGet(cfg, svc))
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var out map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &out)
+		return rec, out
+	}
+
+	if rec, _ := do(http.MethodPost, "/kernel/allocate", `{"cpu":1}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing divisionId should be rejected, got %d", rec.Code)
+	}
+	if rec, _ := do(http.MethodPost, "/kernel/allocate", `{"divisionId":"div-1","cpu":1,"gpu":1}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("ambiguous resources should be rejected, got %d", rec.Code)
+	}
+
+	rec, out := do(http.MethodPost, "/kernel/allocate", `{"divisionId":"div-1","gpu":2,"status":"applied","requested_by":"ops"}`)
+	if rec.Code != http.StatusAccepted || out["status"] != "pending" || out["allocatorRequestId"] != "alloc-1" || out["requester"] != "ops" {
+		t.Fatalf("create: %d %v", rec.Code, out)
+	}
+	id, _ := out["allocationId"].(string)
+	if rec.Header().Get("Location") != "/kernel/allocate/"+id {
+		t.Fatalf("missing Location header")
+	}
+
+	if _, out := do(http.MethodGet, "/kernel/allocate/"+id, ""); out["status"] != "pending" {
+		t.Fatalf("expected pending, got %v", out)
+	}
+	approved = true
+	if rec, out := do(http.MethodGet, "/kernel/allocate/"+id, ""); rec.Code != http.StatusOK || out["status"] != "applied" || out["appliedBy"] != "operator" {
+		t.Fatalf("allocator status not reconciled on read: %d %v", rec.Code, out)
+	}
+	if rec, _ := do(http.MethodGet, "/kernel/allocate/nope", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+	// Re-posting an existing id must not reset the record or file it again.
+	if rec, _ := do(http.MethodPost, "/kernel/allocate", `{"id":"`+id+`","divisionId":"div-1","gpu":2}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a reused id, got %d", rec.Code)
+	}
+	if _, out := do(http.MethodGet, "/kernel/allocate/"+id, ""); out["status"] != "applied" || out["allocatorRequestId"] != "alloc-1" {
+		t.Fatalf("reused id changed the record: %v", out)
+	}
+	if rec, _ := do(http.MethodPost, "/kernel/allocate", `{"id":"../escape","divisionId":"div-1","gpu":2}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a path id, got %d", rec.Code)
+	}
+}
+
+func TestAllocateRequiresRole(t *testing.T) {
+	enforce := true
+	cfg := config.Default()
+	cfg.RBAC.Enforce = &enforce
+	svc := allocation.NewService(allocation.NewFileStore(t.TempDir()), nil,
+		audit.NewFileStore(t.TempDir()), signer.NewLocalSigner("s"), allocation.Config{})
+	r := chi.NewRouter()
+	r.Post("/kernel/allocate", handleAllocatePost(cfg, svc))
+	r.Get("/kernel/allocate/{id}", handleAllocateGet(cfg, svc))
+
+	do := func(method, path, body string, ai *auth.AuthInfo) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if ai != nil {
+			req = req.WithContext(auth.ContextWithAuthInfo(req.Context(), ai))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	body := `{"id":"r1","divisionId":"div-1","cpu":1}`
+	if code := do(http.MethodPost, "/kernel/allocate", body, nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a principal, got %d", code)
+	}
+	auditor := &auth.AuthInfo{Subject: "eve", Roles: []string{auth.RoleAuditor}}
+	if code := do(http.MethodPost, "/kernel/allocate", body, auditor); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an auditor, got %d", code)
+	}
+	lead := &auth.AuthInfo{Subject: "dana", Roles: []string{auth.RoleDivisionLead}}
+	if code := do(http.MethodPost, "/kernel/allocate", body, lead); code != http.StatusAccepted {
+		t.Fatalf("expected 202 for a division lead, got %d", code)
+	}
+	if code := do(http.MethodGet, "/kernel/allocate/r1", "", auditor); code != http.StatusForbidden {
+		t.Fatalf("expected 403 reading as an auditor, got %d", code)
+	}
+	if code := do(http.MethodGet, "/kernel/allocate/r1", "", &auth.AuthInfo{PeerCN: "ai-infra"}); code != http.StatusOK {
+		t.Fatalf("expected 200 for a service principal, got %d", code)
+	}
+}
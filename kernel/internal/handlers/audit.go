@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -20,7 +25,7 @@ import (
 func handleAuditPost(cfg *config.Config, s signer.Signer, store audit.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// mTLS guard if configured (main should configure TLS)
-		if cfg.RequireMTLS {
+		if cfg.TLS.RequireMTLS {
 			if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
 				http.Error(w, "mTLS required", http.StatusUnauthorized)
 				return
@@ -28,7 +33,7 @@ func handleAuditPost(cfg *config.Config, s signer.Signer, store audit.Store) htt
 		}
 
 		// In production, require an authenticated principal (any authenticated principal).
-		if os.Getenv("NODE_ENV") == "production" {
+		if cfg.EnforceRBAC() {
 			ai := auth.FromContext(r.Context())
 			if ai == nil {
 				http.Error(w, "unauthenticated", http.StatusUnauthorized)
@@ -72,19 +77,10 @@ func handleAuditPost(cfg *config.Config, s signer.Signer, store audit.Store) htt
 
 // GET /kernel/audit/{id}
 // Production: only SuperAdmin or Auditor allowed.
-func handleAuditGet(store audit.Store) http.HandlerFunc {
+func handleAuditGet(cfg *config.Config, store audit.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Production RBAC: only SuperAdmin or Auditor
-		if os.Getenv("NODE_ENV") == "production" {
-			ai := auth.FromContext(r.Context())
-			if ai == nil {
-				http.Error(w, "unauthenticated", http.StatusUnauthorized)
-				return
-			}
-			if !auth.HasRole(ai, auth.RoleSuperAdmin) && !auth.HasRole(ai, auth.RoleAuditor) {
-				http.Error(w, "forbidden", http.StatusForbidden)
-				return
-			}
+		if !authorizeAuditRead(cfg, w, r) {
+			return
 		}
 
 		id := chi.URLParam(r, "id")
@@ -104,3 +100,126 @@ func handleAuditGet(store audit.Store) http.HandlerFunc {
 		writeJSON(w, http.StatusOK, ev)
 	}
 }
+
+// authorizeAuditRead enforces (in production) that only SuperAdmin or Auditor read the
+// audit log. It writes the error response and returns false when the caller is refused.
+func authorizeAuditRead(cfg *config.Config, w http.ResponseWriter, r *http.Request) bool {
+	if !cfg.EnforceRBAC() {
+		return true
+	}
+	ai := auth.FromContext(r.Context())
+	if ai == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return false
+	}
+	if !auth.HasRole(ai, auth.RoleSuperAdmin) && !auth.HasRole(ai, auth.RoleAuditor) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// sseField strips line breaks so caller-chosen event types cannot inject SSE fields.
+var sseField = strings.NewReplacer("\r", "", "\n", "")
+
+// GET /kernel/audit/stream
+// Server-Sent Events feed of newly appended audit events: each event is sent with
+// "id: <seq>", "event: <eventType>" and the AuditEvent JSON as data. Clients resume with
+// the Last-Event-ID header (or ?lastEventId=); without it the feed starts at the current
+// head. ?eventType= (repeatable or comma-separated) filters by event type.
+// Production: only SuperAdmin or Auditor allowed.
+func handleAuditStream(cfg *config.Config, hub *audit.Hub) http.HandlerFunc {
+	heartbeat := time.Duration(cfg.AuditFeed.HeartbeatSeconds) * time.Second
+	writeTimeout := time.Duration(cfg.AuditFeed.WriteTimeoutMS) * time.Millisecond
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeAuditRead(cfg, w, r) {
+			return
+		}
+
+		after := int64(-1)
+		if v := firstNonEmpty(r.Header.Get("Last-Event-ID"), r.URL.Query().Get("lastEventId")); v != "" {
+			seq, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || seq < 0 {
+				http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+			after = seq
+		}
+		var types []string
+		for _, v := range r.URL.Query()["eventType"] {
+			for _, t := range strings.Split(v, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					types = append(types, t)
+				}
+			}
+		}
+
+		sub, err := hub.Subscribe(r.Context(), after, types)
+		if err != nil {
+			if errors.Is(err, audit.ErrTooManySubscribers) {
+				w.Header().Set("Retry-After", "5")
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			http.Error(w, "subscribe: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		// The server-wide WriteTimeout would cut the stream; each write gets its own
+		// deadline instead, so a subscriber that stops reading is disconnected (and
+		// resumes with Last-Event-ID) rather than buffered for.
+		rc := http.NewResponseController(w)
+		send := func(format string, args ...interface{}) bool {
+			_ = rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if _, err := fmt.Fprintf(w, format, args...); err != nil {
+				return false
+			}
+			return rc.Flush() == nil
+		}
+
+		if !send(": connected\n\n") {
+			return
+		}
+		sent := sub.Cursor()
+		for {
+			events, err := sub.Next(r.Context(), heartbeat)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) && !errors.Is(err, audit.ErrFeedClosed) {
+					log.Printf("[audit.feed] subscriber: %v", err)
+				}
+				return
+			}
+			if len(events) == 0 {
+				// A bare id moves the client's Last-Event-ID past events the filter skipped.
+				if cur := sub.Cursor(); cur > sent {
+					sent = cur
+					if !send("id: %d\n\n", cur) {
+						return
+					}
+				} else if !send(": keepalive\n\n") {
+					return
+				}
+				continue
+			}
+			var b strings.Builder
+			for _, ev := range events {
+				data, err := json.Marshal(ev)
+				if err != nil {
+					log.Printf("[audit.feed] marshal event %s: %v", ev.ID, err)
+					continue
+				}
+				fmt.Fprintf(&b, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, sseField.Replace(ev.EventType), data)
+			}
+			if !send("%s", b.String()) {
+				return
+			}
+			sent = events[len(events)-1].Seq
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/config"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+)
+
+func TestAuditStreamResumesAndFilters(t *testing.T) {
+	ctx := context.Background()
+	store := audit.NewFileStore(t.TempDir())
+	hub := audit.NewHub(store, audit.HubConfig{MaxSubscribers: 1})
+	store.SetOnAppend(func(*audit.AuditEvent) { hub.Notify() })
+	s := signer.NewLocalSigner("stream-test")
+	appendEvent := func(eventType string) {
+		if err := store.AppendAuditEvent(ctx, &audit.AuditEvent{EventType: eventType, Payload: map[string]interface{}{}}, s); err != nil {
+			t.Fatalf("AppendAuditEvent: %v", err)
+		}
+	}
+	for _, typ := range []string{"sign.completed", "eval.submitted", "sign.completed"} {
+		appendEvent(typ)
+	}
+
+	cfg := config.Default()
+	srv := httptest.NewServer(handleAuditStream(cfg, hub))
+	defer srv.Close()
+
+	if resp, err := http.Get(srv.URL + "?lastEventId=abc"); err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed Last-Event-ID, got %v %v", resp, err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"?eventType=sign.completed,allocation.applied", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	if second, err := http.Get(srv.URL); err != nil || second.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 beyond maxSubscribers, got %v %v", second, err)
+	}
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	expect := func(want ...string) {
+		for _, w := range want {
+			select {
+			case line := <-lines:
+				if !strings.HasPrefix(line, w) {
+					t.Fatalf("expected line %q, got %q", w, line)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("timed out waiting for %q", w)
+			}
+		}
+	}
+
+	// Seq 2 (eval.submitted) is filtered out; seq 3 is replayed.
+	expect(": connected", "", "id: 3", "event: sign.completed", `data: {"id":`, "")
+	appendEvent("eval.submitted")
+	appendEvent("allocation.applied")
+	expect("id: 5", "event: allocation.applied", `data: {"id":`)
+}
@@ -31,8 +31,8 @@ type DivisionManifest map[string]interface{}
 // Response: { manifest: <manifest>, manifestSignature: <ManifestSignature> }
 func handleDivisionPost(cfg *config.Config, db *sql.DB, s signer.Signer, store audit.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Production RBAC: require DivisionLead or SuperAdmin
-		if os.Getenv("NODE_ENV") == "production" {
+		// RBAC (enforced in production): require DivisionLead or SuperAdmin
+		if cfg.EnforceRBAC() {
 			ai := auth.FromContext(r.Context())
 			if ai == nil {
 				http.Error(w, "unauthenticated", http.StatusUnauthorized)
@@ -133,8 +133,8 @@ func handleDivisionPost(cfg *config.Config, db *sql.DB, s signer.Signer, store a
 // Returns the manifest JSON if present.
 func handleDivisionGet(cfg *config.Config, db *sql.DB, store audit.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Production: require authenticated principal
-		if os.Getenv("NODE_ENV") == "production" {
+		// RBAC (enforced in production): require authenticated principal
+		if cfg.EnforceRBAC() {
 			ai := auth.FromContext(r.Context())
 			if ai == nil {
 				http.Error(w, "unauthenticated", http.StatusUnauthorized)
@@ -5,14 +5,20 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/auth"
 	"github.com/ILLUVRSE/Main/kernel/internal/config"
 	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/evalclient"
 )
 
 // EvalReport is the minimal ingestion model for /kernel/eval
@@ -25,9 +31,18 @@ type EvalReport struct {
 }
 
 // POST /kernel/eval
-// Accepts an EvalReport and persists it, then emits an audit event.
-func handleEvalPost(cfg *config.Config, db *sql.DB, s signer.Signer, store audit.Store) http.HandlerFunc {
+// Accepts an EvalReport and emits eval.submitted. When the eval-engine ingestion
+// service is configured the Kernel is its authenticated front door: the report is
+// forwarded with the eval.submitted event id, so eval-engine stores that id on the
+// report and any promotion, and the response carries the computed AgentScore and
+// PromotionEvent (eval.scored records the outcome). Otherwise the report is persisted
+// locally and only its id is returned.
+// Production: service principals, SuperAdmin or Operator.
+func handleEvalPost(cfg *config.Config, db *sql.DB, s signer.Signer, store audit.Store, ingest *evalclient.Ingestion) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeEvalSubmit(cfg, w, r) {
+			return
+		}
 		var req EvalReport
 		dec := json.NewDecoder(r.Body)
 		dec.UseNumber()
@@ -40,6 +55,12 @@ func handleEvalPost(cfg *config.Config, db *sql.DB, s signer.Signer, store audit
 			http.Error(w, "agentId and metricSet required", http.StatusBadRequest)
 			return
 		}
+		if ingest != nil {
+			if msg := validateForScoring(&req); msg != "" {
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+		}
 		// Ensure id and timestamp
 		if req.Id == "" {
 			req.Id = audit.NewUUID()
@@ -49,16 +70,18 @@ func handleEvalPost(cfg *config.Config, db *sql.DB, s signer.Signer, store audit
 			req.Timestamp = &now
 		}
 
-		// persist
-		if db != nil {
-			if err := insertEvalToDB(r.Context(), db, &req); err != nil {
-				http.Error(w, "db persist error: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-		} else {
-			if err := writeEvalToFile(&req); err != nil {
-				http.Error(w, "file persist error: "+err.Error(), http.StatusInternalServerError)
-				return
+		// persist locally only when eval-engine does not own the report
+		if ingest == nil {
+			if db != nil {
+				if err := insertEvalToDB(r.Context(), db, &req); err != nil {
+					http.Error(w, "db persist error: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+			} else {
+				if err := writeEvalToFile(&req); err != nil {
+					http.Error(w, "file persist error: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
 			}
 		}
 
@@ -79,9 +102,109 @@ func handleEvalPost(cfg *config.Config, db *sql.DB, s signer.Signer, store audit
 			return
 		}
 
-		// Respond with accepted eval id
-		writeJSON(w, http.StatusAccepted, map[string]string{"eval_id": req.Id})
+		if ingest == nil {
+			// Respond with accepted eval id
+			writeJSON(w, http.StatusAccepted, map[string]string{"eval_id": req.Id})
+			return
+		}
+
+		res, err := ingest.SubmitReport(r.Context(), evalclient.EvalSubmission{
+			ID:            req.Id,
+			AgentID:       req.AgentId,
+			Metrics:       req.MetricSet,
+			Source:        req.Source,
+			Timestamp:     req.Timestamp,
+			KernelAuditID: aev.ID,
+		})
+		if err != nil {
+			failed := &audit.AuditEvent{
+				EventType: "eval.failed",
+				Payload: map[string]interface{}{
+					"evalId":           req.Id,
+					"agentId":          req.AgentId,
+					"submittedAuditId": aev.ID,
+					"error":            err.Error(),
+				},
+				Ts: time.Now().UTC(),
+			}
+			if aerr := store.AppendAuditEvent(r.Context(), failed, s); aerr != nil {
+				log.Printf("[eval] append eval.failed for %s: %v", req.Id, aerr)
+			}
+			http.Error(w, "eval-engine ingestion: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		payload := map[string]interface{}{
+			"evalId":           req.Id,
+			"agentId":          req.AgentId,
+			"reportId":         res.ReportID,
+			"score":            res.Score.Score,
+			"confidence":       res.Score.Confidence,
+			"submittedAuditId": aev.ID,
+		}
+		if res.Promotion != nil {
+			payload["promotionId"] = res.Promotion.ID
+			payload["promotionAction"] = res.Promotion.Action
+		}
+		scored := &audit.AuditEvent{EventType: "eval.scored", Payload: payload, Ts: time.Now().UTC()}
+		if err := store.AppendAuditEvent(r.Context(), scored, s); err != nil {
+			http.Error(w, "append audit event: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"eval_id":      req.Id,
+			"reportId":     res.ReportID,
+			"auditEventId": aev.ID,
+			"score":        res.Score,
+			"promotion":    res.Promotion,
+		})
+	}
+}
+
+// authorizeEvalSubmit applies the eval submission RBAC rule (enforced in production):
+// service principals (mTLS peer CN), SuperAdmin or Operator. Submissions are scored by
+// eval-engine and can promote agents, so anonymous callers are refused. It writes the
+// error response and returns false when the caller is refused.
+func authorizeEvalSubmit(cfg *config.Config, w http.ResponseWriter, r *http.Request) bool {
+	if !cfg.EnforceRBAC() {
+		return true
+	}
+	ai := auth.FromContext(r.Context())
+	if ai == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return false
+	}
+	if ai.PeerCN == "" && !auth.HasAnyRole(ai, auth.RoleSuperAdmin, auth.RoleOperator) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// validateForScoring applies eval-engine's scoring rules up front so a report it cannot
+// score is rejected with 400 instead of surfacing as a gateway error: the id (which
+// eval-engine reuses) must be a UUID and every metric a finite number.
+func validateForScoring(req *EvalReport) string {
+	if req.Id != "" {
+		if _, err := uuid.Parse(req.Id); err != nil {
+			return "id must be a UUID"
+		}
+	}
+	if len(req.MetricSet) == 0 {
+		return "metricSet must not be empty"
+	}
+	for k, v := range req.MetricSet {
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Sprintf("metricSet.%s must be a number", k)
+		}
+		f, err := n.Float64()
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Sprintf("metricSet.%s must be a finite number", k)
+		}
 	}
+	return ""
 }
 
 // persist helpers
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/auth"
+	"github.com/ILLUVRSE/Main/kernel/internal/config"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/evalclient"
+)
+
+func TestEvalForwardsToIngestion(t *testing.T) {
+	var forwarded map[string]interface{}
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/eval/submit" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&forwarded)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"reportId":  forwarded["id"],
+			"score":     map[string]interface{}{"agentId": forwarded["agentId"], "score": 0.91, "confidence": 0.7},
+			"promotion": map[string]interface{}{"id": "promo-1", "action": "promote", "kernelAuditId": forwarded["kernelAuditId"]},
+		})
+	}))
+	defer srv.Close()
+
+	ingest, err := evalclient.NewIngestion(evalclient.Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewIngestion: %v", err)
+	}
+	store := audit.NewFileStore(t.TempDir())
+	h := handleEvalPost(config.Default(), nil, signer.NewLocalSigner("s"), store, ingest)
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/kernel/eval", strings.NewReader(body)))
+		return rec
+	}
+
+	for _, body := range []string{
+		`{"agentId":"a1","metricSet":{}}`,
+		`{"agentId":"a1","metricSet":{"accuracy":"high"}}`,
+		`{"id":"eval-1","agentId":"a1","metricSet":{"accuracy":0.9}}`,
+	} {
+		if rec := post(body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+
+	rec := post(`{"agentId":"a1","metricSet":{"accuracy":0.91}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		EvalId       string                     `json:"eval_id"`
+		ReportId     string                     `json:"reportId"`
+		AuditEventId string                     `json:"auditEventId"`
+		Score        evalclient.AgentScore      `json:"score"`
+		Promotion    *evalclient.PromotionEvent `json:"promotion"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.ReportId != out.EvalId || out.Score.Score != 0.91 || out.Promotion == nil || out.Promotion.ID != "promo-1" {
+		t.Fatalf("unexpected response %+v", out)
+	}
+	if forwarded["kernelAuditId"] != out.AuditEventId || out.Promotion.KernelAuditID != out.AuditEventId {
+		t.Fatalf("eval-engine records should carry the Kernel audit id %s, got %v", out.AuditEventId, forwarded)
+	}
+	ev, err := store.GetAuditEvent(context.Background(), out.AuditEventId)
+	if err != nil || ev.EventType != "eval.submitted" {
+		t.Fatalf("audit event: %+v %v", ev, err)
+	}
+
+	fail = true
+	if rec := post(`{"agentId":"a1","metricSet":{"accuracy":0.5}}`); rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502 when ingestion is down, got %d", rec.Code)
+	}
+}
+
+func TestEvalRequiresRole(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"reportId": body["id"],
+			"score":    map[string]interface{}{"agentId": body["agentId"], "score": 0.5, "confidence": 0.5},
+		})
+	}))
+	defer srv.Close()
+	ingest, err := evalclient.NewIngestion(evalclient.Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewIngestion: %v", err)
+	}
+	enforce := true
+	cfg := config.Default()
+	cfg.RBAC.Enforce = &enforce
+	h := handleEvalPost(cfg, nil, signer.NewLocalSigner("s"), audit.NewFileStore(t.TempDir()), ingest)
+	post := func(ai *auth.AuthInfo) int {
+		req := httptest.NewRequest(http.MethodPost, "/kernel/eval", strings.NewReader(`{"agentId":"a1","metricSet":{"accuracy":0.5}}`))
+		if ai != nil {
+			req = req.WithContext(auth.ContextWithAuthInfo(req.Context(), ai))
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+
+	if code := post(nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a principal, got %d", code)
+	}
+	if code := post(&auth.AuthInfo{Subject: "eve", Roles: []string{auth.RoleAuditor}}); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an auditor, got %d", code)
+	}
+	if calls != 0 {
+		t.Fatalf("refused submissions must not reach eval-engine, got %d calls", calls)
+	}
+	if code := post(&auth.AuthInfo{PeerCN: "ai-infra"}); code != http.StatusOK {
+		t.Fatalf("expected 200 for a service principal, got %d", code)
+	}
+	if code := post(&auth.AuthInfo{Subject: "olga", Roles: []string{auth.RoleOperator}}); code != http.StatusOK {
+		t.Fatalf("expected 200 for an operator, got %d", code)
+	}
+}
@@ -8,23 +8,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"reflect"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/ILLUVRSE/Main/kernel/internal/allocation"
 	"github.com/ILLUVRSE/Main/kernel/internal/audit"
 	"github.com/ILLUVRSE/Main/kernel/internal/auth"
 	"github.com/ILLUVRSE/Main/kernel/internal/canonical"
 	"github.com/ILLUVRSE/Main/kernel/internal/config"
 	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/kernel/internal/signjobs"
+	"github.com/ILLUVRSE/Main/shared/evalclient"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 // RegisterRoutes wires kernel HTTP routes.
 //
 // It accepts the AppContext instance from cmd/kernel/main.go (as an empty interface)
-// and extracts the fields it needs via reflection: Config, DB, Signer, Store.
+// and extracts the fields it needs via reflection: Config, DB, Signer, Store, the
+// optional SignJobs store that enables /kernel/sign/jobs, the optional Allocations
+// service (a record-only service is built when it is absent), the optional
+// EvalIngestion client that /kernel/eval forwards to and the optional AuditFeed hub
+// that enables /kernel/audit/stream.
 func RegisterRoutes(app interface{}, r chi.Router) {
 	cfg, db, sgn, store, ok := extractDependencies(app)
 	if !ok {
@@ -48,14 +55,31 @@ func RegisterRoutes(app interface{}, r chi.Router) {
 	r.Get("/kernel/agent/{id}/state", handleAgentGet(cfg, db, store))
 
 	// Eval and Allocation
-	r.Post("/kernel/eval", handleEvalPost(cfg, db, sgn, store))
-	r.Post("/kernel/allocate", handleAllocatePost(cfg, db, sgn, store))
+	r.Post("/kernel/eval", handleEvalPost(cfg, db, sgn, store, extractEvalIngestion(app)))
+	// Allocation requests are driven through internal/allocation (see allocate.go)
+	allocs := extractAllocations(app)
+	if allocs == nil {
+		allocs = allocation.NewService(allocation.StoreFor(db), nil, store, sgn, allocation.Config{})
+	}
+	r.Post("/kernel/allocate", handleAllocatePost(cfg, allocs))
+	r.Get("/kernel/allocate/{id}", handleAllocateGet(cfg, allocs))
 
 	// Sign & Audit
-	r.Post("/kernel/sign", handleSign(sgn, store))
+	r.Post("/kernel/sign", handleSign(cfg, sgn, store))
+	// Batch signing implemented in kernel/internal/handlers/sign_batch.go
+	r.Post("/kernel/sign/batch", handleSignBatch(cfg, sgn, store))
+	// Asynchronous signing jobs implemented in kernel/internal/handlers/sign_jobs.go
+	if jobs := extractSignJobs(app); jobs != nil {
+		r.Post("/kernel/sign/jobs", handleSignJobPost(cfg, sgn, store, jobs))
+		r.Get("/kernel/sign/jobs/{id}", handleSignJobGet(cfg, jobs))
+		r.Post("/kernel/sign/jobs/{id}/decision", handleSignJobDecision(cfg, sgn, store, jobs))
+	}
 	// Audit handlers implemented in kernel/internal/handlers/audit.go
 	r.Post("/kernel/audit", handleAuditPost(cfg, sgn, store))
-	r.Get("/kernel/audit/{id}", handleAuditGet(store))
+	r.Get("/kernel/audit/{id}", handleAuditGet(cfg, store))
+	if hub := extractAuditFeed(app); hub != nil {
+		r.Get("/kernel/audit/stream", handleAuditStream(cfg, hub))
+	}
 
 	// Reasoning trace (implemented in kernel/internal/handlers/reason.go)
 	r.Get("/kernel/reason/{node}", handleReasonGet(cfg, store))
@@ -121,6 +145,57 @@ func extractDependencies(app interface{}) (cfg *config.Config, db *sql.DB, sgn s
 	return cfg, dbp, sgnCast, storeCast, true
 }
 
+// appField returns the named field of the app context, or nil when it is absent or nil.
+func appField(app interface{}, name string) interface{} {
+	v := reflect.ValueOf(app)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.FieldByName(name)
+	if !f.IsValid() {
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if f.IsNil() {
+			return nil
+		}
+	}
+	return f.Interface()
+}
+
+// extractSignJobs returns the optional SignJobs store from the app context, or nil when
+// asynchronous signing is not wired.
+func extractSignJobs(app interface{}) signjobs.Store {
+	jobs, _ := appField(app, "SignJobs").(signjobs.Store)
+	return jobs
+}
+
+// extractAllocations returns the optional Allocations service from the app context.
+func extractAllocations(app interface{}) *allocation.Service {
+	allocs, _ := appField(app, "Allocations").(*allocation.Service)
+	return allocs
+}
+
+// extractEvalIngestion returns the optional eval-engine ingestion client from the app
+// context; without it /kernel/eval only records reports.
+func extractEvalIngestion(app interface{}) *evalclient.Ingestion {
+	ingest, _ := appField(app, "EvalIngestion").(*evalclient.Ingestion)
+	return ingest
+}
+
+// extractAuditFeed returns the optional audit feed hub that enables /kernel/audit/stream.
+func extractAuditFeed(app interface{}) *audit.Hub {
+	hub, _ := appField(app, "AuditFeed").(*audit.Hub)
+	return hub
+}
+
 // --- Handlers (core handlers retained here; division/agent/reason handled in separate files) ---
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -149,20 +224,10 @@ func handleReady(cfg *config.Config, db *sql.DB, store audit.Store) http.Handler
 // POST /kernel/sign
 // Request: { "manifest": {...}, "signerId":"...", "version":"1.0.0" }
 // Response: ManifestSignature
-func handleSign(s signer.Signer, store audit.Store) http.HandlerFunc {
+func handleSign(cfg *config.Config, s signer.Signer, store audit.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Production RBAC: allow service principals (mTLS peer CN) OR SuperAdmin role.
-		if os.Getenv("NODE_ENV") == "production" {
-			ai := auth.FromContext(r.Context())
-			if ai == nil {
-				http.Error(w, "unauthenticated", http.StatusUnauthorized)
-				return
-			}
-			// If no PeerCN (not a service principal), require SuperAdmin role.
-			if ai.PeerCN == "" && !auth.HasRole(ai, auth.RoleSuperAdmin) {
-				http.Error(w, "forbidden", http.StatusForbidden)
-				return
-			}
+		if !authorizeSign(cfg, w, r) {
+			return
 		}
 
 		var req struct {
@@ -190,27 +255,18 @@ func handleSign(s signer.Signer, store audit.Store) http.HandlerFunc {
 
 		// sign hash
 		sum := sha256.Sum256(canon)
+		_, span := tracing.Start(r.Context(), "kernel.sign")
 		sig, signerId, err := s.Sign(sum[:])
+		span.SetAttribute("signer.id", signerId)
+		span.RecordError(err)
+		span.End()
 		if err != nil {
 			http.Error(w, "sign error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// choose manifestId if present
-		manifestId := ""
-		if m, ok := req.Manifest.(map[string]interface{}); ok {
-			if idv, ok := m["id"]; ok {
-				if s2, ok := idv.(string); ok && s2 != "" {
-					manifestId = s2
-				}
-			}
-		}
-		if manifestId == "" {
-			manifestId = fmt.Sprintf("%x-%d", sum[:8], time.Now().Unix())
-		}
-
 		ms := audit.ManifestSignature{
-			ManifestId: manifestId,
+			ManifestId: manifestIDFor(req.Manifest, sum[:]),
 			SignerId:   signerId,
 			Signature:  base64.StdEncoding.EncodeToString(sig),
 			Version:    req.Version,
@@ -226,6 +282,36 @@ func handleSign(s signer.Signer, store audit.Store) http.HandlerFunc {
 	}
 }
 
+// authorizeSign applies the signing RBAC rule (enforced in production): service
+// principals (mTLS peer CN) or the SuperAdmin role. It writes the error response and
+// returns false when the caller may not sign.
+func authorizeSign(cfg *config.Config, w http.ResponseWriter, r *http.Request) bool {
+	if !cfg.EnforceRBAC() {
+		return true
+	}
+	ai := auth.FromContext(r.Context())
+	if ai == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return false
+	}
+	// If no PeerCN (not a service principal), require SuperAdmin role.
+	if ai.PeerCN == "" && !auth.HasRole(ai, auth.RoleSuperAdmin) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// manifestIDFor returns the manifest's "id" field, or one derived from its hash.
+func manifestIDFor(manifest interface{}, sum []byte) string {
+	if m, ok := manifest.(map[string]interface{}); ok {
+		if id, ok := m["id"].(string); ok && id != "" {
+			return id
+		}
+	}
+	return fmt.Sprintf("%x-%d", sum[:8], time.Now().Unix())
+}
+
 // helper JSON writer
 func writeJSON(w http.ResponseWriter, code int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/canonical"
+	"github.com/ILLUVRSE/Main/kernel/internal/config"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/merkle"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+// Batch signing modes.
+const (
+	SignBatchEach   = "each"   // one signature per manifest hash
+	SignBatchMerkle = "merkle" // one signature over the RFC 6962 Merkle root of the hashes
+)
+
+// maxSignBatchBody bounds a batch request body; single requests use MaxJSONBody.
+const maxSignBatchBody = 16 << 20
+
+// SignBatchItem is one manifest in a batch request.
+type SignBatchItem struct {
+	Manifest interface{} `json:"manifest"`
+	Version  string      `json:"version,omitempty"` // defaults to the batch version
+}
+
+// SignBatchResult reports the outcome for one item. Items that failed carry Error and
+// nothing else; the rest of the batch is unaffected.
+type SignBatchResult struct {
+	Index      int    `json:"index"`
+	ManifestId string `json:"manifestId,omitempty"`
+	Hash       string `json:"hash,omitempty"` // hex sha256 of the canonical manifest
+
+	// each mode
+	SignatureId string `json:"signatureId,omitempty"`
+	Signature   string `json:"signature,omitempty"` // base64
+
+	// merkle mode: verify with merkle.Verify(hash, LeafIndex, treeSize, Proof, root)
+	LeafIndex *int     `json:"leafIndex,omitempty"`
+	Proof     []string `json:"proof,omitempty"` // hex audit path, leaf to root
+
+	Error string `json:"error,omitempty"`
+}
+
+// SignBatchResponse is returned by POST /kernel/sign/batch.
+type SignBatchResponse struct {
+	BatchId       string            `json:"batchId"`
+	Mode          string            `json:"mode"`
+	SignerId      string            `json:"signerId,omitempty"`
+	Root          string            `json:"root,omitempty"`          // merkle: hex root
+	RootSignature string            `json:"rootSignature,omitempty"` // merkle: base64 signature over the root
+	TreeSize      int               `json:"treeSize,omitempty"`      // merkle: number of leaves
+	AuditId       string            `json:"auditId,omitempty"`
+	Succeeded     int               `json:"succeeded"`
+	Failed        int               `json:"failed"`
+	Items         []SignBatchResult `json:"items"`
+}
+
+// POST /kernel/sign/batch
+// Request: { "mode": "each"|"merkle", "version": "1.0.0", "items": [{ "manifest": {...}, "version"?: "..." }] }
+// Each manifest is canonicalized and hashed like POST /kernel/sign. In "each" mode every
+// hash is signed and stored as a ManifestSignature; in "merkle" mode only the Merkle root
+// is signed (one KMS call) and each item gets an inclusion proof. Items that cannot be
+// canonicalized or signed are reported individually. One "manifest.batch_signed" audit
+// event references every signed manifest id.
+func handleSignBatch(cfg *config.Config, s signer.Signer, store audit.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeSign(cfg, w, r) {
+			return
+		}
+
+		var req struct {
+			Mode    string          `json:"mode"`
+			Version string          `json:"version"`
+			Items   []SignBatchItem `json:"items"`
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxSignBatchBody)
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Mode == "" {
+			req.Mode = SignBatchEach
+		}
+		if req.Mode != SignBatchEach && req.Mode != SignBatchMerkle {
+			http.Error(w, fmt.Sprintf("mode must be %q or %q", SignBatchEach, SignBatchMerkle), http.StatusBadRequest)
+			return
+		}
+		if len(req.Items) == 0 {
+			http.Error(w, "items required", http.StatusBadRequest)
+			return
+		}
+		if len(req.Items) > cfg.Signer.BatchMaxItems {
+			http.Error(w, fmt.Sprintf("batch of %d items exceeds the limit of %d", len(req.Items), cfg.Signer.BatchMaxItems), http.StatusBadRequest)
+			return
+		}
+
+		ctx, span := tracing.Start(r.Context(), "kernel.sign_batch")
+		defer span.End()
+		span.SetAttribute("batch.mode", req.Mode)
+		span.SetAttribute("batch.items", len(req.Items))
+
+		resp := SignBatchResponse{BatchId: audit.NewUUID(), Mode: req.Mode, Items: make([]SignBatchResult, len(req.Items))}
+		hashes := make([][]byte, len(req.Items))
+		for i, item := range req.Items {
+			res := &resp.Items[i]
+			res.Index = i
+			if item.Manifest == nil {
+				res.Error = "manifest required"
+				continue
+			}
+			canon, err := canonical.MarshalCanonical(item.Manifest)
+			if err != nil {
+				res.Error = "canonicalize error: " + err.Error()
+				continue
+			}
+			sum := sha256.Sum256(canon)
+			hashes[i] = sum[:]
+			res.Hash = hex.EncodeToString(sum[:])
+			res.ManifestId = manifestIDFor(item.Manifest, sum[:])
+		}
+
+		sign := func(hash []byte) ([]byte, string, error) {
+			_, span := tracing.Start(ctx, "kernel.sign")
+			defer span.End()
+			sig, signerId, err := s.Sign(hash)
+			span.SetAttribute("signer.id", signerId)
+			span.RecordError(err)
+			return sig, signerId, err
+		}
+
+		switch req.Mode {
+		case SignBatchEach:
+			for i := range resp.Items {
+				res := &resp.Items[i]
+				if res.Error != "" {
+					continue
+				}
+				sig, signerId, err := sign(hashes[i])
+				if err != nil {
+					res.Error = "sign error: " + err.Error()
+					continue
+				}
+				version := req.Items[i].Version
+				if version == "" {
+					version = req.Version
+				}
+				ms := audit.ManifestSignature{
+					ManifestId: res.ManifestId,
+					SignerId:   signerId,
+					Signature:  base64.StdEncoding.EncodeToString(sig),
+					Version:    version,
+					Ts:         time.Now().UTC(),
+				}
+				if err := store.InsertManifestSignature(ctx, &ms); err != nil {
+					res.Error = "store manifest signature: " + err.Error()
+					continue
+				}
+				resp.SignerId = signerId
+				res.SignatureId = ms.ID
+				res.Signature = ms.Signature
+			}
+
+		case SignBatchMerkle:
+			var leaves [][]byte
+			for i := range resp.Items {
+				if resp.Items[i].Error == "" {
+					leaves = append(leaves, hashes[i])
+				}
+			}
+			if len(leaves) == 0 {
+				break
+			}
+			tree := merkle.NewTree(leaves)
+			root := tree.Root()
+			sig, signerId, err := sign(root)
+			if err != nil {
+				for i := range resp.Items {
+					if resp.Items[i].Error == "" {
+						resp.Items[i].Error = "sign error: " + err.Error()
+					}
+				}
+				break
+			}
+			ms := audit.ManifestSignature{
+				ManifestId: "merkle:" + hex.EncodeToString(root),
+				SignerId:   signerId,
+				Signature:  base64.StdEncoding.EncodeToString(sig),
+				Version:    req.Version,
+				Ts:         time.Now().UTC(),
+			}
+			if err := store.InsertManifestSignature(ctx, &ms); err != nil {
+				http.Error(w, "store manifest signature: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			resp.SignerId = signerId
+			resp.Root = hex.EncodeToString(root)
+			resp.RootSignature = ms.Signature
+			resp.TreeSize = len(leaves)
+			leaf := 0
+			for i := range resp.Items {
+				res := &resp.Items[i]
+				if res.Error != "" {
+					continue
+				}
+				proof, err := tree.Proof(leaf)
+				if err != nil {
+					http.Error(w, "merkle proof: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				idx := leaf
+				res.LeafIndex = &idx
+				for _, p := range proof {
+					res.Proof = append(res.Proof, hex.EncodeToString(p))
+				}
+				leaf++
+			}
+		}
+
+		var signed []string
+		var failed []map[string]interface{}
+		for _, res := range resp.Items {
+			if res.Error != "" {
+				resp.Failed++
+				failed = append(failed, map[string]interface{}{"index": res.Index, "manifestId": res.ManifestId, "error": res.Error})
+				continue
+			}
+			resp.Succeeded++
+			signed = append(signed, res.ManifestId)
+		}
+		span.SetAttribute("batch.failed", resp.Failed)
+		if resp.Succeeded == 0 {
+			writeJSON(w, http.StatusUnprocessableEntity, resp)
+			return
+		}
+
+		payload := map[string]interface{}{
+			"batchId":     resp.BatchId,
+			"mode":        resp.Mode,
+			"signerId":    resp.SignerId,
+			"manifestIds": signed,
+			"failed":      failed,
+		}
+		if resp.Mode == SignBatchMerkle {
+			payload["root"] = resp.Root
+			payload["treeSize"] = resp.TreeSize
+		}
+		ev := &audit.AuditEvent{
+			EventType: "manifest.batch_signed",
+			Payload:   payload,
+			Ts:        time.Now().UTC(),
+		}
+		if err := store.AppendAuditEvent(ctx, ev, s); err != nil {
+			http.Error(w, "append audit event: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp.AuditId = ev.ID
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
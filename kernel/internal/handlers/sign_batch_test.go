@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/config"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/merkle"
+)
+
+func postSignBatch(t *testing.T, h http.HandlerFunc, body string) (*httptest.ResponseRecorder, SignBatchResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/kernel/sign/batch", strings.NewReader(body)))
+	var resp SignBatchResponse
+	if rec.Code == http.StatusOK || rec.Code == http.StatusUnprocessableEntity {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v: %s", err, rec.Body.String())
+		}
+	}
+	return rec, resp
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestSignBatchEach(t *testing.T) {
+	cfg := config.Default()
+	s := signer.NewLocalSigner("batch-signer")
+	store := audit.NewFileStore(t.TempDir())
+	h := handleSignBatch(cfg, s, store)
+
+	rec, resp := postSignBatch(t, h, `{"version":"1.0.0","items":[
+		{"manifest":{"id":"m-1","v":1}},
+		{"manifest":null},
+		{"manifest":{"id":"m-3","v":3},"version":"2.0.0"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp.Succeeded != 2 || resp.Failed != 1 || resp.Items[1].Error != "manifest required" {
+		t.Fatalf("expected the null manifest to fail alone, got %+v", resp)
+	}
+	for _, i := range []int{0, 2} {
+		item := resp.Items[i]
+		sig, _ := base64.StdEncoding.DecodeString(item.Signature)
+		if !ed25519.Verify(s.PublicKey(), mustHex(t, item.Hash), sig) {
+			t.Fatalf("item %d signature does not verify", i)
+		}
+	}
+
+	ev, err := store.GetAuditEvent(context.Background(), resp.AuditId)
+	if err != nil {
+		t.Fatalf("audit event: %v", err)
+	}
+	payload := ev.Payload.(map[string]interface{})
+	ids, _ := json.Marshal(payload["manifestIds"])
+	if ev.EventType != "manifest.batch_signed" || string(ids) != `["m-1","m-3"]` {
+		t.Fatalf("unexpected audit event %s %+v", ev.EventType, payload)
+	}
+}
+
+func TestSignBatchMerkle(t *testing.T) {
+	cfg := config.Default()
+	s := signer.NewLocalSigner("batch-signer")
+	h := handleSignBatch(cfg, s, audit.NewFileStore(t.TempDir()))
+
+	rec, resp := postSignBatch(t, h, `{"mode":"merkle","items":[
+		{"manifest":{"id":"a"}},{"manifest":{"id":"b"}},{},{"manifest":{"id":"c"}}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	root := mustHex(t, resp.Root)
+	sig, _ := base64.StdEncoding.DecodeString(resp.RootSignature)
+	if !ed25519.Verify(s.PublicKey(), root, sig) {
+		t.Fatalf("root signature does not verify")
+	}
+	if resp.TreeSize != 3 || resp.Failed != 1 || resp.Items[2].LeafIndex != nil {
+		t.Fatalf("failed items must be left out of the tree, got %+v", resp)
+	}
+	for _, item := range resp.Items {
+		if item.Error != "" {
+			continue
+		}
+		var proof [][]byte
+		for _, p := range item.Proof {
+			proof = append(proof, mustHex(t, p))
+		}
+		if !merkle.Verify(mustHex(t, item.Hash), *item.LeafIndex, resp.TreeSize, proof, root) {
+			t.Fatalf("inclusion proof for %s does not verify", item.ManifestId)
+		}
+	}
+}
+
+func TestSignBatchRejects(t *testing.T) {
+	cfg := config.Default()
+	cfg.Signer.BatchMaxItems = 2
+	h := handleSignBatch(cfg, signer.NewLocalSigner("batch-signer"), audit.NewFileStore(t.TempDir()))
+
+	for body, want := range map[string]int{
+		`{"items":[]}`: http.StatusBadRequest,
+		`{"mode":"tree","items":[{"manifest":{}}]}`:                   http.StatusBadRequest,
+		`{"items":[{"manifest":{}},{"manifest":{}},{"manifest":{}}]}`: http.StatusBadRequest,
+		`{"items":[{},{}]}`: http.StatusUnprocessableEntity,
+	} {
+		if rec, _ := postSignBatch(t, h, body); rec.Code != want {
+			t.Fatalf("%s: expected %d, got %d", body, want, rec.Code)
+		}
+	}
+}
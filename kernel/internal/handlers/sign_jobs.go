@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/auth"
+	"github.com/ILLUVRSE/Main/kernel/internal/canonical"
+	"github.com/ILLUVRSE/Main/kernel/internal/config"
+	"github.com/ILLUVRSE/Main/kernel/internal/ratelimit"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/kernel/internal/signjobs"
+)
+
+// POST /kernel/sign/jobs
+// Request: { "manifest": {...}, "version": "1.0.0", "callbackUrl"?: "https://...", "requireApproval"?: true }
+// Response: 202 with the queued Job. The manifest is canonicalized and hashed now; a
+// signjobs.Worker signs the hash later. Jobs wait in awaiting_approval when the request
+// or signJobs.requireApproval asks for an operator decision.
+func handleSignJobPost(cfg *config.Config, s signer.Signer, store audit.Store, jobs signjobs.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeSign(cfg, w, r) {
+			return
+		}
+
+		var req struct {
+			Manifest        interface{} `json:"manifest"`
+			Version         string      `json:"version"`
+			CallbackURL     string      `json:"callbackUrl"`
+			RequireApproval bool        `json:"requireApproval"`
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Manifest == nil {
+			http.Error(w, "manifest required", http.StatusBadRequest)
+			return
+		}
+		if req.CallbackURL != "" {
+			if cfg.SignJobs.CallbackSecret == "" {
+				http.Error(w, "callbacks are disabled: no callback secret configured", http.StatusBadRequest)
+				return
+			}
+			if msg := checkCallbackURL(cfg, req.CallbackURL); msg != "" {
+				http.Error(w, msg, http.StatusBadRequest)
+				return
+			}
+		}
+
+		canon, err := canonical.MarshalCanonical(req.Manifest)
+		if err != nil {
+			http.Error(w, "canonicalize error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sum := sha256.Sum256(canon)
+
+		now := time.Now().UTC()
+		job := &signjobs.Job{
+			ID:              audit.NewUUID(),
+			Status:          signjobs.StatusQueued,
+			ManifestId:      manifestIDFor(req.Manifest, sum[:]),
+			ManifestHash:    hex.EncodeToString(sum[:]),
+			Version:         req.Version,
+			Requester:       ratelimit.PrincipalKey(auth.FromContext(r.Context()), r, ratelimit.KeyByPrincipal),
+			RequireApproval: req.RequireApproval || cfg.SignJobs.RequireApproval,
+			CallbackURL:     req.CallbackURL,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		}
+		if job.RequireApproval {
+			job.Status = signjobs.StatusAwaitingApproval
+		}
+		if err := jobs.Create(r.Context(), job); err != nil {
+			http.Error(w, "create sign job: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := signjobs.RecordTransition(r.Context(), store, s, job); err != nil {
+			http.Error(w, "append audit event: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Location", "/kernel/sign/jobs/"+job.ID)
+		writeJSON(w, http.StatusAccepted, job)
+	}
+}
+
+// GET /kernel/sign/jobs/{id}
+func handleSignJobGet(cfg *config.Config, jobs signjobs.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeSign(cfg, w, r) {
+			return
+		}
+		job, err := jobs.Get(r.Context(), chi.URLParam(r, "id"))
+		if errors.Is(err, signjobs.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "get sign job: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	}
+}
+
+// POST /kernel/sign/jobs/{id}/decision
+// Request: { "approve": true|false, "reason"?: "..." }
+// Releases a job awaiting approval to the workers or rejects it. Under RBAC only a
+// SuperAdmin other than the requester may decide.
+func handleSignJobDecision(cfg *config.Config, s signer.Signer, store audit.Store, jobs signjobs.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ai := auth.FromContext(r.Context())
+		decider := ratelimit.PrincipalKey(ai, r, ratelimit.KeyByPrincipal)
+		if cfg.EnforceRBAC() {
+			if ai == nil {
+				http.Error(w, "unauthenticated", http.StatusUnauthorized)
+				return
+			}
+			if !auth.HasRole(ai, auth.RoleSuperAdmin) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+
+		var req struct {
+			Approve *bool  `json:"approve"`
+			Reason  string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid json: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Approve == nil {
+			http.Error(w, "approve required", http.StatusBadRequest)
+			return
+		}
+
+		id := chi.URLParam(r, "id")
+		if cfg.EnforceRBAC() {
+			job, err := jobs.Get(r.Context(), id)
+			if err == nil && job.Requester == decider {
+				http.Error(w, "the requester cannot decide their own sign job", http.StatusForbidden)
+				return
+			}
+		}
+
+		job, err := jobs.Decide(r.Context(), id, *req.Approve, decider, req.Reason)
+		switch {
+		case errors.Is(err, signjobs.ErrNotFound):
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		case errors.Is(err, signjobs.ErrConflict):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, "decide sign job: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := signjobs.RecordTransition(r.Context(), store, s, job); err != nil {
+			http.Error(w, "append audit event: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	}
+}
+
+// checkCallbackURL returns why u is unacceptable as a callback target, or "".
+func checkCallbackURL(cfg *config.Config, u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return "callbackUrl must be an absolute http(s) URL"
+	}
+	if cfg.IsProduction() && parsed.Scheme != "https" {
+		return "callbackUrl must use https in production"
+	}
+	if len(cfg.SignJobs.CallbackHosts) == 0 {
+		return "callbacks are disabled: no callback hosts configured"
+	}
+	if !signjobs.CallbackHostAllowed(cfg.SignJobs.CallbackHosts, parsed) {
+		return "callbackUrl host " + parsed.Host + " is not an allowed callback host"
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/auth"
+	"github.com/ILLUVRSE/Main/kernel/internal/config"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/kernel/internal/signjobs"
+)
+
+func signJobRouter(cfg *config.Config, s signer.Signer, store audit.Store, jobs signjobs.Store) chi.Router {
+	r := chi.NewRouter()
+	r.Post("/kernel/sign/jobs", handleSignJobPost(cfg, s, store, jobs))
+	r.Get("/kernel/sign/jobs/{id}", handleSignJobGet(cfg, jobs))
+	r.Post("/kernel/sign/jobs/{id}/decision", handleSignJobDecision(cfg, s, store, jobs))
+	return r
+}
+
+func doSignJob(t *testing.T, r http.Handler, method, path, body string, ai *auth.AuthInfo) (*httptest.ResponseRecorder, signjobs.Job) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if ai != nil {
+		req = req.WithContext(auth.ContextWithAuthInfo(req.Context(), ai))
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	var job signjobs.Job
+	if rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+			t.Fatalf("decode job: %v: %s", err, rec.Body.String())
+		}
+	}
+	return rec, job
+}
+
+func TestSignJobApprovalFlow(t *testing.T) {
+	enforce := true
+	cfg := config.Default()
+	cfg.RBAC.Enforce = &enforce
+	s := signer.NewLocalSigner("jobs-signer")
+	store := audit.NewFileStore(t.TempDir())
+	jobs := signjobs.NewMemoryStore()
+	r := signJobRouter(cfg, s, store, jobs)
+
+	svc := &auth.AuthInfo{PeerCN: "ai-infra"}
+	admin := &auth.AuthInfo{Subject: "alice", Roles: []string{auth.RoleSuperAdmin}}
+
+	rec, job := doSignJob(t, r, http.MethodPost, "/kernel/sign/jobs", `{"manifest":{"id":"model-7"},"version":"1.0.0","requireApproval":true}`, svc)
+	if rec.Code != http.StatusAccepted || job.Status != signjobs.StatusAwaitingApproval || job.Requester != "cn:ai-infra" {
+		t.Fatalf("create: %d %+v", rec.Code, job)
+	}
+	if rec.Header().Get("Location") != "/kernel/sign/jobs/"+job.ID {
+		t.Fatalf("missing Location header")
+	}
+
+	decision := "/kernel/sign/jobs/" + job.ID + "/decision"
+	if rec, _ := doSignJob(t, r, http.MethodPost, decision, `{"approve":true}`, svc); rec.Code != http.StatusForbidden {
+		t.Fatalf("a service principal must not approve, got %d", rec.Code)
+	}
+	if rec, _ := doSignJob(t, r, http.MethodPost, decision, `{}`, admin); rec.Code != http.StatusBadRequest {
+		t.Fatalf("missing approve should be rejected, got %d", rec.Code)
+	}
+	rec, job = doSignJob(t, r, http.MethodPost, decision, `{"approve":true,"reason":"CHG-42"}`, admin)
+	if rec.Code != http.StatusOK || job.Status != signjobs.StatusQueued || job.DecidedBy != "sub:alice" {
+		t.Fatalf("approve: %d %+v", rec.Code, job)
+	}
+	if rec, _ := doSignJob(t, r, http.MethodPost, decision, `{"approve":false}`, admin); rec.Code != http.StatusConflict {
+		t.Fatalf("second decision should conflict, got %d", rec.Code)
+	}
+
+	w := signjobs.NewWorker(jobs, s, store, signjobs.WorkerConfig{})
+	if worked, err := w.RunOnce(context.Background()); !worked || err != nil {
+		t.Fatalf("RunOnce: %v %v", worked, err)
+	}
+	rec, job = doSignJob(t, r, http.MethodGet, "/kernel/sign/jobs/"+job.ID, "", svc)
+	if rec.Code != http.StatusOK || job.Status != signjobs.StatusSucceeded || job.Signature == "" || job.SignerId != "jobs-signer" {
+		t.Fatalf("get: %d %+v", rec.Code, job)
+	}
+	if rec, _ := doSignJob(t, r, http.MethodGet, "/kernel/sign/jobs/nope", "", svc); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestSignJobRejectsBadCallbacks(t *testing.T) {
+	cfg := config.Default()
+	r := signJobRouter(cfg, signer.NewLocalSigner("s"), audit.NewFileStore(t.TempDir()), signjobs.NewMemoryStore())
+
+	body := `{"manifest":{"id":"m"},"callbackUrl":"https://idea.example/cb"}`
+	if rec, _ := doSignJob(t, r, http.MethodPost, "/kernel/sign/jobs", body, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("callbacks without a secret should be refused, got %d", rec.Code)
+	}
+
+	cfg.SignJobs.CallbackSecret = "k"
+	if rec, _ := doSignJob(t, r, http.MethodPost, "/kernel/sign/jobs", body, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("callbacks without allowed hosts should be refused, got %d", rec.Code)
+	}
+
+	cfg.SignJobs.CallbackHosts = []string{"idea.example"}
+	for _, u := range []string{"ftp://idea.example/cb", "/relative", "https://", "http://169.254.169.254/latest", "https://idea.example.evil/cb"} {
+		b := `{"manifest":{"id":"m"},"callbackUrl":"` + u + `"}`
+		if rec, _ := doSignJob(t, r, http.MethodPost, "/kernel/sign/jobs", b, nil); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", u, rec.Code)
+		}
+	}
+	rec, job := doSignJob(t, r, http.MethodPost, "/kernel/sign/jobs", body, nil)
+	if rec.Code != http.StatusAccepted || job.Status != signjobs.StatusQueued || job.CallbackURL == "" {
+		t.Fatalf("create with callback: %d %+v", rec.Code, job)
+	}
+}
@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/auth"
+)
+
+// Key strategies for Rule.KeyBy.
+const (
+	KeyByPrincipal = "principal" // mTLS peer CN, else OIDC subject, else client IP
+	KeyByRole      = "role"      // the principal's canonical roles (shared by everyone holding them)
+)
+
+// Rule limits one route, identified by method and exact path.
+type Rule struct {
+	Method string
+	Path   string
+	Limit  Limit
+	KeyBy  string
+}
+
+// Breach describes a limited request; see Middleware.SetBreachRecorder.
+type Breach struct {
+	Route      string
+	Principal  string
+	Limit      Limit
+	RetryAfter time.Duration
+}
+
+// Middleware enforces Rules in front of the Kernel router. It must run after the auth
+// and OIDC middleware so the principal is known.
+type Middleware struct {
+	limiter Limiter
+	rules   map[string]Rule
+
+	recordBreach   func(context.Context, Breach)
+	breachInterval time.Duration
+	mu             sync.Mutex
+	lastBreach     map[string]time.Time
+}
+
+// NewMiddleware returns a Middleware applying rules with limiter.
+func NewMiddleware(limiter Limiter, rules []Rule) *Middleware {
+	m := &Middleware{
+		limiter:        limiter,
+		rules:          make(map[string]Rule, len(rules)),
+		breachInterval: time.Minute,
+		lastBreach:     make(map[string]time.Time),
+	}
+	for _, rule := range rules {
+		rule.Method = strings.ToUpper(rule.Method)
+		if rule.KeyBy == "" {
+			rule.KeyBy = KeyByPrincipal
+		}
+		m.rules[rule.Method+" "+rule.Path] = rule
+	}
+	return m
+}
+
+// SetBreachRecorder registers fn to be called (in its own goroutine) when a request is
+// limited. Breaches are reported at most once per bucket per minute so a client hammering
+// the Kernel cannot turn the rejections into an audit-log flood.
+func (m *Middleware) SetBreachRecorder(fn func(context.Context, Breach)) {
+	m.recordBreach = fn
+}
+
+// Handler wraps next.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+		rule, ok := m.rules[route]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal := PrincipalKey(auth.FromContext(r.Context()), r, rule.KeyBy)
+		bucket := route + "|" + principal
+		d, err := m.limiter.Allow(r.Context(), bucket, rule.Limit)
+		if err != nil {
+			// Fail open: a limiter outage must not take signing down with it.
+			log.Printf("[ratelimit] limiter error for %s: %v (allowing request)", bucket, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if d.Allowed {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m.breach(r.Context(), bucket, Breach{Route: route, Principal: principal, Limit: rule.Limit, RetryAfter: d.RetryAfter})
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(d.RetryAfter.Seconds())))))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	})
+}
+
+func (m *Middleware) breach(ctx context.Context, bucket string, b Breach) {
+	if m.recordBreach == nil {
+		return
+	}
+	now := time.Now()
+	m.mu.Lock()
+	if last, ok := m.lastBreach[bucket]; ok && now.Sub(last) < m.breachInterval {
+		m.mu.Unlock()
+		return
+	}
+	for k, last := range m.lastBreach {
+		if now.Sub(last) >= m.breachInterval {
+			delete(m.lastBreach, k)
+		}
+	}
+	m.lastBreach[bucket] = now
+	m.mu.Unlock()
+
+	go m.recordBreach(context.WithoutCancel(ctx), b)
+}
+
+// PrincipalKey identifies the caller for bucketing.
+func PrincipalKey(ai *auth.AuthInfo, r *http.Request, keyBy string) string {
+	if keyBy == KeyByRole && ai != nil && len(ai.Roles) > 0 {
+		roles := append([]string(nil), ai.Roles...)
+		sort.Strings(roles)
+		return "role:" + strings.Join(roles, ",")
+	}
+	if ai != nil && ai.PeerCN != "" {
+		return "cn:" + ai.PeerCN
+	}
+	if ai != nil && ai.Subject != "" {
+		return "sub:" + ai.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
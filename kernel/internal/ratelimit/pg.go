@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PGLimiter keeps buckets in the rate_limit_buckets table (sql/migrations/008_rate_limits.sql)
+// so every replica draws from the same budget. Each Allow locks the bucket row for the
+// duration of one short transaction and uses the database clock.
+type PGLimiter struct {
+	db *sql.DB
+}
+
+// NewPGLimiter returns a Postgres-backed limiter.
+func NewPGLimiter(db *sql.DB) *PGLimiter {
+	return &PGLimiter{db: db}
+}
+
+// Allow implements Limiter.
+func (p *PGLimiter) Allow(ctx context.Context, key string, limit Limit) (Decision, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Decision{}, fmt.Errorf("ratelimit: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (bucket_key, tokens, updated_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (bucket_key) DO NOTHING`, key, float64(limit.Burst)); err != nil {
+		return Decision{}, fmt.Errorf("ratelimit: init bucket: %w", err)
+	}
+
+	var (
+		tokens       float64
+		updated, now time.Time
+	)
+	if err := tx.QueryRowContext(ctx, `
+		SELECT tokens, updated_at, now()
+		FROM rate_limit_buckets
+		WHERE bucket_key = $1
+		FOR UPDATE`, key).Scan(&tokens, &updated, &now); err != nil {
+		return Decision{}, fmt.Errorf("ratelimit: lock bucket: %w", err)
+	}
+
+	d := take(&tokens, now.Sub(updated), limit)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3 WHERE bucket_key = $1`,
+		key, tokens, now); err != nil {
+		return Decision{}, fmt.Errorf("ratelimit: update bucket: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Decision{}, fmt.Errorf("ratelimit: commit: %w", err)
+	}
+	return d, nil
+}
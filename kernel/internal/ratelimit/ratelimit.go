@@ -0,0 +1,95 @@
+// Package ratelimit provides token-bucket rate limiting for Kernel endpoints.
+//
+// Buckets are keyed by route and principal. MemoryLimiter keeps them in process (one
+// replica); PGLimiter keeps them in Postgres so all replicas share one budget.
+// Middleware applies per-route rules, answers limited requests with 429 and Retry-After,
+// and reports breaches (throttled per bucket) so they can be written to the audit log.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Burst tokens, refilled at Rate tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Decision is the outcome of taking one token.
+type Decision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // time until a token is available when not allowed
+}
+
+// Limiter takes one token from the bucket identified by key.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// take refills tokens for elapsed and tries to consume one.
+func take(tokens *float64, elapsed time.Duration, limit Limit) Decision {
+	if elapsed > 0 {
+		*tokens = math.Min(float64(limit.Burst), *tokens+elapsed.Seconds()*limit.Rate)
+	}
+	if *tokens >= 1 {
+		*tokens--
+		return Decision{Allowed: true, Remaining: int(*tokens)}
+	}
+	wait := time.Duration((1 - *tokens) / limit.Rate * float64(time.Second))
+	return Decision{RetryAfter: wait}
+}
+
+// MemoryLimiter keeps buckets in process memory.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Duration // time to refill from empty; idle buckets past it are dropped
+}
+
+// NewMemoryLimiter returns an empty in-memory limiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{buckets: make(map[string]*memoryBucket), now: time.Now}
+}
+
+// Allow implements Limiter.
+func (m *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+	b.full = time.Duration(float64(limit.Burst) / limit.Rate * float64(time.Second))
+	d := take(&b.tokens, now.Sub(b.updated), limit)
+	b.updated = now
+	return d, nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again, at most once a minute.
+func (m *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.updated) > b.full {
+			delete(m.buckets, key)
+		}
+	}
+}
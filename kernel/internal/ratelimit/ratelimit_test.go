@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/auth"
+)
+
+func TestMemoryLimiterTokenBucket(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	m := NewMemoryLimiter()
+	m.now = func() time.Time { return now }
+	limit := Limit{Rate: 2, Burst: 3}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if d, _ := m.Allow(ctx, "k", limit); !d.Allowed {
+			t.Fatalf("request %d within burst was limited", i)
+		}
+	}
+	d, _ := m.Allow(ctx, "k", limit)
+	if d.Allowed || d.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected limit with 500ms retry, got %+v", d)
+	}
+	if d, _ := m.Allow(ctx, "other", limit); !d.Allowed {
+		t.Fatalf("buckets must be independent per key")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if d, _ := m.Allow(ctx, "k", limit); !d.Allowed {
+		t.Fatalf("token should have refilled")
+	}
+
+	now = now.Add(time.Hour)
+	m.Allow(ctx, "fresh", limit)
+	if _, ok := m.buckets["k"]; ok {
+		t.Fatalf("idle full buckets should be swept")
+	}
+}
+
+func TestPGLimiterAllow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO rate_limit_buckets").
+		WithArgs("POST /kernel/sign|cn:svc", float64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT tokens, updated_at, now\\(\\)").
+		WithArgs("POST /kernel/sign|cn:svc").
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "updated_at", "now"}).AddRow(0.25, now.Add(-250*time.Millisecond), now))
+	mock.ExpectExec("UPDATE rate_limit_buckets").
+		WithArgs("POST /kernel/sign|cn:svc", 0.5, now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	d, err := NewPGLimiter(db).Allow(context.Background(), "POST /kernel/sign|cn:svc", Limit{Rate: 1, Burst: 5})
+	if err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	if d.Allowed || d.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected limit with 500ms retry, got %+v", d)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+type errLimiter struct{}
+
+func (errLimiter) Allow(context.Context, string, Limit) (Decision, error) {
+	return Decision{}, errors.New("db down")
+}
+
+func TestMiddleware(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		breaches []Breach
+		recorded = make(chan struct{}, 10)
+	)
+	m := NewMiddleware(NewMemoryLimiter(), []Rule{
+		{Method: "post", Path: "/kernel/sign", Limit: Limit{Rate: 0.1, Burst: 1}},
+	})
+	m.SetBreachRecorder(func(_ context.Context, b Breach) {
+		mu.Lock()
+		breaches = append(breaches, b)
+		mu.Unlock()
+		recorded <- struct{}{}
+	})
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	call := func(method, path, cn string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if cn != "" {
+			req = req.WithContext(auth.ContextWithAuthInfo(req.Context(), &auth.AuthInfo{PeerCN: cn}))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := call(http.MethodPost, "/kernel/sign", "ai-infra"); rec.Code != http.StatusOK {
+		t.Fatalf("first request should pass, got %d", rec.Code)
+	}
+	for i := 0; i < 3; i++ {
+		rec := call(http.MethodPost, "/kernel/sign", "ai-infra")
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "10" {
+			t.Fatalf("expected 429 with Retry-After 10, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+	if rec := call(http.MethodPost, "/kernel/sign", "eval-engine"); rec.Code != http.StatusOK {
+		t.Fatalf("other principals have their own bucket, got %d", rec.Code)
+	}
+	if rec := call(http.MethodGet, "/kernel/sign", "ai-infra"); rec.Code != http.StatusOK {
+		t.Fatalf("routes without a rule are not limited, got %d", rec.Code)
+	}
+
+	select {
+	case <-recorded:
+	case <-time.After(time.Second):
+		t.Fatalf("breach not recorded")
+	}
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(breaches) != 1 || breaches[0].Principal != "cn:ai-infra" || breaches[0].Route != "POST /kernel/sign" {
+		t.Fatalf("expected one throttled breach record, got %+v", breaches)
+	}
+
+	open := NewMiddleware(errLimiter{}, []Rule{{Method: "POST", Path: "/kernel/sign", Limit: Limit{Rate: 1, Burst: 1}}})
+	rec := httptest.NewRecorder()
+	open.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/kernel/sign", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("limiter errors should fail open, got %d", rec.Code)
+	}
+}
+
+func TestPrincipalKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/kernel/sign", nil)
+	r.RemoteAddr = "10.0.0.7:5123"
+	cases := []struct {
+		ai    *auth.AuthInfo
+		keyBy string
+		want  string
+	}{
+		{nil, KeyByPrincipal, "ip:10.0.0.7"},
+		{&auth.AuthInfo{Subject: "user-1"}, KeyByPrincipal, "sub:user-1"},
+		{&auth.AuthInfo{PeerCN: "svc", Subject: "user-1"}, KeyByPrincipal, "cn:svc"},
+		{&auth.AuthInfo{Subject: "user-1", Roles: []string{"SuperAdmin", "Auditor"}}, KeyByRole, "role:Auditor,SuperAdmin"},
+		{&auth.AuthInfo{Subject: "user-1"}, KeyByRole, "sub:user-1"},
+	}
+	for _, c := range cases {
+		if got := PrincipalKey(c.ai, r, c.keyBy); got != c.want {
+			t.Fatalf("PrincipalKey(%+v, %s) = %q, want %q", c.ai, c.keyBy, got, c.want)
+		}
+	}
+}
@@ -29,11 +29,47 @@ type kmsSigner struct {
 	publicKey   []byte
 }
 
-// NewKMSSigner creates a KMS-backed signer. If kmsEndpoint is empty and requireKMS is true,
-// an error is returned. If kmsEndpoint is empty and requireKMS is false, (nil, nil) is returned
-// so callers may fall back to a local signer.
+// KMSSignerConfig configures a KMS-backed signer.
+type KMSSignerConfig struct {
+	Endpoint    string        // KMS base URL
+	SignerID    string        // signer id reported with signatures (default kernel-signer-kms)
+	BearerToken string        // optional bearer token for the KMS
+	Timeout     time.Duration // request timeout (default 5s)
+	// Optional mTLS material for the KMS connection.
+	MTLSCertPath string
+	MTLSKeyPath  string
+	MTLSCAPath   string
+	// RequireKMS turns best-effort failures (mTLS material, public key fetch) into errors.
+	RequireKMS bool
+}
+
+// NewKMSSigner creates a KMS-backed signer from kmsEndpoint and the SIGNER_ID,
+// KMS_BEARER_TOKEN, KMS_TIMEOUT_MS and KMS_MTLS_* environment variables. If kmsEndpoint
+// is empty and requireKMS is true, an error is returned. If kmsEndpoint is empty and
+// requireKMS is false, (nil, nil) is returned so callers may fall back to a local signer.
 func NewKMSSigner(kmsEndpoint string, requireKMS bool) (Signer, error) {
-	kmsEndpoint = strings.TrimRight(kmsEndpoint, "/")
+	cfg := KMSSignerConfig{
+		Endpoint:     kmsEndpoint,
+		SignerID:     os.Getenv("SIGNER_ID"),
+		BearerToken:  os.Getenv("KMS_BEARER_TOKEN"),
+		MTLSCertPath: os.Getenv("KMS_MTLS_CERT_PATH"),
+		MTLSKeyPath:  os.Getenv("KMS_MTLS_KEY_PATH"),
+		MTLSCAPath:   os.Getenv("KMS_MTLS_CA_PATH"),
+		RequireKMS:   requireKMS,
+	}
+	if v := os.Getenv("KMS_TIMEOUT_MS"); v != "" {
+		if t, err := strconv.Atoi(v); err == nil && t > 0 {
+			cfg.Timeout = time.Duration(t) * time.Millisecond
+		}
+	}
+	return NewKMSSignerFromConfig(cfg)
+}
+
+// NewKMSSignerFromConfig creates a KMS-backed signer. An empty Endpoint is an error when
+// RequireKMS is set and (nil, nil) otherwise.
+func NewKMSSignerFromConfig(cfg KMSSignerConfig) (Signer, error) {
+	kmsEndpoint := strings.TrimRight(cfg.Endpoint, "/")
+	requireKMS := cfg.RequireKMS
 	if kmsEndpoint == "" {
 		if requireKMS {
 			return nil, fmt.Errorf("REQUIRE_KMS=true but KMS_ENDPOINT not set")
@@ -41,23 +77,19 @@ func NewKMSSigner(kmsEndpoint string, requireKMS bool) (Signer, error) {
 		return nil, nil
 	}
 
-	// Environment-driven configuration (kept small and explicit)
-	signerId := os.Getenv("SIGNER_ID")
+	signerId := cfg.SignerID
 	if signerId == "" {
 		signerId = "kernel-signer-kms"
 	}
-	bearer := os.Getenv("KMS_BEARER_TOKEN")
-
-	timeoutMs := 5000
-	if v := os.Getenv("KMS_TIMEOUT_MS"); v != "" {
-		if t, err := strconv.Atoi(v); err == nil && t > 0 {
-			timeoutMs = t
-		}
+	bearer := cfg.BearerToken
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
 	}
 
-	certPath := os.Getenv("KMS_MTLS_CERT_PATH")
-	keyPath := os.Getenv("KMS_MTLS_KEY_PATH")
-	caPath := os.Getenv("KMS_MTLS_CA_PATH")
+	certPath := cfg.MTLSCertPath
+	keyPath := cfg.MTLSKeyPath
+	caPath := cfg.MTLSCAPath
 
 	var tlsCfg *tls.Config
 	if certPath != "" && keyPath != "" {
@@ -113,7 +145,7 @@ func NewKMSSigner(kmsEndpoint string, requireKMS bool) (Signer, error) {
 	tr := &http.Transport{TLSClientConfig: tlsCfg}
 	client := &http.Client{
 		Transport: tr,
-		Timeout:   time.Duration(timeoutMs) * time.Millisecond,
+		Timeout:   timeout,
 	}
 
 	ks := &kmsSigner{
@@ -0,0 +1,207 @@
+package signjobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PGStore keeps jobs in the sign_jobs table (sql/migrations/009_sign_jobs.sql). Claims
+// use FOR UPDATE SKIP LOCKED so concurrent workers on any replica never take the same job.
+type PGStore struct {
+	db *sql.DB
+}
+
+// NewPGStore returns a Postgres-backed job store.
+func NewPGStore(db *sql.DB) *PGStore {
+	return &PGStore{db: db}
+}
+
+const jobColumns = `id, status, manifest_id, manifest_hash, version, requester, require_approval,
+	decided_by, decision_reason, signature_id, signer_id, signature, error,
+	callback_url, callback_status, callback_attempts, callback_error,
+	created_at, updated_at, completed_at, next_callback_at, lease_until`
+
+// Create implements Store.
+func (p *PGStore) Create(ctx context.Context, j *Job) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO sign_jobs (`+jobColumns+`)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22)`,
+		j.ID, string(j.Status), j.ManifestId, j.ManifestHash, j.Version, j.Requester, j.RequireApproval,
+		j.DecidedBy, j.DecisionReason, j.SignatureId, j.SignerId, j.Signature, j.Error,
+		j.CallbackURL, j.CallbackStatus, j.CallbackAttempts, j.CallbackError,
+		j.CreatedAt, j.UpdatedAt, j.CompletedAt, j.NextCallbackAt, j.LeaseUntil)
+	if err != nil {
+		return fmt.Errorf("signjobs: insert job: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store.
+func (p *PGStore) Get(ctx context.Context, id string) (*Job, error) {
+	j, err := scanJob(p.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM sign_jobs WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signjobs: get job: %w", err)
+	}
+	return j, nil
+}
+
+// Decide implements Store.
+func (p *PGStore) Decide(ctx context.Context, id string, approve bool, by, reason string) (*Job, error) {
+	status := StatusRejected
+	if approve {
+		status = StatusQueued
+	}
+	j, err := scanJob(p.db.QueryRowContext(ctx, `
+		UPDATE sign_jobs SET
+		  status = $2, decided_by = $3, decision_reason = $4, updated_at = now(),
+		  completed_at = CASE WHEN $5 THEN NULL ELSE now() END,
+		  callback_status = CASE WHEN NOT $5 AND callback_url <> '' THEN 'pending' ELSE callback_status END,
+		  next_callback_at = CASE WHEN NOT $5 AND callback_url <> '' THEN now() ELSE next_callback_at END
+		WHERE id = $1 AND status = 'awaiting_approval'
+		RETURNING `+jobColumns, id, string(status), by, reason, approve))
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, gerr := p.Get(ctx, id); gerr != nil {
+			return nil, gerr
+		}
+		return nil, errNotAwaitingApproval
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signjobs: decide job: %w", err)
+	}
+	return j, nil
+}
+
+// ClaimSign implements Store.
+func (p *PGStore) ClaimSign(ctx context.Context, lease time.Duration) (*Job, error) {
+	j, err := scanJob(p.db.QueryRowContext(ctx, `
+		UPDATE sign_jobs SET
+		  status = 'signing', updated_at = now(),
+		  lease_until = now() + $1 * interval '1 millisecond'
+		WHERE id = (
+		  SELECT id FROM sign_jobs
+		  WHERE status = 'queued' OR (status = 'signing' AND lease_until < now())
+		  ORDER BY created_at
+		  LIMIT 1
+		  FOR UPDATE SKIP LOCKED)
+		RETURNING `+jobColumns, lease.Milliseconds()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signjobs: claim job: %w", err)
+	}
+	return j, nil
+}
+
+// ClaimCallback implements Store.
+func (p *PGStore) ClaimCallback(ctx context.Context, lease time.Duration) (*Job, error) {
+	j, err := scanJob(p.db.QueryRowContext(ctx, `
+		UPDATE sign_jobs SET
+		  next_callback_at = now() + $1 * interval '1 millisecond'
+		WHERE id = (
+		  SELECT id FROM sign_jobs
+		  WHERE callback_status = 'pending' AND next_callback_at <= now()
+		  ORDER BY next_callback_at
+		  LIMIT 1
+		  FOR UPDATE SKIP LOCKED)
+		RETURNING `+jobColumns, lease.Milliseconds()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signjobs: claim callback: %w", err)
+	}
+	return j, nil
+}
+
+// Update implements Store.
+func (p *PGStore) Update(ctx context.Context, j *Job, lease Lease) error {
+	res, err := p.db.ExecContext(ctx, `
+		UPDATE sign_jobs SET
+		  status = $2, signature_id = $3, signer_id = $4, signature = $5, error = $6,
+		  callback_status = $7, callback_attempts = $8, callback_error = $9,
+		  updated_at = $10, completed_at = $11, next_callback_at = $12, lease_until = $13
+		WHERE id = $1 AND CASE WHEN $14
+		  THEN status = 'signing' AND lease_until = $15
+		  ELSE callback_status = 'pending' AND next_callback_at = $15 END`,
+		j.ID, string(j.Status), j.SignatureId, j.SignerId, j.Signature, j.Error,
+		j.CallbackStatus, j.CallbackAttempts, j.CallbackError,
+		j.UpdatedAt, j.CompletedAt, j.NextCallbackAt, j.LeaseUntil,
+		lease.Signing, lease.Until)
+	if err != nil {
+		return fmt.Errorf("signjobs: update job: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("signjobs: update job: %w", err)
+	}
+	if n == 0 {
+		if _, err := p.Get(ctx, j.ID); err != nil {
+			return err
+		}
+		return errClaimLost
+	}
+	return nil
+}
+
+// Renew implements Store.
+func (p *PGStore) Renew(ctx context.Context, j *Job, lease Lease, until time.Time) (Lease, error) {
+	res, err := p.db.ExecContext(ctx, `
+		UPDATE sign_jobs SET
+		  lease_until = CASE WHEN $2 THEN $4 ELSE lease_until END,
+		  next_callback_at = CASE WHEN $2 THEN next_callback_at ELSE $4 END
+		WHERE id = $1 AND CASE WHEN $2
+		  THEN status = 'signing' AND lease_until = $3
+		  ELSE callback_status = 'pending' AND next_callback_at = $3 END`,
+		j.ID, lease.Signing, lease.Until, until)
+	if err != nil {
+		return Lease{}, fmt.Errorf("signjobs: renew lease: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return Lease{}, fmt.Errorf("signjobs: renew lease: %w", err)
+	}
+	if n == 0 {
+		if _, err := p.Get(ctx, j.ID); err != nil {
+			return Lease{}, err
+		}
+		return Lease{}, errClaimLost
+	}
+	renewed := Lease{Signing: lease.Signing, Until: until}
+	renewed.set(j)
+	return renewed, nil
+}
+
+func scanJob(row *sql.Row) (*Job, error) {
+	var (
+		j                                   Job
+		status                              string
+		completed, nextCallback, leaseUntil sql.NullTime
+	)
+	err := row.Scan(&j.ID, &status, &j.ManifestId, &j.ManifestHash, &j.Version, &j.Requester, &j.RequireApproval,
+		&j.DecidedBy, &j.DecisionReason, &j.SignatureId, &j.SignerId, &j.Signature, &j.Error,
+		&j.CallbackURL, &j.CallbackStatus, &j.CallbackAttempts, &j.CallbackError,
+		&j.CreatedAt, &j.UpdatedAt, &completed, &nextCallback, &leaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	j.Status = Status(status)
+	j.CompletedAt = nullTime(completed)
+	j.NextCallbackAt = nullTime(nextCallback)
+	j.LeaseUntil = nullTime(leaseUntil)
+	return &j, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time.UTC()
+	return &v
+}
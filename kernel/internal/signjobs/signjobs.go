@@ -0,0 +1,295 @@
+// Package signjobs runs manifest signing asynchronously for callers that cannot hold a
+// request open while KMS/HSM signs (for example when an HSM operator must approve).
+//
+// POST /kernel/sign/jobs records a Job and returns its id. A Worker claims queued jobs,
+// signs the manifest hash and stores the ManifestSignature; the result is read with
+// GET /kernel/sign/jobs/{id} or POSTed to the job's callback URL, HMAC-signed and
+// retried with backoff. Jobs that require approval wait in awaiting_approval until an
+// operator approves or rejects them. Every state transition is written to the audit log.
+//
+// MemoryStore keeps jobs in process (development); PGStore keeps them in Postgres so
+// several Kernel replicas can share the queue.
+package signjobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is a job's position in its lifecycle.
+type Status string
+
+// Job states. Succeeded, Failed and Rejected are terminal.
+const (
+	StatusAwaitingApproval Status = "awaiting_approval"
+	StatusQueued           Status = "queued"
+	StatusSigning          Status = "signing"
+	StatusSucceeded        Status = "succeeded"
+	StatusFailed           Status = "failed"
+	StatusRejected         Status = "rejected"
+)
+
+// Terminal reports whether no further state transitions follow s.
+func (s Status) Terminal() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusRejected
+}
+
+// Callback delivery states.
+const (
+	CallbackPending   = "pending"
+	CallbackDelivered = "delivered"
+	CallbackFailed    = "failed"
+)
+
+var (
+	// ErrNotFound is returned for unknown job ids.
+	ErrNotFound = errors.New("signjobs: job not found")
+	// ErrConflict is returned when a job is not in the state an operation requires.
+	ErrConflict = errors.New("signjobs: job is not in the required state")
+
+	errNotAwaitingApproval = fmt.Errorf("%w: not awaiting approval", ErrConflict)
+	errClaimLost           = fmt.Errorf("%w: claim lost to another worker", ErrConflict)
+)
+
+// Job is one asynchronous signing request. Its JSON form is the GET response and the
+// callback body.
+type Job struct {
+	ID              string `json:"id"`
+	Status          Status `json:"status"`
+	ManifestId      string `json:"manifestId"`
+	ManifestHash    string `json:"manifestHash"` // hex sha256 of the canonical manifest; this is what gets signed
+	Version         string `json:"version,omitempty"`
+	Requester       string `json:"requester,omitempty"`
+	RequireApproval bool   `json:"requireApproval"`
+	DecidedBy       string `json:"decidedBy,omitempty"`
+	DecisionReason  string `json:"decisionReason,omitempty"`
+
+	// Set when the job succeeded.
+	SignatureId string `json:"signatureId,omitempty"`
+	SignerId    string `json:"signerId,omitempty"`
+	Signature   string `json:"signature,omitempty"` // base64
+
+	// Set when the job failed.
+	Error string `json:"error,omitempty"`
+
+	CallbackURL      string `json:"callbackUrl,omitempty"`
+	CallbackStatus   string `json:"callbackStatus,omitempty"`
+	CallbackAttempts int    `json:"callbackAttempts,omitempty"`
+	CallbackError    string `json:"callbackError,omitempty"`
+
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+	// NextCallbackAt is when the next delivery attempt is due; LeaseUntil is when a
+	// claimed job may be reclaimed by another worker.
+	NextCallbackAt *time.Time `json:"-"`
+	LeaseUntil     *time.Time `json:"-"`
+}
+
+// Lease is a worker's hold on a job returned by a claim. A signing lease (from ClaimSign)
+// holds while the job is signing with LeaseUntil equal to Until; a callback lease (from
+// ClaimCallback) while its callback is pending with NextCallbackAt equal to Until.
+type Lease struct {
+	Signing bool
+	Until   time.Time
+}
+
+// set makes j held under l.
+func (l Lease) set(j *Job) {
+	until := l.Until
+	if l.Signing {
+		j.LeaseUntil = &until
+	} else {
+		j.NextCallbackAt = &until
+	}
+}
+
+// held reports whether j is still held under l.
+func (l Lease) held(j *Job) bool {
+	if l.Signing {
+		return j.Status == StatusSigning && j.LeaseUntil != nil && j.LeaseUntil.Equal(l.Until)
+	}
+	return j.CallbackStatus == CallbackPending && j.NextCallbackAt != nil && j.NextCallbackAt.Equal(l.Until)
+}
+
+// Store persists jobs. Claims are exclusive: a job returned by ClaimSign or
+// ClaimCallback is not returned again until its lease expires.
+type Store interface {
+	// Create persists a new job.
+	Create(ctx context.Context, job *Job) error
+
+	// Get returns the job with id or ErrNotFound.
+	Get(ctx context.Context, id string) (*Job, error)
+
+	// Decide moves a job out of awaiting_approval: to queued when approve is true,
+	// otherwise to rejected. It returns ErrConflict for jobs in any other state.
+	Decide(ctx context.Context, id string, approve bool, by, reason string) (*Job, error)
+
+	// ClaimSign moves the oldest queued job (or a signing job whose lease expired) to
+	// signing under a lease and returns it, or nil when there is none.
+	ClaimSign(ctx context.Context, lease time.Duration) (*Job, error)
+
+	// ClaimCallback returns a job whose callback delivery is due, pushing its next
+	// attempt out by lease, or nil when there is none.
+	ClaimCallback(ctx context.Context, lease time.Duration) (*Job, error)
+
+	// Update writes the job's mutable fields (status, result, callback state) if the
+	// job is still held under lease. It returns ErrConflict when the lease was lost,
+	// for example because it expired and another worker reclaimed the job.
+	Update(ctx context.Context, job *Job, lease Lease) error
+
+	// Renew extends a lease that is still held to until, updating job, and returns the
+	// new lease. It returns ErrConflict when the lease was lost, like Update. Workers
+	// renew before side effects that must happen at most once per job.
+	Renew(ctx context.Context, job *Job, lease Lease, until time.Time) (Lease, error)
+}
+
+// MemoryStore keeps jobs in process memory; jobs are lost on restart.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+	now  func() time.Time
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]*Job), now: time.Now}
+}
+
+// Create implements Store.
+func (m *MemoryStore) Create(_ context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = clone(job)
+	return nil
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(_ context.Context, id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return clone(j), nil
+}
+
+// Decide implements Store.
+func (m *MemoryStore) Decide(_ context.Context, id string, approve bool, by, reason string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if j.Status != StatusAwaitingApproval {
+		return nil, errNotAwaitingApproval
+	}
+	now := m.now().UTC()
+	j.DecidedBy, j.DecisionReason, j.UpdatedAt = by, reason, now
+	if approve {
+		j.Status = StatusQueued
+	} else {
+		j.Status = StatusRejected
+		j.CompletedAt = &now
+		if j.CallbackURL != "" {
+			j.CallbackStatus = CallbackPending
+			j.NextCallbackAt = &now
+		}
+	}
+	return clone(j), nil
+}
+
+// ClaimSign implements Store.
+func (m *MemoryStore) ClaimSign(_ context.Context, lease time.Duration) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now().UTC()
+	var next *Job
+	for _, j := range m.sorted() {
+		if j.Status == StatusQueued || (j.Status == StatusSigning && j.LeaseUntil != nil && j.LeaseUntil.Before(now)) {
+			next = j
+			break
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+	until := now.Add(lease)
+	next.Status, next.LeaseUntil, next.UpdatedAt = StatusSigning, &until, now
+	return clone(next), nil
+}
+
+// ClaimCallback implements Store.
+func (m *MemoryStore) ClaimCallback(_ context.Context, lease time.Duration) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now().UTC()
+	for _, j := range m.sorted() {
+		if j.CallbackStatus == CallbackPending && j.NextCallbackAt != nil && !j.NextCallbackAt.After(now) {
+			until := now.Add(lease)
+			j.NextCallbackAt = &until
+			return clone(j), nil
+		}
+	}
+	return nil, nil
+}
+
+// Update implements Store.
+func (m *MemoryStore) Update(_ context.Context, job *Job, lease Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.jobs[job.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if !lease.held(current) {
+		return errClaimLost
+	}
+	m.jobs[job.ID] = clone(job)
+	return nil
+}
+
+// Renew implements Store.
+func (m *MemoryStore) Renew(_ context.Context, job *Job, lease Lease, until time.Time) (Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	current, ok := m.jobs[job.ID]
+	if !ok {
+		return Lease{}, ErrNotFound
+	}
+	if !lease.held(current) {
+		return Lease{}, errClaimLost
+	}
+	renewed := Lease{Signing: lease.Signing, Until: until}
+	renewed.set(current)
+	renewed.set(job)
+	return renewed, nil
+}
+
+// sorted returns jobs oldest first so claims are FIFO.
+func (m *MemoryStore) sorted() []*Job {
+	out := make([]*Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		out = append(out, j)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].CreatedAt.Before(out[b].CreatedAt) })
+	return out
+}
+
+func clone(j *Job) *Job {
+	c := *j
+	for _, t := range []**time.Time{&c.CompletedAt, &c.NextCallbackAt, &c.LeaseUntil} {
+		if *t != nil {
+			v := **t
+			*t = &v
+		}
+	}
+	return &c
+}
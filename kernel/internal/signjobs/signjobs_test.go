@@ -0,0 +1,425 @@
+package signjobs
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+)
+
+func newJob(id string, created time.Time) *Job {
+	sum := sha256.Sum256([]byte(id))
+	return &Job{
+		ID:           id,
+		Status:       StatusQueued,
+		ManifestId:   "manifest-" + id,
+		ManifestHash: hex.EncodeToString(sum[:]),
+		CreatedAt:    created,
+		UpdatedAt:    created,
+	}
+}
+
+func TestMemoryStoreClaimsAndDecisions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0).UTC()
+	m := NewMemoryStore()
+	m.now = func() time.Time { return now }
+
+	_ = m.Create(ctx, newJob("b", now.Add(time.Second)))
+	_ = m.Create(ctx, newJob("a", now))
+	held := newJob("held", now.Add(-time.Minute))
+	held.Status = StatusAwaitingApproval
+	_ = m.Create(ctx, held)
+
+	j, _ := m.ClaimSign(ctx, time.Minute)
+	if j == nil || j.ID != "a" || j.Status != StatusSigning {
+		t.Fatalf("expected FIFO claim of a, got %+v", j)
+	}
+	stale, staleLease := j, Lease{Signing: true, Until: *j.LeaseUntil}
+	j, _ = m.ClaimSign(ctx, time.Minute)
+	if j == nil || j.ID != "b" {
+		t.Fatalf("expected b, got %+v", j)
+	}
+	if j, _ := m.ClaimSign(ctx, time.Minute); j != nil {
+		t.Fatalf("jobs awaiting approval or under lease must not be claimed, got %s", j.ID)
+	}
+
+	now = now.Add(2 * time.Minute)
+	j, _ = m.ClaimSign(ctx, time.Minute)
+	if j == nil || j.ID != "a" {
+		t.Fatalf("expired lease should be reclaimed, got %+v", j)
+	}
+	stale.Status = StatusFailed
+	if err := m.Update(ctx, stale, staleLease); !errors.Is(err, ErrConflict) {
+		t.Fatalf("updating under a lost lease should conflict, got %v", err)
+	}
+	if got, _ := m.Get(ctx, "a"); got.Status != StatusSigning {
+		t.Fatalf("a lost lease must not overwrite the job, got %+v", got)
+	}
+	if err := m.Update(ctx, j, Lease{Signing: true, Until: *j.LeaseUntil}); err != nil {
+		t.Fatalf("update under the current lease: %v", err)
+	}
+
+	if _, err := m.Decide(ctx, "a", true, "cn:op", ""); !errors.Is(err, ErrConflict) {
+		t.Fatalf("deciding a signing job should conflict, got %v", err)
+	}
+	if _, err := m.Decide(ctx, "missing", true, "cn:op", ""); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	j, err := m.Decide(ctx, "held", true, "cn:op", "ticket-1")
+	if err != nil || j.Status != StatusQueued || j.DecidedBy != "cn:op" {
+		t.Fatalf("approve: %+v %v", j, err)
+	}
+}
+
+// blockingSigner holds signatures over one hash until release is closed, like an HSM
+// waiting for an operator; audit events are signed immediately.
+type blockingSigner struct {
+	signer.Signer
+	hash    string
+	release chan struct{}
+}
+
+func (b *blockingSigner) Sign(hash []byte) ([]byte, string, error) {
+	if hex.EncodeToString(hash) == b.hash {
+		<-b.release
+	}
+	return b.Signer.Sign(hash)
+}
+
+func TestWorkerSignsAndDeliversCallback(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("s3cret")
+
+	var (
+		mu       sync.Mutex
+		received []*Job
+		failures = 2
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !kernelclient.VerifySignJobCallback(secret, r.Header.Get(HeaderTimestamp), body, r.Header.Get(HeaderSignature)) {
+			t.Errorf("bad callback signature %q", r.Header.Get(HeaderSignature))
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var j Job
+		_ = json.Unmarshal(body, &j)
+		received = append(received, &j)
+	}))
+	defer srv.Close()
+
+	jobs := NewMemoryStore()
+	local := signer.NewLocalSigner("jobs-signer")
+	store := audit.NewFileStore(t.TempDir())
+	w := NewWorker(jobs, local, store, WorkerConfig{CallbackSecret: secret, CallbackHosts: []string{serverHost(srv)}, CallbackMaxAttempts: 5, CallbackBackoff: time.Millisecond})
+
+	job := newJob("j1", time.Now().UTC())
+	job.CallbackURL = srv.URL
+	_ = jobs.Create(ctx, job)
+
+	for i := 0; i < 20; i++ {
+		worked, err := w.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		if !worked {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if got, _ := jobs.Get(ctx, "j1"); got.CallbackStatus == CallbackDelivered {
+			break
+		}
+	}
+
+	got, _ := jobs.Get(ctx, "j1")
+	if got.Status != StatusSucceeded || got.CallbackStatus != CallbackDelivered || got.CallbackAttempts != 3 {
+		t.Fatalf("unexpected job state %+v", got)
+	}
+	sig, _ := base64.StdEncoding.DecodeString(got.Signature)
+	hash, _ := hex.DecodeString(got.ManifestHash)
+	if !ed25519.Verify(local.PublicKey(), hash, sig) {
+		t.Fatalf("job signature does not verify")
+	}
+	if len(received) != 1 || received[0].Signature != got.Signature || received[0].Status != StatusSucceeded {
+		t.Fatalf("callback body should carry the result, got %+v", received)
+	}
+}
+
+func TestWorkerGivesUpOnCallback(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	jobs := NewMemoryStore()
+	w := NewWorker(jobs, signer.NewLocalSigner("s"), audit.NewFileStore(t.TempDir()),
+		WorkerConfig{CallbackHosts: []string{serverHost(srv)}, CallbackMaxAttempts: 2, CallbackBackoff: time.Millisecond})
+	job := newJob("j2", time.Now().UTC())
+	job.CallbackURL = srv.URL
+	_ = jobs.Create(ctx, job)
+
+	for i := 0; i < 20; i++ {
+		if _, err := w.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		if got, _ := jobs.Get(ctx, "j2"); got.CallbackStatus == CallbackFailed {
+			break
+		}
+		time.Sleep(3 * time.Millisecond)
+	}
+	got, _ := jobs.Get(ctx, "j2")
+	if got.CallbackStatus != CallbackFailed || got.CallbackAttempts != 2 || got.CallbackError == "" {
+		t.Fatalf("expected delivery to be abandoned after 2 attempts, got %+v", got)
+	}
+}
+
+func TestWorkerRefusesDisallowedCallbackHosts(t *testing.T) {
+	ctx := context.Background()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	jobs := NewMemoryStore()
+	w := NewWorker(jobs, signer.NewLocalSigner("s"), audit.NewFileStore(t.TempDir()),
+		WorkerConfig{CallbackHosts: []string{"hooks.example"}, CallbackMaxAttempts: 1})
+	job := newJob("j5", time.Now().UTC())
+	job.CallbackURL = srv.URL
+	_ = jobs.Create(ctx, job)
+	for i := 0; i < 3; i++ {
+		if _, err := w.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+	}
+	got, _ := jobs.Get(ctx, "j5")
+	if got.CallbackStatus != CallbackFailed || !strings.Contains(got.CallbackError, "not allowed") || atomic.LoadInt32(&requests) != 0 {
+		t.Fatalf("expected delivery to a disallowed host to fail without a request, got %+v", got)
+	}
+
+	// Redirects from an allowed host are not followed.
+	w.cfg.CallbackHosts = []string{serverHost(srv)}
+	job = newJob("j6", time.Now().UTC())
+	job.CallbackURL = srv.URL
+	_ = jobs.Create(ctx, job)
+	for i := 0; i < 3; i++ {
+		if _, err := w.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+	}
+	got, _ = jobs.Get(ctx, "j6")
+	if got.CallbackStatus != CallbackFailed || got.CallbackError != "callback returned HTTP 302" {
+		t.Fatalf("expected the redirect to fail delivery, got %+v", got)
+	}
+}
+
+// serverHost returns the host:port a test server listens on.
+func serverHost(srv *httptest.Server) string {
+	u, _ := url.Parse(srv.URL)
+	return u.Host
+}
+
+func TestWorkerLeaseBoundsSlowSigner(t *testing.T) {
+	ctx := context.Background()
+	jobs := NewMemoryStore()
+	job := newJob("j3", time.Now().UTC())
+	slow := &blockingSigner{Signer: signer.NewLocalSigner("hsm"), hash: job.ManifestHash, release: make(chan struct{})}
+	defer close(slow.release)
+	w := NewWorker(jobs, slow, audit.NewFileStore(t.TempDir()), WorkerConfig{Lease: 20 * time.Millisecond})
+	_ = jobs.Create(ctx, job)
+
+	if _, err := w.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	got, _ := jobs.Get(ctx, "j3")
+	if got.Status != StatusFailed || got.Error == "" {
+		t.Fatalf("signing past the lease should fail the job, got %+v", got)
+	}
+}
+
+// hookSigner runs before ahead of signing hash; audit events are signed directly.
+type hookSigner struct {
+	signer.Signer
+	hash   string
+	before func()
+}
+
+func (h *hookSigner) Sign(hash []byte) ([]byte, string, error) {
+	if hex.EncodeToString(hash) == h.hash {
+		h.before()
+	}
+	return h.Signer.Sign(hash)
+}
+
+// signatureCounter counts the manifest signatures stored through it.
+type signatureCounter struct {
+	audit.Store
+	n int32
+}
+
+func (c *signatureCounter) InsertManifestSignature(ctx context.Context, ms *audit.ManifestSignature) error {
+	atomic.AddInt32(&c.n, 1)
+	return c.Store.InsertManifestSignature(ctx, ms)
+}
+
+func TestWorkerStoresNoSignatureAfterLosingLease(t *testing.T) {
+	ctx := context.Background()
+	jobs := NewMemoryStore()
+	var skew int64
+	jobs.now = func() time.Time { return time.Now().Add(time.Duration(atomic.LoadInt64(&skew))) }
+	job := newJob("j4", time.Now().UTC())
+	_ = jobs.Create(ctx, job)
+
+	// While the first worker signs, its lease expires and another worker reclaims the job.
+	var reclaimed *Job
+	s := &hookSigner{Signer: signer.NewLocalSigner("hsm"), hash: job.ManifestHash, before: func() {
+		atomic.StoreInt64(&skew, int64(time.Hour))
+		reclaimed, _ = jobs.ClaimSign(ctx, time.Minute)
+	}}
+	store := &signatureCounter{Store: audit.NewFileStore(t.TempDir())}
+	w := NewWorker(jobs, s, store, WorkerConfig{Lease: time.Minute})
+
+	if _, err := w.RunOnce(ctx); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected the lost lease to surface as ErrConflict, got %v", err)
+	}
+	if reclaimed == nil || reclaimed.ID != "j4" {
+		t.Fatalf("expected the job to be reclaimed, got %+v", reclaimed)
+	}
+	if n := atomic.LoadInt32(&store.n); n != 0 {
+		t.Fatalf("a worker that lost its lease stored %d manifest signatures", n)
+	}
+	got, _ := jobs.Get(ctx, "j4")
+	if got.Status != StatusSigning || !got.LeaseUntil.Equal(*reclaimed.LeaseUntil) {
+		t.Fatalf("the new lease holder's claim must be untouched, got %+v", got)
+	}
+}
+
+func TestBackoffDoublesAndCaps(t *testing.T) {
+	w := NewWorker(NewMemoryStore(), nil, nil, WorkerConfig{CallbackBackoff: time.Second, CallbackMaxBackoff: 5 * time.Second})
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 9: 5 * time.Second} {
+		if got := w.backoff(attempts); got != want {
+			t.Fatalf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestPGStoreClaimSign(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().UTC()
+	cols := []string{"id", "status", "manifest_id", "manifest_hash", "version", "requester", "require_approval",
+		"decided_by", "decision_reason", "signature_id", "signer_id", "signature", "error",
+		"callback_url", "callback_status", "callback_attempts", "callback_error",
+		"created_at", "updated_at", "completed_at", "next_callback_at", "lease_until"}
+	mock.ExpectQuery("UPDATE sign_jobs SET\\s+status = 'signing'").
+		WithArgs(int64(60000)).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow("j1", "signing", "m1", "ab", "1.0.0", "cn:svc", false,
+			"", "", "", "", "", "", "", "", 0, "", now, now, nil, nil, now.Add(time.Minute)))
+	mock.ExpectQuery("UPDATE sign_jobs SET\\s+status = 'signing'").
+		WillReturnRows(sqlmock.NewRows(cols))
+
+	p := NewPGStore(db)
+	j, err := p.ClaimSign(context.Background(), time.Minute)
+	if err != nil || j == nil || j.ID != "j1" || j.Status != StatusSigning || j.LeaseUntil == nil || j.CompletedAt != nil {
+		t.Fatalf("claim: %+v %v", j, err)
+	}
+	if j, err := p.ClaimSign(context.Background(), time.Minute); j != nil || err != nil {
+		t.Fatalf("empty queue should return nil, nil; got %+v %v", j, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPGStoreUpdateRequiresLease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().UTC()
+	lease := Lease{Signing: true, Until: now.Add(time.Minute)}
+	job := newJob("j1", now)
+	job.Status = StatusSucceeded
+	mock.ExpectExec("UPDATE sign_jobs SET .* WHERE id = \\$1 AND CASE WHEN \\$14\\s+THEN status = 'signing' AND lease_until = \\$15").
+		WithArgs("j1", "succeeded", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			true, lease.Until).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM sign_jobs WHERE id = \\$1").
+		WithArgs("j1").
+		WillReturnRows(sqlmock.NewRows(strings.Split(strings.Join(strings.Fields(jobColumns), ""), ",")).AddRow("j1", "signing", "m1", "ab", "1.0.0", "cn:svc", false,
+			"", "", "", "", "", "", "", "", 0, "", now, now, nil, nil, now.Add(2*time.Minute)))
+
+	if err := NewPGStore(db).Update(context.Background(), job, lease); !errors.Is(err, ErrConflict) {
+		t.Fatalf("update under a lost lease should conflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPGStoreRenewRequiresLease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().UTC()
+	lease := Lease{Signing: true, Until: now.Add(time.Minute)}
+	until := now.Add(2 * time.Minute)
+	mock.ExpectExec("UPDATE sign_jobs SET\\s+lease_until = CASE WHEN \\$2 THEN \\$4 .* WHERE id = \\$1 AND CASE WHEN \\$2\\s+THEN status = 'signing' AND lease_until = \\$3").
+		WithArgs("j1", true, lease.Until, until).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE sign_jobs SET").
+		WithArgs("j1", true, lease.Until, until).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT .* FROM sign_jobs WHERE id = \\$1").
+		WithArgs("j1").
+		WillReturnRows(sqlmock.NewRows(strings.Split(strings.Join(strings.Fields(jobColumns), ""), ",")).AddRow("j1", "signing", "m1", "ab", "1.0.0", "cn:svc", false,
+			"", "", "", "", "", "", "", "", 0, "", now, now, nil, nil, now.Add(3*time.Minute)))
+
+	store := NewPGStore(db)
+	job := newJob("j1", now)
+	renewed, err := store.Renew(context.Background(), job, lease, until)
+	if err != nil || !renewed.Until.Equal(until) || !job.LeaseUntil.Equal(until) {
+		t.Fatalf("Renew: %+v %v", renewed, err)
+	}
+	if _, err := store.Renew(context.Background(), job, lease, until); !errors.Is(err, ErrConflict) {
+		t.Fatalf("renewing a lost lease should conflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,387 @@
+package signjobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+// Callback request headers. The signature is "sha256=" + hex HMAC-SHA256 over
+// "<timestamp>.<body>" keyed with the callback secret; receivers should reject stale
+// timestamps.
+const (
+	HeaderJobID     = "X-Kernel-Job-Id"
+	HeaderTimestamp = "X-Kernel-Timestamp"
+	HeaderSignature = "X-Kernel-Signature"
+)
+
+// WorkerConfig configures signing and callback delivery.
+type WorkerConfig struct {
+	// Concurrency is the number of jobs signed or delivered in parallel.
+	Concurrency int
+
+	// PollInterval is how long an idle worker waits before claiming again.
+	PollInterval time.Duration
+
+	// Lease bounds one signing attempt (including any HSM approval wait); a job still
+	// signing after it is reclaimed by another worker.
+	Lease time.Duration
+
+	// CallbackSecret keys the callback HMAC.
+	CallbackSecret []byte
+
+	// CallbackHosts are the only hosts callbacks are delivered to (see
+	// CallbackHostAllowed). Jobs naming any other host fail delivery without a request.
+	CallbackHosts []string
+
+	// CallbackTimeout bounds one delivery attempt.
+	CallbackTimeout time.Duration
+
+	// CallbackMaxAttempts is the number of deliveries tried before giving up.
+	CallbackMaxAttempts int
+
+	// CallbackBackoff is the delay after the first failed delivery; it doubles per
+	// attempt up to CallbackMaxBackoff.
+	CallbackBackoff    time.Duration
+	CallbackMaxBackoff time.Duration
+}
+
+// Worker signs claimed jobs and delivers their callbacks.
+type Worker struct {
+	jobs   Store
+	signer signer.Signer
+	audit  audit.Store
+	cfg    WorkerConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWorker constructs a Worker. If cfg fields are zero, sensible defaults are used.
+func NewWorker(jobs Store, s signer.Signer, as audit.Store, cfg WorkerConfig) *Worker {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 2
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 10 * time.Minute
+	}
+	if cfg.CallbackTimeout <= 0 {
+		cfg.CallbackTimeout = 10 * time.Second
+	}
+	if cfg.CallbackMaxAttempts <= 0 {
+		cfg.CallbackMaxAttempts = 8
+	}
+	if cfg.CallbackBackoff <= 0 {
+		cfg.CallbackBackoff = 5 * time.Second
+	}
+	if cfg.CallbackMaxBackoff <= 0 {
+		cfg.CallbackMaxBackoff = 10 * time.Minute
+	}
+	return &Worker{
+		jobs:   jobs,
+		signer: s,
+		audit:  as,
+		cfg:    cfg,
+		client: tracing.WrapClient(&http.Client{
+			Timeout: cfg.CallbackTimeout,
+			// Redirects could lead off the allowed hosts; a redirect fails the attempt.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}),
+		now: time.Now,
+	}
+}
+
+// Run processes jobs with Concurrency goroutines until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) error {
+	log.Printf("[signjobs] worker starting (concurrency=%d poll=%s lease=%s)", w.cfg.Concurrency, w.cfg.PollInterval, w.cfg.Lease)
+	defer log.Printf("[signjobs] worker stopped")
+
+	done := make(chan struct{}, w.cfg.Concurrency)
+	for i := 0; i < w.cfg.Concurrency; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for {
+				worked, err := w.RunOnce(ctx)
+				if err != nil {
+					log.Printf("[signjobs] %v", err)
+				}
+				if worked && err == nil {
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(w.cfg.PollInterval):
+				}
+			}
+		}()
+	}
+	for i := 0; i < w.cfg.Concurrency; i++ {
+		<-done
+	}
+	return ctx.Err()
+}
+
+// RunOnce signs one queued job or, if none is queued, delivers one due callback. It
+// reports whether there was work.
+func (w *Worker) RunOnce(ctx context.Context) (bool, error) {
+	job, err := w.jobs.ClaimSign(ctx, w.cfg.Lease)
+	if err != nil {
+		return false, err
+	}
+	if job != nil {
+		return true, w.sign(ctx, job)
+	}
+	job, err = w.jobs.ClaimCallback(ctx, 2*w.cfg.CallbackTimeout)
+	if err != nil {
+		return false, err
+	}
+	if job != nil {
+		return true, w.deliver(ctx, job)
+	}
+	return false, nil
+}
+
+// sign signs the job's manifest hash, stores the ManifestSignature and records the outcome.
+func (w *Worker) sign(ctx context.Context, job *Job) error {
+	lease := Lease{Signing: true}
+	if job.LeaseUntil != nil {
+		lease.Until = *job.LeaseUntil
+	}
+	if err := RecordTransition(ctx, w.audit, w.signer, job); err != nil {
+		log.Printf("[signjobs] audit %s signing: %v", job.ID, err)
+	}
+
+	sigCtx, cancel := context.WithTimeout(ctx, w.cfg.Lease)
+	defer cancel()
+	_, span := tracing.Start(sigCtx, "kernel.sign_job")
+	span.SetAttribute("job.id", job.ID)
+	sig, signerId, err := w.signHash(sigCtx, job.ManifestHash)
+	span.SetAttribute("signer.id", signerId)
+	span.RecordError(err)
+	span.End()
+
+	now := w.now().UTC()
+	if err == nil {
+		// Renew the lease before storing the signature: a worker whose lease was
+		// reclaimed while it signed stops here, and the renewed lease keeps the job from
+		// being reclaimed until its outcome is recorded, so each job stores at most one
+		// ManifestSignature.
+		renewed, rerr := w.jobs.Renew(ctx, job, lease, now.Add(w.cfg.Lease))
+		if rerr != nil {
+			return fmt.Errorf("renew job %s: %w", job.ID, rerr)
+		}
+		lease = renewed
+		ms := audit.ManifestSignature{
+			ManifestId: job.ManifestId,
+			SignerId:   signerId,
+			Signature:  base64.StdEncoding.EncodeToString(sig),
+			Version:    job.Version,
+			Ts:         now,
+		}
+		if err = w.audit.InsertManifestSignature(ctx, &ms); err != nil {
+			err = fmt.Errorf("store manifest signature: %w", err)
+		} else {
+			job.Status = StatusSucceeded
+			job.SignatureId, job.SignerId, job.Signature = ms.ID, signerId, ms.Signature
+		}
+	}
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+	job.UpdatedAt, job.CompletedAt, job.LeaseUntil = now, &now, nil
+	if job.CallbackURL != "" {
+		job.CallbackStatus = CallbackPending
+		job.NextCallbackAt = &now
+	}
+	if err := w.jobs.Update(ctx, job, lease); err != nil {
+		return fmt.Errorf("update job %s: %w", job.ID, err)
+	}
+	if err := RecordTransition(ctx, w.audit, w.signer, job); err != nil {
+		log.Printf("[signjobs] audit %s %s: %v", job.ID, job.Status, err)
+	}
+	return nil
+}
+
+// signHash runs the (possibly long) signer call, abandoning it when ctx ends.
+func (w *Worker) signHash(ctx context.Context, hexHash string) ([]byte, string, error) {
+	hash, err := hex.DecodeString(hexHash)
+	if err != nil {
+		return nil, "", fmt.Errorf("decode manifest hash: %w", err)
+	}
+	type result struct {
+		sig      []byte
+		signerId string
+		err      error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		sig, signerId, err := w.signer.Sign(hash)
+		ch <- result{sig, signerId, err}
+	}()
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			return nil, r.signerId, fmt.Errorf("sign error: %w", r.err)
+		}
+		return r.sig, r.signerId, nil
+	case <-ctx.Done():
+		return nil, "", fmt.Errorf("sign error: %w", ctx.Err())
+	}
+}
+
+// deliver POSTs the job to its callback URL and schedules a retry on failure.
+func (w *Worker) deliver(ctx context.Context, job *Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("marshal job %s: %w", job.ID, err)
+	}
+	var lease Lease
+	if job.NextCallbackAt != nil {
+		lease.Until = *job.NextCallbackAt
+	}
+	job.CallbackAttempts++
+	derr := w.post(ctx, job, body)
+
+	now := w.now().UTC()
+	job.UpdatedAt = now
+	final := false
+	switch {
+	case derr == nil:
+		job.CallbackStatus, job.CallbackError, job.NextCallbackAt = CallbackDelivered, "", nil
+		final = true
+	case job.CallbackAttempts >= w.cfg.CallbackMaxAttempts:
+		job.CallbackStatus, job.CallbackError, job.NextCallbackAt = CallbackFailed, derr.Error(), nil
+		final = true
+	default:
+		next := now.Add(w.backoff(job.CallbackAttempts))
+		job.CallbackError, job.NextCallbackAt = derr.Error(), &next
+	}
+	if err := w.jobs.Update(ctx, job, lease); err != nil {
+		return fmt.Errorf("update job %s: %w", job.ID, err)
+	}
+	if final {
+		if err := RecordTransition(ctx, w.audit, w.signer, job); err != nil {
+			log.Printf("[signjobs] audit %s callback %s: %v", job.ID, job.CallbackStatus, err)
+		}
+	}
+	return nil
+}
+
+func (w *Worker) post(ctx context.Context, job *Job, body []byte) error {
+	ts := strconv.FormatInt(w.now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	// Checked again at delivery: jobs outlive configuration changes.
+	if !CallbackHostAllowed(w.cfg.CallbackHosts, req.URL) {
+		return fmt.Errorf("callback host %s is not allowed", req.URL.Host)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderJobID, job.ID)
+	req.Header.Set(HeaderTimestamp, ts)
+	req.Header.Set(HeaderSignature, CallbackSignature(w.cfg.CallbackSecret, ts, body))
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// CallbackHostAllowed reports whether u targets one of hosts. An entry "host" allows any
+// port, "host:port" only that port; hosts compare case-insensitively. Restricting
+// callbacks to configured hosts keeps jobs from making the Kernel POST to internal
+// services.
+func CallbackHostAllowed(hosts []string, u *url.URL) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(h, u.Host) || strings.EqualFold(h, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.cfg.CallbackBackoff
+	for i := 1; i < attempts && d < w.cfg.CallbackMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > w.cfg.CallbackMaxBackoff {
+		d = w.cfg.CallbackMaxBackoff
+	}
+	return d
+}
+
+// CallbackSignature returns the X-Kernel-Signature value for a callback body.
+func CallbackSignature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RecordTransition appends a "sign_job.<status>" audit event for job's current state,
+// or "sign_job.callback_<state>" once callback delivery has finished.
+func RecordTransition(ctx context.Context, as audit.Store, s signer.Signer, job *Job) error {
+	eventType := "sign_job." + string(job.Status)
+	if job.CallbackStatus == CallbackDelivered || job.CallbackStatus == CallbackFailed {
+		eventType = "sign_job.callback_" + job.CallbackStatus
+	}
+	payload := map[string]interface{}{
+		"jobId":        job.ID,
+		"status":       string(job.Status),
+		"manifestId":   job.ManifestId,
+		"manifestHash": job.ManifestHash,
+		"requester":    job.Requester,
+	}
+	optional := map[string]string{
+		"decidedBy":      job.DecidedBy,
+		"decisionReason": job.DecisionReason,
+		"signatureId":    job.SignatureId,
+		"signerId":       job.SignerId,
+		"error":          job.Error,
+		"callbackUrl":    job.CallbackURL,
+		"callbackError":  job.CallbackError,
+	}
+	for k, v := range optional {
+		if v != "" {
+			payload[k] = v
+		}
+	}
+	if job.CallbackAttempts > 0 {
+		payload["callbackAttempts"] = job.CallbackAttempts
+	}
+	ev := &audit.AuditEvent{
+		EventType: eventType,
+		Payload:   payload,
+		Ts:        time.Now().UTC(),
+	}
+	return as.AppendAuditEvent(ctx, ev, s)
+}
@@ -0,0 +1,136 @@
+// Package telemetry holds the Kernel's instrumentation: Prometheus metrics for HTTP,
+// signing, audit append, audit streaming and JWKS (registered on shared/metrics.Default
+// and served from GET /metrics), and trace id stamping of audit events (shared/tracing).
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/auth"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/metrics"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+// Registry is the registry served at /metrics.
+var Registry = metrics.Default
+
+var (
+	signDuration = Registry.NewHistogramVec("kernel_sign_duration_seconds",
+		"Latency of signing requests by signer backend.", nil, "backend")
+	signErrors = Registry.NewCounterVec("kernel_sign_errors_total",
+		"Failed signing requests by signer backend.", "backend")
+
+	auditAppendDuration = Registry.NewHistogramVec("kernel_audit_append_duration_seconds",
+		"Latency of audit event appends (canonicalize, hash, sign, persist).", nil, "result")
+
+	streamAttempts = Registry.NewCounterVec("kernel_audit_stream_attempts_total",
+		"Audit streamer produce+archive attempts by result.", "result")
+	streamBacklog = Registry.NewGaugeVec("kernel_audit_stream_backlog",
+		"Audit events waiting to be streamed (pending or retry).")
+	streamDeadLetters = Registry.NewGaugeVec("kernel_audit_stream_dead_letters",
+		"Audit events that exhausted their stream attempts (stream_status=failed).")
+)
+
+func init() {
+	Registry.NewCounterFunc("kernel_jwks_fetch_total", "Successful JWKS fetches observed.", func() float64 {
+		return float64(auth.GetJWKSMetrics().FetchCount)
+	})
+	Registry.NewCounterFunc("kernel_jwks_fetch_failures_total", "JWKS fetch failures observed.", func() float64 {
+		return float64(auth.GetJWKSMetrics().FailCount)
+	})
+	Registry.NewGaugeFunc("kernel_jwks_last_fetch_timestamp_seconds", "Unix time of the last successful JWKS fetch.", func() float64 {
+		lf := auth.GetJWKSMetrics().LastFetch
+		if lf.IsZero() {
+			return 0
+		}
+		return float64(lf.UnixNano()) / 1e9
+	})
+	Registry.NewGaugeFunc("kernel_jwks_last_fetch_error", "1 if the last JWKS fetch failed, otherwise 0.", func() float64 {
+		if auth.GetJWKSMetrics().LastError != "" {
+			return 1
+		}
+		return 0
+	})
+}
+
+// NewHTTPMetrics returns the Kernel's per-route HTTP instrumentation.
+func NewHTTPMetrics() *metrics.HTTPMetrics {
+	return metrics.NewHTTPMetrics(Registry, "kernel")
+}
+
+// SignerBackend names the backend of s for the "backend" label.
+func SignerBackend(s signer.Signer) string {
+	if _, ok := s.(*signer.LocalSigner); ok {
+		return "local"
+	}
+	return "kms"
+}
+
+// InstrumentSigner records latency and errors of every Sign call on s.
+func InstrumentSigner(s signer.Signer, backend string) signer.Signer {
+	return &instrumentedSigner{Signer: s, backend: backend}
+}
+
+type instrumentedSigner struct {
+	signer.Signer
+	backend string
+}
+
+func (i *instrumentedSigner) Sign(hash []byte) ([]byte, string, error) {
+	start := time.Now()
+	sig, id, err := i.Signer.Sign(hash)
+	signDuration.With(i.backend).Observe(time.Since(start).Seconds())
+	if err != nil {
+		signErrors.With(i.backend).Inc()
+	}
+	return sig, id, err
+}
+
+// InstrumentStore records audit append latency on s.
+func InstrumentStore(s audit.Store) audit.Store {
+	return &instrumentedStore{Store: s}
+}
+
+type instrumentedStore struct {
+	audit.Store
+}
+
+// AppendAuditEvent also stamps the caller's trace id into ev.Metadata (metadata is not
+// part of the hash chain) and records an audit.append span.
+func (i *instrumentedStore) AppendAuditEvent(ctx context.Context, ev *audit.AuditEvent, s signer.Signer) error {
+	ctx, span := tracing.Start(ctx, "audit.append")
+	defer span.End()
+	span.SetAttribute("audit.event_type", ev.EventType)
+	ev.Metadata = tracing.AnnotateValue(ctx, ev.Metadata)
+
+	start := time.Now()
+	err := i.Store.AppendAuditEvent(ctx, ev, s)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	auditAppendDuration.With(result).Observe(time.Since(start).Seconds())
+	span.RecordError(err)
+	return err
+}
+
+// StreamObserver feeds audit streamer progress into the streamer metrics.
+var StreamObserver audit.StreamObserver = streamObserver{}
+
+type streamObserver struct{}
+
+func (streamObserver) StreamAttempt(success bool) {
+	if success {
+		streamAttempts.With("success").Inc()
+		return
+	}
+	streamAttempts.With("failure").Inc()
+}
+
+func (streamObserver) StreamBacklog(pending, failed int64) {
+	streamBacklog.With().Set(float64(pending))
+	streamDeadLetters.With().Set(float64(failed))
+}
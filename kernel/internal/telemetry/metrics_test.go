@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ILLUVRSE/Main/kernel/internal/audit"
+	"github.com/ILLUVRSE/Main/kernel/internal/signer"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+type failingSigner struct{}
+
+func (failingSigner) Sign(hash []byte) ([]byte, string, error) {
+	return nil, "", errors.New("kms down")
+}
+func (failingSigner) PublicKey() []byte { return nil }
+
+type nopStore struct{ audit.Store }
+
+func (nopStore) AppendAuditEvent(ctx context.Context, ev *audit.AuditEvent, s signer.Signer) error {
+	_, _, err := s.Sign([]byte("h"))
+	return err
+}
+
+func TestKernelMetricsExposed(t *testing.T) {
+	local := InstrumentSigner(signer.NewLocalSigner("t"), "local")
+	if SignerBackend(signer.NewLocalSigner("t")) != "local" {
+		t.Fatalf("local signer should report the local backend")
+	}
+	if _, _, err := local.Sign([]byte("x")); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	kms := InstrumentSigner(failingSigner{}, "kms")
+	store := InstrumentStore(nopStore{})
+	if err := store.AppendAuditEvent(context.Background(), &audit.AuditEvent{}, kms); err == nil {
+		t.Fatalf("expected append error from failing signer")
+	}
+	StreamObserver.StreamAttempt(true)
+	StreamObserver.StreamAttempt(false)
+	StreamObserver.StreamBacklog(4, 1)
+
+	var b strings.Builder
+	Registry.WriteText(&b)
+	out := b.String()
+	for _, want := range []string{
+		`kernel_sign_duration_seconds_count{backend="local"} 1`,
+		`kernel_sign_errors_total{backend="kms"} 1`,
+		`kernel_audit_append_duration_seconds_count{result="error"} 1`,
+		`kernel_audit_stream_attempts_total{result="failure"} 1`,
+		"kernel_audit_stream_backlog 4",
+		"kernel_audit_stream_dead_letters 1",
+		"# TYPE kernel_jwks_fetch_total counter",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in /metrics output:\n%s", want, out)
+		}
+	}
+}
+
+func TestAuditAppendStampsTraceID(t *testing.T) {
+	ctx, span := tracing.Start(context.Background(), "request")
+	defer span.End()
+
+	ev := &audit.AuditEvent{EventType: "test", Metadata: map[string]interface{}{"origin": "ai-infra"}}
+	if err := InstrumentStore(nopStore{}).AppendAuditEvent(ctx, ev, signer.NewLocalSigner("t")); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	md, ok := ev.Metadata.(map[string]interface{})
+	if !ok {
+		t.Fatalf("metadata should stay a map, got %T", ev.Metadata)
+	}
+	if md["traceId"] != span.SpanContext().TraceIDString() || md["origin"] != "ai-infra" {
+		t.Fatalf("trace id not stamped into audit metadata: %v", md)
+	}
+}
@@ -0,0 +1,39 @@
+-- kernel/sql/migrations/007_audit_checkpoints.sql
+-- Signed checkpoints (tombstones) left behind by the audit retention pruner.
+--
+-- When a contiguous range of expired, archived audit_events rows is deleted, the
+-- pruner writes one checkpoint that records the range boundaries:
+--  - prev_hash: the prev_hash of the first pruned event (hash of the last kept event
+--    before the range, or NULL/empty for the chain genesis)
+--  - last_hash: the hash of the last pruned event (the prev_hash of the next kept event)
+--  - archive_keys: S3 object keys of the pruned events so they can be restored
+--
+-- hash = SHA256(canonical(checkpoint body)) and signature is Ed25519 over hash, so
+-- VerifyChain can bridge the gap between kept events without the pruned payloads.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audit_checkpoints (
+  id UUID PRIMARY KEY,
+  first_event_id UUID NOT NULL,
+  last_event_id UUID NOT NULL,
+  first_ts TIMESTAMPTZ NOT NULL,
+  last_ts TIMESTAMPTZ NOT NULL,
+  event_count INTEGER NOT NULL,
+  prev_hash TEXT,
+  last_hash TEXT NOT NULL,
+  archive_keys JSONB NOT NULL DEFAULT '[]'::jsonb,
+  hash TEXT NOT NULL,
+  signature TEXT NOT NULL,
+  signer_id VARCHAR NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_checkpoints_prev_hash ON audit_checkpoints(prev_hash);
+CREATE INDEX IF NOT EXISTS idx_audit_checkpoints_last_hash ON audit_checkpoints(last_hash);
+
+-- Speeds up the pruner's candidate scan.
+CREATE INDEX IF NOT EXISTS idx_audit_events_prunable ON audit_events (ts)
+  WHERE retention_expires_at IS NOT NULL AND s3_object_key IS NOT NULL;
+
+COMMIT;
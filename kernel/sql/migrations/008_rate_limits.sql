@@ -0,0 +1,19 @@
+-- kernel/sql/migrations/008_rate_limits.sql
+-- Token buckets for per-principal rate limiting (internal/ratelimit.PGLimiter).
+--
+-- bucket_key is "<METHOD> <path>|<principal>" (e.g. "POST /kernel/sign|cn:ai-infra").
+-- tokens is the balance at updated_at; readers refill it from the configured rate
+-- under a row lock, so all Kernel replicas share one budget per bucket.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+  bucket_key TEXT PRIMARY KEY,
+  tokens DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Supports deleting idle buckets: DELETE FROM rate_limit_buckets WHERE updated_at < now() - interval '1 day';
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
+
+COMMIT;
@@ -0,0 +1,39 @@
+-- Asynchronous signing jobs (internal/signjobs.PGStore).
+--
+-- status: awaiting_approval -> queued -> signing -> succeeded | failed, or
+-- awaiting_approval -> rejected. Workers claim queued rows (and signing rows whose
+-- lease_until has passed) with FOR UPDATE SKIP LOCKED. callback_status is '' when no
+-- callback_url was given, otherwise pending -> delivered | failed; next_callback_at is
+-- when the next delivery attempt is due.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS sign_jobs (
+  id TEXT PRIMARY KEY,
+  status TEXT NOT NULL,
+  manifest_id TEXT NOT NULL,
+  manifest_hash TEXT NOT NULL,
+  version TEXT NOT NULL DEFAULT '',
+  requester TEXT NOT NULL DEFAULT '',
+  require_approval BOOLEAN NOT NULL DEFAULT false,
+  decided_by TEXT NOT NULL DEFAULT '',
+  decision_reason TEXT NOT NULL DEFAULT '',
+  signature_id TEXT NOT NULL DEFAULT '',
+  signer_id TEXT NOT NULL DEFAULT '',
+  signature TEXT NOT NULL DEFAULT '',
+  error TEXT NOT NULL DEFAULT '',
+  callback_url TEXT NOT NULL DEFAULT '',
+  callback_status TEXT NOT NULL DEFAULT '',
+  callback_attempts INTEGER NOT NULL DEFAULT 0,
+  callback_error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  completed_at TIMESTAMPTZ,
+  next_callback_at TIMESTAMPTZ,
+  lease_until TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sign_jobs_claim ON sign_jobs (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_sign_jobs_callback_due ON sign_jobs (next_callback_at) WHERE callback_status = 'pending';
+
+COMMIT;
@@ -0,0 +1,20 @@
+-- Allocation requests forwarded to the eval-engine Resource Allocator
+-- (internal/allocation.PGStore).
+--
+-- allocator_request_id is the allocator's id for the request once it has been filed;
+-- status moves pending -> applied | rejected as the Kernel reconciles it, or ends failed
+-- when the request never reached the allocator. payload holds the full Kernel record.
+
+BEGIN;
+
+ALTER TABLE allocations ADD COLUMN IF NOT EXISTS status TEXT;
+ALTER TABLE allocations ADD COLUMN IF NOT EXISTS allocator_request_id TEXT;
+ALTER TABLE allocations ADD COLUMN IF NOT EXISTS pool TEXT;
+ALTER TABLE allocations ADD COLUMN IF NOT EXISTS delta INTEGER;
+
+-- The reconciler polls pending requests that have reached the allocator, oldest first.
+CREATE INDEX IF NOT EXISTS idx_allocations_pending
+  ON allocations (created_at)
+  WHERE status = 'pending' AND allocator_request_id IS NOT NULL;
+
+COMMIT;
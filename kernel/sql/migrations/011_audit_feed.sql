@@ -0,0 +1,39 @@
+-- Chain sequence numbers and insert notifications for the live audit feed
+-- (GET /kernel/audit/stream, internal/audit.PGStore.EventsSince / ListenPG).
+--
+-- seq orders audit_events along the chain and is the SSE event id subscribers resume
+-- from with Last-Event-ID. Existing rows are numbered in ts order; new rows take the
+-- next value of audit_events_seq (PGStore.AppendAuditEvent reads it back with RETURNING).
+-- Every insert notifies channel audit_events with the new seq so subscribers on any
+-- replica are woken without polling.
+
+BEGIN;
+
+CREATE SEQUENCE IF NOT EXISTS audit_events_seq;
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS seq BIGINT;
+
+UPDATE audit_events a
+SET seq = o.n
+FROM (SELECT id, row_number() OVER (ORDER BY ts, id) AS n FROM audit_events) o
+WHERE a.id = o.id AND a.seq IS NULL;
+
+SELECT setval('audit_events_seq', COALESCE((SELECT MAX(seq) FROM audit_events), 0) + 1, false);
+ALTER TABLE audit_events ALTER COLUMN seq SET DEFAULT nextval('audit_events_seq');
+ALTER TABLE audit_events ALTER COLUMN seq SET NOT NULL;
+ALTER SEQUENCE audit_events_seq OWNED BY audit_events.seq;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_events_seq ON audit_events (seq);
+
+CREATE OR REPLACE FUNCTION notify_audit_event() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('audit_events', NEW.seq::text);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_notify ON audit_events;
+CREATE TRIGGER audit_events_notify
+  AFTER INSERT ON audit_events
+  FOR EACH ROW EXECUTE FUNCTION notify_audit_event();
+
+COMMIT;
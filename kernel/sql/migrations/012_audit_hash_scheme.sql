@@ -0,0 +1,14 @@
+-- Hash scheme of each audit event: the canonicalization its hash covers.
+--
+-- Events appended before the kernel switched to RFC 8785 (JCS) canonical JSON were
+-- hashed with the legacy canonicalizer (canonical.MarshalLegacy) and are marked
+-- 'legacy' so VerifyChain and restores recompute their hashes the same way. New rows
+-- are 'jcs'.
+
+BEGIN;
+
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS hash_scheme TEXT NOT NULL DEFAULT 'legacy'
+  CHECK (hash_scheme IN ('legacy', 'jcs'));
+ALTER TABLE audit_events ALTER COLUMN hash_scheme SET DEFAULT 'jcs';
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- audit_events.sampled marks events a sampling retention rule selected to keep.
+--
+-- Earlier kernels set it on the events a rule dropped instead. Those rows expire at
+-- their own ts, which no kept event does, so they are cleared here. Events that were
+-- kept by sampling before this migration cannot be told apart and stay false.
+
+BEGIN;
+
+UPDATE audit_events SET sampled = false
+  WHERE sampled AND retention_expires_at = ts;
+
+COMMIT;
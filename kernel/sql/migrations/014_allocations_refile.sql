@@ -0,0 +1,12 @@
+-- Allocation requests the Kernel could not file with the Resource Allocator stay pending
+-- without an allocator_request_id, and the reconciler files them again. Its pending
+-- index therefore covers every pending request.
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_allocations_pending;
+CREATE INDEX IF NOT EXISTS idx_allocations_pending
+  ON allocations (created_at)
+  WHERE status = 'pending';
+
+COMMIT;
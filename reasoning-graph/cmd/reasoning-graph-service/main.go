@@ -17,6 +17,8 @@ import (
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/service"
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/signing"
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/store"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 func main() {
@@ -24,6 +26,12 @@ func main() {
 	if err != nil {
 		log.Fatalf("config load: %v", err)
 	}
+	tracer, err := tracing.FromEnv("reasoning-graph")
+	if err != nil {
+		log.Fatalf("tracing init: %v", err)
+	}
+	tracing.SetDefault(tracer)
+	defer tracer.Close()
 
 	db, err := sql.Open("postgres", cfg.DatabaseURL)
 	if err != nil {
@@ -38,16 +46,34 @@ func main() {
 		log.Fatalf("db ping: %v", err)
 	}
 
-	reasonStore := store.NewPGStore(db)
-	signer, err := signing.NewEd25519SignerFromB64(cfg.SignerKeyB64, cfg.SignerID)
-	if err != nil {
-		log.Fatalf("signer init: %v", err)
+	reasonStore := store.WithTracing(store.NewPGStore(db))
+	var kernel *kernelclient.Client
+	if cfg.KernelAPIURL != "" {
+		kernel, err = kernelclient.New(kernelclient.ConfigFromEnv("reasoning-graph"))
+		if err != nil {
+			log.Fatalf("kernel client init: %v", err)
+		}
+	}
+
+	var signer signing.Signer
+	if kernel != nil {
+		signer = signing.NewKernelSigner(kernel, cfg.SignerID)
+	} else {
+		signer, err = signing.NewEd25519SignerFromB64(cfg.SignerKeyB64, cfg.SignerID)
+		if err != nil {
+			log.Fatalf("signer init: %v", err)
+		}
 	}
 	svc := service.New(reasonStore, signer, service.Config{
 		MaxTraceDepth:    cfg.MaxTraceDepth,
 		SnapshotDepth:    cfg.SnapshotDepth,
 		MaxSnapshotRoots: cfg.MaxSnapshotRoots,
 	})
+	if kernel != nil {
+		svc.SetAuditor(kernel)
+	} else {
+		log.Printf("[warn] KERNEL_API_URL not set; snapshots are signed locally and not audited")
+	}
 
 	server := httpserver.New(cfg, reasonStore, svc)
 	httpServer := &http.Server{
@@ -23,6 +23,7 @@ type Config struct {
 	SnapshotDepth       int
 	MaxSnapshotRoots    int
 	MaxNodePayloadBytes int
+	KernelAPIURL        string
 }
 
 const (
@@ -51,13 +52,14 @@ func Load() (Config, error) {
 		SnapshotDepth:       getInt("REASONING_GRAPH_SNAPSHOT_DEPTH", defaultSnapshotDepth),
 		MaxSnapshotRoots:    getInt("REASONING_GRAPH_MAX_SNAPSHOT_ROOTS", defaultMaxSnapshotRoots),
 		MaxNodePayloadBytes: getInt("REASONING_GRAPH_MAX_NODE_PAYLOAD_BYTES", defaultPayloadLimit),
+		KernelAPIURL:        os.Getenv("KERNEL_API_URL"),
 	}
 
 	if cfg.DatabaseURL == "" {
 		return Config{}, fmt.Errorf("DATABASE_URL or REASONING_GRAPH_DATABASE_URL is required")
 	}
-	if cfg.SignerKeyB64 == "" {
-		return Config{}, fmt.Errorf("REASONING_GRAPH_SNAPSHOT_KEY_B64 is required")
+	if cfg.SignerKeyB64 == "" && cfg.KernelAPIURL == "" {
+		return Config{}, fmt.Errorf("REASONING_GRAPH_SNAPSHOT_KEY_B64 or KERNEL_API_URL is required")
 	}
 	return cfg, nil
 }
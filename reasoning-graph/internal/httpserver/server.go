@@ -20,6 +20,8 @@ import (
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/models"
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/service"
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/store"
+	"github.com/ILLUVRSE/Main/shared/metrics"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 type Server struct {
@@ -27,6 +29,9 @@ type Server struct {
 	db       store.Store
 	svc      *service.Service
 	verifier *auth.Verifier
+
+	metrics     *metrics.Registry
+	httpMetrics *metrics.HTTPMetrics
 }
 
 func New(cfg config.Config, db store.Store, svc *service.Service) *Server {
@@ -36,11 +41,14 @@ func New(cfg config.Config, db store.Store, svc *service.Service) *Server {
 		// Since we can't easily log here without logger passed in, we'll let it be nil and fail in middleware
 		fmt.Printf("Warning: failed to initialize auth verifier: %v\n", err)
 	}
+	reg := metrics.NewRegistry()
 	return &Server{
-		cfg:      cfg,
-		db:       db,
-		svc:      svc,
-		verifier: v,
+		cfg:         cfg,
+		db:          db,
+		svc:         svc,
+		verifier:    v,
+		metrics:     reg,
+		httpMetrics: metrics.NewHTTPMetrics(reg, "reasoning-graph"),
 	}
 }
 
@@ -48,10 +56,13 @@ func (s *Server) Router() http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(tracing.Middleware)
+	r.Use(s.httpMetrics.Middleware)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
 
 	r.Get("/health", s.handleHealth)
+	r.Handle("/metrics", s.metrics.Handler())
 
 	r.Route("/reason", func(r chi.Router) {
 		r.Group(func(r chi.Router) {
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+)
+
+// Auditor appends events to the Kernel audit log (*kernelclient.Client satisfies it).
+type Auditor interface {
+	AppendAudit(ctx context.Context, eventType string, payload, metadata interface{}) (kernelclient.AuditEvent, error)
+}
+
+// SetAuditor routes snapshot audit events to the Kernel. Without one, events are not
+// emitted (local development).
+func (s *Service) SetAuditor(a Auditor) {
+	s.auditor = a
+}
+
+// emitAudit records a graph event in the Kernel. The write has already committed, so a
+// failure is logged rather than returned to the caller.
+func (s *Service) emitAudit(ctx context.Context, eventType string, payload map[string]interface{}) {
+	if s.auditor == nil {
+		return
+	}
+	if _, err := s.auditor.AppendAudit(ctx, eventType, payload, map[string]interface{}{"origin": "reasoning-graph"}); err != nil {
+		log.Printf("[audit] kernel append %s failed: %v", eventType, err)
+	}
+}
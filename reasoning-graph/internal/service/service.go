@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
@@ -13,9 +12,11 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/ILLUVRSE/Main/reasoning-graph/internal/canonical"
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/models"
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/signing"
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/store"
+	"github.com/ILLUVRSE/Main/shared/tracing"
 )
 
 var (
@@ -30,6 +31,7 @@ type Service struct {
 	maxTraceDepth    int
 	snapshotDepth    int
 	maxSnapshotRoots int
+	auditor          Auditor
 }
 
 type Config struct {
@@ -506,13 +508,25 @@ func (s *Service) CreateSnapshot(ctx context.Context, req SnapshotRequest) (mode
 	if err != nil {
 		return models.ReasonSnapshot{}, err
 	}
-	canonical, err := canonicalizeSnapshot(nodes, edges)
+	canon, err := canonicalizeSnapshot(nodes, edges)
 	if err != nil {
 		return models.ReasonSnapshot{}, err
 	}
 
-	hash := sha256.Sum256(canonical)
-	signatureBytes, err := s.signer.Sign(ctx, hash[:])
+	hash := sha256.Sum256(canon)
+	var (
+		signatureBytes []byte
+		signerID       = s.signer.SignerID()
+	)
+	signCtx, span := tracing.Start(ctx, "signing.sign")
+	if ds, ok := s.signer.(signing.DocumentSigner); ok {
+		signatureBytes, signerID, err = ds.SignCanonical(signCtx, canon)
+	} else {
+		signatureBytes, err = s.signer.Sign(signCtx, hash[:])
+	}
+	span.SetAttribute("signing.signer_id", signerID)
+	span.RecordError(err)
+	span.End()
 	if err != nil {
 		return models.ReasonSnapshot{}, fmt.Errorf("sign snapshot hash: %w", err)
 	}
@@ -523,10 +537,20 @@ func (s *Service) CreateSnapshot(ctx context.Context, req SnapshotRequest) (mode
 		Description: req.Description,
 		Hash:        fmt.Sprintf("%x", hash[:]),
 		Signature:   signature,
-		SignerID:    s.signer.SignerID(),
-		Snapshot:    canonical,
+		SignerID:    signerID,
+		Snapshot:    canon,
 	}
-	return s.store.CreateSnapshot(ctx, input)
+	snapshot, err := s.store.CreateSnapshot(ctx, input)
+	if err != nil {
+		return models.ReasonSnapshot{}, err
+	}
+	s.emitAudit(ctx, "reasoning.snapshot.created", map[string]interface{}{
+		"snapshotId":  snapshot.ID,
+		"rootNodeIds": snapshot.RootNodeIDs,
+		"hash":        snapshot.Hash,
+		"signerId":    snapshot.SignerID,
+	})
+	return snapshot, nil
 }
 
 func (s *Service) collectSubgraph(ctx context.Context, rootIDs []uuid.UUID) (map[uuid.UUID]models.ReasonNode, map[uuid.UUID]models.ReasonEdge, error) {
@@ -608,7 +632,7 @@ func canonicalizeSnapshot(nodes map[uuid.UUID]models.ReasonNode, edges map[uuid.
 		Nodes: nodeList,
 		Edges: edgeList,
 	}
-	data, err := json.Marshal(payload)
+	data, err := canonical.Canonicalize(payload)
 	if err != nil {
 		return nil, fmt.Errorf("marshal snapshot canonical json: %w", err)
 	}
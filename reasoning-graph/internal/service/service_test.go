@@ -18,6 +18,8 @@ import (
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/models"
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/signing"
 	"github.com/ILLUVRSE/Main/reasoning-graph/internal/testutil"
+	"github.com/ILLUVRSE/Main/shared/canonical"
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
 )
 
 func TestComputeTraceAncestors(t *testing.T) {
@@ -145,6 +147,42 @@ func TestCreateSnapshotHashesAndSigns(t *testing.T) {
 	}
 }
 
+func TestCreateSnapshotSignsThroughKernel(t *testing.T) {
+	mem := testutil.NewMemoryStore()
+	root := fakeNode("decision")
+	child := fakeNode("action")
+	mem.AddNode(root)
+	mem.AddNode(child)
+	mem.Link(root.ID, child.ID, "causal")
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	kernel := &fakeKernel{priv: priv}
+	svc := New(mem, signing.NewKernelSigner(kernel, "reasoning-graph-dev"), Config{SnapshotDepth: 2, MaxSnapshotRoots: 4})
+	svc.SetAuditor(kernel)
+
+	snap, err := svc.CreateSnapshot(context.Background(), SnapshotRequest{RootNodeIDs: []uuid.UUID{root.ID}})
+	if err != nil {
+		t.Fatalf("CreateSnapshot returned error: %v", err)
+	}
+	if snap.SignerID != "kernel-signer-1" {
+		t.Fatalf("expected kernel signer id, got %q", snap.SignerID)
+	}
+	hash := sha256.Sum256(snap.Snapshot)
+	sigBytes, err := base64.StdEncoding.DecodeString(snap.Signature)
+	if err != nil {
+		t.Fatalf("decode signature: %v", err)
+	}
+	if !ed25519.Verify(pub, hash[:], sigBytes) {
+		t.Fatalf("kernel signature should cover the snapshot hash")
+	}
+	if len(kernel.events) != 1 || kernel.events[0] != "reasoning.snapshot.created" {
+		t.Fatalf("expected snapshot audit event, got %v", kernel.events)
+	}
+}
+
 func TestCanonicalizeSnapshotDeterministic(t *testing.T) {
 	nodes := []models.ReasonNode{
 		fakeNodeWithID("observation", uuid.MustParse("11111111-1111-1111-1111-111111111111")),
@@ -188,6 +226,31 @@ func TestCanonicalizeSnapshotDeterministic(t *testing.T) {
 	}
 }
 
+// fakeKernel mirrors Kernel /kernel/sign (signature over sha256(JCS(manifest))) and
+// records audit appends.
+type fakeKernel struct {
+	priv   ed25519.PrivateKey
+	events []string
+}
+
+func (k *fakeKernel) Sign(ctx context.Context, manifest interface{}, version string) (kernelclient.ManifestSignature, error) {
+	canon, err := canonical.Marshal(manifest)
+	if err != nil {
+		return kernelclient.ManifestSignature{}, err
+	}
+	sum := sha256.Sum256(canon)
+	return kernelclient.ManifestSignature{
+		SignerID:  "kernel-signer-1",
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(k.priv, sum[:])),
+		Version:   version,
+	}, nil
+}
+
+func (k *fakeKernel) AppendAudit(ctx context.Context, eventType string, payload, metadata interface{}) (kernelclient.AuditEvent, error) {
+	k.events = append(k.events, eventType)
+	return kernelclient.AuditEvent{EventType: eventType}, nil
+}
+
 type noopSigner struct{}
 
 func (noopSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
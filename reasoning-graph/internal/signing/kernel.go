@@ -0,0 +1,69 @@
+package signing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+)
+
+// SnapshotSignatureVersion is sent as the manifest version when snapshots are signed by
+// the Kernel.
+const SnapshotSignatureVersion = "reasoning-graph.snapshot.v1"
+
+// ErrDigestSigningUnsupported is returned by KernelSigner.Sign: the Kernel signs
+// documents (sha256 of their canonical JSON), not caller-supplied digests.
+var ErrDigestSigningUnsupported = errors.New("kernel signer signs documents, not raw digests")
+
+// DocumentSigner signs sha256 of already-canonical JSON and reports the signer that
+// produced the signature.
+type DocumentSigner interface {
+	SignCanonical(ctx context.Context, canon []byte) ([]byte, string, error)
+}
+
+// KernelClient is the subset of the Kernel API used for signing.
+type KernelClient interface {
+	Sign(ctx context.Context, manifest interface{}, version string) (kernelclient.ManifestSignature, error)
+}
+
+// KernelSigner requests snapshot signatures from Kernel POST /kernel/sign. Snapshots are
+// already canonical JSON, so the Kernel's sha256(JCS(manifest)) equals the stored hash.
+type KernelSigner struct {
+	client KernelClient
+
+	mu       sync.RWMutex
+	signerID string
+}
+
+func NewKernelSigner(client KernelClient, signerID string) *KernelSigner {
+	return &KernelSigner{client: client, signerID: signerID}
+}
+
+func (k *KernelSigner) SignCanonical(ctx context.Context, canon []byte) ([]byte, string, error) {
+	ms, err := k.client.Sign(ctx, json.RawMessage(canon), SnapshotSignatureVersion)
+	if err != nil {
+		return nil, "", fmt.Errorf("kernel sign: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(ms.Signature)
+	if err != nil {
+		return nil, "", fmt.Errorf("kernel sign: decode signature: %w", err)
+	}
+	k.mu.Lock()
+	k.signerID = ms.SignerID
+	k.mu.Unlock()
+	return sig, ms.SignerID, nil
+}
+
+func (k *KernelSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	return nil, ErrDigestSigningUnsupported
+}
+
+func (k *KernelSigner) SignerID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.signerID
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/reasoning-graph/internal/models"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+// WithTracing wraps s so every call records a "db.<Method>" span and new nodes and edges
+// carry the caller's trace id in their metadata (traceId/spanId), linking graph entries
+// to the Kernel audit events of the same request.
+func WithTracing(s Store) Store {
+	return &tracedStore{next: s}
+}
+
+type tracedStore struct {
+	next Store
+}
+
+func traced[T any](ctx context.Context, op string, fn func(context.Context) (T, error)) (T, error) {
+	ctx, span := tracing.Start(ctx, "db."+op)
+	defer span.End()
+	span.SetAttribute("db.system", "postgresql")
+	v, err := fn(ctx)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedStore) CreateNode(ctx context.Context, in NodeInput) (models.ReasonNode, error) {
+	in.Metadata = tracing.AnnotateJSON(ctx, in.Metadata)
+	return traced(ctx, "CreateNode", func(ctx context.Context) (models.ReasonNode, error) {
+		return t.next.CreateNode(ctx, in)
+	})
+}
+
+func (t *tracedStore) GetNode(ctx context.Context, id uuid.UUID) (models.ReasonNode, error) {
+	return traced(ctx, "GetNode", func(ctx context.Context) (models.ReasonNode, error) {
+		return t.next.GetNode(ctx, id)
+	})
+}
+
+func (t *tracedStore) CreateEdge(ctx context.Context, in EdgeInput) (models.ReasonEdge, error) {
+	in.Metadata = tracing.AnnotateJSON(ctx, in.Metadata)
+	return traced(ctx, "CreateEdge", func(ctx context.Context) (models.ReasonEdge, error) {
+		return t.next.CreateEdge(ctx, in)
+	})
+}
+
+func (t *tracedStore) ListEdgesFrom(ctx context.Context, nodeID uuid.UUID) ([]models.ReasonEdge, error) {
+	return traced(ctx, "ListEdgesFrom", func(ctx context.Context) ([]models.ReasonEdge, error) {
+		return t.next.ListEdgesFrom(ctx, nodeID)
+	})
+}
+
+func (t *tracedStore) ListEdgesTo(ctx context.Context, nodeID uuid.UUID) ([]models.ReasonEdge, error) {
+	return traced(ctx, "ListEdgesTo", func(ctx context.Context) ([]models.ReasonEdge, error) {
+		return t.next.ListEdgesTo(ctx, nodeID)
+	})
+}
+
+func (t *tracedStore) CreateSnapshot(ctx context.Context, in SnapshotInput) (models.ReasonSnapshot, error) {
+	return traced(ctx, "CreateSnapshot", func(ctx context.Context) (models.ReasonSnapshot, error) {
+		return t.next.CreateSnapshot(ctx, in)
+	})
+}
+
+func (t *tracedStore) GetSnapshot(ctx context.Context, id uuid.UUID) (models.ReasonSnapshot, error) {
+	return traced(ctx, "GetSnapshot", func(ctx context.Context) (models.ReasonSnapshot, error) {
+		return t.next.GetSnapshot(ctx, id)
+	})
+}
+
+func (t *tracedStore) ListAnnotations(ctx context.Context, targetIDs []uuid.UUID) ([]models.ReasonAnnotation, error) {
+	return traced(ctx, "ListAnnotations", func(ctx context.Context) ([]models.ReasonAnnotation, error) {
+		return t.next.ListAnnotations(ctx, targetIDs)
+	})
+}
+
+func (t *tracedStore) Ping(ctx context.Context) error {
+	_, err := traced(ctx, "Ping", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, t.next.Ping(ctx)
+	})
+	return err
+}
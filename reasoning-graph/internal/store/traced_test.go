@@ -0,0 +1,41 @@
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ILLUVRSE/Main/reasoning-graph/internal/store"
+	"github.com/ILLUVRSE/Main/reasoning-graph/internal/testutil"
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+func TestWithTracingStampsNodeMetadata(t *testing.T) {
+	ctx, span := tracing.Start(context.Background(), "request")
+	defer span.End()
+
+	st := store.WithTracing(testutil.NewMemoryStore())
+	node, err := st.CreateNode(ctx, store.NodeInput{
+		Type:     "decision",
+		Payload:  json.RawMessage(`{}`),
+		Metadata: json.RawMessage(`{"source":"eval-engine"}`),
+	})
+	if err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+	var md map[string]string
+	if err := json.Unmarshal(node.Metadata, &md); err != nil {
+		t.Fatalf("decode metadata: %v", err)
+	}
+	if md["traceId"] != span.SpanContext().TraceIDString() || md["source"] != "eval-engine" {
+		t.Fatalf("trace id not stamped into node metadata: %v", md)
+	}
+
+	plain, err := store.WithTracing(testutil.NewMemoryStore()).CreateNode(context.Background(), store.NodeInput{Type: "decision"})
+	if err != nil {
+		t.Fatalf("create node: %v", err)
+	}
+	if string(plain.Metadata) != "{}" {
+		t.Fatalf("untraced context should not add metadata, got %s", plain.Metadata)
+	}
+}
@@ -149,6 +149,10 @@ func (m *MemoryStore) GetSnapshot(ctx context.Context, id uuid.UUID) (models.Rea
 	return snapshot, nil
 }
 
+func (m *MemoryStore) ListAnnotations(ctx context.Context, targetIDs []uuid.UUID) ([]models.ReasonAnnotation, error) {
+	return []models.ReasonAnnotation{}, nil
+}
+
 func (m *MemoryStore) Ping(ctx context.Context) error {
 	return nil
 }
@@ -40,6 +40,7 @@ func (m *MockStore) ListEdgesTo(ctx context.Context, id uuid.UUID) ([]models.Rea
 func (m *MockStore) ListEdgesFrom(ctx context.Context, id uuid.UUID) ([]models.ReasonEdge, error) { return nil, nil }
 func (m *MockStore) GetSnapshot(ctx context.Context, id uuid.UUID) (models.ReasonSnapshot, error) { return models.ReasonSnapshot{}, nil }
 func (m *MockStore) CreateSnapshot(ctx context.Context, input store.SnapshotInput) (models.ReasonSnapshot, error) { return models.ReasonSnapshot{}, nil }
+func (m *MockStore) ListAnnotations(ctx context.Context, ids []uuid.UUID) ([]models.ReasonAnnotation, error) { return nil, nil }
 
 // Helper to generate RSA keys
 func generateKeyPair() (*rsa.PrivateKey, []byte, error) {
@@ -0,0 +1,266 @@
+// Package canonical implements RFC 8785 JSON Canonicalization Scheme (JCS).
+//
+// It is the single canonical JSON implementation shared by every Go service
+// (kernel, ai-infra, eval-engine, reasoning-graph) for hashing and signing, so the
+// same logical payload produces the same bytes everywhere:
+//   - object members sorted by the UTF-16 code units of their names
+//   - numbers serialized as IEEE-754 doubles using the ECMAScript Number.toString rules
+//   - strings escaped minimally (only '"', '\\' and control characters)
+//   - no insignificant whitespace
+package canonical
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrInvalidNumber is returned for NaN and ±Inf, which JSON cannot represent.
+var ErrInvalidNumber = errors.New("canonical: NaN and Infinity are not valid JSON numbers")
+
+// Marshal returns the JCS canonical form of v. v may be any value encoding/json can
+// marshal; structs are converted through their JSON representation first.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Transform parses JSON text and returns its JCS canonical form.
+func Transform(data []byte) ([]byte, error) {
+	v, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(v)
+}
+
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("canonical decode: %w", err)
+	}
+	if dec.More() {
+		return nil, errors.New("canonical decode: trailing data after JSON value")
+	}
+	return v, nil
+}
+
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch vv := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if vv {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case string:
+		return encodeString(buf, vv)
+	case json.Number:
+		f, err := strconv.ParseFloat(vv.String(), 64)
+		if err != nil {
+			return fmt.Errorf("canonical number %q: %w", vv.String(), err)
+		}
+		return encodeNumber(buf, f)
+	case float64:
+		return encodeNumber(buf, vv)
+	case float32:
+		return encodeNumber(buf, float64(vv))
+	case int:
+		return encodeNumber(buf, float64(vv))
+	case int8:
+		return encodeNumber(buf, float64(vv))
+	case int16:
+		return encodeNumber(buf, float64(vv))
+	case int32:
+		return encodeNumber(buf, float64(vv))
+	case int64:
+		return encodeNumber(buf, float64(vv))
+	case uint:
+		return encodeNumber(buf, float64(vv))
+	case uint8:
+		return encodeNumber(buf, float64(vv))
+	case uint16:
+		return encodeNumber(buf, float64(vv))
+	case uint32:
+		return encodeNumber(buf, float64(vv))
+	case uint64:
+		return encodeNumber(buf, float64(vv))
+	case json.RawMessage:
+		if len(vv) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		dv, err := decode(vv)
+		if err != nil {
+			return err
+		}
+		return encode(buf, dv)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range vv {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encode(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sortUTF16(keys)
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeString(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encode(buf, vv[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		// nil pointers/maps/slices marshal as null
+		if rv := reflect.ValueOf(v); (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Map || rv.Kind() == reflect.Slice) && rv.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		// Fallback: marshal then re-decode with UseNumber and encode recursively.
+		b, err := json.Marshal(vv)
+		if err != nil {
+			return fmt.Errorf("canonical marshal fallback: %w", err)
+		}
+		dv, err := decode(b)
+		if err != nil {
+			return err
+		}
+		return encode(buf, dv)
+	}
+	return nil
+}
+
+// sortUTF16 sorts keys by their UTF-16 code units as required by RFC 8785 §3.2.3.
+func sortUTF16(keys []string) {
+	enc := make(map[string][]uint16, len(keys))
+	for _, k := range keys {
+		enc[k] = utf16.Encode([]rune(k))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := enc[keys[i]], enc[keys[j]]
+		for n := 0; n < len(a) && n < len(b); n++ {
+			if a[n] != b[n] {
+				return a[n] < b[n]
+			}
+		}
+		return len(a) < len(b)
+	})
+}
+
+// encodeString writes s as a JSON string using the JCS escaping rules (RFC 8785 §3.2.2.2).
+func encodeString(buf *bytes.Buffer, s string) error {
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("canonical: invalid UTF-8 in string %q", s)
+	}
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return nil
+}
+
+// encodeNumber writes f using the ECMAScript Number.prototype.toString algorithm
+// (RFC 8785 §3.2.2.3).
+func encodeNumber(buf *bytes.Buffer, f float64) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return ErrInvalidNumber
+	}
+	if f == 0 {
+		buf.WriteByte('0') // also covers -0
+		return nil
+	}
+	if f < 0 {
+		buf.WriteByte('-')
+		f = -f
+	}
+
+	// Shortest round-trip digits: "d.ddddde±XX"
+	sci := strconv.FormatFloat(f, 'e', -1, 64)
+	mant, expStr, _ := strings.Cut(sci, "e")
+	digits := strings.Replace(mant, ".", "", 1)
+	exp, err := strconv.Atoi(expStr)
+	if err != nil {
+		return fmt.Errorf("canonical number exponent %q: %w", expStr, err)
+	}
+	k := len(digits)
+	n := exp + 1 // value = 0.digits × 10^n
+
+	switch {
+	case k <= n && n <= 21:
+		buf.WriteString(digits)
+		buf.WriteString(strings.Repeat("0", n-k))
+	case 0 < n && n <= 21:
+		buf.WriteString(digits[:n])
+		buf.WriteByte('.')
+		buf.WriteString(digits[n:])
+	case -6 < n && n <= 0:
+		buf.WriteString("0.")
+		buf.WriteString(strings.Repeat("0", -n))
+		buf.WriteString(digits)
+	default:
+		buf.WriteByte(digits[0])
+		if k > 1 {
+			buf.WriteByte('.')
+			buf.WriteString(digits[1:])
+		}
+		buf.WriteByte('e')
+		if n-1 >= 0 {
+			buf.WriteByte('+')
+		}
+		buf.WriteString(strconv.Itoa(n - 1))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package canonical_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ILLUVRSE/Main/shared/canonical"
+)
+
+// Number vectors from RFC 8785 Appendix B (IEEE-754 bit pattern -> expected text).
+var numberVectors = []struct {
+	bits uint64
+	want string
+}{
+	{0x0000000000000000, "0"},
+	{0x8000000000000000, "0"},
+	{0x0000000000000001, "5e-324"},
+	{0x8000000000000001, "-5e-324"},
+	{0x7fefffffffffffff, "1.7976931348623157e+308"},
+	{0xffefffffffffffff, "-1.7976931348623157e+308"},
+	{0x4340000000000000, "9007199254740992"},
+	{0xc340000000000000, "-9007199254740992"},
+	{0x4430000000000000, "295147905179352830000"},
+	{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+	{0x44b52d02c7e14af6, "1e+23"},
+	{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+	{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+	{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+	{0x444b1ae4d6e2ef50, "1e+21"},
+	{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+	{0x3eb0c6f7a0b5ed8d, "0.000001"},
+	{0x41b3de4355555553, "333333333.3333332"},
+	{0x41b3de4355555554, "333333333.33333325"},
+	{0x41b3de4355555555, "333333333.3333333"},
+	{0x41b3de4355555556, "333333333.3333334"},
+	{0x41b3de4355555557, "333333333.33333343"},
+	{0xbecbf647612f3696, "-0.0000033333333333333333"},
+	{0x43143ff3c1cb0959, "1424953923781206.2"},
+}
+
+func TestNumberVectors(t *testing.T) {
+	for _, v := range numberVectors {
+		f := math.Float64frombits(v.bits)
+		got, err := canonical.Marshal(f)
+		if err != nil {
+			t.Fatalf("%016x: %v", v.bits, err)
+		}
+		if string(got) != v.want {
+			t.Errorf("%016x: want %s got %s", v.bits, v.want, got)
+		}
+	}
+}
+
+func TestInvalidNumbers(t *testing.T) {
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := canonical.Marshal(f); !errors.Is(err, canonical.ErrInvalidNumber) {
+			t.Fatalf("expected ErrInvalidNumber for %v, got %v", f, err)
+		}
+	}
+}
+
+// RFC 8785 §3.2.2 example.
+func TestTransformRFCExample(t *testing.T) {
+	in := `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`
+	want := `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`
+	got, err := canonical.Transform([]byte(in))
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("want %s\n got %s", want, got)
+	}
+}
+
+// RFC 8785 §3.2.3 sorting example: keys are ordered by UTF-16 code units, so the
+// emoji (surrogate pair D83D DE00) sorts before U+FB33.
+func TestTransformUTF16KeyOrder(t *testing.T) {
+	in := `{
+  "\u20ac": "Euro Sign",
+  "\r": "Carriage Return",
+  "\ufb33": "Hebrew Letter Dalet With Dagesh",
+  "1": "One",
+  "\ud83d\ude00": "Emoji: Grinning Face",
+  "\u0080": "Control",
+  "\u00f6": "Latin Small Letter O With Diaeresis"
+}`
+	want := "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"
+	got, err := canonical.Transform([]byte(in))
+	if err != nil {
+		t.Fatalf("Transform: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("want %s\n got %s", want, got)
+	}
+}
+
+func TestMarshalNoHTMLEscapingAndStructs(t *testing.T) {
+	type inner struct {
+		B string          `json:"b"`
+		A json.RawMessage `json:"a"`
+	}
+	got, err := canonical.Marshal(inner{B: "<&>\u2028", A: json.RawMessage(`{"z":1.0,"y":[10]}`)})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := "{\"a\":{\"y\":[10],\"z\":1},\"b\":\"<&>\u2028\"}"
+	if string(got) != want {
+		t.Fatalf("want %s\n got %s", want, got)
+	}
+}
+
+// The same payload must canonicalize identically whether numbers arrive as
+// json.Number text, float64 or Go integers.
+func TestNumberRepresentationsAgree(t *testing.T) {
+	a, _ := canonical.Marshal(map[string]interface{}{"n": json.Number("1.50"), "i": json.Number("10")})
+	b, _ := canonical.Marshal(map[string]interface{}{"n": 1.5, "i": 10})
+	if string(a) != string(b) || string(a) != `{"i":10,"n":1.5}` {
+		t.Fatalf("representations differ: %s vs %s", a, b)
+	}
+}
@@ -0,0 +1,92 @@
+package evalclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Allocation statuses reported by the Resource Allocator.
+const (
+	AllocationPending  = "pending"
+	AllocationApplied  = "applied"
+	AllocationRejected = "rejected"
+)
+
+// AllocationRequest is the body of POST /alloc/request.
+type AllocationRequest struct {
+	PromotionID string `json:"promotionId,omitempty"`
+	AgentID     string `json:"agentId"`
+	Pool        string `json:"pool"`
+	Delta       int    `json:"delta"`
+	Reason      string `json:"reason,omitempty"`
+	RequestedBy string `json:"requestedBy,omitempty"`
+}
+
+// AllocationTicket is the allocator's answer to POST /alloc/request.
+type AllocationTicket struct {
+	RequestID string `json:"requestId"`
+	Status    string `json:"status"`
+}
+
+// Allocation is the allocator's record of one request (GET /alloc/{id}).
+type Allocation struct {
+	ID               string          `json:"id"`
+	PromotionID      string          `json:"promotionId,omitempty"`
+	AgentID          string          `json:"agentId"`
+	Pool             string          `json:"pool"`
+	Delta            int             `json:"delta"`
+	Reason           string          `json:"reason"`
+	Status           string          `json:"status"`
+	SentinelDecision json.RawMessage `json:"sentinelDecision,omitempty"`
+	RequestedBy      string          `json:"requestedBy,omitempty"`
+	AppliedBy        *string         `json:"appliedBy,omitempty"`
+	AppliedAt        *time.Time      `json:"appliedAt,omitempty"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	UpdatedAt        time.Time       `json:"updatedAt"`
+}
+
+// Allocator calls the eval-engine Resource Allocator.
+type Allocator struct {
+	c *Client
+}
+
+// NewAllocator builds an Allocator client for cfg.BaseURL.
+func NewAllocator(cfg Config) (*Allocator, error) {
+	c, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Allocator{c: c}, nil
+}
+
+// RequestAllocation files a pending allocation request.
+func (a *Allocator) RequestAllocation(ctx context.Context, req AllocationRequest) (AllocationTicket, error) {
+	var out AllocationTicket
+	if err := a.c.do(ctx, http.MethodPost, "/alloc/request", req, &out); err != nil {
+		return AllocationTicket{}, err
+	}
+	if out.RequestID == "" {
+		return AllocationTicket{}, errors.New("eval-engine POST /alloc/request: response has no requestId")
+	}
+	return out, nil
+}
+
+// Approve asks the allocator to apply a pending request. The allocator runs its
+// SentinelNet check, so the returned record is either applied or rejected.
+func (a *Allocator) Approve(ctx context.Context, requestID, approvedBy string) (Allocation, error) {
+	var out Allocation
+	body := map[string]string{"requestId": requestID, "approvedBy": approvedBy}
+	err := a.c.do(ctx, http.MethodPost, "/alloc/approve", body, &out)
+	return out, err
+}
+
+// Get fetches the allocator's current record for requestID.
+func (a *Allocator) Get(ctx context.Context, requestID string) (Allocation, error) {
+	var out Allocation
+	err := a.c.do(ctx, http.MethodGet, "/alloc/"+url.PathEscape(requestID), nil, &out)
+	return out, err
+}
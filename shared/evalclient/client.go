@@ -0,0 +1,105 @@
+// Package evalclient is the Go client the Kernel uses to drive the eval-engine services
+// it fronts: the Resource Allocator (/alloc/*) and the eval ingestion service (/eval/*).
+//
+// Each eval-engine service listens on its own address, so a Client is built per
+// service URL and wrapped by the typed service clients (Allocator, Ingestion). Requests
+// go through shared/jsonhttp, which handles bearer token auth and W3C traceparent
+// propagation; the client adds typed errors (*APIError, matchable with errors.Is against
+// ErrNotFound etc.). Only GETs are retried: the eval-engine write endpoints are not
+// idempotent.
+package evalclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ILLUVRSE/Main/shared/jsonhttp"
+)
+
+var (
+	ErrBadRequest  = errors.New("eval-engine: bad request")
+	ErrNotFound    = errors.New("eval-engine: not found")
+	ErrUnavailable = errors.New("eval-engine: unavailable")
+)
+
+// APIError is returned for any non-2xx eval-engine response.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("eval-engine %s %s: HTTP %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// Is maps status codes onto the package sentinel errors.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnavailable:
+		return jsonhttp.Retryable(e.StatusCode)
+	}
+	return false
+}
+
+// Config configures a Client. Only BaseURL is required.
+type Config struct {
+	BaseURL     string
+	BearerToken string
+	// Service is sent as User-Agent so eval-engine logs identify the caller.
+	Service    string
+	Timeout    time.Duration
+	Retries    int
+	Backoff    time.Duration
+	HTTPClient *http.Client
+}
+
+// Client calls one eval-engine service.
+type Client struct {
+	core *jsonhttp.Client
+}
+
+// New builds a Client. Retries 0 selects the default (2); negative disables retries.
+func New(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("eval-engine base url required")
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+	hc, err := jsonhttp.New(jsonhttp.Options{
+		Name:        "eval-engine",
+		BaseURL:     cfg.BaseURL,
+		BearerToken: cfg.BearerToken,
+		Service:     cfg.Service,
+		HTTPClient:  client,
+		Retries:     cfg.Retries,
+		Backoff:     cfg.Backoff,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{core: hc}, nil
+}
+
+// do sends one request, retrying transient failures of GETs. out may be nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	err := c.core.Do(ctx, method, path, in, out)
+	if se, ok := jsonhttp.AsStatusError(err); ok {
+		return &APIError{Method: se.Method, Path: se.Path, StatusCode: se.StatusCode, Message: se.Message}
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package evalclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ILLUVRSE/Main/shared/evalclient"
+)
+
+func TestAllocatorRoutesAndErrors(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		posts int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/alloc/request":
+			mu.Lock()
+			posts++
+			mu.Unlock()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.Method == http.MethodPost && r.URL.Path == "/alloc/approve":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			by := body["approvedBy"]
+			_ = json.NewEncoder(w).Encode(evalclient.Allocation{ID: body["requestId"], Status: evalclient.AllocationApplied, AppliedBy: &by})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"allocation not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	alloc, err := evalclient.NewAllocator(evalclient.Config{BaseURL: srv.URL, Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewAllocator: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := alloc.RequestAllocation(ctx, evalclient.AllocationRequest{AgentID: "a", Pool: "cpu", Delta: 1}); !errors.Is(err, evalclient.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if posts != 1 {
+		t.Fatalf("writes must not be retried, got %d attempts", posts)
+	}
+	a, err := alloc.Approve(ctx, "alloc-1", "kernel")
+	if err != nil || a.Status != evalclient.AllocationApplied || a.AppliedBy == nil || *a.AppliedBy != "kernel" {
+		t.Fatalf("Approve: %+v %v", a, err)
+	}
+	_, err = alloc.Get(ctx, "missing")
+	var apiErr *evalclient.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, evalclient.ErrNotFound) || apiErr.Message != "allocation not found" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package evalclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// EvalSubmission is the body of POST /eval/submit.
+type EvalSubmission struct {
+	// ID is optional and must be a UUID; the Kernel sends its own eval id.
+	ID         string                 `json:"id,omitempty"`
+	AgentID    string                 `json:"agentId"`
+	DivisionID string                 `json:"divisionId,omitempty"`
+	Metrics    map[string]interface{} `json:"metrics"`
+	Source     string                 `json:"source,omitempty"`
+	Tags       json.RawMessage        `json:"tags,omitempty"`
+	Timestamp  *time.Time             `json:"timestamp,omitempty"`
+	// KernelAuditID is stored on the report and on any promotion it triggers.
+	KernelAuditID string `json:"kernelAuditId,omitempty"`
+}
+
+// AgentScore is the score eval-engine computed for an agent.
+type AgentScore struct {
+	AgentID    string          `json:"agentId"`
+	DivisionID string          `json:"divisionId,omitempty"`
+	Score      float64         `json:"score"`
+	Components json.RawMessage `json:"components,omitempty"`
+	Confidence float64         `json:"confidence"`
+	Window     string          `json:"window,omitempty"`
+	ComputedAt time.Time       `json:"computedAt"`
+}
+
+// PromotionEvent is a promotion eval-engine raised for an agent.
+type PromotionEvent struct {
+	ID                  string    `json:"id"`
+	AgentID             string    `json:"agentId"`
+	Action              string    `json:"action"`
+	Rationale           string    `json:"rationale"`
+	Confidence          float64   `json:"confidence"`
+	Status              string    `json:"status"`
+	RequestedBy         string    `json:"requestedBy"`
+	AllocationRequestID string    `json:"allocationRequestId,omitempty"`
+	KernelAuditID       string    `json:"kernelAuditId,omitempty"`
+	CreatedAt           time.Time `json:"createdAt"`
+}
+
+// EvalResult is the answer to POST /eval/submit. Promotion is nil unless the score
+// crossed the promotion threshold.
+type EvalResult struct {
+	ReportID  string          `json:"reportId"`
+	Score     AgentScore      `json:"score"`
+	Promotion *PromotionEvent `json:"promotion,omitempty"`
+}
+
+// Ingestion calls the eval-engine ingestion service.
+type Ingestion struct {
+	c *Client
+}
+
+// NewIngestion builds an Ingestion client for cfg.BaseURL.
+func NewIngestion(cfg Config) (*Ingestion, error) {
+	c, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Ingestion{c: c}, nil
+}
+
+// SubmitReport stores a report and returns the resulting score and promotion.
+func (i *Ingestion) SubmitReport(ctx context.Context, sub EvalSubmission) (EvalResult, error) {
+	var out EvalResult
+	err := i.c.do(ctx, http.MethodPost, "/eval/submit", sub, &out)
+	return out, err
+}
@@ -0,0 +1,215 @@
+// Package jsonhttp is the JSON-over-HTTP core shared by the Go service clients
+// (shared/kernelclient, shared/evalclient). It sends JSON requests to one base URL with
+// bearer token auth and a User-Agent, propagates the W3C traceparent (shared/tracing),
+// retries transient failures with exponential backoff and reports non-2xx responses as
+// *StatusError. The typed clients wrap it with their endpoints and error types.
+package jsonhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ILLUVRSE/Main/shared/tracing"
+)
+
+// maxResponseBody caps how much of a response body is read.
+const maxResponseBody = 4 << 20
+
+// Options configures a Client. Name and BaseURL are required.
+type Options struct {
+	// Name prefixes errors, e.g. "kernel" or "eval-engine".
+	Name        string
+	BaseURL     string
+	BearerToken string
+	// Service is sent as User-Agent so the callee's logs identify the caller.
+	Service string
+	// HTTPClient sends the requests; it is wrapped for trace propagation.
+	HTTPClient *http.Client
+	// Retries 0 selects the default (2); negative disables retries.
+	Retries int
+	Backoff time.Duration
+	// IdempotentWrites retries non-GET requests too. They carry an Idempotency-Key
+	// that stays the same across the retries of one call; without it only GETs are
+	// retried.
+	IdempotentWrites bool
+}
+
+// Client sends JSON requests to one service.
+type Client struct {
+	name             string
+	baseURL          string
+	token            string
+	service          string
+	client           *http.Client
+	retries          int
+	backoff          time.Duration
+	idempotentWrites bool
+}
+
+// New builds a Client.
+func New(opts Options) (*Client, error) {
+	base := strings.TrimRight(opts.BaseURL, "/")
+	if base == "" {
+		return nil, fmt.Errorf("%s base url required", opts.Name)
+	}
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	retries := opts.Retries
+	if retries == 0 {
+		retries = 2
+	} else if retries < 0 {
+		retries = 0
+	}
+	backoff := opts.Backoff
+	if backoff <= 0 {
+		backoff = 200 * time.Millisecond
+	}
+	return &Client{
+		name:             opts.Name,
+		baseURL:          base,
+		token:            opts.BearerToken,
+		service:          opts.Service,
+		client:           tracing.WrapClient(client),
+		retries:          retries,
+		backoff:          backoff,
+		idempotentWrites: opts.IdempotentWrites,
+	}, nil
+}
+
+// StatusError is returned for any non-2xx response.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: HTTP %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// Retryable reports whether a response status is transient: 429, 502, 503 or 504.
+func Retryable(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusBadGateway ||
+		code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey makes the next write on ctx send key instead of a generated one, so
+// callers can retry a whole operation without duplicating it.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// Do sends one logical request, retrying transient failures. in is encoded as the JSON
+// body when non-nil and a 2xx response body is decoded into out when non-nil.
+func (c *Client) Do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("%s marshal %s: %w", c.name, path, err)
+		}
+		body = b
+	}
+	retries := c.retries
+	idemKey := ""
+	if method != http.MethodGet {
+		if c.idempotentWrites {
+			idemKey, _ = ctx.Value(idempotencyKeyCtx{}).(string)
+			if idemKey == "" {
+				idemKey = uuid.New().String()
+			}
+		} else {
+			retries = 0
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.backoff * time.Duration(1<<(attempt-1))):
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("%s request %s: %w", c.name, path, err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", "application/json")
+		if idemKey != "" {
+			req.Header.Set("Idempotency-Key", idemKey)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		if c.service != "" {
+			req.Header.Set("User-Agent", c.service)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = fmt.Errorf("%s %s %s: %w", c.name, method, path, err)
+			continue
+		}
+		respBody, readErr := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			statusErr := &StatusError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: errorMessage(respBody)}
+			if Retryable(resp.StatusCode) {
+				lastErr = statusErr
+				continue
+			}
+			return statusErr
+		}
+		if readErr != nil {
+			return fmt.Errorf("%s read %s: %w", c.name, path, readErr)
+		}
+		if out != nil && len(respBody) > 0 {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return fmt.Errorf("%s decode %s: %w", c.name, path, err)
+			}
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// errorMessage extracts {"error": "..."} bodies and falls back to the plain text
+// http.Error writes.
+func errorMessage(body []byte) string {
+	var e struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error != "" {
+		return e.Error
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// AsStatusError returns err's *StatusError, if it wraps one.
+func AsStatusError(err error) (*StatusError, bool) {
+	var se *StatusError
+	ok := errors.As(err, &se)
+	return se, ok
+}
@@ -0,0 +1,114 @@
+package jsonhttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ILLUVRSE/Main/shared/jsonhttp"
+)
+
+// flaky answers 503 to the first fails requests and records their methods and
+// Idempotency-Key headers.
+type flaky struct {
+	mu    sync.Mutex
+	fails int
+	seen  []*http.Request
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.seen = append(f.seen, r)
+	n := len(f.seen)
+	f.mu.Unlock()
+	if n <= f.fails {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var in map[string]string
+	_ = json.NewDecoder(r.Body).Decode(&in)
+	_ = json.NewEncoder(w).Encode(map[string]string{"echo": in["msg"], "agent": r.Header.Get("User-Agent"), "auth": r.Header.Get("Authorization")})
+}
+
+func newClient(t *testing.T, h http.Handler, idempotent bool) *jsonhttp.Client {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c, err := jsonhttp.New(jsonhttp.Options{
+		Name:             "test",
+		BaseURL:          srv.URL + "/",
+		BearerToken:      "tok",
+		Service:          "svc",
+		Backoff:          time.Millisecond,
+		IdempotentWrites: idempotent,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func TestIdempotentWritesRetryWithOneKey(t *testing.T) {
+	f := &flaky{fails: 2}
+	c := newClient(t, f, true)
+	var out map[string]string
+	ctx := jsonhttp.WithIdempotencyKey(context.Background(), "op-1")
+	if err := c.Do(ctx, http.MethodPost, "/echo", map[string]string{"msg": "hi"}, &out); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if out["echo"] != "hi" || out["agent"] != "svc" || out["auth"] != "Bearer tok" {
+		t.Fatalf("unexpected response %v", out)
+	}
+	if len(f.seen) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(f.seen))
+	}
+	for _, r := range f.seen {
+		if r.Header.Get("Idempotency-Key") != "op-1" || r.URL.Path != "/echo" {
+			t.Fatalf("attempt %s key=%q", r.URL.Path, r.Header.Get("Idempotency-Key"))
+		}
+	}
+}
+
+func TestWritesAreNotRetriedWithoutIdempotency(t *testing.T) {
+	f := &flaky{fails: 1}
+	c := newClient(t, f, false)
+	err := c.Do(context.Background(), http.MethodPost, "/echo", map[string]string{"msg": "hi"}, nil)
+	se, ok := jsonhttp.AsStatusError(err)
+	if !ok || se.StatusCode != http.StatusServiceUnavailable || len(f.seen) != 1 {
+		t.Fatalf("expected one failed attempt, got %v after %d", err, len(f.seen))
+	}
+	if f.seen[0].Header.Get("Idempotency-Key") != "" {
+		t.Fatalf("non-idempotent writes must not send an Idempotency-Key")
+	}
+
+	// GETs are still retried.
+	if err := c.Do(context.Background(), http.MethodGet, "/echo", nil, nil); err != nil || len(f.seen) != 2 {
+		t.Fatalf("GET: %v after %d", err, len(f.seen))
+	}
+}
+
+func TestStatusErrorMessage(t *testing.T) {
+	c := newClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"name is required"}`))
+	}), false)
+
+	err := c.Do(context.Background(), http.MethodGet, "/json", nil, nil)
+	if se, ok := jsonhttp.AsStatusError(err); !ok || se.Message != "name is required" || se.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected error %v", err)
+	}
+	err = c.Do(context.Background(), http.MethodGet, "/plain", nil, nil)
+	if se, ok := jsonhttp.AsStatusError(err); !ok || se.Message != "not found" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := jsonhttp.New(jsonhttp.Options{Name: "test"}); err == nil {
+		t.Fatalf("expected a missing base url error, got %v", err)
+	}
+}
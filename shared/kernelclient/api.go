@@ -0,0 +1,325 @@
+package kernelclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ManifestSignature is returned by POST /kernel/sign and POST /kernel/division.
+type ManifestSignature struct {
+	ID         string    `json:"id,omitempty"`
+	ManifestID string    `json:"manifestId"`
+	SignerID   string    `json:"signerId"`
+	Signature  string    `json:"signature"`
+	Version    string    `json:"version,omitempty"`
+	Ts         time.Time `json:"ts"`
+}
+
+// AuditEvent mirrors the Kernel's audit record.
+type AuditEvent struct {
+	ID        string          `json:"id,omitempty"`
+	EventType string          `json:"eventType"`
+	Payload   json.RawMessage `json:"payload"`
+	PrevHash  string          `json:"prevHash,omitempty"`
+	Hash      string          `json:"hash,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+	SignerID  string          `json:"signerId,omitempty"`
+	Ts        time.Time       `json:"ts"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+}
+
+// EvalReport is the body of POST /kernel/eval.
+type EvalReport struct {
+	ID        string                 `json:"id,omitempty"`
+	AgentID   string                 `json:"agentId"`
+	MetricSet map[string]interface{} `json:"metricSet"`
+	Timestamp *time.Time             `json:"timestamp,omitempty"`
+	Source    string                 `json:"source,omitempty"`
+}
+
+// EvalResult is the POST /kernel/eval response when the Kernel forwards reports to the
+// eval-engine. Score and Promotion are passed through as returned by eval-engine.
+type EvalResult struct {
+	EvalID       string          `json:"eval_id"`
+	ReportID     string          `json:"reportId,omitempty"`
+	AuditEventID string          `json:"auditEventId,omitempty"`
+	Score        json.RawMessage `json:"score,omitempty"`
+	Promotion    json.RawMessage `json:"promotion,omitempty"`
+}
+
+// AllocationRequest is the body of POST /kernel/allocate.
+type AllocationRequest struct {
+	ID         string `json:"id,omitempty"`
+	DivisionID string `json:"divisionId"`
+	CPU        int    `json:"cpu,omitempty"`
+	GPU        int    `json:"gpu,omitempty"`
+	MemoryMB   int    `json:"memoryMB,omitempty"`
+	// EntityID, PromotionID, Pool and Delta address the Resource Allocator directly;
+	// otherwise exactly one of CPU, GPU and MemoryMB selects the pool.
+	EntityID    string `json:"entityId,omitempty"`
+	PromotionID string `json:"promotionId,omitempty"`
+	Pool        string `json:"pool,omitempty"`
+	Delta       int    `json:"delta,omitempty"`
+	Requester   string `json:"requester,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// AllocationResult is the Kernel's allocation record, returned by POST /kernel/allocate
+// and GET /kernel/allocate/{id}. Status is pending, applied, rejected or failed.
+type AllocationResult struct {
+	AllocationID       string          `json:"allocationId"`
+	Status             string          `json:"status"`
+	DivisionID         string          `json:"divisionId,omitempty"`
+	EntityID           string          `json:"entityId,omitempty"`
+	Pool               string          `json:"pool,omitempty"`
+	Delta              int             `json:"delta,omitempty"`
+	Requester          string          `json:"requester,omitempty"`
+	AllocatorRequestID string          `json:"allocatorRequestId,omitempty"`
+	SentinelDecision   json.RawMessage `json:"sentinelDecision,omitempty"`
+	AppliedBy          string          `json:"appliedBy,omitempty"`
+	Error              string          `json:"error,omitempty"`
+}
+
+// SignerKey is a Kernel signer public key from /kernel/security/status.
+type SignerKey struct {
+	SignerID  string `json:"signerId"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"publicKey"` // base64
+}
+
+// Signers lists the Kernel signer public keys, used to verify Kernel signatures.
+func (c *Client) Signers(ctx context.Context) ([]SignerKey, error) {
+	var out struct {
+		Signers []SignerKey `json:"signers"`
+	}
+	err := c.do(ctx, http.MethodGet, "/kernel/security/status", nil, &out)
+	return out.Signers, err
+}
+
+// Sign asks the Kernel to sign manifest. The Kernel signs sha256(JCS(manifest)), so a
+// caller that already holds canonical JSON can pass it as json.RawMessage and verify
+// the signature against sha256 of those bytes.
+func (c *Client) Sign(ctx context.Context, manifest interface{}, version string) (ManifestSignature, error) {
+	var out ManifestSignature
+	err := c.do(ctx, http.MethodPost, "/kernel/sign", map[string]interface{}{
+		"manifest": manifest,
+		"version":  version,
+	}, &out)
+	return out, err
+}
+
+// SignBatchItem is one manifest in a batch signing request.
+type SignBatchItem struct {
+	Manifest interface{} `json:"manifest"`
+	Version  string      `json:"version,omitempty"`
+}
+
+// SignBatchItemResult is the per-item outcome of SignBatch; Error is set for items the
+// Kernel could not sign.
+type SignBatchItemResult struct {
+	Index       int      `json:"index"`
+	ManifestID  string   `json:"manifestId,omitempty"`
+	Hash        string   `json:"hash,omitempty"` // hex sha256(JCS(manifest))
+	SignatureID string   `json:"signatureId,omitempty"`
+	Signature   string   `json:"signature,omitempty"` // each mode
+	LeafIndex   *int     `json:"leafIndex,omitempty"` // merkle mode
+	Proof       []string `json:"proof,omitempty"`     // merkle mode, hex
+	Error       string   `json:"error,omitempty"`
+}
+
+// SignBatchResult is returned by POST /kernel/sign/batch. In merkle mode Root is signed
+// once and each item proves inclusion with shared/merkle.Verify.
+type SignBatchResult struct {
+	BatchID       string                `json:"batchId"`
+	Mode          string                `json:"mode"`
+	SignerID      string                `json:"signerId,omitempty"`
+	Root          string                `json:"root,omitempty"`
+	RootSignature string                `json:"rootSignature,omitempty"`
+	TreeSize      int                   `json:"treeSize,omitempty"`
+	AuditID       string                `json:"auditId,omitempty"`
+	Succeeded     int                   `json:"succeeded"`
+	Failed        int                   `json:"failed"`
+	Items         []SignBatchItemResult `json:"items"`
+}
+
+// SignBatch signs many manifests in one request with one audit event. mode is "each"
+// (a signature per manifest) or "merkle" (one signature over a Merkle root). Partial
+// failures are reported per item; a batch where every item failed returns an *APIError
+// with status 422.
+func (c *Client) SignBatch(ctx context.Context, mode, version string, items []SignBatchItem) (SignBatchResult, error) {
+	var out SignBatchResult
+	err := c.do(ctx, http.MethodPost, "/kernel/sign/batch", map[string]interface{}{
+		"mode":    mode,
+		"version": version,
+		"items":   items,
+	}, &out)
+	return out, err
+}
+
+// SignJobRequest is the body of POST /kernel/sign/jobs.
+type SignJobRequest struct {
+	Manifest        interface{} `json:"manifest"`
+	Version         string      `json:"version,omitempty"`
+	CallbackURL     string      `json:"callbackUrl,omitempty"`
+	RequireApproval bool        `json:"requireApproval,omitempty"`
+}
+
+// SignJob is an asynchronous signing job. Status is awaiting_approval, queued,
+// signing, succeeded, failed or rejected; Signature is set once it succeeded.
+type SignJob struct {
+	ID               string     `json:"id"`
+	Status           string     `json:"status"`
+	ManifestID       string     `json:"manifestId"`
+	ManifestHash     string     `json:"manifestHash"` // hex sha256(JCS(manifest))
+	Version          string     `json:"version,omitempty"`
+	Requester        string     `json:"requester,omitempty"`
+	RequireApproval  bool       `json:"requireApproval"`
+	DecidedBy        string     `json:"decidedBy,omitempty"`
+	DecisionReason   string     `json:"decisionReason,omitempty"`
+	SignatureID      string     `json:"signatureId,omitempty"`
+	SignerID         string     `json:"signerId,omitempty"`
+	Signature        string     `json:"signature,omitempty"`
+	Error            string     `json:"error,omitempty"`
+	CallbackURL      string     `json:"callbackUrl,omitempty"`
+	CallbackStatus   string     `json:"callbackStatus,omitempty"`
+	CallbackAttempts int        `json:"callbackAttempts,omitempty"`
+	CallbackError    string     `json:"callbackError,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+	CompletedAt      *time.Time `json:"completedAt,omitempty"`
+}
+
+// Done reports whether the job reached a terminal state.
+func (j SignJob) Done() bool {
+	return j.Status == "succeeded" || j.Status == "failed" || j.Status == "rejected"
+}
+
+// SubmitSignJob queues an asynchronous signing job and returns it without waiting
+// for the signature.
+func (c *Client) SubmitSignJob(ctx context.Context, req SignJobRequest) (SignJob, error) {
+	var out SignJob
+	err := c.do(ctx, http.MethodPost, "/kernel/sign/jobs", req, &out)
+	return out, err
+}
+
+// GetSignJob fetches a signing job.
+func (c *Client) GetSignJob(ctx context.Context, id string) (SignJob, error) {
+	var out SignJob
+	err := c.do(ctx, http.MethodGet, "/kernel/sign/jobs/"+url.PathEscape(id), nil, &out)
+	return out, err
+}
+
+// DecideSignJob approves or rejects a job awaiting approval.
+func (c *Client) DecideSignJob(ctx context.Context, id string, approve bool, reason string) (SignJob, error) {
+	var out SignJob
+	err := c.do(ctx, http.MethodPost, "/kernel/sign/jobs/"+url.PathEscape(id)+"/decision", map[string]interface{}{
+		"approve": approve,
+		"reason":  reason,
+	}, &out)
+	return out, err
+}
+
+// VerifySignJobCallback checks the X-Kernel-Signature header of a sign job callback:
+// "sha256=" + hex HMAC-SHA256 of "<X-Kernel-Timestamp>.<body>" keyed with the shared
+// callback secret. Callers should also reject timestamps outside their replay window.
+func VerifySignJobCallback(secret []byte, timestamp string, body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(want), []byte(signature))
+}
+
+// AppendAudit appends an event to the Kernel audit log and returns the signed,
+// chained record.
+func (c *Client) AppendAudit(ctx context.Context, eventType string, payload, metadata interface{}) (AuditEvent, error) {
+	body := map[string]interface{}{"eventType": eventType, "payload": payload}
+	if metadata != nil {
+		body["metadata"] = metadata
+	}
+	var out AuditEvent
+	err := c.do(ctx, http.MethodPost, "/kernel/audit", body, &out)
+	return out, err
+}
+
+// GetAudit fetches one audit event by id.
+func (c *Client) GetAudit(ctx context.Context, id string) (AuditEvent, error) {
+	var out AuditEvent
+	err := c.do(ctx, http.MethodGet, "/kernel/audit/"+url.PathEscape(id), nil, &out)
+	return out, err
+}
+
+// RegisterDivision upserts a division manifest (id and name required) and returns the
+// Kernel's signature over it.
+func (c *Client) RegisterDivision(ctx context.Context, manifest map[string]interface{}) (ManifestSignature, error) {
+	var out struct {
+		ManifestSignature ManifestSignature `json:"manifestSignature"`
+	}
+	err := c.do(ctx, http.MethodPost, "/kernel/division", manifest, &out)
+	return out.ManifestSignature, err
+}
+
+// GetDivision returns a division manifest.
+func (c *Client) GetDivision(ctx context.Context, id string) (map[string]interface{}, error) {
+	var out struct {
+		Manifest map[string]interface{} `json:"manifest"`
+	}
+	err := c.do(ctx, http.MethodGet, "/kernel/division/"+url.PathEscape(id), nil, &out)
+	return out.Manifest, err
+}
+
+// CreateAgent registers an agent profile and returns its id.
+func (c *Client) CreateAgent(ctx context.Context, profile map[string]interface{}) (string, error) {
+	var out struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/kernel/agent", profile, &out)
+	return out.ID, err
+}
+
+// GetAgentState returns the stored agent profile.
+func (c *Client) GetAgentState(ctx context.Context, id string) (map[string]interface{}, error) {
+	var out struct {
+		State map[string]interface{} `json:"state"`
+	}
+	err := c.do(ctx, http.MethodGet, "/kernel/agent/"+url.PathEscape(id)+"/state", nil, &out)
+	return out.State, err
+}
+
+// SubmitEval records an eval report and returns its id.
+func (c *Client) SubmitEval(ctx context.Context, report EvalReport) (string, error) {
+	var out struct {
+		EvalID string `json:"eval_id"`
+	}
+	err := c.do(ctx, http.MethodPost, "/kernel/eval", report, &out)
+	return out.EvalID, err
+}
+
+// Evaluate submits an eval report and returns the full response, including the score
+// computed by eval-engine when the Kernel forwards reports to it.
+func (c *Client) Evaluate(ctx context.Context, report EvalReport) (EvalResult, error) {
+	var out EvalResult
+	err := c.do(ctx, http.MethodPost, "/kernel/eval", report, &out)
+	return out, err
+}
+
+// Allocate submits an allocation request.
+func (c *Client) Allocate(ctx context.Context, req AllocationRequest) (AllocationResult, error) {
+	var out AllocationResult
+	err := c.do(ctx, http.MethodPost, "/kernel/allocate", req, &out)
+	return out, err
+}
+
+// GetAllocation fetches an allocation record, reconciled with the Resource Allocator.
+func (c *Client) GetAllocation(ctx context.Context, id string) (AllocationResult, error) {
+	var out AllocationResult
+	err := c.do(ctx, http.MethodGet, "/kernel/allocate/"+url.PathEscape(id), nil, &out)
+	return out, err
+}
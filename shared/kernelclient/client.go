@@ -0,0 +1,197 @@
+// Package kernelclient is the Go client for the Kernel API used by the Go services
+// (ai-infra, eval-engine, reasoning-graph).
+//
+// The Kernel is the authority for signatures and the audit log, so services call it
+// instead of signing or recording audit events locally. The client, built on the
+// shared JSON HTTP core in shared/jsonhttp, handles:
+//   - mTLS (client certificate + optional CA bundle) and bearer token auth
+//   - retries with backoff for transport errors, 429 and 502/503/504
+//   - an Idempotency-Key header that stays the same across retries of one call
+//   - typed errors (*APIError, matchable with errors.Is against ErrNotFound etc.)
+//   - W3C traceparent propagation (shared/tracing)
+//
+// kernel/api/openapi.yaml remains the contract; kernel/api/gen is the raw OpenAPI
+// generator output and is not imported by services.
+package kernelclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ILLUVRSE/Main/shared/jsonhttp"
+)
+
+var (
+	ErrBadRequest   = errors.New("kernel: bad request")
+	ErrUnauthorized = errors.New("kernel: unauthorized")
+	ErrForbidden    = errors.New("kernel: forbidden")
+	ErrNotFound     = errors.New("kernel: not found")
+	ErrConflict     = errors.New("kernel: conflict")
+	ErrUnavailable  = errors.New("kernel: unavailable")
+)
+
+// APIError is returned for any non-2xx Kernel response.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("kernel %s %s: HTTP %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// Is maps status codes onto the package sentinel errors.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnavailable:
+		return jsonhttp.Retryable(e.StatusCode)
+	}
+	return false
+}
+
+// Config configures a Client. Only BaseURL is required.
+type Config struct {
+	BaseURL     string
+	BearerToken string
+	// mTLS: client certificate/key and an optional CA bundle for the Kernel's cert.
+	ClientCertFile string
+	ClientKeyFile  string
+	CAFile         string
+	// Service is sent as User-Agent so Kernel logs identify the caller.
+	Service    string
+	Timeout    time.Duration
+	Retries    int
+	Backoff    time.Duration
+	HTTPClient *http.Client
+}
+
+// ConfigFromEnv reads the shared Kernel client variables: KERNEL_API_URL,
+// KERNEL_API_TOKEN, KERNEL_CLIENT_CERT, KERNEL_CLIENT_KEY, KERNEL_CA_CERT,
+// KERNEL_TIMEOUT_MS and KERNEL_RETRIES.
+func ConfigFromEnv(service string) Config {
+	cfg := Config{
+		BaseURL:        os.Getenv("KERNEL_API_URL"),
+		BearerToken:    os.Getenv("KERNEL_API_TOKEN"),
+		ClientCertFile: os.Getenv("KERNEL_CLIENT_CERT"),
+		ClientKeyFile:  os.Getenv("KERNEL_CLIENT_KEY"),
+		CAFile:         os.Getenv("KERNEL_CA_CERT"),
+		Service:        service,
+	}
+	if v := os.Getenv("KERNEL_TIMEOUT_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+			cfg.Timeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if v := os.Getenv("KERNEL_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.Retries = n
+		} else if err == nil && n == 0 {
+			cfg.Retries = -1
+		}
+	}
+	return cfg
+}
+
+// Client calls the Kernel API.
+type Client struct {
+	core *jsonhttp.Client
+}
+
+// New builds a Client. Retries 0 selects the default (2); negative disables retries.
+func New(cfg Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("kernel base url required")
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		tlsCfg, err := loadTLS(cfg)
+		if err != nil {
+			return nil, err
+		}
+		client = &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsCfg, Proxy: http.ProxyFromEnvironment},
+		}
+	}
+	// Every Kernel call carries the caller's traceparent so the Kernel stamps the trace
+	// id into the audit events it records.
+	hc, err := jsonhttp.New(jsonhttp.Options{
+		Name:             "kernel",
+		BaseURL:          cfg.BaseURL,
+		BearerToken:      cfg.BearerToken,
+		Service:          cfg.Service,
+		HTTPClient:       client,
+		Retries:          cfg.Retries,
+		Backoff:          cfg.Backoff,
+		IdempotentWrites: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{core: hc}, nil
+}
+
+func loadTLS(cfg Config) (*tls.Config, error) {
+	if cfg.ClientCertFile == "" && cfg.CAFile == "" {
+		return nil, nil
+	}
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCertFile, cfg.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load kernel client cert: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read kernel CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("parse kernel CA bundle %s", cfg.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return tlsCfg, nil
+}
+
+// WithIdempotencyKey makes the next mutating call on ctx send key instead of a generated
+// one, so callers can retry a whole operation without duplicating it in the Kernel.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return jsonhttp.WithIdempotencyKey(ctx, key)
+}
+
+// do sends one logical request, retrying transient failures with the same
+// Idempotency-Key. out may be nil.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	err := c.core.Do(ctx, method, path, in, out)
+	if se, ok := jsonhttp.AsStatusError(err); ok {
+		return &APIError{Method: se.Method, Path: se.Path, StatusCode: se.StatusCode, Message: se.Message}
+	}
+	return err
+}
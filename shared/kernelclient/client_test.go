@@ -0,0 +1,101 @@
+package kernelclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ILLUVRSE/Main/shared/kernelclient"
+)
+
+func TestRetriesReuseIdempotencyKey(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/kernel/audit" || r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("unexpected request %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		n := len(keys)
+		mu.Unlock()
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"id": "ev-1", "eventType": body["eventType"], "hash": "abc"})
+	}))
+	defer srv.Close()
+
+	c, err := kernelclient.New(kernelclient.Config{BaseURL: srv.URL, BearerToken: "tok", Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ev, err := c.AppendAudit(context.Background(), "test.event", map[string]interface{}{"a": 1}, nil)
+	if err != nil {
+		t.Fatalf("AppendAudit: %v", err)
+	}
+	if ev.ID != "ev-1" || ev.EventType != "test.event" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if len(keys) != 3 || keys[0] == "" || keys[0] != keys[1] || keys[1] != keys[2] {
+		t.Fatalf("expected 3 attempts with one idempotency key, got %v", keys)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/kernel/audit/missing":
+			http.Error(w, "not found", http.StatusNotFound)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid DivisionManifest: id and name are required"}`))
+		}
+	}))
+	defer srv.Close()
+
+	c, err := kernelclient.New(kernelclient.Config{BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := c.GetAudit(context.Background(), "missing"); !errors.Is(err, kernelclient.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	_, err = c.RegisterDivision(context.Background(), map[string]interface{}{})
+	var apiErr *kernelclient.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, kernelclient.ErrBadRequest) || apiErr.Message != "invalid DivisionManifest: id and name are required" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestCallerIdempotencyKey(t *testing.T) {
+	got := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("Idempotency-Key")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"allocationId":"a-1","status":"pending"}`))
+	}))
+	defer srv.Close()
+
+	c, _ := kernelclient.New(kernelclient.Config{BaseURL: srv.URL})
+	ctx := kernelclient.WithIdempotencyKey(context.Background(), "alloc-req-1")
+	res, err := c.Allocate(ctx, kernelclient.AllocationRequest{DivisionID: "div-1", CPU: 2})
+	if err != nil || res.AllocationID != "a-1" {
+		t.Fatalf("Allocate: %+v %v", res, err)
+	}
+	if key := <-got; key != "alloc-req-1" {
+		t.Fatalf("expected caller key, got %q", key)
+	}
+}
@@ -0,0 +1,150 @@
+// Package merkle implements the RFC 6962 (Certificate Transparency) Merkle tree hash
+// and inclusion proofs over SHA-256.
+//
+// The Kernel's batch signing endpoint signs one root over many manifest hashes and
+// returns an inclusion proof per manifest; this package is shared so any Go service
+// can verify those proofs. Leaves and interior nodes are domain-separated
+// (0x00 || leaf, 0x01 || left || right), so a leaf can never be passed off as a node.
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+)
+
+// LeafHash returns the hash of one leaf's data.
+func LeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// Tree is a Merkle tree over a fixed list of leaves. Leaf and interior node hashes are
+// computed once by NewTree, so the root and every inclusion proof are read from them
+// instead of rehashing the leaves per proof. A Tree is safe for concurrent use.
+type Tree struct {
+	leaves [][]byte
+	nodes  map[[2]int][]byte // subtree hashes by (first leaf, leaf count)
+	root   []byte
+}
+
+// NewTree builds the tree over leaves (leaf data, not leaf hashes).
+func NewTree(leaves [][]byte) *Tree {
+	t := &Tree{leaves: make([][]byte, len(leaves)), nodes: make(map[[2]int][]byte)}
+	for i, l := range leaves {
+		t.leaves[i] = LeafHash(l)
+	}
+	if len(leaves) == 0 {
+		sum := sha256.Sum256(nil)
+		t.root = sum[:]
+	} else {
+		t.root = t.subtree(0, len(leaves))
+	}
+	return t
+}
+
+// Root returns the Merkle tree hash. The root of an empty tree is SHA-256 of the empty
+// string.
+func (t *Tree) Root() []byte {
+	return t.root
+}
+
+// Size returns the number of leaves.
+func (t *Tree) Size() int {
+	return len(t.leaves)
+}
+
+// Proof returns the inclusion proof (audit path) for leaf index, ordered from the leaf's
+// sibling up to the child of the root.
+func (t *Tree) Proof(index int) ([][]byte, error) {
+	if index < 0 || index >= len(t.leaves) {
+		return nil, fmt.Errorf("merkle: index %d out of range for %d leaves", index, len(t.leaves))
+	}
+	return t.path(index, 0, len(t.leaves)), nil
+}
+
+// subtree returns the hash of the n leaves starting at first, memoizing interior nodes.
+// Only NewTree adds nodes; every subtree a proof needs is part of the root's.
+func (t *Tree) subtree(first, n int) []byte {
+	if n == 1 {
+		return t.leaves[first]
+	}
+	key := [2]int{first, n}
+	if h, ok := t.nodes[key]; ok {
+		return h
+	}
+	k := split(n)
+	h := nodeHash(t.subtree(first, k), t.subtree(first+k, n-k))
+	t.nodes[key] = h
+	return h
+}
+
+func (t *Tree) path(index, first, n int) [][]byte {
+	if n == 1 {
+		return nil
+	}
+	k := split(n)
+	if index < first+k {
+		return append(t.path(index, first, k), t.subtree(first+k, n-k))
+	}
+	return append(t.path(index, first+k, n-k), t.subtree(first, k))
+}
+
+// Root returns the Merkle tree hash over leaves (leaf data, not leaf hashes). The root
+// of an empty tree is SHA-256 of the empty string.
+func Root(leaves [][]byte) []byte {
+	return NewTree(leaves).Root()
+}
+
+// Proof returns the inclusion proof (audit path) for leaves[index], ordered from the
+// leaf's sibling up to the child of the root. Use a Tree for several proofs over the
+// same leaves.
+func Proof(leaves [][]byte, index int) ([][]byte, error) {
+	return NewTree(leaves).Proof(index)
+}
+
+// Verify reports whether proof shows that data is leaf index of a tree of size leaves
+// with the given root (RFC 9162 §2.1.3.2).
+func Verify(data []byte, index, size int, proof [][]byte, root []byte) bool {
+	if index < 0 || index >= size {
+		return false
+	}
+	fn, sn := index, size-1
+	r := LeafHash(data)
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(r, root)
+}
+
+// split returns the largest power of two smaller than n (n > 1).
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
@@ -0,0 +1,102 @@
+package merkle_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/ILLUVRSE/Main/shared/merkle"
+)
+
+func leaves(n int) [][]byte {
+	out := make([][]byte, n)
+	for i := range out {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("manifest-%d", i)))
+		out[i] = sum[:]
+	}
+	return out
+}
+
+// Known roots from the RFC 6962 reference test vectors (certificate-transparency-go).
+func TestRootVectors(t *testing.T) {
+	data := [][]byte{
+		{},
+		{0x00},
+		{0x10},
+		{0x20, 0x21},
+		{0x30, 0x31},
+		{0x40, 0x41, 0x42, 0x43},
+		{0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57},
+		{0x60, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f},
+	}
+	want := []string{
+		"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+		"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+		"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+	}
+	for n := 1; n <= len(data); n++ {
+		if got := hex.EncodeToString(merkle.Root(data[:n])); got != want[n-1] {
+			t.Fatalf("root of %d leaves = %s, want %s", n, got, want[n-1])
+		}
+	}
+}
+
+func TestProofRoundTrip(t *testing.T) {
+	for n := 1; n <= 17; n++ {
+		ls := leaves(n)
+		root := merkle.Root(ls)
+		for i := 0; i < n; i++ {
+			proof, err := merkle.Proof(ls, i)
+			if err != nil {
+				t.Fatalf("proof(%d/%d): %v", i, n, err)
+			}
+			if !merkle.Verify(ls[i], i, n, proof, root) {
+				t.Fatalf("proof for leaf %d of %d does not verify", i, n)
+			}
+			if n > 1 && merkle.Verify(ls[(i+1)%n], i, n, proof, root) {
+				t.Fatalf("proof for leaf %d of %d verified the wrong leaf", i, n)
+			}
+			if n > 1 && merkle.Verify(ls[i], i, n, proof, merkle.Root(ls[:n-1])) {
+				t.Fatalf("proof for leaf %d of %d verified against the wrong root", i, n)
+			}
+		}
+	}
+}
+
+func TestTreeProofs(t *testing.T) {
+	ls := leaves(13)
+	tree := merkle.NewTree(ls)
+	if tree.Size() != 13 || !bytes.Equal(tree.Root(), merkle.Root(ls)) {
+		t.Fatalf("tree of %d leaves has root %x", tree.Size(), tree.Root())
+	}
+	for i := range ls {
+		proof, err := tree.Proof(i)
+		if err != nil || !merkle.Verify(ls[i], i, len(ls), proof, tree.Root()) {
+			t.Fatalf("tree proof for leaf %d does not verify: %v", i, err)
+		}
+	}
+	if _, err := tree.Proof(len(ls)); err == nil {
+		t.Fatalf("expected an out of range error")
+	}
+	if empty := merkle.NewTree(nil); empty.Size() != 0 || !bytes.Equal(empty.Root(), merkle.Root(nil)) {
+		t.Fatalf("unexpected empty tree root %x", empty.Root())
+	}
+}
+
+func TestLeafIsNotANode(t *testing.T) {
+	ls := leaves(2)
+	concat := append(merkle.LeafHash(ls[0]), merkle.LeafHash(ls[1])...)
+	if bytes.Equal(merkle.Root([][]byte{concat}), merkle.Root(ls)) {
+		t.Fatalf("a single leaf must not collide with an interior node")
+	}
+	if _, err := merkle.Proof(ls, 2); err == nil {
+		t.Fatalf("expected out-of-range index to be rejected")
+	}
+}
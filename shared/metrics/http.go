@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// HTTPMetrics records request counts and latencies per route.
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+	inflight *GaugeVec
+	service  string
+}
+
+// NewHTTPMetrics registers http_requests_total, http_request_duration_seconds and
+// http_requests_in_flight on reg, labelled with service.
+func NewHTTPMetrics(reg *Registry, service string) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: reg.NewCounterVec("http_requests_total", "HTTP requests by route and status code.", "service", "method", "route", "code"),
+		duration: reg.NewHistogramVec("http_request_duration_seconds", "HTTP request latency by route.", nil, "service", "method", "route"),
+		inflight: reg.NewGaugeVec("http_requests_in_flight", "HTTP requests currently being served.", "service"),
+		service:  service,
+	}
+}
+
+// Middleware instruments next. The route label is the chi route pattern (e.g.
+// /kernel/agent/{id}/state) or the net/http ServeMux pattern, so path parameters do not
+// create new series; unmatched requests are reported as "unmatched".
+func (m *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		inflight := m.inflight.With(m.service)
+		inflight.Inc()
+		defer inflight.Dec()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := routePattern(r)
+		m.requests.With(m.service, r.Method, route, strconv.Itoa(rec.status)).Inc()
+		m.duration.With(m.service, r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if p := rctx.RoutePattern(); p != "" {
+			return p
+		}
+	}
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	return "unmatched"
+}
+
+// statusRecorder captures the response status while keeping streaming (Flush) working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status = code
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,380 @@
+// Package metrics is a small Prometheus-compatible instrumentation package shared by the
+// Go services (kernel, ai-infra, eval-engine, reasoning-graph).
+//
+// It implements counters, gauges, histograms and function-backed collectors, and
+// serves them in the Prometheus text exposition format (version 0.0.4) from
+// Registry.Handler. Services mount that handler at GET /metrics and wrap their router
+// with HTTPMetrics for per-route request counts and latencies.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are latency buckets (seconds) suitable for HTTP handlers and RPCs.
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the process-wide registry used by packages that instrument themselves.
+var Default = NewRegistry()
+
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds collectors and renders them for scraping. Metric names must be unique
+// within a registry; registering a duplicate panics, like prometheus.MustRegister.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]collector{}}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.collectors[c.name()]; dup {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", c.name()))
+	}
+	r.collectors[c.name()] = c
+}
+
+// WriteText writes every collector in the text exposition format, sorted by name.
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.collectors))
+	for n := range r.collectors {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	cs := make([]collector, 0, len(names))
+	for _, n := range names {
+		cs = append(cs, r.collectors[n])
+	}
+	r.mu.RUnlock()
+	for _, c := range cs {
+		c.write(w)
+	}
+}
+
+// Handler serves the registry for Prometheus scrapes.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// --- vectors ---
+
+// vec stores one child per distinct label value tuple.
+type vec[T any] struct {
+	metricName string
+	help       string
+	labels     []string
+	newChild   func() *T
+
+	mu       sync.RWMutex
+	children map[string]*T
+	values   map[string][]string
+}
+
+func newVec[T any](name, help string, labels []string, newChild func() *T) *vec[T] {
+	return &vec[T]{
+		metricName: name,
+		help:       help,
+		labels:     labels,
+		newChild:   newChild,
+		children:   map[string]*T{},
+		values:     map[string][]string{},
+	}
+}
+
+func (v *vec[T]) name() string { return v.metricName }
+
+func (v *vec[T]) with(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.RLock()
+	c, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return c
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok := v.children[key]; ok {
+		return c
+	}
+	c = v.newChild()
+	v.children[key] = c
+	v.values[key] = append([]string(nil), values...)
+	return c
+}
+
+// each visits children in label order so output is stable between scrapes.
+func (v *vec[T]) each(fn func(labelValues []string, child *T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.children))
+	for k := range v.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	type entry struct {
+		values []string
+		child  *T
+	}
+	entries := make([]entry, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, entry{v.values[k], v.children[k]})
+	}
+	v.mu.RUnlock()
+	for _, e := range entries {
+		fn(e.values, e.child)
+	}
+}
+
+func (v *vec[T]) header(w io.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.metricName, escapeHelp(v.help), v.metricName, typ)
+}
+
+// --- counter ---
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add increments the counter; negative values are ignored.
+func (c *Counter) Add(d float64) {
+	if d < 0 {
+		return
+	}
+	c.mu.Lock()
+	c.v += d
+	c.mu.Unlock()
+}
+
+func (c *Counter) value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.v
+}
+
+type CounterVec struct{ *vec[Counter] }
+
+// NewCounterVec registers a counter with the given label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{newVec(name, help, labels, func() *Counter { return &Counter{} })}
+	r.register(v)
+	return v
+}
+
+func (v *CounterVec) With(labelValues ...string) *Counter { return v.with(labelValues) }
+
+func (v *CounterVec) write(w io.Writer) {
+	v.header(w, "counter")
+	v.each(func(lv []string, c *Counter) {
+		fmt.Fprintf(w, "%s%s %s\n", v.metricName, formatLabels(v.labels, lv), formatFloat(c.value()))
+	})
+}
+
+// --- gauge ---
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (g *Gauge) Set(x float64) {
+	g.mu.Lock()
+	g.v = x
+	g.mu.Unlock()
+}
+
+func (g *Gauge) Add(d float64) {
+	g.mu.Lock()
+	g.v += d
+	g.mu.Unlock()
+}
+
+func (g *Gauge) Inc() { g.Add(1) }
+func (g *Gauge) Dec() { g.Add(-1) }
+
+func (g *Gauge) value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.v
+}
+
+type GaugeVec struct{ *vec[Gauge] }
+
+// NewGaugeVec registers a gauge with the given label names.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{newVec(name, help, labels, func() *Gauge { return &Gauge{} })}
+	r.register(v)
+	return v
+}
+
+func (v *GaugeVec) With(labelValues ...string) *Gauge { return v.with(labelValues) }
+
+func (v *GaugeVec) write(w io.Writer) {
+	v.header(w, "gauge")
+	v.each(func(lv []string, g *Gauge) {
+		fmt.Fprintf(w, "%s%s %s\n", v.metricName, formatLabels(v.labels, lv), formatFloat(g.value()))
+	})
+}
+
+// --- histogram ---
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	upper []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *Histogram) Observe(x float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, ub := range h.upper {
+		if x <= ub {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += x
+	h.count++
+}
+
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+// NewHistogramVec registers a histogram with the given buckets (nil means DefBuckets).
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	v := &HistogramVec{buckets: b}
+	v.vec = newVec(name, help, labels, func() *Histogram {
+		return &Histogram{upper: b, counts: make([]uint64, len(b))}
+	})
+	r.register(v)
+	return v
+}
+
+func (v *HistogramVec) With(labelValues ...string) *Histogram { return v.with(labelValues) }
+
+func (v *HistogramVec) write(w io.Writer) {
+	v.header(w, "histogram")
+	bucketLabels := append(append([]string(nil), v.labels...), "le")
+	v.each(func(lv []string, h *Histogram) {
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		sum, count := h.sum, h.count
+		h.mu.Unlock()
+
+		le := make([]string, len(lv)+1)
+		copy(le, lv)
+		var cum uint64
+		for i, ub := range v.buckets {
+			cum += counts[i]
+			le[len(lv)] = formatFloat(ub)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.metricName, formatLabels(bucketLabels, le), cum)
+		}
+		le[len(lv)] = "+Inf"
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.metricName, formatLabels(bucketLabels, le), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.metricName, formatLabels(v.labels, lv), formatFloat(sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.metricName, formatLabels(v.labels, lv), count)
+	})
+}
+
+// --- function-backed collectors ---
+
+type funcCollector struct {
+	metricName string
+	help       string
+	typ        string
+	fn         func() float64
+}
+
+func (f *funcCollector) name() string { return f.metricName }
+
+func (f *funcCollector) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", f.metricName, escapeHelp(f.help), f.metricName, f.typ, f.metricName, formatFloat(f.fn()))
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn at scrape time.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcCollector{metricName: name, help: help, typ: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter whose value is read from fn at scrape time. fn must
+// be monotonic; it is used to expose counters owned by other packages.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcCollector{metricName: name, help: help, typ: "counter", fn: fn})
+}
+
+// --- formatting ---
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTextExposition(t *testing.T) {
+	reg := NewRegistry()
+	c := reg.NewCounterVec("jobs_total", "Jobs processed.", "result")
+	c.With("ok").Add(2)
+	c.With(`bad"quote`).Inc()
+	h := reg.NewHistogramVec("job_seconds", "Job latency.", []float64{0.1, 1}, "kind")
+	h.With("a").Observe(0.05)
+	h.With("a").Observe(0.5)
+	h.With("a").Observe(3)
+	reg.NewGaugeFunc("queue_depth", "Queued jobs.", func() float64 { return 7 })
+
+	var b strings.Builder
+	reg.WriteText(&b)
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE jobs_total counter\n",
+		`jobs_total{result="ok"} 2` + "\n",
+		`jobs_total{result="bad\"quote"} 1` + "\n",
+		"# TYPE job_seconds histogram\n",
+		`job_seconds_bucket{kind="a",le="0.1"} 1` + "\n",
+		`job_seconds_bucket{kind="a",le="1"} 2` + "\n",
+		`job_seconds_bucket{kind="a",le="+Inf"} 3` + "\n",
+		`job_seconds_sum{kind="a"} 3.55` + "\n",
+		`job_seconds_count{kind="a"} 3` + "\n",
+		"# TYPE queue_depth gauge\nqueue_depth 7\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "job_seconds") > strings.Index(out, "jobs_total") {
+		t.Fatalf("collectors should be sorted by name:\n%s", out)
+	}
+}
+
+func TestDuplicateMetricPanics(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounterVec("dup_total", "first")
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic on duplicate registration")
+		}
+	}()
+	reg.NewGaugeVec("dup_total", "second")
+}
+
+func TestHTTPMetricsUsesRoutePattern(t *testing.T) {
+	reg := NewRegistry()
+	hm := NewHTTPMetrics(reg, "kernel")
+	r := chi.NewRouter()
+	r.Use(hm.Middleware)
+	r.Get("/kernel/agent/{id}/state", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Handle("/metrics", reg.Handler())
+
+	for _, id := range []string{"a", "b"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/kernel/agent/"+id+"/state", nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	out := rec.Body.String()
+	for _, want := range []string{
+		`http_requests_total{service="kernel",method="GET",route="/kernel/agent/{id}/state",code="404"} 2`,
+		`http_requests_total{service="kernel",method="GET",route="unmatched",code="404"} 1`,
+		`http_request_duration_seconds_count{service="kernel",method="GET",route="/kernel/agent/{id}/state"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in output:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package tracing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter receives finished, sampled spans.
+type Exporter interface {
+	Export(SpanData) error
+	Close() error
+}
+
+// Exporter formats accepted by NewFileExporter and TRACING_EXPORTER.
+const (
+	FormatJSON = "json"
+	FormatOTLP = "otlp"
+)
+
+// FileExporter appends one line per span to a file: a SpanData object (json) or an
+// OTLP/JSON ExportTraceServiceRequest holding that single span (otlp), matching the
+// OpenTelemetry collector file exporter layout.
+type FileExporter struct {
+	format string
+
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+}
+
+// NewFileExporter opens path for appending.
+func NewFileExporter(path, format string) (*FileExporter, error) {
+	if format != FormatJSON && format != FormatOTLP {
+		return nil, fmt.Errorf("unknown trace export format %q", format)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open trace file: %w", err)
+	}
+	return &FileExporter{format: format, f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (e *FileExporter) Export(s SpanData) error {
+	var (
+		line []byte
+		err  error
+	)
+	if e.format == FormatOTLP {
+		line, err = json.Marshal(otlpRequest(s))
+	} else {
+		line, err = json.Marshal(s)
+	}
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	// Flush per span: traces are diagnostic and must survive a crash.
+	return e.w.Flush()
+}
+
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.w.Flush(); err != nil {
+		return err
+	}
+	return e.f.Close()
+}
+
+// FromEnv builds a tracer for service from TRACING_EXPORTER (none|json|otlp) and
+// TRACING_FILE (default "<service>-traces.jsonl").
+func FromEnv(service string) (*Tracer, error) {
+	format := strings.ToLower(strings.TrimSpace(os.Getenv("TRACING_EXPORTER")))
+	if format == "" || format == "none" {
+		return NewTracer(service, nil), nil
+	}
+	path := strings.TrimSpace(os.Getenv("TRACING_FILE"))
+	if path == "" {
+		path = service + "-traces.jsonl"
+	}
+	exp, err := NewFileExporter(path, format)
+	if err != nil {
+		return nil, err
+	}
+	return NewTracer(service, exp), nil
+}
+
+// --- OTLP/JSON encoding ---
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// OTLP SpanKind values: 1 internal, 2 server, 3 client.
+func otlpKind(k string) int {
+	switch k {
+	case "server":
+		return 2
+	case "client":
+		return 3
+	default:
+		return 1
+	}
+}
+
+func otlpValue(v interface{}) map[string]interface{} {
+	switch x := v.(type) {
+	case string:
+		return map[string]interface{}{"stringValue": x}
+	case bool:
+		return map[string]interface{}{"boolValue": x}
+	case int:
+		return map[string]interface{}{"intValue": strconv.Itoa(x)}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(x, 10)}
+	case float64:
+		return map[string]interface{}{"doubleValue": x}
+	default:
+		return map[string]interface{}{"stringValue": fmt.Sprint(x)}
+	}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func otlpRequest(s SpanData) map[string]interface{} {
+	keys := make([]string, 0, len(s.Attributes))
+	for k := range s.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, otlpKeyValue{Key: k, Value: otlpValue(s.Attributes[k])})
+	}
+	span := otlpSpan{
+		TraceID:           s.TraceID,
+		SpanID:            s.SpanID,
+		ParentSpanID:      s.ParentSpanID,
+		Name:              s.Name,
+		Kind:              otlpKind(s.Kind),
+		StartTimeUnixNano: unixNano(s.Start),
+		EndTimeUnixNano:   unixNano(s.End),
+		Attributes:        attrs,
+	}
+	if s.Error != "" {
+		span.Status = &otlpStatus{Code: 2, Message: s.Error}
+	}
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpKeyValue{{Key: "service.name", Value: otlpValue(s.Service)}},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]string{"name": "github.com/ILLUVRSE/Main/shared/tracing"},
+				"spans": []otlpSpan{span},
+			}},
+		}},
+	}
+}
@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Inject writes the traceparent of the span active in ctx into h.
+func Inject(ctx context.Context, h http.Header) {
+	if sc := SpanContextFromContext(ctx); sc.IsValid() {
+		h.Set(TraceparentHeader, sc.Traceparent())
+	}
+}
+
+// Extract returns ctx carrying the remote parent from h's traceparent, if valid.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	if sc, err := ParseTraceparent(h.Get(TraceparentHeader)); err == nil {
+		return ContextWithRemoteSpanContext(ctx, sc)
+	}
+	return ctx
+}
+
+// Middleware continues the caller's trace (or starts one) with a server span per
+// request. The span is named "METHOD route" using the chi route pattern once routing
+// has completed.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := Extract(r.Context(), r.Header)
+		ctx, span := Default().Start(ctx, r.Method+" "+r.URL.Path, SpanKindServer)
+		defer span.End()
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.Path)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttribute("http.route", rctx.RoutePattern())
+		}
+		span.SetAttribute("http.status_code", rec.status)
+		if rec.status >= 500 {
+			span.RecordError(errStatus(rec.status))
+		}
+	})
+}
+
+// Transport wraps base (http.DefaultTransport when nil) so each outbound request gets a
+// client span and carries its traceparent.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if _, ok := base.(*transport); ok {
+		return base
+	}
+	return &transport{base: base}
+}
+
+// WrapClient returns a shallow copy of c (a new client when nil) with a tracing
+// Transport.
+func WrapClient(c *http.Client) *http.Client {
+	if c == nil {
+		return &http.Client{Transport: Transport(nil)}
+	}
+	cp := *c
+	cp.Transport = Transport(c.Transport)
+	return &cp
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := Default().Start(req.Context(), req.Method+" "+req.URL.Host, SpanKindClient)
+	defer span.End()
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+
+	out := req.Clone(ctx)
+	Inject(ctx, out.Header)
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.RecordError(errStatus(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+type errStatus int
+
+func (e errStatus) Error() string {
+	return "http status " + http.StatusText(int(e))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status = code
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Metadata keys stamped into audit and reasoning metadata.
+const (
+	MetadataTraceID = "traceId"
+	MetadataSpanID  = "spanId"
+)
+
+// Annotate returns a copy of md with the trace and span ids from ctx added. Existing
+// keys are kept, so an explicitly supplied traceId wins. md may be nil.
+func Annotate(ctx context.Context, md map[string]interface{}) map[string]interface{} {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return md
+	}
+	out := make(map[string]interface{}, len(md)+2)
+	for k, v := range md {
+		out[k] = v
+	}
+	if _, ok := out[MetadataTraceID]; !ok {
+		out[MetadataTraceID] = sc.TraceIDString()
+		out[MetadataSpanID] = sc.SpanIDString()
+	}
+	return out
+}
+
+// AnnotateValue stamps trace ids into an arbitrary metadata value when it is nil or a
+// JSON object (map[string]interface{} or json.RawMessage); other values are returned
+// unchanged.
+func AnnotateValue(ctx context.Context, md interface{}) interface{} {
+	switch m := md.(type) {
+	case nil:
+		if out := Annotate(ctx, nil); out != nil {
+			return out
+		}
+		return nil
+	case map[string]interface{}:
+		return Annotate(ctx, m)
+	case json.RawMessage:
+		return AnnotateJSON(ctx, m)
+	default:
+		return md
+	}
+}
+
+// AnnotateJSON stamps trace ids into a JSON object. Empty input is treated as {};
+// non-object JSON is returned unchanged.
+func AnnotateJSON(ctx context.Context, raw json.RawMessage) json.RawMessage {
+	if !SpanContextFromContext(ctx).IsValid() {
+		return raw
+	}
+	md := map[string]interface{}{}
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &md); err != nil {
+			return raw
+		}
+	}
+	out, err := json.Marshal(Annotate(ctx, md))
+	if err != nil {
+		return raw
+	}
+	return out
+}
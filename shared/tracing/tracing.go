@@ -0,0 +1,342 @@
+// Package tracing is a small OpenTelemetry-style tracer shared by the Go services.
+//
+// It propagates W3C Trace Context (the traceparent header) across HTTP hops, records
+// spans around interesting operations (HTTP handlers, outbound calls, signing, DB and
+// policy checks) and hands finished spans to an Exporter. Exporters write one record per
+// line to a local file either as plain JSON spans or as OTLP/JSON
+// ExportTraceServiceRequest messages, so traces from every service can be merged and
+// loaded into an OTLP-capable backend.
+//
+// A process installs its tracer once with SetDefault (usually from FromEnv); library
+// code calls the package-level Start so it does not need a tracer threaded through.
+// Without a configured exporter spans are still created, so trace ids keep flowing into
+// audit metadata and downstream calls; they are simply not exported.
+package tracing
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceparentHeader is the W3C Trace Context propagation header.
+const TraceparentHeader = "traceparent"
+
+// SpanKind mirrors the OpenTelemetry span kinds used here.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+func (sc SpanContext) TraceIDString() string { return hex.EncodeToString(sc.TraceID[:]) }
+func (sc SpanContext) SpanIDString() string  { return hex.EncodeToString(sc.SpanID[:]) }
+
+// Traceparent formats sc as a version 00 traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceIDString() + "-" + sc.SpanIDString() + "-" + flags
+}
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+// ParseTraceparent parses a traceparent header value. Unknown future versions are
+// accepted as long as the version 00 fields are well formed, as the spec requires.
+func ParseTraceparent(h string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	var sc SpanContext
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, errInvalidTraceparent
+	}
+	sc.Sampled = flags[0]&0x01 == 1
+	return sc, nil
+}
+
+// --- context ---
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithSpan returns ctx carrying s as the active span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// SpanFromContext returns the active span, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithRemoteSpanContext records a parent received from another process.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext returns the active span's context, falling back to a remote
+// parent, or the zero SpanContext.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.sc
+	}
+	if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		return sc
+	}
+	return SpanContext{}
+}
+
+// TraceID returns the hex trace id active in ctx, or "".
+func TraceID(ctx context.Context) string {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceIDString()
+}
+
+// --- spans ---
+
+// Span is an in-progress operation. Methods are safe for concurrent use and a nil
+// *Span is a no-op.
+type Span struct {
+	tracer *Tracer
+	name   string
+	kind   SpanKind
+	sc     SpanContext
+	parent [8]byte
+	start  time.Time
+	mu     sync.Mutex
+	attrs  map[string]interface{}
+	errMsg string
+	ended  bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetName replaces the span name (e.g. once the HTTP route is known).
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.attrs == nil {
+		s.attrs = map[string]interface{}{}
+	}
+	s.attrs[key] = value
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errMsg = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and exports it if sampled. Calling End twice is a no-op.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	end := time.Now()
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	data := SpanData{
+		TraceID:    s.sc.TraceIDString(),
+		SpanID:     s.sc.SpanIDString(),
+		Name:       s.name,
+		Kind:       s.kind.String(),
+		Service:    s.tracer.service,
+		Start:      s.start,
+		End:        end,
+		Attributes: s.attrs,
+		Error:      s.errMsg,
+	}
+	if s.parent != [8]byte{} {
+		data.ParentSpanID = hex.EncodeToString(s.parent[:])
+	}
+	s.mu.Unlock()
+	if s.sc.Sampled && s.tracer.exporter != nil {
+		_ = s.tracer.exporter.Export(data)
+	}
+}
+
+// SpanData is a finished span handed to an Exporter.
+type SpanData struct {
+	TraceID      string                 `json:"traceId"`
+	SpanID       string                 `json:"spanId"`
+	ParentSpanID string                 `json:"parentSpanId,omitempty"`
+	Name         string                 `json:"name"`
+	Kind         string                 `json:"kind"`
+	Service      string                 `json:"service"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// --- tracer ---
+
+// Tracer creates spans for one service.
+type Tracer struct {
+	service  string
+	exporter Exporter
+}
+
+// NewTracer returns a tracer for service. A nil exporter creates spans (so ids
+// propagate) without exporting them.
+func NewTracer(service string, exp Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exp}
+}
+
+// Close flushes and closes the exporter.
+func (t *Tracer) Close() error {
+	if t == nil || t.exporter == nil {
+		return nil
+	}
+	return t.exporter.Close()
+}
+
+// Start begins a span as a child of the span (or remote parent) in ctx, or as a new
+// root, and returns ctx carrying it.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	s := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent.IsValid() {
+		s.sc.TraceID = parent.TraceID
+		s.sc.Sampled = parent.Sampled
+		s.parent = parent.SpanID
+	} else {
+		s.sc.TraceID = newTraceID()
+		s.sc.Sampled = true
+	}
+	s.sc.SpanID = newSpanID()
+	return ContextWithSpan(ctx, s), s
+}
+
+var defaultTracer atomic.Pointer[Tracer]
+
+func init() {
+	defaultTracer.Store(NewTracer("unknown", nil))
+}
+
+// SetDefault installs t as the process tracer used by Start, Middleware and Transport.
+func SetDefault(t *Tracer) {
+	if t != nil {
+		defaultTracer.Store(t)
+	}
+}
+
+// Default returns the process tracer.
+func Default() *Tracer {
+	return defaultTracer.Load()
+}
+
+// Start begins an internal span on the default tracer.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return Default().Start(ctx, name, SpanKindInternal)
+}
+
+// --- ids ---
+
+var (
+	rngMu sync.Mutex
+	rng   = rand.New(rand.NewSource(seed()))
+)
+
+func seed() int64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+func newTraceID() (id [16]byte) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	for id == [16]byte{} {
+		_, _ = rng.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() (id [8]byte) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	for id == [8]byte{} {
+		_, _ = rng.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const h = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(h)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if sc.TraceIDString() != "4bf92f3577b34da6a3ce929d0e0e4736" || !sc.Sampled || sc.Traceparent() != h {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestPropagationAcrossServices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	exp, err := NewFileExporter(path, FormatOTLP)
+	if err != nil {
+		t.Fatalf("exporter: %v", err)
+	}
+	prev := Default()
+	SetDefault(NewTracer("test", exp))
+	defer SetDefault(prev)
+
+	var downstreamTrace string
+	downstream := chi.NewRouter()
+	downstream.Use(Middleware)
+	downstream.Post("/kernel/audit", func(w http.ResponseWriter, r *http.Request) {
+		downstreamTrace = TraceID(r.Context())
+		md := Annotate(r.Context(), map[string]interface{}{"origin": "test"})
+		_ = json.NewEncoder(w).Encode(md)
+	})
+	ds := httptest.NewServer(downstream)
+	defer ds.Close()
+
+	client := WrapClient(nil)
+	upstream := chi.NewRouter()
+	upstream.Use(Middleware)
+	upstream.Post("/promote/{id}", func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodPost, ds.URL+"/kernel/audit", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Errorf("downstream call: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	const incoming = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodPost, "/promote/42", nil)
+	req.Header.Set(TraceparentHeader, incoming)
+	upstream.ServeHTTP(httptest.NewRecorder(), req)
+
+	if downstreamTrace != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace id not propagated downstream, got %q", downstreamTrace)
+	}
+	if err := exp.Close(); err != nil {
+		t.Fatalf("close exporter: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open traces: %v", err)
+	}
+	defer f.Close()
+	names := map[string]string{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var doc struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []struct {
+						TraceID      string `json:"traceId"`
+						ParentSpanID string `json:"parentSpanId"`
+						Name         string `json:"name"`
+						Kind         int    `json:"kind"`
+					} `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &doc); err != nil {
+			t.Fatalf("decode otlp line: %v", err)
+		}
+		span := doc.ResourceSpans[0].ScopeSpans[0].Spans[0]
+		if span.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Fatalf("span %q has trace %q", span.Name, span.TraceID)
+		}
+		names[span.Name] = span.ParentSpanID
+	}
+	for _, want := range []string{"POST /promote/{id}", "POST /kernel/audit"} {
+		if _, ok := names[want]; !ok {
+			t.Fatalf("missing span %q in %v", want, names)
+		}
+	}
+	if names["POST /promote/{id}"] != "00f067aa0ba902b7" {
+		t.Fatalf("server span should be a child of the incoming traceparent")
+	}
+	clientSpans := 0
+	for n := range names {
+		if strings.HasPrefix(n, "POST 127.0.0.1") {
+			clientSpans++
+		}
+	}
+	if clientSpans != 1 {
+		t.Fatalf("expected one client span, got spans %v", names)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	if got := Annotate(context.Background(), nil); got != nil {
+		t.Fatalf("no trace in context should leave metadata untouched, got %v", got)
+	}
+	ctx, span := Start(context.Background(), "op")
+	defer span.End()
+	md := Annotate(ctx, map[string]interface{}{"origin": "x"})
+	if md[MetadataTraceID] != span.SpanContext().TraceIDString() || md["origin"] != "x" {
+		t.Fatalf("unexpected metadata %v", md)
+	}
+	raw := AnnotateJSON(ctx, json.RawMessage(`{"a":1}`))
+	if !strings.Contains(string(raw), `"traceId":"`+span.SpanContext().TraceIDString()+`"`) {
+		t.Fatalf("trace id not stamped into %s", raw)
+	}
+	if got := AnnotateJSON(ctx, json.RawMessage(`[1]`)); string(got) != `[1]` {
+		t.Fatalf("non-object metadata should be unchanged, got %s", got)
+	}
+}